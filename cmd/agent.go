@@ -1,61 +1,263 @@
 package cmd
 
 import (
+    "context"
     "encoding/json"
     "errors"
     "fmt"
+    "path/filepath"
     "sort"
     "strings"
+    "text/tabwriter"
+    "time"
 
     "github.com/example/sre-ai/internal/agent"
+    "github.com/example/sre-ai/internal/config"
+    "github.com/example/sre-ai/internal/notify"
+    "github.com/example/sre-ai/internal/runs"
+    "github.com/example/sre-ai/internal/upload"
+    "github.com/example/sre-ai/internal/warnings"
     "github.com/spf13/cobra"
 )
 
-func newAgentCmd() *cobra.Command {
+func newAgentCmd(opts *config.GlobalOptions) *cobra.Command {
     cmd := &cobra.Command{
         Use:   "agent",
         Short: "Run autonomous but auditable agent flows",
     }
 
-    cmd.AddCommand(newAgentRunCmd())
-    cmd.AddCommand(newAgentOncallCmd())
+    cmd.AddCommand(newAgentRunCmd(opts))
+    cmd.AddCommand(newAgentValidateCmd(opts))
+    cmd.AddCommand(newAgentGraphCmd(opts))
+    cmd.AddCommand(newAgentLsCmd(opts))
+    cmd.AddCommand(newAgentOncallCmd(opts))
     return cmd
 }
 
-func newAgentRunCmd() *cobra.Command {
+func newAgentRunCmd(opts *config.GlobalOptions) *cobra.Command {
     var workflowPath string
     var inputPairs []string
     var planOnly bool
+    var debug bool
+    var breakAt []string
+    var watches []string
+    var timeout time.Duration
+    var uploadTo string
+    var profile bool
+    var profileSort string
 
     cmd := &cobra.Command{
         Use:   "run",
         Short: "Execute an agent workflow",
+        Long: `Execute an agent workflow.
+
+--debug pauses before every step to show its rendered params and prompt. At the
+pause a param can be changed (set key=value, or e to edit them all in $EDITOR), the
+step skipped, and the state of earlier steps inspected; after the step it can be
+re-run. Every attempt is recorded in run history. Type h at a pause for the commands.
+
+--break-at stage.step stops at that step. With --debug the run goes on without
+pausing until it reaches a breakpoint; without it, the run stops before the step and
+records it with its rendered params and prompt. --watch prints an expression such as
+steps.triage.json.errorRate, or a template, after every step.
+
+--profile ends the output with every step's wall time, subprocess CPU time, model
+tokens, and tool output size, most costly first; --profile-sort picks the cost
+(time, tokens, cpu, or output). The same numbers are kept in run history for
+'sre-ai runs show --profile'.`,
+        Example: `  sre-ai agent run --workflow rca.yaml --debug
+  sre-ai agent run --workflow rca.yaml --debug --break-at analyze.summarize_thread
+  sre-ai agent run --workflow rca.yaml --break-at summarize_thread --watch steps.load_thread.thread.conversation.0
+  sre-ai agent run --workflow rca.yaml --profile --profile-sort tokens`,
         RunE: func(cmd *cobra.Command, args []string) error {
             if workflowPath == "" {
                 return errors.New("--workflow is required")
             }
+            if debug && planOnly {
+                return errors.New("--debug cannot be combined with --plan")
+            }
+            if debug && opts.NoInteractive {
+                return errors.New("--debug needs an interactive terminal; drop --no-interactive")
+            }
+            if planOnly && (len(breakAt) > 0 || len(watches) > 0) {
+                return errors.New("--break-at and --watch cannot be combined with --plan")
+            }
+            if planOnly && uploadTo != "" {
+                return errors.New("--upload publishes a recorded run; drop --plan")
+            }
+            if planOnly && profile {
+                return errors.New("--profile measures an executed run; drop --plan")
+            }
+            if _, err := agent.ParseProfileKey(profileSort); err != nil {
+                return err
+            }
+            if cmd.Flags().Changed("profile-sort") && !profile {
+                return errors.New("--profile-sort needs --profile")
+            }
 
             provided, err := agent.ParseInputPairs(inputPairs)
             if err != nil {
                 return err
             }
 
-            runner, err := agent.NewRunner(workflowPath, &globalOpts, provided, cmd.ErrOrStderr())
+            runner, err := agent.NewRunner(cmd.Context(), workflowPath, opts, provided, cmd.ErrOrStderr())
             if err != nil {
                 return err
             }
 
-            result, err := runner.Execute(cmd.Context(), planOnly)
+            if err := runner.SetBreakpoints(breakAt); err != nil {
+                return err
+            }
+            runner.SetConfirmer(confirmerFor(cmd, opts))
+            watched := &watchList{exprs: watches, eval: runner.Watch, out: cmd.ErrOrStderr()}
+            if debug {
+                runner.SetDebugger(newStepDebugger(cmd.InOrStdin(), cmd.ErrOrStderr(), watched, len(breakAt) > 0).pause)
+            } else if len(watches) > 0 {
+                runner.SetObserver(func(stage agent.StageSpec, result agent.StepResult) {
+                    fmt.Fprintf(cmd.ErrOrStderr(), "after %s/%s (%s):\n", stage.ID, result.StepName, result.Status)
+                    watched.print()
+                })
+            }
+
+            var record *runs.Record
+            if !planOnly {
+                // History is best-effort; a read-only config dir must not block a run.
+                record, err = runs.Start(cmd.Context(), "agent", runner.WorkflowMeta().Name, workflowPath, runs.CaptureEnvironment(cmd.Context(), opts, ""))
+                if err != nil {
+                    warnings.Add(cmd.Context(), "runs", "run history disabled: %v", err)
+                    record = nil
+                }
+                if record != nil {
+                    runner.SetRun(record.ID, record.ArtifactsDir())
+                }
+            }
+
+            ctx := cmd.Context()
+            if timeout > 0 {
+                // Model calls inherit the deadline, so a slow provider cannot overrun it.
+                var cancel context.CancelFunc
+                ctx, cancel = context.WithTimeout(ctx, timeout)
+                defer cancel()
+            }
+            result, err := runner.Execute(ctx, planOnly)
+            if record != nil {
+                if result != nil {
+                    result.RunID = record.ID
+                }
+                if saveErr := record.Finish(cmd.Context(), result, err); saveErr != nil {
+                    warnings.Add(cmd.Context(), "runs", "could not save run %s: %v", record.ID, saveErr)
+                }
+            }
+            // The bundle is published whether or not the run succeeded; failed runs are
+            // the ones whose evidence is wanted.
+            var uploaded string
+            var uploadErr error
+            if uploadTo != "" {
+                if record == nil {
+                    uploadErr = errors.New("run history is disabled, so there is no run bundle to upload")
+                } else if bundle, bundleErr := upload.Bundle(record.ArtifactsDir()); bundleErr != nil {
+                    uploadErr = bundleErr
+                } else {
+                    var obj *upload.Object
+                    obj, uploaded, uploadErr = publish(cmd, opts, uploadTo, record.ID+"/run.tar.gz", bundle, upload.TypeGzip)
+                    if obj != nil && result != nil {
+                        result.Uploads = append(result.Uploads, obj)
+                    }
+                }
+            }
+            if !planOnly {
+                runID := ""
+                if record != nil {
+                    runID = record.ID
+                }
+                notifyAgentRun(cmd, opts, runner.WorkflowMeta().Name, runID, result, err)
+            }
+            if profile && result != nil {
+                // Built after Finish, so history keeps the per-step numbers and not this view.
+                if p, profileErr := agent.NewProfile(result, profileSort); profileErr == nil {
+                    result.Profile = p
+                    if err != nil || (opts.Text && !opts.JSON) {
+                        fmt.Fprintln(cmd.ErrOrStderr(), formatProfile(p))
+                    }
+                }
+            }
             if err != nil {
+                if uploaded != "" {
+                    fmt.Fprintln(cmd.ErrOrStderr(), uploaded)
+                }
+                // A stopped rollout is the failure; show how far each wave got.
+                if result != nil && !opts.Quiet {
+                    for _, step := range result.Steps {
+                        if step.Rollout != nil && step.Rollout.Decision != "completed" {
+                            fmt.Fprintln(cmd.ErrOrStderr(), formatRollout(step))
+                        }
+                    }
+                }
+                if record != nil {
+                    return fmt.Errorf("run %s: %w", record.ID, err)
+                }
                 return err
             }
+            if uploadErr != nil {
+                if record != nil {
+                    return fmt.Errorf("run %s completed but was not uploaded: %w", record.ID, uploadErr)
+                }
+                return uploadErr
+            }
 
             status := "completed"
             if result.PlanOnly {
                 status = "planned"
             }
+            if result.StoppedAt != "" {
+                status = "stopped at breakpoint " + result.StoppedAt
+            }
             human := fmt.Sprintf("Workflow %s %s (%d steps)", result.Workflow, status, len(result.Steps))
-            if globalOpts.Text && !globalOpts.JSON {
+            if result.RunID != "" {
+                human = fmt.Sprintf("%s - run %s", human, result.RunID)
+            }
+            review, verified, skipped := 0, 0, 0
+            for _, step := range result.Steps {
+                if step.Status == "needs_review" {
+                    review++
+                }
+                if step.Status == "skipped" {
+                    skipped++
+                }
+                if step.Verification != nil && step.Verification.Status == "verified" {
+                    verified++
+                }
+            }
+            if review > 0 {
+                human = fmt.Sprintf("%s; %d step(s) need human review (consensus conflict)", human, review)
+            }
+            if verified > 0 {
+                human = fmt.Sprintf("%s; %d remediation(s) verified", human, verified)
+            }
+            if skipped > 0 {
+                human = fmt.Sprintf("%s; %d step(s) skipped", human, skipped)
+            }
+            for _, step := range result.Steps {
+                if step.Rollout != nil {
+                    human += "\n" + formatRollout(step)
+                }
+            }
+            for _, obj := range result.Uploads {
+                human += "\n" + uploadLine(obj)
+            }
+            if result.Estimate != nil {
+                human += formatPlanEstimate(result)
+            }
+            if result.StoppedAt != "" {
+                human += formatBreakpointStep(result.Steps[len(result.Steps)-1])
+            }
+            if result.Profile != nil {
+                human += "\n" + formatProfile(result.Profile)
+            }
+            if opts.Text && !opts.JSON {
+                if err := writeJSONFile(cmd, opts, result); err != nil {
+                    return err
+                }
                 textOut := formatAgentTextOutput(result)
                 if textOut == "" {
                     textOut = human
@@ -63,18 +265,254 @@ func newAgentRunCmd() *cobra.Command {
                 fmt.Fprintln(cmd.OutOrStdout(), textOut)
                 return nil
             }
-            return printOutput(cmd, result, human)
+            return printOutput(cmd, opts, result, human)
         },
     }
 
     cmd.Flags().StringVar(&workflowPath, "workflow", "", "Path to workflow YAML definition")
     cmd.Flags().StringSliceVar(&inputPairs, "input", nil, "Workflow input as key=value (repeatable)")
-    cmd.Flags().BoolVar(&planOnly, "plan", false, "Only validate the workflow without executing steps")
+    cmd.Flags().BoolVar(&planOnly, "plan", false, "Only validate the workflow and estimate per-step calls, tokens, and time")
+    cmd.Flags().BoolVar(&debug, "debug", false, "Pause before and after each step to inspect, edit params, skip, or re-run it")
+    cmd.Flags().StringSliceVar(&breakAt, "break-at", nil, "Stop at this step, as stage.step (repeatable)")
+    cmd.Flags().StringArrayVar(&watches, "watch", nil, "Print this path or template after every step (repeatable)")
+    cmd.Flags().DurationVar(&timeout, "timeout", 0, "Abort the run after this long; model calls share the remaining time (0 waits indefinitely)")
+    cmd.Flags().BoolVar(&profile, "profile", false, "Print the cost of every step (time, CPU, tokens, tool output), most costly first")
+    cmd.Flags().StringVar(&profileSort, "profile-sort", "time", "Cost the profile is sorted by: time, tokens, cpu, or output")
+    cmd.Flags().StringVar(&uploadTo, "upload", "", "Upload the run bundle (record and artifacts) to this destination from the config file's upload block")
 
     return cmd
 }
 
-func newAgentOncallCmd() *cobra.Command {
+// notifyAgentRun sends a run that failed or has steps needing human review through the
+// notify routes. A run aborted from the debugger is not news.
+func notifyAgentRun(cmd *cobra.Command, opts *config.GlobalOptions, workflow, runID string, result *agent.Result, runErr error) {
+    if errors.Is(runErr, agent.ErrDebugAbort) {
+        return
+    }
+    ev := notify.Event{Kind: "agent_run", Service: workflow, RunID: runID}
+    if runErr != nil {
+        ev.Severity = "high"
+        ev.Title = fmt.Sprintf("workflow %s failed", workflow)
+        ev.Text = runErr.Error()
+    } else {
+        var review []string
+        for _, step := range result.Steps {
+            if step.Status == "needs_review" {
+                review = append(review, step.StageID+"/"+step.StepName)
+            }
+        }
+        if len(review) == 0 {
+            return
+        }
+        ev.Severity = "warning"
+        ev.Title = fmt.Sprintf("workflow %s needs human review", workflow)
+        ev.Text = "Consensus conflict in " + strings.Join(review, ", ")
+    }
+    notifyEvent(cmd, opts, ev)
+}
+
+// formatPlanEstimate lists each step's estimated provider calls, tokens, and time under --plan.
+func formatPlanEstimate(result *agent.Result) string {
+    var buf strings.Builder
+    for _, step := range result.Steps {
+        est := step.Estimate
+        if est == nil {
+            continue
+        }
+        line := fmt.Sprintf("\n  %s/%s (%s):", step.StageID, step.StepName, step.Type)
+        if est.ProviderCalls > 0 {
+            line += fmt.Sprintf(" %d provider call(s), ~%d prompt + ~%d output tokens,", est.ProviderCalls, est.PromptTokens, est.OutputTokens)
+        }
+        if est.ToolCalls > 0 {
+            line += fmt.Sprintf(" %d tool call(s),", est.ToolCalls)
+        }
+        buf.WriteString(fmt.Sprintf("%s ~%.1fs", line, est.Seconds))
+        for _, note := range est.Notes {
+            buf.WriteString(fmt.Sprintf("\n    note: %s", note))
+        }
+    }
+    total := result.Estimate
+    approx := "~"
+    if total.Partial {
+        approx = ">="
+    }
+    buf.WriteString(fmt.Sprintf("\nEstimated total: %d provider call(s), %s%d prompt + ~%d output tokens, %d tool call(s), %s%.1fs",
+        total.ProviderCalls, approx, total.PromptTokens, total.OutputTokens, total.ToolCalls, approx, total.Seconds))
+    return buf.String()
+}
+
+func newAgentValidateCmd(opts *config.GlobalOptions) *cobra.Command {
+    var workflowPath string
+    var strict bool
+    var graph string
+
+    cmd := &cobra.Command{
+        Use:   "validate [workflow.yaml]",
+        Short: "Check a workflow's structure and lint its prompt templates",
+        Long: `Check a workflow's structure and lint its prompt templates.
+
+Validation also follows the data flow between steps: every template reference to
+steps.<name>.<key> must name a step that has run by then and a key it captures
+(_raw, params, or a capture alias), and every .inputs reference a declared input.
+--graph prints that flow as Graphviz DOT or a Mermaid flowchart instead of the
+summary, with any issues on stderr; with --output json the graph is under "graph".`,
+        Example: `  sre-ai agent validate workflows/lark_oncall.yaml --strict
+  sre-ai agent validate workflows/lark_oncall.yaml --graph dot | dot -Tsvg > flow.svg
+  sre-ai agent validate workflows/lark_oncall.yaml --graph mermaid`,
+        Args: cobra.MaximumNArgs(1),
+        RunE: func(cmd *cobra.Command, args []string) error {
+            if workflowPath == "" && len(args) > 0 {
+                workflowPath = args[0]
+            }
+            if workflowPath == "" {
+                return errors.New("--workflow is required")
+            }
+            graph = strings.ToLower(graph)
+            if graph != "" && graph != "dot" && graph != "mermaid" {
+                return fmt.Errorf("--graph must be dot or mermaid, got %q", graph)
+            }
+
+            wf, _, err := agent.LoadWorkflow(workflowPath)
+            if err != nil {
+                return err
+            }
+            issues := agent.Validate(wf)
+
+            payload := map[string]any{
+                "workflow": wf.Name,
+                "path":     workflowPath,
+                "valid":    !agent.HasErrors(issues),
+                "issues":   issues,
+            }
+            human := formatValidateHuman(wf.Name, issues)
+            if graph != "" {
+                flow, _ := agent.AnalyzeDataFlow(wf)
+                payload["graph"] = flow
+                if len(issues) > 0 {
+                    fmt.Fprintln(cmd.ErrOrStderr(), human)
+                }
+                if graph == "dot" {
+                    human = strings.TrimSuffix(flow.DOT(wf.Name), "\n")
+                } else {
+                    human = strings.TrimSuffix(flow.Mermaid(), "\n")
+                }
+            }
+            if err := printOutput(cmd, opts, payload, human); err != nil {
+                return err
+            }
+            if agent.HasErrors(issues) {
+                return fmt.Errorf("workflow %s failed validation", workflowPath)
+            }
+            if strict && len(issues) > 0 {
+                return fmt.Errorf("workflow %s has %d warning(s) (--strict)", workflowPath, len(issues))
+            }
+            return nil
+        },
+    }
+
+    cmd.Flags().StringVar(&workflowPath, "workflow", "", "Path to workflow YAML definition")
+    cmd.Flags().BoolVar(&strict, "strict", false, "Treat lint warnings as failures")
+    cmd.Flags().StringVar(&graph, "graph", "", "Print the data flow between inputs, steps, and outputs as dot or mermaid")
+
+    return cmd
+}
+
+func formatValidateHuman(name string, issues []agent.LintIssue) string {
+    if len(issues) == 0 {
+        return fmt.Sprintf("Workflow %s is valid", name)
+    }
+    var buf strings.Builder
+    buf.WriteString(fmt.Sprintf("Workflow %s: %d issue(s)", name, len(issues)))
+    for _, issue := range issues {
+        location := issue.Stage
+        if issue.Step != "" {
+            location = fmt.Sprintf("%s/%s", issue.Stage, issue.Step)
+        }
+        if location == "" {
+            location = "workflow"
+        }
+        buf.WriteString(fmt.Sprintf("\n  [%s] %s: %s", issue.Severity, location, issue.Message))
+    }
+    return buf.String()
+}
+
+// agentListing is one row of `agent ls`.
+type agentListing struct {
+    Name        string             `json:"name"`
+    Description string             `json:"description,omitempty"`
+    Path        string             `json:"path"`
+    Stats       runs.WorkflowStats `json:"stats"`
+}
+
+func newAgentLsCmd(opts *config.GlobalOptions) *cobra.Command {
+    var dir string
+
+    cmd := &cobra.Command{
+        Use:   "ls",
+        Short: "List workflows with run counts and feedback scores",
+        RunE: func(cmd *cobra.Command, args []string) error {
+            paths, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+            if err != nil {
+                return err
+            }
+            more, _ := filepath.Glob(filepath.Join(dir, "*", "*.yaml"))
+            paths = append(paths, more...)
+
+            records, err := runs.List(cmd.Context())
+            if err != nil {
+                return err
+            }
+            stats := runs.Stats(records)
+
+            listings := make([]agentListing, 0, len(paths))
+            for _, path := range paths {
+                wf, _, err := agent.LoadWorkflow(path)
+                if err != nil || wf.Name == "" || len(wf.Workflow.Stages) == 0 {
+                    continue
+                }
+                listing := agentListing{Name: wf.Name, Description: wf.Description, Path: path}
+                listing.Stats = stats[wf.Name]
+                listing.Stats.Workflow = wf.Name
+                listings = append(listings, listing)
+            }
+            sort.Slice(listings, func(i, j int) bool { return listings[i].Name < listings[j].Name })
+
+            return printOutput(cmd, opts, map[string]any{"workflows": listings}, formatAgentList(listings))
+        },
+    }
+
+    cmd.Flags().StringVar(&dir, "dir", "workflows", "Directory containing workflow YAML files")
+
+    return cmd
+}
+
+func formatAgentList(listings []agentListing) string {
+    if len(listings) == 0 {
+        return "No workflows found"
+    }
+    var buf strings.Builder
+    tw := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+    fmt.Fprintln(tw, "NAME\tRUNS\tSCORE\tPATH")
+    flagged := 0
+    for _, l := range listings {
+        score := "-"
+        if l.Stats.Ratings > 0 {
+            score = fmt.Sprintf("%.1f (%d)", l.Stats.MeanScore, l.Stats.Ratings)
+        }
+        if l.Stats.LowScore {
+            score += " LOW"
+            flagged++
+        }
+        fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", l.Name, l.Stats.Runs, score, l.Path)
+    }
+    tw.Flush()
+    if flagged > 0 {
+        buf.WriteString(fmt.Sprintf("%d workflow(s) consistently rated <= %.1f; review with 'sre-ai runs export --workflow <name>'", flagged, runs.LowScoreThreshold))
+    }
+    return strings.TrimRight(buf.String(), "\n")
+}
+
+func newAgentOncallCmd(opts *config.GlobalOptions) *cobra.Command {
     var start bool
     var stop bool
     var output string
@@ -95,7 +533,7 @@ func newAgentOncallCmd() *cobra.Command {
                 "output": output,
             }
             human := fmt.Sprintf("Oncall session %s", status)
-            return printOutput(cmd, payload, human)
+            return printOutput(cmd, opts, payload, human)
         },
     }
 
@@ -0,0 +1,421 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/example/sre-ai/internal/agent"
+	"gopkg.in/yaml.v3"
+)
+
+// maxDebugOutputLines bounds step output shown after a step; 'o' prints all of it.
+const maxDebugOutputLines = 30
+
+const debugBeforeHelp = `  c, n, enter     run the step
+  s               skip the step (recorded as skipped)
+  p               show the rendered params and prompt again
+  set key=value   set a param; the value is read as YAML (3, true, [a, b])
+  unset key       remove a param
+  e               edit all params in $EDITOR
+  i [step[.path]] inspect step state, e.g. i load_thread.thread; "i inputs" for inputs
+  w expr          watch a path or template after every step; w alone lists watches
+  unwatch expr    stop watching
+  g               stop pausing and run the rest of the workflow
+  q               abort the run`
+
+const debugAfterHelp = `  c, enter        continue: to the next breakpoint when there are any, else the next step
+  n               pause at the next step
+  r               re-run the step, pausing before it again
+  o               show the full step output
+  i [step[.path]] inspect step state
+  w expr          watch a path or template after every step; w alone lists watches
+  unwatch expr    stop watching
+  g               stop pausing and run the rest of the workflow
+  q               abort the run`
+
+// watchList holds the --watch expressions of a run.
+type watchList struct {
+	exprs []string
+	eval  func(expr string) (interface{}, error)
+	out   io.Writer
+}
+
+func (w *watchList) print() {
+	for _, expr := range w.exprs {
+		value, err := w.eval(expr)
+		switch {
+		case err != nil:
+			fmt.Fprintf(w.out, "  watch %s: %v\n", expr, err)
+		case value == nil:
+			fmt.Fprintf(w.out, "  watch %s = <undefined>\n", expr)
+		default:
+			fmt.Fprintf(w.out, "  watch %s = %s\n", expr, debugCompact(value))
+		}
+	}
+}
+
+// stepDebugger is the interactive debugger behind 'agent run --debug'. It talks on
+// stderr so --json output on stdout stays machine-readable.
+type stepDebugger struct {
+	reader  *bufio.Reader
+	out     io.Writer
+	watches *watchList
+	// stepping pauses at every step; otherwise only at breakpoints.
+	stepping       bool
+	hasBreakpoints bool
+	// detached is set by 'g'; the remaining steps then run without pausing.
+	detached bool
+}
+
+// newStepDebugger starts out pausing at every step, or only at breakpoints when the
+// run has any.
+func newStepDebugger(in io.Reader, out io.Writer, watches *watchList, hasBreakpoints bool) *stepDebugger {
+	return &stepDebugger{reader: bufio.NewReader(in), out: out, watches: watches, stepping: !hasBreakpoints, hasBreakpoints: hasBreakpoints}
+}
+
+func (d *stepDebugger) pause(ctx context.Context, point *agent.DebugPoint) (agent.DebugAction, error) {
+	if point.Phase == agent.DebugAfter && len(d.watches.exprs) > 0 && (d.detached || !d.stepping && !point.Breakpoint) {
+		fmt.Fprintf(d.out, "after %s/%s:\n", point.Stage.ID, point.StepName)
+		d.watches.print()
+	}
+	if d.detached || !d.stepping && !point.Breakpoint {
+		return agent.DebugContinue, nil
+	}
+	if point.Phase == agent.DebugBefore {
+		d.showBefore(point)
+	} else {
+		d.showAfter(point)
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return agent.DebugAbort, err
+		}
+		fmt.Fprint(d.out, "(debug) ")
+		line, err := d.reader.ReadString('\n')
+		if err != nil && (line == "" || !errors.Is(err, io.EOF)) {
+			if errors.Is(err, io.EOF) {
+				return agent.DebugAbort, errors.New("debugger input closed")
+			}
+			return agent.DebugAbort, err
+		}
+		command, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+		arg = strings.TrimSpace(arg)
+
+		switch command {
+		case "", "c", "continue":
+			if point.Phase == agent.DebugAfter && d.hasBreakpoints {
+				d.stepping = false
+			}
+			return agent.DebugContinue, nil
+		case "n", "next":
+			d.stepping = true
+			return agent.DebugContinue, nil
+		case "q", "quit", "abort":
+			return agent.DebugAbort, nil
+		case "g", "go":
+			d.detached = true
+			return agent.DebugContinue, nil
+		case "i", "inspect":
+			d.inspect(point, arg)
+			continue
+		case "w", "watch":
+			if arg == "" {
+				if len(d.watches.exprs) == 0 {
+					fmt.Fprintln(d.out, "no watches")
+				}
+				d.watches.print()
+				continue
+			}
+			d.watches.exprs = append(d.watches.exprs, arg)
+			d.watches.print()
+			continue
+		case "unwatch":
+			kept := d.watches.exprs[:0]
+			for _, expr := range d.watches.exprs {
+				if expr != arg {
+					kept = append(kept, expr)
+				}
+			}
+			if len(kept) == len(d.watches.exprs) {
+				fmt.Fprintf(d.out, "not watching %s\n", arg)
+			}
+			d.watches.exprs = kept
+			continue
+		case "h", "help", "?":
+			if point.Phase == agent.DebugBefore {
+				fmt.Fprintln(d.out, debugBeforeHelp)
+			} else {
+				fmt.Fprintln(d.out, debugAfterHelp)
+			}
+			continue
+		}
+
+		if point.Phase == agent.DebugBefore {
+			switch command {
+			case "s", "skip":
+				return agent.DebugSkip, nil
+			case "p", "params":
+				d.showBefore(point)
+			case "set":
+				d.setParam(point, arg)
+			case "unset":
+				if _, ok := point.Params[arg]; !ok {
+					fmt.Fprintf(d.out, "no param %q\n", arg)
+					continue
+				}
+				delete(point.Params, arg)
+				fmt.Fprintf(d.out, "unset %s\n", arg)
+			case "e", "edit":
+				if err := d.editParams(point); err != nil {
+					fmt.Fprintf(d.out, "edit: %v\n", err)
+				}
+			default:
+				fmt.Fprintf(d.out, "unknown command %q; h for help\n", command)
+			}
+			continue
+		}
+
+		switch command {
+		case "r", "rerun":
+			return agent.DebugRerun, nil
+		case "o", "output":
+			if point.Result != nil {
+				fmt.Fprintln(d.out, debugJSON(point.Result.Output))
+			}
+		default:
+			fmt.Fprintf(d.out, "unknown command %q; h for help\n", command)
+		}
+	}
+}
+
+func (d *stepDebugger) showBefore(point *agent.DebugPoint) {
+	header := fmt.Sprintf("\n>> %s/%s (%s)", point.Stage.ID, point.StepName, point.Step.Type)
+	if point.Step.Tool != "" {
+		header += " tool " + point.Step.Tool
+	}
+	if agent.IsHighRisk(point.Stage, point.Step) {
+		header += " [high risk]"
+	}
+	if point.Breakpoint {
+		header += " [breakpoint]"
+	}
+	if point.Attempt > 0 {
+		header += fmt.Sprintf(" - re-run %d", point.Attempt)
+	}
+	fmt.Fprintln(d.out, header)
+	if point.Step.Description != "" {
+		fmt.Fprintf(d.out, "   %s\n", point.Step.Description)
+	}
+	if point.RenderError != nil {
+		fmt.Fprintf(d.out, "render error: %v\n", point.RenderError)
+	}
+	if len(point.Params) > 0 {
+		fmt.Fprintf(d.out, "params:\n%s\n", indentLines(debugJSON(point.Params), "  "))
+	}
+	if point.Prompt != "" {
+		fmt.Fprintf(d.out, "prompt:\n%s\n", indentLines(point.Prompt, "  "))
+	}
+	fmt.Fprintln(d.out, "c run, s skip, set key=value, e edit, i inspect, q abort, h help")
+}
+
+func (d *stepDebugger) showAfter(point *agent.DebugPoint) {
+	if point.Result == nil {
+		fmt.Fprintf(d.out, "<< %s/%s finished\n", point.Stage.ID, point.StepName)
+	} else {
+		fmt.Fprintf(d.out, "<< %s/%s %s\n", point.Stage.ID, point.StepName, point.Result.Status)
+		if point.Result.Error != "" {
+			fmt.Fprintf(d.out, "error: %s\n", point.Result.Error)
+		}
+		if point.Result.Output != nil {
+			output := strings.Split(debugJSON(point.Result.Output), "\n")
+			if len(output) > maxDebugOutputLines {
+				output = append(output[:maxDebugOutputLines], fmt.Sprintf("... %d more line(s); o shows all", len(output)-maxDebugOutputLines))
+			}
+			fmt.Fprintf(d.out, "output:\n%s\n", indentLines(strings.Join(output, "\n"), "  "))
+		}
+	}
+	d.watches.print()
+	if point.Err != nil {
+		fmt.Fprintln(d.out, "The run stops here unless you re-run the step: r re-run, i inspect, q abort, h help")
+		return
+	}
+	fmt.Fprintln(d.out, "c continue, n next, r re-run, o output, i inspect, w watch, q abort, h help")
+}
+
+// formatBreakpointStep shows the rendered params and prompt of the step a run stopped at.
+func formatBreakpointStep(step agent.StepResult) string {
+	var buf strings.Builder
+	if step.Error != "" {
+		fmt.Fprintf(&buf, "\nrender error: %s", step.Error)
+	}
+	if output, ok := step.Output.(map[string]interface{}); ok && output["params"] != nil {
+		fmt.Fprintf(&buf, "\nparams:\n%s", indentLines(debugJSON(output["params"]), "  "))
+	}
+	if step.Prompt != "" {
+		fmt.Fprintf(&buf, "\nprompt:\n%s", indentLines(step.Prompt, "  "))
+	}
+	return buf.String()
+}
+
+func (d *stepDebugger) setParam(point *agent.DebugPoint, arg string) {
+	key, raw, ok := strings.Cut(arg, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		fmt.Fprintln(d.out, "usage: set key=value")
+		return
+	}
+	var value interface{}
+	if err := yaml.Unmarshal([]byte(raw), &value); err != nil {
+		value = raw
+	}
+	if point.Params == nil {
+		point.Params = map[string]interface{}{}
+	}
+	point.Params[key] = normalizeYAML(value)
+	fmt.Fprintf(d.out, "set %s = %s\n", key, debugCompact(point.Params[key]))
+}
+
+// editParams opens the params as YAML in $VISUAL or $EDITOR and reads them back.
+func (d *stepDebugger) editParams(point *agent.DebugPoint) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	params := point.Params
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	data, err := yaml.Marshal(params)
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp("", "sre-ai-params-*.yaml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	fields := strings.Fields(editor)
+	editCmd := exec.Command(fields[0], append(fields[1:], file.Name())...)
+	editCmd.Stdin, editCmd.Stdout, editCmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := editCmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", editor, err)
+	}
+	data, err = os.ReadFile(file.Name())
+	if err != nil {
+		return err
+	}
+	var edited map[string]interface{}
+	if err := yaml.Unmarshal(data, &edited); err != nil {
+		return fmt.Errorf("params unchanged: %w", err)
+	}
+	if edited == nil {
+		edited = map[string]interface{}{}
+	}
+	point.Params, _ = normalizeYAML(edited).(map[string]interface{})
+	fmt.Fprintf(d.out, "params:\n%s\n", indentLines(debugJSON(point.Params), "  "))
+	return nil
+}
+
+func (d *stepDebugger) inspect(point *agent.DebugPoint, path string) {
+	if path == "inputs" {
+		fmt.Fprintln(d.out, debugJSON(point.Inputs))
+		return
+	}
+	if path == "" {
+		names := make([]string, 0, len(point.State))
+		for name := range point.State {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			fmt.Fprintln(d.out, "no step state yet; i inputs shows the inputs")
+			return
+		}
+		for _, name := range names {
+			keys := make([]string, 0, len(point.State[name]))
+			for key := range point.State[name] {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			fmt.Fprintf(d.out, "  %s: %s\n", name, strings.Join(keys, ", "))
+		}
+		return
+	}
+	state := make(map[string]interface{}, len(point.State))
+	for name, values := range point.State {
+		state[name] = values
+	}
+	value := agent.LookupValue(state, path)
+	if value == nil {
+		fmt.Fprintf(d.out, "nothing at %s\n", path)
+		return
+	}
+	fmt.Fprintln(d.out, debugJSON(value))
+}
+
+// normalizeYAML turns the map[interface{}]interface{} values YAML can produce into the
+// map[string]interface{} shape the runner works with.
+func normalizeYAML(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeYAML(item)
+		}
+		return v
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			out[fmt.Sprint(key)] = normalizeYAML(item)
+		}
+		return out
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeYAML(item)
+		}
+		return v
+	}
+	return value
+}
+
+func debugJSON(value interface{}) string {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+func debugCompact(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+func indentLines(text, prefix string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = prefix + line
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/example/sre-ai/internal/agent"
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/runs"
+	"github.com/example/sre-ai/internal/warnings"
+	"github.com/spf13/cobra"
+)
+
+func newAgentGraphCmd(opts *config.GlobalOptions) *cobra.Command {
+	var workflowPath string
+	var runID string
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Draw a workflow's stages and steps as a Mermaid or DOT flowchart",
+		Long: `Draw a workflow's stages and steps as a Mermaid or DOT flowchart.
+
+Each stage is a box around its steps, drawn in run order; rollback steps hang off
+their remediation with a dashed arrow. With --run, every step is coloured by the
+status it ended with in that run and labelled with its duration, so the chart can go
+straight into a postmortem. --workflow defaults to the workflow the run used.`,
+		Example: `  sre-ai agent graph --workflow workflows/lark_oncall.yaml > docs/lark_oncall.mmd
+  sre-ai agent graph --run 20260301-101500-ab12
+  sre-ai agent graph --workflow workflows/lark_oncall.yaml --format dot | dot -Tpng > rca.png`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format = strings.ToLower(format)
+			if format != "mermaid" && format != "dot" {
+				return fmt.Errorf("--format must be mermaid or dot, got %q", format)
+			}
+
+			var rec *runs.Record
+			if runID != "" {
+				var err error
+				if rec, err = runs.Load(cmd.Context(), runID); err != nil {
+					return err
+				}
+				if rec.Kind != "agent" {
+					return fmt.Errorf("run %s is a %s run, not an agent workflow run", rec.ID, rec.Kind)
+				}
+				if workflowPath == "" {
+					workflowPath = rec.WorkflowPath
+				}
+			}
+			if workflowPath == "" {
+				return errors.New("--workflow is required")
+			}
+
+			wf, _, err := agent.LoadWorkflow(workflowPath)
+			if err != nil {
+				return err
+			}
+			var res *agent.Result
+			if rec != nil {
+				if rec.Workflow != wf.Name {
+					warnings.Add(cmd.Context(), "agent", "run %s is of workflow %s, not %s", rec.ID, rec.Workflow, wf.Name)
+				}
+				res = rec.Result
+				if res == nil {
+					// Runs that failed before the first step have no result.
+					res = &agent.Result{}
+				}
+				res.RunID = rec.ID
+			}
+
+			chart := agent.NewFlowchart(wf, res)
+			rendered := chart.Mermaid()
+			if format == "dot" {
+				rendered = chart.DOT()
+			}
+			payload := map[string]any{
+				"workflow": wf.Name,
+				"format":   format,
+				"chart":    chart,
+				"graph":    rendered,
+			}
+			if rec != nil {
+				payload["run_id"] = rec.ID
+				payload["status"] = rec.Status
+			}
+			return printOutput(cmd, opts, payload, strings.TrimSuffix(rendered, "\n"))
+		},
+	}
+
+	cmd.Flags().StringVar(&workflowPath, "workflow", "", "Path to workflow YAML definition")
+	cmd.Flags().StringVar(&runID, "run", "", "Annotate steps with the status and duration from this run (ID or unique prefix)")
+	cmd.Flags().StringVar(&format, "format", "mermaid", "Chart format: mermaid or dot")
+	return cmd
+}
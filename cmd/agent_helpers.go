@@ -1,44 +1,128 @@
 package cmd
 
 import (
-	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/confirm"
+	"github.com/example/sre-ai/internal/messages"
 	"github.com/spf13/cobra"
 )
 
-func promptForConfirmation(cmd *cobra.Command, question string) (bool, error) {
-	fmt.Fprintf(cmd.OutOrStdout(), "%s [y/N]: ", question)
-	reader := bufio.NewReader(cmd.InOrStdin())
-	resp, err := reader.ReadString('\n')
-	if err != nil {
-		return false, err
+const (
+	defaultSlackTokenEnv = "SLACK_BOT_TOKEN"
+	defaultWebListen     = "127.0.0.1:8765"
+)
+
+// confirmerFor picks who approves actions in this invocation: --confirm approves
+// everything, then a confirmer carried by the context (serve mode or --confirm-via
+// web), then the channel
+// chosen with --confirm-via or confirm.via. The terminal is the fallback unless
+// --no-interactive is set. Answers from a channel are logged in the audit log.
+func confirmerFor(cmd *cobra.Command, opts *config.GlobalOptions) confirm.Confirmer {
+	c := confirmChannel(cmd, opts)
+	switch c.(type) {
+	case confirm.Auto, confirm.Unavailable:
+		return c
 	}
-	resp = strings.TrimSpace(strings.ToLower(resp))
-	return resp == "y" || resp == "yes", nil
+	return confirm.Recorded{Confirmer: c}
 }
 
-func runKubectlDryRun(cmd *cobra.Command, actions []map[string]any) error {
-	for _, action := range actions {
-		commandStr, _ := action["command"].(string)
-		if commandStr == "" {
-			continue
+func confirmChannel(cmd *cobra.Command, opts *config.GlobalOptions) confirm.Confirmer {
+	if opts.AutoConfirm {
+		return confirm.Auto{}
+	}
+	if c, ok := confirm.FromContext(cmd.Context()); ok {
+		return c
+	}
+	if strings.EqualFold(opts.Confirm.Via, "slack") {
+		tokenEnv := opts.Confirm.SlackTokenEnv
+		if tokenEnv == "" {
+			tokenEnv = defaultSlackTokenEnv
 		}
-		if !strings.Contains(commandStr, "kubectl") {
-			continue
+		return &confirm.Slack{
+			Token:   os.Getenv(tokenEnv),
+			Channel: opts.Confirm.SlackChannel,
+			Timeout: opts.Confirm.Timeout,
 		}
-		dry := ensureDryRun(commandStr)
-		if globalOpts.JSON {
+	}
+	if opts.NoInteractive {
+		return confirm.Unavailable{}
+	}
+	return confirm.TTY{In: cmd.InOrStdin(), Out: cmd.OutOrStdout()}
+}
+
+// webConfirmer asks through one-time links served on confirm.web.listen. The listener
+// starts with the first request, so commands that never ask do not bind the port, and
+// stops when the command's context ends.
+type webConfirmer struct {
+	ctx  context.Context
+	addr string
+	web  *confirm.Web
+	once sync.Once
+	err  error
+}
+
+func newWebConfirmer(cmd *cobra.Command, opts *config.GlobalOptions) *webConfirmer {
+	addr := opts.Confirm.WebListen
+	if addr == "" {
+		addr = defaultWebListen
+	}
+	ctx, errOut := cmd.Context(), cmd.ErrOrStderr()
+	web := confirm.NewWeb(opts.Confirm.WebBaseURL, func(req confirm.Request, link string) {
+		fmt.Fprintf(errOut, "%s %s\n", req.Question, messages.Text(ctx, messages.WebHowTo, link))
+	})
+	web.Timeout = opts.Confirm.Timeout
+	return &webConfirmer{ctx: ctx, addr: addr, web: web}
+}
+
+func (c *webConfirmer) Confirm(ctx context.Context, req confirm.Request) (bool, error) {
+	c.once.Do(func() { c.err = c.web.ListenAndServe(c.ctx, c.addr) })
+	if c.err != nil {
+		return false, c.err
+	}
+	return c.web.Confirm(ctx, req)
+}
+
+// canConfirm reports whether anyone can be asked, so commands can refuse up front
+// instead of failing partway through.
+func canConfirm(cmd *cobra.Command, opts *config.GlobalOptions) bool {
+	_, unavailable := confirmerFor(cmd, opts).(confirm.Unavailable)
+	return !unavailable
+}
+
+func promptForConfirmation(cmd *cobra.Command, opts *config.GlobalOptions, req confirm.Request) (bool, error) {
+	return confirmerFor(cmd, opts).Confirm(cmd.Context(), req)
+}
+
+func runKubectlDryRun(cmd *cobra.Command, opts *config.GlobalOptions, actions []map[string]any) error {
+	for _, dry := range kubectlCommands(actions) {
+		if opts.JSON {
 			fmt.Fprintf(cmd.OutOrStdout(), "{\"action\":\"dry-run\",\"command\":\"%s\"}\n", escapeJSON(dry))
-		} else if !globalOpts.Quiet {
+		} else if !opts.Quiet {
 			fmt.Fprintf(cmd.OutOrStdout(), "dry-run kubectl: %s\n", dry)
 		}
 	}
 	return nil
 }
 
+// kubectlCommands returns the kubectl commands among actions, each made a dry run.
+func kubectlCommands(actions []map[string]any) []string {
+	var commands []string
+	for _, action := range actions {
+		commandStr, _ := action["command"].(string)
+		if commandStr == "" || !strings.Contains(commandStr, "kubectl") {
+			continue
+		}
+		commands = append(commands, ensureDryRun(commandStr))
+	}
+	return commands
+}
+
 func ensureDryRun(command string) string {
 	if strings.Contains(command, "--dry-run") {
 		return command
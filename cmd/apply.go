@@ -1,53 +1,61 @@
 package cmd
 
 import (
-	"errors"
 	"fmt"
 
+	"github.com/example/sre-ai/internal/audit"
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/confirm"
+	"github.com/example/sre-ai/internal/messages"
+	"github.com/example/sre-ai/internal/warnings"
 	"github.com/spf13/cobra"
 )
 
-func newApplyCmd() *cobra.Command {
+func newApplyCmd(opts *config.GlobalOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "apply",
 		Short: "Apply infrastructure or operational changes",
 	}
 
-	cmd.AddCommand(newApplyIacCmd())
+	cmd.AddCommand(newApplyIacCmd(opts))
 	return cmd
 }
 
-func newApplyIacCmd() *cobra.Command {
+func newApplyIacCmd(opts *config.GlobalOptions) *cobra.Command {
 	var stack string
 
 	cmd := &cobra.Command{
 		Use:   "iac",
 		Short: "Apply an IaC plan",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if globalOpts.DryRun {
-				return printOutput(cmd, map[string]string{"status": "dry-run"}, "Dry-run only; not applying changes")
+			if opts.DryRun {
+				return printOutput(cmd, opts, map[string]string{"status": "dry-run"}, "Dry-run only; not applying changes")
 			}
 
-			if !globalOpts.AutoConfirm {
-				if globalOpts.NoInteractive {
-					return errors.New("refusing to apply without --confirm in no-interactive mode")
+			if !opts.AutoConfirm {
+				if !canConfirm(cmd, opts) {
+					return messages.Error(cmd.Context(), messages.RefuseApply)
 				}
 
-				confirmed, err := promptForConfirmation(cmd, fmt.Sprintf("Apply IaC stack %s?", stack))
+				confirmed, err := promptForConfirmation(cmd, opts, confirm.Ask(cmd.Context(), messages.ConfirmApplyStack, []string{"iac/" + stack}, stack))
 				if err != nil {
 					return err
 				}
 				if !confirmed {
-					return printOutput(cmd, map[string]string{"status": "cancelled"}, "Apply cancelled")
+					return printOutput(cmd, opts, map[string]string{"status": "cancelled"}, messages.Text(cmd.Context(), messages.ApplyCancelled))
 				}
 			}
 
+			if _, err := audit.Record(cmd.Context(), audit.ActionApply, "iac/"+stack, "", ""); err != nil {
+				warnings.Add(cmd.Context(), "audit", "could not log apply of %s: %v", stack, err)
+			}
+
 			payload := map[string]any{
 				"stack":  stack,
 				"status": "applied",
 			}
 			human := fmt.Sprintf("Applied IaC stack %s", stack)
-			return printOutput(cmd, payload, human)
+			return printOutput(cmd, opts, payload, human)
 		},
 	}
 
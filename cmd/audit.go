@@ -0,0 +1,287 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/example/sre-ai/internal/audit"
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/runs"
+	"github.com/example/sre-ai/internal/timefmt"
+	"github.com/example/sre-ai/internal/timeparse"
+	"github.com/spf13/cobra"
+)
+
+func newAuditCmd(opts *config.GlobalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "List and verify the audit log and sign or verify reports",
+	}
+	cmd.AddCommand(newAuditLsCmd(opts))
+	cmd.AddCommand(newAuditVerifyCmd(opts))
+	cmd.AddCommand(newAuditSignCmd(opts))
+	cmd.AddCommand(newAuditKeyCmd(opts))
+	return cmd
+}
+
+func newAuditLsCmd(opts *config.GlobalOptions) *cobra.Command {
+	var who, action, since string
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "ls",
+		Short: "List audit log entries, newest first",
+		Long: `ls lists what sre-ai did and who did it. BY is the principal that started the action.
+For a confirmation (confirm.approve or confirm.deny), ANSWERED is whoever answered,
+when that was someone else, such as the Slack user who reacted. --principal keeps
+the entries that a principal started or answered.`,
+		Example: `  sre-ai audit ls --principal alice --since 7d
+  sre-ai audit ls --action confirm.approve --principal slack:U024BE7LH`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var cutoff time.Time
+			if since != "" {
+				var err error
+				if cutoff, err = timeparse.Cutoff(since, time.Now()); err != nil {
+					return fmt.Errorf("--since: %w", err)
+				}
+			}
+			path, err := audit.LogPath(cmd.Context())
+			if err != nil {
+				return err
+			}
+			entries, err := audit.Read(path)
+			if err != nil {
+				return err
+			}
+			matched := []audit.Entry{}
+			for i := len(entries) - 1; i >= 0; i-- {
+				entry := entries[i]
+				if (who != "" && !entry.Principal(who)) || (action != "" && entry.Action != action) || (!cutoff.IsZero() && entry.At.Before(cutoff)) {
+					continue
+				}
+				matched = append(matched, entry)
+				if limit > 0 && len(matched) == limit {
+					break
+				}
+			}
+
+			var buf strings.Builder
+			if len(matched) == 0 {
+				buf.WriteString("No audit entries")
+			} else {
+				tw := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+				fmt.Fprintln(tw, "SEQ\tAT\tACTION\tSUBJECT\tBY\tANSWERED")
+				for _, entry := range matched {
+					actor, by := entry.Actor, entry.By
+					if actor == "" {
+						actor = "-"
+					}
+					if by == "" {
+						by = "-"
+					}
+					fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", entry.Seq, timefmt.Timestamp(cmd.Context(), entry.At), entry.Action, entry.Subject, actor, by)
+				}
+				tw.Flush()
+			}
+			return printOutput(cmd, opts, map[string]any{"entries": matched}, strings.TrimRight(buf.String(), "\n"))
+		},
+	}
+
+	cmd.Flags().StringVar(&who, "principal", "", "Only list entries this principal started or answered")
+	cmd.Flags().StringVar(&action, "action", "", "Only list entries with this action, e.g. apply, run.finish, or confirm.deny")
+	cmd.Flags().StringVar(&since, "since", "", "Only list entries within this window (7d, 36h) or after a timestamp")
+	cmd.Flags().IntVar(&limit, "limit", 50, "Maximum entries to list (0 for all)")
+	return cmd
+}
+
+func newAuditVerifyCmd(opts *config.GlobalOptions) *cobra.Command {
+	var head string
+	var sigPath string
+	var keyPaths []string
+
+	cmd := &cobra.Command{
+		Use:   "verify [report]",
+		Short: "Check the audit log's hash chain and the run records it covers, or a signed report",
+		Long: `Without arguments, verify walks the audit log: every entry must hash to its
+recorded value and point at the entry before it, and every run record it covers must
+still match the digest logged when the run last changed. --head checks that an entry
+hash noted earlier, for example in the incident ticket, is still in the log, which
+catches a log rewritten from scratch.
+
+With a report path, verify checks the report against its detached signature
+(<report>.sig). The signing key must be the local key or one given with --key.`,
+		Example: `  sre-ai audit verify
+  sre-ai audit verify --head 3f9a0c...
+  sre-ai audit verify rca.md --key alice.pub`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 1 {
+				return verifyReport(cmd, opts, args[0], sigPath, keyPaths)
+			}
+			if sigPath != "" || len(keyPaths) > 0 {
+				return errors.New("--sig and --key apply when verifying a report")
+			}
+			path, err := audit.LogPath(cmd.Context())
+			if err != nil {
+				return err
+			}
+			entries, err := audit.Read(path)
+			if err != nil {
+				return err
+			}
+			problems := audit.VerifyChain(entries)
+			check, err := runs.VerifyAudit(cmd.Context(), entries)
+			if err != nil {
+				return err
+			}
+			problems = append(problems, check.Problems...)
+			latest := ""
+			if n := len(entries); n > 0 {
+				latest = entries[n-1].Hash
+			}
+			if head = strings.TrimSpace(head); head != "" {
+				found := false
+				for _, entry := range entries {
+					if entry.Hash == head {
+						found = true
+						break
+					}
+				}
+				if !found {
+					problems = append(problems, audit.Problem{Reason: fmt.Sprintf("head %s is not in the log; the log was rewritten", head)})
+				}
+			}
+
+			payload := map[string]any{
+				"log":      path,
+				"entries":  len(entries),
+				"head":     latest,
+				"runs":     check.Checked,
+				"pruned":   check.Pruned,
+				"problems": problems,
+				"ok":       len(problems) == 0,
+			}
+			if len(problems) > 0 {
+				var buf strings.Builder
+				fmt.Fprintf(&buf, "Audit log %s failed verification:", path)
+				for _, p := range problems {
+					buf.WriteString("\n  ")
+					if p.Seq > 0 {
+						fmt.Fprintf(&buf, "entry %d: ", p.Seq)
+					}
+					if p.Subject != "" {
+						fmt.Fprintf(&buf, "run %s: ", p.Subject)
+					}
+					buf.WriteString(p.Reason)
+				}
+				if err := printOutput(cmd, opts, payload, buf.String()); err != nil {
+					return err
+				}
+				// The problems are already reported; only the status is passed on.
+				cmd.SilenceUsage = true
+				cmd.SilenceErrors = true
+				return &exitCodeError{code: 1}
+			}
+			if len(entries) == 0 {
+				return printOutput(cmd, opts, payload, "Audit log is empty")
+			}
+			human := fmt.Sprintf("Audit log OK: %d entries, %d run record(s) match", len(entries), check.Checked)
+			if check.Pruned > 0 {
+				human += fmt.Sprintf(", %d pruned", check.Pruned)
+			}
+			human += "\nHead: " + latest
+			return printOutput(cmd, opts, payload, human)
+		},
+	}
+
+	cmd.Flags().StringVar(&head, "head", "", "Entry hash recorded earlier that must still be in the log")
+	cmd.Flags().StringVar(&sigPath, "sig", "", "Signature file for the report (default <report>.sig)")
+	cmd.Flags().StringArrayVar(&keyPaths, "key", nil, "Trusted public key file, PEM or base64 (repeatable; default the local key)")
+	return cmd
+}
+
+func verifyReport(cmd *cobra.Command, opts *config.GlobalOptions, report, sigPath string, keyPaths []string) error {
+	ctx := cmd.Context()
+	if sigPath == "" {
+		sigPath = report + audit.SignatureSuffix
+	}
+	data, err := os.ReadFile(report)
+	if err != nil {
+		return err
+	}
+	sig, err := audit.ReadSignature(sigPath)
+	if err != nil {
+		return err
+	}
+	var trusted []ed25519.PublicKey
+	for _, path := range keyPaths {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		pub, err := audit.ParsePublicKey(raw)
+		if err != nil {
+			return fmt.Errorf("--key %s: %w", path, err)
+		}
+		trusted = append(trusted, pub)
+	}
+	if err := sig.Verify(ctx, data, trusted); err != nil {
+		return fmt.Errorf("%s: %w", report, err)
+	}
+	payload := map[string]any{"report": report, "signature": sig, "ok": true}
+	human := fmt.Sprintf("%s: signature OK (key %s", report, sig.KeyID)
+	if sig.Signer != "" {
+		human += ", signed by " + sig.Signer
+	}
+	human += " at " + sig.SignedAt.Format("2006-01-02 15:04:05Z") + ")"
+	return printOutput(cmd, opts, payload, human)
+}
+
+func newAuditSignCmd(opts *config.GlobalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "sign <report>",
+		Short: "Sign a report with the local key, writing <report>.sig",
+		Long: `Sign writes a detached ed25519 signature next to the report and logs the signing
+in the audit log. The key is created under the config directory on first use; share
+its public key (sre-ai audit key) with whoever verifies the report.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.DryRun {
+				return printOutput(cmd, opts, map[string]any{"report": args[0], "status": "dry-run"}, fmt.Sprintf("Dry-run: would sign %s", args[0]))
+			}
+			sig, sigPath, err := audit.SignFile(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			payload := map[string]any{"report": args[0], "signature_path": sigPath, "signature": sig}
+			return printOutput(cmd, opts, payload, fmt.Sprintf("Signed %s with key %s (%s)", args[0], sig.KeyID, sigPath))
+		},
+	}
+}
+
+func newAuditKeyCmd(opts *config.GlobalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "key",
+		Short: "Print the public half of the local signing key",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, err := audit.SigningKey(cmd.Context())
+			if err != nil {
+				return err
+			}
+			pub := key.Public().(ed25519.PublicKey)
+			encoded, err := audit.PublicKeyPEM(pub)
+			if err != nil {
+				return err
+			}
+			payload := map[string]any{"key_id": audit.KeyID(pub), "public_key": string(encoded)}
+			return printOutput(cmd, opts, payload, strings.TrimRight(string(encoded), "\n"))
+		},
+	}
+}
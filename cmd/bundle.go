@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/example/sre-ai/internal/bundle"
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/incidents"
+	"github.com/example/sre-ai/internal/runtimes"
+	"github.com/example/sre-ai/internal/state"
+	"github.com/example/sre-ai/internal/warnings"
+	"github.com/spf13/cobra"
+)
+
+func newBundleCmd(opts *config.GlobalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Package sre-ai for installation on networks without internet access",
+	}
+	cmd.AddCommand(newBundleBuildCmd(opts))
+	cmd.AddCommand(newBundleInstallCmd(opts))
+	return cmd
+}
+
+func newBundleBuildCmd(opts *config.GlobalOptions) *cobra.Command {
+	var include []string
+	var out string
+	var target string
+	var binary string
+	var runtimesDir string
+	var workflowsDir string
+
+	cmd := &cobra.Command{
+		Use:   "build",
+		Short: "Package the binary, bundled runtimes, workflows, and knowledge notes into one archive",
+		Long: `Package the binary, bundled runtimes, workflows, and knowledge notes into one
+archive for 'sre-ai bundle install' on a machine without internet access.
+
+The archive starts with a sre-ai-offline.json manifest that records the target
+platform and the SHA-256 of every file. Runtimes are the distributions that
+'sre-ai runtime bundle' wrote under --runtimes-dir for the target; each is
+verified before it is packaged, and those without a manifest are left out.
+Knowledge is a snapshot of the notes under the config dir's knowledge/.
+
+The running binary is packaged unless --binary names another, which is required
+when --target is not the current platform. A component named in --include that
+has nothing to package fails the build; with the default --include it is left
+out with a warning.`,
+		Example: `  sre-ai bundle build --out sre-ai-offline.tar.gz
+  sre-ai bundle build --include runtimes,workflows --dry-run
+  sre-ai bundle build --target linux/arm64 --binary dist/sre-ai-linux-arm64`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			components, err := bundle.ParseComponents(include)
+			if err != nil {
+				return err
+			}
+			buildOpts := bundle.BuildOptions{Include: components, Binary: binary, RuntimesDir: runtimesDir, WorkflowsDir: workflowsDir}
+			if target != "" {
+				if buildOpts.Target, err = runtimes.ParseTarget(target); err != nil {
+					return err
+				}
+			}
+			if buildOpts.KnowledgeDir, err = incidents.KnowledgeDir(cmd.Context()); err != nil {
+				return err
+			}
+
+			plan, err := bundle.Collect(buildOpts)
+			if err != nil {
+				return err
+			}
+			for _, c := range plan.Empty {
+				if cmd.Flags().Changed("include") {
+					return fmt.Errorf("nothing to bundle for %s; %s", c, emptyComponentHint(c, buildOpts))
+				}
+				warnings.Add(cmd.Context(), "bundle", "left out %s: %s", c, emptyComponentHint(c, buildOpts))
+			}
+			for _, skipped := range plan.Skipped {
+				warnings.Add(cmd.Context(), "bundle", "left out runtime %s", skipped)
+			}
+
+			manifest := plan.Manifest
+			verb := "Bundled"
+			if opts.DryRun {
+				verb = "Dry-run: would bundle"
+			} else if err := plan.Write(out); err != nil {
+				return fmt.Errorf("write %s: %w", out, err)
+			}
+			lines := []string{fmt.Sprintf("%s %d files (%s) for %s into %s", verb, len(manifest.Files), state.FormatSize(plan.Size()), manifest.Target, out)}
+			counts := manifest.Count()
+			for _, c := range manifest.Components {
+				line := fmt.Sprintf("  %-10s %d file(s)", c, counts[c])
+				if c == bundle.ComponentRuntimes {
+					line += ": " + strings.Join(manifest.Runtimes, ", ")
+				}
+				lines = append(lines, line)
+			}
+			payload := map[string]any{"archive": out, "manifest": manifest, "skipped": plan.Skipped, "dry_run": opts.DryRun}
+			return printOutput(cmd, opts, payload, strings.Join(lines, "\n"))
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&include, "include", []string{"runtimes", "workflows", "knowledge"}, "Components to package besides the binary: runtimes, workflows, knowledge")
+	cmd.Flags().StringVar(&out, "out", "sre-ai-offline.tar.gz", "Archive to write")
+	cmd.Flags().StringVar(&target, "target", "", "Platform to bundle for as os/arch (default the current platform)")
+	cmd.Flags().StringVar(&binary, "binary", "", "sre-ai binary to package (default the running one)")
+	cmd.Flags().StringVar(&runtimesDir, "runtimes-dir", "third_party", "Directory 'sre-ai runtime bundle' wrote the runtimes to")
+	cmd.Flags().StringVar(&workflowsDir, "workflows-dir", "workflows", "Directory containing workflow YAML files")
+	return cmd
+}
+
+func emptyComponentHint(c bundle.Component, opts bundle.BuildOptions) string {
+	switch c {
+	case bundle.ComponentRuntimes:
+		return fmt.Sprintf("no runtime under %s is bundled for %s; run 'sre-ai runtime bundle --target %s --dir %s'", opts.RuntimesDir, targetOrCurrent(opts.Target), targetOrCurrent(opts.Target), opts.RuntimesDir)
+	case bundle.ComponentWorkflows:
+		return fmt.Sprintf("no files under %s (set --workflows-dir)", opts.WorkflowsDir)
+	default:
+		return fmt.Sprintf("no notes under %s", opts.KnowledgeDir)
+	}
+}
+
+func targetOrCurrent(t runtimes.Target) runtimes.Target {
+	if t == (runtimes.Target{}) {
+		return runtimes.CurrentTarget()
+	}
+	return t
+}
+
+func newBundleInstallCmd(opts *config.GlobalOptions) *cobra.Command {
+	var prefix string
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "install <archive>",
+		Short: "Install an archive written by 'sre-ai bundle build'",
+		Long: `Install an archive written by 'sre-ai bundle build'.
+
+Every file is checked against the archive's manifest, and the archive must have
+been built for this platform, before anything is written. The binary, runtimes,
+and workflows go under --prefix (bin/, third_party/, workflows/), where the
+installed binary finds its runtimes; knowledge notes go to the config dir's
+knowledge/. Existing files are kept unless --force is set.`,
+		Example: `  sre-ai bundle install sre-ai-offline.tar.gz --dry-run
+  sre-ai bundle install sre-ai-offline.tar.gz --prefix /opt/sre-ai --force`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			archive := args[0]
+			installOpts := bundle.InstallOptions{Prefix: prefix, Force: force, DryRun: opts.DryRun}
+			var err error
+			if installOpts.Prefix == "" {
+				if installOpts.Prefix, err = bundle.DefaultPrefix(); err != nil {
+					return err
+				}
+			}
+			if installOpts.KnowledgeDir, err = incidents.KnowledgeDir(cmd.Context()); err != nil {
+				return err
+			}
+
+			result, err := bundle.Install(archive, installOpts)
+			if err != nil {
+				return err
+			}
+
+			verb := "Installed"
+			if opts.DryRun {
+				verb = "Dry-run: would install"
+			}
+			lines := []string{fmt.Sprintf("%s %d files from %s into %s", verb, len(result.Written), archive, installOpts.Prefix)}
+			if len(result.Skipped) > 0 {
+				lines = append(lines, fmt.Sprintf("Skipped %d existing files (use --force to overwrite):", len(result.Skipped)))
+				for _, p := range result.Skipped {
+					lines = append(lines, "  - "+p)
+				}
+			}
+			lines = append(lines, fmt.Sprintf("Add %s to PATH", filepath.Dir(result.Binary)))
+			if result.WorkflowsDir != "" {
+				lines = append(lines, fmt.Sprintf("Workflows: sre-ai agent ls --dir %s", result.WorkflowsDir))
+			}
+			return printOutput(cmd, opts, result, strings.Join(lines, "\n"))
+		},
+	}
+
+	cmd.Flags().StringVar(&prefix, "prefix", "", "Install directory (default ~/.local/share/sre-ai, %LOCALAPPDATA%\\sre-ai on Windows)")
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite existing files")
+	return cmd
+}
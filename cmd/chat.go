@@ -7,18 +7,30 @@ import (
     "io"
     "strings"
 
-    "github.com/example/sre-ai/internal/credentials"
+    "github.com/example/sre-ai/internal/config"
     "github.com/example/sre-ai/internal/providers"
     "github.com/spf13/cobra"
 )
 
-func newChatCmd() *cobra.Command {
+func newChatCmd(opts *config.GlobalOptions) *cobra.Command {
     var session string
     var prompt string
+    var withWorkspace bool
+    var tools []string
+    var maxToolCalls int
 
     cmd := &cobra.Command{
         Use:   "chat",
         Short: "Send a single prompt to the configured chat model",
+        Long: `Send a single prompt to the configured chat model.
+
+With --tools the model may call tools of the named local MCP servers before it
+answers. Tools that do not declare themselves read-only need a confirmation, or
+--confirm; declined calls are not run. With --json the output is the full
+transcript: messages, tool calls with their arguments and results, the reply, and
+token usage.`,
+        Example: `  sre-ai chat "why would a pod be OOMKilled at 60% of its limit?"
+  sre-ai chat --tools kube,prom --json "which checkout pods restarted in the last hour?"`,
         RunE: func(cmd *cobra.Command, args []string) error {
             text := prompt
             if text == "" && len(args) > 0 {
@@ -26,7 +38,7 @@ func newChatCmd() *cobra.Command {
             }
 
             if text == "" {
-                if globalOpts.NoInteractive {
+                if opts.NoInteractive {
                     return errors.New("prompt required; pass text as arguments or via --prompt")
                 }
                 fmt.Fprint(cmd.OutOrStdout(), "Prompt: ")
@@ -42,28 +54,55 @@ func newChatCmd() *cobra.Command {
                 return errors.New("no prompt provided")
             }
 
-            model := globalOpts.Model
+            ws, err := detectWorkspace(withWorkspace)
+            if err != nil {
+                return err
+            }
+            query := text
+            if ws != nil {
+                query = ws.Summary() + "\n" + text
+            }
+
+            model := opts.ExplicitModel()
             if model == "" {
-                model = providers.DefaultGeminiModel()
+                model = providers.DefaultModel(opts.Provider)
             }
 
-            if globalOpts.DryRun {
+            if opts.DryRun {
                 payload := map[string]any{
                     "session": session,
                     "model":   model,
                     "prompt":  text,
                     "status":  "dry-run",
                 }
-                return printOutput(cmd, payload, "Dry-run: would query Gemini chat")
+                if len(tools) > 0 {
+                    payload["tools"] = tools
+                }
+                if ws != nil {
+                    payload["workspace"] = ws
+                }
+                return printOutput(cmd, opts, payload, fmt.Sprintf("Dry-run: would query %s chat", opts.Provider))
             }
 
-            apiKey, err := credentials.LoadGeminiKey()
+            client, err := providers.New(cmd.Context(), opts.Provider, model)
             if err != nil {
                 return err
             }
+            if len(tools) > 0 {
+                sessions, err := openChatSessions(cmd.Context(), tools)
+                if err != nil {
+                    return err
+                }
+                defer closeChatSessions(sessions)
+                transcript := &chatTranscript{Session: session, Model: model, Prompt: text, Tools: tools, Workspace: ws}
+                loop := &chatToolLoop{client: client, sessions: sessions, confirmer: confirmerFor(cmd, opts), maxCalls: maxToolCalls}
+                if err := loop.run(cmd.Context(), transcript, query); err != nil {
+                    return err
+                }
+                return printOutput(cmd, opts, transcript, fmt.Sprintf("[%s] %s", session, transcript.Reply))
+            }
 
-            client := providers.NewGeminiClient(apiKey, model)
-            reply, err := client.Generate(cmd.Context(), text)
+            reply, err := client.Generate(cmd.Context(), query)
             if err != nil {
                 return err
             }
@@ -74,13 +113,19 @@ func newChatCmd() *cobra.Command {
                 "prompt":  text,
                 "reply":   reply,
             }
+            if ws != nil {
+                payload["workspace"] = ws
+            }
             human := fmt.Sprintf("[%s] %s", session, reply)
-            return printOutput(cmd, payload, human)
+            return printOutput(cmd, opts, payload, human)
         },
     }
 
     cmd.Flags().StringVar(&session, "session", "default", "Session id to reuse")
     cmd.Flags().StringVarP(&prompt, "prompt", "p", "", "Prompt text to send")
+    cmd.Flags().StringSliceVar(&tools, "tools", nil, "Local MCP servers (aliases) whose tools the model may call")
+    cmd.Flags().IntVar(&maxToolCalls, "max-tool-calls", 8, "Stop when the model asks for more tool calls than this")
+    addWorkspaceFlag(cmd.Flags(), &withWorkspace)
 
     return cmd
 }
@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/sre-ai/internal/confirm"
+	"github.com/example/sre-ai/internal/mcp"
+	"github.com/example/sre-ai/internal/messages"
+	"github.com/example/sre-ai/internal/providers"
+	"github.com/example/sre-ai/internal/workspace"
+)
+
+// chatTranscript is the full record of a chat --tools conversation: what was said, every
+// tool call the model asked for and what came back, and the tokens it took.
+type chatTranscript struct {
+	Session   string             `json:"session"`
+	Model     string             `json:"model"`
+	Prompt    string             `json:"prompt"`
+	Tools     []string           `json:"tools"`
+	Messages  []chatMessage      `json:"messages"`
+	ToolCalls []chatToolCall     `json:"tool_calls"`
+	Reply     string             `json:"reply"`
+	Usage     providers.Usage    `json:"usage"`
+	Workspace *workspace.Context `json:"workspace,omitempty"`
+}
+
+// chatMessage is one turn. Role is user, assistant, or tool; tool turns carry the id of
+// the call they answer.
+type chatMessage struct {
+	Role       string `json:"role"`
+	Content    string `json:"content"`
+	ToolCallID string `json:"tool_call_id,omitempty"`
+}
+
+// chatToolCall records one tool call. Declined calls were not run; Error is set when the
+// call failed before the tool could answer.
+type chatToolCall struct {
+	ID         string                 `json:"id"`
+	Server     string                 `json:"server"`
+	Tool       string                 `json:"tool"`
+	Arguments  map[string]interface{} `json:"arguments"`
+	Result     *mcp.ToolResult        `json:"result,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	Declined   bool                   `json:"declined,omitempty"`
+	DurationMS int64                  `json:"duration_ms"`
+}
+
+// chatToolLoop drives the conversation. Providers only take a prompt, so the tools are
+// described in it and the model answers with one JSON object per turn: a tool call or
+// the final answer.
+type chatToolLoop struct {
+	client    providers.Client
+	sessions  map[string]*mcp.Session
+	confirmer confirm.Confirmer
+	maxCalls  int
+}
+
+type chatTurn struct {
+	Tool      string                 `json:"tool"`
+	Arguments map[string]interface{} `json:"arguments"`
+	Answer    *string                `json:"answer"`
+}
+
+func (l *chatToolLoop) run(ctx context.Context, transcript *chatTranscript, query string) error {
+	transcript.Messages = append(transcript.Messages, chatMessage{Role: "user", Content: query})
+	for {
+		reply, usage, err := providers.GenerateWithUsage(ctx, l.client, l.prompt(transcript))
+		if err != nil {
+			return err
+		}
+		transcript.Usage.Add(usage)
+		transcript.Messages = append(transcript.Messages, chatMessage{Role: "assistant", Content: reply})
+
+		var turn chatTurn
+		if err := json.Unmarshal([]byte(providers.StripCodeFence(reply)), &turn); err != nil || (turn.Tool == "" && turn.Answer == nil) {
+			// A reply that is not the JSON asked for is taken as the answer.
+			transcript.Reply = strings.TrimSpace(reply)
+			return nil
+		}
+		if turn.Tool == "" {
+			transcript.Reply = *turn.Answer
+			return nil
+		}
+		if len(transcript.ToolCalls) >= l.maxCalls {
+			return fmt.Errorf("model asked for more than %d tool calls (--max-tool-calls)", l.maxCalls)
+		}
+		call := l.call(ctx, len(transcript.ToolCalls)+1, turn)
+		transcript.ToolCalls = append(transcript.ToolCalls, call)
+		transcript.Messages = append(transcript.Messages, chatMessage{Role: "tool", Content: toolMessage(call), ToolCallID: call.ID})
+	}
+}
+
+// call runs the tool unless it is unknown or, not being read-only, is declined.
+func (l *chatToolLoop) call(ctx context.Context, n int, turn chatTurn) chatToolCall {
+	call := chatToolCall{ID: fmt.Sprintf("call_%d", n), Tool: turn.Tool, Arguments: turn.Arguments}
+	if call.Arguments == nil {
+		call.Arguments = map[string]interface{}{}
+	}
+	alias, name, ok := strings.Cut(turn.Tool, ".")
+	session := l.sessions[alias]
+	if !ok || session == nil {
+		call.Error = fmt.Sprintf("unknown tool %s", turn.Tool)
+		return call
+	}
+	call.Server, call.Tool = alias, name
+	tool, ok := session.Tool(name)
+	if !ok {
+		call.Error = fmt.Sprintf("server %s has no tool %s", alias, name)
+		return call
+	}
+	if !tool.ReadOnly() {
+		args, _ := json.MarshalIndent(call.Arguments, "", "  ")
+		req := confirm.Ask(ctx, messages.ConfirmToolCall, []string{"mcp/" + alias + "/" + name}, alias, name)
+		req.Detail = string(args)
+		approved, err := l.confirmer.Confirm(ctx, req)
+		if err != nil || !approved {
+			call.Declined = true
+			if err != nil {
+				call.Error = err.Error()
+			}
+			return call
+		}
+	}
+	start := time.Now()
+	result, err := session.CallTool(ctx, name, call.Arguments)
+	call.DurationMS = time.Since(start).Milliseconds()
+	if err != nil {
+		call.Error = err.Error()
+		return call
+	}
+	call.Result = result
+	return call
+}
+
+func toolMessage(call chatToolCall) string {
+	switch {
+	case call.Declined:
+		return "declined: the user did not approve this call"
+	case call.Error != "":
+		return "error: " + call.Error
+	case call.Result.IsError:
+		return "tool error: " + call.Result.Text()
+	}
+	if text := call.Result.Text(); text != "" {
+		return text
+	}
+	data, _ := json.Marshal(call.Result.StructuredContent)
+	return string(data)
+}
+
+func (l *chatToolLoop) prompt(transcript *chatTranscript) string {
+	var b strings.Builder
+	b.WriteString("You are an SRE assistant with tools. Answer with exactly one JSON object and nothing else:\n")
+	b.WriteString(`{"tool": "<server>.<tool>", "arguments": {...}} to call a tool, or {"answer": "..."} when you can answer.` + "\n")
+	b.WriteString("Call tools only when they help; a declined call will not run, so answer without it.\n\nTools:\n")
+	aliases := make([]string, 0, len(l.sessions))
+	for alias := range l.sessions {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	for _, alias := range aliases {
+		for _, tool := range l.sessions[alias].ToolList() {
+			fmt.Fprintf(&b, "- %s.%s: %s", alias, tool.Name, tool.Description)
+			if len(tool.InputSchema) > 0 {
+				schema, _ := json.Marshal(tool.InputSchema)
+				fmt.Fprintf(&b, " input schema: %s", schema)
+			}
+			b.WriteString("\n")
+		}
+	}
+	b.WriteString("\nConversation:\n")
+	for _, msg := range transcript.Messages {
+		switch msg.Role {
+		case "tool":
+			fmt.Fprintf(&b, "[tool result %s]\n%s\n", msg.ToolCallID, msg.Content)
+		default:
+			fmt.Fprintf(&b, "[%s]\n%s\n", msg.Role, msg.Content)
+		}
+	}
+	return b.String()
+}
+
+// openChatSessions starts the named MCP servers under the watchdog, closing any already
+// started on error.
+func openChatSessions(ctx context.Context, aliases []string) (map[string]*mcp.Session, error) {
+	sessions := map[string]*mcp.Session{}
+	for _, alias := range aliases {
+		alias = strings.TrimSpace(alias)
+		if alias == "" || sessions[alias] != nil {
+			continue
+		}
+		session, err := mcp.OpenSession(ctx, alias, nil)
+		if err != nil {
+			closeChatSessions(sessions)
+			return nil, err
+		}
+		session.Watch(ctx, mcp.WatchOptions{})
+		sessions[alias] = session
+	}
+	return sessions, nil
+}
+
+func closeChatSessions(sessions map[string]*mcp.Session) {
+	for _, session := range sessions {
+		session.Close()
+	}
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
     "bufio"
+    "context"
     "errors"
     "fmt"
     "io"
@@ -9,7 +10,9 @@ import (
     "os/exec"
     "path/filepath"
     "runtime"
+    "sort"
     "strings"
+    "time"
 
     "github.com/example/sre-ai/internal/config"
     "github.com/example/sre-ai/internal/credentials"
@@ -19,25 +22,26 @@ import (
 
 const geminiAPIKeyURL = "https://aistudio.google.com/app/apikey"
 
-func newConfigCmd() *cobra.Command {
+func newConfigCmd(opts *config.GlobalOptions) *cobra.Command {
     cmd := &cobra.Command{
         Use:   "config",
         Short: "Inspect or bootstrap CLI configuration",
     }
 
-    cmd.AddCommand(newConfigInitCmd())
-    cmd.AddCommand(newConfigShowCmd())
-    cmd.AddCommand(newConfigLoginCmd())
+    cmd.AddCommand(newConfigInitCmd(opts))
+    cmd.AddCommand(newConfigShowCmd(opts))
+    cmd.AddCommand(newConfigLoginCmd(opts))
+    cmd.AddCommand(newConfigMigrateCmd(opts))
     return cmd
 }
 
-func newConfigInitCmd() *cobra.Command {
+func newConfigInitCmd(opts *config.GlobalOptions) *cobra.Command {
     return &cobra.Command{
         Use:   "init",
         Short: "Create a starter configuration file",
         RunE: func(cmd *cobra.Command, args []string) error {
-            if globalOpts.DryRun {
-                path, err := resolveConfigPath()
+            if opts.DryRun {
+                path, err := resolveConfigPath(cmd.Context(), opts)
                 if err != nil {
                     return err
                 }
@@ -45,15 +49,15 @@ func newConfigInitCmd() *cobra.Command {
                     "path":   path,
                     "status": "dry-run",
                 }
-                return printOutput(cmd, payload, fmt.Sprintf("Dry-run: would create config at %s", path))
+                return printOutput(cmd, opts, payload, fmt.Sprintf("Dry-run: would create config at %s", path))
             }
 
-            cfgPath, err := resolveConfigPath()
+            cfgPath, err := resolveConfigPath(cmd.Context(), opts)
             if err != nil {
                 return err
             }
 
-            if err := os.MkdirAll(filepath.Dir(cfgPath), 0o755); err != nil {
+            if err := config.EnsureDir(filepath.Dir(cfgPath)); err != nil {
                 return err
             }
 
@@ -62,84 +66,72 @@ func newConfigInitCmd() *cobra.Command {
             }
 
             sample := defaultConfigYAML()
-            if err := os.WriteFile(cfgPath, []byte(sample), 0o644); err != nil {
+            if err := config.WriteFile(cfgPath, []byte(sample)); err != nil {
                 return err
             }
 
             payload := map[string]any{"path": cfgPath}
-            return printOutput(cmd, payload, fmt.Sprintf("Wrote config to %s\nRun 'sre-ai config login --provider gemini' to add credentials", cfgPath))
+            return printOutput(cmd, opts, payload, fmt.Sprintf("Wrote config to %s\nRun 'sre-ai config login --provider gemini' to add credentials, or 'sre-ai init' for guided setup", cfgPath))
         },
     }
 }
 
-func newConfigShowCmd() *cobra.Command {
-    return &cobra.Command{
-        Use:   "show",
-        Short: "Print effective configuration",
-        RunE: func(cmd *cobra.Command, args []string) error {
-            payload := map[string]any{
-                "model":       globalOpts.Model,
-                "provider":    globalOpts.Provider,
-                "session":     globalOpts.Session,
-                "caps":        globalOpts.Caps,
-                "mcp_servers": globalOpts.MCPServers,
-                "dry_run":     globalOpts.DryRun,
-            }
-            human := fmt.Sprintf("Model=%s Provider=%s", globalOpts.Model, globalOpts.Provider)
-            return printOutput(cmd, payload, human)
-        },
-    }
-}
-
-func newConfigLoginCmd() *cobra.Command {
+func newConfigLoginCmd(opts *config.GlobalOptions) *cobra.Command {
     var provider string
     var noBrowser bool
 
     cmd := &cobra.Command{
         Use:   "login",
-        Short: "Authenticate with an AI provider",
+        Short: "Authenticate with an AI provider or store a service token",
+        Long: `Authenticate with an AI provider or store a service token.
+
+--provider gemini stores the Gemini API key. Any other name (e.g. github) stores a
+token that MCP server definitions reference as an env value, e.g.
+GITHUB_TOKEN: credential:github. Logging in again replaces the token, so every
+server using it picks up the new value on its next launch.`,
         RunE: func(cmd *cobra.Command, args []string) error {
-            if globalOpts.NoInteractive {
+            if opts.NoInteractive {
                 return errors.New("login requires interactive mode; rerun without --no-interactive")
             }
 
             switch strings.ToLower(provider) {
             case "gemini":
-                return runGeminiLogin(cmd, !noBrowser)
+                return runGeminiLogin(cmd, opts, !noBrowser)
             default:
-                return fmt.Errorf("unsupported provider %s", provider)
+                return runTokenLogin(cmd, opts, strings.ToLower(provider))
             }
         },
     }
 
-    cmd.Flags().StringVar(&provider, "provider", "gemini", "AI provider to authenticate (gemini)")
+    cmd.Flags().StringVar(&provider, "provider", "gemini", "Provider to authenticate (gemini), or a credential name such as github")
     cmd.Flags().BoolVar(&noBrowser, "no-browser", false, "Do not attempt to launch a browser automatically")
 
     return cmd
 }
 
-func runGeminiLogin(cmd *cobra.Command, launchBrowser bool) error {
-    targetPath, err := credentials.GeminiKeyPath()
+func runGeminiLogin(cmd *cobra.Command, opts *config.GlobalOptions, launchBrowser bool) error {
+    ctx := cmd.Context()
+    targetPath, err := credentials.GeminiKeyPath(ctx)
     if err != nil {
         return err
     }
 
     fmt.Fprintf(cmd.OutOrStdout(), "Open Gemini API key page to create or view a key:\n  %s\n", geminiAPIKeyURL)
-    if launchBrowser && !globalOpts.DryRun {
+    if launchBrowser && !opts.DryRun {
         if err := openBrowser(geminiAPIKeyURL); err != nil {
-            if globalOpts.Verbose > 0 && !globalOpts.Quiet {
+            if opts.Verbose > 0 && !opts.Quiet {
                 fmt.Fprintf(cmd.ErrOrStderr(), "warning: unable to launch browser: %v\n", err)
             }
         }
     }
 
-    if globalOpts.DryRun {
+    if opts.DryRun {
         payload := map[string]any{
             "provider":        "gemini",
             "credential_file": targetPath,
             "status":          "dry-run",
         }
-        return printOutput(cmd, payload, fmt.Sprintf("Dry-run: would store Gemini API key at %s", targetPath))
+        return printOutput(cmd, opts, payload, fmt.Sprintf("Dry-run: would store Gemini API key at %s", targetPath))
     }
 
     key, err := promptForAPIKey(cmd, "Paste your Gemini API key: ")
@@ -150,7 +142,7 @@ func runGeminiLogin(cmd *cobra.Command, launchBrowser bool) error {
         return errors.New("no API key provided")
     }
 
-    savedPath, err := credentials.SaveGeminiKey(key)
+    savedPath, err := credentials.SaveGeminiKey(ctx, key)
     if err != nil {
         return err
     }
@@ -159,7 +151,45 @@ func runGeminiLogin(cmd *cobra.Command, launchBrowser bool) error {
         "provider":        "gemini",
         "credential_file": savedPath,
     }
-    return printOutput(cmd, payload, fmt.Sprintf("Gemini API key stored at %s", savedPath))
+    return printOutput(cmd, opts, payload, fmt.Sprintf("Gemini API key stored at %s", savedPath))
+}
+
+// runTokenLogin stores a token under name for credential:<name> references.
+func runTokenLogin(cmd *cobra.Command, opts *config.GlobalOptions, name string) error {
+    ctx := cmd.Context()
+    targetPath, err := credentials.TokenPath(ctx, name)
+    if err != nil {
+        return err
+    }
+
+    if opts.DryRun {
+        payload := map[string]any{
+            "provider":        name,
+            "credential_file": targetPath,
+            "status":          "dry-run",
+        }
+        return printOutput(cmd, opts, payload, fmt.Sprintf("Dry-run: would store %s token at %s", name, targetPath))
+    }
+
+    token, err := promptForAPIKey(cmd, fmt.Sprintf("Paste the %s token: ", name))
+    if err != nil {
+        return err
+    }
+    if token == "" {
+        return errors.New("no token provided")
+    }
+
+    savedPath, err := credentials.SaveToken(ctx, name, token)
+    if err != nil {
+        return err
+    }
+
+    payload := map[string]any{
+        "provider":        name,
+        "credential_file": savedPath,
+        "reference":       credentials.RefPrefix + name,
+    }
+    return printOutput(cmd, opts, payload, fmt.Sprintf("%s token stored at %s; reference it in MCP server env as %s%s", name, savedPath, credentials.RefPrefix, name))
 }
 
 func promptForAPIKey(cmd *cobra.Command, prompt string) (string, error) {
@@ -185,35 +215,127 @@ func openBrowser(url string) error {
     return command.Start()
 }
 
-func resolveConfigPath() (string, error) {
-    if globalOpts.ConfigPath != "" {
-        return globalOpts.ConfigPath, nil
+func newConfigMigrateCmd(opts *config.GlobalOptions) *cobra.Command {
+    return &cobra.Command{
+        Use:   "migrate",
+        Short: "Upgrade the config file to the current layout",
+        Long: fmt.Sprintf(`Upgrade an older config file to config_version %d, keeping its comments.
+
+Older layouts are rewritten: a list of mcp.servers ("alias=path" or {alias, path})
+becomes an alias: path map, and a single-provider auth block (auth.provider) moves
+under auth.<provider>. The original is kept next to it as config.yaml.bak-<time>.
+--dry-run prints the changes without writing anything.`, config.CurrentVersion),
+        Args: cobra.NoArgs,
+        // The file may not load until it is migrated, so skip the root's config.Load.
+        PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return nil },
+        RunE: func(cmd *cobra.Command, args []string) error {
+            path, err := resolveConfigPath(cmd.Context(), opts)
+            if err != nil {
+                return err
+            }
+            result, err := config.Migrate(path, opts.DryRun, time.Now())
+            if err != nil {
+                return err
+            }
+            if len(result.Changes) == 0 {
+                return printOutput(cmd, opts, result, fmt.Sprintf("%s is already at config_version %d", path, result.ToVersion))
+            }
+            verb := "Migrated"
+            if result.DryRun {
+                verb = "Dry-run: would migrate"
+            }
+            lines := []string{fmt.Sprintf("%s %s from config_version %d to %d:", verb, path, result.FromVersion, result.ToVersion)}
+            for _, change := range result.Changes {
+                lines = append(lines, "  - "+change)
+            }
+            if result.Backup != "" {
+                lines = append(lines, "Original saved to "+result.Backup)
+            }
+            return printOutput(cmd, opts, result, strings.Join(lines, "\n"))
+        },
+    }
+}
+
+func resolveConfigPath(ctx context.Context, opts *config.GlobalOptions) (string, error) {
+    if opts.ConfigPath != "" {
+        return opts.ConfigPath, nil
     }
-    return config.DefaultConfigPath()
+    return config.DefaultConfigPath(ctx)
 }
 
 func defaultConfigYAML() string {
-    return fmt.Sprintf(`model: %s
-provider: gemini
-default_caps: [read_files]
-mcp:
-  servers:
-    github: ~/.config/sre-ai/mcp/github.json
-    files: ~/.config/sre-ai/mcp/files.json
-contexts:
-  k8s:
-    kubecontext: prod-us
-    namespace: default
-iac:
-  engine: terraform
-  stacks:
-    prod:
-      path: ./infra/prod
-auth:
-  gemini:
-    credential_file: ~/.config/sre-ai/credentials/gemini.json
-logging:
-  level: info
-  redact: true
-`, providers.DefaultGeminiModel())
+    return renderConfigYAML(defaultSetup())
+}
+
+// configSetup captures the choices that shape a generated config file.
+type configSetup struct {
+    Model       string
+    Provider    string
+    Kubecontext string
+    Namespace   string
+    IaCEngine   string
+    IaCStacks   map[string]string
+    MCPServers  map[string]string
+}
+
+func defaultSetup() configSetup {
+    return configSetup{
+        Model:       providers.DefaultGeminiModel(),
+        Provider:    "gemini",
+        Kubecontext: "prod-us",
+        Namespace:   "default",
+        IaCEngine:   "terraform",
+        IaCStacks:   map[string]string{"prod": "./infra/prod"},
+        MCPServers: map[string]string{
+            "github": "~/.config/sre-ai/mcp/github.json",
+            "files":  "~/.config/sre-ai/mcp/files.json",
+        },
+    }
+}
+
+func renderConfigYAML(setup configSetup) string {
+    var b strings.Builder
+    fmt.Fprintf(&b, "config_version: %d\n", config.CurrentVersion)
+    fmt.Fprintf(&b, "model: %s\n", setup.Model)
+    fmt.Fprintf(&b, "provider: %s\n", setup.Provider)
+    b.WriteString("default_caps: [read_files]\n")
+    if len(setup.MCPServers) > 0 {
+        b.WriteString("mcp:\n  servers:\n")
+        for _, alias := range sortedKeys(setup.MCPServers) {
+            fmt.Fprintf(&b, "    %s: %s\n", alias, setup.MCPServers[alias])
+        }
+    }
+    if setup.Kubecontext != "" {
+        b.WriteString("contexts:\n  k8s:\n")
+        fmt.Fprintf(&b, "    kubecontext: %s\n", setup.Kubecontext)
+        namespace := setup.Namespace
+        if namespace == "" {
+            namespace = "default"
+        }
+        fmt.Fprintf(&b, "    namespace: %s\n", namespace)
+    }
+    if setup.IaCEngine != "" {
+        b.WriteString("iac:\n")
+        fmt.Fprintf(&b, "  engine: %s\n", setup.IaCEngine)
+        if len(setup.IaCStacks) > 0 {
+            b.WriteString("  stacks:\n")
+            for _, name := range sortedKeys(setup.IaCStacks) {
+                fmt.Fprintf(&b, "    %s:\n      path: %s\n", name, setup.IaCStacks[name])
+            }
+        }
+    }
+    if setup.Provider == "gemini" {
+        b.WriteString("auth:\n  gemini:\n    credential_file: ~/.config/sre-ai/credentials/gemini.json\n")
+    }
+    b.WriteString("logging:\n  level: info\n  redact: true\n")
+    return b.String()
+}
+
+func sortedKeys(values map[string]string) []string {
+    keys := make([]string, 0, len(values))
+    for k := range values {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+    return keys
 }
@@ -0,0 +1,266 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/providers"
+	"github.com/example/sre-ai/internal/state"
+	"github.com/example/sre-ai/internal/templatex"
+	"github.com/spf13/cobra"
+)
+
+// configSetting is one effective setting as config show reports it. Values are rendered
+// the way the config file writes them; "" means unset.
+type configSetting struct {
+	Key     string        `json:"key"`
+	Value   string        `json:"value"`
+	Default string        `json:"default"`
+	Source  config.Source `json:"source"`
+	Origin  string        `json:"origin,omitempty"`
+}
+
+func newConfigShowCmd(opts *config.GlobalOptions) *cobra.Command {
+	var diff bool
+
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Print effective configuration and where each setting came from",
+		Long: `Print every effective setting with its source. In order of precedence a setting
+comes from a flag, an SRE_AI_* environment variable (SRE_AI_MODEL,
+SRE_AI_TIME_ZONE, ...), the project config (` + config.ProjectConfigName + ` in the working
+directory or a parent, up to the repository root), the global config file, or the
+built-in default.
+
+--diff lists only the settings that differ from their defaults.`,
+		Example: `  sre-ai config show
+  sre-ai config show --diff --provider ollama`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			globalPath, err := resolveConfigPath(cmd.Context(), opts)
+			if err != nil {
+				return err
+			}
+			settings := effectiveSettings(opts)
+			if diff {
+				changed := settings[:0]
+				for _, s := range settings {
+					if s.Value != s.Default {
+						changed = append(changed, s)
+					}
+				}
+				settings = changed
+			}
+			_, statErr := os.Stat(globalPath)
+			payload := map[string]any{
+				"model":       opts.Model,
+				"provider":    opts.Provider,
+				"session":     opts.Session,
+				"caps":        opts.Caps,
+				"mcp_servers": opts.MCPServers,
+				"dry_run":     opts.DryRun,
+				"files": map[string]any{
+					"global":        globalPath,
+					"global_exists": statErr == nil,
+					"project":       opts.ProjectConfig,
+				},
+				"settings": settings,
+			}
+			return printOutput(cmd, opts, payload, formatConfigSettings(globalPath, statErr == nil, opts.ProjectConfig, settings, diff))
+		},
+	}
+
+	cmd.Flags().BoolVar(&diff, "diff", false, "Only list settings that differ from the defaults, next to the default")
+
+	return cmd
+}
+
+// effectiveSettings lists the settings config.Load knows, in config file order, followed
+// by the MCP servers, endpoints, hosts, uploads, runtimes, and provider limits that are configured.
+func effectiveSettings(opts *config.GlobalOptions) []configSetting {
+	defaults := DefaultOptions()
+	var settings []configSetting
+	add := func(key, value, def string) {
+		src := opts.SourceOf(key)
+		settings = append(settings, configSetting{Key: key, Value: value, Default: def, Source: src.Source, Origin: src.Origin})
+	}
+
+	add("model", opts.Model, defaults.Model)
+	add("provider", opts.Provider, defaults.Provider)
+	add("temperature", strconv.FormatFloat(opts.Temperature, 'g', -1, 64), strconv.FormatFloat(defaults.Temperature, 'g', -1, 64))
+	add("max_tokens", intSetting(opts.MaxTokens), "")
+	add("session", opts.Session, "")
+	add("default_caps", strings.Join(opts.Caps, ","), "")
+	add("contexts.k8s.kubecontext", opts.Kube.Context, "")
+	add("contexts.k8s.namespace", opts.Kube.Namespace, "")
+	add("time.zone", opts.Time.Zone, "")
+	add("time.layout", opts.Time.Layout, "")
+	add("time.relative", boolSetting(opts.Time.Relative, true), "true")
+	add("locale", opts.Locale, "")
+	add("principal", opts.Principal, "")
+	add("confirm.via", opts.Confirm.Via, "")
+	add("confirm.timeout", durationSetting(opts.Confirm.Timeout), "")
+	add("confirm.slack.channel", opts.Confirm.SlackChannel, "")
+	add("confirm.slack.token_env", opts.Confirm.SlackTokenEnv, "")
+	add("confirm.web.listen", opts.Confirm.WebListen, defaultWebListen)
+	add("confirm.web.base_url", opts.Confirm.WebBaseURL, "")
+	add("retention.auto", boolSetting(opts.Retention.Auto, true), "true")
+	if policies, err := state.Policies(opts.Retention.Limits); err == nil {
+		for _, kind := range state.PruneKinds {
+			add("retention."+kind, policySetting(policies[kind]), policySetting(state.DefaultPolicies[kind]))
+		}
+	}
+	if limits, err := templatex.LimitsFor(opts); err == nil {
+		def := templatex.Defaults
+		add("templates.max_output", sizeSetting(limits.MaxOutput), sizeSetting(def.MaxOutput))
+		add("templates.max_range", strconv.Itoa(limits.MaxRange), strconv.Itoa(def.MaxRange))
+		add("templates.timeout", limits.Timeout.String(), def.Timeout.String())
+	}
+
+	limits := map[string]bool{}
+	for _, name := range providers.DefaultMaxInFlightProviders() {
+		limits[name] = true
+	}
+	for name := range opts.MaxInFlight {
+		limits[name] = true
+	}
+	for _, name := range sortedSet(limits) {
+		value := providers.DefaultMaxInFlight(name)
+		if n, ok := opts.MaxInFlight[name]; ok && n > 0 {
+			value = n
+		}
+		add("providers.max_in_flight."+name, strconv.Itoa(value), strconv.Itoa(providers.DefaultMaxInFlight(name)))
+	}
+	for _, alias := range sortedKeys(opts.MCPServers) {
+		add("mcp.servers."+alias, opts.MCPServers[alias], "")
+	}
+	endpoints := make([]string, 0, len(opts.Endpoints))
+	for name := range opts.Endpoints {
+		endpoints = append(endpoints, name)
+	}
+	sort.Strings(endpoints)
+	for _, name := range endpoints {
+		add("endpoints."+name, strings.Join(opts.Endpoints[name].Match, ","), "")
+	}
+	groups := make([]string, 0, len(opts.Hosts))
+	for name := range opts.Hosts {
+		groups = append(groups, name)
+	}
+	sort.Strings(groups)
+	for _, name := range groups {
+		add("hosts."+name, strings.Join(opts.Hosts[name].Addresses, ","), "")
+	}
+	uploads := make([]string, 0, len(opts.Uploads))
+	for name := range opts.Uploads {
+		uploads = append(uploads, name)
+	}
+	sort.Strings(uploads)
+	for _, name := range uploads {
+		add("upload."+name, opts.Uploads[name].URL, "")
+	}
+	for _, name := range sortedKeys(opts.Runtimes) {
+		add("runtimes."+name, opts.Runtimes[name], "")
+	}
+	return settings
+}
+
+func formatConfigSettings(globalPath string, globalExists bool, projectPath string, settings []configSetting, diff bool) string {
+	var buf strings.Builder
+	global := globalPath
+	if !globalExists {
+		global += " (missing)"
+	}
+	fmt.Fprintf(&buf, "Global config:  %s\n", global)
+	if projectPath == "" {
+		projectPath = "-"
+	}
+	fmt.Fprintf(&buf, "Project config: %s\n\n", projectPath)
+	if len(settings) == 0 {
+		buf.WriteString("Every setting is at its default")
+		return buf.String()
+	}
+	tw := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	if diff {
+		fmt.Fprintln(tw, "KEY\tDEFAULT\tVALUE\tSOURCE")
+	} else {
+		fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	}
+	for _, s := range settings {
+		source := string(s.Source)
+		if s.Origin != "" {
+			source += " (" + s.Origin + ")"
+		}
+		if diff {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", s.Key, settingText(s.Default), settingText(s.Value), source)
+		} else {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Key, settingText(s.Value), source)
+		}
+	}
+	tw.Flush()
+	return strings.TrimRight(buf.String(), "\n")
+}
+
+func settingText(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return truncateLine(value, 60)
+}
+
+func intSetting(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+func boolSetting(b *bool, def bool) string {
+	if b == nil {
+		return strconv.FormatBool(def)
+	}
+	return strconv.FormatBool(*b)
+}
+
+func durationSetting(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// sizeSetting renders bytes as the config file writes them, e.g. 1.5GiB.
+func sizeSetting(n int64) string {
+	return strings.ReplaceAll(state.FormatSize(n), " ", "")
+}
+
+// policySetting renders a retention policy as the config file writes it, e.g.
+// "max_age=90d max_size=1GiB".
+func policySetting(p state.Policy) string {
+	var parts []string
+	if p.MaxAge > 0 {
+		age := p.MaxAge.String()
+		if p.MaxAge%(24*time.Hour) == 0 {
+			age = fmt.Sprintf("%dd", p.MaxAge/(24*time.Hour))
+		}
+		parts = append(parts, "max_age="+age)
+	}
+	if p.MaxSize > 0 {
+		parts = append(parts, "max_size="+sizeSetting(p.MaxSize))
+	}
+	return strings.Join(parts, " ")
+}
+
+func sortedSet(values map[string]bool) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,56 +1,164 @@
 package cmd
 
 import (
+    "errors"
     "fmt"
+    "regexp"
+    "sort"
     "strings"
+    "time"
 
+    "github.com/example/sre-ai/internal/config"
+    "github.com/example/sre-ai/internal/confirm"
+    "github.com/example/sre-ai/internal/gitlog"
+    "github.com/example/sre-ai/internal/incidents"
+    "github.com/example/sre-ai/internal/k8s"
+    "github.com/example/sre-ai/internal/messages"
+    "github.com/example/sre-ai/internal/notify"
+    "github.com/example/sre-ai/internal/promql"
+    "github.com/example/sre-ai/internal/providers"
+    "github.com/example/sre-ai/internal/rules"
+    "github.com/example/sre-ai/internal/runs"
+    "github.com/example/sre-ai/internal/sparkline"
+    "github.com/example/sre-ai/internal/timefmt"
+    "github.com/example/sre-ai/internal/timeparse"
+    "github.com/example/sre-ai/internal/warnings"
     "github.com/spf13/cobra"
 )
 
+// Targets and Rollup are set when diagnose collected live evidence per namespace, Node
+// when it diagnosed a single node, and Rules when known failure signatures matched that
+// evidence. Similar and RunID are set once a diagnosis completes.
 type planResult struct {
-    Summary  string           `json:"summary"`
-    Findings []string         `json:"findings"`
-    Actions  []map[string]any `json:"actions"`
-    Evidence []map[string]any `json:"evidence"`
+    Summary  string                `json:"summary"`
+    Findings []string              `json:"findings"`
+    Actions  []map[string]any      `json:"actions"`
+    Evidence []map[string]any      `json:"evidence"`
+    Targets  []k8s.NamespaceReport `json:"targets,omitempty"`
+    Rollup   *k8s.Rollup           `json:"rollup,omitempty"`
+    Node     *k8s.NodeReport       `json:"node,omitempty"`
+    Rules    []rules.Hit           `json:"rules,omitempty"`
+    Similar  []incidents.Match     `json:"similar_incidents,omitempty"`
+    RunID    string                `json:"run_id,omitempty"`
+
+    // Notifications is where the diagnosis was sent by the notify routes.
+    Notifications []notify.Delivery `json:"notifications,omitempty"`
+}
+
+// diagnoseFlags are shared by every diagnose subcommand via persistent flags.
+type diagnoseFlags struct {
+    withWorkspace bool
+    changePaths   []string
+    repo          string
+    similar       int
+    similarModel  string
+    knowledgeDir  string
+    metrics       []string
+    prometheus    string
 }
 
-func newDiagnoseCmd() *cobra.Command {
+func newDiagnoseCmd(opts *config.GlobalOptions) *cobra.Command {
     cmd := &cobra.Command{
         Use:   "diagnose",
         Short: "Diagnose reliability issues across systems",
+        Long: `Diagnose reliability issues across systems.
+
+--metric name=query adds a PromQL range query over the --since window as metrics
+evidence, read from --prometheus or $PROMETHEUS_URL. The human output draws each
+series as a sparkline with its min, max, and last value; --json carries the points.`,
+        Example: `  sre-ai diagnose k8s --namespace checkout --metric 'errors=sum(rate(http_requests_total{code=~"5.."}[5m])) by (pod)'`,
     }
+    shared := &diagnoseFlags{}
+    addWorkspaceFlag(cmd.PersistentFlags(), &shared.withWorkspace)
+    cmd.PersistentFlags().StringSliceVar(&shared.changePaths, "changes-path", nil, "Service paths whose recent git commits are added as evidence")
+    cmd.PersistentFlags().StringVar(&shared.repo, "repo", ".", "Git repository used for --changes-path")
+    cmd.PersistentFlags().IntVar(&shared.similar, "similar", 3, "Similar past incidents to include (0 disables)")
+    cmd.PersistentFlags().StringVar(&shared.similarModel, "similar-model", "local", "Embedding provider[/model] for --similar: local, ollama, or gemini")
+    cmd.PersistentFlags().StringVar(&shared.knowledgeDir, "knowledge", "", "Directory of incident notes to search (default <config dir>/knowledge)")
+    cmd.PersistentFlags().StringArrayVar(&shared.metrics, "metric", nil, "PromQL range query added as metrics evidence, as name=query (repeatable)")
+    cmd.PersistentFlags().StringVar(&shared.prometheus, "prometheus", "", "Prometheus address for --metric (default $"+promql.URLEnv+")")
 
-    cmd.AddCommand(newDiagnoseK8sCmd())
-    cmd.AddCommand(newDiagnoseCiCmd())
-    cmd.AddCommand(newDiagnoseHostCmd())
+    cmd.AddCommand(newDiagnoseK8sCmd(opts, shared))
+    cmd.AddCommand(newDiagnoseCiCmd(opts, shared))
+    cmd.AddCommand(newDiagnoseHostCmd(opts, shared))
 
     return cmd
 }
 
-func newDiagnoseK8sCmd() *cobra.Command {
+func newDiagnoseK8sCmd(opts *config.GlobalOptions, shared *diagnoseFlags) *cobra.Command {
     var (
         kubecontext string
-        namespace   string
+        namespaces  []string
+        selector    string
+        node        string
+        sshFallback bool
+        sshUser     string
         since       string
         include     []string
         planOnly    bool
+        preflight   bool
     )
 
     cmd := &cobra.Command{
         Use:   "k8s",
         Short: "Diagnose Kubernetes workloads",
+        Long: `Diagnose Kubernetes workloads by reading pods and warning events with kubectl.
+
+Several namespaces (--namespace a,b,c or --selector team=payments) are collected
+concurrently; the result has one section per namespace under "targets" plus a
+cross-namespace "rollup". --node diagnoses one node instead: conditions, node
+events, kubelet logs (node logs API, or ssh + journalctl as a fallback), and the
+pods scheduled there. --plan skips collection.
+
+Before collecting, the permissions the diagnosis needs are checked with
+SelfSubjectAccessReviews. Missing ones are reported up front together with the
+RBAC rules that grant them; --preflight=false skips the check.
+
+Completed diagnoses are recorded in run history. Their findings are compared with
+past diagnoses and the notes in the knowledge directory, and the closest matches
+are listed as similar past incidents together with what fixed them; record a fix
+with 'sre-ai runs resolve <id> <note>'.`,
         RunE: func(cmd *cobra.Command, args []string) error {
+            window, err := parseSince(since)
+            if err != nil {
+                return err
+            }
+            if !cmd.Flags().Changed("kubecontext") {
+                kubecontext = opts.Kube.Context
+            }
+            if !cmd.Flags().Changed("namespace") && opts.Kube.Namespace != "" {
+                namespaces = []string{opts.Kube.Namespace}
+            }
+            client := k8s.Client{Context: kubecontext}
+            if preflight && selector != "" && !planOnly {
+                if err := preflightK8s(cmd, client, k8s.DiagnosisPermissions(nil, selector, "")); err != nil {
+                    return err
+                }
+            }
+            if selector != "" && !planOnly {
+                matched, err := client.Namespaces(cmd.Context(), selector)
+                if err != nil {
+                    return fmt.Errorf("resolve --selector %s: %w", selector, err)
+                }
+                if len(matched) == 0 {
+                    return fmt.Errorf("no namespaces match selector %s", selector)
+                }
+                namespaces = matched
+            }
+
+            if len(namespaces) == 0 {
+                return errors.New("at least one --namespace or a --selector is required")
+            }
+            scope := strings.Join(namespaces, ", ")
+            if selector != "" && planOnly {
+                scope = fmt.Sprintf("namespaces matching %s", selector)
+            }
             result := planResult{
-                Summary: fmt.Sprintf("Evaluated namespace %s in context %s", namespace, kubecontext),
+                Summary: fmt.Sprintf("Evaluated namespace %s in context %s", scope, kubecontext),
                 Findings: []string{
                     "Pending pods detected",
                 },
-                Actions: []map[string]any{
-                    {
-                        "intent":  "Inspect rollout",
-                        "command": fmt.Sprintf("kubectl --context %s -n %s get deploy", kubecontext, namespace),
-                    },
-                },
+                Actions: []map[string]any{},
                 Evidence: []map[string]any{
                     {
                         "type":  "logs",
@@ -58,17 +166,85 @@ func newDiagnoseK8sCmd() *cobra.Command {
                     },
                 },
             }
+            // --node focuses on one node; namespaces are still collected when asked for explicitly.
+            withNamespaces := node == "" || cmd.Flags().Changed("namespace") || selector != ""
+            if node != "" {
+                result.Summary = fmt.Sprintf("Evaluated node %s in context %s", node, kubecontext)
+                result.Findings = []string{"Node health unknown until collected"}
+                result.Actions = append(result.Actions, map[string]any{
+                    "intent":  fmt.Sprintf("Describe node %s", node),
+                    "command": fmt.Sprintf("kubectl --context %s describe node %s", kubecontext, node),
+                })
+            }
+            if withNamespaces {
+                for _, ns := range namespaces {
+                    result.Actions = append(result.Actions, map[string]any{
+                        "intent":  fmt.Sprintf("Inspect rollout in %s", ns),
+                        "command": fmt.Sprintf("kubectl --context %s -n %s get deploy", kubecontext, ns),
+                    })
+                }
+                if len(namespaces) == 1 && node == "" {
+                    result.Actions[0]["intent"] = "Inspect rollout"
+                }
+            }
+
+            if !planOnly {
+                if preflight {
+                    var checked []string
+                    if withNamespaces {
+                        checked = namespaces
+                    }
+                    if err := preflightK8s(cmd, client, k8s.DiagnosisPermissions(checked, "", node)); err != nil {
+                        return err
+                    }
+                }
+                result.Findings = nil
+                if node != "" {
+                    report := client.CollectNode(cmd.Context(), node, k8s.NodeOptions{Since: window, SSHFallback: sshFallback, SSHUser: sshUser})
+                    result.Node = &report
+                    for _, collectErr := range report.Errors {
+                        warnings.Add(cmd.Context(), "k8s", "node %s: %s", node, collectErr)
+                    }
+                    for _, finding := range report.Findings {
+                        result.Findings = append(result.Findings, fmt.Sprintf("node %s: %s", node, finding))
+                    }
+                }
+                if withNamespaces {
+                    collectK8sEvidence(cmd, client, &result, namespaces, window)
+                }
+                applyRules(cmd, &result)
+            }
+
+            if err := addChangeEvidence(cmd, shared, &result, since); err != nil {
+                return err
+            }
+            if err := addMetricEvidence(cmd, shared, &result, since); err != nil {
+                return err
+            }
+            if !planOnly {
+                addSimilarIncidents(cmd, shared, &result)
+                recordDiagnosis(cmd, opts, "k8s", kubecontext, &result)
+                service := scope
+                if !withNamespaces {
+                    service = "node/" + node
+                }
+                notifyDiagnosis(cmd, opts, service, kubecontext, &result)
+            }
 
-            if err := printOutput(cmd, result, renderPlan("Kubernetes", include, result)); err != nil {
+            if err := addWorkspaceEvidence(shared, &result); err != nil {
                 return err
             }
 
-            if planOnly || globalOpts.DryRun {
+            if err := printOutput(cmd, opts, result, renderPlan("Kubernetes", include, result)); err != nil {
+                return err
+            }
+
+            if planOnly || opts.DryRun {
                 return nil
             }
 
-            if !globalOpts.AutoConfirm && !globalOpts.NoInteractive {
-                confirmed, err := promptForConfirmation(cmd, "Execute proposed kubectl diagnostics?")
+            if !opts.AutoConfirm && canConfirm(cmd, opts) {
+                confirmed, err := promptForConfirmation(cmd, opts, confirm.Ask(cmd.Context(), messages.ConfirmKubectl, kubectlCommands(result.Actions)))
                 if err != nil {
                     return err
                 }
@@ -77,20 +253,44 @@ func newDiagnoseK8sCmd() *cobra.Command {
                 }
             }
 
-            return runKubectlDryRun(cmd, result.Actions)
+            return runKubectlDryRun(cmd, opts, result.Actions)
         },
     }
 
-    cmd.Flags().StringVar(&kubecontext, "kubecontext", "", "Kubeconfig context to target")
-    cmd.Flags().StringVar(&namespace, "namespace", "default", "Kubernetes namespace")
-    cmd.Flags().StringVar(&since, "since", "1h", "Time window to inspect")
+    cmd.Flags().StringVar(&kubecontext, "kubecontext", "", "Kubeconfig context to target (default: contexts.k8s.kubecontext from config, else the current context)")
+    cmd.Flags().StringSliceVar(&namespaces, "namespace", []string{"default"}, "Kubernetes namespace(s), comma separated; collected concurrently")
+    cmd.Flags().StringVar(&selector, "selector", "", "Diagnose every namespace matching this label selector (e.g. team=payments)")
+    cmd.Flags().StringVar(&node, "node", "", "Diagnose a node: conditions, node events, kubelet logs, and its pods")
+    cmd.Flags().BoolVar(&sshFallback, "ssh-fallback", true, "Read kubelet logs over ssh (journalctl) when the node logs API is unavailable")
+    cmd.Flags().StringVar(&sshUser, "ssh-user", "", "User for the --ssh-fallback connection")
+    cmd.Flags().StringVar(&since, "since", "1h", "Time window to inspect: 90m, 2h30m, 1d, a timestamp, or 'yesterday 14:00'")
     cmd.Flags().StringSliceVar(&include, "include", []string{"pods", "events"}, "Resources to include")
     cmd.Flags().BoolVar(&planOnly, "plan", false, "Only produce a plan without execution")
+    cmd.Flags().BoolVar(&preflight, "preflight", true, "Check RBAC permissions before collecting and report missing ones up front")
 
     return cmd
 }
 
-func newDiagnoseCiCmd() *cobra.Command {
+// preflightK8s checks that the credentials can read what the diagnosis collects. A
+// missing required permission fails with the RBAC rules that grant it; a missing
+// optional one, or a review that cannot run at all, is only a warning.
+func preflightK8s(cmd *cobra.Command, client k8s.Client, perms []k8s.Permission) error {
+    degraded, err := client.Preflight(cmd.Context(), perms)
+    var accessErr *k8s.AccessError
+    if errors.As(err, &accessErr) {
+        return err
+    }
+    if err != nil {
+        warnings.Add(cmd.Context(), "k8s", "permissions not checked: %v", err)
+        return nil
+    }
+    for _, check := range degraded {
+        warnings.Add(cmd.Context(), "k8s", "no permission to %s (%s); collection falls back or skips it", check.Permission, check.Need)
+    }
+    return nil
+}
+
+func newDiagnoseCiCmd(opts *config.GlobalOptions, shared *diagnoseFlags) *cobra.Command {
     var (
         provider string
         runID    string
@@ -102,6 +302,9 @@ func newDiagnoseCiCmd() *cobra.Command {
         Use:   "ci",
         Short: "Diagnose CI pipelines",
         RunE: func(cmd *cobra.Command, args []string) error {
+            if _, err := parseSince(since); err != nil {
+                return err
+            }
             result := planResult{
                 Summary: fmt.Sprintf("Analyzed CI run %s on %s", runID, provider),
                 Findings: []string{"Workflow failure detected"},
@@ -113,7 +316,17 @@ func newDiagnoseCiCmd() *cobra.Command {
                 },
             }
 
-            if err := printOutput(cmd, result, renderPlan("CI", nil, result)); err != nil {
+            if err := addWorkspaceEvidence(shared, &result); err != nil {
+                return err
+            }
+            if err := addChangeEvidence(cmd, shared, &result, since); err != nil {
+                return err
+            }
+            if err := addMetricEvidence(cmd, shared, &result, since); err != nil {
+                return err
+            }
+
+            if err := printOutput(cmd, opts, result, renderPlan("CI", nil, result)); err != nil {
                 return err
             }
 
@@ -123,14 +336,14 @@ func newDiagnoseCiCmd() *cobra.Command {
 
     cmd.Flags().StringVar(&provider, "provider", "github", "CI provider")
     cmd.Flags().StringVar(&runID, "run-id", "", "Pipeline run identifier")
-    cmd.Flags().StringVar(&since, "since", "1h", "Time window to inspect")
+    cmd.Flags().StringVar(&since, "since", "1h", "Time window to inspect: 90m, 2h30m, 1d, a timestamp, or 'yesterday 14:00'")
     cmd.Flags().BoolVar(&planOnly, "plan", false, "Only produce a plan without execution")
 
     _ = planOnly
     return cmd
 }
 
-func newDiagnoseHostCmd() *cobra.Command {
+func newDiagnoseHostCmd(opts *config.GlobalOptions, shared *diagnoseFlags) *cobra.Command {
     var (
         target   string
         since    string
@@ -142,6 +355,9 @@ func newDiagnoseHostCmd() *cobra.Command {
         Use:   "host",
         Short: "Diagnose individual hosts",
         RunE: func(cmd *cobra.Command, args []string) error {
+            if _, err := parseSince(since); err != nil {
+                return err
+            }
             result := planResult{
                 Summary: fmt.Sprintf("Inspected host %s", target),
                 Findings: []string{"High load detected"},
@@ -153,7 +369,17 @@ func newDiagnoseHostCmd() *cobra.Command {
                 },
             }
 
-            if err := printOutput(cmd, result, renderPlan("Host", collect, result)); err != nil {
+            if err := addWorkspaceEvidence(shared, &result); err != nil {
+                return err
+            }
+            if err := addChangeEvidence(cmd, shared, &result, since); err != nil {
+                return err
+            }
+            if err := addMetricEvidence(cmd, shared, &result, since); err != nil {
+                return err
+            }
+
+            if err := printOutput(cmd, opts, result, renderPlan("Host", collect, result)); err != nil {
                 return err
             }
 
@@ -162,7 +388,7 @@ func newDiagnoseHostCmd() *cobra.Command {
     }
 
     cmd.Flags().StringVar(&target, "target", "", "Hostname or IP")
-    cmd.Flags().StringVar(&since, "since", "30m", "Time window to inspect")
+    cmd.Flags().StringVar(&since, "since", "30m", "Time window to inspect: 90m, 2h30m, 1d, a timestamp, or 'yesterday 14:00'")
     cmd.Flags().StringSliceVar(&collect, "collect", []string{"journal", "top"}, "Artifacts to collect")
     cmd.Flags().BoolVar(&planOnly, "plan", false, "Only produce a plan without execution")
 
@@ -170,11 +396,356 @@ func newDiagnoseHostCmd() *cobra.Command {
     return cmd
 }
 
+// collectK8sEvidence reads pods and events from each namespace in parallel and appends
+// what was observed to the findings; several namespaces also get a rollup.
+func collectK8sEvidence(cmd *cobra.Command, client k8s.Client, result *planResult, namespaces []string, since time.Duration) {
+    reports := client.CollectNamespaces(cmd.Context(), namespaces, since)
+    result.Targets = reports
+    for _, report := range reports {
+        if report.Error != "" {
+            warnings.Add(cmd.Context(), "k8s", "namespace %s: %s", report.Namespace, report.Error)
+        }
+    }
+    if len(reports) == 1 {
+        result.Findings = append(result.Findings, reports[0].Findings...)
+        return
+    }
+    rollup := k8s.BuildRollup(reports)
+    result.Rollup = &rollup
+    result.Findings = append(result.Findings, rollup.Findings...)
+    for _, report := range reports {
+        for _, finding := range report.Findings {
+            result.Findings = append(result.Findings, fmt.Sprintf("%s: %s", report.Namespace, finding))
+        }
+    }
+}
+
+// applyRules matches the collected evidence against known failure signatures. Their
+// deterministic findings go first; a rule set that fails to load is only a warning.
+func applyRules(cmd *cobra.Command, result *planResult) {
+    set, err := rules.LoadDefault(cmd.Context())
+    if err != nil {
+        warnings.Add(cmd.Context(), "rules", "rules not evaluated: %v", err)
+        return
+    }
+    var evidence []interface{}
+    for _, target := range result.Targets {
+        evidence = append(evidence, target)
+    }
+    if result.Node != nil {
+        evidence = append(evidence, result.Node)
+    }
+    result.Rules = set.Evaluate(rules.Lines(evidence...))
+    if len(result.Rules) == 0 {
+        return
+    }
+    findings := make([]string, 0, len(result.Rules)+len(result.Findings))
+    for _, hit := range result.Rules {
+        findings = append(findings, fmt.Sprintf("rule %s: %s", hit.Rule, hit.Finding))
+    }
+    result.Findings = append(findings, result.Findings...)
+}
+
+// addSimilarIncidents matches the findings against past diagnoses and knowledge notes.
+// The search is best effort: failures become warnings and do not fail the diagnosis.
+func addSimilarIncidents(cmd *cobra.Command, shared *diagnoseFlags, result *planResult) {
+    if shared.similar <= 0 || len(result.Findings) == 0 {
+        return
+    }
+    matches, err := findSimilarIncidents(cmd, shared, result)
+    if err != nil {
+        warnings.Add(cmd.Context(), "incidents", "similar incident search failed: %v", err)
+        return
+    }
+    result.Similar = matches
+}
+
+func findSimilarIncidents(cmd *cobra.Command, shared *diagnoseFlags, result *planResult) ([]incidents.Match, error) {
+    ctx := cmd.Context()
+    provider, model, _ := strings.Cut(shared.similarModel, "/")
+    embedder, err := providers.NewEmbedder(ctx, provider, model)
+    if err != nil {
+        return nil, err
+    }
+    records, err := runs.List(ctx)
+    if err != nil {
+        return nil, err
+    }
+    candidates := incidents.FromRuns(records)
+    dir := shared.knowledgeDir
+    if dir == "" {
+        if dir, err = incidents.KnowledgeDir(ctx); err != nil {
+            return nil, err
+        }
+    }
+    notes, err := incidents.LoadKnowledge(dir)
+    if err != nil {
+        return nil, err
+    }
+    candidates = append(candidates, notes...)
+    query := strings.Join(append([]string{result.Summary}, result.Findings...), "\n")
+    return incidents.FindSimilar(cmd.Context(), embedder, query, candidates, shared.similar, incidents.DefaultMinScore)
+}
+
+// recordDiagnosis saves the summary and findings to run history so later diagnoses can
+// find this one. Like the search, it is best effort and skipped under --dry-run.
+func recordDiagnosis(cmd *cobra.Command, opts *config.GlobalOptions, scope, kubecontext string, result *planResult) {
+    if opts.DryRun {
+        return
+    }
+    record, err := runs.Start(cmd.Context(), "diagnose", scope, "", runs.CaptureEnvironment(cmd.Context(), opts, kubecontext))
+    if err == nil {
+        record.Summary = result.Summary
+        record.Findings = result.Findings
+        err = record.Finish(cmd.Context(), nil, nil)
+    }
+    if err != nil {
+        warnings.Add(cmd.Context(), "runs", "could not record diagnosis: %v", err)
+        return
+    }
+    result.RunID = record.ID
+}
+
+// notifyDiagnosis sends a diagnosis with findings through the notify routes. Its
+// severity is the highest of the matched rules, or warning when none matched; repeats
+// with the same rules on the same scope are one event for dedupe.
+func notifyDiagnosis(cmd *cobra.Command, opts *config.GlobalOptions, service, environment string, result *planResult) {
+    if len(result.Findings) == 0 {
+        return
+    }
+    severity := "warning"
+    ruleIDs := make([]string, 0, len(result.Rules))
+    for _, hit := range result.Rules {
+        if notify.SeverityRank(hit.Severity) > notify.SeverityRank(severity) {
+            severity = hit.Severity
+        }
+        ruleIDs = append(ruleIDs, hit.Rule)
+    }
+    sort.Strings(ruleIDs)
+    text := result.Summary
+    for _, finding := range result.Findings {
+        text += "\n- " + finding
+    }
+    result.Notifications = notifyEvent(cmd, opts, notify.Event{
+        Kind:        "diagnose",
+        Severity:    severity,
+        Service:     service,
+        Environment: environment,
+        Title:       result.Findings[0],
+        Text:        text,
+        RunID:       result.RunID,
+        Key:         strings.Join([]string{"diagnose", service, environment, strings.Join(ruleIDs, ",")}, "|"),
+    })
+}
+
+func addWorkspaceEvidence(shared *diagnoseFlags, result *planResult) error {
+    ws, err := detectWorkspace(shared.withWorkspace)
+    if err != nil || ws == nil {
+        return err
+    }
+    result.Evidence = append(result.Evidence, workspaceEvidence(ws))
+    return nil
+}
+
+// parseSince reads a --since value: a duration such as 2h30m or 1d, a timestamp, or
+// "yesterday 14:00". The result is the look-back window from now.
+func parseSince(since string) (time.Duration, error) {
+    window, err := timeparse.Window(since, time.Now())
+    if err != nil {
+        return 0, fmt.Errorf("--since: %w", err)
+    }
+    return window, nil
+}
+
+// metricStepTarget is how many points a --metric range query aims for over the window.
+const metricStepTarget = 60
+
+// addMetricEvidence runs each --metric as a range query over the diagnose window. A
+// query that fails is a warning; the diagnosis goes on without it.
+func addMetricEvidence(cmd *cobra.Command, shared *diagnoseFlags, result *planResult, since string) error {
+    if len(shared.metrics) == 0 {
+        return nil
+    }
+    window, err := parseSince(since)
+    if err != nil {
+        return err
+    }
+    if window <= 0 {
+        window = time.Hour
+    }
+    step := (window / metricStepTarget).Round(time.Second)
+    if step < 15*time.Second {
+        step = 15 * time.Second
+    }
+    end := time.Now()
+    client := promql.Client{BaseURL: shared.prometheus}
+    for _, raw := range shared.metrics {
+        name, query := parseMetricFlag(raw)
+        if query == "" {
+            return fmt.Errorf("--metric %q: query is empty", raw)
+        }
+        series, err := client.QueryRange(cmd.Context(), query, end.Add(-window), end, step)
+        if err != nil {
+            warnings.Add(cmd.Context(), "metrics", "%s: %v", name, err)
+            continue
+        }
+        result.Evidence = append(result.Evidence, map[string]any{
+            "type":   "metrics",
+            "name":   name,
+            "query":  query,
+            "since":  since,
+            "step":   step.String(),
+            "series": series,
+        })
+    }
+    return nil
+}
+
+// parseMetricFlag splits name=query. PromQL matchers contain '=' too, so a prefix that is
+// not a plain name means the whole value is the query, which then names itself.
+func parseMetricFlag(raw string) (string, string) {
+    raw = strings.TrimSpace(raw)
+    if name, query, ok := strings.Cut(raw, "="); ok && metricName.MatchString(strings.TrimSpace(name)) {
+        return strings.TrimSpace(name), strings.TrimSpace(query)
+    }
+    return raw, raw
+}
+
+var metricName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// addChangeEvidence records commits touching --changes-path within the diagnose window.
+func addChangeEvidence(cmd *cobra.Command, shared *diagnoseFlags, result *planResult, since string) error {
+    if len(shared.changePaths) == 0 {
+        return nil
+    }
+    window, err := parseSince(since)
+    if err != nil {
+        return err
+    }
+    query := gitlog.Query{Dir: shared.repo, Paths: shared.changePaths}
+    if window > 0 {
+        query.Since = time.Now().Add(-window)
+    }
+    commits, err := gitlog.Log(cmd.Context(), query)
+    if err != nil {
+        return err
+    }
+    summary := gitlog.Summarize(commits, 5)
+    result.Evidence = append(result.Evidence, map[string]any{
+        "type":    "changes",
+        "since":   since,
+        "paths":   shared.changePaths,
+        "summary": summary,
+        "commits": commits,
+    })
+    if len(commits) > 0 {
+        result.Findings = append(result.Findings, fmt.Sprintf("%d commit(s) (%d merge(s)) touched %s in the last %s; latest %s %q",
+            summary.Commits, summary.Merges, strings.Join(shared.changePaths, ", "), since, commits[0].Short, commits[0].Subject))
+    }
+    return nil
+}
+
+// metricSeriesShown caps the series drawn per --metric; the rest are counted.
+const metricSeriesShown = 5
+
+// renderMetricEvidence draws each series of a metric as a sparkline with its range.
+func renderMetricEvidence(name, since any, series []promql.Series) []string {
+    if len(series) == 0 {
+        return []string{fmt.Sprintf("  metric %s: no data since %s", name, since)}
+    }
+    if len(series) == 1 {
+        values := series[0].Values()
+        return []string{fmt.Sprintf("  metric %s (since %s): %s  %s", name, since, sparkline.Render(values, 0), sparkline.Stats(values))}
+    }
+    lines := []string{fmt.Sprintf("  metric %s (since %s, %d series):", name, since, len(series))}
+    shown := series
+    if len(shown) > metricSeriesShown {
+        shown = shown[:metricSeriesShown]
+    }
+    width := 0
+    for _, s := range shown {
+        width = max(width, len(s.Name()))
+    }
+    for _, s := range shown {
+        values := s.Values()
+        lines = append(lines, fmt.Sprintf("    %-*s  %s  %s", width, s.Name(), sparkline.Render(values, 0), sparkline.Stats(values)))
+    }
+    if more := len(series) - len(shown); more > 0 {
+        lines = append(lines, fmt.Sprintf("    ... and %d more series (see --json)", more))
+    }
+    return lines
+}
+
 func renderPlan(scope string, include []string, plan planResult) string {
     parts := []string{fmt.Sprintf("Plan for %s diagnostics:", scope)}
     if len(include) > 0 {
         parts = append(parts, fmt.Sprintf("  include: %s", strings.Join(include, ", ")))
     }
+    for _, evidence := range plan.Evidence {
+        switch evidence["type"] {
+        case "workspace":
+            if stacks, ok := evidence["stacks"].([]string); ok && len(stacks) > 0 {
+                parts = append(parts, fmt.Sprintf("  workspace: %s", strings.Join(stacks, ", ")))
+            }
+        case "changes":
+            if summary, ok := evidence["summary"].(gitlog.Summary); ok {
+                parts = append(parts, fmt.Sprintf("  recent changes: %d commit(s), %d merge(s) since %s", summary.Commits, summary.Merges, evidence["since"]))
+            }
+        case "metrics":
+            if series, ok := evidence["series"].([]promql.Series); ok {
+                parts = append(parts, renderMetricEvidence(evidence["name"], evidence["since"], series)...)
+            }
+        }
+    }
+    if n := plan.Node; n != nil {
+        ready := "unknown"
+        for _, cond := range n.Conditions {
+            if cond.Type == "Ready" {
+                ready = cond.Status
+            }
+        }
+        line := fmt.Sprintf("  node %s: ready=%s, %d pod(s), %d unhealthy", n.Node, ready, n.Pods.Total, len(n.Problems))
+        if n.Kubelet != nil {
+            line = fmt.Sprintf("%s, kubelet log via %s", line, n.Kubelet.Source)
+        }
+        if len(n.Conditions) == 0 && len(n.Errors) > 0 {
+            line = fmt.Sprintf("  node %s: collection failed", n.Node)
+        }
+        parts = append(parts, line)
+    }
+    for _, target := range plan.Targets {
+        line := fmt.Sprintf("  namespace %s: %d pod(s), %d pending, %d failed, %d restart(s)",
+            target.Namespace, target.Pods.Total, target.Pods.Pending, target.Pods.Failed, target.Pods.Restarts)
+        if target.Error != "" && target.Pods.Total == 0 {
+            line = fmt.Sprintf("  namespace %s: collection failed", target.Namespace)
+        }
+        parts = append(parts, line)
+    }
+    if plan.Rollup != nil {
+        for _, finding := range plan.Rollup.Findings {
+            parts = append(parts, fmt.Sprintf("  rollup: %s", finding))
+        }
+    }
+    for _, hit := range plan.Rules {
+        label := hit.Rule
+        if hit.Severity != "" {
+            label = fmt.Sprintf("%s [%s]", hit.Rule, hit.Severity)
+        }
+        parts = append(parts, fmt.Sprintf("  rule %s: %s (%d matching line(s))", label, hit.Title, hit.Count))
+        for _, step := range hit.Remediation {
+            parts = append(parts, fmt.Sprintf("    - %s", step))
+        }
+    }
+    for _, match := range plan.Similar {
+        line := fmt.Sprintf("  similar past incident (%s %s, %s, %.2f): %s", match.Source, match.ID, timefmt.Ago(match.When), match.Score, match.Title)
+        if match.Resolution != "" {
+            line = fmt.Sprintf("%s; fixed by: %s", line, match.Resolution)
+        }
+        parts = append(parts, line)
+    }
+    if plan.RunID != "" {
+        parts = append(parts, fmt.Sprintf("  recorded as run %s (sre-ai runs resolve %s <what fixed it>)", plan.RunID, plan.RunID))
+    }
     for i, action := range plan.Actions {
         parts = append(parts, fmt.Sprintf("  %d. %s", i+1, action["intent"]))
     }
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/runtimes"
+	"github.com/spf13/cobra"
+)
+
+// doctorCheck is a single line of the doctor report.
+type doctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func newDoctorCmd(opts *config.GlobalOptions) *cobra.Command {
+	var fixPerms bool
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the local environment used to launch MCP servers",
+		Long: `Check the local environment used to launch MCP servers.
+
+doctor also reports files and directories under the config dir that other users can
+read. --fix-perms tightens them to 0600 and 0700 (use --dry-run to preview).`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			reports := runtimes.DetectAll(cmd.Context())
+			checks := make([]doctorCheck, 0, len(reports))
+
+			for i := range reports {
+				report := &reports[i]
+				check := doctorCheck{Name: "runtime " + string(report.Runtime)}
+				switch {
+				case report.Chosen == nil:
+					check.Status = "warn"
+					check.Detail = report.Error
+				case report.Error != "":
+					// An override was configured but could not be used; a fallback was chosen.
+					check.Status = "warn"
+					check.Detail = fmt.Sprintf("%s; using %s", report.Error, report.Chosen.Binary)
+				default:
+					check.Status = "ok"
+					if report.Chosen.Version == "" {
+						report.Chosen.Version = runtimes.Version(report.Chosen.Binary)
+					}
+					check.Detail = describeDetection(*report.Chosen)
+				}
+				checks = append(checks, check)
+			}
+
+			permCheck, issues, err := checkConfigPermissions(cmd.Context(), fixPerms, opts.DryRun)
+			if err != nil {
+				return err
+			}
+			checks = append(checks, permCheck)
+
+			payload := map[string]any{
+				"checks":   checks,
+				"runtimes": reports,
+			}
+			if len(issues) > 0 {
+				payload["permissions"] = issues
+			}
+			return printOutput(cmd, opts, payload, formatDoctorHuman(opts, checks, reports, issues))
+		},
+	}
+	cmd.Flags().BoolVar(&fixPerms, "fix-perms", false, "Restrict config dir files to 0600 and directories to 0700")
+	return cmd
+}
+
+// checkConfigPermissions reports config dir entries other users can access, fixing
+// them when fix is set and this is not a dry run.
+func checkConfigPermissions(ctx context.Context, fix, dryRun bool) (doctorCheck, []config.PermIssue, error) {
+	check := doctorCheck{Name: "config permissions"}
+	dir, err := config.ConfigDir(ctx)
+	if err != nil {
+		return check, nil, err
+	}
+	issues, err := config.CheckPermissions(dir, fix && !dryRun)
+	if err != nil {
+		return check, issues, fmt.Errorf("check permissions under %s: %w", dir, err)
+	}
+	switch {
+	case len(issues) == 0:
+		check.Status = "ok"
+		check.Detail = fmt.Sprintf("%s is private to the current user", dir)
+	case fix && dryRun:
+		check.Status = "warn"
+		check.Detail = fmt.Sprintf("dry-run: would tighten %d path(s) under %s", len(issues), dir)
+	case fix:
+		check.Status = "fixed"
+		check.Detail = fmt.Sprintf("tightened %d path(s) under %s", len(issues), dir)
+	default:
+		check.Status = "warn"
+		check.Detail = fmt.Sprintf("%d path(s) under %s are accessible to other users; run 'sre-ai doctor --fix-perms'", len(issues), dir)
+	}
+	return check, issues, nil
+}
+
+func describeDetection(det runtimes.Detection) string {
+	parts := []string{det.Binary}
+	if det.Version != "" {
+		parts = append(parts, det.Version)
+	}
+	parts = append(parts, fmt.Sprintf("via %s", det.Source))
+	if !det.OnPath {
+		parts = append(parts, "prepended to PATH for MCP servers")
+	}
+	return strings.Join(parts, " - ")
+}
+
+func formatDoctorHuman(opts *config.GlobalOptions, checks []doctorCheck, reports []runtimes.Report, issues []config.PermIssue) string {
+	var builder strings.Builder
+	for _, check := range checks {
+		builder.WriteString(fmt.Sprintf("[%s] %s", check.Status, check.Name))
+		if check.Detail != "" {
+			builder.WriteString(": ")
+			builder.WriteString(check.Detail)
+		}
+		builder.WriteString("\n")
+	}
+
+	for _, issue := range issues {
+		if issue.Fixed {
+			builder.WriteString(fmt.Sprintf("  %s: %s -> %s\n", issue.Path, issue.Mode, issue.Want))
+		} else {
+			builder.WriteString(fmt.Sprintf("  %s: %s (want %s)\n", issue.Path, issue.Mode, issue.Want))
+		}
+	}
+
+	if opts.Verbose > 0 {
+		for _, report := range reports {
+			if len(report.Candidates) <= 1 {
+				continue
+			}
+			builder.WriteString(fmt.Sprintf("%s candidates:\n", report.Runtime))
+			for _, det := range report.Candidates {
+				builder.WriteString(fmt.Sprintf("  - %s (%s)\n", det.Binary, det.Source))
+			}
+		}
+	}
+
+	builder.WriteString("Set runtimes.<name> in config.yaml to force a specific binary or bin directory.")
+	return builder.String()
+}
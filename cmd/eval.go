@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/eval"
+	"github.com/example/sre-ai/internal/timefmt"
+	"github.com/spf13/cobra"
+)
+
+func newEvalCmd(opts *config.GlobalOptions) *cobra.Command {
+	var suitePath string
+	var targetFlags []string
+	var reportPath string
+
+	cmd := &cobra.Command{
+		Use:   "eval",
+		Short: "Compare providers and models against a suite of prompts or workflow fixtures",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if suitePath == "" {
+				return errors.New("--suite is required")
+			}
+			suite, err := eval.LoadSuite(suitePath)
+			if err != nil {
+				return err
+			}
+
+			var targets []eval.Target
+			for _, value := range targetFlags {
+				target, err := eval.ParseTarget(value)
+				if err != nil {
+					return err
+				}
+				targets = append(targets, target)
+			}
+
+			if opts.DryRun {
+				payload := map[string]any{
+					"suite":   suite.Name,
+					"cases":   len(suite.Cases),
+					"targets": append(targets, suite.Targets...),
+					"status":  "dry-run",
+				}
+				return printOutput(cmd, opts, payload, fmt.Sprintf("Dry-run: would evaluate %d case(s) from %s", len(suite.Cases), suite.Name))
+			}
+
+			evalOpts := eval.Options{Global: opts, Targets: targets}
+			if !opts.JSON {
+				evalOpts.Progress = func(r eval.CaseResult) {
+					status := fmt.Sprintf("%.2f", r.Score)
+					if r.Error != "" {
+						status = "error"
+					}
+					fmt.Fprintf(cmd.ErrOrStderr(), "  %s / %s: %s (%s)\n", r.Target, r.Case, status, timefmt.Duration(r.Duration))
+				}
+			}
+
+			report, err := eval.Run(cmd.Context(), suite, evalOpts)
+			if err != nil {
+				return err
+			}
+
+			if reportPath != "" {
+				data, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return err
+				}
+				if err := os.WriteFile(reportPath, append(data, '\n'), 0o644); err != nil {
+					return err
+				}
+			}
+			return printOutput(cmd, opts, report, formatEvalReport(report))
+		},
+	}
+
+	cmd.Flags().StringVar(&suitePath, "suite", "", "Path to the eval suite YAML")
+	cmd.Flags().StringSliceVar(&targetFlags, "target", nil, "provider/model to evaluate (repeatable; replaces the suite's targets)")
+	cmd.Flags().StringVar(&reportPath, "report", "", "Also write the JSON report to this file")
+
+	return cmd
+}
+
+func formatEvalReport(report *eval.Report) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Eval suite %s\n\n", report.Suite))
+
+	scores := map[string]map[string]string{}
+	for _, r := range report.Results {
+		if scores[r.Case] == nil {
+			scores[r.Case] = map[string]string{}
+		}
+		cell := fmt.Sprintf("%.2f", r.Score)
+		if r.Error != "" {
+			cell = "error"
+		}
+		scores[r.Case][r.Target] = cell
+	}
+
+	tw := tabwriter.NewWriter(&builder, 0, 4, 2, ' ', 0)
+	header := []string{"CASE"}
+	for _, t := range report.Targets {
+		header = append(header, t.Target)
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, name := range report.Cases {
+		row := []string{name}
+		for _, t := range report.Targets {
+			row = append(row, scores[name][t.Target])
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	summary := []string{"MEAN"}
+	for _, t := range report.Targets {
+		summary = append(summary, fmt.Sprintf("%.2f", t.MeanScore))
+	}
+	fmt.Fprintln(tw, strings.Join(summary, "\t"))
+	latency := []string{"LATENCY"}
+	for _, t := range report.Targets {
+		latency = append(latency, timefmt.Duration(t.Latency))
+	}
+	fmt.Fprintln(tw, strings.Join(latency, "\t"))
+	tw.Flush()
+
+	for _, r := range report.Results {
+		if r.Error != "" {
+			builder.WriteString(fmt.Sprintf("\n%s / %s failed: %s", r.Target, r.Case, r.Error))
+		}
+	}
+	if report.Best != "" {
+		builder.WriteString(fmt.Sprintf("\nBest: %s", report.Best))
+	}
+	return strings.TrimRight(builder.String(), "\n")
+}
@@ -1,22 +1,30 @@
 package cmd
 
 import (
+    "context"
     "fmt"
+    "strings"
+    "time"
 
+    "github.com/example/sre-ai/internal/config"
+    "github.com/example/sre-ai/internal/gitlog"
+    "github.com/example/sre-ai/internal/timefmt"
+    "github.com/example/sre-ai/internal/timeparse"
     "github.com/spf13/cobra"
 )
 
-func newExplainCmd() *cobra.Command {
+func newExplainCmd(opts *config.GlobalOptions) *cobra.Command {
     cmd := &cobra.Command{
         Use:   "explain",
         Short: "Explain logs and commands",
     }
-    cmd.AddCommand(newExplainLogsCmd())
-    cmd.AddCommand(newExplainCommandCmd())
+    cmd.AddCommand(newExplainLogsCmd(opts))
+    cmd.AddCommand(newExplainCommandCmd(opts))
+    cmd.AddCommand(newExplainCommitCmd(opts))
     return cmd
 }
 
-func newExplainLogsCmd() *cobra.Command {
+func newExplainLogsCmd(opts *config.GlobalOptions) *cobra.Command {
     var files []string
     var since string
     var format string
@@ -25,25 +33,32 @@ func newExplainLogsCmd() *cobra.Command {
         Use:   "logs",
         Short: "Summarize log patterns",
         RunE: func(cmd *cobra.Command, args []string) error {
+            from, err := timeparse.Cutoff(since, time.Now())
+            if err != nil {
+                return fmt.Errorf("--since: %w", err)
+            }
             payload := map[string]any{
                 "summary": "Identified error spikes",
                 "files":   files,
                 "since":   since,
                 "format":  format,
             }
+            if !from.IsZero() {
+                payload["from"] = from.UTC().Format(time.RFC3339)
+            }
             human := fmt.Sprintf("Logs summary for %v since %s", files, since)
-            return printOutput(cmd, payload, human)
+            return printOutput(cmd, opts, payload, human)
         },
     }
 
     cmd.Flags().StringSliceVar(&files, "files", nil, "Log files to analyze")
-    cmd.Flags().StringVar(&since, "since", "1h", "Time window to inspect")
+    cmd.Flags().StringVar(&since, "since", "1h", "Time window to inspect: 90m, 2h30m, 1d, a timestamp, or 'yesterday 14:00'")
     cmd.Flags().StringVar(&format, "format", "table", "Output format")
 
     return cmd
 }
 
-func newExplainCommandCmd() *cobra.Command {
+func newExplainCommandCmd(opts *config.GlobalOptions) *cobra.Command {
     cmd := &cobra.Command{
         Use:   "command",
         Short: "Explain command semantics",
@@ -55,9 +70,59 @@ func newExplainCommandCmd() *cobra.Command {
                 "explanation": "Allows inbound TCP traffic on port 443",
             }
             human := fmt.Sprintf("Command explanation: %s", payload["explanation"])
-            return printOutput(cmd, payload, human)
+            return printOutput(cmd, opts, payload, human)
+        },
+    }
+
+    return cmd
+}
+
+func newExplainCommitCmd(opts *config.GlobalOptions) *cobra.Command {
+    var repo string
+
+    cmd := &cobra.Command{
+        Use:   "commit <sha>",
+        Short: "Summarize what a commit changed",
+        Args:  cobra.ExactArgs(1),
+        RunE: func(cmd *cobra.Command, args []string) error {
+            commit, err := gitlog.Show(cmd.Context(), repo, args[0])
+            if err != nil {
+                return err
+            }
+            summary := gitlog.Summarize([]gitlog.Commit{*commit}, 10)
+            payload := map[string]any{
+                "commit":  commit,
+                "summary": summary,
+            }
+            return printOutput(cmd, opts, payload, formatCommitHuman(cmd.Context(), commit))
         },
     }
 
+    cmd.Flags().StringVar(&repo, "repo", ".", "Path to the git repository")
+
     return cmd
 }
+
+func formatCommitHuman(ctx context.Context, commit *gitlog.Commit) string {
+    var builder strings.Builder
+    kind := "commit"
+    if commit.Merge {
+        kind = "merge"
+    }
+    builder.WriteString(fmt.Sprintf("%s %s by %s on %s\n", kind, commit.Short, commit.Author, timefmt.Timestamp(ctx, commit.Date)))
+    builder.WriteString(fmt.Sprintf("  %s\n", commit.Subject))
+    if commit.Body != "" {
+        for _, line := range strings.Split(commit.Body, "\n") {
+            builder.WriteString(fmt.Sprintf("  %s\n", line))
+        }
+    }
+    builder.WriteString(fmt.Sprintf("%d file(s), +%d -%d", len(commit.Files), commit.Insertions, commit.Deletions))
+    for _, file := range commit.Files {
+        if file.Binary {
+            builder.WriteString(fmt.Sprintf("\n  %s (binary)", file.Path))
+            continue
+        }
+        builder.WriteString(fmt.Sprintf("\n  %s +%d -%d", file.Path, file.Insertions, file.Deletions))
+    }
+    return builder.String()
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/facts"
+	"github.com/example/sre-ai/internal/timefmt"
+	"github.com/spf13/cobra"
+)
+
+func newFactsCmd(opts *config.GlobalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "facts",
+		Short: "Inspect and edit the facts workflows remember across runs (per --session)",
+	}
+	cmd.AddCommand(newFactsLsCmd(opts))
+	cmd.AddCommand(newFactsGetCmd(opts))
+	cmd.AddCommand(newFactsSetCmd(opts))
+	cmd.AddCommand(newFactsRmCmd(opts))
+	return cmd
+}
+
+func newFactsLsCmd(opts *config.GlobalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "ls [prefix]",
+		Short: "List facts, optionally only keys under a service prefix",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := facts.Open(cmd.Context(), opts.Session)
+			if err != nil {
+				return err
+			}
+			prefix := ""
+			if len(args) == 1 {
+				prefix = strings.TrimSuffix(args[0], ".") + "."
+			}
+			all := store.All()
+			selected := make(map[string]facts.Fact)
+			var buf strings.Builder
+			tw := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "KEY\tVALUE\tUPDATED\tSOURCE")
+			for _, key := range store.Keys() {
+				if prefix != "" && !strings.HasPrefix(key, prefix) {
+					continue
+				}
+				f := all[key]
+				selected[key] = f
+				source := f.Source
+				if source == "" {
+					source = "-"
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", key, factText(f.Value), timefmt.Timestamp(cmd.Context(), f.UpdatedAt), source)
+			}
+			tw.Flush()
+			human := strings.TrimRight(buf.String(), "\n")
+			if len(selected) == 0 {
+				human = fmt.Sprintf("No facts in session %s", store.Session)
+			}
+			return printOutput(cmd, opts, map[string]any{"session": store.Session, "facts": selected}, human)
+		},
+	}
+}
+
+func newFactsGetCmd(opts *config.GlobalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <key>",
+		Short: "Print one fact",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := facts.Open(cmd.Context(), opts.Session)
+			if err != nil {
+				return err
+			}
+			value, ok := store.Get(args[0])
+			if !ok {
+				return fmt.Errorf("no fact %s in session %s", args[0], store.Session)
+			}
+			return printOutput(cmd, opts, map[string]any{"key": args[0], "value": value}, factText(value))
+		},
+	}
+}
+
+func newFactsSetCmd(opts *config.GlobalOptions) *cobra.Command {
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Store a fact by hand, e.g. checkout.last_known_good_version v1.42.0",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var value interface{} = args[1]
+			if asJSON {
+				if err := json.Unmarshal([]byte(args[1]), &value); err != nil {
+					return fmt.Errorf("--json-value: %w", err)
+				}
+			}
+			store, err := facts.Open(cmd.Context(), opts.Session)
+			if err != nil {
+				return err
+			}
+			if err := store.Set(map[string]interface{}{args[0]: value}, "cli"); err != nil {
+				return err
+			}
+			return printOutput(cmd, opts, map[string]any{"session": store.Session, "key": args[0], "value": value}, fmt.Sprintf("Set %s in session %s", args[0], store.Session))
+		},
+	}
+
+	cmd.Flags().BoolVar(&asJSON, "json-value", false, "Parse the value as JSON instead of storing it as a string")
+
+	return cmd
+}
+
+func newFactsRmCmd(opts *config.GlobalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "rm <key>...",
+		Short: "Forget facts",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := facts.Open(cmd.Context(), opts.Session)
+			if err != nil {
+				return err
+			}
+			found, err := store.Delete(args...)
+			if err != nil {
+				return err
+			}
+			if !found {
+				return fmt.Errorf("no such fact in session %s", store.Session)
+			}
+			return printOutput(cmd, opts, map[string]any{"session": store.Session, "removed": args}, fmt.Sprintf("Removed %s from session %s", strings.Join(args, ", "), store.Session))
+		},
+	}
+}
+
+// factText renders a fact value on one line; structured values are shown as JSON.
+func factText(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
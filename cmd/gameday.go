@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/example/sre-ai/internal/agent"
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/confirm"
+	"github.com/example/sre-ai/internal/gameday"
+	"github.com/example/sre-ai/internal/messages"
+	"github.com/example/sre-ai/internal/timefmt"
+	"github.com/spf13/cobra"
+)
+
+// gamedayCapability must be granted via --cap before any failure is injected.
+const gamedayCapability = "chaos"
+
+func newGamedayCmd(opts *config.GlobalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gameday",
+		Short: "Run controlled failure-injection scenarios",
+	}
+
+	cmd.AddCommand(newGamedayRunCmd(opts))
+	return cmd
+}
+
+func newGamedayRunCmd(opts *config.GlobalOptions) *cobra.Command {
+	var inputPairs []string
+	var planOnly bool
+
+	cmd := &cobra.Command{
+		Use:   "run <scenario.yaml>",
+		Short: "Execute a gameday scenario and report SLO impact",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			scenario, err := gameday.LoadScenario(args[0])
+			if err != nil {
+				return err
+			}
+
+			provided, err := agent.ParseInputPairs(inputPairs)
+			if err != nil {
+				return err
+			}
+
+			planOnly = planOnly || opts.DryRun
+			if !planOnly {
+				if !hasCapability(opts, gamedayCapability) {
+					return fmt.Errorf("gameday injects failures; grant the capability with --cap %s", gamedayCapability)
+				}
+				if !canConfirm(cmd, opts) {
+					return messages.Error(cmd.Context(), messages.RefuseInject)
+				}
+			}
+
+			confirmStep := func(stage agent.StageSpec, stepName string, step agent.StepSpec) (bool, error) {
+				if opts.AutoConfirm {
+					return true, nil
+				}
+				req := confirm.Ask(cmd.Context(), messages.ConfirmInjectFailure, injectResources(step), stage.ID+"/"+stepName)
+				req.Detail = step.Description
+				return promptForConfirmation(cmd, opts, req)
+			}
+
+			report, runErr := gameday.Run(cmd.Context(), scenario, opts, provided, planOnly, confirmStep, cmd.ErrOrStderr())
+			if report == nil {
+				return runErr
+			}
+			if err := printOutput(cmd, opts, report, formatGamedayReport(report)); err != nil {
+				return err
+			}
+			return runErr
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&inputPairs, "input", nil, "Scenario input as key=value (repeatable)")
+	cmd.Flags().BoolVar(&planOnly, "plan", false, "List the scenario steps without injecting failures")
+
+	return cmd
+}
+
+// injectResources lists what an injection step acts on: its tool, its target, and
+// each parameter, so the operator sees them before confirming.
+func injectResources(step agent.StepSpec) []string {
+	var resources []string
+	if step.Tool != "" {
+		resources = append(resources, "tool "+step.Tool)
+	}
+	if step.Target != "" {
+		resources = append(resources, "target "+step.Target)
+	}
+	keys := make([]string, 0, len(step.Params))
+	for key := range step.Params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		resources = append(resources, fmt.Sprintf("%s=%v", key, step.Params[key]))
+	}
+	return resources
+}
+
+func hasCapability(opts *config.GlobalOptions, name string) bool {
+	for _, c := range opts.Caps {
+		if strings.EqualFold(strings.TrimSpace(c), name) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatGamedayReport(report *gameday.Report) string {
+	var builder strings.Builder
+
+	status := "completed"
+	switch {
+	case report.PlanOnly:
+		status = "planned"
+	case report.Error != "":
+		status = "failed"
+	case report.Aborted:
+		status = "aborted"
+	}
+	builder.WriteString(fmt.Sprintf("Gameday %s %s", report.Scenario, status))
+	if !report.PlanOnly {
+		builder.WriteString(fmt.Sprintf(" - %d injection", report.Injections))
+		if report.Injections != 1 {
+			builder.WriteString("s")
+		}
+		builder.WriteString(fmt.Sprintf(" - %s", timefmt.Duration(report.Duration)))
+	}
+	builder.WriteString("\n")
+	if report.Hypothesis != "" {
+		builder.WriteString("Hypothesis: ")
+		builder.WriteString(report.Hypothesis)
+		builder.WriteString("\n")
+	}
+	if report.BlastRadius != "" {
+		builder.WriteString("Blast radius: ")
+		builder.WriteString(report.BlastRadius)
+		builder.WriteString("\n")
+	}
+
+	builder.WriteString("Steps:\n")
+	for _, step := range report.Steps {
+		builder.WriteString(fmt.Sprintf("  - %s/%s [%s]", step.StageID, step.StepName, step.Status))
+		if step.Error != "" {
+			builder.WriteString(": ")
+			builder.WriteString(step.Error)
+		}
+		builder.WriteString("\n")
+	}
+
+	if len(report.SLOs) > 0 {
+		builder.WriteString("SLOs:\n")
+		for _, slo := range report.SLOs {
+			state := "held"
+			if slo.Breached {
+				state = "BREACHED"
+			} else if len(slo.Observations) == 0 {
+				state = "not observed"
+			}
+			builder.WriteString(fmt.Sprintf("  - %s (%s): %s", slo.Name, slo.Objective, state))
+			if slo.Detail != "" {
+				builder.WriteString(" - ")
+				builder.WriteString(slo.Detail)
+			}
+			builder.WriteString("\n")
+		}
+	}
+
+	if report.AbortReason != "" {
+		builder.WriteString("Aborted: ")
+		builder.WriteString(report.AbortReason)
+		builder.WriteString("\n")
+	}
+
+	return strings.TrimRight(builder.String(), "\n")
+}
@@ -1,22 +1,31 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"strings"
 
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/k8s"
 	"github.com/spf13/cobra"
 )
 
-func newGenerateCmd() *cobra.Command {
+func newGenerateCmd(opts *config.GlobalOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "generate",
 		Short: "Generate artifacts like runbooks or IaC",
 	}
-	cmd.AddCommand(newGenerateRunbookCmd())
-	cmd.AddCommand(newGenerateIacCmd())
+	// withWorkspace is shared by every generate subcommand via a persistent flag.
+	withWorkspace := new(bool)
+	addWorkspaceFlag(cmd.PersistentFlags(), withWorkspace)
+	cmd.AddCommand(newGenerateRunbookCmd(opts, withWorkspace))
+	cmd.AddCommand(newGenerateIacCmd(opts, withWorkspace))
+	cmd.AddCommand(newGenerateRBACCmd(opts))
 	return cmd
 }
 
-func newGenerateRunbookCmd() *cobra.Command {
+func newGenerateRunbookCmd(opts *config.GlobalOptions, withWorkspace *bool) *cobra.Command {
 	var service string
 	var from string
 	var output string
@@ -30,8 +39,15 @@ func newGenerateRunbookCmd() *cobra.Command {
 				"source":  from,
 				"output":  output,
 			}
+			ws, err := detectWorkspace(*withWorkspace)
+			if err != nil {
+				return err
+			}
+			if ws != nil {
+				payload["workspace"] = ws
+			}
 			human := fmt.Sprintf("Generated runbook draft for %s", service)
-			return printOutput(cmd, payload, human)
+			return printOutput(cmd, opts, payload, human)
 		},
 	}
 
@@ -42,7 +58,7 @@ func newGenerateRunbookCmd() *cobra.Command {
 	return cmd
 }
 
-func newGenerateIacCmd() *cobra.Command {
+func newGenerateIacCmd(opts *config.GlobalOptions, withWorkspace *bool) *cobra.Command {
 	var provider string
 	var resource string
 	var tags []string
@@ -58,8 +74,15 @@ func newGenerateIacCmd() *cobra.Command {
 				"tags":     tags,
 				"output":   out,
 			}
+			ws, err := detectWorkspace(*withWorkspace)
+			if err != nil {
+				return err
+			}
+			if ws != nil {
+				payload["workspace"] = ws
+			}
 			human := fmt.Sprintf("Generated IaC snippet for %s", resource)
-			return printOutput(cmd, payload, human)
+			return printOutput(cmd, opts, payload, human)
 		},
 	}
 
@@ -70,3 +93,116 @@ func newGenerateIacCmd() *cobra.Command {
 
 	return cmd
 }
+
+func newGenerateRBACCmd(opts *config.GlobalOptions) *cobra.Command {
+	var collectors []string
+	var namespaces []string
+	var withSelector bool
+	var name string
+	var serviceAccount string
+	var user string
+	var group string
+	var out string
+
+	cmd := &cobra.Command{
+		Use:   "rbac",
+		Short: "Generate least-privilege RBAC for the Kubernetes collectors",
+		Long: `Generate the RBAC YAML granting exactly what the Kubernetes collectors read.
+
+Each namespace gets a Role and RoleBinding; permissions without a namespace
+(diagnose-node, or --for diagnose-k8s with no --namespace) go into a ClusterRole
+and ClusterRoleBinding. The subject is a ServiceAccount, created alongside, unless
+--user or --group names one. The pre-collection permission check uses
+SelfSubjectAccessReviews, which every authenticated identity may create.
+
+Collectors: diagnose-k8s (pods and events), diagnose-node (nodes, node logs API,
+and events and pods across namespaces), and rollout (deployments, statefulsets,
+and daemonsets, read by verify steps).`,
+		Example: `  sre-ai generate rbac --for diagnose-k8s --namespace prod
+  sre-ai generate rbac --for diagnose-k8s,rollout --namespace prod,staging --service-account ops/sre-ai --out rbac.yaml
+  sre-ai generate rbac --for diagnose-node --group sre-oncall | kubectl apply -f -`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(collectors) == 0 {
+				return fmt.Errorf("--for is required (one of %s)", strings.Join(k8s.Collectors, ", "))
+			}
+			subject, err := rbacSubject(serviceAccount, user, group, namespaces)
+			if err != nil {
+				return err
+			}
+			var perms []k8s.Permission
+			for _, collector := range collectors {
+				more, err := k8s.CollectorPermissions(strings.TrimSpace(collector), namespaces, withSelector)
+				if err != nil {
+					return err
+				}
+				perms = append(perms, more...)
+			}
+			manifest := k8s.RBACManifests(name, subject, perms)
+
+			payload := map[string]any{
+				"for":         collectors,
+				"namespaces":  namespaces,
+				"subject":     subject,
+				"permissions": perms,
+				"manifest":    manifest,
+			}
+			human := strings.TrimRight(manifest, "\n")
+			if out != "" {
+				if opts.DryRun {
+					payload["status"] = "dry-run"
+					human = fmt.Sprintf("Dry-run: would write RBAC for %s to %s", strings.Join(collectors, ", "), out)
+				} else {
+					if err := os.WriteFile(out, []byte(manifest), 0o644); err != nil {
+						return err
+					}
+					payload["output"] = out
+					human = fmt.Sprintf("Wrote RBAC for %s to %s", strings.Join(collectors, ", "), out)
+				}
+			}
+			return printOutput(cmd, opts, payload, human)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&collectors, "for", nil, "Collectors to grant: "+strings.Join(k8s.Collectors, ", "))
+	cmd.Flags().StringSliceVar(&namespaces, "namespace", nil, "Namespaces to grant in (default all namespaces, via a ClusterRole)")
+	cmd.Flags().BoolVar(&withSelector, "selector", false, "Also allow listing namespaces, for diagnose k8s --selector")
+	cmd.Flags().StringVar(&name, "name", "sre-ai", "Name of the roles and bindings")
+	cmd.Flags().StringVar(&serviceAccount, "service-account", "", "ServiceAccount subject as [namespace/]name (default sre-ai in the first --namespace)")
+	cmd.Flags().StringVar(&user, "user", "", "Bind to this user instead of a ServiceAccount")
+	cmd.Flags().StringVar(&group, "group", "", "Bind to this group instead of a ServiceAccount")
+	cmd.Flags().StringVar(&out, "out", "", "Write the YAML to this file instead of printing it")
+
+	return cmd
+}
+
+// rbacSubject picks the binding subject from the flags; at most one may be set.
+func rbacSubject(serviceAccount, user, group string, namespaces []string) (k8s.Subject, error) {
+	set := 0
+	for _, v := range []string{serviceAccount, user, group} {
+		if v != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return k8s.Subject{}, errors.New("--service-account, --user, and --group are mutually exclusive")
+	}
+	switch {
+	case user != "":
+		return k8s.Subject{Kind: "User", Name: user}, nil
+	case group != "":
+		return k8s.Subject{Kind: "Group", Name: group}, nil
+	}
+	subject := k8s.Subject{Kind: "ServiceAccount", Name: "sre-ai", Namespace: "default"}
+	if len(namespaces) > 0 {
+		subject.Namespace = namespaces[0]
+	}
+	if serviceAccount != "" {
+		if ns, sa, ok := strings.Cut(serviceAccount, "/"); ok {
+			subject.Namespace, subject.Name = ns, sa
+		} else {
+			subject.Name = serviceAccount
+		}
+	}
+	return subject, nil
+}
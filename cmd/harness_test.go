@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/providers"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// fakeMCPEnv makes the test binary serve the fake MCP server instead of running tests.
+const fakeMCPEnv = "SRE_AI_TEST_FAKE_MCP"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeMCPEnv) != "" {
+		serveFakeMCP(os.Stdin, os.Stdout, os.Getenv(fakeMCPEnv))
+		os.Exit(0)
+	}
+	flag.Parse()
+	os.Exit(m.Run())
+}
+
+// serveFakeMCP answers initialize, tools/list, and tools/call over Content-Length
+// framing. Its one tool, status, is read-only and reports status.
+func serveFakeMCP(in io.Reader, out io.Writer, status string) {
+	reader := bufio.NewReader(in)
+	for {
+		length := -1
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimSpace(line)
+			if line == "" {
+				break
+			}
+			if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(name, "Content-Length") {
+				length, _ = strconv.Atoi(strings.TrimSpace(value))
+			}
+		}
+		if length < 0 {
+			return
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			return
+		}
+		var req struct {
+			ID     json.RawMessage        `json:"id"`
+			Method string                 `json:"method"`
+			Params map[string]interface{} `json:"params"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil || len(req.ID) == 0 {
+			continue
+		}
+		var result interface{} = map[string]interface{}{}
+		switch req.Method {
+		case "initialize":
+			result = map[string]interface{}{
+				"protocolVersion": req.Params["protocolVersion"],
+				"serverInfo":      map[string]interface{}{"name": "fake", "version": "1.0.0"},
+				"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			}
+		case "tools/list":
+			result = map[string]interface{}{"tools": []map[string]interface{}{{
+				"name":        "status",
+				"description": "Report the service status",
+				"inputSchema": map[string]interface{}{"type": "object"},
+				"annotations": map[string]interface{}{"readOnlyHint": true},
+			}}}
+		case "tools/call":
+			result = map[string]interface{}{"content": []map[string]interface{}{{"type": "text", "text": status}}}
+		}
+		data, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+		fmt.Fprintf(out, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	}
+}
+
+// fakeModel calls the first status tool it is offered, then answers with its result.
+type fakeModel struct{}
+
+var offeredStatusTool = regexp.MustCompile(`(?m)^- (\S+\.status):`)
+
+func (fakeModel) Generate(ctx context.Context, prompt string) (string, error) {
+	if _, result, ok := strings.Cut(prompt, "[tool result call_1]\n"); ok {
+		answer, _, _ := strings.Cut(result, "\n")
+		data, _ := json.Marshal(map[string]string{"answer": answer})
+		return string(data), nil
+	}
+	if m := offeredStatusTool.FindStringSubmatch(prompt); m != nil {
+		return fmt.Sprintf(`{"tool": %q, "arguments": {}}`, m[1]), nil
+	}
+	return `{"answer": "no tools"}`, nil
+}
+
+// run executes one command line in-process against the config dir in ctx.
+func run(t *testing.T, ctx context.Context, args ...string) (string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	if code := Run(ctx, args, strings.NewReader(""), &stdout, &stderr); code != 0 {
+		t.Fatalf("sre-ai %s: exit %d\n%s", strings.Join(args, " "), code, stderr.String())
+	}
+	return stdout.String(), stderr.String()
+}
+
+func TestTreesInOneProcessKeepTheirConfigApart(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tree := range []struct{ alias, status string }{
+		{"alpha", "checkout is healthy"},
+		{"beta", "payments is degraded"},
+	} {
+		tree := tree
+		t.Run(tree.alias, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			ctx := config.WithDir(context.Background(), dir)
+			ctx = providers.WithFactory(ctx, func(context.Context, string, string) (providers.Client, error) {
+				return fakeModel{}, nil
+			})
+
+			def := filepath.Join(t.TempDir(), "server.json")
+			data, _ := json.Marshal(map[string]interface{}{
+				"command": exe,
+				"env":     map[string]string{fakeMCPEnv: tree.status},
+			})
+			if err := os.WriteFile(def, data, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			run(t, ctx, "mcp", "add", tree.alias+"="+def)
+
+			out, _ := run(t, ctx, "mcp", "ls", "--json")
+			var listed struct {
+				Servers []struct {
+					Alias  string `json:"alias"`
+					Source string `json:"source"`
+				} `json:"servers"`
+			}
+			if err := json.Unmarshal([]byte(out), &listed); err != nil {
+				t.Fatalf("mcp ls --json: %v\n%s", err, out)
+			}
+			var local []string
+			for _, server := range listed.Servers {
+				if server.Source == "local" {
+					local = append(local, server.Alias)
+				}
+			}
+			if len(local) != 1 || local[0] != tree.alias {
+				t.Errorf("local servers = %v, want only %s", local, tree.alias)
+			}
+
+			out, _ = run(t, ctx, "chat", "--tools", tree.alias, "--json", "how is the service?")
+			var transcript chatTranscript
+			if err := json.Unmarshal([]byte(out), &transcript); err != nil {
+				t.Fatalf("chat --json: %v\n%s", err, out)
+			}
+			if transcript.Reply != tree.status || len(transcript.ToolCalls) != 1 || transcript.ToolCalls[0].Server != tree.alias {
+				t.Errorf("reply %q after calls %+v; want %q from %s.status", transcript.Reply, transcript.ToolCalls, tree.status, tree.alias)
+			}
+		})
+	}
+}
+
+func TestFailedCommandsKeepStdoutClean(t *testing.T) {
+	ctx := config.WithDir(context.Background(), t.TempDir())
+	var stdout, stderr bytes.Buffer
+	if code := Run(ctx, []string{"agent", "run", "--json"}, strings.NewReader(""), &stdout, &stderr); code != 1 {
+		t.Fatalf("exit %d, want 1\n%s", code, stderr.String())
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("stdout = %q, want nothing", stdout.String())
+	}
+	if got := stderr.String(); got != "error: --workflow is required\n" {
+		t.Errorf("stderr = %q, want only the error", got)
+	}
+}
+
+func TestHelpMatchesGolden(t *testing.T) {
+	ctx := config.WithDir(context.Background(), t.TempDir())
+	for _, args := range [][]string{
+		{"--help"},
+		{"chat", "--help"},
+		{"mcp", "--help"},
+		{"agent", "run", "--help"},
+	} {
+		name := strings.Join(args[:len(args)-1], "_")
+		if name == "" {
+			name = "root"
+		}
+		t.Run(name, func(t *testing.T) {
+			out, _ := run(t, ctx, args...)
+			golden := filepath.Join("testdata", name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, []byte(out), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run go test ./cmd -update to create it)", err)
+			}
+			if out != string(want) {
+				t.Errorf("sre-ai %s differs from %s (run go test ./cmd -update to accept):\n%s", strings.Join(args, " "), golden, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/example/sre-ai/internal/agent"
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/messages"
+	"github.com/example/sre-ai/internal/notify"
+	"github.com/example/sre-ai/internal/providers"
+	"github.com/example/sre-ai/internal/warnings"
+	"github.com/spf13/cobra"
+)
+
+func newIncidentCmd(opts *config.GlobalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "incident",
+		Short: "Open incidents with PagerDuty or Opsgenie",
+	}
+	cmd.AddCommand(newIncidentPageCmd(opts))
+	return cmd
+}
+
+func newIncidentPageCmd(opts *config.GlobalOptions) *cobra.Command {
+	var page notify.Page
+	var draft string
+	var window time.Duration
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "page",
+		Short: "Page the on-call engineer through PagerDuty or Opsgenie",
+		Long: `Page the on-call engineer through PagerDuty or Opsgenie.
+
+Paging wakes people up, so it needs the page capability (--cap page) and a
+confirmation. Give the title and body yourself, or pass what is known with --draft
+and let the model write them. Each page carries a dedup key, derived from the
+service and title unless --dedup-key is set; a key already paged from this machine
+within --window is not paged again, and the providers fold repeats of an open
+incident into it.`,
+		Example: `  sre-ai incident page --cap page --service checkout --severity critical --title "checkout 5xx above SLO"
+  kubectl describe pod -n payments | sre-ai incident page --cap page --service payments --draft - --dry-run
+  sre-ai incident page --cap page --provider opsgenie --service search --draft "p99 latency 4s since 09:10, rollback did not help"`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			page.Provider = strings.ToLower(strings.TrimSpace(page.Provider))
+			if strings.TrimSpace(page.Title) == "" && draft == "" {
+				return errors.New("--title or --draft is required")
+			}
+			if !opts.DryRun {
+				if !hasCapability(opts, notify.PageCapability) {
+					return fmt.Errorf("incident page pages a human; grant the capability with --cap %s", notify.PageCapability)
+				}
+				if !canConfirm(cmd, opts) {
+					return messages.Error(cmd.Context(), messages.RefusePage)
+				}
+			}
+
+			if strings.TrimSpace(page.Title) == "" {
+				if err := draftIncidentPage(cmd, opts, draft, &page); err != nil {
+					return err
+				}
+			}
+			if page.DedupKey == "" {
+				page.DedupKey = notify.PageDedupKey(page.Service, page.Environment, page.Title)
+			}
+			if err := page.Validate(); err != nil {
+				return err
+			}
+
+			result := notify.PageResult{Page: page}
+			if !force {
+				previous, err := notify.RecentPage(cmd.Context(), page.DedupKey, window, time.Now())
+				if err != nil {
+					return err
+				}
+				if previous != nil {
+					result.Status, result.Previous = notify.PageDeduped, previous
+					return printOutput(cmd, opts, result, formatPageResult(result))
+				}
+			}
+			if opts.DryRun {
+				result.Status = notify.PageDryRun
+				return printOutput(cmd, opts, result, formatPageResult(result))
+			}
+
+			approved, err := confirmerFor(cmd, opts).Confirm(cmd.Context(), agent.PageRequest(cmd.Context(), page))
+			if err != nil {
+				return err
+			}
+			if !approved {
+				return messages.Error(cmd.Context(), messages.PageDeclined)
+			}
+			result, err = notify.SendPage(cmd.Context(), page)
+			if err != nil && result.Status == "" {
+				return err
+			}
+			if err != nil {
+				warnings.Add(cmd.Context(), "page", "%v", err)
+			}
+			return printOutput(cmd, opts, result, formatPageResult(result))
+		},
+	}
+
+	cmd.Flags().StringVar(&page.Provider, "provider", "pagerduty", "Paging provider: pagerduty or opsgenie")
+	cmd.Flags().StringVar(&page.Service, "service", "", "Service the incident is about")
+	cmd.Flags().StringVar(&page.Severity, "severity", "high", "Severity: info, low, warning, medium, high, or critical")
+	cmd.Flags().StringVar(&page.Environment, "env", "", "Environment, e.g. prod")
+	cmd.Flags().StringVar(&page.Title, "title", "", "Incident title (drafted from --draft when empty)")
+	cmd.Flags().StringVar(&page.Body, "body", "", "Incident details (drafted from --draft when empty)")
+	cmd.Flags().StringVar(&page.DedupKey, "dedup-key", "", "Dedup key (default derived from service, environment, and title)")
+	cmd.Flags().StringVar(&page.KeyEnv, "key-env", "", "Environment variable holding the routing or API key (default PAGERDUTY_ROUTING_KEY or OPSGENIE_API_KEY)")
+	cmd.Flags().StringVar(&page.URL, "url", "", "Provider endpoint, e.g. an EU one (default the provider's public API)")
+	cmd.Flags().StringVar(&draft, "draft", "", "What is known about the incident, for the model to write the page from; - reads stdin")
+	cmd.Flags().DurationVar(&window, "window", notify.DefaultPageWindow, "Do not page a dedup key again within this long")
+	cmd.Flags().BoolVar(&force, "force", false, "Page even if the dedup key was paged within --window")
+	return cmd
+}
+
+// draftIncidentPage has the model write the page's title, and body unless --body is set.
+func draftIncidentPage(cmd *cobra.Command, opts *config.GlobalOptions, draft string, page *notify.Page) error {
+	ctx := cmd.Context()
+	if draft == "-" {
+		data, err := io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			return err
+		}
+		draft = string(data)
+	}
+	if strings.TrimSpace(draft) == "" {
+		return errors.New("--draft has nothing to write the page from")
+	}
+	model := opts.ExplicitModel()
+	if model == "" {
+		model = providers.DefaultModel(opts.Provider)
+	}
+	client, err := providers.New(ctx, opts.Provider, model)
+	if err != nil {
+		return err
+	}
+	reply, err := client.Generate(cmd.Context(), notify.DraftPrompt(draft))
+	if err != nil {
+		return err
+	}
+	title, body, err := notify.ParseDraft(reply)
+	if err != nil {
+		return err
+	}
+	page.Title = title
+	if page.Body == "" {
+		page.Body = body
+	}
+	return nil
+}
+
+func pageTarget(page notify.Page) string {
+	if page.Service != "" {
+		return page.Service
+	}
+	return "on-call"
+}
+
+func formatPageResult(result notify.PageResult) string {
+	p := result.Page
+	var b strings.Builder
+	switch result.Status {
+	case notify.PagePaged:
+		fmt.Fprintf(&b, "Paged %s via %s", pageTarget(p), p.Provider)
+	case notify.PageDeduped:
+		fmt.Fprintf(&b, "Not paged: %s was already paged at %s", p.DedupKey, result.Previous.Local().Format(time.RFC3339))
+	case notify.PageDryRun:
+		fmt.Fprintf(&b, "Dry-run: would page %s via %s", pageTarget(p), p.Provider)
+	}
+	fmt.Fprintf(&b, "\n  [%s] %s\n  dedup key: %s", strings.ToUpper(p.Severity), p.Title, p.DedupKey)
+	if p.Body != "" {
+		b.WriteString("\n\n" + p.Body)
+	}
+	return b.String()
+}
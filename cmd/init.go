@@ -0,0 +1,350 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/credentials"
+	"github.com/example/sre-ai/internal/mcp"
+	"github.com/example/sre-ai/internal/messages"
+	"github.com/spf13/cobra"
+)
+
+// mcpSuggestion is a local MCP server offered when its companion CLI is installed.
+type mcpSuggestion struct {
+	Alias    string
+	Detect   string
+	Reason   string
+	Server   mcp.ServerDefinition
+	Required []string
+}
+
+var mcpSuggestions = []mcpSuggestion{
+	{
+		Alias:  "github",
+		Detect: "gh",
+		Reason: "GitHub CLI detected",
+		Server: mcp.ServerDefinition{
+			Command: "npx",
+			Args:    []string{"-y", "@modelcontextprotocol/server-github"},
+			Env:     map[string]string{"GITHUB_PERSONAL_ACCESS_TOKEN": ""},
+			Notes:   "set GITHUB_PERSONAL_ACCESS_TOKEN (e.g. from 'gh auth token')",
+		},
+	},
+	{
+		Alias:  "kubernetes",
+		Detect: "kubectl",
+		Reason: "kubectl detected",
+		Server: mcp.ServerDefinition{
+			Command: "npx",
+			Args:    []string{"-y", "mcp-server-kubernetes"},
+			Notes:   "uses the current kubeconfig context",
+		},
+	},
+	{
+		Alias:    "terraform",
+		Detect:   "terraform",
+		Reason:   "terraform detected",
+		Required: []string{"docker"},
+		Server: mcp.ServerDefinition{
+			Command: "docker",
+			Args:    []string{"run", "-i", "--rm", "hashicorp/terraform-mcp-server"},
+			Notes:   "requires docker",
+		},
+	},
+}
+
+// setupWizard reads all answers through one buffered reader so piped answers are not lost.
+type setupWizard struct {
+	cmd    *cobra.Command
+	opts   *config.GlobalOptions
+	reader *bufio.Reader
+	out    io.Writer
+}
+
+func newInitCmd(opts *config.GlobalOptions) *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Interactively set up provider, kubecontext, and MCP servers",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfgPath, err := resolveConfigPath(cmd.Context(), opts)
+			if err != nil {
+				return err
+			}
+			if _, err := os.Stat(cfgPath); err == nil && !force {
+				return fmt.Errorf("config exists at %s (use --force to replace it)", cfgPath)
+			}
+
+			w := &setupWizard{cmd: cmd, opts: opts, reader: bufio.NewReader(cmd.InOrStdin()), out: cmd.OutOrStdout()}
+			if opts.JSON || opts.Quiet {
+				w.out = io.Discard
+			}
+
+			setup := defaultSetup()
+			setup.MCPServers = nil
+			setup.IaCEngine = ""
+			setup.IaCStacks = nil
+
+			fmt.Fprintln(w.out, "sre-ai setup")
+
+			provider, err := w.ask("Model provider", "gemini")
+			if err != nil {
+				return err
+			}
+			setup.Provider = strings.ToLower(provider)
+			if setup.Provider != "gemini" {
+				return fmt.Errorf("unsupported provider %s (supported: gemini)", setup.Provider)
+			}
+			model, err := w.ask("Model", setup.Model)
+			if err != nil {
+				return err
+			}
+			setup.Model = model
+
+			loggedIn := false
+			if _, err := credentials.LoadGeminiKey(cmd.Context()); err == nil {
+				fmt.Fprintln(w.out, "Gemini credentials already stored")
+				loggedIn = true
+			} else if !opts.NoInteractive && !opts.DryRun {
+				login, err := w.confirm("Store a Gemini API key now?", true)
+				if err != nil {
+					return err
+				}
+				if login {
+					if err := w.loginGemini(cmd.Context()); err != nil {
+						return err
+					}
+					loggedIn = true
+				}
+			}
+
+			setup.Kubecontext, setup.Namespace, err = w.chooseKubecontext(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			if _, err := exec.LookPath("terraform"); err == nil {
+				setup.IaCEngine = "terraform"
+				stack, err := w.ask("Terraform stack path for 'prod' (blank to skip)", "")
+				if err != nil {
+					return err
+				}
+				if stack != "" {
+					setup.IaCStacks = map[string]string{"prod": stack}
+				}
+			}
+
+			registered, err := w.offerMCPServers(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			payload := map[string]any{
+				"path":        cfgPath,
+				"provider":    setup.Provider,
+				"model":       setup.Model,
+				"kubecontext": setup.Kubecontext,
+				"namespace":   setup.Namespace,
+				"mcp_servers": registered,
+				"logged_in":   loggedIn,
+			}
+			if opts.DryRun {
+				payload["status"] = "dry-run"
+				payload["config"] = renderConfigYAML(setup)
+				return printOutput(cmd, opts, payload, fmt.Sprintf("Dry-run: would write config to %s\n%s", cfgPath, renderConfigYAML(setup)))
+			}
+
+			if err := config.WriteFile(cfgPath, []byte(renderConfigYAML(setup))); err != nil {
+				return err
+			}
+			for _, alias := range registered {
+				for _, s := range mcpSuggestions {
+					if s.Alias == alias {
+						if err := mcp.AddLocalServer(cmd.Context(), alias, s.Server, "sre-ai init"); err != nil {
+							return err
+						}
+					}
+				}
+			}
+
+			lines := []string{fmt.Sprintf("Wrote config to %s", cfgPath)}
+			if len(registered) > 0 {
+				lines = append(lines, fmt.Sprintf("Registered MCP servers: %s (check with 'sre-ai mcp test <alias>')", strings.Join(registered, ", ")))
+			}
+			if !loggedIn {
+				lines = append(lines, "Run 'sre-ai config login --provider gemini' to add credentials")
+			}
+			return printOutput(cmd, opts, payload, strings.Join(lines, "\n"))
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "Replace an existing config file")
+
+	return cmd
+}
+
+func (w *setupWizard) ask(question, def string) (string, error) {
+	if w.opts.NoInteractive {
+		return def, nil
+	}
+	if def != "" {
+		fmt.Fprintf(w.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(w.out, "%s: ", question)
+	}
+	line, err := w.reader.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return def, nil
+	}
+	return line, nil
+}
+
+func (w *setupWizard) confirm(question string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	answer, err := w.ask(fmt.Sprintf("%s (%s)", question, hint), "")
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(answer) {
+	case "":
+		return def, nil
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+func (w *setupWizard) loginGemini(ctx context.Context) error {
+	fmt.Fprintf(w.out, "Open Gemini API key page to create or view a key:\n  %s\n", geminiAPIKeyURL)
+	if err := openBrowser(geminiAPIKeyURL); err != nil && w.opts.Verbose > 0 {
+		fmt.Fprintf(w.cmd.ErrOrStderr(), "warning: unable to launch browser: %v\n", err)
+	}
+	key, err := w.ask("Paste your Gemini API key", "")
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		fmt.Fprintln(w.out, "No key entered; skipping login")
+		return nil
+	}
+	path, err := credentials.SaveGeminiKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w.out, "Gemini API key stored at %s\n", path)
+	return nil
+}
+
+func (w *setupWizard) chooseKubecontext(ctx context.Context) (string, string, error) {
+	if _, err := exec.LookPath("kubectl"); err != nil {
+		fmt.Fprintln(w.out, "kubectl not found on PATH; skipping Kubernetes context")
+		return "", "", nil
+	}
+
+	contexts, current := discoverKubecontexts(ctx)
+	if len(contexts) == 0 {
+		fmt.Fprintln(w.out, "No kubeconfig contexts found")
+		return "", "", nil
+	}
+
+	fmt.Fprintln(w.out, "Kubernetes contexts:")
+	def := 1
+	for i, name := range contexts {
+		marker := " "
+		if name == current {
+			marker = "*"
+			def = i + 1
+		}
+		fmt.Fprintf(w.out, "  %s %d) %s\n", marker, i+1, name)
+	}
+	answer, err := w.ask("Default kubecontext (number or name)", strconv.Itoa(def))
+	if err != nil {
+		return "", "", err
+	}
+	chosen := answer
+	if idx, err := strconv.Atoi(answer); err == nil {
+		if idx < 1 || idx > len(contexts) {
+			return "", "", fmt.Errorf("kubecontext choice %d out of range", idx)
+		}
+		chosen = contexts[idx-1]
+	}
+
+	namespace, err := w.ask("Default namespace", "default")
+	if err != nil {
+		return "", "", err
+	}
+	return chosen, namespace, nil
+}
+
+func discoverKubecontexts(ctx context.Context) ([]string, string) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "kubectl", "config", "get-contexts", "-o", "name").Output()
+	if err != nil {
+		return nil, ""
+	}
+	var contexts []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			contexts = append(contexts, trimmed)
+		}
+	}
+	current, _ := exec.CommandContext(ctx, "kubectl", "config", "current-context").Output()
+	return contexts, strings.TrimSpace(string(current))
+}
+
+func (w *setupWizard) offerMCPServers(ctx context.Context) ([]string, error) {
+	existing, err := mcp.ListLocalServers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var accepted []string
+	for _, s := range mcpSuggestions {
+		if _, err := exec.LookPath(s.Detect); err != nil {
+			continue
+		}
+		missing := false
+		for _, req := range s.Required {
+			if _, err := exec.LookPath(req); err != nil {
+				missing = true
+			}
+		}
+		if missing {
+			continue
+		}
+		if _, ok := existing[s.Alias]; ok {
+			fmt.Fprintf(w.out, "MCP server %s already registered\n", s.Alias)
+			continue
+		}
+		question := messages.Text(ctx, messages.ConfirmRegisterServer, s.Reason, s.Alias, strings.TrimSpace(s.Server.Command+" "+strings.Join(s.Server.Args, " ")))
+		ok, err := w.confirm(question, true)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			accepted = append(accepted, s.Alias)
+		}
+	}
+	return accepted, nil
+}
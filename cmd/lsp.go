@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/lsp"
+	"github.com/example/sre-ai/internal/mcp"
+	"github.com/spf13/cobra"
+)
+
+func newLSPCmd(opts *config.GlobalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "lsp",
+		Short: "Serve workflow authoring support to editors over stdio JSON-RPC",
+		Long: `Serve workflow authoring support to editors over stdio, speaking the Language
+Server Protocol's JSON-RPC with Content-Length framing.
+
+Open workflow files get diagnostics from agent validate on every change, completion
+of step names and captures inside {{ }} templates (only steps that run earlier),
+inputs, template functions, tool names, tool kinds, step types, MCP aliases, host
+groups, and upload destinations, and hover for tools, steps, and inputs.
+
+Two extra requests serve plugins that want the data itself: sre-ai/validate
+({"uri": ..., "text": ...}) returns the issues and symbols, and sre-ai/tools lists
+the registered MCP servers ({"alias": ...} starts a local server once to list its
+tools).`,
+		Example: `  # Neovim
+  vim.lsp.start({ name = "sre-ai", cmd = { "sre-ai", "lsp" }, root_dir = vim.fn.getcwd() })`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := mcp.Warmup(cmd.Context(), opts); err != nil {
+				return err
+			}
+			return lsp.Serve(cmd.Context(), cmd.InOrStdin(), cmd.OutOrStdout(), opts)
+		},
+	}
+}
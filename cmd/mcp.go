@@ -4,40 +4,62 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/example/sre-ai/internal/config"
 	"github.com/example/sre-ai/internal/mcp"
+	"github.com/example/sre-ai/internal/timefmt"
+	"github.com/example/sre-ai/internal/warnings"
 	"github.com/spf13/cobra"
 )
 
-func newMCPCmd() *cobra.Command {
+func newMCPCmd(opts *config.GlobalOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "mcp",
 		Short: "Manage MCP server integrations",
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			return mcp.Warmup(cmd.Context(), &globalOpts)
+			// Cobra runs only the nearest hook, so load config (runtimes, endpoints) here too.
+			if hook := cmd.Root().PersistentPreRunE; hook != nil {
+				if err := hook(cmd, args); err != nil {
+					return err
+				}
+			}
+			return mcp.Warmup(cmd.Context(), opts)
 		},
 	}
 
-	cmd.AddCommand(newMCPLsCmd())
-	cmd.AddCommand(newMCPAddCmd())
-	cmd.AddCommand(newMCPRmCmd())
-	cmd.AddCommand(newMCPTestCmd())
+	cmd.AddCommand(newMCPLsCmd(opts))
+	cmd.AddCommand(newMCPAddCmd(opts))
+	cmd.AddCommand(newMCPRmCmd(opts))
+	cmd.AddCommand(newMCPTestCmd(opts))
+	cmd.AddCommand(newMCPRunCmd(opts))
 	return cmd
 }
 
-func newMCPLsCmd() *cobra.Command {
+func newMCPLsCmd(opts *config.GlobalOptions) *cobra.Command {
 	return &cobra.Command{
 		Use:   "ls",
 		Short: "List configured MCP servers",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			infos := mcp.DefaultRegistry.Snapshot()
+			infos := mcp.RegistryFrom(cmd.Context()).Snapshot()
+			health, err := mcp.LoadHealth(cmd.Context())
+			if err != nil {
+				warnings.Add(cmd.Context(), "mcp", "restart history unavailable: %v", err)
+			}
+			for i := range infos {
+				if h, ok := health[infos[i].Alias]; ok {
+					infos[i].Health = &h
+				}
+			}
 			payload := map[string]any{"servers": infos}
 
 			var builder strings.Builder
@@ -84,6 +106,16 @@ func newMCPLsCmd() *cobra.Command {
 						builder.WriteString(info.Workdir)
 						builder.WriteString("\n")
 					}
+					if info.EnvPolicy != "" {
+						builder.WriteString("  env policy: ")
+						builder.WriteString(info.EnvPolicy)
+						if len(info.EnvAllow) > 0 {
+							builder.WriteString(" (")
+							builder.WriteString(strings.Join(info.EnvAllow, ", "))
+							builder.WriteString(")")
+						}
+						builder.WriteString("\n")
+					}
 					if len(info.Env) > 0 {
 						keys := make([]string, 0, len(info.Env))
 						for k := range info.Env {
@@ -103,14 +135,28 @@ func newMCPLsCmd() *cobra.Command {
 						builder.WriteString(info.Notes)
 						builder.WriteString("\n")
 					}
+					if info.Health != nil {
+						builder.WriteString(formatServerHealth(*info.Health))
+						builder.WriteString("\n")
+					}
 				}
 			}
-			return printOutput(cmd, payload, strings.TrimSpace(builder.String()))
+			return printOutput(cmd, opts, payload, strings.TrimSpace(builder.String()))
 		},
 	}
 }
 
-func newMCPAddCmd() *cobra.Command {
+// formatServerHealth summarizes a server's restarts by the session watchdog.
+func formatServerHealth(h mcp.Health) string {
+	line := fmt.Sprintf("  restarts: %d in the last 24h, %d total; last %s (%s)",
+		h.Flaps(time.Now().Add(-24*time.Hour)), h.Restarts, timefmt.Ago(h.LastRestart), h.LastReason)
+	if h.LastError != "" {
+		line += fmt.Sprintf("\n  last restart failed: %s", h.LastError)
+	}
+	return line
+}
+
+func newMCPAddCmd(opts *config.GlobalOptions) *cobra.Command {
 	return &cobra.Command{
 		Use:   "add <alias=path>",
 		Short: "Add or update a local MCP server definition",
@@ -124,7 +170,7 @@ func newMCPAddCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
-			if err := mcp.AddLocalServer(alias, def, expandPathForDisplay(path)); err != nil {
+			if err := mcp.AddLocalServer(cmd.Context(), alias, def, expandPathForDisplay(path)); err != nil {
 				return err
 			}
 			payload := map[string]any{
@@ -133,38 +179,48 @@ func newMCPAddCmd() *cobra.Command {
 				"args":    def.Args,
 			}
 			human := fmt.Sprintf("Saved MCP server %s", alias)
-			return printOutput(cmd, payload, human)
+			return printOutput(cmd, opts, payload, human)
 		},
 	}
 }
 
-func newMCPRmCmd() *cobra.Command {
+func newMCPRmCmd(opts *config.GlobalOptions) *cobra.Command {
 	return &cobra.Command{
 		Use:   "rm <alias>",
 		Short: "Remove a configured MCP server",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			alias := args[0]
-			if err := mcp.RemoveLocalServer(alias); err != nil {
+			if err := mcp.RemoveLocalServer(cmd.Context(), alias); err != nil {
 				return err
 			}
 			payload := map[string]any{"alias": alias}
-			return printOutput(cmd, payload, fmt.Sprintf("Removed MCP server %s", alias))
+			return printOutput(cmd, opts, payload, fmt.Sprintf("Removed MCP server %s", alias))
 		},
 	}
 }
 
-func newMCPTestCmd() *cobra.Command {
-	return &cobra.Command{
+func newMCPTestCmd(opts *config.GlobalOptions) *cobra.Command {
+	var sel toolSelection
+
+	cmd := &cobra.Command{
 		Use:   "test <alias>",
 		Short: "Launch a local MCP server to verify configuration",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			alias := args[0]
+			if sel.filter != "" {
+				if _, err := path.Match(sel.filter, ""); err != nil {
+					return fmt.Errorf("--filter %q: %w", sel.filter, err)
+				}
+			}
+			if sel.limit < 0 || sel.offset < 0 {
+				return errors.New("--tools-limit and --tools-offset must not be negative")
+			}
 			ctx, cancel := context.WithTimeout(cmd.Context(), 10*time.Second)
 			defer cancel()
 
-			logger := newMCPLogger(cmd)
+			logger := newMCPLogger(cmd, opts)
 			if logger != nil {
 				logger.Printf("probe start alias=%s", alias)
 			}
@@ -177,14 +233,25 @@ func newMCPTestCmd() *cobra.Command {
 			}
 
 			payload := map[string]any{
-				"alias":            alias,
-				"server_name":      result.ServerName,
-				"server_version":   result.ServerVersion,
-				"protocol_version": result.ProtocolVersion,
-				"capabilities":     result.Capabilities,
-				"tools":            result.Tools,
-				"notifications":    result.Notifications,
-				"duration_ms":      result.Duration.Milliseconds(),
+				"alias":              alias,
+				"server_name":        result.ServerName,
+				"server_version":     result.ServerVersion,
+				"protocol_version":   result.ProtocolVersion,
+				"offered_protocol":   result.OfferedProtocolVersion,
+				"capabilities":       result.Capabilities,
+				"notifications":      result.Notifications,
+				"notification_stats": result.NotificationStats,
+				"duration_ms":        result.Duration.Milliseconds(),
+				"timings_ms":         probeTimingsMillis(result.Timings),
+			}
+			shown, page := sel.apply(result.Tools)
+			payload["tools"] = toolsPayload(shown, sel)
+			payload["tools_page"] = page
+			if result.ResourceCount != nil {
+				payload["resource_count"] = *result.ResourceCount
+			}
+			if result.PromptCount != nil {
+				payload["prompt_count"] = *result.PromptCount
 			}
 			if result.Instructions != "" {
 				payload["instructions"] = result.Instructions
@@ -193,13 +260,132 @@ func newMCPTestCmd() *cobra.Command {
 				payload["stderr"] = result.Stderr
 			}
 
-			human := formatProbeHuman(alias, result)
-			return printOutput(cmd, payload, human)
+			human := formatProbeHuman(alias, result, shown, page, sel.summary)
+			return printOutput(cmd, opts, payload, human)
 		},
 	}
+
+	cmd.Flags().IntVar(&sel.limit, "tools-limit", 0, "Show at most this many tools (0 for all)")
+	cmd.Flags().IntVar(&sel.offset, "tools-offset", 0, "Skip this many matching tools; pair with tools_page.next_offset")
+	cmd.Flags().StringVar(&sel.filter, "filter", "", "Only show tools whose name matches this glob (e.g. 'k8s_*')")
+	cmd.Flags().BoolVar(&sel.summary, "summary", false, "List tool names and titles only, without descriptions or schemas")
+	cmd.Flags().BoolVar(&sel.fullSchemas, "full-schemas", false, fmt.Sprintf("Include input/output schemas larger than %d bytes instead of collapsing them", collapseSchemaBytes))
+
+	return cmd
 }
 
-func formatProbeHuman(alias string, result *mcp.ProbeResult) string {
+// collapseSchemaBytes is the serialized size above which schemas are summarised in
+// mcp test output unless --full-schemas is set.
+const collapseSchemaBytes = 2048
+
+// toolSelection holds the mcp test flags that trim the tool catalog.
+type toolSelection struct {
+	limit       int
+	offset      int
+	filter      string
+	summary     bool
+	fullSchemas bool
+}
+
+// toolPage is the pagination metadata reported next to the selected tools.
+type toolPage struct {
+	Total      int    `json:"total"`
+	Matched    int    `json:"matched"`
+	Offset     int    `json:"offset"`
+	Limit      int    `json:"limit,omitempty"`
+	Returned   int    `json:"returned"`
+	NextOffset *int   `json:"next_offset,omitempty"`
+	Filter     string `json:"filter,omitempty"`
+}
+
+// apply filters tools by name glob (case-insensitive) and cuts out the requested page.
+func (s toolSelection) apply(tools []mcp.ToolSummary) ([]mcp.ToolSummary, toolPage) {
+	page := toolPage{Total: len(tools), Offset: s.offset, Limit: s.limit, Filter: s.filter}
+	matched := tools
+	if s.filter != "" {
+		pattern := strings.ToLower(s.filter)
+		matched = make([]mcp.ToolSummary, 0, len(tools))
+		for _, tool := range tools {
+			if ok, _ := path.Match(pattern, strings.ToLower(tool.Name)); ok {
+				matched = append(matched, tool)
+			}
+		}
+	}
+	page.Matched = len(matched)
+	start := s.offset
+	if start > len(matched) {
+		start = len(matched)
+	}
+	end := len(matched)
+	if s.limit > 0 && start+s.limit < end {
+		end = start + s.limit
+		next := end
+		page.NextOffset = &next
+	}
+	shown := matched[start:end]
+	page.Returned = len(shown)
+	return shown, page
+}
+
+// toolsPayload renders the selected tools for JSON output: names and titles only under
+// --summary, otherwise full entries with oversized schemas collapsed.
+func toolsPayload(tools []mcp.ToolSummary, sel toolSelection) []any {
+	out := make([]any, 0, len(tools))
+	for _, tool := range tools {
+		// The hash covers the full schemas, so it is taken before they are collapsed.
+		hash := tool.SchemaHash()
+		if sel.summary {
+			entry := map[string]any{"name": tool.Name, "schemaHash": hash}
+			if tool.Title != "" {
+				entry["title"] = tool.Title
+			}
+			out = append(out, entry)
+			continue
+		}
+		if !sel.fullSchemas {
+			tool.InputSchema = collapseSchema(tool.InputSchema)
+			tool.OutputSchema = collapseSchema(tool.OutputSchema)
+		}
+		out = append(out, struct {
+			mcp.ToolSummary
+			SchemaHash string `json:"schemaHash"`
+		}{tool, hash})
+	}
+	return out
+}
+
+// collapseSchema replaces a large JSON schema with its size and top-level property
+// names, which is usually enough to pick a tool without scrolling past the schema.
+func collapseSchema(schema map[string]interface{}) map[string]interface{} {
+	if len(schema) == 0 {
+		return schema
+	}
+	data, err := json.Marshal(schema)
+	if err != nil || len(data) <= collapseSchemaBytes {
+		return schema
+	}
+	collapsed := map[string]interface{}{
+		"collapsed": true,
+		"bytes":     len(data),
+	}
+	if t, ok := schema["type"]; ok {
+		collapsed["type"] = t
+	}
+	if props, ok := schema["properties"].(map[string]interface{}); ok {
+		names := make([]string, 0, len(props))
+		for name := range props {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		collapsed["properties"] = names
+	}
+	if required, ok := schema["required"]; ok {
+		collapsed["required"] = required
+	}
+	return collapsed
+}
+
+func formatProbeHuman(alias string, result *mcp.ProbeResult, shown []mcp.ToolSummary, page toolPage, summary bool) string {
 	var builder strings.Builder
 
 	header := alias
@@ -217,15 +403,36 @@ func formatProbeHuman(alias string, result *mcp.ProbeResult) string {
 		protocol = "unknown"
 	}
 	toolsCount := len(result.Tools)
+	if result.OfferedProtocolVersion != "" && result.OfferedProtocolVersion != result.ProtocolVersion {
+		protocol = fmt.Sprintf("%s (offered %s, server countered)", protocol, result.OfferedProtocolVersion)
+	}
 	builder.WriteString(fmt.Sprintf("Protocol %s - %d tool", protocol, toolsCount))
 	if toolsCount != 1 {
 		builder.WriteString("s")
 	}
 	if result.Duration > 0 {
-		builder.WriteString(fmt.Sprintf(" - %s", result.Duration.Round(10*time.Millisecond)))
+		builder.WriteString(fmt.Sprintf(" - %s", timefmt.Duration(result.Duration)))
 	}
 	builder.WriteString("\n")
 
+	if latency := describeProbeTimings(result.Timings); latency != "" {
+		builder.WriteString("Latency: ")
+		builder.WriteString(latency)
+		builder.WriteString("\n")
+	}
+	if result.ResourceCount != nil || result.PromptCount != nil {
+		var counts []string
+		if result.ResourceCount != nil {
+			counts = append(counts, fmt.Sprintf("%d resources", *result.ResourceCount))
+		}
+		if result.PromptCount != nil {
+			counts = append(counts, fmt.Sprintf("%d prompts", *result.PromptCount))
+		}
+		builder.WriteString("Catalog: ")
+		builder.WriteString(strings.Join(counts, ", "))
+		builder.WriteString("\n")
+	}
+
 	caps := describeCapabilities(result.Capabilities)
 	if len(caps) == 0 {
 		builder.WriteString("Capabilities: none reported\n")
@@ -241,8 +448,16 @@ func formatProbeHuman(alias string, result *mcp.ProbeResult) string {
 	if toolsCount == 0 {
 		builder.WriteString("Tools: none reported\n")
 	} else {
-		builder.WriteString("Tools:\n")
-		for _, tool := range result.Tools {
+		builder.WriteString("Tools")
+		if page.Returned < page.Total {
+			builder.WriteString(fmt.Sprintf(" (showing %d of %d", page.Returned, page.Matched))
+			if page.Filter != "" {
+				builder.WriteString(fmt.Sprintf(" matching %q", page.Filter))
+			}
+			builder.WriteString(")")
+		}
+		builder.WriteString(":\n")
+		for _, tool := range shown {
 			display := tool.Title
 			if display == "" {
 				display = tool.Name
@@ -254,12 +469,19 @@ func formatProbeHuman(alias string, result *mcp.ProbeResult) string {
 			if desc == "" {
 				desc = "(no description)"
 			}
+			if summary {
+				builder.WriteString(fmt.Sprintf("  - %s\n", display))
+				continue
+			}
 			builder.WriteString(fmt.Sprintf("  - %s: %s\n", display, desc))
 			if len(tool.InputSchema) > 0 {
 				builder.WriteString("    schema: ")
 				builder.WriteString(compactPreview(tool.InputSchema))
 				builder.WriteString("\n")
 			}
+			builder.WriteString("    pin: ")
+			builder.WriteString(tool.Name + "@" + tool.SchemaHash())
+			builder.WriteString("\n")
 		}
 	}
 
@@ -277,7 +499,12 @@ func formatProbeHuman(alias string, result *mcp.ProbeResult) string {
 	}
 
 	if len(result.Notifications) > 0 {
-		builder.WriteString("Notifications:\n")
+		stats := result.NotificationStats
+		builder.WriteString("Notifications")
+		if stats.Coalesced > 0 || stats.Dropped > 0 {
+			builder.WriteString(fmt.Sprintf(" (%d received, %d repeats coalesced, %d dropped)", stats.Received, stats.Coalesced, stats.Dropped))
+		}
+		builder.WriteString(":\n")
 		for _, note := range result.Notifications {
 			builder.WriteString("  - ")
 			builder.WriteString(note.Method)
@@ -285,6 +512,9 @@ func formatProbeHuman(alias string, result *mcp.ProbeResult) string {
 				builder.WriteString(": ")
 				builder.WriteString(note.Detail)
 			}
+			if note.Count > 1 {
+				builder.WriteString(fmt.Sprintf(" (x%d)", note.Count))
+			}
 			builder.WriteString("\n")
 		}
 	}
@@ -305,6 +535,46 @@ func formatProbeHuman(alias string, result *mcp.ProbeResult) string {
 	return strings.TrimRight(builder.String(), "\n")
 }
 
+func probeTimingsMillis(t mcp.ProbeTimings) map[string]float64 {
+	ms := func(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+	out := map[string]float64{
+		"spawn":      ms(t.Spawn),
+		"initialize": ms(t.Initialize),
+		"tools_list": ms(t.ToolsList),
+	}
+	if t.ResourcesList > 0 {
+		out["resources_list"] = ms(t.ResourcesList)
+	}
+	if t.PromptsList > 0 {
+		out["prompts_list"] = ms(t.PromptsList)
+	}
+	if t.Ping > 0 {
+		out["ping"] = ms(t.Ping)
+	}
+	return out
+}
+
+func describeProbeTimings(t mcp.ProbeTimings) string {
+	phases := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"spawn", t.Spawn},
+		{"initialize", t.Initialize},
+		{"tools/list", t.ToolsList},
+		{"resources/list", t.ResourcesList},
+		{"prompts/list", t.PromptsList},
+		{"ping", t.Ping},
+	}
+	parts := make([]string, 0, len(phases))
+	for _, p := range phases {
+		if p.d > 0 {
+			parts = append(parts, fmt.Sprintf("%s %s", p.name, timefmt.Duration(p.d)))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
 func describeCapabilities(caps map[string]interface{}) []string {
 	if len(caps) == 0 {
 		return nil
@@ -372,8 +642,109 @@ func compactPreview(value interface{}) string {
 	return string(data)
 }
 
-func newMCPLogger(cmd *cobra.Command) mcp.Logger {
-	if globalOpts.Verbose == 0 {
+func newMCPRunCmd(opts *config.GlobalOptions) *cobra.Command {
+	var (
+		stdinFile string
+		envPairs  []string
+		workdir   string
+		timeout   time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "run <alias> [-- args...]",
+		Short: "Run a local MCP server command the way a workflow tool would",
+		Long: `Run executes the configured command for <alias> with any arguments after "--",
+mirroring a workflow step of kind mcp. The command's stdout and stderr are passed
+through and its exit code becomes the CLI's exit code.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			alias := args[0]
+			extraArgs := args[1:]
+
+			stdin := ""
+			switch stdinFile {
+			case "":
+			case "-":
+				data, err := io.ReadAll(cmd.InOrStdin())
+				if err != nil {
+					return fmt.Errorf("read stdin: %w", err)
+				}
+				stdin = string(data)
+			default:
+				data, err := os.ReadFile(stdinFile)
+				if err != nil {
+					return fmt.Errorf("read stdin file: %w", err)
+				}
+				stdin = string(data)
+			}
+
+			env := make(map[string]string, len(envPairs))
+			for _, pair := range envPairs {
+				key, value, ok := strings.Cut(pair, "=")
+				if !ok || strings.TrimSpace(key) == "" {
+					return fmt.Errorf("invalid --env %q, expected KEY=VALUE", pair)
+				}
+				env[strings.TrimSpace(key)] = value
+			}
+
+			ctx := cmd.Context()
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+
+			stdout, stderr, code, _, runErr := mcp.RunLocalCommandWithOptions(ctx, alias, mcp.RunOptions{
+				Args:    extraArgs,
+				Stdin:   stdin,
+				Env:     env,
+				Workdir: workdir,
+			}, newMCPLogger(cmd, opts))
+			if runErr != nil && code == 0 {
+				return runErr
+			}
+
+			payload := map[string]any{
+				"alias":     alias,
+				"args":      extraArgs,
+				"exit_code": code,
+				"stdout":    stdout,
+				"stderr":    stderr,
+			}
+			var parsed any
+			if raw := strings.TrimSpace(stdout); raw != "" && json.Unmarshal([]byte(raw), &parsed) == nil {
+				payload["json"] = parsed
+			}
+			if opts.JSON {
+				if err := printOutput(cmd, opts, payload, ""); err != nil {
+					return err
+				}
+			} else {
+				if err := writeJSONFile(cmd, opts, payload); err != nil {
+					return err
+				}
+				fmt.Fprint(cmd.OutOrStdout(), stdout)
+				fmt.Fprint(cmd.ErrOrStderr(), stderr)
+			}
+
+			if code != 0 {
+				// The command already reported its failure; only the status is passed on.
+				cmd.SilenceUsage = true
+				cmd.SilenceErrors = true
+				return &exitCodeError{code: code}
+			}
+			return nil
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&stdinFile, "stdin-file", "", "File to feed to the command's stdin (- reads this process's stdin)")
+	flags.StringArrayVar(&envPairs, "env", nil, "Environment override KEY=VALUE (repeatable)")
+	flags.StringVar(&workdir, "workdir", "", "Run in this directory instead of the definition's workdir (placeholders allowed)")
+	flags.DurationVar(&timeout, "timeout", 0, "Kill the command after this long (0 waits indefinitely)")
+	return cmd
+}
+
+func newMCPLogger(cmd *cobra.Command, opts *config.GlobalOptions) mcp.Logger {
+	if opts.Verbose == 0 {
 		return nil
 	}
 
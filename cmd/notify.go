@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/notify"
+	"github.com/example/sre-ai/internal/warnings"
+	"github.com/spf13/cobra"
+)
+
+func newNotifyCmd(opts *config.GlobalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "notify",
+		Short: "Route notifications to Slack, email, or PagerDuty by severity, service, and environment",
+		Long: `Route notifications to Slack, email, or PagerDuty by severity, service, and environment.
+
+Routes and channels are declared in notify.yaml in the config dir. Completed
+diagnoses with findings, and agent runs that fail or need review, are sent through
+them; notify send sends an event by hand, e.g. from a script or cron job.`,
+	}
+	cmd.AddCommand(newNotifyCheckCmd(opts))
+	cmd.AddCommand(newNotifySendCmd(opts))
+	return cmd
+}
+
+func newNotifyCheckCmd(opts *config.GlobalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "check",
+		Short: "Validate notify.yaml and list its routes",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			n, path, err := loadNotifier(cmd.Context())
+			if err != nil {
+				return err
+			}
+			var buf strings.Builder
+			fmt.Fprintf(&buf, "%s is valid\n", path)
+			tw := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "ROUTE\tMATCH\tCHANNELS\tDEDUPE\tQUIET HOURS")
+			for i, route := range n.Config.Routes {
+				name := route.Name
+				if name == "" {
+					name = fmt.Sprintf("#%d", i+1)
+				}
+				channels := strings.Join(route.Channels, ",")
+				if route.Continue {
+					channels += " (continue)"
+				}
+				dedupe := route.Dedupe
+				if dedupe == "" {
+					dedupe = n.Config.Dedupe
+				}
+				if dedupe == "" {
+					dedupe = notify.DefaultDedupe.String()
+				}
+				quiet := "-"
+				if n.Config.QuietHours != nil {
+					quiet = "held"
+					if route.QuietHours != nil && !*route.QuietHours {
+						quiet = "ignored"
+					}
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", name, describeMatch(route.Match), channels, dedupe, quiet)
+			}
+			tw.Flush()
+			payload := map[string]any{"path": path, "config": n.Config}
+			return printOutput(cmd, opts, payload, strings.TrimRight(buf.String(), "\n"))
+		},
+	}
+}
+
+func newNotifySendCmd(opts *config.GlobalOptions) *cobra.Command {
+	var ev notify.Event
+
+	cmd := &cobra.Command{
+		Use:   "send",
+		Short: "Send an event through the notify routes",
+		Example: `  sre-ai notify send --severity critical --service checkout --env prod --title "checkout 5xx above SLO"
+  sre-ai notify send --severity warning --title "disk 85% on db-1" --dry-run`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if strings.TrimSpace(ev.Title) == "" {
+				return errors.New("--title is required")
+			}
+			n, _, err := loadNotifier(cmd.Context())
+			if err != nil {
+				return err
+			}
+			deliveries, err := n.Notify(cmd.Context(), ev, opts.DryRun)
+			if err != nil {
+				return err
+			}
+			payload := map[string]any{"event": ev, "deliveries": deliveries, "dry_run": opts.DryRun}
+			if err := printOutput(cmd, opts, payload, formatDeliveries(deliveries)); err != nil {
+				return err
+			}
+			for _, d := range deliveries {
+				if d.Status == notify.StatusFailed {
+					return fmt.Errorf("notification to %s failed: %s", d.Channel, d.Reason)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&ev.Severity, "severity", "warning", "Event severity: info, low, warning, medium, high, or critical")
+	cmd.Flags().StringVar(&ev.Service, "service", "", "Service the event is about")
+	cmd.Flags().StringVar(&ev.Environment, "env", "", "Environment, e.g. prod (default notify.yaml environment)")
+	cmd.Flags().StringVar(&ev.Title, "title", "", "One-line headline")
+	cmd.Flags().StringVar(&ev.Text, "text", "", "Details")
+	cmd.Flags().StringVar(&ev.Kind, "kind", "manual", "Event kind routes match on")
+	cmd.Flags().StringVar(&ev.Key, "key", "", "Dedupe key (default kind, service, environment, and title)")
+	return cmd
+}
+
+// loadNotifier reads notify.yaml for the notify commands, where a missing file is an error.
+func loadNotifier(ctx context.Context) (*notify.Notifier, string, error) {
+	path, err := notify.Path(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	n, err := notify.Load(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, path, fmt.Errorf("no notification routes configured; create %s", path)
+	}
+	return n, path, err
+}
+
+// notifyEvent sends an event produced by another command. Notifications are best
+// effort: without notify.yaml they are off, and failures only become warnings.
+func notifyEvent(cmd *cobra.Command, opts *config.GlobalOptions, ev notify.Event) []notify.Delivery {
+	ctx := cmd.Context()
+	n, err := notify.LoadDefault(ctx)
+	if err != nil {
+		warnings.Add(cmd.Context(), "notify", "notifications not sent: %v", err)
+		return nil
+	}
+	if n == nil {
+		return nil
+	}
+	deliveries, err := n.Notify(cmd.Context(), ev, opts.DryRun)
+	if err != nil {
+		warnings.Add(cmd.Context(), "notify", "%v", err)
+	}
+	for _, d := range deliveries {
+		if d.Status == notify.StatusFailed {
+			warnings.Add(cmd.Context(), "notify", "notification to %s failed: %s", d.Channel, d.Reason)
+		}
+	}
+	return deliveries
+}
+
+func formatDeliveries(deliveries []notify.Delivery) string {
+	if len(deliveries) == 0 {
+		return "No route matched; nothing sent"
+	}
+	lines := make([]string, 0, len(deliveries))
+	for _, d := range deliveries {
+		line := fmt.Sprintf("%s via route %s: %s", d.Channel, d.Route, strings.ReplaceAll(d.Status, "_", " "))
+		if d.Reason != "" {
+			line += " (" + d.Reason + ")"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func describeMatch(m notify.Match) string {
+	var parts []string
+	add := func(name string, values []string) {
+		if len(values) > 0 {
+			parts = append(parts, name+"="+strings.Join(values, "|"))
+		}
+	}
+	add("kind", m.Kind)
+	add("severity", m.Severity)
+	if m.MinSeverity != "" {
+		parts = append(parts, "severity>="+m.MinSeverity)
+	}
+	add("service", m.Service)
+	add("env", m.Environment)
+	if len(parts) == 0 {
+		return "*"
+	}
+	return strings.Join(parts, " ")
+}
@@ -1,24 +1,126 @@
 package cmd
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/warnings"
 	"github.com/spf13/cobra"
 )
 
-func printOutput(cmd *cobra.Command, payload any, human string) error {
-	if globalOpts.JSON {
-		data, err := json.MarshalIndent(payload, "", "  ")
+// maxFooterWarnings caps the warnings listed under human output; --json has them all.
+const maxFooterWarnings = 5
+
+func printOutput(cmd *cobra.Command, opts *config.GlobalOptions, payload any, human string) error {
+	collector := warnings.FromContext(cmd.Context())
+	var pending []warnings.Warning
+	if opts.JSON || (!opts.Quiet && human != "") {
+		pending = collector.Take()
+	} else {
+		// Nothing reaches the terminal here, so Run prints them to stderr at the end.
+		pending = collector.Pending()
+	}
+
+	if opts.JSON {
+		data, err := marshalPayload(payload, pending)
 		if err != nil {
 			return err
 		}
 		fmt.Fprintln(cmd.OutOrStdout(), string(data))
-		return nil
+	} else if !opts.Quiet && human != "" {
+		fmt.Fprintln(cmd.OutOrStdout(), human+formatWarningsFooter(pending))
 	}
+	// The terminal output comes first so a bad --json-file path does not hide the result.
+	return writeJSONPayload(opts, payload, pending)
+}
+
+// writeJSONFile saves the structured payload to --json-file, if set, independently of
+// what goes to the terminal. Commands that bypass printOutput call it directly.
+func writeJSONFile(cmd *cobra.Command, opts *config.GlobalOptions, payload any) error {
+	return writeJSONPayload(opts, payload, warnings.FromContext(cmd.Context()).Pending())
+}
 
-	if !globalOpts.Quiet && human != "" {
-		fmt.Fprintln(cmd.OutOrStdout(), human)
+func writeJSONPayload(opts *config.GlobalOptions, payload any, list []warnings.Warning) error {
+	if opts.JSONFile == "" {
+		return nil
+	}
+	data, err := marshalPayload(payload, list)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(opts.JSONFile, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write --json-file: %w", err)
 	}
 	return nil
 }
+
+// marshalPayload indents payload as JSON. Warnings are added as a top-level "warnings"
+// field after the payload's own fields when it is an object.
+func marshalPayload(payload any, list []warnings.Warning) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) > 0 && len(data) >= 2 && data[0] == '{' {
+		extra, err := json.Marshal(list)
+		if err != nil {
+			return nil, err
+		}
+		spliced := append([]byte(nil), data[:len(data)-1]...)
+		if len(data) > 2 {
+			spliced = append(spliced, ',')
+		}
+		spliced = append(spliced, `"warnings":`...)
+		spliced = append(spliced, extra...)
+		data = append(spliced, '}')
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// formatWarningsFooter summarises warnings below human output, folding repeats.
+func formatWarningsFooter(list []warnings.Warning) string {
+	if len(list) == 0 {
+		return ""
+	}
+	var lines []string
+	counts := make(map[string]int)
+	for _, w := range list {
+		line := w.String()
+		if counts[line] == 0 {
+			lines = append(lines, line)
+		}
+		counts[line]++
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("\n\nWarnings (%d):", len(list)))
+	for idx, line := range lines {
+		if idx == maxFooterWarnings {
+			builder.WriteString(fmt.Sprintf("\n  ... and %d more (use --json to see all)", len(lines)-idx))
+			break
+		}
+		builder.WriteString("\n  - ")
+		builder.WriteString(line)
+		if n := counts[line]; n > 1 {
+			builder.WriteString(fmt.Sprintf(" (x%d)", n))
+		}
+	}
+	return builder.String()
+}
+
+// flushWarnings prints warnings that no output reported, e.g. because the command
+// failed, streamed its own output, or ran with --quiet.
+func flushWarnings(c *warnings.Collector, w io.Writer) {
+	for _, warning := range c.Take() {
+		fmt.Fprintf(w, "warning: %s\n", warning)
+	}
+}
@@ -4,20 +4,21 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/example/sre-ai/internal/config"
 	"github.com/spf13/cobra"
 )
 
-func newPlanCmd() *cobra.Command {
+func newPlanCmd(opts *config.GlobalOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "plan",
 		Short: "Plan infrastructure or operational changes",
 	}
 
-	cmd.AddCommand(newPlanIacCmd())
+	cmd.AddCommand(newPlanIacCmd(opts))
 	return cmd
 }
 
-func newPlanIacCmd() *cobra.Command {
+func newPlanIacCmd(opts *config.GlobalOptions) *cobra.Command {
 	var stack string
 
 	cmd := &cobra.Command{
@@ -32,7 +33,7 @@ func newPlanIacCmd() *cobra.Command {
 				},
 			}
 			human := fmt.Sprintf("IaC plan ready for stack %s", stack)
-			return printOutput(cmd, payload, human)
+			return printOutput(cmd, opts, payload, human)
 		},
 	}
 
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/example/sre-ai/internal/agent"
+	"github.com/example/sre-ai/internal/state"
+	"github.com/example/sre-ai/internal/timefmt"
+)
+
+// formatProfile renders a run profile as a table, most costly step first, with each
+// step's share of the sorted cost.
+func formatProfile(p *agent.Profile) string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "Profile by %s (%d steps):\n", p.SortedBy, len(p.Steps))
+	if len(p.Steps) == 0 {
+		buf.WriteString("  no steps ran")
+		return buf.String()
+	}
+	tw := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "  STEP\tTYPE\tSTATUS\tTIME\tCPU\tTOKENS\tOUTPUT\tSHARE")
+	for _, step := range p.Steps {
+		name := step.StageID + "/" + step.StepName
+		if step.Attempt > 0 {
+			name += fmt.Sprintf(" (attempt %d)", step.Attempt+1)
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", name, step.Type, step.Status, profileCells(step.Cost, profileShare(p, step.Cost)))
+	}
+	fmt.Fprintf(tw, "  total\t\t\t%s\n", profileCells(p.Total, ""))
+	tw.Flush()
+	return strings.TrimRight(buf.String(), "\n")
+}
+
+func profileCells(c agent.Cost, share string) string {
+	cpu, tokens, output := "-", "-", "-"
+	if c.CPUTime > 0 {
+		cpu = timefmt.Duration(c.CPUTime)
+	}
+	if c.Tokens > 0 {
+		tokens = fmt.Sprint(c.Tokens)
+	}
+	if c.OutputBytes > 0 {
+		output = state.FormatSize(c.OutputBytes)
+	}
+	cells := []string{timefmt.Duration(c.Duration), cpu, tokens, output}
+	if share != "" {
+		cells = append(cells, share)
+	}
+	return strings.Join(cells, "\t")
+}
+
+// profileShare is the step's percentage of the run total in the sorted cost.
+func profileShare(p *agent.Profile, c agent.Cost) string {
+	var part, total float64
+	switch p.SortedBy {
+	case "tokens":
+		part, total = float64(c.Tokens), float64(p.Total.Tokens)
+	case "cpu":
+		part, total = float64(c.CPUTime), float64(p.Total.CPUTime)
+	case "output":
+		part, total = float64(c.OutputBytes), float64(p.Total.OutputBytes)
+	default:
+		part, total = float64(c.Duration), float64(p.Total.Duration)
+	}
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", part/total*100)
+}
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/example/sre-ai/internal/agent"
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/prompts"
+	"github.com/example/sre-ai/internal/providers"
+	"github.com/example/sre-ai/internal/templatex"
+	"github.com/spf13/cobra"
+)
+
+func newPromptsCmd(opts *config.GlobalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prompts",
+		Short: "List, show, and run reusable prompt snippets",
+		Long: `List, show, and run reusable prompt snippets for one-off asks that do not need a
+workflow, such as summarizing an incident channel or drafting a change request.
+
+Built-in prompts cover summarize-incident-channel, change-request, and explain-alert.
+YAML files in <config dir>/prompts add prompts or replace built-ins with the same name.`,
+	}
+	cmd.AddCommand(newPromptsLsCmd(opts))
+	cmd.AddCommand(newPromptsShowCmd(opts))
+	cmd.AddCommand(newPromptsRunCmd(opts))
+	return cmd
+}
+
+func newPromptsLsCmd(opts *config.GlobalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "ls",
+		Short: "List the loaded prompts",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			lib, err := prompts.LoadDefault(cmd.Context())
+			if err != nil {
+				return err
+			}
+			list := lib.Prompts()
+
+			var buf strings.Builder
+			tw := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "NAME\tVARS\tSOURCE\tDESCRIPTION")
+			for _, p := range list {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", p.Name, promptVarNames(p), p.Source, p.Description)
+			}
+			tw.Flush()
+			return printOutput(cmd, opts, map[string]any{"prompts": list}, strings.TrimRight(buf.String(), "\n"))
+		},
+	}
+}
+
+func newPromptsShowCmd(opts *config.GlobalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "show <name>",
+		Short: "Show a prompt's vars and template",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := loadPrompt(cmd, args[0])
+			if err != nil {
+				return err
+			}
+			return printOutput(cmd, opts, p, formatPrompt(p))
+		},
+	}
+}
+
+func newPromptsRunCmd(opts *config.GlobalOptions) *cobra.Command {
+	var varPairs []string
+	var inputPath string
+
+	cmd := &cobra.Command{
+		Use:   "run <name>",
+		Short: "Render a prompt with --var values and send it to the model",
+		Long: `Render a prompt with --var values and send it to the model. --input supplies the
+text a prompt reads, such as a channel export; "-" reads stdin. Under --dry-run the
+rendered prompt is printed instead of sent.`,
+		Example: `  slack-export incident-4711 | sre-ai prompts run summarize-incident-channel --input - --var service=checkout
+  sre-ai prompts run change-request --var service=payments --var change="Raise the pool size to 64"`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := loadPrompt(cmd, args[0])
+			if err != nil {
+				return err
+			}
+			values, err := agent.ParseInputPairs(varPairs)
+			if err != nil {
+				return err
+			}
+			var input string
+			if inputPath != "" {
+				var data []byte
+				if inputPath == "-" {
+					data, err = io.ReadAll(cmd.InOrStdin())
+				} else {
+					data, err = os.ReadFile(inputPath)
+				}
+				if err != nil {
+					return err
+				}
+				input = string(data)
+			}
+			limits, err := templatex.LimitsFor(opts)
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			text, err := p.Render(values, input, limits)
+			if err != nil {
+				return err
+			}
+
+			model := opts.ExplicitModel()
+			if model == "" {
+				model = providers.DefaultModel(opts.Provider)
+			}
+			payload := map[string]any{
+				"prompt":   p.Name,
+				"model":    model,
+				"rendered": text,
+			}
+			if opts.DryRun {
+				payload["status"] = "dry-run"
+				return printOutput(cmd, opts, payload, fmt.Sprintf("Dry-run: would send prompt %s to %s:\n\n%s", p.Name, opts.Provider, text))
+			}
+
+			client, err := providers.New(cmd.Context(), opts.Provider, model)
+			if err != nil {
+				return err
+			}
+			reply, err := client.Generate(cmd.Context(), text)
+			if err != nil {
+				return err
+			}
+			payload["reply"] = reply
+			return printOutput(cmd, opts, payload, reply)
+		},
+	}
+	cmd.Flags().StringArrayVar(&varPairs, "var", nil, "Prompt var as key=value (repeatable)")
+	cmd.Flags().StringVar(&inputPath, "input", "", "File with the text the prompt reads; - reads stdin")
+	return cmd
+}
+
+func loadPrompt(cmd *cobra.Command, name string) (prompts.Prompt, error) {
+	lib, err := prompts.LoadDefault(cmd.Context())
+	if err != nil {
+		return prompts.Prompt{}, err
+	}
+	p, ok := lib.Get(name)
+	if !ok {
+		return prompts.Prompt{}, fmt.Errorf("no prompt named %s; see sre-ai prompts ls", name)
+	}
+	return p, nil
+}
+
+func promptVarNames(p prompts.Prompt) string {
+	if len(p.Vars) == 0 {
+		return "-"
+	}
+	names := make([]string, 0, len(p.Vars))
+	for _, v := range p.Vars {
+		name := v.Name
+		if v.Required {
+			name += "*"
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, ",")
+}
+
+func formatPrompt(p prompts.Prompt) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (%s)", p.Name, p.Source)
+	if p.Description != "" {
+		b.WriteString("\n" + p.Description)
+	}
+	if len(p.Vars) > 0 {
+		b.WriteString("\n\nVars:")
+		for _, v := range p.Vars {
+			fmt.Fprintf(&b, "\n  %s", v.Name)
+			switch {
+			case v.Required:
+				b.WriteString(" (required)")
+			case v.Default != "":
+				fmt.Fprintf(&b, " (default %q)", v.Default)
+			}
+			if v.Description != "" {
+				b.WriteString(": " + v.Description)
+			}
+		}
+	}
+	if p.Input != "" {
+		fmt.Fprintf(&b, "\n\nInput: %s (--input)", p.Input)
+	}
+	b.WriteString("\n\nTemplate:\n" + strings.TrimRight(p.Template, "\n"))
+	return b.String()
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/runs"
+	"github.com/spf13/cobra"
+)
+
+func newQueryCmd(opts *config.GlobalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "query <sql>",
+		Short: "Query run history with SQL",
+		Long: `Query run history with a read-only SQL statement against its SQLite index.
+
+The index mirrors the run records and is brought up to date before each query.
+Tables:
+
+  runs    id, kind, workflow, principal, workflow_path, status, started_at,
+          finished_at, duration_ms, error, summary, resolution, ratings,
+          mean_score, prompt_tokens, output_tokens
+  steps   run_id, seq, stage, step, type, status, error, attempt, duration_ms,
+          prompt_tokens, output_tokens
+  events  run_id, at, type (started, finished, rated, resolved), actor, detail
+
+Timestamps are UTC text such as 2024-05-01T09:30:00.000Z, so they compare as
+strings; SQLite's datetime('now', '-7 days') gives the same shape after
+strftime('%Y-%m-%dT%H:%M:%fZ', ...).`,
+		Example: `  sre-ai query "SELECT workflow, count(*) AS failures FROM runs WHERE status = 'error' GROUP BY workflow ORDER BY failures DESC"
+  sre-ai query "SELECT stage, step, avg(duration_ms) FROM steps GROUP BY stage, step ORDER BY 3 DESC LIMIT 10" --json`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			statement := strings.TrimSpace(strings.Join(args, " "))
+			if statement == "" {
+				return errors.New("query is empty")
+			}
+			idx, err := runs.OpenIndex(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer idx.Close()
+			result, err := idx.Query(cmd.Context(), statement)
+			if err != nil {
+				return fmt.Errorf("query: %w", err)
+			}
+			return printOutput(cmd, opts, result, formatQueryResult(result))
+		},
+	}
+	return cmd
+}
+
+func formatQueryResult(result *runs.QueryResult) string {
+	if len(result.Rows) == 0 {
+		return "No rows"
+	}
+	var buf strings.Builder
+	tw := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(result.Columns, "\t")))
+	for _, row := range result.Rows {
+		cells := make([]string, len(row))
+		for i, v := range row {
+			switch v := v.(type) {
+			case nil:
+				cells[i] = "-"
+			case float64:
+				cells[i] = fmt.Sprintf("%.4g", v)
+			default:
+				cells[i] = truncateLine(fmt.Sprint(v), 80)
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	tw.Flush()
+	fmt.Fprintf(&buf, "(%d row(s))", len(result.Rows))
+	return buf.String()
+}
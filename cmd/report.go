@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/runs"
+	"github.com/example/sre-ai/internal/sparkline"
+	"github.com/example/sre-ai/internal/timefmt"
+	"github.com/example/sre-ai/internal/timeparse"
+	"github.com/spf13/cobra"
+)
+
+func newReportCmd(opts *config.GlobalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Summarise run history",
+	}
+	cmd.AddCommand(newReportUsageCmd(opts))
+	return cmd
+}
+
+func newReportUsageCmd(opts *config.GlobalOptions) *cobra.Command {
+	var window string
+	var workflow string
+	var top int
+
+	cmd := &cobra.Command{
+		Use:   "usage",
+		Short: "Show how often each workflow ran, how it fared, and what it cost",
+		Long: `Show how often each workflow ran, how it fared, and what it cost.
+
+For every workflow with agent runs in the window the report lists the run count,
+success rate (completed over finished runs), mean duration, tokens spent by prompt
+steps, and the steps that failed most often. Token counts come from the provider
+when it reports them; runs recorded before usage tracking count as zero, and
+estimated counts are marked with "~".`,
+		Example: `  sre-ai report usage --window 30d
+  sre-ai report usage --window 7d --workflow lark-oncall-rca --json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			now := time.Now()
+			since, err := timeparse.Cutoff(window, now)
+			if err != nil {
+				return fmt.Errorf("--window: %w", err)
+			}
+			records, err := listRuns(cmd.Context(), workflow)
+			if err != nil {
+				return err
+			}
+			report := runs.Usage(records, since, now, top)
+			return printOutput(cmd, opts, report, formatUsageReport(cmd.Context(), report))
+		},
+	}
+
+	cmd.Flags().StringVar(&window, "window", "30d", "How far back to look: 30d, 2w, a timestamp, or empty for all history")
+	cmd.Flags().StringVar(&workflow, "workflow", "", "Only report on this workflow")
+	cmd.Flags().IntVar(&top, "top", 5, "Failing steps to list (0 for all)")
+	return cmd
+}
+
+func formatUsageReport(ctx context.Context, report runs.UsageReport) string {
+	if len(report.Workflows) == 0 {
+		if report.Since == nil {
+			return "No workflow runs recorded"
+		}
+		return fmt.Sprintf("No workflow runs since %s", timefmt.Timestamp(ctx, *report.Since))
+	}
+
+	var buf strings.Builder
+	if report.Since == nil {
+		fmt.Fprintf(&buf, "%d run(s) across %d workflow(s)\n\n", report.Runs, len(report.Workflows))
+	} else {
+		fmt.Fprintf(&buf, "%d run(s) across %d workflow(s) since %s\n\n", report.Runs, len(report.Workflows), timefmt.Timestamp(ctx, *report.Since))
+	}
+	tw := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "WORKFLOW\tRUNS\tSUCCESS\tMEAN DURATION\tTOKENS\tLAST RUN\tRUNS PER %s\n", strings.ToUpper(trendUnit(report.TrendBucket)))
+	for _, u := range report.Workflows {
+		success := "-"
+		if u.Completed+u.Failed > 0 {
+			success = fmt.Sprintf("%.0f%%", u.SuccessRate*100)
+		}
+		duration := "-"
+		if u.MeanDuration > 0 {
+			duration = timefmt.Duration(u.MeanDuration)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\n", u.Workflow, u.Runs, success, duration, formatTokens(u.Tokens.Total(), u.Tokens.Estimated), timefmt.Timestamp(ctx, u.LastRun), sparkline.Render(floats(u.Trend), 0))
+	}
+	tw.Flush()
+
+	if len(report.TopFailingSteps) > 0 {
+		buf.WriteString("\nTop failing steps:\n")
+		for _, f := range report.TopFailingSteps {
+			fmt.Fprintf(&buf, "  %s / %s: %d failure(s)", f.Workflow, f.Step, f.Count)
+			if f.LastError != "" {
+				fmt.Fprintf(&buf, " - last: %s", truncateLine(f.LastError, 100))
+			}
+			buf.WriteString("\n")
+		}
+	}
+	fmt.Fprintf(&buf, "\nTotal tokens: %s", formatTokens(report.Tokens.Total(), report.Tokens.Estimated))
+	if report.Tokens.Total() > 0 {
+		tokens := floats(report.TokenTrend)
+		fmt.Fprintf(&buf, "\nTokens per %s: %s  %s", trendUnit(report.TrendBucket), sparkline.Render(tokens, 0), sparkline.Stats(tokens))
+	}
+	return buf.String()
+}
+
+// trendUnit names a trend bucket: "day", or "3 days" for wider ones.
+func trendUnit(bucket time.Duration) string {
+	days := int(bucket / (24 * time.Hour))
+	if days <= 1 {
+		return "day"
+	}
+	return fmt.Sprintf("%d days", days)
+}
+
+func floats(counts []int) []float64 {
+	out := make([]float64, len(counts))
+	for i, n := range counts {
+		out[i] = float64(n)
+	}
+	return out
+}
+
+func formatTokens(n int, estimated bool) string {
+	if estimated {
+		return fmt.Sprintf("~%d", n)
+	}
+	return fmt.Sprintf("%d", n)
+}
+
+// truncateLine keeps the first line of s, cut to max runes.
+func truncateLine(s string, max int) string {
+	s, _, _ = strings.Cut(s, "\n")
+	if r := []rune(s); len(r) > max {
+		return string(r[:max-3]) + "..."
+	}
+	return s
+}
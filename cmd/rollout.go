@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/example/sre-ai/internal/agent"
+)
+
+// formatRollout summarizes a rollout step: its decision, then each wave's targets and
+// how the wave went.
+func formatRollout(step agent.StepResult) string {
+	ro := step.Rollout
+	var b strings.Builder
+	fmt.Fprintf(&b, "Rollout %s %s:", step.StepName, strings.ReplaceAll(ro.Decision, "_", " "))
+	for _, wave := range ro.Waves {
+		label := fmt.Sprintf("wave %d", wave.Wave)
+		if wave.Canary {
+			label += " (canary)"
+		}
+		fmt.Fprintf(&b, "\n  %s %s: %s", label, strings.Join(wave.Targets, ", "), wave.Status)
+		if v := wave.Verification; v != nil && v.Detail != "" {
+			fmt.Fprintf(&b, " (%s)", v.Detail)
+		}
+	}
+	if len(ro.RolledBack) > 0 {
+		fmt.Fprintf(&b, "\n  rolled back with %s", strings.Join(ro.RolledBack, ", "))
+	}
+	return b.String()
+}
@@ -1,78 +1,197 @@
 package cmd
 
 import (
+    "context"
+    "errors"
     "fmt"
+    "io"
     "os"
+    "strings"
 
     "github.com/example/sre-ai/internal/config"
+    "github.com/example/sre-ai/internal/confirm"
     "github.com/example/sre-ai/internal/providers"
+    "github.com/example/sre-ai/internal/warnings"
     // "github.com/example/sre-ai/internal/mcp"
     "github.com/spf13/cobra"
+    "github.com/spf13/pflag"
 )
 
-var (
-    cfgFile string
-    globalOpts = config.GlobalOptions{
+// DefaultOptions returns the options the CLI starts from before flags and config apply.
+func DefaultOptions() config.GlobalOptions {
+    return config.GlobalOptions{
         Temperature: 0.2,
         Provider:    "gemini",
         Model:       providers.DefaultGeminiModel(),
     }
-)
+}
 
-// rootCmd is the base command for the CLI.
-var rootCmd = &cobra.Command{
-    Use:   "sre-ai",
-    Short: "AI-powered SRE/DevOps assistant with MCP integration",
-    PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-        if cfgFile != "" {
-            globalOpts.ConfigPath = cfgFile
-        }
+// NewRootCmd builds the command tree around opts. Flags and config are written into
+// opts and every subcommand reads them from there; the settings packages below cmd
+// need (runtime overrides, endpoints, provider limits, time format, principal, and
+// locale) travel in the command context. Separate trees (the serve daemon, tests) can
+// therefore run side by side.
+func NewRootCmd(opts *config.GlobalOptions) *cobra.Command {
+    root := &cobra.Command{
+        Use:   "sre-ai",
+        Short: "AI-powered SRE/DevOps assistant with MCP integration",
+        PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+            markFlagSources(cmd, opts)
+            if err := config.Load(cmd.Context(), opts); err != nil {
+                return fmt.Errorf("load config: %w", err)
+            }
+            switch strings.ToLower(opts.Confirm.Via) {
+            case "", "tty", "slack", "web":
+            default:
+                return fmt.Errorf("confirm via %q: expected tty, slack, or web", opts.Confirm.Via)
+            }
+            settings, warns, err := loadTreeSettings(cmd.Context(), opts)
+            if err != nil {
+                return err
+            }
+            cmd.SetContext(settings.attach(cmd.Context()))
+            if strings.EqualFold(opts.Confirm.Via, "web") {
+                cmd.SetContext(confirm.WithConfirmer(cmd.Context(), newWebConfirmer(cmd, opts)))
+            }
+            for _, warn := range warns {
+                warnings.Add(cmd.Context(), "messages", "%s", warn)
+            }
+            startAutoPrune(cmd, opts)
+
+            // if err := mcp.Warmup(cmd.Context(), opts); err != nil {
+            // 	return fmt.Errorf("warmup MCP: %w", err)
+            // }
+
+            return nil
+        },
+    }
+
+    flags := root.PersistentFlags()
+    flags.StringVar(&opts.Model, "model", opts.Model, "Model identifier (e.g. gemini-1.5-flash-latest)")
+    flags.StringVar(&opts.Provider, "provider", opts.Provider, "Model provider (gemini|openai|azure|bedrock|ollama|vllm|http)")
+    flags.Float64Var(&opts.Temperature, "temperature", opts.Temperature, "Sampling temperature")
+    flags.IntVar(&opts.MaxTokens, "max-tokens", opts.MaxTokens, "Maximum tokens to request")
+    flags.StringVar(&opts.Session, "session", opts.Session, "Session name for sticky context")
+    flags.BoolVar(&opts.JSON, "json", opts.JSON, "Emit machine-readable JSON output")
+    flags.StringVar(&opts.JSONFile, "json-file", opts.JSONFile, "Also write the JSON payload to this file, keeping human output on the terminal")
+    flags.BoolVar(&opts.Text, "text", opts.Text, "Emit raw text output when supported")
+    flags.BoolVarP(&opts.Quiet, "quiet", "q", opts.Quiet, "Silence human-readable output")
+    flags.CountVarP(&opts.Verbose, "verbose", "v", "Increase verbosity for debugging")
+    flags.BoolVar(&opts.NoInteractive, "no-interactive", opts.NoInteractive, "Do not prompt interactively")
+    flags.StringVar(&opts.ConfigPath, "config", opts.ConfigPath, "Override config file path")
+    flags.StringToStringVar(&opts.MCPServers, "mcp-server", opts.MCPServers, "Attach MCP server alias=path")
+    flags.StringSliceVar(&opts.Caps, "cap", opts.Caps, "Grant capability (repeatable)")
+    flags.BoolVar(&opts.DryRun, "dry-run", opts.DryRun, "Never apply mutations")
+    flags.BoolVar(&opts.AutoConfirm, "confirm", opts.AutoConfirm, "Auto-confirm prompts")
+    flags.StringVar(&opts.Confirm.Via, "confirm-via", opts.Confirm.Via, "Where to ask for approval (tty|slack|web); defaults to config confirm.via")
+
+    root.AddCommand(newDiagnoseCmd(opts))
+    root.AddCommand(newExplainCmd(opts))
+    root.AddCommand(newGenerateCmd(opts))
+    root.AddCommand(newPlanCmd(opts))
+    root.AddCommand(newApplyCmd(opts))
+    root.AddCommand(newAgentCmd(opts))
+    root.AddCommand(newChatCmd(opts))
+    root.AddCommand(newMCPCmd(opts))
+    root.AddCommand(newConfigCmd(opts))
+    root.AddCommand(newGamedayCmd(opts))
+    root.AddCommand(newStateCmd(opts))
+    root.AddCommand(newInitCmd(opts))
+    root.AddCommand(newDoctorCmd(opts))
+    root.AddCommand(newRuntimeCmd(opts))
+    root.AddCommand(newBundleCmd(opts))
+    root.AddCommand(newEvalCmd(opts))
+    root.AddCommand(newRunsCmd(opts))
+    root.AddCommand(newAuditCmd(opts))
+    root.AddCommand(newLSPCmd(opts))
+    root.AddCommand(newQueryCmd(opts))
+    root.AddCommand(newFactsCmd(opts))
+    root.AddCommand(newReportCmd(opts))
+    root.AddCommand(newRulesCmd(opts))
+    root.AddCommand(newPromptsCmd(opts))
+    root.AddCommand(newNotifyCmd(opts))
+    root.AddCommand(newIncidentCmd(opts))
+
+    return root
+}
+
+// flagSettings maps global flags to the config keys they set, so config.Load leaves
+// those alone and config show can tell where a value came from.
+var flagSettings = map[string]string{
+    "model":       "model",
+    "provider":    "provider",
+    "temperature": "temperature",
+    "max-tokens":  "max_tokens",
+    "session":     "session",
+    "cap":         "default_caps",
+    "confirm-via": "confirm.via",
+}
 
-        if err := config.Load(&globalOpts); err != nil {
-            return fmt.Errorf("load config: %w", err)
+func markFlagSources(cmd *cobra.Command, opts *config.GlobalOptions) {
+    cmd.Flags().Visit(func(f *pflag.Flag) {
+        if key, ok := flagSettings[f.Name]; ok {
+            opts.SetSource(key, config.SettingSource{Source: config.SourceFlag, Origin: "--" + f.Name})
         }
+    })
+    for alias := range opts.MCPServers {
+        opts.SetSource("mcp.servers."+alias, config.SettingSource{Source: config.SourceFlag, Origin: "--mcp-server"})
+    }
+}
 
-        // if err := mcp.Warmup(cmd.Context(), &globalOpts); err != nil {
-        // 	return fmt.Errorf("warmup MCP: %w", err)
-        // }
+// exitCodeError makes Execute exit with a specific status instead of 1. A nil err
+// means the failure was already reported and nothing more is printed.
+type exitCodeError struct {
+    code int
+    err  error
+}
 
-        return nil
-    },
+func (e *exitCodeError) Error() string {
+    if e.err == nil {
+        return fmt.Sprintf("exit status %d", e.code)
+    }
+    return e.err.Error()
 }
 
-// Execute runs the root command.
+func (e *exitCodeError) Unwrap() error {
+    return e.err
+}
+
+// Execute runs the CLI against the process's arguments and standard streams, then exits.
 func Execute() {
-    if err := rootCmd.Execute(); err != nil {
-        fmt.Fprintf(os.Stderr, "error: %v\n", err)
-        os.Exit(1)
-    }
+    ctx := config.WithDir(context.Background(), os.Getenv(config.ConfigDirEnv))
+    os.Exit(Run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
 
-func init() {
-    flags := rootCmd.PersistentFlags()
-    flags.StringVar(&globalOpts.Model, "model", globalOpts.Model, "Model identifier (e.g. gemini-1.5-flash-latest)")
-    flags.StringVar(&globalOpts.Provider, "provider", globalOpts.Provider, "Model provider (gemini|openai|azure|bedrock|ollama|vllm|http)")
-    flags.Float64Var(&globalOpts.Temperature, "temperature", globalOpts.Temperature, "Sampling temperature")
-    flags.IntVar(&globalOpts.MaxTokens, "max-tokens", globalOpts.MaxTokens, "Maximum tokens to request")
-    flags.StringVar(&globalOpts.Session, "session", globalOpts.Session, "Session name for sticky context")
-    flags.BoolVar(&globalOpts.JSON, "json", globalOpts.JSON, "Emit machine-readable JSON output")
-    flags.BoolVar(&globalOpts.Text, "text", globalOpts.Text, "Emit raw text output when supported")
-    flags.BoolVarP(&globalOpts.Quiet, "quiet", "q", globalOpts.Quiet, "Silence human-readable output")
-    flags.CountVarP(&globalOpts.Verbose, "verbose", "v", "Increase verbosity for debugging")
-    flags.BoolVar(&globalOpts.NoInteractive, "no-interactive", globalOpts.NoInteractive, "Do not prompt interactively")
-    flags.StringVar(&cfgFile, "config", cfgFile, "Override config file path")
-    flags.StringToStringVar(&globalOpts.MCPServers, "mcp-server", globalOpts.MCPServers, "Attach MCP server alias=path")
-    flags.StringSliceVar(&globalOpts.Caps, "cap", globalOpts.Caps, "Grant capability (repeatable)")
-    flags.BoolVar(&globalOpts.DryRun, "dry-run", globalOpts.DryRun, "Never apply mutations")
-    flags.BoolVar(&globalOpts.AutoConfirm, "confirm", globalOpts.AutoConfirm, "Auto-confirm prompts")
-
-    rootCmd.AddCommand(newDiagnoseCmd())
-    rootCmd.AddCommand(newExplainCmd())
-    rootCmd.AddCommand(newGenerateCmd())
-    rootCmd.AddCommand(newPlanCmd())
-    rootCmd.AddCommand(newApplyCmd())
-    rootCmd.AddCommand(newAgentCmd())
-    rootCmd.AddCommand(newChatCmd())
-    rootCmd.AddCommand(newMCPCmd())
-    rootCmd.AddCommand(newConfigCmd())
+// Run executes one command line in-process with fresh default options and the given
+// streams, and returns the exit status instead of exiting. Command output, prompts,
+// and errors all go through the supplied streams; warnings no output reported are
+// printed to stderr before returning. The config dir comes from ctx (config.WithDir),
+// not the process environment, so runs sharing a process keep their state apart.
+func Run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+    opts := DefaultOptions()
+    root := NewRootCmd(&opts)
+    root.SetArgs(args)
+    root.SetIn(stdin)
+    root.SetOut(stdout)
+    root.SetErr(stderr)
+    // Run reports the error itself; usage on stdout would corrupt --json output.
+    root.SilenceUsage = true
+    root.SilenceErrors = true
+    collector := &warnings.Collector{}
+    ctx, bg := withBackground(ctx)
+    err := root.ExecuteContext(warnings.WithCollector(ctx, collector))
+    bg.wait(autoPruneGrace)
+    flushWarnings(collector, stderr)
+    if err == nil {
+        return 0
+    }
+    var codeErr *exitCodeError
+    if errors.As(err, &codeErr) {
+        if codeErr.err != nil {
+            fmt.Fprintf(stderr, "error: %v\n", codeErr.err)
+        }
+        return codeErr.code
+    }
+    fmt.Fprintf(stderr, "error: %v\n", err)
+    return 1
 }
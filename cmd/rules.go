@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/rules"
+	"github.com/spf13/cobra"
+)
+
+func newRulesCmd(opts *config.GlobalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rules",
+		Short: "List and test the failure signature rules used by diagnose and workflows",
+		Long: `List and test the failure signature rules used by diagnose and workflows.
+
+Built-in rules cover OOMKilled, ImagePullBackOff, CrashLoopBackOff, and full disks.
+YAML files in <config dir>/rules add rules or replace built-ins with the same id.`,
+	}
+	cmd.AddCommand(newRulesLsCmd(opts))
+	cmd.AddCommand(newRulesCheckCmd(opts))
+	return cmd
+}
+
+func newRulesLsCmd(opts *config.GlobalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "ls",
+		Short: "List the loaded rules",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			set, err := rules.LoadDefault(cmd.Context())
+			if err != nil {
+				return err
+			}
+			list := set.Rules()
+
+			var buf strings.Builder
+			tw := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tSEVERITY\tSOURCE\tTITLE")
+			for _, rule := range list {
+				severity := rule.Severity
+				if severity == "" {
+					severity = "-"
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", rule.ID, severity, rule.Source, rule.Title)
+			}
+			tw.Flush()
+			return printOutput(cmd, opts, map[string]any{"rules": list}, strings.TrimRight(buf.String(), "\n"))
+		},
+	}
+}
+
+func newRulesCheckCmd(opts *config.GlobalOptions) *cobra.Command {
+	var ruleFile string
+
+	cmd := &cobra.Command{
+		Use:   "check [file...]",
+		Short: "Match log or evidence files against the rules",
+		Long: `Match log or evidence files against the rules, line by line. With no files, or
+"-", stdin is read. --rules-file checks only the rules in one file, e.g. while writing it.`,
+		Example: `  kubectl describe pod checkout-api-7f9 | sre-ai rules check
+  sre-ai rules check --rules-file ./rules/payments.yaml kubelet.log`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var set *rules.Set
+			var err error
+			if ruleFile != "" {
+				var loaded []rules.Rule
+				if loaded, err = rules.LoadFile(ruleFile); err == nil {
+					set, err = rules.Compile(loaded)
+				}
+			} else {
+				set, err = rules.LoadDefault(cmd.Context())
+			}
+			if err != nil {
+				return err
+			}
+
+			if len(args) == 0 {
+				args = []string{"-"}
+			}
+			var evidence []interface{}
+			for _, path := range args {
+				var data []byte
+				if path == "-" {
+					data, err = io.ReadAll(cmd.InOrStdin())
+				} else {
+					data, err = os.ReadFile(path)
+				}
+				if err != nil {
+					return err
+				}
+				evidence = append(evidence, string(data))
+			}
+			hits := set.Evaluate(rules.Lines(evidence...))
+
+			human := "No rules matched"
+			if len(hits) > 0 {
+				human = rules.Hints(hits)
+			}
+			return printOutput(cmd, opts, map[string]any{"matches": hits}, human)
+		},
+	}
+	cmd.Flags().StringVar(&ruleFile, "rules-file", "", "Check only the rules in this YAML file")
+	return cmd
+}
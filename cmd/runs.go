@@ -0,0 +1,463 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/example/sre-ai/internal/agent"
+	"github.com/example/sre-ai/internal/audit"
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/runs"
+	"github.com/example/sre-ai/internal/timefmt"
+	"github.com/example/sre-ai/internal/timeparse"
+	"github.com/example/sre-ai/internal/upload"
+	"github.com/example/sre-ai/internal/warnings"
+	"github.com/spf13/cobra"
+)
+
+func newRunsCmd(opts *config.GlobalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "runs",
+		Short: "Inspect, rate, and export recorded agent runs and diagnoses",
+	}
+	cmd.AddCommand(newRunsLsCmd(opts))
+	cmd.AddCommand(newRunsShowCmd(opts))
+	cmd.AddCommand(newRunsRateCmd(opts))
+	cmd.AddCommand(newRunsResolveCmd(opts))
+	cmd.AddCommand(newRunsExportCmd(opts))
+	cmd.AddCommand(newRunsPromptDiffCmd(opts))
+	return cmd
+}
+
+func newRunsLsCmd(opts *config.GlobalOptions) *cobra.Command {
+	var filter runs.Filter
+	var since string
+
+	cmd := &cobra.Command{
+		Use:   "ls",
+		Short: "List recorded runs, newest first",
+		Example: `  sre-ai runs ls --status error --since 7d
+  sre-ai runs ls --kind diagnose --workflow k8s --limit 0
+  sre-ai runs ls --principal slack:U024BE7LH`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if since != "" {
+				cutoff, err := timeparse.Cutoff(since, time.Now())
+				if err != nil {
+					return fmt.Errorf("--since: %w", err)
+				}
+				filter.Since = cutoff
+			}
+			records, err := findRuns(cmd, filter)
+			if err != nil {
+				return err
+			}
+
+			var buf strings.Builder
+			if len(records) == 0 {
+				buf.WriteString("No runs recorded")
+			} else {
+				tw := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+				fmt.Fprintln(tw, "ID\tWORKFLOW\tSTATUS\tSTARTED\tBY\tSCORE")
+				for _, rec := range records {
+					score := "-"
+					if mean, ok := rec.MeanScore(); ok {
+						score = fmt.Sprintf("%.1f", mean)
+					}
+					by := rec.Principal
+					if by == "" {
+						by = "-"
+					}
+					fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", rec.ID, rec.Workflow, rec.Status, timefmt.Timestamp(cmd.Context(), rec.StartedAt), by, score)
+				}
+				tw.Flush()
+			}
+			return printOutput(cmd, opts, map[string]any{"runs": records}, strings.TrimRight(buf.String(), "\n"))
+		},
+	}
+
+	cmd.Flags().StringVar(&filter.Workflow, "workflow", "", "Only list runs of this workflow")
+	cmd.Flags().StringVar(&filter.Status, "status", "", "Only list runs with this status: running, completed, error, stopped, or planned")
+	cmd.Flags().StringVar(&filter.Kind, "kind", "", "Only list runs of this kind: agent or diagnose")
+	cmd.Flags().StringVar(&filter.Principal, "principal", "", "Only list runs started by this principal (an OS user, key:<name>, or slack:<user id>)")
+	cmd.Flags().StringVar(&since, "since", "", "Only list runs started within this window (7d, 36h) or after a timestamp")
+	cmd.Flags().IntVar(&filter.Limit, "limit", 20, "Maximum runs to list (0 for all)")
+
+	return cmd
+}
+
+func newRunsShowCmd(opts *config.GlobalOptions) *cobra.Command {
+	var profile bool
+	var profileSort string
+	cmd := &cobra.Command{
+		Use:   "show <id>",
+		Short: "Show a recorded run",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.Flags().Changed("profile-sort") && !profile {
+				return errors.New("--profile-sort needs --profile")
+			}
+			rec, err := runs.Load(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			human := formatRunHuman(cmd.Context(), rec)
+			if profile {
+				if rec.Result == nil {
+					return fmt.Errorf("run %s has no workflow steps to profile", rec.ID)
+				}
+				if rec.Result.Profile, err = agent.NewProfile(rec.Result, profileSort); err != nil {
+					return err
+				}
+				human += "\n" + formatProfile(rec.Result.Profile)
+			}
+			return printOutput(cmd, opts, rec, human)
+		},
+	}
+	cmd.Flags().BoolVar(&profile, "profile", false, "Add the cost of every step (time, CPU, tokens, tool output), most costly first")
+	cmd.Flags().StringVar(&profileSort, "profile-sort", "time", "Cost the profile is sorted by: time, tokens, cpu, or output")
+	return cmd
+}
+
+func newRunsRateCmd(opts *config.GlobalOptions) *cobra.Command {
+	var score int
+	var comment string
+
+	cmd := &cobra.Command{
+		Use:   "rate <id>",
+		Short: "Record a 1-5 score and optional comment for a run",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("score") {
+				return errors.New("--score is required")
+			}
+			rec, err := runs.Rate(cmd.Context(), args[0], score, comment)
+			if err != nil {
+				return err
+			}
+			mean, _ := rec.MeanScore()
+			payload := map[string]any{
+				"run_id":     rec.ID,
+				"workflow":   rec.Workflow,
+				"score":      score,
+				"comment":    comment,
+				"ratings":    len(rec.Ratings),
+				"mean_score": mean,
+			}
+			return printOutput(cmd, opts, payload, fmt.Sprintf("Rated run %s %d/5 (mean %.1f over %d rating(s))", rec.ID, score, mean, len(rec.Ratings)))
+		},
+	}
+
+	cmd.Flags().IntVar(&score, "score", 0, "Score from 1 (useless) to 5 (excellent)")
+	cmd.Flags().StringVar(&comment, "comment", "", "Free-form feedback")
+
+	return cmd
+}
+
+func newRunsResolveCmd(opts *config.GlobalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "resolve <id> <note...>",
+		Short: "Record what fixed the incident behind a run",
+		Long: `Resolve stores a note on a run describing what fixed the incident. Later
+diagnoses that look similar list the note under "similar past incidents".`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rec, err := runs.Resolve(cmd.Context(), args[0], strings.Join(args[1:], " "))
+			if err != nil {
+				return err
+			}
+			payload := map[string]any{"run_id": rec.ID, "resolution": rec.Resolution}
+			return printOutput(cmd, opts, payload, fmt.Sprintf("Recorded resolution for run %s", rec.ID))
+		},
+	}
+}
+
+func newRunsExportCmd(opts *config.GlobalOptions) *cobra.Command {
+	var workflow string
+	var out string
+	var includeUnrated bool
+	var uploadTo string
+	var sign bool
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export rated runs with prompts and replies as JSON lines for prompt tuning",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			toFile := out != "" && out != "-"
+			if sign && !toFile {
+				return errors.New("--sign needs --out; the signature is written next to the file")
+			}
+			records, err := listRuns(cmd.Context(), workflow)
+			if err != nil {
+				return err
+			}
+
+			var w io.Writer = cmd.OutOrStdout()
+			if toFile {
+				file, err := os.Create(out)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				w = file
+			}
+			// With --upload and no --out, the export only goes to the destination.
+			var exported bytes.Buffer
+			if uploadTo != "" {
+				if toFile {
+					w = io.MultiWriter(w, &exported)
+				} else {
+					w = &exported
+				}
+			}
+
+			count, err := runs.Export(w, records, includeUnrated)
+			if err != nil {
+				return err
+			}
+			payload := map[string]any{"runs": count}
+			var lines []string
+			if toFile {
+				payload["path"] = out
+				lines = append(lines, fmt.Sprintf("Exported %d run(s) to %s", count, out))
+			}
+			var sig *audit.Signature
+			if sign {
+				var sigPath string
+				if sig, sigPath, err = audit.SignFile(cmd.Context(), out); err != nil {
+					return err
+				}
+				payload["signature_path"] = sigPath
+				lines = append(lines, fmt.Sprintf("Signed with key %s (%s)", sig.KeyID, sigPath))
+			}
+			if uploadTo != "" {
+				name := "runs-export-" + time.Now().UTC().Format("20060102T150405") + ".jsonl"
+				obj, line, err := publish(cmd, opts, uploadTo, name, exported.Bytes(), upload.TypeJSONL)
+				if err != nil {
+					return err
+				}
+				payload["upload"] = obj
+				lines = append(lines, line)
+				if sig != nil {
+					encoded, err := json.MarshalIndent(sig, "", "  ")
+					if err != nil {
+						return err
+					}
+					sigObj, line, err := publish(cmd, opts, uploadTo, name+audit.SignatureSuffix, append(encoded, '\n'), upload.TypeJSON)
+					if err != nil {
+						return err
+					}
+					payload["signature_upload"] = sigObj
+					lines = append(lines, line)
+				}
+			}
+			if len(lines) == 0 {
+				return nil
+			}
+			return printOutput(cmd, opts, payload, strings.Join(lines, "\n"))
+		},
+	}
+
+	cmd.Flags().StringVar(&workflow, "workflow", "", "Only export runs of this workflow")
+	cmd.Flags().StringVarP(&out, "out", "o", "", "Write to a file instead of stdout")
+	cmd.Flags().BoolVar(&includeUnrated, "include-unrated", false, "Also export runs without feedback")
+	cmd.Flags().StringVar(&uploadTo, "upload", "", "Upload the export to this destination from the config file's upload block")
+	cmd.Flags().BoolVar(&sign, "sign", false, "Sign the exported file with the local key, writing <out>.sig")
+
+	return cmd
+}
+
+func newRunsPromptDiffCmd(opts *config.GlobalOptions) *cobra.Command {
+	var showUnchanged bool
+
+	cmd := &cobra.Command{
+		Use:   "prompt-diff <old-id> <new-id>",
+		Short: "Diff the rendered prompts of two runs step by step",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			oldRun, err := runs.Load(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			newRun, err := runs.Load(cmd.Context(), args[1])
+			if err != nil {
+				return err
+			}
+			changes := runs.PromptDiff(oldRun, newRun)
+			envChanges := runs.EnvironmentChanges(oldRun, newRun)
+
+			var builder strings.Builder
+			if len(changes) == 0 {
+				builder.WriteString("Neither run recorded any prompts")
+			}
+			for _, change := range changes {
+				if change.Status == "unchanged" && !showUnchanged {
+					continue
+				}
+				line := fmt.Sprintf("%s/%s: %s", change.Stage, change.Step, change.Status)
+				switch {
+				case change.Status == "changed" && change.TemplateChanged:
+					line += " (template edited)"
+				case change.Status == "changed":
+					line += " (same template; inputs or step data differ)"
+				}
+				builder.WriteString(line + "\n")
+				if change.Diff != "" {
+					builder.WriteString(change.Diff + "\n")
+				}
+			}
+			if builder.Len() == 0 {
+				builder.WriteString("All prompts identical")
+			}
+			human := strings.TrimRight(builder.String(), "\n")
+			if len(envChanges) > 0 {
+				// A prompt that changed with the same template often comes down to these.
+				human += "\n\nEnvironment changed:\n  " + strings.Join(envChanges, "\n  ")
+			}
+			payload := map[string]any{
+				"old_run": oldRun.ID,
+				"new_run": newRun.ID,
+				"changes": changes,
+			}
+			if len(envChanges) > 0 {
+				payload["environment_changes"] = envChanges
+			}
+			return printOutput(cmd, opts, payload, human)
+		},
+	}
+
+	cmd.Flags().BoolVar(&showUnchanged, "all", false, "Also list steps whose prompt did not change")
+
+	return cmd
+}
+
+// findRuns lists the runs passing filter, newest first, through the SQLite index. When
+// the index cannot be opened the run files are scanned instead.
+func findRuns(cmd *cobra.Command, filter runs.Filter) ([]*runs.Record, error) {
+	ctx := cmd.Context()
+	idx, err := runs.OpenIndex(cmd.Context())
+	if err != nil {
+		warnings.Add(cmd.Context(), "runs", "run index unavailable, scanning run files: %v", err)
+		all, err := runs.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var records []*runs.Record
+		for _, rec := range all {
+			if filter.Match(rec) {
+				records = append(records, rec)
+			}
+			if filter.Limit > 0 && len(records) == filter.Limit {
+				break
+			}
+		}
+		return records, nil
+	}
+	defer idx.Close()
+	ids, err := idx.Find(cmd.Context(), filter)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]*runs.Record, 0, len(ids))
+	for _, id := range ids {
+		rec, err := runs.Load(ctx, id)
+		if err != nil {
+			continue
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+func listRuns(ctx context.Context, workflow string) ([]*runs.Record, error) {
+	records, err := runs.List(ctx)
+	if err != nil || workflow == "" {
+		return records, err
+	}
+	filtered := records[:0]
+	for _, rec := range records {
+		if rec.Workflow == workflow {
+			filtered = append(filtered, rec)
+		}
+	}
+	return filtered, nil
+}
+
+func formatRunHuman(ctx context.Context, rec *runs.Record) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Run %s (%s)\n", rec.ID, rec.Kind))
+	builder.WriteString(fmt.Sprintf("Workflow: %s\n", rec.Workflow))
+	if rec.WorkflowPath != "" {
+		builder.WriteString(fmt.Sprintf("Path: %s\n", rec.WorkflowPath))
+	}
+	builder.WriteString(fmt.Sprintf("Status: %s\n", rec.Status))
+	builder.WriteString(fmt.Sprintf("Started: %s", timefmt.Timestamp(ctx, rec.StartedAt)))
+	if rec.Principal != "" {
+		builder.WriteString(fmt.Sprintf(" by %s", rec.Principal))
+	}
+	if rec.FinishedAt != nil {
+		builder.WriteString(fmt.Sprintf(", took %s", timefmt.Duration(rec.FinishedAt.Sub(rec.StartedAt))))
+	}
+	builder.WriteString("\n")
+	if env := rec.Environment; env != nil {
+		builder.WriteString(fmt.Sprintf("Environment: sre-ai %s on %s, provider %s, model %s\n", env.CLIVersion, env.Platform, env.Provider, env.Model))
+		if env.KubeContext != "" {
+			builder.WriteString(fmt.Sprintf("  kubecontext: %s\n", env.KubeContext))
+		}
+		if env.GitSHA != "" {
+			dirty := ""
+			if env.GitDirty {
+				dirty = " with uncommitted changes"
+			}
+			builder.WriteString(fmt.Sprintf("  repo: %s at %s%s\n", env.Repo, env.GitSHA, dirty))
+		}
+		if env.ConfigFile != "" {
+			builder.WriteString(fmt.Sprintf("  config: %s\n", env.ConfigFile))
+		}
+		if env.ProjectConfig != "" {
+			builder.WriteString(fmt.Sprintf("  project config: %s\n", env.ProjectConfig))
+		}
+	}
+	if rec.Error != "" {
+		builder.WriteString(fmt.Sprintf("Error: %s\n", rec.Error))
+	}
+	if rec.Summary != "" {
+		builder.WriteString(fmt.Sprintf("Summary: %s\n", rec.Summary))
+	}
+	for _, finding := range rec.Findings {
+		builder.WriteString(fmt.Sprintf("  - %s\n", finding))
+	}
+	if rec.Resolution != "" {
+		builder.WriteString(fmt.Sprintf("Resolution: %s\n", rec.Resolution))
+	}
+	if rec.Result != nil {
+		for _, step := range rec.Result.Steps {
+			line := fmt.Sprintf("  - %s/%s [%s] %s", step.StageID, step.StepName, step.Type, step.Status)
+			if step.Duration > 0 {
+				line += " in " + timefmt.Duration(step.Duration)
+			}
+			builder.WriteString(line + "\n")
+		}
+	}
+	for _, rating := range rec.Ratings {
+		line := fmt.Sprintf("Rated %d/5", rating.Score)
+		if rating.Rater != "" {
+			line += " by " + rating.Rater
+		}
+		if !rating.At.IsZero() {
+			line += " " + timefmt.Ago(rating.At)
+		}
+		if rating.Comment != "" {
+			line += ": " + rating.Comment
+		}
+		builder.WriteString(line + "\n")
+	}
+	return strings.TrimRight(builder.String(), "\n")
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/runtimes"
+	"github.com/spf13/cobra"
+)
+
+func newRuntimeCmd(opts *config.GlobalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "runtime",
+		Short: "Manage the runtimes bundled for MCP servers",
+	}
+	cmd.AddCommand(newRuntimeBundleCmd(opts))
+	return cmd
+}
+
+func newRuntimeBundleCmd(opts *config.GlobalOptions) *cobra.Command {
+	var targets []string
+	var dir string
+	var version string
+	var mirror string
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "bundle [runtime]",
+		Short: "Download and verify a pinned runtime into third_party/ for offline use",
+		Long: `Download and verify a pinned runtime into third_party/ for offline use.
+
+The release archive for each --target is checked against the release's published
+SHA-256 list, unpacked to <dir>/<runtime>/<dist>, and given a sre-ai-bundle.json
+manifest with the checksum of every file. The CLI only uses a bundled runtime whose
+manifest matches the platform it runs on and whose binary matches its checksum.
+A bundle that is already present and verifies is left alone unless --force is set.
+
+Only node is supported; it is pinned to ` + runtimes.Pinned[runtimes.Node].Version + `.`,
+		Example: `  sre-ai runtime bundle --target linux/arm64
+  sre-ai runtime bundle --target linux/amd64,linux/arm64,darwin/arm64,windows/amd64
+  sre-ai runtime bundle --mirror https://artifacts.internal/nodejs --dry-run`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := runtimes.Node
+			if len(args) == 1 {
+				name = runtimes.Name(strings.ToLower(args[0]))
+			}
+			release, ok := runtimes.Pinned[name]
+			if !ok {
+				return fmt.Errorf("bundling %s is not supported; supported: node", name)
+			}
+			if version != "" || mirror != "" {
+				if version == "" {
+					version = release.Version
+				}
+				release = runtimes.NodeRelease(version, mirror)
+			}
+
+			parsed := make([]runtimes.Target, 0, len(targets))
+			for _, value := range targets {
+				target, err := runtimes.ParseTarget(value)
+				if err != nil {
+					return err
+				}
+				if _, err := release.Archive(target); err != nil {
+					return err
+				}
+				parsed = append(parsed, target)
+			}
+			if len(parsed) == 0 {
+				parsed = append(parsed, runtimes.CurrentTarget())
+			}
+
+			var results []runtimes.BundleResult
+			var lines []string
+			for _, target := range parsed {
+				if opts.DryRun {
+					archive, _ := release.Archive(target)
+					results = append(results, runtimes.BundleResult{Target: target, Archive: archive, URL: release.BaseURL + "/" + archive})
+					lines = append(lines, fmt.Sprintf("dry-run: would download %s/%s and verify it against %s", release.BaseURL, archive, release.Checksums))
+					continue
+				}
+				result, err := runtimes.Bundle(cmd.Context(), release, target, runtimes.BundleOptions{Dir: dir, Force: force})
+				if err != nil {
+					return fmt.Errorf("%s: %w", target, err)
+				}
+				results = append(results, result)
+				if result.Skipped {
+					lines = append(lines, fmt.Sprintf("%s: %s already bundled and verified", target, result.Dir))
+				} else {
+					lines = append(lines, fmt.Sprintf("%s: bundled %s into %s (%d files, sha256 %s)", target, result.Archive, result.Dir, result.Files, result.SHA256))
+				}
+			}
+
+			payload := map[string]any{
+				"runtime": name,
+				"version": release.Version,
+				"bundles": results,
+				"dry_run": opts.DryRun,
+			}
+			return printOutput(cmd, opts, payload, strings.Join(lines, "\n"))
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&targets, "target", nil, "Platforms to bundle as os/arch, comma separated (default the current platform)")
+	cmd.Flags().StringVar(&dir, "dir", "third_party", "Directory to bundle into; ship it next to the sre-ai binary")
+	cmd.Flags().StringVar(&version, "version", "", "Bundle this version instead of the pinned one")
+	cmd.Flags().StringVar(&mirror, "mirror", "", "Download from this mirror of https://nodejs.org/dist")
+	cmd.Flags().BoolVar(&force, "force", false, "Replace bundles that already exist")
+	return cmd
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/httpx"
+	"github.com/example/sre-ai/internal/mcp"
+	"github.com/example/sre-ai/internal/messages"
+	"github.com/example/sre-ai/internal/principal"
+	"github.com/example/sre-ai/internal/providers"
+	"github.com/example/sre-ai/internal/runtimes"
+	"github.com/example/sre-ai/internal/timefmt"
+)
+
+// treeSettings are the settings built from one tree's config that packages below cmd
+// read from the command context: runtime overrides, provider call slots, endpoints,
+// time format, principal, locale, and the MCP server registry. Each tree has its own, so trees run side by side
+// in one process without seeing each other's config.
+type treeSettings struct {
+	runtimes  map[string]string
+	pool      *providers.Pool
+	endpoints *httpx.Endpoints
+	time      timefmt.Format
+	principal string
+	catalog   *messages.Catalog
+	registry  *mcp.Registry
+}
+
+// loadTreeSettings builds the settings from loaded options. The warnings list skipped
+// translations.
+func loadTreeSettings(ctx context.Context, opts *config.GlobalOptions) (*treeSettings, []string, error) {
+	endpoints, err := httpx.NewEndpoints(opts.Endpoints)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load config: %w", err)
+	}
+	relative := opts.Time.Relative == nil || *opts.Time.Relative
+	format, err := timefmt.New(opts.Time.Zone, opts.Time.Layout, relative)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load config: %w", err)
+	}
+	catalog, warns, err := messages.Load(ctx, opts.Locale)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load config: %w", err)
+	}
+	return &treeSettings{
+		runtimes:  opts.Runtimes,
+		pool:      providers.NewPool(opts.MaxInFlight),
+		endpoints: endpoints,
+		time:      format,
+		principal: opts.Principal,
+		catalog:   catalog,
+		registry:  mcp.NewRegistry(),
+	}, warns, nil
+}
+
+// attach returns ctx carrying the settings. The endpoints' tunnels close when ctx ends.
+func (s *treeSettings) attach(ctx context.Context) context.Context {
+	ctx = runtimes.WithOverrides(ctx, s.runtimes)
+	ctx = providers.WithPool(ctx, s.pool)
+	ctx = httpx.WithEndpoints(ctx, s.endpoints)
+	ctx = timefmt.WithFormat(ctx, s.time)
+	ctx = principal.WithName(ctx, s.principal)
+	ctx = messages.WithCatalog(ctx, s.catalog)
+	ctx = mcp.WithRegistry(ctx, s.registry)
+	context.AfterFunc(ctx, s.endpoints.Close)
+	return ctx
+}
@@ -0,0 +1,295 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/state"
+	"github.com/example/sre-ai/internal/upload"
+	"github.com/example/sre-ai/internal/warnings"
+	"github.com/spf13/cobra"
+)
+
+const statePassphraseEnv = "SRE_AI_STATE_PASSPHRASE"
+
+func newStateCmd(opts *config.GlobalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "state",
+		Short: "Back up or restore local sre-ai state",
+	}
+
+	cmd.AddCommand(newStateExportCmd(opts))
+	cmd.AddCommand(newStateImportCmd(opts))
+	cmd.AddCommand(newStatePruneCmd(opts))
+	return cmd
+}
+
+func newStateExportCmd(opts *config.GlobalOptions) *cobra.Command {
+	var includeCreds bool
+	var encrypt bool
+	var uploadTo string
+
+	cmd := &cobra.Command{
+		Use:   "export <archive>",
+		Short: "Export config, MCP servers, sessions, runs, knowledge, and facts to an archive",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			archive := args[0]
+			if encrypt && !includeCreds {
+				return errors.New("--encrypt only applies together with --include-credentials")
+			}
+
+			exportOpts := state.ExportOptions{ConfigPath: opts.ConfigPath, IncludeCredentials: includeCreds}
+			if encrypt {
+				passphrase, err := statePassphrase(cmd, opts, true)
+				if err != nil {
+					return err
+				}
+				exportOpts.Passphrase = passphrase
+			}
+
+			if opts.DryRun {
+				payload := map[string]any{"archive": archive, "status": "dry-run"}
+				human := fmt.Sprintf("Dry-run: would export state to %s", archive)
+				if uploadTo != "" {
+					payload["upload"] = uploadTo
+					human += " and upload it to " + uploadTo
+				}
+				return printOutput(cmd, opts, payload, human)
+			}
+
+			manifest, err := state.Export(cmd.Context(), archive, exportOpts)
+			if err != nil {
+				return err
+			}
+
+			components := make([]string, 0, len(manifest.Components))
+			for _, c := range manifest.Components {
+				components = append(components, string(c))
+			}
+			payload := map[string]any{
+				"archive":  archive,
+				"manifest": manifest,
+			}
+			human := fmt.Sprintf("Exported %d files (%s) to %s", manifest.Files, strings.Join(components, ", "), archive)
+			if includeCreds && !manifest.EncryptedCredentials {
+				human += "\nwarning: credentials are stored unencrypted; pass --encrypt to protect them"
+			}
+			if uploadTo != "" {
+				data, err := os.ReadFile(archive)
+				if err != nil {
+					return err
+				}
+				obj, line, err := publish(cmd, opts, uploadTo, filepath.Base(archive), data, upload.TypeBinary)
+				if err != nil {
+					return err
+				}
+				payload["upload"] = obj
+				human += "\n" + line
+			}
+			return printOutput(cmd, opts, payload, human)
+		},
+	}
+
+	cmd.Flags().BoolVar(&includeCreds, "include-credentials", false, "Include stored provider credentials")
+	cmd.Flags().BoolVar(&encrypt, "encrypt", false, "Encrypt credentials with a passphrase (prompted or "+statePassphraseEnv+")")
+	cmd.Flags().StringVar(&uploadTo, "upload", "", "Upload the archive to this destination from the config file's upload block")
+
+	return cmd
+}
+
+func newStateImportCmd(opts *config.GlobalOptions) *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "import <archive>",
+		Short: "Restore local state from an archive",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			archive := args[0]
+			importOpts := state.ImportOptions{ConfigPath: opts.ConfigPath, Force: force, DryRun: opts.DryRun}
+
+			result, err := state.Import(cmd.Context(), archive, importOpts)
+			if errors.Is(err, state.ErrPassphraseRequired) {
+				passphrase, perr := statePassphrase(cmd, opts, false)
+				if perr != nil {
+					return perr
+				}
+				importOpts.Passphrase = passphrase
+				result, err = state.Import(cmd.Context(), archive, importOpts)
+			}
+			if err != nil {
+				return err
+			}
+
+			verb := "Imported"
+			if opts.DryRun {
+				verb = "Dry-run: would import"
+			}
+			lines := []string{fmt.Sprintf("%s %d files from %s", verb, len(result.Written), archive)}
+			if len(result.Skipped) > 0 {
+				lines = append(lines, fmt.Sprintf("Skipped %d existing files (use --force to overwrite):", len(result.Skipped)))
+				for _, p := range result.Skipped {
+					lines = append(lines, "  - "+p)
+				}
+			}
+			return printOutput(cmd, opts, result, strings.Join(lines, "\n"))
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite existing files")
+
+	return cmd
+}
+
+func newStatePruneCmd(opts *config.GlobalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove run history, artifacts, logs, caches, and sessions past their retention",
+		Long: `Remove local state past the retention limits of the config file's retention block.
+
+Each kind (runs, artifacts, facts, sessions, logs, cache) has a max_age and a
+max_size. Items older than max_age are removed first, then the oldest until the
+rest fits in max_size. Runs still in progress are kept. Without configuration
+runs keep 90 days up to 1 GiB, artifacts and sessions 30 days, logs 14 days up
+to 100 MiB, and the cache 7 days up to 500 MiB; facts are kept.
+
+Pass --dry-run to list what would be removed.`,
+		Example: `  sre-ai state prune --dry-run
+  sre-ai state prune --json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			policies, err := state.Policies(opts.Retention.Limits)
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			result, err := state.Prune(cmd.Context(), policies, opts.DryRun, time.Now())
+			if err != nil {
+				return fmt.Errorf("prune: %w", err)
+			}
+			return printOutput(cmd, opts, result, formatPruneResult(result))
+		},
+	}
+	return cmd
+}
+
+func formatPruneResult(result state.PruneResult) string {
+	verb := "Removed"
+	if result.DryRun {
+		verb = "Dry-run: would remove"
+	}
+	if len(result.Removed) == 0 {
+		return "Nothing to prune"
+	}
+	lines := []string{fmt.Sprintf("%s %d item(s), %s:", verb, len(result.Removed), state.FormatSize(result.Freed))}
+	for _, r := range result.Removed {
+		lines = append(lines, fmt.Sprintf("  - %-9s %s (%s, %s)", r.Kind, r.Path, state.FormatSize(r.Bytes), r.Reason))
+	}
+	lines = append(lines, "Kept:")
+	for _, usage := range result.Kept {
+		if usage.Items == 0 {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("  %-9s %d item(s), %s", usage.Kind, usage.Items, state.FormatSize(usage.Bytes)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// autoPruneGrace is how long Run waits for a background prune after the command returns.
+const autoPruneGrace = 2 * time.Second
+
+type backgroundKey struct{}
+
+// background tracks work started alongside a command, which Run waits for (briefly)
+// before it returns.
+type background struct {
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+}
+
+func withBackground(ctx context.Context) (context.Context, *background) {
+	ctx, cancel := context.WithCancel(ctx)
+	bg := &background{cancel: cancel}
+	return context.WithValue(ctx, backgroundKey{}, bg), bg
+}
+
+// wait gives background work up to grace to finish, then cancels it and waits for it to
+// stop.
+func (bg *background) wait(grace time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		bg.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(grace):
+		bg.cancel()
+		<-done
+	}
+	bg.cancel()
+}
+
+// startAutoPrune prunes state in the background at most once a day unless retention.auto
+// is false. It stays out of dry runs and the state commands, and does nothing when the
+// command tree was not started by Run.
+func startAutoPrune(cmd *cobra.Command, opts *config.GlobalOptions) {
+	if opts.DryRun || (opts.Retention.Auto != nil && !*opts.Retention.Auto) {
+		return
+	}
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Name() == "state" && c.Parent() != nil && c.Parent().Parent() == nil {
+			return
+		}
+	}
+	ctx := cmd.Context()
+	bg, _ := ctx.Value(backgroundKey{}).(*background)
+	if bg == nil {
+		return
+	}
+	policies, err := state.Policies(opts.Retention.Limits)
+	if err != nil {
+		warnings.Add(ctx, "retention", "automatic prune skipped: %v", err)
+		return
+	}
+	bg.wg.Add(1)
+	go func() {
+		defer bg.wg.Done()
+		if _, _, err := state.AutoPrune(ctx, policies, time.Now()); err != nil && ctx.Err() == nil {
+			warnings.Add(ctx, "retention", "automatic prune: %v", err)
+		}
+	}()
+}
+
+func statePassphrase(cmd *cobra.Command, opts *config.GlobalOptions, confirm bool) (string, error) {
+	if value := os.Getenv(statePassphraseEnv); value != "" {
+		return value, nil
+	}
+	if opts.NoInteractive {
+		return "", fmt.Errorf("passphrase required; set %s in no-interactive mode", statePassphraseEnv)
+	}
+	passphrase, err := promptForAPIKey(cmd, "State passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", errors.New("no passphrase provided")
+	}
+	if confirm {
+		again, err := promptForAPIKey(cmd, "Repeat passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again != passphrase {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+	return passphrase, nil
+}
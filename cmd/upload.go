@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/state"
+	"github.com/example/sre-ai/internal/upload"
+	"github.com/spf13/cobra"
+)
+
+// publish uploads body as name to the --upload destination and describes the outcome.
+func publish(cmd *cobra.Command, opts *config.GlobalOptions, destination, name string, body []byte, contentType string) (*upload.Object, string, error) {
+	obj, err := upload.Publish(cmd.Context(), opts, destination, name, body, contentType)
+	if err != nil {
+		return nil, "", err
+	}
+	return obj, uploadLine(obj), nil
+}
+
+func uploadLine(obj *upload.Object) string {
+	size := state.FormatSize(int64(obj.Size))
+	if obj.DryRun {
+		return fmt.Sprintf("Dry-run: would upload %s to %s", size, obj.URL)
+	}
+	return fmt.Sprintf("Uploaded %s to %s", size, obj.URL)
+}
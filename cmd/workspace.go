@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/example/sre-ai/internal/workspace"
+	"github.com/spf13/pflag"
+)
+
+const withWorkspaceUsage = "Detect the current repo's stack (kustomize, helm, terraform, docker, CI) and include it as context"
+
+func addWorkspaceFlag(flags *pflag.FlagSet, target *bool) {
+	flags.BoolVar(target, "with-workspace", false, withWorkspaceUsage)
+}
+
+// detectWorkspace inspects the working directory; nil is returned when detection is disabled.
+func detectWorkspace(enabled bool) (*workspace.Context, error) {
+	if !enabled {
+		return nil, nil
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	return workspace.Detect(dir)
+}
+
+func workspaceEvidence(ws *workspace.Context) map[string]any {
+	return map[string]any{
+		"type":       "workspace",
+		"root":       ws.Root,
+		"stacks":     ws.Stacks(),
+		"components": ws.Components,
+	}
+}
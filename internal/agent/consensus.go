@@ -0,0 +1,225 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/example/sre-ai/internal/providers"
+)
+
+// ConsensusSpec asks several models the same prompt and compares their answers.
+type ConsensusSpec struct {
+	// Models are provider/model targets, e.g. "gemini/gemini-2.5-flash" or "ollama/llama3.1".
+	Models []string `yaml:"models"`
+	// Quorum is how many answers must agree on a value; defaults to a strict majority.
+	Quorum int `yaml:"quorum"`
+	// OnConflict is "flag" (default: keep agreed fields, mark the step for review) or "fail".
+	OnConflict string `yaml:"on_conflict"`
+}
+
+// ConsensusConflict lists the competing answers for one field.
+type ConsensusConflict struct {
+	Path    string                 `json:"path"`
+	Answers map[string]interface{} `json:"answers"`
+}
+
+type consensusAnswer struct {
+	label string
+	text  string
+	data  interface{}
+	usage providers.Usage
+	err   error
+}
+
+func (r *Runner) executeConsensus(ctx context.Context, step StepSpec, prompt string) (map[string]interface{}, error) {
+	spec := step.Consensus
+	if len(spec.Models) < 2 {
+		return nil, fmt.Errorf("consensus needs at least two models, got %d", len(spec.Models))
+	}
+	quorum := spec.Quorum
+	if quorum <= 0 {
+		quorum = len(spec.Models)/2 + 1
+	}
+	if quorum > len(spec.Models) {
+		return nil, fmt.Errorf("consensus quorum %d exceeds %d models", quorum, len(spec.Models))
+	}
+	structured := strings.EqualFold(step.Expect.Format, "json")
+
+	answers := make([]consensusAnswer, len(spec.Models))
+	var wg sync.WaitGroup
+	for i, target := range spec.Models {
+		wg.Add(1)
+		go func(i int, target string) {
+			defer wg.Done()
+			answers[i] = r.askModel(ctx, target, prompt, structured)
+		}(i, target)
+	}
+	wg.Wait()
+
+	var ok []consensusAnswer
+	failures := map[string]string{}
+	var usage providers.Usage
+	for _, a := range answers {
+		usage.Add(a.usage)
+		if a.err != nil {
+			r.debugf("consensus model=%s error=%v", a.label, a.err)
+			failures[a.label] = a.err.Error()
+			continue
+		}
+		ok = append(ok, a)
+	}
+	r.lastUsage = &usage
+	if len(ok) < quorum {
+		return nil, fmt.Errorf("consensus: only %d of %d models answered, quorum is %d", len(ok), len(spec.Models), quorum)
+	}
+
+	values := make(map[string]interface{}, len(ok))
+	texts := make(map[string]interface{}, len(ok))
+	for _, a := range ok {
+		values[a.label] = a.data
+		texts[a.label] = a.text
+	}
+	merged, conflicts := mergeAnswers("", values, quorum)
+
+	summary := map[string]interface{}{
+		"models": spec.Models,
+		"quorum": quorum,
+		"status": "agreed",
+	}
+	if len(failures) > 0 {
+		summary["failures"] = failures
+	}
+	if len(conflicts) > 0 {
+		summary["status"] = "conflict"
+		summary["conflicts"] = conflicts
+		if strings.EqualFold(spec.OnConflict, "fail") {
+			paths := make([]string, 0, len(conflicts))
+			for _, c := range conflicts {
+				paths = append(paths, c.Path)
+			}
+			return nil, fmt.Errorf("consensus conflict on %s", strings.Join(paths, ", "))
+		}
+	}
+
+	payload := map[string]interface{}{
+		"consensus": summary,
+		"answers":   texts,
+	}
+	if structured {
+		payload["json"] = merged
+		data, _ := json.Marshal(merged)
+		payload["text"] = string(data)
+	} else {
+		payload["text"] = ""
+		for _, a := range ok {
+			if len(conflicts) == 0 && a.data == merged {
+				// Report the agreed answer as a model wrote it, not the normalised form.
+				payload["text"] = a.text
+				break
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		payload["needs_review"] = true
+	}
+	return payload, nil
+}
+
+func (r *Runner) askModel(ctx context.Context, target, prompt string, structured bool) consensusAnswer {
+	provider, model, err := providers.ParseTarget(target)
+	if err != nil {
+		return consensusAnswer{label: target, err: err}
+	}
+	label := fmt.Sprintf("%s/%s", provider, model)
+	client, err := providers.New(ctx, provider, model)
+	if err != nil {
+		return consensusAnswer{label: label, err: err}
+	}
+	text, usage, err := providers.GenerateWithUsage(ctx, client, prompt)
+	if err != nil {
+		return consensusAnswer{label: label, err: err}
+	}
+	answer := consensusAnswer{label: label, text: text, usage: usage}
+	if structured {
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(providers.StripCodeFence(text)), &decoded); err != nil {
+			answer.err = fmt.Errorf("expected json response but decode failed: %w", err)
+			return answer
+		}
+		answer.data = decoded
+	} else {
+		// Free text only agrees when it is the same answer modulo case and spacing.
+		answer.data = strings.Join(strings.Fields(strings.ToLower(text)), " ")
+	}
+	return answer
+}
+
+// mergeAnswers recursively merges objects field by field. A field is agreed when at least
+// quorum answers hold the same value; otherwise it is left out and reported as a conflict.
+func mergeAnswers(path string, values map[string]interface{}, quorum int) (interface{}, []ConsensusConflict) {
+	allObjects := true
+	for _, v := range values {
+		if _, ok := v.(map[string]interface{}); !ok {
+			allObjects = false
+			break
+		}
+	}
+	if allObjects && len(values) > 0 {
+		keys := map[string]bool{}
+		for _, v := range values {
+			for k := range v.(map[string]interface{}) {
+				keys[k] = true
+			}
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+
+		merged := map[string]interface{}{}
+		var conflicts []ConsensusConflict
+		for _, key := range sorted {
+			child := map[string]interface{}{}
+			for label, v := range values {
+				if field, ok := v.(map[string]interface{})[key]; ok {
+					child[label] = field
+				}
+			}
+			value, childConflicts := mergeAnswers(joinPath(path, key), child, quorum)
+			conflicts = append(conflicts, childConflicts...)
+			if value != nil || len(childConflicts) == 0 {
+				merged[key] = value
+			}
+		}
+		return merged, conflicts
+	}
+
+	counts := map[string]int{}
+	canonical := map[string]interface{}{}
+	for _, v := range values {
+		data, _ := json.Marshal(v)
+		counts[string(data)]++
+		canonical[string(data)] = v
+	}
+	for key, n := range counts {
+		if n >= quorum {
+			return canonical[key], nil
+		}
+	}
+	if path == "" {
+		path = "$"
+	}
+	return nil, []ConsensusConflict{{Path: path, Answers: values}}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
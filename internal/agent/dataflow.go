@@ -0,0 +1,533 @@
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// templateRoots are the top-level keys of the template data (see templateData).
+var templateRoots = map[string]bool{"inputs": true, "steps": true, "run": true, "repo": true, "workflow": true, "rollout": true}
+
+// DataFlow is the graph of values passed between a workflow's inputs, steps, and
+// outputs: an edge means a template of To reads a value From captures.
+type DataFlow struct {
+	Nodes []FlowNode `json:"nodes"`
+	Edges []FlowEdge `json:"edges"`
+}
+
+// FlowNode is an input, step, or output of the workflow.
+type FlowNode struct {
+	// ID is "input:<name>", "step:<name>", or "output:<name>".
+	ID    string `json:"id"`
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+	Stage string `json:"stage,omitempty"`
+	Type  string `json:"type,omitempty"`
+}
+
+// FlowEdge is one value read across nodes. Value is the capture alias read from a
+// step (or "_raw"/"params"), and empty for inputs and whole-step reads.
+type FlowEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Value string `json:"value,omitempty"`
+}
+
+// templateRef is a path into the template data read by a template, e.g. steps.load.thread.
+type templateRef struct {
+	path []string
+	pos  string
+}
+
+// flowStep is a step as a producer of values.
+type flowStep struct {
+	order    int
+	provides map[string]bool
+}
+
+// AnalyzeDataFlow builds the data-flow graph of the workflow and reports references
+// to steps that are undefined or run later, to values a step never captures, to
+// undeclared inputs, and to keys missing from the template data. Templates that do
+// not parse are left to Validate.
+func AnalyzeDataFlow(wf *Workflow) (*DataFlow, []LintIssue) {
+	flow := &DataFlow{}
+	var issues []LintIssue
+	report := func(severity, stage, step, format string, args ...interface{}) {
+		issues = append(issues, LintIssue{Severity: severity, Stage: stage, Step: step, Message: fmt.Sprintf(format, args...)})
+	}
+
+	inputNames := make([]string, 0, len(wf.Inputs))
+	for name := range wf.Inputs {
+		inputNames = append(inputNames, name)
+	}
+	sort.Strings(inputNames)
+	for _, name := range inputNames {
+		flow.Nodes = append(flow.Nodes, FlowNode{ID: "input:" + name, Kind: "input", Name: name})
+	}
+
+	steps := map[string]*flowStep{}
+	order := 0
+	for _, stage := range wf.Workflow.Stages {
+		for idx, step := range stage.Steps {
+			name := stepDisplayName(stage, idx, step)
+			steps[name] = &flowStep{order: order, provides: stepProvides(step)}
+			for ri, rb := range rollbackSteps(step) {
+				rbName := rb.Name
+				if rbName == "" {
+					rbName = fmt.Sprintf("%s_rollback_%d", name, ri+1)
+				}
+				steps[rbName] = &flowStep{order: order, provides: stepProvides(rb)}
+			}
+			flow.Nodes = append(flow.Nodes, FlowNode{ID: "step:" + name, Kind: "step", Name: name, Stage: stage.ID, Type: strings.ToLower(step.Type)})
+			if strings.EqualFold(step.Type, "prompt") && !strings.EqualFold(step.Expect.Format, "json") {
+				for alias, source := range step.Capture {
+					if source == "json" || strings.HasPrefix(source, "json.") {
+						report("warning", stage.ID, name, "capture %s reads %s, which is only set with expect.format: json", alias, source)
+					}
+				}
+			}
+			order++
+		}
+	}
+
+	edges := map[FlowEdge]bool{}
+	addEdge := func(edge FlowEdge) {
+		if !edges[edge] {
+			edges[edge] = true
+			flow.Edges = append(flow.Edges, edge)
+		}
+	}
+	check := func(stage, consumer, consumerID string, at int, self bool, refs []templateRef) {
+		for _, ref := range refs {
+			switch root := ref.path[0]; {
+			case root == "inputs":
+				if len(ref.path) < 2 {
+					continue
+				}
+				name := ref.path[1]
+				if _, ok := wf.Inputs[name]; !ok {
+					report("warning", stage, consumer, "%s reads input %q, which is not declared under inputs", ref.pos, name)
+					continue
+				}
+				addEdge(FlowEdge{From: "input:" + name, To: consumerID})
+			case root == "steps":
+				if len(ref.path) < 2 {
+					continue
+				}
+				name := ref.path[1]
+				producer, ok := steps[name]
+				if !ok {
+					report("error", stage, consumer, "%s reads undefined step %q", ref.pos, name)
+					continue
+				}
+				value := ""
+				if len(ref.path) > 2 {
+					value = ref.path[2]
+				}
+				if producer.order > at || producer.order == at && !self && value != "params" {
+					report("error", stage, consumer, "%s reads step %q, which has not run yet", ref.pos, name)
+					continue
+				}
+				if value != "" && !producer.provides[value] {
+					report("warning", stage, consumer, "%s reads steps.%s.%s, but %s never captures %s (it has %s)", ref.pos, name, value, name, value, strings.Join(sortedKeys(producer.provides), ", "))
+					continue
+				}
+				from := "step:" + name
+				if !hasNode(flow, from) {
+					// Rollback steps have no node of their own; they belong to their step.
+					from = rollbackOwner(wf, name)
+				}
+				if from != consumerID {
+					addEdge(FlowEdge{From: from, To: consumerID, Value: value})
+				}
+			case !templateRoots[root]:
+				report("warning", stage, consumer, "%s reads .%s, which is not in the template data (%s)", ref.pos, root, strings.Join(sortedKeys(templateRoots), ", "))
+			}
+		}
+	}
+
+	order = 0
+	for _, stage := range wf.Workflow.Stages {
+		for idx, step := range stage.Steps {
+			name := stepDisplayName(stage, idx, step)
+			id := "step:" + name
+			check(stage.ID, name, id, order, false, stepRefs(name, step))
+			// Verify blocks and rollback steps run after the step, so they may read it.
+			var after []templateRef
+			if v := step.Verify; v != nil {
+				for _, field := range []string{v.PromQL, v.Prometheus, v.Rollout, v.Namespace} {
+					after = append(after, templateRefs(name, field, false)...)
+				}
+			}
+			for _, rb := range rollbackSteps(step) {
+				after = append(after, stepRefs(name, rb)...)
+			}
+			if ro := step.Rollout; ro != nil {
+				for _, target := range ro.Targets {
+					after = append(after, templateRefs(name, target, false)...)
+				}
+				for _, nested := range append(append([]StepSpec(nil), ro.Apply...), ro.Rollback...) {
+					after = append(after, stepRefs(name, nested)...)
+				}
+				if v := ro.Verify; v != nil {
+					for _, field := range []string{v.PromQL, v.Prometheus, v.Rollout, v.Namespace} {
+						after = append(after, templateRefs(name, field, false)...)
+					}
+				}
+			}
+			check(stage.ID, name, id, order, true, after)
+			order++
+		}
+	}
+
+	outputNames := make([]string, 0, len(wf.Outputs))
+	for name := range wf.Outputs {
+		outputNames = append(outputNames, name)
+	}
+	sort.Strings(outputNames)
+	for _, name := range outputNames {
+		id := "output:" + name
+		flow.Nodes = append(flow.Nodes, FlowNode{ID: id, Kind: "output", Name: name})
+		check("outputs", name, id, order, true, templateRefs(name, wf.Outputs[name].Template, false))
+	}
+
+	// A whole-step read (such as with .steps.x) adds nothing when named values flow too.
+	labeled := map[[2]string]bool{}
+	for _, edge := range flow.Edges {
+		if edge.Value != "" {
+			labeled[[2]string{edge.From, edge.To}] = true
+		}
+	}
+	kept := flow.Edges[:0]
+	for _, edge := range flow.Edges {
+		if edge.Value != "" || !labeled[[2]string{edge.From, edge.To}] {
+			kept = append(kept, edge)
+		}
+	}
+	flow.Edges = kept
+	return flow, issues
+}
+
+// stepProvides lists the keys a step leaves in its step state.
+func stepProvides(step StepSpec) map[string]bool {
+	provides := map[string]bool{"_raw": true}
+	if len(step.Params) > 0 {
+		provides["params"] = true
+	}
+	if step.Verify != nil {
+		provides["verification"] = true
+	}
+	if step.Rollout != nil {
+		provides["rollout"] = true
+	}
+	for alias := range step.Capture {
+		provides[alias] = true
+	}
+	return provides
+}
+
+func rollbackSteps(step StepSpec) []StepSpec {
+	if step.Verify == nil {
+		return nil
+	}
+	return step.Verify.Rollback
+}
+
+func rollbackOwner(wf *Workflow, name string) string {
+	for _, stage := range wf.Workflow.Stages {
+		for idx, step := range stage.Steps {
+			owner := stepDisplayName(stage, idx, step)
+			for ri, rb := range rollbackSteps(step) {
+				if rb.Name == name || rb.Name == "" && name == fmt.Sprintf("%s_rollback_%d", owner, ri+1) {
+					return "step:" + owner
+				}
+			}
+		}
+	}
+	return "step:" + name
+}
+
+func hasNode(flow *DataFlow, id string) bool {
+	for _, node := range flow.Nodes {
+		if node.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// stepRefs collects the references of every template a step renders.
+func stepRefs(name string, step StepSpec) []templateRef {
+	refs := paramRefs(name, step.Params)
+	if strings.EqualFold(step.Type, "prompt") || strings.EqualFold(step.Type, "page") {
+		refs = append(refs, templateRefs(name, step.Template, false)...)
+	}
+	if p := step.Page; p != nil {
+		for _, field := range []string{p.Provider, p.Service, p.Severity, p.Environment, p.Title, p.Body, p.DedupKey} {
+			refs = append(refs, templateRefs(name, field, false)...)
+		}
+	}
+	for _, key := range sortedKeys(step.Facts) {
+		refs = append(refs, valueRefs(name, step.Facts[key])...)
+	}
+	if step.Wait != nil {
+		// .result is the polled tool output, available only to wait.until.
+		refs = append(refs, templateRefs(name, step.Wait.Until, true)...)
+	}
+	return refs
+}
+
+func paramRefs(name string, params map[string]interface{}) []templateRef {
+	var refs []templateRef
+	for _, key := range sortedKeys(params) {
+		refs = append(refs, valueRefs(name, params[key])...)
+	}
+	return refs
+}
+
+func valueRefs(name string, value interface{}) []templateRef {
+	switch v := value.(type) {
+	case string:
+		return templateRefs(name, v, false)
+	case map[string]interface{}:
+		return paramRefs(name, v)
+	case []interface{}:
+		var refs []templateRef
+		for _, item := range v {
+			refs = append(refs, valueRefs(name, item)...)
+		}
+		return refs
+	}
+	return nil
+}
+
+// templateRefs parses body and returns the template data paths it reads.
+func templateRefs(name, body string, withResult bool) []templateRef {
+	if !strings.Contains(body, "{{") {
+		return nil
+	}
+	tmpl, err := template.New(name).Funcs(templateFuncs()).Parse(body)
+	if err != nil {
+		return nil
+	}
+	var refs []templateRef
+	for _, t := range tmpl.Templates() {
+		if t.Tree == nil || t.Tree.Root == nil {
+			continue
+		}
+		c := &refCollector{tree: t.Tree, vars: map[string][]string{}}
+		c.walk(t.Tree.Root, false)
+		for _, ref := range c.refs {
+			if !(withResult && ref.path[0] == "result") {
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
+
+// refCollector walks a template tree recording the template data paths it reads.
+// Paths through a rebound dot (inside with or range) are not followed; variables
+// declared from a known path extend it.
+type refCollector struct {
+	tree *parse.Tree
+	vars map[string][]string
+	refs []templateRef
+}
+
+func (c *refCollector) walk(node parse.Node, rebound bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			c.walk(child, rebound)
+		}
+	case *parse.ActionNode:
+		path := c.pipe(n.Pipe, rebound)
+		for _, decl := range n.Pipe.Decl {
+			c.vars[decl.Ident[0]] = path
+		}
+	case *parse.IfNode:
+		c.branch(&n.BranchNode, rebound, false)
+	case *parse.WithNode:
+		c.branch(&n.BranchNode, rebound, true)
+	case *parse.RangeNode:
+		c.branch(&n.BranchNode, rebound, true)
+	case *parse.TemplateNode:
+		c.pipe(n.Pipe, rebound)
+	}
+}
+
+func (c *refCollector) branch(b *parse.BranchNode, rebound, rebinds bool) {
+	path := c.pipe(b.Pipe, rebound)
+	for _, decl := range b.Pipe.Decl {
+		c.vars[decl.Ident[0]] = path
+	}
+	c.walk(b.List, rebound || rebinds)
+	if b.ElseList != nil {
+		c.walk(b.ElseList, rebound)
+	}
+}
+
+// pipe records the references of a pipeline and returns the path it evaluates to when
+// it is a plain path or index expression.
+func (c *refCollector) pipe(pipe *parse.PipeNode, rebound bool) []string {
+	if pipe == nil {
+		return nil
+	}
+	var result []string
+	for i, cmd := range pipe.Cmds {
+		path := c.command(cmd, rebound)
+		if i == 0 && len(pipe.Cmds) == 1 {
+			result = path
+		}
+	}
+	return result
+}
+
+func (c *refCollector) command(cmd *parse.CommandNode, rebound bool) []string {
+	args := cmd.Args
+	if len(args) >= 2 {
+		if ident, ok := args[0].(*parse.IdentifierNode); ok && ident.Ident == "index" {
+			if base := c.resolve(args[1], rebound); base != nil {
+				path := append([]string{}, base...)
+				for _, arg := range args[2:] {
+					key, ok := arg.(*parse.StringNode)
+					if !ok {
+						break
+					}
+					path = append(path, key.Text)
+				}
+				c.add(path, cmd)
+				for _, arg := range args[2:] {
+					c.arg(arg, rebound)
+				}
+				return path
+			}
+		}
+	}
+	var path []string
+	for _, arg := range args {
+		if p := c.arg(arg, rebound); len(args) == 1 {
+			path = p
+		}
+	}
+	return path
+}
+
+func (c *refCollector) arg(arg parse.Node, rebound bool) []string {
+	if pipe, ok := arg.(*parse.PipeNode); ok {
+		return c.pipe(pipe, rebound)
+	}
+	path := c.resolve(arg, rebound)
+	if path != nil {
+		c.add(path, arg)
+	}
+	return path
+}
+
+// resolve returns the template data path an argument names, or nil.
+func (c *refCollector) resolve(arg parse.Node, rebound bool) []string {
+	switch a := arg.(type) {
+	case *parse.FieldNode:
+		if rebound {
+			return nil
+		}
+		return a.Ident
+	case *parse.DotNode:
+		if rebound {
+			return nil
+		}
+		return []string{}
+	case *parse.VariableNode:
+		if a.Ident[0] == "$" {
+			return a.Ident[1:]
+		}
+		if base, ok := c.vars[a.Ident[0]]; ok && base != nil {
+			return append(append([]string{}, base...), a.Ident[1:]...)
+		}
+	}
+	return nil
+}
+
+func (c *refCollector) add(path []string, node parse.Node) {
+	if len(path) == 0 {
+		return
+	}
+	loc, _ := c.tree.ErrorContext(node)
+	if _, rest, ok := strings.Cut(loc, ":"); ok {
+		loc = rest
+	}
+	c.refs = append(c.refs, templateRef{path: path, pos: loc})
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// DOT renders the graph in Graphviz DOT.
+func (f *DataFlow) DOT(name string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %q {\n  rankdir=LR;\n", name)
+	for _, node := range f.Nodes {
+		shape := map[string]string{"input": "ellipse", "step": "box", "output": "note"}[node.Kind]
+		fmt.Fprintf(&b, "  %q [label=%q, shape=%s];\n", node.ID, node.label(), shape)
+	}
+	for _, edge := range f.Edges {
+		if edge.Value != "" {
+			fmt.Fprintf(&b, "  %q -> %q [label=%q];\n", edge.From, edge.To, edge.Value)
+		} else {
+			fmt.Fprintf(&b, "  %q -> %q;\n", edge.From, edge.To)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart.
+func (f *DataFlow) Mermaid() string {
+	ids := map[string]string{}
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for i, node := range f.Nodes {
+		id := fmt.Sprintf("n%d", i)
+		ids[node.ID] = id
+		label := mermaidText(node.label())
+		switch node.Kind {
+		case "input":
+			fmt.Fprintf(&b, "  %s([\"%s\"])\n", id, label)
+		case "output":
+			fmt.Fprintf(&b, "  %s[/\"%s\"/]\n", id, label)
+		default:
+			fmt.Fprintf(&b, "  %s[\"%s\"]\n", id, label)
+		}
+	}
+	for _, edge := range f.Edges {
+		if edge.Value != "" {
+			fmt.Fprintf(&b, "  %s -->|%s| %s\n", ids[edge.From], edge.Value, ids[edge.To])
+		} else {
+			fmt.Fprintf(&b, "  %s --> %s\n", ids[edge.From], ids[edge.To])
+		}
+	}
+	return b.String()
+}
+
+func (n FlowNode) label() string {
+	switch n.Kind {
+	case "input":
+		return "input " + n.Name
+	case "output":
+		return "output " + n.Name
+	}
+	return fmt.Sprintf("%s/%s (%s)", n.Stage, n.Name, n.Type)
+}
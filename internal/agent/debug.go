@@ -0,0 +1,253 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/example/sre-ai/internal/messages"
+)
+
+// DebugPhase says whether a debugger pause comes before a step runs or after it ran.
+type DebugPhase string
+
+const (
+	DebugBefore DebugPhase = "before"
+	DebugAfter  DebugPhase = "after"
+)
+
+// DebugAction is what a debugger decides at a pause.
+type DebugAction string
+
+const (
+	// DebugContinue runs the step (before) or moves on to the next one (after).
+	DebugContinue DebugAction = "continue"
+	// DebugSkip records the step as skipped without running it. Only valid before.
+	DebugSkip DebugAction = "skip"
+	// DebugRerun pauses before the same step again. Only valid after.
+	DebugRerun DebugAction = "rerun"
+	// DebugAbort stops the run.
+	DebugAbort DebugAction = "abort"
+)
+
+// ErrDebugAbort is returned when the run was stopped from the debugger.
+var ErrDebugAbort = errors.New("run aborted from the debugger")
+
+// DebugPoint is a step paused under a debugger. Before the step runs, Params holds its
+// rendered params, which the debugger may replace or edit, and Prompt the rendered
+// template of a prompt step; RenderError is set when either failed to render. After the
+// step ran, Result is its recorded result and Err the error that would stop the run.
+type DebugPoint struct {
+	Phase    DebugPhase
+	Stage    StageSpec
+	StepName string
+	Step     StepSpec
+	// Attempt is 0 for the first run of the step and counts re-runs after that.
+	Attempt     int
+	Params      map[string]interface{}
+	Prompt      string
+	RenderError error
+	Result      *StepResult
+	Err         error
+	// Inputs and State are the run's inputs and step state, for inspection only.
+	Inputs map[string]interface{}
+	State  map[string]map[string]interface{}
+	// Breakpoint is set when the step was named in SetBreakpoints.
+	Breakpoint bool
+}
+
+// StepDebugger is consulted before and after every step of a run.
+type StepDebugger func(ctx context.Context, point *DebugPoint) (DebugAction, error)
+
+// SetDebugger installs a debugger that pauses the run around every step. It has no
+// effect in plan mode.
+func (r *Runner) SetDebugger(debugger StepDebugger) {
+	r.debugger = debugger
+}
+
+// SetBreakpoints marks steps to stop at, each named stage.step or by step name alone.
+// Without a debugger the run stops before the first breakpoint it reaches, recording the
+// step with its rendered params and prompt; with one, the points are flagged Breakpoint.
+func (r *Runner) SetBreakpoints(names []string) error {
+	r.breakpoints = map[string]bool{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		var matched []string
+		for _, stage := range r.workflow.Workflow.Stages {
+			for idx, step := range stage.Steps {
+				stepName := step.Name
+				if stepName == "" {
+					stepName = fmt.Sprintf("%s_step_%d", stage.ID, idx+1)
+				}
+				if name == stage.ID+"."+stepName || name == stepName {
+					matched = append(matched, stage.ID+"."+stepName)
+				}
+			}
+		}
+		switch len(matched) {
+		case 0:
+			return fmt.Errorf("breakpoint %s: no such step; use stage.step", name)
+		case 1:
+			r.breakpoints[matched[0]] = true
+		default:
+			return fmt.Errorf("breakpoint %s matches %s; use stage.step", name, strings.Join(matched, ", "))
+		}
+	}
+	return nil
+}
+
+func (r *Runner) atBreakpoint(stage StageSpec, stepName string) bool {
+	return r.breakpoints[stage.ID+"."+stepName]
+}
+
+// stopAtBreakpoint records the step the run stops at, rendered but not run.
+func (r *Runner) stopAtBreakpoint(res *Result, stage StageSpec, stepName string, step StepSpec, sr StepResult) {
+	sr.Status = "breakpoint"
+	params, err := r.renderParams(step.Params)
+	if err == nil && strings.EqualFold(step.Type, "prompt") {
+		sr.Prompt, err = r.renderTemplate(step.Template)
+	}
+	if len(params) > 0 {
+		sr.Output = map[string]interface{}{"params": params}
+	}
+	if err != nil {
+		sr.Error = err.Error()
+	}
+	res.StoppedAt = stage.ID + "." + stepName
+	r.record(res, stage, sr)
+	r.debugf("stopped at breakpoint stage=%s step=%s", stage.ID, stepName)
+}
+
+// Watch evaluates a watch expression against the template data. The expression is a
+// dotted path such as steps.triage.json.errorRate (a leading dot is allowed), or a
+// template when it contains "{{". A path that leads nowhere yields nil.
+func (r *Runner) Watch(expr string) (interface{}, error) {
+	expr = strings.TrimSpace(expr)
+	if strings.Contains(expr, "{{") {
+		return r.renderTemplate(expr)
+	}
+	data := r.templateData()
+	steps := make(map[string]interface{}, len(r.stepState))
+	for name, state := range r.stepState {
+		steps[name] = state
+	}
+	data["steps"] = steps
+	var current interface{} = data
+	for _, part := range strings.Split(strings.TrimPrefix(expr, "."), ".") {
+		switch typed := current.(type) {
+		case map[string]interface{}:
+			current = typed[part]
+		case []interface{}:
+			idx, err := strconv.Atoi(part)
+			if err != nil || idx < 0 || idx >= len(typed) {
+				return nil, nil
+			}
+			current = typed[idx]
+		default:
+			return nil, nil
+		}
+	}
+	return current, nil
+}
+
+// debugStep runs one step under the debugger, re-running it for as long as asked to.
+// A re-run starts from the params of the previous attempt, edits included. Every
+// attempt is recorded, so history shows what actually ran.
+func (r *Runner) debugStep(ctx context.Context, res *Result, stage StageSpec, stepName string, step StepSpec, sr StepResult) error {
+	var lastParams map[string]interface{}
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && IsHighRisk(stage, step) {
+			allowed, err := r.checkGate(ctx, stage, stepName, step)
+			if err != nil || !allowed {
+				if err == nil {
+					err = messages.Error(ctx, messages.HighRiskBlocked, stepName)
+				}
+				sr.Status = "blocked"
+				sr.Error = err.Error()
+				sr.Attempt = attempt
+				r.record(res, stage, sr)
+				return err
+			}
+		}
+
+		point := r.debugPoint(DebugBefore, stage, stepName, step, attempt)
+		if lastParams != nil {
+			point.Params = copyParams(lastParams)
+		} else {
+			point.Params, point.RenderError = r.renderParams(step.Params)
+		}
+		if point.RenderError == nil && strings.EqualFold(step.Type, "prompt") {
+			point.Prompt, point.RenderError = r.renderTemplate(step.Template)
+		}
+		action, err := r.debugger(ctx, point)
+		if err != nil {
+			return err
+		}
+		switch action {
+		case DebugSkip:
+			sr.Status = "skipped"
+			sr.Attempt = attempt
+			r.record(res, stage, sr)
+			r.debugf("skipped step stage=%s step=%s from the debugger", stage.ID, stepName)
+			return nil
+		case DebugAbort:
+			return ErrDebugAbort
+		}
+
+		// When params failed to render and were not edited, the step renders them
+		// again and fails the same way.
+		r.debugParams = point.Params
+		lastParams = point.Params
+		sr.Attempt = attempt
+		stepErr := r.runStep(ctx, res, stage, stepName, step, sr)
+		r.debugParams = nil
+
+		point = r.debugPoint(DebugAfter, stage, stepName, step, attempt)
+		point.Err = stepErr
+		for i := len(res.Steps) - 1; i >= 0; i-- {
+			if res.Steps[i].StageID == stage.ID && res.Steps[i].StepName == stepName {
+				point.Result = &res.Steps[i]
+				break
+			}
+		}
+		action, err = r.debugger(ctx, point)
+		if err != nil {
+			return err
+		}
+		switch action {
+		case DebugRerun:
+			continue
+		case DebugAbort:
+			if stepErr != nil {
+				return stepErr
+			}
+			return ErrDebugAbort
+		}
+		return stepErr
+	}
+}
+
+// copyParams copies the top level of params so edits to a re-run leave the recorded
+// params of the previous attempt alone.
+func copyParams(params map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(params))
+	for key, value := range params {
+		out[key] = value
+	}
+	return out
+}
+
+func (r *Runner) debugPoint(phase DebugPhase, stage StageSpec, stepName string, step StepSpec, attempt int) *DebugPoint {
+	return &DebugPoint{
+		Phase:      phase,
+		Stage:      stage,
+		StepName:   stepName,
+		Step:       step,
+		Attempt:    attempt,
+		Inputs:     r.inputs,
+		State:      r.stepState,
+		Breakpoint: r.atBreakpoint(stage, stepName),
+	}
+}
@@ -0,0 +1,206 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/example/sre-ai/internal/notify"
+)
+
+// Rough figures behind plan estimates. They are meant to show the relative weight of
+// steps before a run, not to predict billing.
+const (
+	charsPerToken        = 4
+	defaultOutputTokens  = 512
+	promptBaseSeconds    = 2.0
+	outputTokensPerSec   = 50.0
+	promptTokensPerSec   = 2000.0
+	mcpToolSeconds       = 1.0
+	gitToolSeconds       = 0.5
+	wasmToolSeconds      = 0.5
+	unknownOutputComment = "size of %s output is unknown until it runs"
+)
+
+// StepEstimate approximates what one step costs when it runs. Under --plan, sample and
+// mock tools are evaluated so prompts render against their data; other tool output is
+// unknown and only the template itself is counted.
+type StepEstimate struct {
+	ProviderCalls int      `json:"provider_calls"`
+	ToolCalls     int      `json:"tool_calls"`
+	PromptTokens  int      `json:"prompt_tokens"`
+	OutputTokens  int      `json:"output_tokens"`
+	Seconds       float64  `json:"seconds"`
+	Notes         []string `json:"notes,omitempty"`
+}
+
+// PlanEstimate totals the step estimates of a planned run.
+type PlanEstimate struct {
+	ProviderCalls int     `json:"provider_calls"`
+	ToolCalls     int     `json:"tool_calls"`
+	PromptTokens  int     `json:"prompt_tokens"`
+	OutputTokens  int     `json:"output_tokens"`
+	Seconds       float64 `json:"seconds"`
+	// Partial is set when some prompt depends on tool output that could not be sized.
+	Partial bool `json:"partial,omitempty"`
+}
+
+func (p *PlanEstimate) add(est StepEstimate, partial bool) {
+	p.ProviderCalls += est.ProviderCalls
+	p.ToolCalls += est.ToolCalls
+	p.PromptTokens += est.PromptTokens
+	p.OutputTokens += est.OutputTokens
+	p.Seconds += est.Seconds
+	p.Partial = p.Partial || partial
+}
+
+// estimateStep sizes a step without calling providers or live tools. unsized names the
+// earlier steps whose output could not be produced in plan mode.
+func (r *Runner) estimateStep(ctx context.Context, stage StageSpec, stepName string, step StepSpec, unsized map[string]bool) (StepEstimate, bool) {
+	est, partial := r.estimateStepBody(ctx, stage, stepName, step, unsized)
+	if step.Verify != nil {
+		if timings, err := step.Verify.timings(); err == nil {
+			est.Notes = append(est.Notes, fmt.Sprintf("verifies every %s for up to %s; %d rollback step(s) on failure", timings.interval, timings.window, len(step.Verify.Rollback)))
+		}
+	}
+	return est, partial
+}
+
+func (r *Runner) estimateStepBody(ctx context.Context, stage StageSpec, stepName string, step StepSpec, unsized map[string]bool) (StepEstimate, bool) {
+	var est StepEstimate
+	switch strings.ToLower(step.Type) {
+	case "tool":
+		est.ToolCalls = 1
+		spec := r.workflow.Tools[step.Tool]
+		switch strings.ToLower(spec.Kind) {
+		case "mock", "sample":
+			if _, err := r.executeStep(ctx, stage, stepName, step); err != nil {
+				est.Notes = append(est.Notes, fmt.Sprintf("sample data unavailable: %v", err))
+				unsized[stepName] = true
+			}
+		case "mcp":
+			est.Seconds = mcpToolSeconds
+			if step.Fanout != nil && len(step.Fanout.Aliases) > 0 {
+				// The aliases are called in parallel.
+				est.ToolCalls = len(step.Fanout.Aliases)
+			}
+			unsized[stepName] = true
+		case "git":
+			est.Seconds = gitToolSeconds
+			unsized[stepName] = true
+		case "wasm":
+			est.Seconds = wasmToolSeconds
+			unsized[stepName] = true
+		default:
+			unsized[stepName] = true
+		}
+		return est, false
+
+	case "prompt":
+		est.ProviderCalls = 1
+		if step.Consensus != nil && len(step.Consensus.Models) > 0 {
+			est.ProviderCalls = len(step.Consensus.Models)
+		}
+		text, err := r.renderTemplate(step.Template)
+		if err != nil {
+			text = step.Template
+			est.Notes = append(est.Notes, fmt.Sprintf("template did not render (%v); counted unrendered", err))
+		}
+		var pending []string
+		for name := range unsized {
+			if strings.Contains(step.Template, name) {
+				pending = append(pending, name)
+			}
+		}
+		sort.Strings(pending)
+		for _, name := range pending {
+			est.Notes = append(est.Notes, fmt.Sprintf(unknownOutputComment, name))
+		}
+		partial := len(pending) > 0
+		perCallOutput := defaultOutputTokens
+		if r.opts != nil && r.opts.MaxTokens > 0 {
+			perCallOutput = r.opts.MaxTokens
+		}
+		promptTokens := (len(text) + charsPerToken - 1) / charsPerToken
+		est.PromptTokens = promptTokens * est.ProviderCalls
+		est.OutputTokens = perCallOutput * est.ProviderCalls
+		// Consensus asks its models in parallel, so wall time is that of one call.
+		est.Seconds = promptBaseSeconds + float64(promptTokens)/promptTokensPerSec + float64(perCallOutput)/outputTokensPerSec
+		return est, partial
+
+	case "wait":
+		if step.Wait == nil {
+			return est, false
+		}
+		timings, err := step.Wait.timings()
+		if err != nil {
+			est.Notes = append(est.Notes, err.Error())
+			return est, false
+		}
+		est.Seconds = timings.sleep.Seconds()
+		if strings.TrimSpace(step.Wait.Until) != "" {
+			// At least one poll; the real count depends on when the condition holds.
+			est.ToolCalls = 1
+			est.Notes = append(est.Notes, fmt.Sprintf("polls every %s for up to %s", timings.interval, timings.timeout))
+			unsized[stepName] = true
+		}
+		return est, false
+
+	case "script":
+		// Scripts are local and cheap, so they run in plan mode for later prompts to size.
+		if _, err := r.executeStep(ctx, stage, stepName, step); err != nil {
+			est.Notes = append(est.Notes, fmt.Sprintf("script did not run in plan mode: %v", err))
+			unsized[stepName] = true
+		}
+		return est, false
+
+	case "rollout":
+		ro := step.Rollout
+		if ro == nil {
+			return est, false
+		}
+		unsized[stepName] = true
+		targets, err := r.rolloutTargets(ro)
+		if err != nil {
+			est.Notes = append(est.Notes, fmt.Sprintf("targets did not render in plan mode (%v); not sized", err))
+			return est, false
+		}
+		waves, err := ro.rolloutWaves(targets)
+		if err != nil {
+			est.Notes = append(est.Notes, err.Error())
+			return est, false
+		}
+		// Only the apply steps are counted: rollback steps run when a gate fails.
+		for _, nested := range ro.Apply {
+			if strings.EqualFold(nested.Type, "tool") {
+				est.ToolCalls += len(targets)
+				est.Seconds += mcpToolSeconds * float64(len(targets))
+			}
+		}
+		est.Notes = append(est.Notes, fmt.Sprintf("applies to %d target(s) in %d wave(s), canary %s", len(targets), len(waves), strings.Join(waves[0], ", ")))
+		if ro.Verify != nil {
+			if timings, err := ro.Verify.timings(); err == nil {
+				est.Notes = append(est.Notes, fmt.Sprintf("each wave is gated by checks every %s for up to %s; %d rollback step(s) per target on failure", timings.interval, timings.window, len(ro.Rollback)))
+			}
+		}
+		return est, false
+
+	case "page":
+		if step.Page == nil || strings.TrimSpace(step.Page.Title) != "" {
+			return est, false
+		}
+		// The model drafts the title and body; the page itself is a quick API call.
+		est.ProviderCalls = 1
+		context, err := r.renderTemplate(step.Template)
+		if err != nil {
+			context = step.Template
+		}
+		est.PromptTokens = (len(notify.DraftPrompt(context)) + charsPerToken - 1) / charsPerToken
+		est.OutputTokens = defaultOutputTokens
+		est.Seconds = promptBaseSeconds + float64(est.PromptTokens)/promptTokensPerSec + float64(defaultOutputTokens)/outputTokensPerSec
+		est.Notes = append(est.Notes, "drafts the page with the model; pages only after confirmation")
+		return est, false
+	}
+	return est, false
+}
@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/example/sre-ai/internal/facts"
+)
+
+// factStore opens the fact store of the runner's session on first use.
+func (r *Runner) factStore(ctx context.Context) (*facts.Store, error) {
+	if r.facts != nil {
+		return r.facts, nil
+	}
+	session := ""
+	if r.opts != nil {
+		session = r.opts.Session
+	}
+	store, err := facts.Open(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+	r.facts = store
+	return store, nil
+}
+
+// fact backs the {{ fact "service.key" }} template function. A missing key renders as
+// the optional fallback, or an empty string.
+func (r *Runner) fact(key string, fallback ...interface{}) (interface{}, error) {
+	store, err := r.factStore(r.settings)
+	if err != nil {
+		return nil, err
+	}
+	if value, ok := store.Get(key); ok {
+		return value, nil
+	}
+	if len(fallback) > 0 {
+		return fallback[0], nil
+	}
+	return "", nil
+}
+
+// executeSetFact stores the step's rendered facts in the session's fact store.
+func (r *Runner) executeSetFact(ctx context.Context, step StepSpec) (map[string]interface{}, error) {
+	if len(step.Facts) == 0 {
+		return nil, fmt.Errorf("set-fact step has no facts")
+	}
+	values := make(map[string]interface{}, len(step.Facts))
+	keys := make([]string, 0, len(step.Facts))
+	for key, raw := range step.Facts {
+		value, err := r.renderValue(raw)
+		if err != nil {
+			return nil, fmt.Errorf("fact %s: %w", key, err)
+		}
+		values[key] = value
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	store, err := r.factStore(ctx)
+	if err != nil {
+		return nil, err
+	}
+	source := "workflow " + r.workflow.Name
+	if r.runID != "" {
+		source = fmt.Sprintf("%s (run %s)", source, r.runID)
+	}
+	if err := store.Set(values, source); err != nil {
+		return nil, err
+	}
+	r.debugf("set-fact session=%s keys=%v", store.Session, keys)
+	return map[string]interface{}{"session": store.Session, "facts": values}, nil
+}
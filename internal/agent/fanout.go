@@ -0,0 +1,139 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// FanoutSpec runs an mcp tool step against several aliases at once, e.g. the same query
+// against the prod and staging database servers, and keys the results by alias.
+type FanoutSpec struct {
+	// Aliases are the MCP servers to call; each may be a template.
+	Aliases []string `yaml:"aliases"`
+	// MinSuccess is how many aliases must succeed for the step to pass; defaults to all.
+	MinSuccess int `yaml:"min_success"`
+}
+
+type fanoutAnswer struct {
+	alias  string
+	result map[string]interface{}
+	err    error
+}
+
+// executeFanout calls the step's tool once per alias in parallel. The payload holds each
+// alias's usual tool result under results, their decoded JSON under json, and whether the
+// successful answers are identical under fanout.same.
+func (r *Runner) executeFanout(ctx context.Context, step StepSpec, spec ToolSpec, params map[string]interface{}) (map[string]interface{}, error) {
+	fanout := step.Fanout
+	if !strings.EqualFold(spec.Kind, "mcp") {
+		return nil, fmt.Errorf("fanout needs an mcp tool, %s is %s", step.Tool, spec.Kind)
+	}
+	if _, ok := params["alias"]; ok {
+		return nil, fmt.Errorf("fanout sets the alias; drop params.alias")
+	}
+	aliases := make([]string, 0, len(fanout.Aliases))
+	seen := map[string]bool{}
+	for _, raw := range fanout.Aliases {
+		alias, err := r.renderTemplate(raw)
+		if err != nil {
+			return nil, fmt.Errorf("fanout alias %q: %w", raw, err)
+		}
+		alias = strings.TrimSpace(alias)
+		if alias == "" || strings.Contains(alias, "<no value>") {
+			return nil, fmt.Errorf("fanout alias %q references a value that is not set", raw)
+		}
+		if seen[alias] {
+			return nil, fmt.Errorf("fanout lists alias %s twice", alias)
+		}
+		seen[alias] = true
+		aliases = append(aliases, alias)
+	}
+	if len(aliases) < 2 {
+		return nil, fmt.Errorf("fanout needs at least two aliases, got %d", len(aliases))
+	}
+	minSuccess := fanout.MinSuccess
+	if minSuccess <= 0 {
+		minSuccess = len(aliases)
+	}
+	if minSuccess > len(aliases) {
+		return nil, fmt.Errorf("fanout min_success %d exceeds %d aliases", minSuccess, len(aliases))
+	}
+
+	answers := make([]fanoutAnswer, len(aliases))
+	var wg sync.WaitGroup
+	for i, alias := range aliases {
+		callParams := make(map[string]interface{}, len(params)+1)
+		for k, v := range params {
+			callParams[k] = v
+		}
+		callParams["alias"] = alias
+		wg.Add(1)
+		go func(i int, alias string, callParams map[string]interface{}) {
+			defer wg.Done()
+			result, err := r.executeMCPTool(ctx, step.Tool, spec, step, callParams)
+			answers[i] = fanoutAnswer{alias: alias, result: result, err: err}
+		}(i, alias, callParams)
+	}
+	wg.Wait()
+
+	results := make(map[string]interface{}, len(aliases))
+	decoded := map[string]interface{}{}
+	failures := map[string]string{}
+	var succeeded []fanoutAnswer
+	for _, a := range answers {
+		if a.result != nil {
+			results[a.alias] = a.result
+		}
+		if a.err != nil {
+			failures[a.alias] = a.err.Error()
+			continue
+		}
+		succeeded = append(succeeded, a)
+		if v, ok := a.result["json"]; ok {
+			decoded[a.alias] = v
+		}
+	}
+	same := len(succeeded) > 1
+	for i := 1; i < len(succeeded) && same; i++ {
+		same = sameToolOutput(succeeded[0].result, succeeded[i].result)
+	}
+
+	summary := map[string]interface{}{
+		"aliases":     aliases,
+		"min_success": minSuccess,
+		"succeeded":   len(succeeded),
+		"same":        same,
+	}
+	if len(failures) > 0 {
+		summary["failures"] = failures
+	}
+	payload := map[string]interface{}{
+		"fanout":  summary,
+		"results": results,
+		"json":    decoded,
+	}
+	if len(succeeded) < minSuccess {
+		names := make([]string, 0, len(failures))
+		for _, alias := range aliases {
+			if msg, ok := failures[alias]; ok {
+				names = append(names, alias+": "+msg)
+			}
+		}
+		return payload, fmt.Errorf("fanout: %d of %d aliases succeeded, need %d (%s)", len(succeeded), len(aliases), minSuccess, strings.Join(names, "; "))
+	}
+	return payload, nil
+}
+
+// sameToolOutput compares the decoded JSON of two results when both have it, and their
+// stdout otherwise.
+func sameToolOutput(a, b map[string]interface{}) bool {
+	aj, aok := a["json"]
+	bj, bok := b["json"]
+	if aok && bok {
+		return reflect.DeepEqual(aj, bj)
+	}
+	return a["stdout"] == b["stdout"]
+}
@@ -0,0 +1,238 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/example/sre-ai/internal/timefmt"
+)
+
+// Flowchart is a workflow's stages and steps in run order, annotated with the
+// results of a run when one is given.
+type Flowchart struct {
+	Workflow string       `json:"workflow"`
+	RunID    string       `json:"run_id,omitempty"`
+	Stages   []ChartStage `json:"stages"`
+}
+
+// ChartStage is one stage of a Flowchart.
+type ChartStage struct {
+	ID    string      `json:"id"`
+	Kind  string      `json:"kind,omitempty"`
+	Steps []ChartStep `json:"steps"`
+}
+
+// ChartStep is one step of a Flowchart. Status is empty without a run and "not_run"
+// for a step the run never reached; Attempts counts the times the run recorded it.
+type ChartStep struct {
+	Name     string        `json:"name"`
+	Type     string        `json:"type"`
+	HighRisk bool          `json:"high_risk,omitempty"`
+	Status   string        `json:"status,omitempty"`
+	Verified string        `json:"verified,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+	Attempts int           `json:"attempts,omitempty"`
+	Rollback []ChartStep   `json:"rollback,omitempty"`
+}
+
+// chartStatusColors are the fill and stroke used for each step status.
+var chartStatusColors = map[string][2]string{
+	"ok":           {"#d4edda", "#28a745"},
+	"error":        {"#f8d7da", "#dc3545"},
+	"blocked":      {"#f8d7da", "#dc3545"},
+	"failed":       {"#f8d7da", "#dc3545"},
+	"needs_review": {"#fff3cd", "#ffc107"},
+	"breakpoint":   {"#fff3cd", "#ffc107"},
+	"skipped":      {"#e2e3e5", "#6c757d"},
+	"not_run":      {"#e2e3e5", "#6c757d"},
+}
+
+// NewFlowchart lays out the workflow. When res is not nil each step carries the status
+// and duration of its last recorded attempt in res.
+func NewFlowchart(wf *Workflow, res *Result) *Flowchart {
+	chart := &Flowchart{Workflow: wf.Name}
+	if res != nil {
+		chart.RunID = res.RunID
+	}
+	annotate := func(cs *ChartStep, stageID string) {
+		if res == nil {
+			return
+		}
+		cs.Status = "not_run"
+		for _, sr := range res.Steps {
+			if sr.StageID != stageID || sr.StepName != cs.Name {
+				continue
+			}
+			cs.Attempts++
+			cs.Status = sr.Status
+			cs.Duration = sr.Duration
+			cs.Verified = ""
+			if sr.Verification != nil {
+				cs.Verified = sr.Verification.Status
+			}
+		}
+	}
+	for _, stage := range wf.Workflow.Stages {
+		cs := ChartStage{ID: stage.ID, Kind: stage.Kind}
+		for idx, step := range stage.Steps {
+			name := stepDisplayName(stage, idx, step)
+			node := ChartStep{Name: name, Type: strings.ToLower(step.Type), HighRisk: IsHighRisk(stage, step)}
+			annotate(&node, stage.ID)
+			for ri, rb := range rollbackSteps(step) {
+				rbName := rb.Name
+				if rbName == "" {
+					rbName = fmt.Sprintf("%s_rollback_%d", name, ri+1)
+				}
+				rbNode := ChartStep{Name: rbName, Type: strings.ToLower(rb.Type), HighRisk: IsHighRisk(stage, rb)}
+				annotate(&rbNode, stage.ID)
+				node.Rollback = append(node.Rollback, rbNode)
+			}
+			cs.Steps = append(cs.Steps, node)
+		}
+		chart.Stages = append(chart.Stages, cs)
+	}
+	return chart
+}
+
+// class is the status a step is drawn with: a failed verification outranks the step's
+// own "ok".
+func (s ChartStep) class() string {
+	if s.Verified == "failed" {
+		return "failed"
+	}
+	return s.Status
+}
+
+// lines are the rows of the step's label.
+func (s ChartStep) lines() []string {
+	lines := []string{s.Name}
+	detail := []string{s.Type}
+	if s.HighRisk {
+		detail = append(detail, "high risk")
+	}
+	lines = append(lines, strings.Join(detail, ", "))
+	if s.Status != "" {
+		status := []string{strings.ReplaceAll(s.Status, "_", " ")}
+		if s.Verified != "" {
+			status = append(status, "verify "+s.Verified)
+		}
+		if s.Duration > 0 {
+			status = append(status, timefmt.Duration(s.Duration))
+		}
+		if s.Attempts > 1 {
+			status = append(status, fmt.Sprintf("%d attempts", s.Attempts))
+		}
+		lines = append(lines, strings.Join(status, ", "))
+	}
+	return lines
+}
+
+func (s ChartStage) title() string {
+	if s.Kind != "" {
+		return fmt.Sprintf("%s (%s)", s.ID, s.Kind)
+	}
+	return s.ID
+}
+
+// chartNode is a step with the identifier it is drawn under.
+type chartNode struct {
+	id   string
+	step ChartStep
+}
+
+// walk calls stage for each stage with its steps and their rollback steps, and edge for
+// each arrow: between consecutive steps, and dashed from a step to its rollback.
+func (f *Flowchart) walk(stage func(idx int, s ChartStage, nodes []chartNode), edge func(from, to string, rollback bool)) {
+	prev := ""
+	for si, s := range f.Stages {
+		var nodes []chartNode
+		for i, step := range s.Steps {
+			id := fmt.Sprintf("s%d_%d", si, i+1)
+			nodes = append(nodes, chartNode{id: id, step: step})
+			for ri, rb := range step.Rollback {
+				nodes = append(nodes, chartNode{id: fmt.Sprintf("%s_rb%d", id, ri+1), step: rb})
+			}
+		}
+		stage(si, s, nodes)
+		for i, step := range s.Steps {
+			id := fmt.Sprintf("s%d_%d", si, i+1)
+			if prev != "" {
+				edge(prev, id, false)
+			}
+			from := id
+			for ri := range step.Rollback {
+				rbID := fmt.Sprintf("%s_rb%d", id, ri+1)
+				edge(from, rbID, true)
+				from = rbID
+			}
+			prev = id
+		}
+	}
+}
+
+// Mermaid renders the chart as a Mermaid flowchart.
+func (f *Flowchart) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+	classes := map[string][]string{}
+	f.walk(func(idx int, s ChartStage, nodes []chartNode) {
+		fmt.Fprintf(&b, "  subgraph stage%d[\"%s\"]\n", idx, mermaidText(s.title()))
+		for _, node := range nodes {
+			lines := node.step.lines()
+			for i := range lines {
+				lines[i] = mermaidText(lines[i])
+			}
+			fmt.Fprintf(&b, "    %s[\"%s\"]\n", node.id, strings.Join(lines, "<br/>"))
+			if class := node.step.class(); class != "" {
+				classes[class] = append(classes[class], node.id)
+			}
+		}
+		b.WriteString("  end\n")
+	}, func(from, to string, rollback bool) {
+		if rollback {
+			fmt.Fprintf(&b, "  %s -. rollback .-> %s\n", from, to)
+		} else {
+			fmt.Fprintf(&b, "  %s --> %s\n", from, to)
+		}
+	})
+	for _, status := range sortedKeys(classes) {
+		color, ok := chartStatusColors[status]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(&b, "  classDef %s fill:%s,stroke:%s\n", status, color[0], color[1])
+		fmt.Fprintf(&b, "  class %s %s\n", strings.Join(classes[status], ","), status)
+	}
+	return b.String()
+}
+
+// DOT renders the chart in Graphviz DOT, one cluster per stage.
+func (f *Flowchart) DOT() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %q {\n  node [shape=box, style=\"rounded,filled\", fillcolor=white];\n", f.Workflow)
+	f.walk(func(idx int, s ChartStage, nodes []chartNode) {
+		fmt.Fprintf(&b, "  subgraph cluster_%d {\n    label=%q;\n", idx, s.title())
+		for _, node := range nodes {
+			fmt.Fprintf(&b, "    %s [label=%q", node.id, strings.Join(node.step.lines(), "\n"))
+			if color, ok := chartStatusColors[node.step.class()]; ok {
+				fmt.Fprintf(&b, ", fillcolor=%q, color=%q", color[0], color[1])
+			}
+			b.WriteString("];\n")
+		}
+		b.WriteString("  }\n")
+	}, func(from, to string, rollback bool) {
+		if rollback {
+			fmt.Fprintf(&b, "  %s -> %s [style=dashed, label=\"rollback\"];\n", from, to)
+		} else {
+			fmt.Fprintf(&b, "  %s -> %s;\n", from, to)
+		}
+	})
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// mermaidText escapes the characters Mermaid treats as markup inside a quoted label.
+func mermaidText(text string) string {
+	return strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;").Replace(text)
+}
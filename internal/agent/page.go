@@ -0,0 +1,190 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/example/sre-ai/internal/confirm"
+	"github.com/example/sre-ai/internal/messages"
+	"github.com/example/sre-ai/internal/notify"
+	"github.com/example/sre-ai/internal/providers"
+	"github.com/example/sre-ai/internal/warnings"
+)
+
+// PageSpec is the page block of a page step. Every field but Window is a template.
+// Without a title the model drafts title and body from the step's template.
+type PageSpec struct {
+	// Provider is pagerduty or opsgenie.
+	Provider    string `yaml:"provider"`
+	Service     string `yaml:"service"`
+	Severity    string `yaml:"severity"`
+	Environment string `yaml:"environment"`
+	Title       string `yaml:"title"`
+	Body        string `yaml:"body"`
+	// DedupKey defaults to one derived from the workflow, step, and service.
+	DedupKey string `yaml:"dedup_key"`
+	// KeyEnv names the variable holding the routing or API key.
+	KeyEnv string `yaml:"key_env"`
+	// URL overrides the provider endpoint, e.g. for an EU account.
+	URL string `yaml:"url"`
+	// Window is how long the same dedup key is not paged again; default 1h.
+	Window string `yaml:"window"`
+}
+
+// SetConfirmer installs who approves page steps. Without one, page steps fail.
+func (r *Runner) SetConfirmer(c confirm.Confirmer) {
+	r.confirmer = c
+}
+
+// executePage pages a human, behind the page capability and a confirmation. A dedup
+// key paged within the window is not paged again, and dry-run drafts without paging.
+func (r *Runner) executePage(ctx context.Context, stage StageSpec, stepName string, step StepSpec) (map[string]interface{}, error) {
+	spec := step.Page
+	if spec == nil {
+		return nil, fmt.Errorf("page step has no page block")
+	}
+	window, err := pageWindow(spec.Window)
+	if err != nil {
+		return nil, err
+	}
+	page := notify.Page{KeyEnv: spec.KeyEnv, URL: spec.URL}
+	for _, field := range []struct {
+		value string
+		dst   *string
+	}{
+		{spec.Provider, &page.Provider}, {spec.Service, &page.Service}, {spec.Severity, &page.Severity},
+		{spec.Environment, &page.Environment}, {spec.Title, &page.Title}, {spec.Body, &page.Body}, {spec.DedupKey, &page.DedupKey},
+	} {
+		if *field.dst, err = r.renderTemplate(field.value); err != nil {
+			return nil, err
+		}
+		*field.dst = strings.TrimSpace(*field.dst)
+	}
+	page.Provider = strings.ToLower(page.Provider)
+	if page.Severity == "" {
+		page.Severity = "high"
+	}
+	if page.DedupKey == "" {
+		page.DedupKey = notify.PageDedupKey(r.workflow.Name, stage.ID, stepName, page.Service)
+	}
+
+	if previous, err := notify.RecentPage(ctx, page.DedupKey, window, time.Now()); err != nil {
+		return nil, err
+	} else if previous != nil {
+		r.debugf("page step=%s dedup_key=%s already paged at %s", stepName, page.DedupKey, previous.Format(time.RFC3339))
+		return pageOutput(page, notify.PageDeduped), nil
+	}
+	if !hasCap(r.opts, notify.PageCapability) && !r.dryRun() {
+		return nil, fmt.Errorf("page step %s pages a human; grant the capability with --cap %s", stepName, notify.PageCapability)
+	}
+
+	if page.Title == "" {
+		if strings.TrimSpace(step.Template) == "" {
+			return nil, fmt.Errorf("page step needs page.title or a template to draft it from")
+		}
+		if err := r.draftPage(ctx, step, &page); err != nil {
+			return nil, err
+		}
+	}
+	if err := page.Validate(); err != nil {
+		return nil, err
+	}
+	if r.dryRun() {
+		return pageOutput(page, notify.PageDryRun), nil
+	}
+
+	if r.confirmer == nil {
+		return nil, fmt.Errorf("page step %s needs someone to confirm it; run it through 'sre-ai agent run'", stepName)
+	}
+	approved, err := r.confirmer.Confirm(ctx, PageRequest(ctx, page))
+	if err != nil {
+		return nil, err
+	}
+	if !approved {
+		r.debugf("page step=%s declined", stepName)
+		return pageOutput(page, "declined"), nil
+	}
+	result, err := notify.SendPage(ctx, page)
+	if err != nil && result.Status == "" {
+		return nil, err
+	}
+	if err != nil {
+		// The page went out; only recording its dedup key failed.
+		warnings.Add(ctx, "page", "%v", err)
+	}
+	r.debugf("page step=%s provider=%s dedup_key=%s paged", stepName, page.Provider, page.DedupKey)
+	return pageOutput(page, result.Status), nil
+}
+
+// draftPage has the model write the page's title, and body unless one is set, from the
+// rendered template.
+func (r *Runner) draftPage(ctx context.Context, step StepSpec, page *notify.Page) error {
+	context, err := r.renderTemplate(step.Template)
+	if err != nil {
+		return err
+	}
+	prompt := notify.DraftPrompt(context)
+	r.lastPrompt = prompt
+	provider, model := r.modelFor()
+	client, err := providers.New(ctx, provider, model)
+	if err != nil {
+		return err
+	}
+	text, usage, err := providers.GenerateWithUsage(ctx, client, prompt)
+	if err != nil {
+		return err
+	}
+	r.lastUsage = &usage
+	title, body, err := notify.ParseDraft(text)
+	if err != nil {
+		return err
+	}
+	page.Title = title
+	if page.Body == "" {
+		page.Body = body
+	}
+	return nil
+}
+
+func pageOutput(page notify.Page, status string) map[string]interface{} {
+	return map[string]interface{}{
+		"status":    status,
+		"provider":  page.Provider,
+		"service":   page.Service,
+		"severity":  page.Severity,
+		"title":     page.Title,
+		"body":      page.Body,
+		"dedup_key": page.DedupKey,
+	}
+}
+
+// PageRequest is the confirmation asked before page is sent, with its body as detail.
+func PageRequest(ctx context.Context, page notify.Page) confirm.Request {
+	req := confirm.Ask(ctx, messages.ConfirmPage, []string{page.Provider + ": " + pageTarget(page)}, pageTarget(page), page.Provider, page.Severity, page.Title)
+	req.Detail = page.Body
+	return req
+}
+
+func pageTarget(page notify.Page) string {
+	if page.Service != "" {
+		return page.Service
+	}
+	return "on-call"
+}
+
+func pageWindow(value string) (time.Duration, error) {
+	if strings.TrimSpace(value) == "" {
+		return notify.DefaultPageWindow, nil
+	}
+	window, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("page.window: %w", err)
+	}
+	return window, nil
+}
+
+func (r *Runner) dryRun() bool {
+	return r.opts != nil && r.opts.DryRun
+}
@@ -0,0 +1,135 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ProfileKeys are the costs a profile can be sorted by.
+var ProfileKeys = []string{"time", "tokens", "cpu", "output"}
+
+// Cost is what one step, or a whole run, used.
+type Cost struct {
+	Duration    time.Duration `json:"duration"`
+	CPUTime     time.Duration `json:"cpu_time"`
+	Tokens      int           `json:"tokens"`
+	OutputBytes int64         `json:"output_bytes"`
+}
+
+// StepCost is the cost of one executed step; a step the debugger re-ran appears once per
+// attempt.
+type StepCost struct {
+	StageID  string `json:"stage"`
+	StepName string `json:"step"`
+	Type     string `json:"type"`
+	Status   string `json:"status"`
+	Attempt  int    `json:"attempt,omitempty"`
+	Cost
+}
+
+// Profile ranks the steps of a run by cost so the slow or expensive ones stand out.
+type Profile struct {
+	SortedBy string     `json:"sorted_by"`
+	Steps    []StepCost `json:"steps"`
+	Total    Cost       `json:"total"`
+}
+
+// ParseProfileKey normalizes a profile sort key; empty means time.
+func ParseProfileKey(key string) (string, error) {
+	key = strings.ToLower(strings.TrimSpace(key))
+	if key == "" {
+		key = "time"
+	}
+	if _, err := profileOrder(key); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// NewProfile ranks the executed steps of res by key, one of ProfileKeys, most costly
+// first; ties keep run order. Planned steps have no cost and are left out.
+func NewProfile(res *Result, key string) (*Profile, error) {
+	key, err := ParseProfileKey(key)
+	if err != nil {
+		return nil, err
+	}
+	less, _ := profileOrder(key)
+	profile := &Profile{SortedBy: key, Steps: []StepCost{}}
+	for _, step := range res.Steps {
+		if step.Status == "planned" {
+			continue
+		}
+		sc := StepCost{
+			StageID:  step.StageID,
+			StepName: step.StepName,
+			Type:     step.Type,
+			Status:   step.Status,
+			Attempt:  step.Attempt,
+			Cost: Cost{
+				Duration:    step.Duration,
+				CPUTime:     step.CPUTime,
+				OutputBytes: step.OutputBytes,
+			},
+		}
+		if step.Usage != nil {
+			sc.Tokens = step.Usage.PromptTokens + step.Usage.OutputTokens
+		}
+		profile.Steps = append(profile.Steps, sc)
+		profile.Total.Duration += sc.Duration
+		profile.Total.CPUTime += sc.CPUTime
+		profile.Total.Tokens += sc.Tokens
+		profile.Total.OutputBytes += sc.OutputBytes
+	}
+	sort.SliceStable(profile.Steps, func(i, j int) bool {
+		return less(profile.Steps[j].Cost, profile.Steps[i].Cost)
+	})
+	return profile, nil
+}
+
+func profileOrder(key string) (func(a, b Cost) bool, error) {
+	switch key {
+	case "time":
+		return func(a, b Cost) bool { return a.Duration < b.Duration }, nil
+	case "tokens":
+		return func(a, b Cost) bool { return a.Tokens < b.Tokens }, nil
+	case "cpu":
+		return func(a, b Cost) bool { return a.CPUTime < b.CPUTime }, nil
+	case "output":
+		return func(a, b Cost) bool { return a.OutputBytes < b.OutputBytes }, nil
+	}
+	return nil, fmt.Errorf("unknown profile sort %q; use one of %s", key, strings.Join(ProfileKeys, ", "))
+}
+
+// stepMeter measures one step from startMeter to stop.
+type stepMeter struct {
+	r       *Runner
+	started time.Time
+}
+
+func (r *Runner) startMeter() stepMeter {
+	r.outputBytes.Store(0)
+	r.cpuTime.Store(0)
+	return stepMeter{r: r, started: time.Now()}
+}
+
+// stop records the step's wall time, subprocess CPU time, and tool output in sr. The CPU
+// time is that of the processes the step started itself, so runs sharing a process do not
+// count each other's.
+func (m stepMeter) stop(sr *StepResult) {
+	sr.Duration = time.Since(m.started)
+	sr.CPUTime = time.Duration(m.r.cpuTime.Load())
+	sr.OutputBytes = m.r.outputBytes.Load()
+}
+
+// countOutput adds the encoded size of an in-process tool's result to the step's output.
+func (r *Runner) countOutput(result map[string]interface{}) map[string]interface{} {
+	if result != nil {
+		if data, err := json.Marshal(result); err == nil {
+			r.outputBytes.Add(int64(len(data)))
+		}
+	}
+	return result
+}
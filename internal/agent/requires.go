@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/example/sre-ai/internal/mcp"
+	"github.com/example/sre-ai/internal/warnings"
+)
+
+const (
+	// requiresProbeTimeout bounds listing the tools of a server whose catalog is not cached.
+	requiresProbeTimeout = 10 * time.Second
+	// requiresCatalogMaxAge is how long a run trusts a cached catalog before listing the
+	// server's tools again, so an upgrade is noticed within the hour.
+	requiresCatalogMaxAge = time.Hour
+)
+
+// requiredPins parses the requires entries of one server.
+func requiredPins(alias string, entries []string) ([]mcp.ToolPin, error) {
+	if strings.TrimSpace(alias) == "" {
+		return nil, fmt.Errorf("requires: server alias is empty")
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("requires.%s lists no tools", alias)
+	}
+	seen := map[string]bool{}
+	pins := make([]mcp.ToolPin, 0, len(entries))
+	for _, entry := range entries {
+		pin, err := mcp.ParseToolPin(entry)
+		if err != nil {
+			return nil, fmt.Errorf("requires.%s: %w", alias, err)
+		}
+		if seen[pin.Name] {
+			return nil, fmt.Errorf("requires.%s lists %s twice", alias, pin.Name)
+		}
+		seen[pin.Name] = true
+		pins = append(pins, pin)
+	}
+	return pins, nil
+}
+
+// checkRequires fails the run before any step when a server in requires does not list a
+// pinned tool or lists it with a different schema hash, as after an upgrade that
+// changed the tool's contract. Pins are checked against the cached catalog; a server
+// whose cache is missing, older than requiresCatalogMaxAge, or disagrees is probed
+// again. Plan runs start no servers and only check what is cached.
+func (r *Runner) checkRequires(ctx context.Context, planOnly bool) error {
+	var problems []string
+	var failed []string
+	for _, alias := range sortedKeys(r.workflow.Requires) {
+		pins, err := requiredPins(alias, r.workflow.Requires[alias])
+		if err != nil {
+			return err
+		}
+		mismatches, err := r.requiredToolMismatches(ctx, alias, pins, planOnly)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", alias, err))
+			failed = append(failed, alias)
+			continue
+		}
+		for _, m := range mismatches {
+			problems = append(problems, alias+"/"+m)
+		}
+		if len(mismatches) > 0 {
+			failed = append(failed, alias)
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("workflow %s requires tools its MCP servers do not provide:\n  %s\ncompare with 'sre-ai mcp test %s' and update requires", r.workflow.Name, strings.Join(problems, "\n  "), failed[0])
+}
+
+// requiredToolMismatches checks the pins of one server against the freshest catalog it can get.
+func (r *Runner) requiredToolMismatches(ctx context.Context, alias string, pins []mcp.ToolPin, planOnly bool) ([]string, error) {
+	if client, ok := mcp.RegistryFrom(ctx).Get(alias); ok && client.Manifest != nil {
+		return mcp.ManifestCatalog(alias, client.Manifest).Mismatches(pins), nil
+	}
+	catalog, err := mcp.LoadCatalog(ctx, alias)
+	switch {
+	case err == nil:
+		mismatches := catalog.Mismatches(pins)
+		fresh := time.Since(catalog.ListedAt) < requiresCatalogMaxAge
+		if planOnly || (fresh && len(mismatches) == 0) {
+			r.debugf("requires alias=%s checked against catalog listed %s", alias, catalog.ListedAt.Format(time.RFC3339))
+			return mismatches, nil
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, err
+	case planOnly:
+		warnings.Add(ctx, "requires", "%s has no cached tool catalog; its pins are checked when the workflow runs", alias)
+		return nil, nil
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, requiresProbeTimeout)
+	defer cancel()
+	r.debugf("requires alias=%s probing for a current catalog", alias)
+	result, err := mcp.ProbeLocalServer(probeCtx, alias)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list tools: %w", err)
+	}
+	return mcp.NewCatalog(alias, result.ServerName, result.ServerVersion, result.Tools).Mismatches(pins), nil
+}
@@ -0,0 +1,299 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/example/sre-ai/internal/messages"
+)
+
+// RolloutSpec applies a change to its targets in waves: the canary wave first, then
+// batches of the rest. Verify gates every wave before the next one starts. When a gate
+// fails, Rollback runs for each target applied so far, newest first, and the step fails.
+type RolloutSpec struct {
+	// Targets are what the change is applied to, such as clusters or host groups. Each
+	// may be a template; one that renders a JSON list expands to its items.
+	Targets []string `yaml:"targets"`
+	// Canary is how many targets the first wave takes; defaults to 1.
+	Canary int `yaml:"canary"`
+	// Batch is how many targets each later wave takes; defaults to all that remain.
+	Batch int `yaml:"batch"`
+	// Apply are the steps run for each target, which templates see as .rollout.target.
+	Apply []StepSpec `yaml:"apply"`
+	// Verify is the gate after each wave; .rollout.wave lists the wave's targets.
+	Verify *VerifySpec `yaml:"verify"`
+	// Rollback are the steps run for each applied target when a gate fails.
+	Rollback []StepSpec `yaml:"rollback"`
+}
+
+// Rollout is the progress of a rollout step: each wave and the decision taken after it.
+type Rollout struct {
+	// Decision is "completed", "rolled_back", or "stopped" when a wave failed and there
+	// were no rollback steps.
+	Decision   string        `json:"decision"`
+	Waves      []RolloutWave `json:"waves"`
+	Applied    []string      `json:"applied"`
+	RolledBack []string      `json:"rolled_back,omitempty"`
+}
+
+// RolloutWave is one wave of a rollout. Status is "verified", "applied" (no gate),
+// "failed" (the apply or the gate failed), or "pending" for waves never started.
+type RolloutWave struct {
+	Wave         int           `json:"wave"`
+	Canary       bool          `json:"canary,omitempty"`
+	Targets      []string      `json:"targets"`
+	Status       string        `json:"status"`
+	Verification *Verification `json:"verification,omitempty"`
+}
+
+// rolloutWaves splits targets into the canary wave and batches of the rest.
+func (s *RolloutSpec) rolloutWaves(targets []string) ([][]string, error) {
+	if s.Canary < 0 || s.Batch < 0 {
+		return nil, fmt.Errorf("rollout.canary and rollout.batch must not be negative")
+	}
+	canary := s.Canary
+	if canary == 0 {
+		canary = 1
+	}
+	if canary > len(targets) {
+		canary = len(targets)
+	}
+	waves := [][]string{targets[:canary]}
+	rest := targets[canary:]
+	for len(rest) > 0 {
+		n := s.Batch
+		if n == 0 || n > len(rest) {
+			n = len(rest)
+		}
+		waves = append(waves, rest[:n])
+		rest = rest[n:]
+	}
+	return waves, nil
+}
+
+// rolloutTargets renders the targets, expanding those that render a JSON list, and
+// rejects empty or repeated ones.
+func (r *Runner) rolloutTargets(spec *RolloutSpec) ([]string, error) {
+	var targets []string
+	seen := map[string]bool{}
+	for _, raw := range spec.Targets {
+		rendered, err := r.renderTemplate(raw)
+		if err != nil {
+			return nil, fmt.Errorf("rollout.targets: %w", err)
+		}
+		rendered = strings.TrimSpace(rendered)
+		items := []string{rendered}
+		if strings.HasPrefix(rendered, "[") {
+			if err := json.Unmarshal([]byte(rendered), &items); err != nil {
+				return nil, fmt.Errorf("rollout.targets: %q is not a list of strings: %w", rendered, err)
+			}
+		}
+		for _, item := range items {
+			item = strings.TrimSpace(item)
+			if item == "" || item == "<no value>" {
+				return nil, fmt.Errorf("rollout.targets: %q renders an empty target", raw)
+			}
+			if seen[item] {
+				return nil, fmt.Errorf("rollout.targets: %s is listed twice", item)
+			}
+			seen[item] = true
+			targets = append(targets, item)
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("rollout has no targets")
+	}
+	return targets, nil
+}
+
+// runRollout runs a rollout step wave by wave. Nested steps are recorded as
+// <step>/<target>/<name>, so run history shows how far each target got. The rollout
+// step is recorded last with the outcome of every wave; its duration is the time spent
+// in the gates, since the nested steps record their own.
+func (r *Runner) runRollout(ctx context.Context, res *Result, stage StageSpec, stepName string, step StepSpec, sr StepResult) error {
+	spec := step.Rollout
+	fail := func(err error) error {
+		sr.Status = "error"
+		sr.Error = err.Error()
+		r.record(res, stage, sr)
+		return err
+	}
+	if spec == nil {
+		return fail(fmt.Errorf("rollout step %s needs a rollout block", stepName))
+	}
+	targets, err := r.rolloutTargets(spec)
+	if err != nil {
+		return fail(err)
+	}
+	waves, err := spec.rolloutWaves(targets)
+	if err != nil {
+		return fail(err)
+	}
+
+	out := &Rollout{Decision: "completed", Applied: []string{}}
+	for i, wave := range waves {
+		out.Waves = append(out.Waves, RolloutWave{Wave: i + 1, Canary: i == 0 && len(waves) > 1, Targets: wave, Status: "pending"})
+	}
+	sr.Rollout = out
+	defer r.setRolloutData(nil)
+
+	var failure error
+	for i, wave := range waves {
+		current := &out.Waves[i]
+		for _, target := range wave {
+			r.setRolloutData(map[string]interface{}{"target": target, "wave": wave, "applied": out.Applied})
+			_, err := r.runSteps(ctx, res, stage, rolloutStepNames(stepName, target, "apply", spec.Apply), spec.Apply, false)
+			// A partly applied target is rolled back too.
+			out.Applied = append(out.Applied, target)
+			if err != nil {
+				failure = fmt.Errorf("wave %d, target %s: %w", current.Wave, target, err)
+				break
+			}
+		}
+		if failure == nil && spec.Verify != nil {
+			r.setRolloutData(map[string]interface{}{"wave": wave, "applied": out.Applied})
+			started := time.Now()
+			verification, err := r.verifyStep(ctx, stepName, spec.Verify)
+			sr.Duration += time.Since(started)
+			if err != nil {
+				return fail(fmt.Errorf("step %s verify: %w", stepName, err))
+			}
+			current.Verification = verification
+			if verification.Status != "verified" {
+				failure = fmt.Errorf("wave %d (%s) failed verification after %d check(s) in %s: %s", current.Wave, strings.Join(wave, ", "), verification.Checks, verification.Waited, verification.Detail)
+			}
+		}
+		if failure != nil {
+			current.Status = "failed"
+			break
+		}
+		current.Status = "applied"
+		if current.Verification != nil {
+			current.Status = "verified"
+		}
+		r.debugf("rollout step=%s wave=%d targets=%s status=%s; proceeding", stepName, current.Wave, strings.Join(wave, ","), current.Status)
+	}
+
+	r.rolloutState(stepName, out)
+	if failure == nil {
+		sr.Status = "ok"
+		r.record(res, stage, sr)
+		return nil
+	}
+
+	err = fmt.Errorf("step %s rollout stopped at %w", stepName, failure)
+	sr.Error = failure.Error()
+	if len(spec.Rollback) == 0 {
+		out.Decision, sr.Status = "stopped", "stopped"
+		r.rolloutState(stepName, out)
+		r.record(res, stage, sr)
+		return fmt.Errorf("%w; no rollback steps, so %s keep the change", err, strings.Join(out.Applied, ", "))
+	}
+	out.Decision = "rolled_back"
+	r.debugf("rollout step=%s stopped: %v; rolling back %s", stepName, failure, strings.Join(out.Applied, ","))
+	var rollbackErr error
+	for i := len(out.Applied) - 1; i >= 0 && rollbackErr == nil; i-- {
+		target := out.Applied[i]
+		r.setRolloutData(map[string]interface{}{"target": target, "applied": out.Applied})
+		ran, err := r.runSteps(ctx, res, stage, rolloutStepNames(stepName, target, "rollback", spec.Rollback), spec.Rollback, true)
+		out.RolledBack = append(out.RolledBack, ran...)
+		rollbackErr = err
+	}
+	r.rolloutState(stepName, out)
+	sr.Status = "rolled_back"
+	r.record(res, stage, sr)
+	if rollbackErr != nil {
+		return fmt.Errorf("%w; rollback stopped: %v", err, rollbackErr)
+	}
+	return fmt.Errorf("%w; rolled back %s", err, strings.Join(reversed(out.Applied), ", "))
+}
+
+// runSteps runs nested steps, such as rollback steps, in order under names, recording
+// each like a regular step. High-risk ones still pass the policy gate. It stops at the
+// first that fails and returns the names of those that ran.
+func (r *Runner) runSteps(ctx context.Context, res *Result, stage StageSpec, names []string, steps []StepSpec, rollingBack bool) ([]string, error) {
+	kind, blocked := "step", messages.HighRiskBlocked
+	if rollingBack {
+		kind, blocked = "rollback step", messages.RollbackBlocked
+	}
+	var ran []string
+	for idx, step := range steps {
+		name := names[idx]
+		sr := StepResult{StageID: stage.ID, StepName: name, Type: step.Type, Details: step.Description}
+		if IsHighRisk(stage, step) {
+			allowed, err := r.checkGate(ctx, stage, name, step)
+			if err != nil || !allowed {
+				if err == nil {
+					err = messages.Error(ctx, blocked, name)
+				}
+				sr.Status = "blocked"
+				sr.Error = err.Error()
+				r.record(res, stage, sr)
+				return ran, err
+			}
+		}
+		meter := r.startMeter()
+		output, err := r.executeStep(ctx, stage, name, step)
+		meter.stop(&sr)
+		if err != nil {
+			sr.Status = "error"
+			sr.Error = err.Error()
+			r.record(res, stage, sr)
+			return ran, fmt.Errorf("%s %s: %w", kind, name, err)
+		}
+		sr.Status = "ok"
+		sr.Output = output
+		r.record(res, stage, sr)
+		ran = append(ran, name)
+	}
+	return ran, nil
+}
+
+// rolloutStepNames names the nested steps run for one target.
+func rolloutStepNames(stepName, target, kind string, steps []StepSpec) []string {
+	names := make([]string, len(steps))
+	for i, step := range steps {
+		name := step.Name
+		if name == "" {
+			name = fmt.Sprintf("%s_%d", kind, i+1)
+		}
+		names[i] = stepName + "/" + target + "/" + name
+	}
+	return names
+}
+
+// rolloutState exposes the rollout's progress to later steps as .steps.<name>.rollout.
+func (r *Runner) rolloutState(stepName string, out *Rollout) {
+	waves := make([]interface{}, len(out.Waves))
+	for i, wave := range out.Waves {
+		entry := map[string]interface{}{"wave": wave.Wave, "canary": wave.Canary, "targets": wave.Targets, "status": wave.Status}
+		if v := wave.Verification; v != nil {
+			entry["verification"] = map[string]interface{}{"status": v.Status, "checks": v.Checks, "waited": v.Waited, "detail": v.Detail}
+		}
+		waves[i] = entry
+	}
+	if _, ok := r.stepState[stepName]; !ok {
+		r.stepState[stepName] = make(map[string]interface{})
+	}
+	r.stepState[stepName]["rollout"] = map[string]interface{}{
+		"decision":    out.Decision,
+		"waves":       waves,
+		"applied":     append([]string(nil), out.Applied...),
+		"rolled_back": append([]string(nil), out.RolledBack...),
+	}
+}
+
+func (r *Runner) setRolloutData(data map[string]interface{}) {
+	r.rollout = data
+}
+
+func reversed(items []string) []string {
+	out := make([]string, len(items))
+	for i, item := range items {
+		out[len(items)-1-i] = item
+	}
+	return out
+}
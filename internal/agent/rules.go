@@ -0,0 +1,36 @@
+package agent
+
+import (
+	"context"
+	"sort"
+
+	"github.com/example/sre-ai/internal/rules"
+)
+
+// ruleHits evaluates the failure signature rules over what earlier steps produced.
+// Hits already attached to prompt outputs are left out so they are not matched again.
+func (r *Runner) ruleHits(ctx context.Context) ([]rules.Hit, error) {
+	if r.ruleSet == nil {
+		set, err := rules.LoadDefault(ctx)
+		if err != nil {
+			return nil, err
+		}
+		r.ruleSet = set
+	}
+	names := make([]string, 0, len(r.stepState))
+	for name := range r.stepState {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	evidence := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		state := make(map[string]interface{}, len(r.stepState[name]))
+		for key, value := range r.stepState[name] {
+			if key != "rules" {
+				state[key] = value
+			}
+		}
+		evidence = append(evidence, state)
+	}
+	return r.ruleSet.Evaluate(rules.Lines(evidence...)), nil
+}
@@ -0,0 +1,298 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkjson"
+	"go.starlark.net/syntax"
+)
+
+const (
+	defaultScriptMaxSteps = 1_000_000
+	defaultScriptTimeout  = 5 * time.Second
+)
+
+// ScriptSpec configures a script step: Starlark that transforms step state where a
+// template would be unreadable. The script sees inputs and steps as frozen dicts, can read
+// facts with fact(key, default), and reports by assigning result. It has no file, network,
+// or process access, and load() is disabled.
+type ScriptSpec struct {
+	Source string `yaml:"source"`
+	// MaxSteps bounds the Starlark operations executed; default 1,000,000.
+	MaxSteps uint64 `yaml:"max_steps"`
+	// Timeout bounds wall time; default 5s.
+	Timeout string `yaml:"timeout"`
+}
+
+func (s *ScriptSpec) limits() (uint64, time.Duration, error) {
+	steps, timeout := uint64(defaultScriptMaxSteps), defaultScriptTimeout
+	if s.MaxSteps > 0 {
+		steps = s.MaxSteps
+	}
+	if v := strings.TrimSpace(s.Timeout); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return 0, 0, fmt.Errorf("script.timeout %q must be a positive duration such as 500ms or 5s", s.Timeout)
+		}
+		timeout = d
+	}
+	return steps, timeout, nil
+}
+
+// scriptFileOptions allows the statements scripts commonly need at top level.
+var scriptFileOptions = &syntax.FileOptions{While: true, TopLevelControl: true, GlobalReassign: true}
+
+// parseScript checks a script's syntax without running it.
+func parseScript(name, source string) error {
+	_, err := scriptFileOptions.Parse(name+".star", source, 0)
+	return err
+}
+
+// executeScript runs the step's Starlark. A dict result becomes the step result; any
+// other value is returned under "value". Lines the script prints are kept under "output".
+func (r *Runner) executeScript(ctx context.Context, stepName string, step StepSpec) (map[string]interface{}, error) {
+	spec := step.Script
+	if spec == nil || strings.TrimSpace(spec.Source) == "" {
+		return nil, errors.New("script step needs script.source")
+	}
+	maxSteps, timeout, err := spec.limits()
+	if err != nil {
+		return nil, err
+	}
+
+	inputs, err := toStarlark(r.inputs)
+	if err != nil {
+		return nil, fmt.Errorf("script inputs: %w", err)
+	}
+	steps, err := toStarlark(r.stepState)
+	if err != nil {
+		return nil, fmt.Errorf("script steps: %w", err)
+	}
+	var output []interface{}
+	thread := &starlark.Thread{
+		Name:  stepName,
+		Print: func(_ *starlark.Thread, msg string) { output = append(output, msg) },
+		Load: func(_ *starlark.Thread, module string) (starlark.StringDict, error) {
+			return nil, fmt.Errorf("load(%q): scripts cannot load modules", module)
+		},
+	}
+	thread.SetMaxExecutionSteps(maxSteps)
+
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-runCtx.Done():
+			if ctx.Err() != nil {
+				thread.Cancel("run cancelled")
+			} else {
+				thread.Cancel(fmt.Sprintf("timed out after %s", timeout))
+			}
+		case <-stop:
+		}
+	}()
+
+	predeclared := starlark.StringDict{
+		"inputs": inputs,
+		"steps":  steps,
+		"json":   starlarkjson.Module,
+		"fact":   starlark.NewBuiltin("fact", r.scriptFact),
+		"fail":   starlark.NewBuiltin("fail", scriptFail),
+	}
+	globals, err := starlark.ExecFileOptions(scriptFileOptions, thread, stepName+".star", spec.Source, predeclared)
+	r.debugf("script step=%s executed=%d", stepName, thread.ExecutionSteps())
+	if err != nil {
+		var evalErr *starlark.EvalError
+		if errors.As(err, &evalErr) {
+			// Report the innermost script position rather than the whole traceback.
+			for i := 0; i < len(evalErr.CallStack); i++ {
+				if pos := evalErr.CallStack.At(i).Pos; pos.IsValid() && pos.Filename() != "<builtin>" {
+					return nil, fmt.Errorf("script: %s: %s", pos, evalErr.Msg)
+				}
+			}
+		}
+		return nil, fmt.Errorf("script: %w", err)
+	}
+
+	payload := map[string]interface{}{}
+	if value, ok := globals["result"]; ok {
+		converted, err := fromStarlark(value)
+		if err != nil {
+			return nil, fmt.Errorf("script result: %w", err)
+		}
+		if m, ok := converted.(map[string]interface{}); ok {
+			payload = m
+		} else {
+			payload["value"] = converted
+		}
+	}
+	if len(output) > 0 {
+		payload["output"] = output
+	}
+	return payload, nil
+}
+
+// scriptFact is fact(key, default=None).
+func (r *Runner) scriptFact(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var key string
+	var def starlark.Value = starlark.None
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "key", &key, "default?", &def); err != nil {
+		return nil, err
+	}
+	store, err := r.factStore(r.settings)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := store.Get(key)
+	if !ok {
+		return def, nil
+	}
+	return toStarlark(value)
+}
+
+// scriptFail is fail(msg), which stops the script and fails the step with msg.
+func scriptFail(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var msg string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &msg); err != nil {
+		return nil, err
+	}
+	return nil, errors.New(msg)
+}
+
+// toStarlark converts decoded JSON-like values to frozen Starlark values. Whole floats
+// become ints so they can index lists.
+func toStarlark(value interface{}) (starlark.Value, error) {
+	switch v := value.(type) {
+	case nil:
+		return starlark.None, nil
+	case bool:
+		return starlark.Bool(v), nil
+	case string:
+		return starlark.String(v), nil
+	case int:
+		return starlark.MakeInt(v), nil
+	case int64:
+		return starlark.MakeInt64(v), nil
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return starlark.MakeInt64(int64(v)), nil
+		}
+		return starlark.Float(v), nil
+	case []interface{}:
+		items := make([]starlark.Value, len(v))
+		for i, item := range v {
+			converted, err := toStarlark(item)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = converted
+		}
+		list := starlark.NewList(items)
+		list.Freeze()
+		return list, nil
+	case []string:
+		items := make([]interface{}, len(v))
+		for i, s := range v {
+			items[i] = s
+		}
+		return toStarlark(items)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		dict := starlark.NewDict(len(v))
+		for _, k := range keys {
+			converted, err := toStarlark(v[k])
+			if err != nil {
+				return nil, err
+			}
+			if err := dict.SetKey(starlark.String(k), converted); err != nil {
+				return nil, err
+			}
+		}
+		dict.Freeze()
+		return dict, nil
+	case map[string]map[string]interface{}:
+		generic := make(map[string]interface{}, len(v))
+		for k, m := range v {
+			generic[k] = m
+		}
+		return toStarlark(generic)
+	case map[string]string:
+		generic := make(map[string]interface{}, len(v))
+		for k, s := range v {
+			generic[k] = s
+		}
+		return toStarlark(generic)
+	default:
+		// Structured values from other steps (e.g. verify results) go through their JSON form.
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("cannot pass a %T to a script: %w", v, err)
+		}
+		var generic interface{}
+		if err := json.Unmarshal(data, &generic); err != nil {
+			return nil, err
+		}
+		return toStarlark(generic)
+	}
+}
+
+// fromStarlark converts a script's result to plain values; dict keys must be strings.
+func fromStarlark(value starlark.Value) (interface{}, error) {
+	switch v := value.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.Bool:
+		return bool(v), nil
+	case starlark.String:
+		return string(v), nil
+	case starlark.Int:
+		if n, ok := v.Int64(); ok {
+			return n, nil
+		}
+		f, _ := new(big.Float).SetInt(v.BigInt()).Float64()
+		return f, nil
+	case starlark.Float:
+		return float64(v), nil
+	case starlark.Indexable:
+		items := make([]interface{}, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			converted, err := fromStarlark(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			items[i] = converted
+		}
+		return items, nil
+	case *starlark.Dict:
+		out := make(map[string]interface{}, v.Len())
+		for _, item := range v.Items() {
+			key, ok := item[0].(starlark.String)
+			if !ok {
+				return nil, fmt.Errorf("dict key %s is a %s, not a string", item[0], item[0].Type())
+			}
+			converted, err := fromStarlark(item[1])
+			if err != nil {
+				return nil, err
+			}
+			out[string(key)] = converted
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("cannot return a %s", value.Type())
+	}
+}
@@ -0,0 +1,121 @@
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// stepTypes lists the StepSpec types Validate accepts.
+var stepTypes = []string{"tool", "prompt", "set-fact", "script", "wait", "page", "rollout"}
+
+// StepSymbol is a step as seen by a template: the keys it provides under steps.<name>.
+type StepSymbol struct {
+	Name     string   `json:"name"`
+	Stage    string   `json:"stage"`
+	Type     string   `json:"type"`
+	Tool     string   `json:"tool,omitempty"`
+	Provides []string `json:"provides"`
+}
+
+// Symbols lists what a workflow defines, for editor completion and hover. Steps are
+// in run order, rollback steps right after the step they undo.
+type Symbols struct {
+	Inputs  map[string]InputSpec `json:"-"`
+	Tools   map[string]ToolSpec  `json:"-"`
+	Steps   []StepSymbol         `json:"steps"`
+	Outputs []string             `json:"outputs"`
+}
+
+// WorkflowSymbols collects the inputs, tools, steps, and outputs of wf.
+func WorkflowSymbols(wf *Workflow) Symbols {
+	syms := Symbols{Inputs: wf.Inputs, Tools: wf.Tools}
+	add := func(name, stage string, step StepSpec) {
+		provides := make([]string, 0, len(step.Capture)+3)
+		for key := range stepProvides(step) {
+			provides = append(provides, key)
+		}
+		sort.Strings(provides)
+		syms.Steps = append(syms.Steps, StepSymbol{Name: name, Stage: stage, Type: strings.ToLower(step.Type), Tool: step.Tool, Provides: provides})
+	}
+	for _, stage := range wf.Workflow.Stages {
+		for idx, step := range stage.Steps {
+			name := stepDisplayName(stage, idx, step)
+			add(name, stage.ID, step)
+			for ri, rb := range rollbackSteps(step) {
+				rbName := rb.Name
+				if rbName == "" {
+					rbName = fmt.Sprintf("%s_rollback_%d", name, ri+1)
+				}
+				add(rbName, stage.ID, rb)
+			}
+		}
+	}
+	for key := range wf.Outputs {
+		syms.Outputs = append(syms.Outputs, key)
+	}
+	sort.Strings(syms.Outputs)
+	return syms
+}
+
+// Step returns the step named name.
+func (s Symbols) Step(name string) (StepSymbol, bool) {
+	for _, step := range s.Steps {
+		if step.Name == name {
+			return step, true
+		}
+	}
+	return StepSymbol{}, false
+}
+
+// ToolKinds lists the tool kinds the runner can execute.
+func ToolKinds() []string {
+	kinds := make([]string, 0, len(knownToolKinds))
+	for kind := range knownToolKinds {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// StepTypes lists the step types a workflow may use.
+func StepTypes() []string {
+	return append([]string(nil), stepTypes...)
+}
+
+// TemplateRoots lists the top-level keys of the template data.
+func TemplateRoots() []string {
+	roots := make([]string, 0, len(templateRoots))
+	for root := range templateRoots {
+		roots = append(roots, root)
+	}
+	sort.Strings(roots)
+	return roots
+}
+
+// TemplateRootKeys lists the keys under a template root other than inputs and steps,
+// whose keys depend on the workflow.
+func TemplateRootKeys(root string) []string {
+	switch root {
+	case "run":
+		return []string{"dir", "id"}
+	case "workflow":
+		return []string{"dir"}
+	case "repo":
+		return []string{"root"}
+	case "rollout":
+		return []string{"applied", "target", "wave"}
+	}
+	return nil
+}
+
+// TemplateFuncNames lists the functions workflow templates may call, besides the
+// text/template builtins.
+func TemplateFuncNames() []string {
+	names := make([]string, 0, 4)
+	for name := range templateFuncs() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/example/sre-ai/internal/k8s"
+	"github.com/example/sre-ai/internal/mcp"
+	"github.com/example/sre-ai/internal/remote"
+)
+
+// JobSpec configures the Kubernetes Job a step with target k8s-job:<namespace> runs as. The
+// image must contain the tool's command; the pod sees only the step's and the server
+// definition's env.
+type JobSpec struct {
+	Image          string `yaml:"image"`
+	ServiceAccount string `yaml:"service_account"`
+	// Context is the kubeconfig context; default contexts.k8s.kubecontext.
+	Context string `yaml:"context"`
+}
+
+// stepTarget renders and parses the step's target.
+func (r *Runner) stepTarget(step StepSpec) (remote.Target, error) {
+	raw := strings.TrimSpace(step.Target)
+	if strings.Contains(raw, "{{") {
+		rendered, err := r.renderTemplate(raw)
+		if err != nil {
+			return remote.Target{}, fmt.Errorf("target: %w", err)
+		}
+		if strings.Contains(rendered, "<no value>") {
+			return remote.Target{}, fmt.Errorf("target %q references a value that is not set", raw)
+		}
+		raw = rendered
+	}
+	return remote.ParseTarget(raw)
+}
+
+// executeRemote runs the mcp tool's command line on a host group over SSH or as a
+// Kubernetes Job. The result has the usual tool fields plus target and ran_on. A Job's
+// stdout and stderr are both in stdout, as its pod log.
+func (r *Runner) executeRemote(ctx context.Context, toolName, alias string, target remote.Target, job *JobSpec, opts mcp.RunOptions) (map[string]interface{}, error) {
+	argv, env, err := mcp.RemoteCommand(ctx, alias, opts)
+	if err != nil {
+		return nil, fmt.Errorf("tool %s: %w", toolName, err)
+	}
+	r.debugf("remote invoke tool=%s alias=%s target=%s argv=%s", toolName, alias, target, debugDump(argv))
+
+	var res remote.Result
+	var runErr error
+	switch target.Kind {
+	case remote.TargetHost:
+		group, ok := r.opts.Hosts[target.Name]
+		if !ok {
+			return nil, fmt.Errorf("tool %s: host group %s is not configured; add it under hosts in the config file", toolName, target.Name)
+		}
+		res, runErr = remote.RunOnHosts(ctx, target.Name, group, remote.Command{Argv: argv, Env: env, Workdir: opts.Workdir, Stdin: opts.Stdin})
+		if runErr != nil && res.Where != "" {
+			runErr = fmt.Errorf("%s on %s %w", alias, res.Where, runErr)
+		}
+	case remote.TargetJob:
+		if job == nil || strings.TrimSpace(job.Image) == "" {
+			return nil, fmt.Errorf("tool %s: target %s needs job.image", toolName, target)
+		}
+		if opts.Stdin != "" {
+			return nil, fmt.Errorf("tool %s: stdin cannot be passed to a Job", toolName)
+		}
+		kubeContext := job.Context
+		if kubeContext == "" {
+			kubeContext = r.opts.Kube.Context
+		}
+		client := k8s.Client{Context: kubeContext, CPU: &r.cpuTime}
+		jr, err := client.RunJob(ctx, k8s.JobSpec{
+			Namespace:      target.Name,
+			Name:           "sre-ai-" + toolName,
+			Image:          job.Image,
+			Command:        argv,
+			Env:            env,
+			Workdir:        opts.Workdir,
+			ServiceAccount: job.ServiceAccount,
+			Labels:         map[string]string{"sre-ai/workflow": labelValue(r.workflow.Name), "sre-ai/tool": labelValue(toolName)},
+		})
+		res = remote.Result{Stdout: jr.Logs, ExitCode: jr.ExitCode, Where: jr.Job}
+		runErr = err
+	default:
+		return nil, fmt.Errorf("tool %s: unsupported target %s", toolName, target)
+	}
+
+	r.outputBytes.Add(int64(len(res.Stdout) + len(res.Stderr)))
+	result := map[string]interface{}{
+		"stdout":    strings.TrimSpace(res.Stdout),
+		"exit_code": res.ExitCode,
+		"target":    target.String(),
+	}
+	if res.Where != "" {
+		result["ran_on"] = res.Where
+	}
+	if trimmed := strings.TrimSpace(res.Stderr); trimmed != "" {
+		result["stderr"] = trimmed
+	}
+	if raw := strings.TrimSpace(res.Stdout); raw != "" {
+		var parsed interface{}
+		if json.Unmarshal([]byte(raw), &parsed) == nil {
+			result["json"] = parsed
+		}
+	}
+	if runErr != nil {
+		r.debugf("remote error tool=%s target=%s err=%v", toolName, target, runErr)
+		result["error"] = runErr.Error()
+		return result, runErr
+	}
+	r.debugf("remote success tool=%s target=%s ran_on=%s exit=%d", toolName, target, res.Where, res.ExitCode)
+	return result, nil
+}
+
+// labelValue trims s to a valid Kubernetes label value.
+func labelValue(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' || r == '.' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	v := b.String()
+	if len(v) > 63 {
+		v = v[:63]
+	}
+	return strings.Trim(v, "-_.")
+}
@@ -0,0 +1,17 @@
+package agent
+
+import (
+	"text/template"
+
+	"github.com/example/sre-ai/internal/templatex"
+)
+
+// execTemplate renders body against data within the runner's template limits (see
+// templatex.Execute).
+func (r *Runner) execTemplate(name, body string, data interface{}) (string, error) {
+	tmpl, err := template.New(name).Funcs(r.funcMap()).Parse(body)
+	if err != nil {
+		return "", err
+	}
+	return templatex.Execute(tmpl, r.limits, data)
+}
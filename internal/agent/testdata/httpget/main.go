@@ -0,0 +1,42 @@
+//go:build wasip1
+
+// Command httpget is the guest module of the wasm tests: it fetches its first argument
+// with http_get as many times as the second says, starting each fetch with a one-byte
+// buffer and retrying with one as large as the host asks for, and prints every body.
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"unsafe"
+)
+
+//go:wasmimport sre_ai http_get
+func httpGet(urlPtr, urlLen, outPtr, outCap uint32) int32
+
+func get(url string) (string, bool) {
+	buf := make([]byte, 1)
+	for {
+		n := httpGet(uint32(uintptr(unsafe.Pointer(unsafe.StringData(url)))), uint32(len(url)), uint32(uintptr(unsafe.Pointer(&buf[0]))), uint32(len(buf)))
+		if n < 0 {
+			return "", false
+		}
+		if int(n) <= len(buf) {
+			return string(buf[:n]), true
+		}
+		buf = make([]byte, n)
+	}
+}
+
+func main() {
+	times, _ := strconv.Atoi(os.Args[2])
+	for i := 0; i < times; i++ {
+		body, ok := get(os.Args[1])
+		if !ok {
+			fmt.Println("error")
+			os.Exit(1)
+		}
+		fmt.Println(body)
+	}
+}
@@ -0,0 +1,309 @@
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/example/sre-ai/internal/facts"
+	"github.com/example/sre-ai/internal/notify"
+	"github.com/example/sre-ai/internal/remote"
+)
+
+// knownToolKinds lists the ToolSpec kinds the runner can execute.
+var knownToolKinds = map[string]bool{
+	"sample": true,
+	"mock":   true,
+	"mcp":    true,
+	"git":    true,
+	"wasm":   true,
+}
+
+// Validate checks a workflow's structure and templates without executing it.
+// Errors make the workflow unrunnable; warnings come from LintTemplates.
+func Validate(wf *Workflow) []LintIssue {
+	var issues []LintIssue
+	errorf := func(stage, step, format string, args ...interface{}) {
+		issues = append(issues, LintIssue{Severity: "error", Stage: stage, Step: step, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if strings.TrimSpace(wf.Name) == "" {
+		errorf("", "", "workflow name is required")
+	}
+	toolNames := make([]string, 0, len(wf.Tools))
+	for name := range wf.Tools {
+		toolNames = append(toolNames, name)
+	}
+	sort.Strings(toolNames)
+	for _, name := range toolNames {
+		tool := wf.Tools[name]
+		if !knownToolKinds[strings.ToLower(tool.Kind)] {
+			errorf("", "", "tool %s has unsupported kind %q", name, tool.Kind)
+		}
+		if strings.TrimSpace(tool.RawCommand) != "" {
+			if !strings.EqualFold(tool.Kind, "mcp") {
+				errorf("", "", "tool %s: raw_command only applies to mcp tools", name)
+			} else if len(tool.DefaultArgs) > 0 {
+				errorf("", "", "tool %s: use either default_args or raw_command, not both", name)
+			}
+		}
+		if strings.EqualFold(tool.Kind, "wasm") {
+			if tool.Wasm == nil {
+				errorf("", "", "wasm tool %s needs a wasm block with a module", name)
+			} else if err := tool.Wasm.validate(); err != nil {
+				errorf("", "", "tool %s: %v", name, err)
+			}
+		}
+		if strings.EqualFold(tool.Kind, "mcp") && strings.TrimSpace(tool.Alias) == "" {
+			issues = append(issues, LintIssue{Severity: "warning", Message: fmt.Sprintf("mcp tool %s has no alias; every step must pass params.alias or fanout.aliases", name)})
+		}
+	}
+	for _, alias := range sortedKeys(wf.Requires) {
+		if _, err := requiredPins(alias, wf.Requires[alias]); err != nil {
+			errorf("", "", "%v", err)
+		}
+	}
+	if len(wf.Workflow.Stages) == 0 {
+		errorf("", "", "workflow defines no stages")
+	}
+
+	seen := map[string]bool{}
+	for _, stage := range wf.Workflow.Stages {
+		if stage.ID == "" {
+			errorf("", "", "stage without id")
+		}
+		for idx, step := range stage.Steps {
+			name := stepDisplayName(stage, idx, step)
+			if seen[name] {
+				errorf(stage.ID, name, "duplicate step name")
+			}
+			seen[name] = true
+
+			switch strings.ToLower(step.Type) {
+			case "tool":
+				tool, ok := wf.Tools[step.Tool]
+				if !ok {
+					errorf(stage.ID, name, "references undefined tool %q", step.Tool)
+				}
+				if f := step.Fanout; f != nil {
+					if ok && !strings.EqualFold(tool.Kind, "mcp") {
+						errorf(stage.ID, name, "fanout needs an mcp tool, %s is %s", step.Tool, tool.Kind)
+					}
+					if len(f.Aliases) < 2 {
+						errorf(stage.ID, name, "fanout needs at least two aliases")
+					}
+					if f.MinSuccess > len(f.Aliases) {
+						errorf(stage.ID, name, "fanout min_success %d exceeds %d aliases", f.MinSuccess, len(f.Aliases))
+					}
+					if _, ok := step.Params["alias"]; ok {
+						errorf(stage.ID, name, "fanout sets the alias; drop params.alias")
+					}
+				}
+				if step.Target != "" && !strings.Contains(step.Target, "{{") {
+					target, err := remote.ParseTarget(step.Target)
+					switch {
+					case err != nil:
+						errorf(stage.ID, name, "%v", err)
+					case !target.Local() && ok && !strings.EqualFold(tool.Kind, "mcp"):
+						errorf(stage.ID, name, "target needs an mcp tool, %s is %s", step.Tool, tool.Kind)
+					case target.Kind == remote.TargetJob && (step.Job == nil || strings.TrimSpace(step.Job.Image) == ""):
+						errorf(stage.ID, name, "target %s needs job.image", target)
+					}
+				}
+				if step.Job != nil && !strings.HasPrefix(strings.TrimSpace(step.Target), remote.TargetJob+":") && !strings.Contains(step.Target, "{{") {
+					errorf(stage.ID, name, "job only applies to target k8s-job:<namespace>")
+				}
+			case "prompt":
+				if strings.TrimSpace(step.Template) == "" {
+					errorf(stage.ID, name, "prompt step has an empty template")
+				} else if _, err := template.New(name).Funcs(templateFuncs()).Parse(step.Template); err != nil {
+					errorf(stage.ID, name, "template: %v", err)
+				}
+				if c := step.Consensus; c != nil {
+					if len(c.Models) < 2 {
+						errorf(stage.ID, name, "consensus needs at least two models")
+					}
+					if c.Quorum > len(c.Models) {
+						errorf(stage.ID, name, "consensus quorum %d exceeds %d models", c.Quorum, len(c.Models))
+					}
+					if c.OnConflict != "" && c.OnConflict != "flag" && c.OnConflict != "fail" {
+						errorf(stage.ID, name, "consensus on_conflict must be flag or fail")
+					}
+				}
+			case "set-fact":
+				if len(step.Facts) == 0 {
+					errorf(stage.ID, name, "set-fact step needs a facts map")
+				}
+				for key := range step.Facts {
+					if err := facts.ValidateKey(key); err != nil {
+						errorf(stage.ID, name, "%v", err)
+					}
+				}
+			case "script":
+				sc := step.Script
+				if sc == nil || strings.TrimSpace(sc.Source) == "" {
+					errorf(stage.ID, name, "script step needs script.source")
+					break
+				}
+				if err := parseScript(name, sc.Source); err != nil {
+					errorf(stage.ID, name, "script: %v", err)
+				}
+				if _, _, err := sc.limits(); err != nil {
+					errorf(stage.ID, name, "%v", err)
+				}
+			case "wait":
+				w := step.Wait
+				if w == nil {
+					errorf(stage.ID, name, "wait step needs a wait block")
+					break
+				}
+				if _, err := w.timings(); err != nil {
+					errorf(stage.ID, name, "%v", err)
+				}
+				if strings.TrimSpace(w.Until) == "" {
+					if strings.TrimSpace(w.Duration) == "" {
+						errorf(stage.ID, name, "wait step needs wait.duration or wait.until")
+					}
+					break
+				}
+				if _, ok := wf.Tools[step.Tool]; !ok {
+					errorf(stage.ID, name, "wait.until polls a tool; references undefined tool %q", step.Tool)
+				}
+				if _, err := template.New(name).Funcs(templateFuncs()).Parse(w.Until); err != nil {
+					errorf(stage.ID, name, "wait.until: %v", err)
+				}
+				if w.OnTimeout != "" && w.OnTimeout != "fail" && w.OnTimeout != "continue" {
+					errorf(stage.ID, name, "wait.on_timeout must be fail or continue")
+				}
+			case "page":
+				p := step.Page
+				if p == nil {
+					errorf(stage.ID, name, "page step needs a page block")
+					break
+				}
+				if provider := strings.ToLower(p.Provider); !strings.Contains(provider, "{{") && provider != "pagerduty" && provider != "opsgenie" {
+					errorf(stage.ID, name, "page.provider must be pagerduty or opsgenie")
+				}
+				if p.Severity != "" && !strings.Contains(p.Severity, "{{") && notify.SeverityRank(p.Severity) < 0 {
+					errorf(stage.ID, name, "unknown page.severity %q", p.Severity)
+				}
+				if strings.TrimSpace(p.Title) == "" && strings.TrimSpace(step.Template) == "" {
+					errorf(stage.ID, name, "page step needs page.title or a template to draft it from")
+				}
+				for _, field := range []struct{ key, body string }{
+					{"template", step.Template}, {"page.provider", p.Provider}, {"page.service", p.Service},
+					{"page.severity", p.Severity}, {"page.environment", p.Environment}, {"page.title", p.Title},
+					{"page.body", p.Body}, {"page.dedup_key", p.DedupKey},
+				} {
+					if _, err := template.New(name).Funcs(templateFuncs()).Parse(field.body); err != nil {
+						errorf(stage.ID, name, "%s: %v", field.key, err)
+					}
+				}
+				if _, err := pageWindow(p.Window); err != nil {
+					errorf(stage.ID, name, "%v", err)
+				}
+			case "rollout":
+				ro := step.Rollout
+				if ro == nil {
+					errorf(stage.ID, name, "rollout step needs a rollout block")
+					break
+				}
+				if len(ro.Targets) == 0 {
+					errorf(stage.ID, name, "rollout needs targets")
+				}
+				if len(ro.Apply) == 0 {
+					errorf(stage.ID, name, "rollout needs apply steps")
+				}
+				if _, err := ro.rolloutWaves(ro.Targets); err != nil {
+					errorf(stage.ID, name, "%v", err)
+				}
+				for _, field := range ro.Targets {
+					if _, err := template.New(name).Funcs(templateFuncs()).Parse(field); err != nil {
+						errorf(stage.ID, name, "rollout.targets: %v", err)
+					}
+				}
+				if step.Verify != nil {
+					errorf(stage.ID, name, "a rollout step gates its waves with rollout.verify, not verify")
+				}
+				if v := ro.Verify; v != nil {
+					if strings.TrimSpace(v.PromQL) == "" && strings.TrimSpace(v.Rollout) == "" {
+						errorf(stage.ID, name, "rollout.verify needs promql or rollout")
+					}
+					if _, err := v.timings(); err != nil {
+						errorf(stage.ID, name, "%v", err)
+					}
+					if len(v.Rollback) > 0 {
+						errorf(stage.ID, name, "rollback steps of a rollout go in rollout.rollback, which runs them per target")
+					}
+				}
+				for _, nested := range []struct {
+					kind  string
+					steps []StepSpec
+				}{{"apply", ro.Apply}, {"rollback", ro.Rollback}} {
+					for ni, ns := range nested.steps {
+						validateNestedStep(wf, func(format string, args ...interface{}) {
+							errorf(stage.ID, name, "%s step %d %s", nested.kind, ni+1, fmt.Sprintf(format, args...))
+						}, ns)
+					}
+				}
+			default:
+				errorf(stage.ID, name, "unsupported step type %q", step.Type)
+			}
+			if step.RuleHints && !strings.EqualFold(step.Type, "prompt") {
+				errorf(stage.ID, name, "rule_hints only applies to prompt steps")
+			}
+			if v := step.Verify; v != nil {
+				if strings.TrimSpace(v.PromQL) == "" && strings.TrimSpace(v.Rollout) == "" {
+					errorf(stage.ID, name, "verify needs promql or rollout")
+				}
+				if _, err := v.timings(); err != nil {
+					errorf(stage.ID, name, "%v", err)
+				}
+				for ri, rb := range v.Rollback {
+					validateNestedStep(wf, func(format string, args ...interface{}) {
+						errorf(stage.ID, name, "rollback step %d %s", ri+1, fmt.Sprintf(format, args...))
+					}, rb)
+				}
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(wf.Outputs))
+	for key := range wf.Outputs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if _, err := template.New(key).Funcs(templateFuncs()).Parse(wf.Outputs[key].Template); err != nil {
+			errorf("", "", "output %s template: %v", key, err)
+		}
+	}
+
+	issues = append(issues, LintTemplates(wf)...)
+	_, flowIssues := AnalyzeDataFlow(wf)
+	return append(issues, flowIssues...)
+}
+
+// validateNestedStep checks a step run inside another, such as a rollback step.
+func validateNestedStep(wf *Workflow, errorf func(format string, args ...interface{}), step StepSpec) {
+	switch strings.ToLower(step.Type) {
+	case "tool":
+		if _, ok := wf.Tools[step.Tool]; !ok {
+			errorf("references undefined tool %q", step.Tool)
+		}
+	case "prompt", "wait", "set-fact", "page", "script":
+	default:
+		errorf("has unsupported type %q", step.Type)
+	}
+}
+
+// HasErrors reports whether any issue is error-severity.
+func HasErrors(issues []LintIssue) bool {
+	for _, issue := range issues {
+		if issue.Severity == "error" {
+			return true
+		}
+	}
+	return false
+}
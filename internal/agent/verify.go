@@ -0,0 +1,229 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/example/sre-ai/internal/k8s"
+	"github.com/example/sre-ai/internal/promql"
+)
+
+const (
+	defaultVerifyWindow   = 5 * time.Minute
+	defaultVerifyInterval = 15 * time.Second
+)
+
+// VerifySpec checks that a remediation worked. After the step runs, the checks are
+// polled for up to Window; the step is verified once every configured check passes
+// Consecutive times in a row, and failed otherwise, in which case Rollback runs.
+type VerifySpec struct {
+	// PromQL is an instant query. Without Max or Min it passes when it returns any
+	// series, so comparisons such as `error_ratio < 0.01` work as-is.
+	PromQL     string   `yaml:"promql"`
+	Prometheus string   `yaml:"prometheus"`
+	Max        *float64 `yaml:"max"`
+	Min        *float64 `yaml:"min"`
+	// Rollout is a workload such as deployment/checkout-api whose rollout must complete.
+	Rollout     string `yaml:"rollout"`
+	Namespace   string `yaml:"namespace"`
+	KubeContext string `yaml:"kubecontext"`
+
+	Window      string     `yaml:"window"`
+	Interval    string     `yaml:"interval"`
+	Consecutive int        `yaml:"consecutive"`
+	Rollback    []StepSpec `yaml:"rollback"`
+}
+
+// Verification is the outcome of a verify block.
+type Verification struct {
+	// Status is "verified" or "failed".
+	Status string `json:"status"`
+	Checks int    `json:"checks"`
+	Waited string `json:"waited"`
+	Detail string `json:"detail,omitempty"`
+	// RolledBack lists the rollback steps that ran after a failed verification.
+	RolledBack []string `json:"rolled_back,omitempty"`
+}
+
+type verifyTimings struct {
+	window, interval time.Duration
+	consecutive      int
+}
+
+func (v *VerifySpec) timings() (verifyTimings, error) {
+	t := verifyTimings{window: defaultVerifyWindow, interval: defaultVerifyInterval, consecutive: 1}
+	for _, field := range []struct {
+		name, value string
+		dst         *time.Duration
+	}{{"window", v.Window, &t.window}, {"interval", v.Interval, &t.interval}} {
+		if strings.TrimSpace(field.value) == "" {
+			continue
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(field.value))
+		if err != nil || d <= 0 {
+			return t, fmt.Errorf("verify.%s %q must be a positive duration such as 30s or 5m", field.name, field.value)
+		}
+		*field.dst = d
+	}
+	if v.Consecutive < 0 {
+		return t, fmt.Errorf("verify.consecutive must not be negative")
+	}
+	if v.Consecutive > 0 {
+		t.consecutive = v.Consecutive
+	}
+	return t, nil
+}
+
+// verifyStep polls the step's checks until they pass often enough in a row or the window ends.
+func (r *Runner) verifyStep(ctx context.Context, stepName string, spec *VerifySpec) (*Verification, error) {
+	timings, err := spec.timings()
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	deadline := start.Add(timings.window)
+	out := &Verification{Status: "failed"}
+	streak := 0
+	for {
+		out.Checks++
+		ok, detail, err := r.runVerifyChecks(ctx, spec)
+		if err != nil {
+			detail = err.Error()
+		}
+		out.Detail = detail
+		r.debugf("verify step=%s check=%d ok=%v detail=%s", stepName, out.Checks, ok, detail)
+		if ok {
+			streak++
+			if streak >= timings.consecutive {
+				out.Status = "verified"
+				break
+			}
+		} else {
+			streak = 0
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !time.Now().Add(timings.interval).Before(deadline) {
+			break
+		}
+		if err := sleepContext(ctx, timings.interval); err != nil {
+			return nil, err
+		}
+	}
+	out.Waited = time.Since(start).Round(time.Millisecond).String()
+	return out, nil
+}
+
+// runVerifyChecks evaluates every configured check once. Query errors count as a failed check.
+func (r *Runner) runVerifyChecks(ctx context.Context, spec *VerifySpec) (bool, string, error) {
+	var details []string
+	passed := true
+	if strings.TrimSpace(spec.Rollout) != "" {
+		resource, err := r.renderTemplate(spec.Rollout)
+		if err != nil {
+			return false, "", fmt.Errorf("verify.rollout: %w", err)
+		}
+		namespace, err := r.renderTemplate(spec.Namespace)
+		if err != nil {
+			return false, "", fmt.Errorf("verify.namespace: %w", err)
+		}
+		status, err := k8s.Client{Context: spec.KubeContext, CPU: &r.cpuTime}.RolloutStatus(ctx, strings.TrimSpace(namespace), strings.TrimSpace(resource))
+		if err != nil {
+			return false, "", err
+		}
+		passed = passed && status.Complete
+		details = append(details, fmt.Sprintf("%s: %s", status.Resource, status.Detail))
+	}
+	if strings.TrimSpace(spec.PromQL) != "" {
+		expr, err := r.renderTemplate(spec.PromQL)
+		if err != nil {
+			return false, "", fmt.Errorf("verify.promql: %w", err)
+		}
+		base, err := r.renderTemplate(spec.Prometheus)
+		if err != nil {
+			return false, "", fmt.Errorf("verify.prometheus: %w", err)
+		}
+		samples, err := promql.Client{BaseURL: base}.Query(ctx, expr)
+		if err != nil {
+			return false, "", err
+		}
+		ok, detail := promqlPasses(samples, spec.Min, spec.Max)
+		passed = passed && ok
+		details = append(details, detail)
+	}
+	return passed, strings.Join(details, "; "), nil
+}
+
+// promqlPasses applies the bounds to every returned series.
+func promqlPasses(samples []promql.Sample, min, max *float64) (bool, string) {
+	if len(samples) == 0 {
+		return false, "query returned no series"
+	}
+	if min == nil && max == nil {
+		return true, fmt.Sprintf("query returned %d series (first %g)", len(samples), samples[0].Value)
+	}
+	for _, s := range samples {
+		if max != nil && s.Value > *max {
+			return false, fmt.Sprintf("observed %g > max %g", s.Value, *max)
+		}
+		if min != nil && s.Value < *min {
+			return false, fmt.Sprintf("observed %g < min %g", s.Value, *min)
+		}
+	}
+	return true, fmt.Sprintf("observed %g within bounds", samples[0].Value)
+}
+
+// verifyAndRollback records a remediation step together with its verification. When
+// verification fails the rollback steps run and the workflow stops with an error.
+func (r *Runner) verifyAndRollback(ctx context.Context, res *Result, stage StageSpec, stepName string, step StepSpec, sr *StepResult) error {
+	verification, err := r.verifyStep(ctx, stepName, step.Verify)
+	if err != nil {
+		sr.Status = "error"
+		sr.Error = fmt.Sprintf("verify: %v", err)
+		r.record(res, stage, *sr)
+		return fmt.Errorf("step %s verify: %w", stepName, err)
+	}
+	sr.Verification = verification
+	if _, ok := r.stepState[stepName]; !ok {
+		r.stepState[stepName] = make(map[string]interface{})
+	}
+	r.stepState[stepName]["verification"] = map[string]interface{}{
+		"status": verification.Status,
+		"checks": verification.Checks,
+		"waited": verification.Waited,
+		"detail": verification.Detail,
+	}
+	if verification.Status == "verified" {
+		r.record(res, stage, *sr)
+		return nil
+	}
+
+	sr.Status = "verify_failed"
+	sr.Error = fmt.Sprintf("verification failed after %d check(s) in %s: %s", verification.Checks, verification.Waited, verification.Detail)
+	r.record(res, stage, *sr)
+	ran, rollbackErr := r.rollback(ctx, res, stage, stepName, step.Verify.Rollback)
+	verification.RolledBack = ran
+	err = fmt.Errorf("step %s failed verification: %s", stepName, verification.Detail)
+	if rollbackErr != nil {
+		return fmt.Errorf("%w; rollback stopped: %v", err, rollbackErr)
+	}
+	if len(ran) > 0 {
+		return fmt.Errorf("%w; rolled back with %s", err, strings.Join(ran, ", "))
+	}
+	return err
+}
+
+// rollback runs a failed remediation's rollback steps (see runSteps).
+func (r *Runner) rollback(ctx context.Context, res *Result, stage StageSpec, stepName string, steps []StepSpec) ([]string, error) {
+	names := make([]string, len(steps))
+	for idx, step := range steps {
+		names[idx] = step.Name
+		if names[idx] == "" {
+			names[idx] = fmt.Sprintf("%s_rollback_%d", stepName, idx+1)
+		}
+	}
+	return r.runSteps(ctx, res, stage, names, steps, true)
+}
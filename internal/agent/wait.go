@@ -0,0 +1,163 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	defaultWaitInterval = 10 * time.Second
+	defaultWaitTimeout  = 5 * time.Minute
+)
+
+// WaitSpec configures a wait step. With only Duration it sleeps; with Until it re-runs
+// the step's tool every Interval until the Until template renders true or Timeout passes.
+type WaitSpec struct {
+	Duration string `yaml:"duration"`
+	// Until is a template rendered after each poll with the tool output available as .result;
+	// the condition holds when it renders "true".
+	Until    string `yaml:"until"`
+	Interval string `yaml:"interval"`
+	Timeout  string `yaml:"timeout"`
+	// OnTimeout is "fail" (default) or "continue", which records the step with satisfied=false.
+	OnTimeout string `yaml:"on_timeout"`
+}
+
+// waitTimings holds the parsed durations of a wait step.
+type waitTimings struct {
+	sleep, interval, timeout time.Duration
+}
+
+func (w *WaitSpec) timings() (waitTimings, error) {
+	t := waitTimings{interval: defaultWaitInterval, timeout: defaultWaitTimeout}
+	parse := func(field, value string, dst *time.Duration) error {
+		if strings.TrimSpace(value) == "" {
+			return nil
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || d <= 0 {
+			return fmt.Errorf("wait.%s %q must be a positive duration such as 30s or 5m", field, value)
+		}
+		*dst = d
+		return nil
+	}
+	if err := parse("duration", w.Duration, &t.sleep); err != nil {
+		return t, err
+	}
+	if err := parse("interval", w.Interval, &t.interval); err != nil {
+		return t, err
+	}
+	if err := parse("timeout", w.Timeout, &t.timeout); err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+// executeWait sleeps, or polls step.Tool until the condition holds. Tool errors while
+// polling count as "not yet" so commands that fail until a rollout finishes can be used.
+func (r *Runner) executeWait(ctx context.Context, stepName string, step StepSpec) (map[string]interface{}, error) {
+	if step.Wait == nil {
+		return nil, fmt.Errorf("wait step %s has no wait block", stepName)
+	}
+	timings, err := step.Wait.timings()
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+
+	if strings.TrimSpace(step.Wait.Until) == "" {
+		if err := sleepContext(ctx, timings.sleep); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"waited": time.Since(start).Round(time.Millisecond).String()}, nil
+	}
+
+	if timings.sleep > 0 {
+		// A duration before polling gives a rollout time to start.
+		if err := sleepContext(ctx, timings.sleep); err != nil {
+			return nil, err
+		}
+	}
+	deadline := start.Add(timings.timeout)
+	var last map[string]interface{}
+	var lastErr error
+	attempts := 0
+	for {
+		attempts++
+		params, err := r.renderParams(step.Params)
+		if err != nil {
+			return nil, err
+		}
+		last, lastErr = r.executeTool(ctx, step, params)
+		if lastErr == nil {
+			ok, err := r.waitConditionHolds(step.Wait.Until, last)
+			if err != nil {
+				return nil, fmt.Errorf("wait.until: %w", err)
+			}
+			r.debugf("wait step=%s attempt=%d satisfied=%v", stepName, attempts, ok)
+			if ok {
+				return waitResult(true, attempts, start, last), nil
+			}
+		} else {
+			r.debugf("wait step=%s attempt=%d tool error=%v", stepName, attempts, lastErr)
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !time.Now().Add(timings.interval).Before(deadline) {
+			break
+		}
+		if err := sleepContext(ctx, timings.interval); err != nil {
+			return nil, err
+		}
+	}
+
+	if strings.EqualFold(step.Wait.OnTimeout, "continue") {
+		out := waitResult(false, attempts, start, last)
+		if lastErr != nil {
+			out["last_error"] = lastErr.Error()
+		}
+		return out, nil
+	}
+	if lastErr != nil {
+		return nil, fmt.Errorf("condition not met after %d attempts in %s; last error: %w", attempts, timings.timeout, lastErr)
+	}
+	return nil, fmt.Errorf("condition not met after %d attempts in %s", attempts, timings.timeout)
+}
+
+func waitResult(satisfied bool, attempts int, start time.Time, last map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"satisfied": satisfied,
+		"attempts":  attempts,
+		"waited":    time.Since(start).Round(time.Millisecond).String(),
+		"result":    last,
+	}
+}
+
+// waitConditionHolds renders until against the usual template data plus .result.
+func (r *Runner) waitConditionHolds(until string, result map[string]interface{}) (bool, error) {
+	data := r.templateData()
+	data["result"] = result
+	out, err := r.execTemplate("until", until, data)
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(strings.TrimSpace(out), "true"), nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,419 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/httpx"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+const (
+	defaultWasmTimeout  = 30 * time.Second
+	defaultWasmMemoryMB = 64
+	// wasmHostModule is the import module of the host functions.
+	wasmHostModule = "sre_ai"
+	// maxWasmHTTPBody bounds what http_get reads from a response.
+	maxWasmHTTPBody = 8 << 20
+	// maxWasmHTTPRedirects bounds the redirects http_get follows, each to an allowed host.
+	maxWasmHTTPRedirects = 10
+)
+
+// WasmSpec configures a wasm tool: a WASI module run in-process with no access to the
+// host beyond its arguments, stdin, the tool's env, the mounted directories, and the host
+// functions its capabilities grant.
+type WasmSpec struct {
+	// Module is the .wasm file, relative to the workflow file.
+	Module string      `yaml:"module"`
+	Mounts []WasmMount `yaml:"mounts"`
+	// Capabilities grant host functions of the sre_ai import module: log, facts
+	// (fact_get), and http (http_get, limited to AllowHosts).
+	Capabilities []string `yaml:"capabilities"`
+	// AllowHosts lists the hosts http_get may reach; globs such as *.internal work.
+	AllowHosts []string `yaml:"allow_hosts"`
+	// Timeout bounds one run; default 30s.
+	Timeout string `yaml:"timeout"`
+	// MemoryMB caps the module's linear memory; default 64.
+	MemoryMB int `yaml:"memory_mb"`
+}
+
+// WasmMount exposes a host directory to the module, read-only unless Writable.
+type WasmMount struct {
+	// Host may be a template (e.g. "{{ .run.dir }}") and is relative to the workflow file.
+	Host     string `yaml:"host"`
+	Guest    string `yaml:"guest"`
+	Writable bool   `yaml:"writable"`
+}
+
+// wasmHostFunctions maps each host function to the capability that grants it.
+var wasmHostFunctions = map[string]string{
+	"log":      "log",
+	"fact_get": "facts",
+	"http_get": "http",
+}
+
+func (w *WasmSpec) validate() error {
+	if strings.TrimSpace(w.Module) == "" {
+		return errors.New("wasm.module is required")
+	}
+	known := map[string]bool{}
+	for _, c := range wasmHostFunctions {
+		known[c] = true
+	}
+	for _, c := range w.Capabilities {
+		if !known[c] {
+			return fmt.Errorf("wasm capability %q is unknown (known: facts, http, log)", c)
+		}
+	}
+	if len(w.AllowHosts) > 0 && !w.granted("http") {
+		return errors.New("wasm.allow_hosts needs the http capability")
+	}
+	for _, m := range w.Mounts {
+		if strings.TrimSpace(m.Host) == "" || !strings.HasPrefix(m.Guest, "/") {
+			return fmt.Errorf("wasm mount %q:%q needs a host path and an absolute guest path", m.Host, m.Guest)
+		}
+	}
+	if _, err := w.timeout(); err != nil {
+		return err
+	}
+	if w.MemoryMB < 0 {
+		return fmt.Errorf("wasm.memory_mb must be positive")
+	}
+	return nil
+}
+
+func (w *WasmSpec) granted(capability string) bool {
+	for _, c := range w.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *WasmSpec) timeout() (time.Duration, error) {
+	v := strings.TrimSpace(w.Timeout)
+	if v == "" {
+		return defaultWasmTimeout, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("wasm.timeout %q must be a positive duration such as 10s", w.Timeout)
+	}
+	return d, nil
+}
+
+// executeWasmTool runs the tool's module once. Params are those of mcp tools: args,
+// stdin, and env. The result has the same shape: stdout, stderr, exit_code, and json.
+func (r *Runner) executeWasmTool(ctx context.Context, toolName string, spec ToolSpec, params map[string]interface{}) (map[string]interface{}, error) {
+	w := spec.Wasm
+	if w == nil {
+		return nil, fmt.Errorf("wasm tool %s needs a wasm block", toolName)
+	}
+	if err := w.validate(); err != nil {
+		return nil, fmt.Errorf("tool %s: %w", toolName, err)
+	}
+	extraArgs, err := argsFromValue(params["args"])
+	if err != nil {
+		return nil, fmt.Errorf("tool %s args: %w", toolName, err)
+	}
+	args := append([]string{toolName}, spec.DefaultArgs...)
+	args = append(args, extraArgs...)
+	stdin, err := stringFromValue(params["stdin"])
+	if err != nil {
+		return nil, fmt.Errorf("tool %s stdin: %w", toolName, err)
+	}
+	env := make(map[string]string)
+	for k, v := range spec.Env {
+		env[k] = v
+	}
+	if val, ok := params["env"]; ok {
+		extraEnv, err := stringMapFromValue(val)
+		if err != nil {
+			return nil, fmt.Errorf("tool %s env: %w", toolName, err)
+		}
+		for k, v := range extraEnv {
+			env[k] = v
+		}
+	}
+
+	modulePath := r.workflowPath(w.Module)
+	code, err := os.ReadFile(modulePath)
+	if err != nil {
+		return nil, fmt.Errorf("tool %s: %w", toolName, err)
+	}
+	timeout, _ := w.timeout()
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	memoryMB := w.MemoryMB
+	if memoryMB == 0 {
+		memoryMB = defaultWasmMemoryMB
+	}
+	rtConfig := wazero.NewRuntimeConfig().
+		WithCloseOnContextDone(true).
+		// 64 KiB pages.
+		WithMemoryLimitPages(uint32(memoryMB * 16))
+	if dir, err := config.ConfigDir(ctx); err == nil {
+		if cache, err := wazero.NewCompilationCacheWithDir(filepath.Join(dir, "cache", "wasm")); err == nil {
+			defer cache.Close(ctx)
+			rtConfig = rtConfig.WithCompilationCache(cache)
+		}
+	}
+	rt := wazero.NewRuntimeWithConfig(runCtx, rtConfig)
+	defer rt.Close(ctx)
+
+	compiled, err := rt.CompileModule(runCtx, code)
+	if err != nil {
+		return nil, fmt.Errorf("tool %s: compile %s: %w", toolName, w.Module, err)
+	}
+	for _, fn := range compiled.ImportedFunctions() {
+		module, name, _ := fn.Import()
+		if module != wasmHostModule {
+			continue
+		}
+		capability, ok := wasmHostFunctions[name]
+		if !ok {
+			return nil, fmt.Errorf("tool %s imports unknown host function %s.%s", toolName, module, name)
+		}
+		if !w.granted(capability) {
+			return nil, fmt.Errorf("tool %s imports %s.%s, which needs capability %q in wasm.capabilities", toolName, module, name, capability)
+		}
+	}
+	if _, err := wasi_snapshot_preview1.Instantiate(runCtx, rt); err != nil {
+		return nil, err
+	}
+	if err := r.instantiateWasmHost(runCtx, rt, toolName, w); err != nil {
+		return nil, err
+	}
+
+	fsConfig := wazero.NewFSConfig()
+	for _, m := range w.Mounts {
+		host, err := r.renderTemplate(m.Host)
+		if err != nil {
+			return nil, fmt.Errorf("tool %s mount %s: %w", toolName, m.Host, err)
+		}
+		if strings.Contains(host, "<no value>") {
+			return nil, fmt.Errorf("tool %s mount %q references a value that is not set", toolName, m.Host)
+		}
+		host = r.workflowPath(host)
+		if info, err := os.Stat(host); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("tool %s mount %s: not a directory", toolName, host)
+		}
+		if m.Writable {
+			fsConfig = fsConfig.WithDirMount(host, m.Guest)
+		} else {
+			fsConfig = fsConfig.WithReadOnlyDirMount(host, m.Guest)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	modConfig := wazero.NewModuleConfig().
+		WithName(toolName).
+		WithArgs(args...).
+		WithStdin(strings.NewReader(stdin)).
+		WithStdout(&stdout).
+		WithStderr(&stderr).
+		WithFSConfig(fsConfig).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithRandSource(rand.Reader)
+	envKeys := make([]string, 0, len(env))
+	for k := range env {
+		envKeys = append(envKeys, k)
+	}
+	sort.Strings(envKeys)
+	for _, k := range envKeys {
+		modConfig = modConfig.WithEnv(k, env[k])
+	}
+
+	r.debugf("wasm invoke tool=%s module=%s args=%s", toolName, modulePath, debugDump(args[1:]))
+	exitCode := 0
+	_, runErr := rt.InstantiateModule(runCtx, compiled, modConfig)
+	var exitErr *sys.ExitError
+	if errors.As(runErr, &exitErr) {
+		exitCode = int(exitErr.ExitCode())
+		runErr = nil
+		if exitCode != 0 {
+			runErr = fmt.Errorf("%s exited with %d", toolName, exitCode)
+		}
+	}
+	if runCtx.Err() != nil && ctx.Err() == nil {
+		runErr = fmt.Errorf("%s timed out after %s", toolName, timeout)
+	}
+
+	result := map[string]interface{}{
+		"stdout":    strings.TrimSpace(stdout.String()),
+		"exit_code": exitCode,
+	}
+	if trimmed := strings.TrimSpace(stderr.String()); trimmed != "" {
+		result["stderr"] = trimmed
+	}
+	if raw := strings.TrimSpace(stdout.String()); raw != "" {
+		var parsed interface{}
+		if json.Unmarshal([]byte(raw), &parsed) == nil {
+			result["json"] = parsed
+		}
+	}
+	if runErr != nil {
+		r.debugf("wasm error tool=%s err=%v", toolName, runErr)
+		result["error"] = runErr.Error()
+		return result, runErr
+	}
+	r.debugf("wasm success tool=%s exit=%d", toolName, exitCode)
+	return result, nil
+}
+
+// instantiateWasmHost exports the host functions the tool's capabilities grant. Data goes
+// through guest memory: strings are (ptr, len), and functions that return data write it to
+// (out_ptr, out_cap) and return its length, or -1 when there is none. A length larger than
+// out_cap means nothing was written; call again with a buffer that large.
+func (r *Runner) instantiateWasmHost(ctx context.Context, rt wazero.Runtime, toolName string, w *WasmSpec) error {
+	builder := rt.NewHostModuleBuilder(wasmHostModule)
+	// log(msg_ptr, msg_len) writes to the run's debug log.
+	if w.granted("log") {
+		builder.NewFunctionBuilder().WithFunc(func(ctx context.Context, m api.Module, ptr, n uint32) {
+			if msg, ok := m.Memory().Read(ptr, n); ok {
+				r.debugf("wasm log tool=%s: %s", toolName, msg)
+			}
+		}).Export("log")
+	}
+	// fact_get(key_ptr, key_len, out_ptr, out_cap) -> len: the fact as JSON.
+	if w.granted("facts") {
+		builder.NewFunctionBuilder().WithFunc(func(ctx context.Context, m api.Module, keyPtr, keyLen, outPtr, outCap uint32) int32 {
+			key, ok := m.Memory().Read(keyPtr, keyLen)
+			if !ok {
+				return -1
+			}
+			store, err := r.factStore(ctx)
+			if err != nil {
+				return -1
+			}
+			value, found := store.Get(string(key))
+			if !found {
+				return -1
+			}
+			data, err := json.Marshal(value)
+			if err != nil {
+				return -1
+			}
+			return writeGuest(m, outPtr, outCap, data)
+		}).Export("fact_get")
+	}
+	// http_get(url_ptr, url_len, out_ptr, out_cap) -> len: the body of a 2xx response.
+	if w.granted("http") {
+		// retryURL and retryBody hold a response that did not fit, for the call right after.
+		var retryURL string
+		var retryBody []byte
+		builder.NewFunctionBuilder().WithFunc(func(ctx context.Context, m api.Module, urlPtr, urlLen, outPtr, outCap uint32) int32 {
+			raw, ok := m.Memory().Read(urlPtr, urlLen)
+			if !ok {
+				return -1
+			}
+			target := string(raw)
+			// A retry with a larger buffer gets the same response without fetching again;
+			// any other call fetches afresh, so a module polling a URL sees new data.
+			body := retryBody
+			if target != retryURL || body == nil {
+				var err error
+				if body, err = r.wasmHTTPGet(ctx, w, target); err != nil {
+					retryURL, retryBody = "", nil
+					r.debugf("wasm http_get tool=%s url=%s err=%v", toolName, target, err)
+					return -1
+				}
+			}
+			retryURL, retryBody = "", nil
+			if uint32(len(body)) > outCap {
+				retryURL, retryBody = target, body
+			}
+			return writeGuest(m, outPtr, outCap, body)
+		}).Export("http_get")
+	}
+	_, err := builder.Instantiate(ctx)
+	return err
+}
+
+func (r *Runner) wasmHTTPGet(ctx context.Context, w *WasmSpec, target string) ([]byte, error) {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid url %q", target)
+	}
+	if !w.allowsHost(u) {
+		return nil, fmt.Errorf("host %s is not in wasm.allow_hosts", u.Hostname())
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := httpx.Client(0)
+	// Every hop is checked, or an allowed host could redirect the module anywhere.
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxWasmHTTPRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxWasmHTTPRedirects)
+		}
+		if (req.URL.Scheme != "http" && req.URL.Scheme != "https") || !w.allowsHost(req.URL) {
+			return fmt.Errorf("redirect to host %s is not in wasm.allow_hosts", req.URL.Hostname())
+		}
+		return nil
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxWasmHTTPBody))
+}
+
+// allowsHost reports whether u's host matches one of AllowHosts, by hostname glob or
+// exact host:port.
+func (w *WasmSpec) allowsHost(u *url.URL) bool {
+	for _, pattern := range w.AllowHosts {
+		if ok, _ := path.Match(pattern, u.Hostname()); ok || pattern == u.Host {
+			return true
+		}
+	}
+	return false
+}
+
+func writeGuest(m api.Module, ptr, capacity uint32, data []byte) int32 {
+	if uint32(len(data)) > capacity {
+		return int32(len(data))
+	}
+	if !m.Memory().Write(ptr, data) {
+		return -1
+	}
+	return int32(len(data))
+}
+
+// workflowPath resolves p against the workflow file's directory, expanding ~.
+func (r *Runner) workflowPath(p string) string {
+	if strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, p[2:])
+		}
+	}
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(r.baseDir, p)
+}
@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/example/sre-ai/internal/config"
+)
+
+// buildGuest compiles testdata/httpget to a WASI module.
+func buildGuest(t *testing.T) string {
+	t.Helper()
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go not found in PATH")
+	}
+	out := filepath.Join(t.TempDir(), "httpget.wasm")
+	build := exec.Command(gobin, "build", "-o", out, ".")
+	build.Dir = filepath.Join("testdata", "httpget")
+	build.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if msg, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("build guest: %v\n%s", err, msg)
+	}
+	return out
+}
+
+func TestWasmHTTPGetPollsFreshBodiesAndRetriesFromTheBuffer(t *testing.T) {
+	module := buildGuest(t)
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "response %d", fetches.Add(1))
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	r := &Runner{}
+	spec := ToolSpec{Wasm: &WasmSpec{Module: module, Capabilities: []string{"http"}, AllowHosts: []string{host}}}
+	ctx := config.WithDir(context.Background(), t.TempDir())
+	result, err := r.executeWasmTool(ctx, "poll", spec, map[string]interface{}{"args": []interface{}{srv.URL, "2"}})
+	if err != nil {
+		t.Fatalf("executeWasmTool: %v (%v)", err, result)
+	}
+	if got, want := result["stdout"], "response 1\nresponse 2"; got != want {
+		t.Errorf("stdout = %q, want %q", got, want)
+	}
+	// Each poll asks twice, the second time with a buffer large enough.
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("server saw %d requests, want 2", n)
+	}
+}
+
+func TestWasmHTTPGetRefusesRedirectsToHostsNotAllowed(t *testing.T) {
+	var reached atomic.Bool
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached.Store(true)
+		fmt.Fprint(w, "metadata")
+	}))
+	defer internal.Close()
+	allowed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/same" {
+			http.Redirect(w, r, "/ok", http.StatusFound)
+			return
+		}
+		if r.URL.Path == "/ok" {
+			fmt.Fprint(w, "ok")
+			return
+		}
+		http.Redirect(w, r, internal.URL+"/latest/meta-data", http.StatusFound)
+	}))
+	defer allowed.Close()
+	u, _ := url.Parse(allowed.URL)
+
+	r := &Runner{}
+	w := &WasmSpec{Capabilities: []string{"http"}, AllowHosts: []string{u.Host}}
+	if _, err := r.wasmHTTPGet(context.Background(), w, allowed.URL+"/away"); err == nil {
+		t.Error("http_get followed a redirect to a host not in allow_hosts")
+	}
+	if reached.Load() {
+		t.Error("the disallowed host was reached")
+	}
+	if body, err := r.wasmHTTPGet(context.Background(), w, allowed.URL+"/same"); err != nil || string(body) != "ok" {
+		t.Errorf("redirect within the allowed host = %q, %v; want ok", body, err)
+	}
+}
@@ -2,6 +2,8 @@ package agent
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,13 +11,24 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync/atomic"
 	"text/template"
+	"time"
 
 	"github.com/example/sre-ai/internal/config"
-	"github.com/example/sre-ai/internal/credentials"
+	"github.com/example/sre-ai/internal/confirm"
+	"github.com/example/sre-ai/internal/facts"
+	"github.com/example/sre-ai/internal/gitlog"
 	"github.com/example/sre-ai/internal/mcp"
+	"github.com/example/sre-ai/internal/messages"
 	"github.com/example/sre-ai/internal/providers"
+	"github.com/example/sre-ai/internal/rules"
+	"github.com/example/sre-ai/internal/templatex"
+	"github.com/example/sre-ai/internal/timeparse"
+	"github.com/example/sre-ai/internal/upload"
+	"github.com/example/sre-ai/internal/workspace"
 	"gopkg.in/yaml.v3"
 )
 
@@ -30,6 +43,9 @@ type Workflow struct {
 	Workflow    WorkflowSpec          `yaml:"workflow"`
 	Outputs     map[string]OutputSpec `yaml:"outputs"`
 	Macros      map[string]MacroSpec  `yaml:"macros"`
+	// Requires lists, by MCP server alias, the tools the workflow depends on as
+	// name or name@sha256:<hash>; they are checked before any step runs (see checkRequires).
+	Requires map[string][]string `yaml:"requires"`
 }
 
 // AgentSpec defines execution defaults for a workflow.
@@ -56,6 +72,10 @@ type ToolSpec struct {
 	Alias       string            `yaml:"alias"`
 	DefaultArgs []string          `yaml:"default_args"`
 	Env         map[string]string `yaml:"env"`
+	// RawCommand is the default raw_command of mcp steps using this tool.
+	RawCommand string `yaml:"raw_command"`
+	// Wasm configures kind: wasm tools.
+	Wasm *WasmSpec `yaml:"wasm"`
 }
 
 // WorkflowSpec contains the ordered stages to execute.
@@ -68,6 +88,7 @@ type StageSpec struct {
 	ID          string     `yaml:"id"`
 	Kind        string     `yaml:"kind"`
 	Description string     `yaml:"description"`
+	Risk        string     `yaml:"risk"`
 	Steps       []StepSpec `yaml:"steps"`
 }
 
@@ -81,6 +102,21 @@ type StepSpec struct {
 	Params      map[string]interface{} `yaml:"params"`
 	Capture     map[string]string      `yaml:"capture"`
 	Expect      ExpectSpec             `yaml:"expect"`
+	Risk        string                 `yaml:"risk"`
+	Consensus   *ConsensusSpec         `yaml:"consensus"`
+	Wait        *WaitSpec              `yaml:"wait"`
+	Verify      *VerifySpec            `yaml:"verify"`
+	Page        *PageSpec              `yaml:"page"`
+	Fanout      *FanoutSpec            `yaml:"fanout"`
+	Script      *ScriptSpec            `yaml:"script"`
+	Rollout     *RolloutSpec           `yaml:"rollout"`
+	// Target runs an mcp tool step elsewhere: host:<group> or k8s-job:<namespace>.
+	Target string   `yaml:"target"`
+	Job    *JobSpec `yaml:"job"`
+	// Facts are the values a set-fact step stores; a null value forgets the key.
+	Facts map[string]interface{} `yaml:"facts"`
+	// RuleHints appends the failure signatures matched in earlier step outputs to a prompt.
+	RuleHints bool `yaml:"rule_hints"`
 }
 
 // ExpectSpec constrains the shape of a step result.
@@ -91,6 +127,9 @@ type ExpectSpec struct {
 // OutputSpec describes a rendered workflow output.
 type OutputSpec struct {
 	Template string `yaml:"template"`
+	// Upload names a config upload destination that receives the rendered output as
+	// <run-id>/<output>.md.
+	Upload string `yaml:"upload"`
 }
 
 // MacroSpec provides reusable step sequences (unused in MVP but parsed).
@@ -100,6 +139,20 @@ type MacroSpec struct {
 	Notes  map[string]string `yaml:"notes"`
 }
 
+// RiskHigh marks steps (or whole stages) that mutate live systems.
+const RiskHigh = "high"
+
+// StepGate decides whether a high-risk step may run. Returning false blocks the step
+// and stops the run; an error wrapping ErrSkipStep only skips it.
+type StepGate func(ctx context.Context, stage StageSpec, stepName string, step StepSpec) (bool, error)
+
+// ErrSkipStep is wrapped by a StepGate error that refuses one step but lets the run go
+// on: the step is recorded as skipped and the steps after it still run.
+var ErrSkipStep = errors.New("skipped")
+
+// StepObserver is notified after every recorded step result.
+type StepObserver func(stage StageSpec, result StepResult)
+
 // Runner orchestrates workflow execution.
 type Runner struct {
 	workflow  *Workflow
@@ -109,27 +162,95 @@ type Runner struct {
 	opts      *config.GlobalOptions
 	verbose   bool
 	logger    *log.Logger
+	gate      StepGate
+	observer  StepObserver
+
+	providerOverride string
+	modelOverride    string
+	// runID and runDir identify the recorded run, when there is one (see SetRun).
+	runID  string
+	runDir string
+	// lastPrompt is the rendered template of the most recent prompt step.
+	lastPrompt string
+	// lastUsage is the token usage of the most recent prompt step.
+	lastUsage *providers.Usage
+	// outputBytes sums the tool output of the running step; fanout calls add to it
+	// concurrently.
+	outputBytes atomic.Int64
+	// cpuTime sums, in nanoseconds, the CPU time of the subprocesses the running step
+	// started; like outputBytes, fanout calls add to it concurrently.
+	cpuTime atomic.Int64
+	// facts is the session's fact store, opened on first use.
+	facts *facts.Store
+	// ruleSet holds the failure signature rules, loaded on first use.
+	ruleSet *rules.Set
+	// debugger pauses the run around every step (see SetDebugger).
+	debugger StepDebugger
+	// debugParams replaces the rendered params of the next step, as edited in the debugger.
+	debugParams map[string]interface{}
+	// breakpoints holds the stage.step names set with SetBreakpoints.
+	breakpoints map[string]bool
+	// confirmer approves page steps (see SetConfirmer).
+	confirmer confirm.Confirmer
+	// rollout is the .rollout template data while a rollout step runs.
+	rollout map[string]interface{}
+	// limits bound every template the runner renders (see execTemplate).
+	limits templatex.Limits
+	// settings carries the values, not the deadline, of the context the runner was built
+	// with (config dir, principal, locale) for template and script functions, which are
+	// called without a context of their own.
+	settings context.Context
 }
 
 // StepResult captures the outcome of a single executed (or planned) step.
+// PromptHash covers the rendered prompt and TemplateHash the unrendered template,
+// so history can tell template edits apart from changed inputs or tool output.
 type StepResult struct {
-	StageID  string      `json:"stage"`
-	StepName string      `json:"step"`
-	Type     string      `json:"type"`
-	Status   string      `json:"status"`
-	Details  string      `json:"details,omitempty"`
-	Output   interface{} `json:"output,omitempty"`
-	Error    string      `json:"error,omitempty"`
+	StageID      string      `json:"stage"`
+	StepName     string      `json:"step"`
+	Type         string      `json:"type"`
+	Status       string      `json:"status"`
+	Details      string      `json:"details,omitempty"`
+	Prompt       string      `json:"prompt,omitempty"`
+	PromptHash   string      `json:"prompt_hash,omitempty"`
+	TemplateHash string      `json:"template_hash,omitempty"`
+	Output       interface{} `json:"output,omitempty"`
+	Error        string      `json:"error,omitempty"`
+	// Estimate is only set in plan mode.
+	Estimate *StepEstimate `json:"estimate,omitempty"`
+	// Verification is set for steps with a verify block.
+	Verification *Verification `json:"verification,omitempty"`
+	// Rollout is set for rollout steps, with every wave and the decision taken.
+	Rollout *Rollout `json:"rollout,omitempty"`
+	// Usage is the tokens spent by a prompt step, summed over consensus models.
+	Usage *providers.Usage `json:"usage,omitempty"`
+	// Attempt counts re-runs from the debugger; it is 0 for a step's first run.
+	Attempt int `json:"attempt,omitempty"`
+	// Duration is how long the step ran, not counting verification.
+	Duration time.Duration `json:"duration,omitempty"`
+	// CPUTime is the CPU time of the subprocesses the step ran, where the platform
+	// reports it.
+	CPUTime time.Duration `json:"cpu_time,omitempty"`
+	// OutputBytes is the size of the output a tool step read from its tools.
+	OutputBytes int64 `json:"output_bytes,omitempty"`
 }
 
 // Result is returned by a workflow execution.
 type Result struct {
+	RunID       string                 `json:"run_id,omitempty"`
 	Workflow    string                 `json:"workflow"`
 	Description string                 `json:"description,omitempty"`
 	PlanOnly    bool                   `json:"plan_only"`
 	Inputs      map[string]interface{} `json:"inputs"`
 	Steps       []StepResult           `json:"steps"`
 	Outputs     map[string]interface{} `json:"outputs,omitempty"`
+	Estimate    *PlanEstimate          `json:"estimate,omitempty"`
+	// Uploads lists the outputs and run bundles published to object storage.
+	Uploads []*upload.Object `json:"uploads,omitempty"`
+	// StoppedAt is the stage.step breakpoint the run stopped before, if any.
+	StoppedAt string `json:"stopped_at,omitempty"`
+	// Profile is added for --profile output; recorded runs compute it from Steps.
+	Profile *Profile `json:"profile,omitempty"`
 }
 
 // LoadWorkflow parses a workflow file and returns the structured representation.
@@ -139,17 +260,26 @@ func LoadWorkflow(path string) (*Workflow, string, error) {
 		return nil, "", err
 	}
 
-	var wf Workflow
-	if err := yaml.Unmarshal(data, &wf); err != nil {
+	wf, err := ParseWorkflow(data)
+	if err != nil {
 		return nil, "", err
 	}
 
 	baseDir := filepath.Dir(path)
-	return &wf, baseDir, nil
+	return wf, baseDir, nil
+}
+
+// ParseWorkflow decodes a workflow definition, as an editor buffer that is not saved yet.
+func ParseWorkflow(data []byte) (*Workflow, error) {
+	var wf Workflow
+	if err := yaml.Unmarshal(data, &wf); err != nil {
+		return nil, err
+	}
+	return &wf, nil
 }
 
 // NewRunner loads the workflow and prepares it for execution.
-func NewRunner(workflowPath string, opts *config.GlobalOptions, provided map[string]string, logWriter io.Writer) (*Runner, error) {
+func NewRunner(ctx context.Context, workflowPath string, opts *config.GlobalOptions, provided map[string]string, logWriter io.Writer) (*Runner, error) {
 	wf, baseDir, err := LoadWorkflow(workflowPath)
 	if err != nil {
 		return nil, err
@@ -160,6 +290,11 @@ func NewRunner(workflowPath string, opts *config.GlobalOptions, provided map[str
 		return nil, err
 	}
 
+	limits, err := templatex.LimitsFor(opts)
+	if err != nil {
+		return nil, err
+	}
+
 	verbose := opts != nil && opts.Verbose > 0
 	writer := io.Discard
 	if verbose {
@@ -178,6 +313,8 @@ func NewRunner(workflowPath string, opts *config.GlobalOptions, provided map[str
 		opts:      opts,
 		verbose:   verbose,
 		logger:    log.New(writer, "[debug] ", 0),
+		limits:    limits,
+		settings:  context.WithoutCancel(ctx),
 	}, nil
 }
 
@@ -211,6 +348,14 @@ func (r *Runner) Execute(ctx context.Context, planOnly bool) (*Result, error) {
 
 	r.debugf("workflow start name=%s planOnly=%v inputs=%s", r.workflow.Name, planOnly, debugDump(r.inputs))
 
+	unsized := map[string]bool{}
+	if planOnly {
+		res.Estimate = &PlanEstimate{}
+	}
+	if err := r.checkRequires(ctx, planOnly); err != nil {
+		return res, err
+	}
+
 	for _, stage := range r.workflow.Workflow.Stages {
 		r.debugf("stage start id=%s kind=%s", stage.ID, stage.Kind)
 		for idx, step := range stage.Steps {
@@ -229,23 +374,47 @@ func (r *Runner) Execute(ctx context.Context, planOnly bool) (*Result, error) {
 
 			if planOnly {
 				r.debugf("skip stage=%s step=%s (plan mode)", stage.ID, stepName)
+				est, partial := r.estimateStep(ctx, stage, stepName, step, unsized)
+				sr.Estimate = &est
+				res.Estimate.add(est, partial)
 				res.Steps = append(res.Steps, sr)
 				continue
 			}
 
-			output, err := r.executeStep(ctx, stage, stepName, step)
-			if err != nil {
-				sr.Status = "error"
-				sr.Error = err.Error()
-				res.Steps = append(res.Steps, sr)
-				r.debugf("recorded step stage=%s step=%s status=%s error=%s", stage.ID, stepName, sr.Status, sr.Error)
-				return res, err
+			if r.debugger == nil && r.atBreakpoint(stage, stepName) {
+				r.stopAtBreakpoint(res, stage, stepName, step, sr)
+				return res, nil
 			}
 
-			sr.Status = "ok"
-			sr.Output = output
-			res.Steps = append(res.Steps, sr)
-			r.debugf("recorded step stage=%s step=%s status=%s", stage.ID, stepName, sr.Status)
+			if IsHighRisk(stage, step) {
+				allowed, err := r.checkGate(ctx, stage, stepName, step)
+				if errors.Is(err, ErrSkipStep) {
+					sr.Status = "skipped"
+					sr.Error = err.Error()
+					r.record(res, stage, sr)
+					r.debugf("skipped step stage=%s step=%s: %v", stage.ID, stepName, err)
+					continue
+				}
+				if err != nil || !allowed {
+					if err == nil {
+						err = messages.Error(ctx, messages.HighRiskBlocked, stepName)
+					}
+					sr.Status = "blocked"
+					sr.Error = err.Error()
+					r.record(res, stage, sr)
+					return res, err
+				}
+			}
+
+			if r.debugger != nil {
+				if err := r.debugStep(ctx, res, stage, stepName, step, sr); err != nil {
+					return res, err
+				}
+				continue
+			}
+			if err := r.runStep(ctx, res, stage, stepName, step, sr); err != nil {
+				return res, err
+			}
 		}
 	}
 
@@ -256,6 +425,9 @@ func (r *Runner) Execute(ctx context.Context, planOnly bool) (*Result, error) {
 		}
 		res.Outputs = outs
 		r.debugf("workflow outputs=%s", debugDump(outs))
+		if res.Uploads, err = r.uploadOutputs(ctx, outs); err != nil {
+			return res, err
+		}
 	}
 
 	r.debugf("workflow complete name=%s planOnly=%v", r.workflow.Name, planOnly)
@@ -263,10 +435,83 @@ func (r *Runner) Execute(ctx context.Context, planOnly bool) (*Result, error) {
 	return res, nil
 }
 
-func (r *Runner) executeStep(ctx context.Context, stage StageSpec, stepName string, step StepSpec) (map[string]interface{}, error) {
-	renderedParams, err := r.renderParams(step.Params)
+// runStep executes one step and records its result, verifying it when the step has a
+// verify block; rollout steps run their waves instead. A returned error stops the run.
+func (r *Runner) runStep(ctx context.Context, res *Result, stage StageSpec, stepName string, step StepSpec, sr StepResult) error {
+	if strings.EqualFold(step.Type, "rollout") {
+		return r.runRollout(ctx, res, stage, stepName, step, sr)
+	}
+	r.lastPrompt = ""
+	r.lastUsage = nil
+	meter := r.startMeter()
+	output, err := r.executeStep(ctx, stage, stepName, step)
+	meter.stop(&sr)
+	sr.Prompt = r.lastPrompt
+	sr.Usage = r.lastUsage
+	if sr.Prompt != "" {
+		sr.PromptHash = ContentHash(sr.Prompt)
+		sr.TemplateHash = ContentHash(step.Template)
+	}
 	if err != nil {
-		return nil, err
+		sr.Status = "error"
+		sr.Error = err.Error()
+		r.record(res, stage, sr)
+		r.debugf("recorded step stage=%s step=%s status=%s error=%s", stage.ID, stepName, sr.Status, sr.Error)
+		return err
+	}
+
+	sr.Status = "ok"
+	if review, _ := output["needs_review"].(bool); review {
+		sr.Status = "needs_review"
+	}
+	sr.Output = output
+	if step.Verify != nil {
+		return r.verifyAndRollback(ctx, res, stage, stepName, step, &sr)
+	}
+	r.record(res, stage, sr)
+	r.debugf("recorded step stage=%s step=%s status=%s", stage.ID, stepName, sr.Status)
+	return nil
+}
+
+// SetGate installs the policy gate consulted before high-risk steps run.
+func (r *Runner) SetGate(gate StepGate) {
+	r.gate = gate
+}
+
+// SetObserver installs a callback invoked after each executed step.
+func (r *Runner) SetObserver(observer StepObserver) {
+	r.observer = observer
+}
+
+// IsHighRisk reports whether the step, or the stage containing it, is marked high risk.
+func IsHighRisk(stage StageSpec, step StepSpec) bool {
+	return strings.EqualFold(step.Risk, RiskHigh) || strings.EqualFold(stage.Risk, RiskHigh)
+}
+
+func (r *Runner) checkGate(ctx context.Context, stage StageSpec, stepName string, step StepSpec) (bool, error) {
+	if r.gate == nil {
+		return false, messages.Error(ctx, messages.HighRiskUngated, stepName)
+	}
+	r.debugf("gate check stage=%s step=%s", stage.ID, stepName)
+	return r.gate(ctx, stage, stepName, step)
+}
+
+func (r *Runner) record(res *Result, stage StageSpec, sr StepResult) {
+	res.Steps = append(res.Steps, sr)
+	if r.observer != nil {
+		r.observer(stage, sr)
+	}
+}
+
+func (r *Runner) executeStep(ctx context.Context, stage StageSpec, stepName string, step StepSpec) (map[string]interface{}, error) {
+	// Params edited in the debugger are already rendered.
+	renderedParams := r.debugParams
+	r.debugParams = nil
+	if renderedParams == nil {
+		var err error
+		if renderedParams, err = r.renderParams(step.Params); err != nil {
+			return nil, err
+		}
 	}
 
 	r.debugf("stage=%s step=%s type=%s", stage.ID, stepName, step.Type)
@@ -289,6 +534,14 @@ func (r *Runner) executeStep(ctx context.Context, stage StageSpec, stepName stri
 		result, stepErr = r.executeTool(ctx, step, renderedParams)
 	case "prompt":
 		result, stepErr = r.executePrompt(ctx, step, renderedParams)
+	case "wait":
+		result, stepErr = r.executeWait(ctx, stepName, step)
+	case "set-fact":
+		result, stepErr = r.executeSetFact(ctx, step)
+	case "page":
+		result, stepErr = r.executePage(ctx, stage, stepName, step)
+	case "script":
+		result, stepErr = r.executeScript(ctx, stepName, step)
 	default:
 		stepErr = fmt.Errorf("unsupported step type %s", step.Type)
 	}
@@ -328,21 +581,31 @@ func (r *Runner) executeTool(ctx context.Context, step StepSpec, params map[stri
 		return nil, fmt.Errorf("tool %s is not defined", toolName)
 	}
 
+	if step.Fanout != nil {
+		return r.executeFanout(ctx, step, spec, params)
+	}
+
 	switch strings.ToLower(spec.Kind) {
 	case "mock", "sample":
 		data, err := r.resolveSampleData(spec)
 		if err != nil {
 			return nil, err
 		}
-		return map[string]interface{}{"data": data}, nil
+		return r.countOutput(map[string]interface{}{"data": data}), nil
 	case "mcp":
-		return r.executeMCPTool(ctx, toolName, spec, params)
+		return r.executeMCPTool(ctx, toolName, spec, step, params)
+	case "git":
+		result, err := r.executeGitTool(ctx, toolName, params)
+		return r.countOutput(result), err
+	case "wasm":
+		result, err := r.executeWasmTool(ctx, toolName, spec, params)
+		return r.countOutput(result), err
 	default:
 		return nil, fmt.Errorf("tool kind %s not yet supported", spec.Kind)
 	}
 }
 
-func (r *Runner) executeMCPTool(ctx context.Context, toolName string, spec ToolSpec, params map[string]interface{}) (map[string]interface{}, error) {
+func (r *Runner) executeMCPTool(ctx context.Context, toolName string, spec ToolSpec, step StepSpec, params map[string]interface{}) (map[string]interface{}, error) {
 	alias := strings.TrimSpace(spec.Alias)
 	if val, ok := params["alias"].(string); ok && strings.TrimSpace(val) != "" {
 		alias = strings.TrimSpace(val)
@@ -351,13 +614,28 @@ func (r *Runner) executeMCPTool(ctx context.Context, toolName string, spec ToolS
 		return nil, fmt.Errorf("mcp tool %s missing alias", toolName)
 	}
 
-	extraArgs, err := stringSliceFromValue(params["args"])
+	extraArgs, err := argsFromValue(params["args"])
 	if err != nil {
 		return nil, fmt.Errorf("tool %s args: %w", toolName, err)
 	}
 	args := append([]string{}, spec.DefaultArgs...)
 	args = append(args, extraArgs...)
 
+	rawCommand := spec.RawCommand
+	if val, ok := params["raw_command"]; ok {
+		if rawCommand, err = stringFromValue(val); err != nil {
+			return nil, fmt.Errorf("tool %s raw_command: %w", toolName, err)
+		}
+	}
+	if strings.TrimSpace(rawCommand) != "" {
+		if len(args) > 0 {
+			return nil, fmt.Errorf("tool %s: use either args or raw_command, not both", toolName)
+		}
+		if !hasShellCap(r.opts) {
+			return nil, fmt.Errorf("tool %s runs a raw_command through the shell; grant it with --cap %s", toolName, ShellCapability)
+		}
+	}
+
 	stdin, err := stringFromValue(params["stdin"])
 	if err != nil {
 		return nil, fmt.Errorf("tool %s stdin: %w", toolName, err)
@@ -377,6 +655,34 @@ func (r *Runner) executeMCPTool(ctx context.Context, toolName string, spec ToolS
 		}
 	}
 
+	workdir, err := stringFromValue(params["workdir"])
+	if err != nil {
+		return nil, fmt.Errorf("tool %s workdir: %w", toolName, err)
+	}
+	workdir = strings.TrimSpace(workdir)
+	if strings.Contains(workdir, "<no value>") {
+		// Params render before this point; a missing placeholder (e.g. .run.dir in plan mode) must not pick a directory.
+		return nil, fmt.Errorf("tool %s workdir %q references a value that is not set", toolName, workdir)
+	}
+
+	target, err := r.stepTarget(step)
+	if err != nil {
+		return nil, fmt.Errorf("tool %s: %w", toolName, err)
+	}
+	if !target.Local() {
+		// A remote workdir is a path on the target, used as written.
+		return r.executeRemote(ctx, toolName, alias, target, step.Job, mcp.RunOptions{
+			Args:       args,
+			Stdin:      stdin,
+			Env:        env,
+			Workdir:    workdir,
+			RawCommand: rawCommand,
+		})
+	}
+	if workdir != "" && !strings.HasPrefix(workdir, "~") && !filepath.IsAbs(workdir) {
+		workdir = filepath.Join(r.baseDir, workdir)
+	}
+
 	r.debugf("mcp invoke tool=%s alias=%s args=%s", toolName, alias, debugDump(args))
 	if stdin != "" {
 		r.debugf("mcp stdin tool=%s alias=%s value=%s", toolName, alias, stdin)
@@ -385,7 +691,21 @@ func (r *Runner) executeMCPTool(ctx context.Context, toolName string, spec ToolS
 		r.debugf("mcp env tool=%s alias=%s overrides=%s", toolName, alias, debugDump(env))
 	}
 
-	stdout, stderr, code, runErr := mcp.RunLocalCommand(ctx, alias, args, stdin, env, r.logger)
+	if workdir != "" {
+		r.debugf("mcp workdir tool=%s alias=%s dir=%s", toolName, alias, workdir)
+	}
+
+	// Definition workdirs may use the same placeholders as step templates.
+	stdout, stderr, code, cpu, runErr := mcp.RunLocalCommandWithOptions(ctx, alias, mcp.RunOptions{
+		Args:       args,
+		Stdin:      stdin,
+		Env:        env,
+		Workdir:    workdir,
+		Vars:       r.templateData(),
+		RawCommand: rawCommand,
+	}, r.logger)
+	r.cpuTime.Add(int64(cpu))
+	r.outputBytes.Add(int64(len(stdout) + len(stderr)))
 	result := map[string]interface{}{
 		"stdout":    strings.TrimSpace(stdout),
 		"exit_code": code,
@@ -407,6 +727,70 @@ func (r *Runner) executeMCPTool(ctx context.Context, toolName string, spec ToolS
 	r.debugf("mcp success tool=%s alias=%s exit=%d", toolName, alias, code)
 	return result, nil
 }
+
+// executeGitTool summarises recent commits. Params: repo, paths (or path), since, until, limit.
+func (r *Runner) executeGitTool(ctx context.Context, toolName string, params map[string]interface{}) (map[string]interface{}, error) {
+	repo, err := stringFromValue(params["repo"])
+	if err != nil {
+		return nil, fmt.Errorf("tool %s repo: %w", toolName, err)
+	}
+	if repo == "" {
+		repo = "."
+	}
+
+	paths, err := stringSliceFromValue(params["paths"])
+	if err != nil {
+		return nil, fmt.Errorf("tool %s paths: %w", toolName, err)
+	}
+	if single, err := stringFromValue(params["path"]); err == nil && single != "" {
+		paths = append(paths, single)
+	}
+
+	query := gitlog.Query{Dir: repo, Paths: paths, CPU: &r.cpuTime}
+	now := time.Now()
+	for key, target := range map[string]*time.Time{"since": &query.Since, "until": &query.Until} {
+		value, err := stringFromValue(params[key])
+		if err != nil {
+			return nil, fmt.Errorf("tool %s %s: %w", toolName, key, err)
+		}
+		if value == "" {
+			continue
+		}
+		ts, err := timeparse.Cutoff(value, now)
+		if err != nil {
+			return nil, fmt.Errorf("tool %s %s: %w", toolName, key, err)
+		}
+		*target = ts
+	}
+	if limit, ok := params["limit"]; ok {
+		switch v := limit.(type) {
+		case int:
+			query.Limit = v
+		case float64:
+			query.Limit = int(v)
+		}
+	}
+
+	r.debugf("git log tool=%s repo=%s paths=%s since=%s", toolName, repo, debugDump(paths), query.Since)
+	commits, err := gitlog.Log(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	// Round-trip through JSON so captures and templates see plain maps, as with MCP output.
+	data, err := json.Marshal(map[string]interface{}{
+		"commits": commits,
+		"summary": gitlog.Summarize(commits, 10),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 func (r *Runner) resolveSampleData(spec ToolSpec) (interface{}, error) {
 	if spec.SampleData != nil {
 		return spec.SampleData, nil
@@ -437,63 +821,110 @@ func (r *Runner) executePrompt(ctx context.Context, step StepSpec, params map[st
 	if err != nil {
 		return nil, err
 	}
+	var hits []rules.Hit
+	if step.RuleHints {
+		if hits, err = r.ruleHits(ctx); err != nil {
+			return nil, err
+		}
+		if hints := rules.Hints(hits); hints != "" {
+			prompt = prompt + "\n\n" + hints
+		}
+	}
+	r.lastPrompt = prompt
 
-	model := r.workflow.Agent.Model
-	if model == "" {
-		model = r.opts.Model
+	if step.Consensus != nil {
+		payload, err := r.executeConsensus(ctx, step, prompt)
+		if payload != nil && len(hits) > 0 {
+			payload["rules"] = hits
+		}
+		return payload, err
 	}
-	if model == "" {
-		model = providers.DefaultGeminiModel()
+
+	provider, model := r.modelFor()
+	client, err := providers.New(ctx, provider, model)
+	if err != nil {
+		return nil, err
+	}
+	text, usage, err := providers.GenerateWithUsage(ctx, client, prompt)
+	if err != nil {
+		return nil, err
+	}
+	r.lastUsage = &usage
+
+	payload := map[string]interface{}{"text": text}
+	if len(hits) > 0 {
+		payload["rules"] = hits
 	}
+	if strings.EqualFold(step.Expect.Format, "json") {
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(providers.StripCodeFence(text)), &decoded); err != nil {
+			return nil, fmt.Errorf("expected json response but decode failed: %w", err)
+		}
+		payload["json"] = decoded
+	}
+	return payload, nil
+}
 
-	provider := strings.ToLower(r.workflow.Agent.Provider)
+// modelFor resolves the provider and model for prompt steps: an override set with
+// SetModel wins, then the workflow's agent block, then global options.
+func (r *Runner) modelFor() (string, string) {
+	provider := r.providerOverride
 	if provider == "" {
+		provider = strings.ToLower(r.workflow.Agent.Provider)
+	}
+	if provider == "" && r.opts != nil {
 		provider = strings.ToLower(r.opts.Provider)
 	}
 	if provider == "" {
 		provider = "gemini"
 	}
 
-	switch provider {
-	case "gemini":
-		apiKey, err := credentials.LoadGeminiKey()
-		if err != nil {
-			return nil, err
-		}
-		client := providers.NewGeminiClient(apiKey, model)
-		text, err := client.Generate(ctx, prompt)
-		if err != nil {
-			return nil, err
-		}
+	model := r.modelOverride
+	if model == "" {
+		model = r.workflow.Agent.Model
+	}
+	if model == "" && r.opts != nil {
+		model = r.opts.ExplicitModel()
+	}
+	if model == "" {
+		model = providers.DefaultModel(provider)
+	}
+	return provider, model
+}
 
-		payload := map[string]interface{}{"text": text}
-		// strip code fence if it's a ```json block
-		trimmed := strings.TrimSpace(text)
-		if strings.HasPrefix(strings.ToLower(trimmed), "```json") {
-			// drop the leading fence line
-			if i := strings.Index(trimmed, "\n"); i != -1 {
-				trimmed = trimmed[i+1:]
-			} else {
-				trimmed = strings.TrimPrefix(trimmed, "```json")
-			}
-			// remove trailing fence if present
-			if j := strings.LastIndex(trimmed, "```"); j != -1 {
-				trimmed = trimmed[:j]
-			}
-			text = strings.TrimSpace(trimmed)
-		}
+// SetModel forces every prompt step onto the given provider and model, ignoring the
+// workflow's agent block. Used by eval to run one fixture against several targets.
+func (r *Runner) SetModel(provider, model string) {
+	r.providerOverride = strings.ToLower(provider)
+	r.modelOverride = model
+}
 
-		if strings.EqualFold(step.Expect.Format, "json") {
-			var decoded interface{}
-			if err := json.Unmarshal([]byte(text), &decoded); err != nil {
-				return nil, fmt.Errorf("expected json response but decode failed: %w", err)
-			}
-			payload["json"] = decoded
+// SetRun exposes the recorded run to templates as .run.id and .run.dir, so tools can
+// write artifacts next to the run record.
+func (r *Runner) SetRun(id, dir string) {
+	r.runID = id
+	r.runDir = dir
+}
+
+// templateData is the value templates render against.
+func (r *Runner) templateData() map[string]interface{} {
+	data := map[string]interface{}{
+		"inputs":   r.inputs,
+		"steps":    r.stepState,
+		"workflow": map[string]interface{}{"dir": r.baseDir},
+	}
+	if r.runDir != "" {
+		data["run"] = map[string]interface{}{"id": r.runID, "dir": r.runDir}
+	}
+	if r.rollout != nil {
+		data["rollout"] = r.rollout
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		if root, ok := workspace.GitRoot(cwd); ok {
+			data["repo"] = map[string]interface{}{"root": root}
 		}
-		return payload, nil
-	default:
-		return nil, fmt.Errorf("provider %s not supported for prompts", provider)
 	}
+	return data
 }
 
 func (r *Runner) renderOutputs() (map[string]interface{}, error) {
@@ -511,6 +942,32 @@ func (r *Runner) renderOutputs() (map[string]interface{}, error) {
 	return outputs, nil
 }
 
+// uploadOutputs publishes the outputs that name an upload destination, in name order.
+func (r *Runner) uploadOutputs(ctx context.Context, outs map[string]interface{}) ([]*upload.Object, error) {
+	names := make([]string, 0, len(r.workflow.Outputs))
+	for name, spec := range r.workflow.Outputs {
+		if strings.TrimSpace(spec.Upload) != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	prefix := r.runID
+	if prefix == "" {
+		prefix = r.workflow.Name + "-" + time.Now().UTC().Format("20060102T150405")
+	}
+	var objects []*upload.Object
+	for _, name := range names {
+		body := fmt.Sprint(outs[name])
+		obj, err := upload.Publish(ctx, r.opts, r.workflow.Outputs[name].Upload, prefix+"/"+name+".md", []byte(body), upload.TypeMarkdown)
+		if err != nil {
+			return objects, fmt.Errorf("output %s: %w", name, err)
+		}
+		r.debugf("uploaded output=%s url=%s dry_run=%v", name, obj.URL, obj.DryRun)
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
 func resolveInputs(specs map[string]InputSpec, provided map[string]string) (map[string]interface{}, error) {
 	resolved := make(map[string]interface{})
 
@@ -542,27 +999,55 @@ func resolveInputs(specs map[string]InputSpec, provided map[string]string) (map[
 	return resolved, nil
 }
 
-func (r *Runner) renderTemplate(body string) (string, error) {
-	tmpl, err := template.New("workflow").Funcs(template.FuncMap{
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
 		"toJSON": func(v interface{}) string {
 			b, _ := json.MarshalIndent(v, "", "  ")
 			return string(b)
 		},
-	}).Parse(body)
-	if err != nil {
-		return "", err
+		"quoteEvidence": quoteEvidence,
+		// join joins a list with sep, e.g. {{ join .rollout.wave "|" }} for a PromQL regex.
+		"join": func(items interface{}, sep string) string {
+			var parts []string
+			switch typed := items.(type) {
+			case []string:
+				parts = typed
+			case []interface{}:
+				for _, item := range typed {
+					parts = append(parts, fmt.Sprint(item))
+				}
+			default:
+				return fmt.Sprint(items)
+			}
+			return strings.Join(parts, sep)
+		},
+		// fact is replaced per runner (see funcMap); this stub lets templates parse in Validate.
+		"fact":  func(key string, fallback ...interface{}) interface{} { return nil },
+		"rules": func() []rules.Hit { return nil },
 	}
+}
 
-	data := map[string]interface{}{
-		"inputs": r.inputs,
-		"steps":  r.stepState,
-	}
+// funcMap is templateFuncs with fact bound to this runner's fact store.
+func (r *Runner) funcMap() template.FuncMap {
+	funcs := templateFuncs()
+	funcs["fact"] = r.fact
+	funcs["rules"] = func() ([]rules.Hit, error) { return r.ruleHits(r.settings) }
+	return funcs
+}
 
-	var buf strings.Builder
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return "", err
-	}
-	return buf.String(), nil
+func (r *Runner) renderTemplate(body string) (string, error) {
+	return r.execTemplate("workflow", body, r.templateData())
+}
+
+// ContentHash returns a short, stable identifier for prompt text.
+func ContentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return "sha256:" + hex.EncodeToString(sum[:])[:16]
+}
+
+// LookupValue resolves a dotted path inside a captured step payload.
+func LookupValue(container map[string]interface{}, path string) interface{} {
+	return lookupValue(container, path)
 }
 
 func lookupValue(container map[string]interface{}, path string) interface{} {
@@ -614,6 +1099,35 @@ func (r *Runner) StepState() map[string]map[string]interface{} {
 func (r *Runner) WorkflowMeta() *Workflow {
 	return r.workflow
 }
+
+// ShellCapability must be granted (--cap shell) before a raw_command reaches the shell.
+const ShellCapability = "shell"
+
+func hasShellCap(opts *config.GlobalOptions) bool {
+	return hasCap(opts, ShellCapability)
+}
+
+func hasCap(opts *config.GlobalOptions, name string) bool {
+	if opts == nil {
+		return false
+	}
+	for _, c := range opts.Caps {
+		if strings.EqualFold(strings.TrimSpace(c), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// argsFromValue reads the args param: a list is taken element by element, verbatim;
+// a single string is split with mcp.SplitArgs so quoted arguments keep their spaces.
+func argsFromValue(value interface{}) ([]string, error) {
+	if str, ok := value.(string); ok {
+		return mcp.SplitArgs(str)
+	}
+	return stringSliceFromValue(value)
+}
+
 func stringSliceFromValue(value interface{}) ([]string, error) {
 	if value == nil {
 		return nil, nil
@@ -0,0 +1,22 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/providers"
+)
+
+func TestModelForUsesTheChosenProvidersDefault(t *testing.T) {
+	opts := &config.GlobalOptions{Provider: "gemini", Model: providers.DefaultGeminiModel()}
+	r := &Runner{workflow: &Workflow{Agent: AgentSpec{Provider: "ollama"}}, opts: opts}
+	if provider, model := r.modelFor(); provider != "ollama" || model != providers.DefaultModel("ollama") {
+		t.Fatalf("modelFor() = %s, %s; want ollama with its default model", provider, model)
+	}
+
+	opts.Model = "llama3.1:70b"
+	opts.SetSource("model", config.SettingSource{Source: config.SourceFlag, Origin: "--model"})
+	if _, model := r.modelFor(); model != "llama3.1:70b" {
+		t.Fatalf("modelFor() model = %s; want the --model value", model)
+	}
+}
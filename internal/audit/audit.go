@@ -0,0 +1,245 @@
+// Package audit keeps a tamper-evident log of what sre-ai did. Each entry carries the
+// hash of the one before it, so editing, removing, or reordering an entry breaks the
+// chain from that point on. Entries record digests rather than content: the run record
+// or report they describe stays where it is, and verification recomputes its digest.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/principal"
+)
+
+const (
+	auditDirName = "audit"
+	logFileName  = "log.jsonl"
+
+	// lockStale is how old a lock file may get before it is assumed abandoned.
+	lockStale = 10 * time.Second
+)
+
+// Actions recorded in the log.
+const (
+	ActionRunFinish  = "run.finish"
+	ActionRunRate    = "run.rate"
+	ActionRunResolve = "run.resolve"
+	ActionApply      = "apply"
+	ActionSign       = "report.sign"
+	ActionApprove    = "confirm.approve"
+	ActionDeny       = "confirm.deny"
+)
+
+// Entry is one line of the audit log. Hash covers every other field, Prev included.
+type Entry struct {
+	Seq     int       `json:"seq"`
+	At      time.Time `json:"at"`
+	Actor   string    `json:"actor,omitempty"`
+	Action  string    `json:"action"`
+	Subject string    `json:"subject"`
+	// By is who answered a confirmation, when that was not the actor.
+	By string `json:"by,omitempty"`
+	// Digest is the sha256 of what Subject referred to when the entry was written.
+	Digest string `json:"digest,omitempty"`
+	Detail string `json:"detail,omitempty"`
+	Prev   string `json:"prev"`
+	Hash   string `json:"hash"`
+}
+
+// Problem is one way the log or what it describes fails verification.
+type Problem struct {
+	Seq     int    `json:"seq,omitempty"`
+	Subject string `json:"subject,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+// Dir returns the directory holding the audit log and the signing key.
+func Dir(ctx context.Context) (string, error) {
+	base, err := config.ConfigDir(ctx)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, auditDirName), nil
+}
+
+// LogPath returns where the audit log is kept.
+func LogPath(ctx context.Context) (string, error) {
+	dir, err := Dir(ctx)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, logFileName), nil
+}
+
+// Record appends an entry for action on subject, chained to the last entry in the log.
+// The actor is the principal carried by ctx.
+func Record(ctx context.Context, action, subject, digest, detail string) (*Entry, error) {
+	return record(ctx, Entry{Action: action, Subject: subject, Digest: digest, Detail: detail})
+}
+
+// RecordConfirmation logs the answer to a confirmation of question about subject. by is
+// who answered; it is left out when the actor answered themselves.
+func RecordConfirmation(ctx context.Context, subject, question string, approved bool, by string) (*Entry, error) {
+	action := ActionDeny
+	if approved {
+		action = ActionApprove
+	}
+	entry := Entry{Action: action, Subject: subject, Detail: question}
+	if by != principal.Current(ctx) {
+		entry.By = by
+	}
+	return record(ctx, entry)
+}
+
+// Principal reports whether p started the entry or answered it.
+func (e Entry) Principal(p string) bool {
+	return e.Actor == p || e.By == p
+}
+
+func record(ctx context.Context, entry Entry) (*Entry, error) {
+	path, err := LogPath(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.EnsureDir(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	unlock, err := lock(path + ".lock")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	entries, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+	entry.Seq = 1
+	entry.At = time.Now().UTC()
+	entry.Actor = principal.Current(ctx)
+	if n := len(entries); n > 0 {
+		entry.Seq = entries[n-1].Seq + 1
+		entry.Prev = entries[n-1].Hash
+	}
+	entry.Hash = entry.computeHash()
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, config.FilePerm)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return nil, err
+	}
+	return &entry, f.Sync()
+}
+
+// Read returns the entries in the log at path. A missing log has no entries.
+func Read(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var entries []Entry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal([]byte(text), &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// VerifyChain checks that entries are numbered in order, that each hash matches its
+// entry, and that each entry points at the one before it.
+func VerifyChain(entries []Entry) []Problem {
+	var problems []Problem
+	prev := ""
+	for i, entry := range entries {
+		if entry.Seq != i+1 {
+			problems = append(problems, Problem{Seq: entry.Seq, Reason: fmt.Sprintf("expected entry %d; entries were removed or reordered", i+1)})
+		}
+		if entry.Prev != prev {
+			problems = append(problems, Problem{Seq: entry.Seq, Reason: "does not follow the previous entry"})
+		}
+		if entry.computeHash() != entry.Hash {
+			problems = append(problems, Problem{Seq: entry.Seq, Reason: "hash mismatch; the entry was modified"})
+		}
+		prev = entry.Hash
+	}
+	return problems
+}
+
+// computeHash hashes the entry's JSON encoding with Hash left empty.
+func (e Entry) computeHash() string {
+	e.Hash = ""
+	data, _ := json.Marshal(e)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Digest returns the hex sha256 of data.
+func Digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// DigestFile returns the hex sha256 of the file at path.
+func DigestFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return Digest(data), nil
+}
+
+// lock serialises writers across processes with an exclusive lock file, taking over
+// one left behind by a process that died holding it.
+func lock(path string) (func(), error) {
+	deadline := time.Now().Add(2 * lockStale)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, config.FilePerm)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if info, statErr := os.Stat(path); statErr == nil && time.Since(info.ModTime()) > lockStale {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("audit log is locked by %s", path)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
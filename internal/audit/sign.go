@@ -0,0 +1,234 @@
+package audit
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/principal"
+)
+
+const (
+	keyFileName = "signing.key"
+	// SignatureSuffix is appended to a report's path to name its detached signature.
+	SignatureSuffix = ".sig"
+	algorithm       = "ed25519"
+)
+
+// Signature is a detached signature over a report file. Signature signs the JSON
+// encoding of every other field, so the signer and time cannot be swapped either.
+type Signature struct {
+	Algorithm string    `json:"algorithm"`
+	KeyID     string    `json:"key_id"`
+	PublicKey string    `json:"public_key"`
+	SHA256    string    `json:"sha256"`
+	Signer    string    `json:"signer,omitempty"`
+	SignedAt  time.Time `json:"signed_at"`
+	Signature string    `json:"signature,omitempty"`
+}
+
+// ErrNoKey reports that no signing key has been created yet.
+var ErrNoKey = errors.New("no signing key yet; it is created when a report is first signed")
+
+// SigningKey loads the local ed25519 signing key, creating it on first use.
+func SigningKey(ctx context.Context) (ed25519.PrivateKey, error) {
+	return loadKey(ctx, true)
+}
+
+func loadKey(ctx context.Context, create bool) (ed25519.PrivateKey, error) {
+	dir, err := Dir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, keyFileName)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if !create {
+			return nil, ErrNoKey
+		}
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if err := config.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})); err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM private key", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an ed25519 key", path)
+	}
+	return key, nil
+}
+
+// PublicKeyPEM encodes pub for sharing with reviewers.
+func PublicKeyPEM(pub ed25519.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// ParsePublicKey reads an ed25519 public key given as PEM or as the base64 public_key
+// of a signature file.
+func ParsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		pub, ok := parsed.(ed25519.PublicKey)
+		if !ok {
+			return nil, errors.New("not an ed25519 public key")
+		}
+		return pub, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, errors.New("public key must be PEM or base64-encoded ed25519")
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// KeyID is a short fingerprint of pub.
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Sign signs data with the local key on behalf of the principal carried by ctx.
+func Sign(ctx context.Context, data []byte) (*Signature, error) {
+	key, err := SigningKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pub := key.Public().(ed25519.PublicKey)
+	sig := &Signature{
+		Algorithm: algorithm,
+		KeyID:     KeyID(pub),
+		PublicKey: base64.StdEncoding.EncodeToString(pub),
+		SHA256:    Digest(data),
+		Signer:    principal.Current(ctx),
+		SignedAt:  time.Now().UTC().Truncate(time.Second),
+	}
+	sig.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, sig.message()))
+	return sig, nil
+}
+
+// SignFile signs the file at path, writes the signature next to it, and records the
+// signing in the audit log. It returns the signature's path.
+func SignFile(ctx context.Context, path string) (*Signature, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	sig, err := Sign(ctx, data)
+	if err != nil {
+		return nil, "", err
+	}
+	encoded, err := json.MarshalIndent(sig, "", "  ")
+	if err != nil {
+		return nil, "", err
+	}
+	sigPath := path + SignatureSuffix
+	if err := os.WriteFile(sigPath, append(encoded, '\n'), 0o644); err != nil {
+		return nil, "", err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	if _, err := Record(ctx, ActionSign, abs, sig.SHA256, "key "+sig.KeyID); err != nil {
+		return nil, "", fmt.Errorf("signed %s but could not record it: %w", path, err)
+	}
+	return sig, sigPath, nil
+}
+
+// Verify checks sig against data. trusted lists the keys a signature may come from;
+// when it is empty the local signing key is the only trusted one.
+func (sig *Signature) Verify(ctx context.Context, data []byte, trusted []ed25519.PublicKey) error {
+	if sig.Algorithm != algorithm {
+		return fmt.Errorf("unsupported signature algorithm %q", sig.Algorithm)
+	}
+	pub, err := ParsePublicKey([]byte(sig.PublicKey))
+	if err != nil {
+		return err
+	}
+	if len(trusted) == 0 {
+		key, err := loadKey(ctx, false)
+		if errors.Is(err, ErrNoKey) {
+			return fmt.Errorf("signed by key %s; pass its public key with --key", KeyID(pub))
+		}
+		if err != nil {
+			return err
+		}
+		trusted = []ed25519.PublicKey{key.Public().(ed25519.PublicKey)}
+	}
+	known := false
+	for _, t := range trusted {
+		if t.Equal(pub) {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return fmt.Errorf("signed by key %s, which is not trusted; pass its public key with --key", KeyID(pub))
+	}
+	raw, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil || !ed25519.Verify(pub, sig.message(), raw) {
+		return errors.New("signature is invalid; the signature file was modified")
+	}
+	if Digest(data) != sig.SHA256 {
+		return errors.New("content does not match the signature; the report was modified after signing")
+	}
+	return nil
+}
+
+// ReadSignature loads a signature file.
+func ReadSignature(path string) (*Signature, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var sig Signature
+	if err := json.Unmarshal(data, &sig); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &sig, nil
+}
+
+func (sig Signature) message() []byte {
+	sig.Signature = ""
+	data, _ := json.Marshal(sig)
+	return data
+}
@@ -0,0 +1,630 @@
+// Package bundle packages sre-ai for networks without internet access. Collect and
+// Write build one tar.gz holding the binary, the runtime distributions bundled under
+// third_party, the workflows, and the knowledge notes; Install unpacks it on the target
+// once every file has been checked against the archive's manifest.
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/runs"
+	"github.com/example/sre-ai/internal/runtimes"
+)
+
+const (
+	// ManifestName is the first entry of every bundle.
+	ManifestName  = "sre-ai-offline.json"
+	formatVersion = 1
+)
+
+// Component names a part of a bundle and the top-level directory it is stored under.
+type Component string
+
+const (
+	ComponentBinary    Component = "binary"
+	ComponentRuntimes  Component = "runtimes"
+	ComponentWorkflows Component = "workflows"
+	ComponentKnowledge Component = "knowledge"
+)
+
+// Optional are the components a bundle can leave out; the binary is always included.
+var Optional = []Component{ComponentRuntimes, ComponentWorkflows, ComponentKnowledge}
+
+// ParseComponents reads a comma separated --include list.
+func ParseComponents(values []string) ([]Component, error) {
+	var out []Component
+	seen := map[Component]bool{}
+	for _, value := range values {
+		c := Component(strings.ToLower(strings.TrimSpace(value)))
+		if c == "" || seen[c] {
+			continue
+		}
+		known := false
+		for _, o := range Optional {
+			known = known || c == o
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown component %q (want runtimes, workflows, or knowledge)", value)
+		}
+		seen[c] = true
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// dir is where the component's files are stored in the archive.
+func (c Component) dir() string {
+	switch c {
+	case ComponentBinary:
+		return "bin"
+	case ComponentRuntimes:
+		return "third_party"
+	}
+	return string(c)
+}
+
+// Manifest describes a bundle: the platform it was built for and every entry with its
+// checksum.
+type Manifest struct {
+	Version    int             `json:"version"`
+	Created    time.Time       `json:"created"`
+	CLIVersion string          `json:"cli_version"`
+	Target     runtimes.Target `json:"target"`
+	Components []Component     `json:"components"`
+	// Runtimes lists the bundled distributions as <runtime>/<dist>.
+	Runtimes []string `json:"runtimes,omitempty"`
+	Files    []File   `json:"files"`
+}
+
+// File is one entry of a bundle. Symlinks, such as bin/npm in a Node distribution, have
+// Link set and no checksum.
+type File struct {
+	Path   string      `json:"path"`
+	Mode   fs.FileMode `json:"mode"`
+	Size   int64       `json:"size,omitempty"`
+	SHA256 string      `json:"sha256,omitempty"`
+	Link   string      `json:"link,omitempty"`
+}
+
+// Count returns how many files the bundle holds per component.
+func (m *Manifest) Count() map[Component]int {
+	counts := map[Component]int{}
+	for _, f := range m.Files {
+		counts[componentOf(f.Path)]++
+	}
+	return counts
+}
+
+func componentOf(name string) Component {
+	top, _, _ := strings.Cut(name, "/")
+	for _, c := range append([]Component{ComponentBinary}, Optional...) {
+		if top == c.dir() {
+			return c
+		}
+	}
+	return ""
+}
+
+// BuildOptions controls Collect.
+type BuildOptions struct {
+	Include []Component
+	// Target is the platform the bundle is for; defaults to the current one.
+	Target runtimes.Target
+	// Binary is the sre-ai executable to package; defaults to the running one, and must
+	// be given when Target is another platform.
+	Binary       string
+	RuntimesDir  string
+	WorkflowsDir string
+	KnowledgeDir string
+}
+
+// Plan is what a bundle will hold, ready to Write. Skipped explains the runtime
+// distributions left out and Empty lists included components with nothing to package.
+type Plan struct {
+	Manifest *Manifest   `json:"manifest"`
+	Skipped  []string    `json:"skipped,omitempty"`
+	Empty    []Component `json:"empty,omitempty"`
+	sources  map[string]string
+}
+
+// Collect finds and checksums everything a bundle for opts would hold.
+func Collect(opts BuildOptions) (*Plan, error) {
+	target := opts.Target
+	if target == (runtimes.Target{}) {
+		target = runtimes.CurrentTarget()
+	}
+	plan := &Plan{
+		Manifest: &Manifest{Version: formatVersion, Created: time.Now().UTC(), CLIVersion: runs.CLIVersion(), Target: target},
+		sources:  map[string]string{},
+	}
+
+	binary := opts.Binary
+	if binary == "" {
+		if target != runtimes.CurrentTarget() {
+			return nil, fmt.Errorf("the running binary is built for %s; pass --binary with a sre-ai binary built for %s", runtimes.CurrentTarget(), target)
+		}
+		exe, err := os.Executable()
+		if err != nil {
+			return nil, fmt.Errorf("locate the sre-ai binary: %w", err)
+		}
+		if binary, err = filepath.EvalSymlinks(exe); err != nil {
+			return nil, fmt.Errorf("locate the sre-ai binary: %w", err)
+		}
+	}
+	name := "sre-ai"
+	if target.OS == "windows" {
+		name += ".exe"
+	}
+	if err := plan.addFile(path.Join(ComponentBinary.dir(), name), binary, 0o755); err != nil {
+		return nil, err
+	}
+	plan.Manifest.Components = append(plan.Manifest.Components, ComponentBinary)
+
+	for _, c := range opts.Include {
+		before := len(plan.Manifest.Files)
+		var err error
+		switch c {
+		case ComponentRuntimes:
+			err = plan.addRuntimes(opts.RuntimesDir, target)
+		case ComponentWorkflows:
+			err = plan.addTree(opts.WorkflowsDir, c.dir(), false)
+		case ComponentKnowledge:
+			err = plan.addTree(opts.KnowledgeDir, c.dir(), false)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(plan.Manifest.Files) == before {
+			plan.Empty = append(plan.Empty, c)
+			continue
+		}
+		plan.Manifest.Components = append(plan.Manifest.Components, c)
+	}
+	sort.Slice(plan.Manifest.Files, func(i, j int) bool { return plan.Manifest.Files[i].Path < plan.Manifest.Files[j].Path })
+	return plan, nil
+}
+
+// addRuntimes adds the distributions under dir/<runtime>/<dist> whose manifest names
+// target, after checking them against it. Distributions without a manifest cannot be
+// checked on the other side and are left out.
+func (p *Plan) addRuntimes(dir string, target runtimes.Target) error {
+	for _, name := range runtimes.Known {
+		root := filepath.Join(dir, string(name))
+		entries, err := os.ReadDir(root)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			dist := filepath.Join(root, entry.Name())
+			label := string(name) + "/" + entry.Name()
+			manifest, err := runtimes.ReadManifest(dist)
+			if errors.Is(err, fs.ErrNotExist) {
+				p.Skipped = append(p.Skipped, fmt.Sprintf("%s: no %s; bundle it again with 'sre-ai runtime bundle --force'", label, runtimes.ManifestFile))
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", label, err)
+			}
+			if manifest.Target != target {
+				p.Skipped = append(p.Skipped, fmt.Sprintf("%s: built for %s", label, manifest.Target))
+				continue
+			}
+			if err := runtimes.VerifyBundle(dist); err != nil {
+				return fmt.Errorf("%s: %w; bundle it again with 'sre-ai runtime bundle --force'", label, err)
+			}
+			if err := p.addTree(dist, path.Join(ComponentRuntimes.dir(), label), true); err != nil {
+				return err
+			}
+			p.Manifest.Runtimes = append(p.Manifest.Runtimes, label)
+		}
+	}
+	return nil
+}
+
+// addTree adds the regular files under dir, keeping their modes, and with links set the
+// relative symlinks that stay inside it. A missing dir adds nothing.
+func (p *Plan) addTree(dir, prefix string, links bool) error {
+	if dir == "" {
+		return nil
+	}
+	err := filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if file == dir && errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil || rel == "." {
+			return err
+		}
+		name := path.Join(prefix, filepath.ToSlash(rel))
+		switch {
+		case d.Type().IsRegular():
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			return p.addFile(name, file, info.Mode().Perm())
+		case d.Type()&fs.ModeSymlink != 0 && links:
+			link, err := os.Readlink(file)
+			if err != nil {
+				return err
+			}
+			if !linkInside(name, link, prefix) {
+				return fmt.Errorf("%s links outside %s", file, dir)
+			}
+			p.Manifest.Files = append(p.Manifest.Files, File{Path: name, Mode: 0o777, Link: filepath.ToSlash(link)})
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("collect %s: %w", dir, err)
+	}
+	return nil
+}
+
+func (p *Plan) addFile(name, source string, mode fs.FileMode) error {
+	f, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", source, err)
+	}
+	p.Manifest.Files = append(p.Manifest.Files, File{Path: name, Mode: mode, Size: size, SHA256: hex.EncodeToString(hash.Sum(nil))})
+	p.sources[name] = source
+	return nil
+}
+
+// linkInside reports whether the symlink at name pointing to link resolves to a path
+// under root, all in archive terms.
+func linkInside(name, link, root string) bool {
+	link = filepath.ToSlash(link)
+	if path.IsAbs(link) || filepath.IsAbs(link) {
+		return false
+	}
+	return strings.HasPrefix(path.Join(path.Dir(name), link), root+"/")
+}
+
+// Size is the uncompressed size of the planned files.
+func (p *Plan) Size() int64 {
+	var n int64
+	for _, f := range p.Manifest.Files {
+		n += f.Size
+	}
+	return n
+}
+
+// Write writes the bundle to out, manifest first. Files are checked again as they are
+// copied, so one that changed since Collect fails the build rather than the install.
+func (p *Plan) Write(out string) error {
+	manifest, err := json.MarshalIndent(p.Manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	partial := out + ".partial"
+	f, err := os.OpenFile(partial, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(partial)
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	if err := tw.WriteHeader(&tar.Header{Name: ManifestName, Mode: 0o644, Size: int64(len(manifest)), ModTime: now, Typeflag: tar.TypeReg}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(manifest); err != nil {
+		return err
+	}
+	for _, file := range p.Manifest.Files {
+		if file.Link != "" {
+			if err := tw.WriteHeader(&tar.Header{Name: file.Path, Mode: int64(file.Mode), Linkname: file.Link, ModTime: now, Typeflag: tar.TypeSymlink}); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := p.copyFile(tw, file, now); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(partial, out)
+}
+
+func (p *Plan) copyFile(tw *tar.Writer, file File, now time.Time) error {
+	source := p.sources[file.Path]
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err := tw.WriteHeader(&tar.Header{Name: file.Path, Mode: int64(file.Mode), Size: file.Size, ModTime: now, Typeflag: tar.TypeReg}); err != nil {
+		return err
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tw, hash), in); err != nil {
+		return fmt.Errorf("%s changed while bundling: %w", source, err)
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != file.SHA256 {
+		return fmt.Errorf("%s changed while bundling", source)
+	}
+	return nil
+}
+
+// DefaultPrefix is where Install puts the binary, runtimes, and workflows:
+// ~/.local/share/sre-ai, or %LOCALAPPDATA%\sre-ai on Windows.
+func DefaultPrefix() (string, error) {
+	if dir := os.Getenv("LOCALAPPDATA"); dir != "" && runtime.GOOS == "windows" {
+		return filepath.Join(dir, "sre-ai"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "sre-ai"), nil
+}
+
+// InstallOptions controls Install.
+type InstallOptions struct {
+	// Prefix receives bin/, third_party/, and workflows/; the binary finds the runtimes
+	// in ../third_party.
+	Prefix string
+	// KnowledgeDir receives the knowledge notes.
+	KnowledgeDir string
+	// Force overwrites files that already exist.
+	Force bool
+	// DryRun checks the bundle and reports what would be written without touching disk.
+	DryRun bool
+}
+
+// InstallResult lists what an install wrote (or would write).
+type InstallResult struct {
+	Manifest     *Manifest `json:"manifest"`
+	Binary       string    `json:"binary"`
+	WorkflowsDir string    `json:"workflows_dir,omitempty"`
+	Written      []string  `json:"written"`
+	Skipped      []string  `json:"skipped,omitempty"`
+}
+
+// Install checks the bundle at archive against its manifest, then unpacks it. Nothing is
+// written unless every file matches and the bundle was built for this platform.
+func Install(archive string, opts InstallOptions) (*InstallResult, error) {
+	manifest, err := verifyArchive(archive)
+	if err != nil {
+		return nil, err
+	}
+	if current := runtimes.CurrentTarget(); manifest.Target != current {
+		return nil, fmt.Errorf("%s was built for %s, not %s; build one with 'sre-ai bundle build --target %s --binary <sre-ai for %s>'", archive, manifest.Target, current, current, current)
+	}
+
+	result := &InstallResult{Manifest: manifest}
+	targets := make(map[string]string, len(manifest.Files))
+	for _, file := range manifest.Files {
+		target, err := installPath(opts, file.Path)
+		if err != nil {
+			return nil, err
+		}
+		switch componentOf(file.Path) {
+		case ComponentBinary:
+			result.Binary = target
+		case ComponentWorkflows:
+			result.WorkflowsDir = filepath.Join(opts.Prefix, "workflows")
+		}
+		if _, err := os.Lstat(target); err == nil && !opts.Force {
+			result.Skipped = append(result.Skipped, target)
+			continue
+		}
+		result.Written = append(result.Written, target)
+		targets[file.Path] = target
+	}
+	if opts.DryRun || len(targets) == 0 {
+		return result, nil
+	}
+
+	err = readArchive(archive, func(hdr *tar.Header, r io.Reader) error {
+		target, ok := targets[hdr.Name]
+		if !ok {
+			return nil
+		}
+		private := componentOf(hdr.Name) == ComponentKnowledge
+		dirPerm := os.FileMode(0o755)
+		if private {
+			dirPerm = config.DirPerm
+		}
+		if err := os.MkdirAll(filepath.Dir(target), dirPerm); err != nil {
+			return err
+		}
+		if hdr.Typeflag == tar.TypeSymlink {
+			if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			return os.Symlink(filepath.FromSlash(hdr.Linkname), target)
+		}
+		mode := hdr.FileInfo().Mode().Perm()
+		if private {
+			mode = config.FilePerm
+		}
+		return writeFile(target, r, mode)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// installPath maps an archive entry to where Install puts it.
+func installPath(opts InstallOptions, name string) (string, error) {
+	rel, ok := strings.CutPrefix(name, ComponentKnowledge.dir()+"/")
+	base := opts.KnowledgeDir
+	if !ok {
+		rel, base = name, opts.Prefix
+	}
+	if base == "" {
+		return "", fmt.Errorf("no install location for %s", name)
+	}
+	return filepath.Join(base, filepath.FromSlash(rel)), nil
+}
+
+// verifyArchive reads the whole bundle, checking that it holds exactly the files its
+// manifest lists, each with its recorded checksum.
+func verifyArchive(archive string) (*Manifest, error) {
+	var manifest *Manifest
+	var expected map[string]File
+	seen := map[string]bool{}
+	err := readArchive(archive, func(hdr *tar.Header, r io.Reader) error {
+		if manifest == nil {
+			if hdr.Name != ManifestName {
+				return fmt.Errorf("%s is not an sre-ai offline bundle (missing %s)", archive, ManifestName)
+			}
+			var err error
+			manifest, expected, err = readManifest(r)
+			return err
+		}
+		file, ok := expected[hdr.Name]
+		if !ok || seen[hdr.Name] {
+			return fmt.Errorf("entry %s is not listed in %s", hdr.Name, ManifestName)
+		}
+		seen[hdr.Name] = true
+		if file.Link != "" {
+			if hdr.Typeflag != tar.TypeSymlink || hdr.Linkname != file.Link {
+				return fmt.Errorf("%s: link does not match %s", hdr.Name, ManifestName)
+			}
+			return nil
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return fmt.Errorf("%s: expected a regular file", hdr.Name)
+		}
+		hash := sha256.New()
+		if _, err := io.Copy(hash, r); err != nil {
+			return fmt.Errorf("%s: %w", hdr.Name, err)
+		}
+		if hex.EncodeToString(hash.Sum(nil)) != file.SHA256 {
+			return fmt.Errorf("%s: checksum mismatch", hdr.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("%s is empty", archive)
+	}
+	for _, file := range manifest.Files {
+		if !seen[file.Path] {
+			return nil, fmt.Errorf("%s: listed in %s but missing from the archive", file.Path, ManifestName)
+		}
+	}
+	return manifest, nil
+}
+
+// readManifest parses the manifest and checks that every entry stays inside its
+// component, so the install cannot be steered outside its locations.
+func readManifest(r io.Reader) (*Manifest, map[string]File, error) {
+	var manifest Manifest
+	if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+		return nil, nil, fmt.Errorf("parse %s: %w", ManifestName, err)
+	}
+	if manifest.Version > formatVersion {
+		return nil, nil, fmt.Errorf("bundle version %d is newer than supported version %d", manifest.Version, formatVersion)
+	}
+	files := make(map[string]File, len(manifest.Files))
+	for _, file := range manifest.Files {
+		clean := path.Clean(file.Path)
+		top, rest, _ := strings.Cut(clean, "/")
+		if rest == "" || clean != file.Path || componentOf(clean) == "" {
+			return nil, nil, fmt.Errorf("%s entry %q is not inside a bundle component", ManifestName, file.Path)
+		}
+		if file.Link != "" && (top != ComponentRuntimes.dir() || !linkInside(file.Path, file.Link, top)) {
+			return nil, nil, fmt.Errorf("%s entry %q links outside the bundle", ManifestName, file.Path)
+		}
+		files[file.Path] = file
+	}
+	return &manifest, files, nil
+}
+
+func readArchive(archive string, fn func(hdr *tar.Header, r io.Reader) error) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", archive, err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", archive, err)
+		}
+		if err := fn(hdr, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// writeFile replaces target through a temporary file, so an interrupted install, or one
+// replacing the running binary, never leaves a truncated file behind.
+func writeFile(target string, r io.Reader, mode fs.FileMode) error {
+	partial := target + ".partial"
+	out, err := os.OpenFile(partial, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		os.Remove(partial)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(partial)
+		return err
+	}
+	if err := os.Chmod(partial, mode); err != nil {
+		os.Remove(partial)
+		return err
+	}
+	return os.Rename(partial, target)
+}
@@ -1,11 +1,13 @@
 package config
 
 import (
+    "context"
     "errors"
     "fmt"
     "os"
     "path/filepath"
     "strings"
+    "time"
 
     "github.com/spf13/viper"
 )
@@ -18,6 +20,8 @@ type GlobalOptions struct {
     MaxTokens     int
     Session       string
     JSON          bool
+    // JSONFile, when set, also receives the JSON payload while the terminal gets the human output.
+    JSONFile      string
     Text          bool
     Quiet         bool
     Verbose       int
@@ -27,10 +31,173 @@ type GlobalOptions struct {
     Caps          []string
     DryRun        bool
     AutoConfirm   bool
+    // Runtimes maps a runtime name (node, python) to an explicit binary or bin dir.
+    Runtimes      map[string]string
+    // Time controls how reports render timestamps.
+    Time          TimeOptions
+    // Locale picks the translation of prompts and warnings; empty follows $LANG.
+    Locale        string
+    // Principal is who runs and audit entries are attributed to; empty is the OS user.
+    Principal     string
+    // Confirm selects where approval prompts go when a run is not on a terminal.
+    Confirm       ConfirmOptions
+    // Endpoints holds client certificates and SSH tunnels for remote endpoints, by name.
+    Endpoints     map[string]EndpointOptions
+    // Hosts are the machines exec steps can target with host:<group>, by group name.
+    Hosts         map[string]HostGroupOptions
+    // Uploads are the object storage destinations for --upload, by name (e.g. per environment).
+    Uploads       map[string]UploadOptions
+    // MaxInFlight caps concurrent model calls per provider (gemini, ollama).
+    MaxInFlight   map[string]int
+    // Retention bounds how much run history and other local state is kept.
+    Retention     RetentionOptions
+    // Templates bounds how much work rendering one workflow template may do.
+    Templates     TemplateOptions
+    // Kube is the config file's contexts.k8s block: the default kubecontext and namespace.
+    Kube          KubeOptions
+    // Sources records where each setting that is not a default came from (see Load).
+    Sources       map[string]SettingSource
+    // ProjectConfig is the project config file Load applied, if any.
+    ProjectConfig string
 }
 
-// ConfigDir returns the directory that stores sre-ai configuration artifacts.
-func ConfigDir() (string, error) {
+// KubeOptions picks the cluster commands target when no --kubecontext is given.
+type KubeOptions struct {
+    Context   string
+    Namespace string
+}
+
+// TimeOptions is the config file's time block.
+type TimeOptions struct {
+    // Zone is "Local" (default), "UTC", or an IANA zone name.
+    Zone     string
+    // Layout is a Go time layout or one of default, rfc3339, datetime, rfc1123.
+    Layout   string
+    // Relative appends "(3m ago)" to timestamps; nil means true.
+    Relative *bool
+}
+
+// ConfirmOptions is the config file's confirm block.
+type ConfirmOptions struct {
+    // Via is "tty" (default), "slack", or "web".
+    Via          string
+    SlackChannel string
+    // SlackTokenEnv names the environment variable holding the bot token; default SLACK_BOT_TOKEN.
+    SlackTokenEnv string
+    // WebListen is the address web approval links are served on; default 127.0.0.1:8765.
+    WebListen    string
+    // WebBaseURL is how approvers reach WebListen; default http://<WebListen>.
+    WebBaseURL   string
+    // Timeout bounds how long a remote approval is awaited; zero uses the channel's default.
+    Timeout      time.Duration
+}
+
+// TemplateOptions is the config file's templates block. Zero values keep the defaults.
+type TemplateOptions struct {
+    // MaxOutput is a size such as 4MiB that one rendered template may not exceed.
+    MaxOutput string
+    // MaxRange caps the range iterations of one template, counted over every range in it.
+    MaxRange  int
+    Timeout   time.Duration
+}
+
+// EndpointOptions is one entry of the config file's endpoints block: how to reach the
+// hosts it matches.
+type EndpointOptions struct {
+    // Match lists host or host:port patterns, globs allowed ("*.monitoring.internal").
+    Match []string
+    TLS   *EndpointTLSOptions
+    SSH   *EndpointSSHOptions
+}
+
+// EndpointTLSOptions configures mutual TLS. Paths may start with ~.
+type EndpointTLSOptions struct {
+    // CA is a PEM bundle trusted instead of the system roots.
+    CA         string
+    Cert       string
+    Key        string
+    ServerName string
+}
+
+// EndpointSSHOptions configures a tunnel through a bastion, opened on first use.
+type EndpointSSHOptions struct {
+    // Bastion is [user@]host[:port].
+    Bastion      string
+    // IdentityFile is a private key; without one the ssh-agent at $SSH_AUTH_SOCK is used.
+    IdentityFile string
+    // KnownHosts verifies the bastion's host key; default ~/.ssh/known_hosts.
+    KnownHosts   string
+    // Local and Target set up a listening port forward for processes such as MCP
+    // servers: Local (default 127.0.0.1:0) forwards to Target as seen from the bastion.
+    Local        string
+    Target       string
+}
+
+// HostGroupOptions is one entry of the config file's hosts block. A command targeting the
+// group runs on the first address that accepts the connection.
+type HostGroupOptions struct {
+    // Addresses are [user@]host[:port], tried in order.
+    Addresses    []string
+    // IdentityFile is a private key; without one the ssh-agent at $SSH_AUTH_SOCK is used.
+    IdentityFile string
+    // KnownHosts verifies the hosts' keys; default ~/.ssh/known_hosts.
+    KnownHosts   string
+}
+
+// UploadOptions is one entry of the config file's upload block: where artifacts are
+// published and how they are stored.
+type UploadOptions struct {
+    // URL is s3://bucket/prefix, gs://bucket/prefix, or azblob://account/container/prefix.
+    URL        string
+    // Region is the S3 region; default $AWS_REGION.
+    Region     string
+    // Endpoint replaces the service URL, for S3-compatible stores and emulators.
+    Endpoint   string
+    // Encryption is "default" (provider-managed keys) or "kms" (the customer key in KMSKey).
+    Encryption string
+    KMSKey     string
+    // Tags are set on every object, for lifecycle rules.
+    Tags       map[string]string
+}
+
+// RetentionOptions is the config file's retention block. Limits stay as written
+// (durations such as 90d, sizes such as 500MB); the state package parses them.
+type RetentionOptions struct {
+    // Auto prunes in the background at most once a day; nil means true.
+    Auto    *bool
+    // Limits maps a kind of state (runs, artifacts, facts, sessions, logs, cache) to its limits.
+    Limits  map[string]RetentionLimit
+}
+
+// RetentionLimit caps one kind of state by age and total size; empty keeps the default.
+type RetentionLimit struct {
+    MaxAge  string
+    MaxSize string
+}
+
+type retentionEntry struct {
+    MaxAge  string `mapstructure:"max_age"`
+    MaxSize string `mapstructure:"max_size"`
+}
+
+// ConfigDirEnv names the variable the CLI reads at startup to keep its state somewhere
+// other than ~/.config/sre-ai, e.g. a temp dir in scripts.
+const ConfigDirEnv = "SRE_AI_CONFIG_DIR"
+
+type dirKey struct{}
+
+// WithDir returns a context whose configuration artifacts live in dir; empty keeps the
+// default.
+func WithDir(ctx context.Context, dir string) context.Context {
+    return context.WithValue(ctx, dirKey{}, strings.TrimSpace(dir))
+}
+
+// ConfigDir returns the directory that stores sre-ai configuration artifacts: the one
+// set with WithDir, else ~/.config/sre-ai.
+func ConfigDir(ctx context.Context) (string, error) {
+    if dir, _ := ctx.Value(dirKey{}).(string); dir != "" {
+        return dir, nil
+    }
     home, err := os.UserHomeDir()
     if err != nil {
         return "", fmt.Errorf("resolve home dir: %w", err)
@@ -39,71 +206,281 @@ func ConfigDir() (string, error) {
 }
 
 // DefaultConfigPath resolves the default config file path.
-func DefaultConfigPath() (string, error) {
-    dir, err := ConfigDir()
+func DefaultConfigPath(ctx context.Context) (string, error) {
+    dir, err := ConfigDir(ctx)
     if err != nil {
         return "", err
     }
     return filepath.Join(dir, "config.yaml"), nil
 }
 
-// Load merges configuration from file and environment into the provided options.
-func Load(opts *GlobalOptions) error {
+// fileConfig is the shape of a config file, global or project.
+type fileConfig struct {
+    Model       string            `mapstructure:"model"`
+    Provider    string            `mapstructure:"provider"`
+    Temperature float64           `mapstructure:"temperature"`
+    MaxTokens   int               `mapstructure:"max_tokens"`
+    Session     string            `mapstructure:"session"`
+    DefaultCaps []string          `mapstructure:"default_caps"`
+    MCP         struct {
+        Servers map[string]string `mapstructure:"servers"`
+    } `mapstructure:"mcp"`
+    Contexts    struct {
+        K8s struct {
+            Kubecontext string `mapstructure:"kubecontext"`
+            Namespace   string `mapstructure:"namespace"`
+        } `mapstructure:"k8s"`
+    } `mapstructure:"contexts"`
+    Runtimes    map[string]string `mapstructure:"runtimes"`
+    Providers   struct {
+        MaxInFlight map[string]int `mapstructure:"max_in_flight"`
+    } `mapstructure:"providers"`
+    Time        struct {
+        Zone     string `mapstructure:"zone"`
+        Layout   string `mapstructure:"layout"`
+        Relative *bool  `mapstructure:"relative"`
+    } `mapstructure:"time"`
+    Locale      string `mapstructure:"locale"`
+    Principal   string `mapstructure:"principal"`
+    Confirm     struct {
+        Via     string        `mapstructure:"via"`
+        Timeout time.Duration `mapstructure:"timeout"`
+        Slack   struct {
+            Channel  string `mapstructure:"channel"`
+            TokenEnv string `mapstructure:"token_env"`
+        } `mapstructure:"slack"`
+        Web     struct {
+            Listen  string `mapstructure:"listen"`
+            BaseURL string `mapstructure:"base_url"`
+        } `mapstructure:"web"`
+    } `mapstructure:"confirm"`
+    Retention   struct {
+        Auto      *bool          `mapstructure:"auto"`
+        Runs      retentionEntry `mapstructure:"runs"`
+        Artifacts retentionEntry `mapstructure:"artifacts"`
+        Facts     retentionEntry `mapstructure:"facts"`
+        Sessions  retentionEntry `mapstructure:"sessions"`
+        Logs      retentionEntry `mapstructure:"logs"`
+        Cache     retentionEntry `mapstructure:"cache"`
+    } `mapstructure:"retention"`
+    Templates   struct {
+        MaxOutput string        `mapstructure:"max_output"`
+        MaxRange  int           `mapstructure:"max_range"`
+        Timeout   time.Duration `mapstructure:"timeout"`
+    } `mapstructure:"templates"`
+    Endpoints   map[string]struct {
+        Match []string `mapstructure:"match"`
+        TLS   *struct {
+            CA         string `mapstructure:"ca"`
+            Cert       string `mapstructure:"cert"`
+            Key        string `mapstructure:"key"`
+            ServerName string `mapstructure:"server_name"`
+        } `mapstructure:"tls"`
+        SSH   *struct {
+            Bastion      string `mapstructure:"bastion"`
+            IdentityFile string `mapstructure:"identity_file"`
+            KnownHosts   string `mapstructure:"known_hosts"`
+            Local        string `mapstructure:"local"`
+            Target       string `mapstructure:"target"`
+        } `mapstructure:"ssh"`
+    } `mapstructure:"endpoints"`
+    Hosts       map[string]struct {
+        Addresses    []string `mapstructure:"addresses"`
+        IdentityFile string   `mapstructure:"identity_file"`
+        KnownHosts   string   `mapstructure:"known_hosts"`
+    } `mapstructure:"hosts"`
+    Upload      map[string]struct {
+        URL        string            `mapstructure:"url"`
+        Region     string            `mapstructure:"region"`
+        Endpoint   string            `mapstructure:"endpoint"`
+        Encryption string            `mapstructure:"encryption"`
+        KMSKey     string            `mapstructure:"kms_key"`
+        Tags       map[string]string `mapstructure:"tags"`
+    } `mapstructure:"upload"`
+}
+
+// Load merges configuration from the environment and config files into opts. Settings
+// already recorded in opts.Sources (by flags) are kept; otherwise SRE_AI_* environment
+// variables win over the project config, which wins over the global config. Each setting
+// applied is recorded in opts.Sources.
+func Load(ctx context.Context, opts *GlobalOptions) error {
     if opts.MCPServers == nil {
         opts.MCPServers = make(map[string]string)
     }
+    if err := loadEnv(opts); err != nil {
+        return err
+    }
 
-    v := viper.New()
-    v.SetConfigType("yaml")
-    v.AutomaticEnv()
-    v.SetEnvPrefix("sre_ai")
-    v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+    if cwd, err := os.Getwd(); err == nil {
+        if path, ok := FindProjectConfig(cwd); ok {
+            v, cfg, err := readConfigFile(path)
+            if err != nil {
+                return fmt.Errorf("project config %s: %w", path, err)
+            }
+            if cfg != nil {
+                for _, key := range projectDenied {
+                    if v.IsSet(key) {
+                        return fmt.Errorf("project config %s may not set %s; move it to the global config", path, key)
+                    }
+                }
+                opts.ProjectConfig = path
+                applyFile(opts, v, cfg, SettingSource{Source: SourceProject, Origin: path})
+            }
+        }
+    }
 
     cfgPath := opts.ConfigPath
     if cfgPath == "" {
-        defaultPath, err := DefaultConfigPath()
+        defaultPath, err := DefaultConfigPath(ctx)
         if err != nil {
             return err
         }
         cfgPath = defaultPath
     }
+    v, cfg, err := readConfigFile(cfgPath)
+    if err != nil {
+        return err
+    }
+    if cfg != nil {
+        applyFile(opts, v, cfg, SettingSource{Source: SourceGlobal, Origin: cfgPath})
+    }
+    return nil
+}
 
-    v.SetConfigFile(cfgPath)
+// readConfigFile parses path; a missing file yields a nil config and no error.
+func readConfigFile(path string) (*viper.Viper, *fileConfig, error) {
+    v := viper.New()
+    v.SetConfigType("yaml")
+    v.SetConfigFile(path)
     if err := v.ReadInConfig(); err != nil {
         var pathErr *os.PathError
         if errors.As(err, &pathErr) || strings.Contains(err.Error(), "Not Found") {
-            return nil
+            return nil, nil, nil
         }
-        return err
+        return nil, nil, err
     }
-
-    var fileCfg struct {
-        Model       string            `mapstructure:"model"`
-        Provider    string            `mapstructure:"provider"`
-        DefaultCaps []string          `mapstructure:"default_caps"`
-        MCP         struct {
-            Servers map[string]string `mapstructure:"servers"`
-        } `mapstructure:"mcp"`
+    version := 1
+    if v.IsSet("config_version") {
+        version = v.GetInt("config_version")
     }
-
-    if err := v.Unmarshal(&fileCfg); err != nil {
-        return fmt.Errorf("parse config: %w", err)
+    var cfg fileConfig
+    if err := checkVersion(path, version, v.Unmarshal(&cfg)); err != nil {
+        return nil, nil, err
     }
+    return v, &cfg, nil
+}
 
-    if opts.Model == "" {
-        opts.Model = fileCfg.Model
+// applyFile fills in the settings cfg sets that no higher-precedence source has set.
+// Map entries (MCP servers, endpoints, hosts, uploads, runtimes, limits) are settings of their own.
+func applyFile(opts *GlobalOptions, v *viper.Viper, cfg *fileConfig, src SettingSource) {
+    set := func(key string, present bool, apply func()) {
+        if !present {
+            return
+        }
+        if _, ok := opts.Sources[key]; ok {
+            return
+        }
+        apply()
+        opts.SetSource(key, src)
     }
-    if opts.Provider == "" {
-        opts.Provider = fileCfg.Provider
+    scalar := func(key string, apply func()) {
+        set(key, v.IsSet(key), apply)
     }
-    if len(opts.Caps) == 0 && len(fileCfg.DefaultCaps) > 0 {
-        opts.Caps = append(opts.Caps, fileCfg.DefaultCaps...)
+
+    scalar("model", func() { opts.Model = cfg.Model })
+    scalar("provider", func() { opts.Provider = cfg.Provider })
+    scalar("temperature", func() { opts.Temperature = cfg.Temperature })
+    scalar("max_tokens", func() { opts.MaxTokens = cfg.MaxTokens })
+    scalar("session", func() { opts.Session = cfg.Session })
+    scalar("default_caps", func() { opts.Caps = append([]string(nil), cfg.DefaultCaps...) })
+    scalar("contexts.k8s.kubecontext", func() { opts.Kube.Context = cfg.Contexts.K8s.Kubecontext })
+    scalar("contexts.k8s.namespace", func() { opts.Kube.Namespace = cfg.Contexts.K8s.Namespace })
+    scalar("time.zone", func() { opts.Time.Zone = cfg.Time.Zone })
+    scalar("time.layout", func() { opts.Time.Layout = cfg.Time.Layout })
+    scalar("time.relative", func() { opts.Time.Relative = cfg.Time.Relative })
+    scalar("locale", func() { opts.Locale = cfg.Locale })
+    scalar("principal", func() { opts.Principal = cfg.Principal })
+    scalar("confirm.via", func() { opts.Confirm.Via = cfg.Confirm.Via })
+    scalar("confirm.timeout", func() { opts.Confirm.Timeout = cfg.Confirm.Timeout })
+    scalar("confirm.slack.channel", func() { opts.Confirm.SlackChannel = cfg.Confirm.Slack.Channel })
+    scalar("confirm.slack.token_env", func() { opts.Confirm.SlackTokenEnv = cfg.Confirm.Slack.TokenEnv })
+    scalar("confirm.web.listen", func() { opts.Confirm.WebListen = cfg.Confirm.Web.Listen })
+    scalar("confirm.web.base_url", func() { opts.Confirm.WebBaseURL = cfg.Confirm.Web.BaseURL })
+    scalar("retention.auto", func() { opts.Retention.Auto = cfg.Retention.Auto })
+    scalar("templates.max_output", func() { opts.Templates.MaxOutput = cfg.Templates.MaxOutput })
+    scalar("templates.max_range", func() { opts.Templates.MaxRange = cfg.Templates.MaxRange })
+    scalar("templates.timeout", func() { opts.Templates.Timeout = cfg.Templates.Timeout })
+
+    for alias, path := range cfg.MCP.Servers {
+        path := path
+        set("mcp.servers."+alias, true, func() { opts.MCPServers[alias] = path })
     }
-    for k, v := range fileCfg.MCP.Servers {
-        if _, ok := opts.MCPServers[k]; !ok {
-            opts.MCPServers[k] = v
+    for name, ep := range cfg.Endpoints {
+        entry := EndpointOptions{Match: ep.Match}
+        if ep.TLS != nil {
+            entry.TLS = &EndpointTLSOptions{CA: ep.TLS.CA, Cert: ep.TLS.Cert, Key: ep.TLS.Key, ServerName: ep.TLS.ServerName}
+        }
+        if ep.SSH != nil {
+            entry.SSH = &EndpointSSHOptions{Bastion: ep.SSH.Bastion, IdentityFile: ep.SSH.IdentityFile, KnownHosts: ep.SSH.KnownHosts, Local: ep.SSH.Local, Target: ep.SSH.Target}
         }
+        name := name
+        set("endpoints."+name, true, func() {
+            if opts.Endpoints == nil {
+                opts.Endpoints = make(map[string]EndpointOptions)
+            }
+            opts.Endpoints[name] = entry
+        })
+    }
+    for name, group := range cfg.Hosts {
+        name, entry := name, HostGroupOptions{Addresses: group.Addresses, IdentityFile: group.IdentityFile, KnownHosts: group.KnownHosts}
+        set("hosts."+name, true, func() {
+            if opts.Hosts == nil {
+                opts.Hosts = make(map[string]HostGroupOptions)
+            }
+            opts.Hosts[name] = entry
+        })
+    }
+    for name, u := range cfg.Upload {
+        name, entry := name, UploadOptions{URL: u.URL, Region: u.Region, Endpoint: u.Endpoint, Encryption: u.Encryption, KMSKey: u.KMSKey, Tags: u.Tags}
+        set("upload."+name, true, func() {
+            if opts.Uploads == nil {
+                opts.Uploads = make(map[string]UploadOptions)
+            }
+            opts.Uploads[name] = entry
+        })
+    }
+    for k, n := range cfg.Providers.MaxInFlight {
+        k, n := k, n
+        set("providers.max_in_flight."+k, true, func() {
+            if opts.MaxInFlight == nil {
+                opts.MaxInFlight = make(map[string]int)
+            }
+            opts.MaxInFlight[k] = n
+        })
+    }
+    for name, entry := range map[string]retentionEntry{
+        "runs":      cfg.Retention.Runs,
+        "artifacts": cfg.Retention.Artifacts,
+        "facts":     cfg.Retention.Facts,
+        "sessions":  cfg.Retention.Sessions,
+        "logs":      cfg.Retention.Logs,
+        "cache":     cfg.Retention.Cache,
+    } {
+        name, entry := name, entry
+        set("retention."+name, entry.MaxAge != "" || entry.MaxSize != "", func() {
+            if opts.Retention.Limits == nil {
+                opts.Retention.Limits = make(map[string]RetentionLimit)
+            }
+            opts.Retention.Limits[name] = RetentionLimit{MaxAge: entry.MaxAge, MaxSize: entry.MaxSize}
+        })
+    }
+    for k, path := range cfg.Runtimes {
+        k, path := k, path
+        set("runtimes."+k, true, func() {
+            if opts.Runtimes == nil {
+                opts.Runtimes = make(map[string]string)
+            }
+            opts.Runtimes[k] = path
+        })
     }
-
-    return nil
 }
@@ -0,0 +1,236 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the config_version this build writes and reads. Files without
+// config_version are version 1.
+const CurrentVersion = 2
+
+// migration upgrades a config document to version to. apply edits the document's
+// top-level mapping in place and describes each change; it finds nothing to do in a
+// file that already has the new layout.
+type migration struct {
+	to    int
+	name  string
+	apply func(root *yaml.Node) ([]string, error)
+}
+
+var migrations = []migration{
+	{to: 2, name: "mcp.servers list to map", apply: migrateMCPServers},
+	{to: 2, name: "auth per provider", apply: migrateAuth},
+}
+
+// MigrateResult reports what Migrate changed (or, in a dry run, would change).
+type MigrateResult struct {
+	Path        string   `json:"path"`
+	FromVersion int      `json:"from_version"`
+	ToVersion   int      `json:"to_version"`
+	Changes     []string `json:"changes"`
+	// Backup is the copy of the original file, when one was written.
+	Backup string `json:"backup,omitempty"`
+	DryRun bool   `json:"dry_run,omitempty"`
+}
+
+// Migrate upgrades the config file at path to CurrentVersion, keeping comments and key
+// order. The original is copied to path.bak-<timestamp> before it is rewritten. A file
+// that is already current is left alone and reported with no changes.
+func Migrate(path string, dryRun bool, now time.Time) (*MigrateResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	result := &MigrateResult{Path: path, FromVersion: 1, ToVersion: CurrentVersion, Changes: []string{}, DryRun: dryRun}
+	if len(doc.Content) == 0 {
+		return result, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: expected a mapping at the top level", path)
+	}
+	if _, value := mappingEntry(root, "config_version"); value != nil {
+		n, err := strconv.Atoi(value.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: config_version %q is not a number", path, value.Value)
+		}
+		result.FromVersion = n
+	}
+	if result.FromVersion > CurrentVersion {
+		return nil, fmt.Errorf("%s has config_version %d, newer than this sre-ai understands (%d)", path, result.FromVersion, CurrentVersion)
+	}
+	if result.FromVersion == CurrentVersion {
+		return result, nil
+	}
+
+	for _, m := range migrations {
+		if m.to <= result.FromVersion {
+			continue
+		}
+		changes, err := m.apply(root)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", m.name, err)
+		}
+		result.Changes = append(result.Changes, changes...)
+	}
+	setVersion(root, CurrentVersion)
+	result.Changes = append(result.Changes, fmt.Sprintf("config_version: set to %d", CurrentVersion))
+	if dryRun {
+		return result, nil
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	backup := path + ".bak-" + now.UTC().Format("20060102T150405")
+	if err := WriteFile(backup, data); err != nil {
+		return nil, fmt.Errorf("back up %s: %w", path, err)
+	}
+	result.Backup = backup
+	if err := WriteFile(path, buf.Bytes()); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// checkVersion rejects a config written by a newer sre-ai, and points older layouts that
+// fail to parse at config migrate.
+func checkVersion(path string, version int, parseErr error) error {
+	if version > CurrentVersion {
+		return fmt.Errorf("%s has config_version %d, newer than this sre-ai understands (%d)", path, version, CurrentVersion)
+	}
+	if parseErr != nil && version < CurrentVersion {
+		return fmt.Errorf("parse config: %w (the file may predate config_version %d; run 'sre-ai config migrate')", parseErr, CurrentVersion)
+	}
+	if parseErr != nil {
+		return fmt.Errorf("parse config: %w", parseErr)
+	}
+	return nil
+}
+
+// migrateMCPServers turns a list of servers, written as "alias=path" strings or as
+// {alias, path} mappings, into the alias: path map.
+func migrateMCPServers(root *yaml.Node) ([]string, error) {
+	_, mcp := mappingEntry(root, "mcp")
+	if mcp == nil || mcp.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	_, servers := mappingEntry(mcp, "servers")
+	if servers == nil || servers.Kind != yaml.SequenceNode {
+		return nil, nil
+	}
+	converted := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, item := range servers.Content {
+		var alias, path string
+		switch item.Kind {
+		case yaml.ScalarNode:
+			a, p, ok := strings.Cut(item.Value, "=")
+			if !ok {
+				return nil, fmt.Errorf("mcp.servers entry %q: expected alias=path", item.Value)
+			}
+			alias, path = a, p
+		case yaml.MappingNode:
+			for _, name := range []string{"alias", "name"} {
+				if _, v := mappingEntry(item, name); v != nil {
+					alias = v.Value
+					break
+				}
+			}
+			if _, v := mappingEntry(item, "path"); v != nil {
+				path = v.Value
+			}
+		}
+		alias, path = strings.TrimSpace(alias), strings.TrimSpace(path)
+		if alias == "" || path == "" {
+			return nil, fmt.Errorf("mcp.servers entry at line %d: expected an alias and a path", item.Line)
+		}
+		if _, dup := mappingEntry(converted, alias); dup != nil {
+			return nil, fmt.Errorf("mcp.servers lists alias %s twice", alias)
+		}
+		converted.Content = append(converted.Content, scalarNode(alias), scalarNode(path))
+	}
+	converted.HeadComment, converted.LineComment, converted.FootComment = servers.HeadComment, servers.LineComment, servers.FootComment
+	*servers = *converted
+	return []string{fmt.Sprintf("mcp.servers: converted a list of %d server(s) to an alias: path map", len(converted.Content)/2)}, nil
+}
+
+// migrateAuth moves the single-provider auth block (auth.provider plus its settings) to
+// auth.<provider>.
+func migrateAuth(root *yaml.Node) ([]string, error) {
+	_, auth := mappingEntry(root, "auth")
+	if auth == nil || auth.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	_, provider := mappingEntry(auth, "provider")
+	if provider == nil || provider.Kind != yaml.ScalarNode {
+		return nil, nil
+	}
+	name := strings.ToLower(strings.TrimSpace(provider.Value))
+	if name == "" {
+		return nil, errors.New("auth.provider is empty")
+	}
+	inner := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	var moved []string
+	for i := 0; i+1 < len(auth.Content); i += 2 {
+		k, v := auth.Content[i], auth.Content[i+1]
+		if k.Value == "provider" {
+			continue
+		}
+		if k.Value == "api_key_file" {
+			// Renamed along with the move.
+			k = scalarNode("credential_file")
+		}
+		inner.Content = append(inner.Content, k, v)
+		moved = append(moved, k.Value)
+	}
+	auth.Content = []*yaml.Node{scalarNode(name), inner}
+	return []string{fmt.Sprintf("auth: moved %s under auth.%s", strings.Join(moved, ", "), name)}, nil
+}
+
+// mappingEntry returns the key and value nodes of key in a mapping node.
+func mappingEntry(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// setVersion writes config_version as the first key.
+func setVersion(root *yaml.Node, version int) {
+	if _, value := mappingEntry(root, "config_version"); value != nil {
+		value.Value = strconv.Itoa(version)
+		value.Tag = "!!int"
+		return
+	}
+	key := scalarNode("config_version")
+	if len(root.Content) > 0 {
+		// Keep the file's leading comment at the top.
+		key.HeadComment, root.Content[0].HeadComment = root.Content[0].HeadComment, ""
+	}
+	value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(version)}
+	root.Content = append([]*yaml.Node{key, value}, root.Content...)
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
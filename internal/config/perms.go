@@ -0,0 +1,87 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Everything under ConfigDir may hold API keys, server environments, or incident
+// details, so it is kept private to the current user regardless of the umask.
+const (
+	DirPerm  os.FileMode = 0o700
+	FilePerm os.FileMode = 0o600
+)
+
+// EnsureDir creates dir and any missing parents with DirPerm.
+func EnsureDir(dir string) error {
+	return os.MkdirAll(dir, DirPerm)
+}
+
+// WriteFile writes data to path with FilePerm, creating its directory with DirPerm.
+// os.WriteFile keeps the mode of a file it truncates, so an existing file is
+// tightened as well.
+func WriteFile(path string, data []byte) error {
+	if err := EnsureDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, FilePerm); err != nil {
+		return err
+	}
+	return os.Chmod(path, FilePerm)
+}
+
+// PermIssue is a file or directory that other users can read, write, or enter.
+type PermIssue struct {
+	Path  string `json:"path"`
+	Mode  string `json:"mode"`
+	Want  string `json:"want"`
+	Fixed bool   `json:"fixed,omitempty"`
+}
+
+// CheckPermissions walks dir and reports entries with group or other permission
+// bits. With fix set each one is chmodded: directories to DirPerm, files to their
+// owner bits only. Symlinks are not followed. Windows does not use Unix modes, so
+// nothing is reported there.
+func CheckPermissions(dir string, fix bool) ([]PermIssue, error) {
+	if runtime.GOOS == "windows" {
+		return nil, nil
+	}
+	var issues []PermIssue
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		mode := info.Mode().Perm()
+		if mode&0o077 == 0 {
+			return nil
+		}
+		want := mode &^ 0o077
+		if d.IsDir() {
+			want = DirPerm
+		}
+		issue := PermIssue{Path: path, Mode: fmt.Sprintf("%04o", mode), Want: fmt.Sprintf("%04o", want)}
+		if fix {
+			if err := os.Chmod(path, want); err != nil {
+				return err
+			}
+			issue.Fixed = true
+		}
+		issues = append(issues, issue)
+		return nil
+	})
+	return issues, err
+}
@@ -0,0 +1,173 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Source says where an effective setting came from.
+type Source string
+
+// Sources, from the highest precedence to the lowest.
+const (
+	SourceFlag    Source = "flag"
+	SourceEnv     Source = "env"
+	SourceProject Source = "project"
+	SourceGlobal  Source = "global"
+	SourceDefault Source = "default"
+)
+
+// SettingSource records the source of one setting and, within it, the flag, environment
+// variable, or file that set it.
+type SettingSource struct {
+	Source Source `json:"source"`
+	Origin string `json:"origin,omitempty"`
+}
+
+// SetSource records that key was set by src. Keys are config file paths such as
+// "time.zone" or "mcp.servers.github".
+func (o *GlobalOptions) SetSource(key string, src SettingSource) {
+	if o.Sources == nil {
+		o.Sources = make(map[string]SettingSource)
+	}
+	o.Sources[key] = src
+}
+
+// ExplicitModel is Model when a flag, environment variable, or config file set it, and
+// empty otherwise: the built-in default names a Gemini model, which is wrong for
+// any other provider, so callers fall back to their provider's own default.
+func (o *GlobalOptions) ExplicitModel() string {
+	if o.SourceOf("model").Source == SourceDefault {
+		return ""
+	}
+	return o.Model
+}
+
+// SourceOf reports where key came from; unset keys are defaults.
+func (o *GlobalOptions) SourceOf(key string) SettingSource {
+	if src, ok := o.Sources[key]; ok {
+		return src
+	}
+	return SettingSource{Source: SourceDefault}
+}
+
+// ProjectConfigName is the project config file, looked up from the working directory
+// towards the repository root.
+const ProjectConfigName = ".sre-ai.yaml"
+
+// projectDenied are the settings a project config may not set: they run commands, grant
+// capabilities, pick where data is sent, or say who is acting, which a cloned repository
+// must not be able to do.
+var projectDenied = []string{"default_caps", "mcp", "runtimes", "endpoints", "hosts", "upload", "principal"}
+
+// FindProjectConfig returns the nearest ProjectConfigName in dir or its parents, stopping
+// at the enclosing git repository's root.
+func FindProjectConfig(dir string) (string, bool) {
+	for {
+		path := filepath.Join(dir, ProjectConfigName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return "", false
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// EnvName is the environment variable for a setting: SRE_AI_ followed by the key in upper
+// case with dots as underscores, e.g. SRE_AI_TIME_ZONE.
+func EnvName(key string) string {
+	return "SRE_AI_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// envSettings are the settings that can come from the environment, in the order they
+// are read.
+var envSettings = []struct {
+	key   string
+	apply func(opts *GlobalOptions, value string) error
+}{
+	{"model", func(opts *GlobalOptions, value string) error { opts.Model = value; return nil }},
+	{"provider", func(opts *GlobalOptions, value string) error { opts.Provider = value; return nil }},
+	{"temperature", func(opts *GlobalOptions, value string) error {
+		t, err := strconv.ParseFloat(value, 64)
+		opts.Temperature = t
+		return err
+	}},
+	{"max_tokens", func(opts *GlobalOptions, value string) error {
+		n, err := strconv.Atoi(value)
+		opts.MaxTokens = n
+		return err
+	}},
+	{"session", func(opts *GlobalOptions, value string) error { opts.Session = value; return nil }},
+	{"default_caps", func(opts *GlobalOptions, value string) error {
+		opts.Caps = nil
+		for _, c := range strings.Split(value, ",") {
+			if c = strings.TrimSpace(c); c != "" {
+				opts.Caps = append(opts.Caps, c)
+			}
+		}
+		return nil
+	}},
+	{"contexts.k8s.kubecontext", func(opts *GlobalOptions, value string) error { opts.Kube.Context = value; return nil }},
+	{"contexts.k8s.namespace", func(opts *GlobalOptions, value string) error { opts.Kube.Namespace = value; return nil }},
+	{"time.zone", func(opts *GlobalOptions, value string) error { opts.Time.Zone = value; return nil }},
+	{"time.layout", func(opts *GlobalOptions, value string) error { opts.Time.Layout = value; return nil }},
+	{"time.relative", func(opts *GlobalOptions, value string) error {
+		b, err := strconv.ParseBool(value)
+		opts.Time.Relative = &b
+		return err
+	}},
+	{"locale", func(opts *GlobalOptions, value string) error { opts.Locale = value; return nil }},
+	{"principal", func(opts *GlobalOptions, value string) error { opts.Principal = value; return nil }},
+	{"confirm.via", func(opts *GlobalOptions, value string) error { opts.Confirm.Via = value; return nil }},
+	{"confirm.timeout", func(opts *GlobalOptions, value string) error {
+		d, err := time.ParseDuration(value)
+		opts.Confirm.Timeout = d
+		return err
+	}},
+	{"retention.auto", func(opts *GlobalOptions, value string) error {
+		b, err := strconv.ParseBool(value)
+		opts.Retention.Auto = &b
+		return err
+	}},
+	{"templates.max_output", func(opts *GlobalOptions, value string) error { opts.Templates.MaxOutput = value; return nil }},
+	{"templates.max_range", func(opts *GlobalOptions, value string) error {
+		n, err := strconv.Atoi(value)
+		opts.Templates.MaxRange = n
+		return err
+	}},
+	{"templates.timeout", func(opts *GlobalOptions, value string) error {
+		d, err := time.ParseDuration(value)
+		opts.Templates.Timeout = d
+		return err
+	}},
+}
+
+// loadEnv applies the environment variables of envSettings that are set, unless a flag
+// already set the same setting.
+func loadEnv(opts *GlobalOptions) error {
+	for _, s := range envSettings {
+		name := EnvName(s.key)
+		value, ok := os.LookupEnv(name)
+		if !ok || strings.TrimSpace(value) == "" {
+			continue
+		}
+		if _, set := opts.Sources[s.key]; set {
+			continue
+		}
+		if err := s.apply(opts, strings.TrimSpace(value)); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		opts.SetSource(s.key, SettingSource{Source: SourceEnv, Origin: name})
+	}
+	return nil
+}
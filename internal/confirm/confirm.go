@@ -0,0 +1,110 @@
+package confirm
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/example/sre-ai/internal/messages"
+)
+
+// Request describes one action waiting for an operator's approval.
+type Request struct {
+	// Question is the yes/no question, e.g. "Apply IaC stack prod?".
+	Question string
+	// Detail optionally adds context shown below the question by channels that have room for it.
+	Detail string
+	// Severity styles the question; destructive ones stand out in red on a terminal.
+	Severity messages.Severity
+	// Resources lists exactly what the action touches, one per line.
+	Resources []string
+}
+
+// Ask builds the request for a catalog message in ctx's locale, with its severity and
+// the resources the action touches.
+func Ask(ctx context.Context, id messages.ID, resources []string, args ...any) Request {
+	return Request{
+		Question:  messages.Text(ctx, id, args...),
+		Severity:  messages.SeverityOf(id),
+		Resources: resources,
+	}
+}
+
+// Confirmer asks someone to approve an action. A false result with a nil error means
+// the action was declined.
+type Confirmer interface {
+	Confirm(ctx context.Context, req Request) (bool, error)
+}
+
+// ErrUnavailable reports that no one can be asked, as in a headless run with no
+// approval channel configured.
+var ErrUnavailable = errors.New("no confirmation channel available")
+
+type contextKey struct{}
+
+// WithConfirmer returns a context whose confirmations go to c. Serve mode sets this so
+// commands run on behalf of a request ask through the web instead of a terminal.
+func WithConfirmer(ctx context.Context, c Confirmer) context.Context {
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// FromContext returns the confirmer attached with WithConfirmer, if any.
+func FromContext(ctx context.Context) (Confirmer, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	c, ok := ctx.Value(contextKey{}).(Confirmer)
+	return c, ok && c != nil
+}
+
+// Auto approves everything; it backs --confirm.
+type Auto struct{}
+
+func (Auto) Confirm(context.Context, Request) (bool, error) { return true, nil }
+
+// Unavailable refuses every request with ErrUnavailable.
+type Unavailable struct{}
+
+func (Unavailable) Confirm(context.Context, Request) (bool, error) { return false, ErrUnavailable }
+
+// TTY prompts on a terminal and reads a y/N answer.
+type TTY struct {
+	In  io.Reader
+	Out io.Writer
+}
+
+func (t TTY) Confirm(ctx context.Context, req Request) (bool, error) {
+	if req.Detail != "" {
+		fmt.Fprintln(t.Out, req.Detail)
+	}
+	if len(req.Resources) > 0 {
+		fmt.Fprintln(t.Out, messages.Style(t.Out, req.Severity, messages.Text(ctx, messages.Resources)))
+		for _, r := range req.Resources {
+			fmt.Fprintf(t.Out, "  - %s\n", r)
+		}
+	}
+	fmt.Fprintf(t.Out, "%s %s: ", messages.Style(t.Out, req.Severity, req.Question), messages.Text(ctx, messages.AnswerHint))
+	reader := bufio.NewReader(t.In)
+	resp, err := reader.ReadString('\n')
+	if err != nil {
+		return false, err
+	}
+	return IsYes(ctx, resp), nil
+}
+
+// IsYes reports whether answer approves: y or yes, or a yes word of ctx's locale.
+func IsYes(ctx context.Context, answer string) bool {
+	answer = strings.TrimSpace(strings.ToLower(answer))
+	if answer == "y" || answer == "yes" {
+		return true
+	}
+	for _, word := range strings.Split(messages.Text(ctx, messages.AnswerYes), ",") {
+		if w := strings.TrimSpace(strings.ToLower(word)); w != "" && answer == w {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package confirm
+
+import (
+	"context"
+	"strings"
+
+	"github.com/example/sre-ai/internal/audit"
+	"github.com/example/sre-ai/internal/principal"
+	"github.com/example/sre-ai/internal/warnings"
+)
+
+// Decider is a confirmer that knows who answered, such as the Slack user who reacted.
+type Decider interface {
+	Decide(ctx context.Context, req Request) (approved bool, by string, err error)
+}
+
+// Recorded logs every answer Confirmer gives in the audit log, with the principal who
+// asked and the one who answered. A confirmer that is not a Decider is answered by the
+// current principal, as at a terminal. Failing to log is a warning, not a refusal.
+type Recorded struct {
+	Confirmer Confirmer
+}
+
+func (r Recorded) Confirm(ctx context.Context, req Request) (bool, error) {
+	var approved bool
+	var by string
+	var err error
+	if d, ok := r.Confirmer.(Decider); ok {
+		approved, by, err = d.Decide(ctx, req)
+	} else {
+		approved, err = r.Confirmer.Confirm(ctx, req)
+		by = principal.Current(ctx)
+	}
+	if err != nil {
+		return approved, err
+	}
+	subject := strings.Join(req.Resources, ", ")
+	if subject == "" {
+		subject = req.Question
+	}
+	if _, logErr := audit.RecordConfirmation(ctx, subject, req.Question, approved, by); logErr != nil {
+		warnings.Add(ctx, "audit", "could not log the answer to %q: %v", req.Question, logErr)
+	}
+	return approved, nil
+}
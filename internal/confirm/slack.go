@@ -0,0 +1,207 @@
+package confirm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/example/sre-ai/internal/httpx"
+	"github.com/example/sre-ai/internal/messages"
+	"github.com/example/sre-ai/internal/principal"
+)
+
+const (
+	slackAPIBaseURL      = "https://slack.com/api"
+	defaultSlackTimeout  = 15 * time.Minute
+	defaultSlackInterval = 5 * time.Second
+)
+
+var (
+	slackApprove = map[string]bool{"white_check_mark": true, "heavy_check_mark": true, "+1": true}
+	slackDeny    = map[string]bool{"x": true, "no_entry": true, "-1": true}
+)
+
+// Slack posts the question to a channel and waits for someone to react with
+// :white_check_mark: (approve) or :x: (deny). It only needs a bot token with
+// chat:write and reactions:read, so it works without a public callback URL.
+type Slack struct {
+	Token   string
+	Channel string
+	// Timeout bounds the wait for a reaction; zero means 15 minutes. Timing out declines.
+	Timeout time.Duration
+	// Interval is how often reactions are polled; zero means 5 seconds.
+	Interval time.Duration
+	// BaseURL overrides the Slack Web API endpoint.
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+func (s *Slack) Confirm(ctx context.Context, req Request) (bool, error) {
+	approved, _, err := s.Decide(ctx, req)
+	return approved, err
+}
+
+// Decide posts req and waits for a reaction, returning the principal of the Slack user
+// who reacted.
+func (s *Slack) Decide(ctx context.Context, req Request) (bool, string, error) {
+	if s.Token == "" || s.Channel == "" {
+		return false, "", fmt.Errorf("slack confirmation needs a bot token and channel: %w", ErrUnavailable)
+	}
+	timeout, interval := s.Timeout, s.Interval
+	if timeout <= 0 {
+		timeout = defaultSlackTimeout
+	}
+	if interval <= 0 {
+		interval = defaultSlackInterval
+	}
+
+	question := req.Question
+	if req.Severity == messages.Destructive {
+		question = ":warning: *" + question + "*"
+	}
+	text := fmt.Sprintf("*%s*\n%s", messages.Text(ctx, messages.NeedsApproval), question)
+	if req.Detail != "" {
+		text += "\n" + req.Detail
+	}
+	if len(req.Resources) > 0 {
+		text += "\n" + messages.Text(ctx, messages.Resources)
+		for _, r := range req.Resources {
+			text += "\n• `" + r + "`"
+		}
+	}
+	text += "\n" + messages.Text(ctx, messages.SlackHowTo)
+	var posted struct {
+		Channel string `json:"channel"`
+		TS      string `json:"ts"`
+	}
+	if err := s.call(ctx, http.MethodPost, "chat.postMessage", map[string]any{"channel": s.Channel, "text": text}, &posted); err != nil {
+		return false, "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.reply(ctx, posted.Channel, posted.TS, messages.Text(ctx, messages.SlackNoAnswer, timeout))
+			return false, "", fmt.Errorf("no Slack approval within %s", timeout)
+		case <-ticker.C:
+		}
+		approved, user, decided, err := s.decision(ctx, posted.Channel, posted.TS)
+		if err != nil {
+			if ctx.Err() != nil {
+				continue
+			}
+			return false, "", err
+		}
+		if !decided {
+			continue
+		}
+		verdict := "Denied"
+		if approved {
+			verdict = "Approved"
+		}
+		s.reply(ctx, posted.Channel, posted.TS, fmt.Sprintf("%s by <@%s>.", verdict, user))
+		return approved, principal.Slack(user), nil
+	}
+}
+
+// decision reads the message's reactions. A deny reaction wins over an approval.
+func (s *Slack) decision(ctx context.Context, channel, ts string) (approved bool, user string, decided bool, err error) {
+	var resp struct {
+		Message struct {
+			Reactions []struct {
+				Name  string   `json:"name"`
+				Users []string `json:"users"`
+			} `json:"reactions"`
+		} `json:"message"`
+	}
+	query := url.Values{"channel": {channel}, "timestamp": {ts}}
+	if err := s.call(ctx, http.MethodGet, "reactions.get?"+query.Encode(), nil, &resp); err != nil {
+		return false, "", false, err
+	}
+	for _, r := range resp.Message.Reactions {
+		if slackDeny[r.Name] && len(r.Users) > 0 {
+			return false, r.Users[0], true, nil
+		}
+	}
+	for _, r := range resp.Message.Reactions {
+		if slackApprove[r.Name] && len(r.Users) > 0 {
+			return true, r.Users[0], true, nil
+		}
+	}
+	return false, "", false, nil
+}
+
+// reply posts the outcome in the approval thread; failures are ignored since the
+// decision has already been made. It keeps ctx's values but not its deadline, which
+// may be what ended the wait.
+func (s *Slack) reply(ctx context.Context, channel, ts, text string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	_ = s.call(ctx, http.MethodPost, "chat.postMessage", map[string]any{"channel": channel, "thread_ts": ts, "text": text}, nil)
+}
+
+func (s *Slack) call(ctx context.Context, method, endpoint string, body any, out any) error {
+	base := s.BaseURL
+	if base == "" {
+		base = slackAPIBaseURL
+	}
+	var payload *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(data)
+	} else {
+		payload = bytes.NewReader(nil)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(base, "/")+"/"+endpoint, payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+	client := s.HTTPClient
+	if client == nil {
+		client = httpx.Client(30 * time.Second)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("slack %s: %w", endpointName(endpoint), err)
+	}
+	defer resp.Body.Close()
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return fmt.Errorf("slack %s: %s", endpointName(endpoint), resp.Status)
+	}
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return fmt.Errorf("slack %s: %w", endpointName(endpoint), err)
+	}
+	if !status.OK {
+		return fmt.Errorf("slack %s: %s", endpointName(endpoint), status.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(raw, out)
+}
+
+func endpointName(endpoint string) string {
+	name, _, _ := strings.Cut(endpoint, "?")
+	return name
+}
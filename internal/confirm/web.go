@@ -0,0 +1,184 @@
+package confirm
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/sre-ai/internal/messages"
+)
+
+// WebPathPrefix is where Web expects to be mounted on the serve mux.
+const WebPathPrefix = "/confirm/"
+
+const defaultWebTimeout = 15 * time.Minute
+
+// Web hands out one-time confirmation links for headless runs. Confirm registers the
+// request, passes the link to Notify (which relays it to whoever started the run), and
+// blocks until the link is answered, the context ends, or Timeout passes.
+type Web struct {
+	// BaseURL is the externally reachable address of the serve daemon, e.g. "https://sre-ai.internal:8080".
+	BaseURL string
+	// Notify receives each pending request with its link.
+	Notify func(req Request, link string)
+	// Timeout bounds the wait; zero means 15 minutes. Timing out declines.
+	Timeout time.Duration
+
+	mu      sync.Mutex
+	pending map[string]*webPending
+}
+
+type webPending struct {
+	req Request
+	// catalog is the locale of the run that asked, used for the page it is answered on.
+	catalog *messages.Catalog
+	answer  chan bool
+}
+
+// NewWeb returns a web confirmer that announces links through notify.
+func NewWeb(baseURL string, notify func(Request, string)) *Web {
+	return &Web{BaseURL: baseURL, Notify: notify}
+}
+
+func (w *Web) Confirm(ctx context.Context, req Request) (bool, error) {
+	token, err := newToken()
+	if err != nil {
+		return false, err
+	}
+	p := &webPending{req: req, catalog: messages.FromContext(ctx), answer: make(chan bool, 1)}
+	w.mu.Lock()
+	if w.pending == nil {
+		w.pending = make(map[string]*webPending)
+	}
+	w.pending[token] = p
+	w.mu.Unlock()
+	defer func() {
+		w.mu.Lock()
+		delete(w.pending, token)
+		w.mu.Unlock()
+	}()
+
+	link := strings.TrimRight(w.BaseURL, "/") + WebPathPrefix + token
+	if w.Notify == nil {
+		return false, fmt.Errorf("web confirmation has nowhere to send %s: %w", link, ErrUnavailable)
+	}
+	w.Notify(req, link)
+
+	timeout := w.Timeout
+	if timeout <= 0 {
+		timeout = defaultWebTimeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case ok := <-p.answer:
+		return ok, nil
+	case <-timer.C:
+		return false, fmt.Errorf("no answer at %s within %s", link, timeout)
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// webPage is executed with a webPending.
+var webPage = template.Must(template.New("confirm").Parse(`<!doctype html>
+<html><head><meta charset="utf-8"><title>{{.Text "prompt.needs_approval"}}</title></head>
+<body>
+<h1{{if eq .Req.Severity "destructive"}} style="color:#b00020"{{end}}>{{.Req.Question}}</h1>
+{{if .Req.Detail}}<pre>{{.Req.Detail}}</pre>{{end}}
+{{if .Req.Resources}}<p>{{.Text "prompt.resources"}}</p>
+<ul>{{range .Req.Resources}}<li><code>{{.}}</code></li>{{end}}</ul>{{end}}
+<form method="post">
+<button name="decision" value="approve">{{.Text "prompt.web_approve"}}</button>
+<button name="decision" value="deny">{{.Text "prompt.web_deny"}}</button>
+</form>
+</body></html>
+`))
+
+// ServeHTTP shows the pending question on GET and records the answer on POST
+// (decision=approve or decision=deny). Each link answers once.
+func (w *Web) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.URL.Path, WebPathPrefix)
+	w.mu.Lock()
+	p, ok := w.pending[token]
+	w.mu.Unlock()
+	if !ok || token == "" {
+		http.Error(rw, "confirmation not found or already answered", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = webPage.Execute(rw, p)
+	case http.MethodPost:
+		var approved bool
+		switch r.FormValue("decision") {
+		case "approve":
+			approved = true
+		case "deny":
+		default:
+			http.Error(rw, "decision must be approve or deny", http.StatusBadRequest)
+			return
+		}
+		w.mu.Lock()
+		_, still := w.pending[token]
+		delete(w.pending, token)
+		w.mu.Unlock()
+		if !still {
+			http.Error(rw, "confirmation already answered", http.StatusConflict)
+			return
+		}
+		p.answer <- approved
+		if approved {
+			fmt.Fprintln(rw, p.catalog.Text(messages.WebApproved))
+		} else {
+			fmt.Fprintln(rw, p.catalog.Text(messages.WebDenied))
+		}
+	default:
+		rw.Header().Set("Allow", "GET, POST")
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ListenAndServe serves w's links on addr until ctx ends, for runs with no serve daemon
+// to mount w on. It returns once the listener is bound, and sets BaseURL from the bound
+// address if it is empty.
+func (w *Web) ListenAndServe(ctx context.Context, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("web confirmation: %w", err)
+	}
+	if w.BaseURL == "" {
+		w.BaseURL = "http://" + ln.Addr().String()
+	}
+	mux := http.NewServeMux()
+	mux.Handle(WebPathPrefix, w)
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		_ = srv.Close()
+	}()
+	go func() { _ = srv.Serve(ln) }()
+	return nil
+}
+
+// Req and Text expose a pending request to webPage.
+func (p *webPending) Req() Request { return p.req }
+
+func (p *webPending) Text(id string) string { return p.catalog.Text(messages.ID(id)) }
+
+func newToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("confirmation token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,117 @@
+package confirm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// answerWith returns a Notify that answers each link by posting decision to w.
+func answerWith(t *testing.T, w *Web, decision string, codes chan<- int) func(Request, string) {
+	return func(_ Request, link string) {
+		u, err := url.Parse(link)
+		if err != nil {
+			t.Errorf("link %q: %v", link, err)
+			return
+		}
+		go func() {
+			get := httptest.NewRecorder()
+			w.ServeHTTP(get, httptest.NewRequest(http.MethodGet, u.Path, nil))
+			if get.Code != http.StatusOK || !strings.Contains(get.Body.String(), "Restart checkout?") {
+				t.Errorf("GET %s = %d %q; want the question", u.Path, get.Code, get.Body.String())
+			}
+			post := httptest.NewRequest(http.MethodPost, u.Path, strings.NewReader(url.Values{"decision": {decision}}.Encode()))
+			post.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rec := httptest.NewRecorder()
+			w.ServeHTTP(rec, post)
+			codes <- rec.Code
+		}()
+	}
+}
+
+func TestWebApprovesAndDeniesThroughItsLinks(t *testing.T) {
+	req := Request{Question: "Restart checkout?"}
+	for _, tc := range []struct {
+		decision string
+		want     bool
+	}{{"approve", true}, {"deny", false}} {
+		codes := make(chan int, 1)
+		w := &Web{BaseURL: "https://sre-ai.example"}
+		w.Notify = answerWith(t, w, tc.decision, codes)
+		got, err := w.Confirm(context.Background(), req)
+		if err != nil || got != tc.want {
+			t.Errorf("%s: Confirm = %v, %v; want %v", tc.decision, got, err, tc.want)
+		}
+		if code := <-codes; code != http.StatusOK {
+			t.Errorf("%s: POST status = %d, want 200", tc.decision, code)
+		}
+		if len(w.pending) != 0 {
+			t.Errorf("%s: %d requests still pending", tc.decision, len(w.pending))
+		}
+	}
+}
+
+func TestWebRejectsUnknownAndRepeatedAnswers(t *testing.T) {
+	codes := make(chan int, 1)
+	w := &Web{BaseURL: "https://sre-ai.example"}
+	var link string
+	answer := answerWith(t, w, "approve", codes)
+	w.Notify = func(req Request, l string) { link = l; answer(req, l) }
+	if ok, err := w.Confirm(context.Background(), Request{Question: "Restart checkout?"}); !ok || err != nil {
+		t.Fatalf("Confirm = %v, %v; want approved", ok, err)
+	}
+	<-codes
+
+	u, _ := url.Parse(link)
+	for _, path := range []string{u.Path, WebPathPrefix + "unknown", WebPathPrefix} {
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path+"?decision=approve", nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("POST %s = %d, want 404", path, rec.Code)
+		}
+	}
+}
+
+func TestWebGivesUpWhenTheContextEnds(t *testing.T) {
+	w := NewWeb("https://sre-ai.example", func(Request, string) {})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	ok, err := w.Confirm(ctx, Request{Question: "Restart checkout?"})
+	if ok || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Confirm = %v, %v; want a declined deadline error", ok, err)
+	}
+	if len(w.pending) != 0 {
+		t.Fatalf("%d requests still pending after the context ended", len(w.pending))
+	}
+
+	w.Timeout = 20 * time.Millisecond
+	if ok, err := w.Confirm(context.Background(), Request{Question: "Restart checkout?"}); ok || err == nil {
+		t.Fatalf("Confirm = %v, %v; want a timeout", ok, err)
+	}
+}
+
+func TestWebListenAndServe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	links := make(chan string, 1)
+	w := NewWeb("", func(_ Request, link string) { links <- link })
+	if err := w.ListenAndServe(ctx, "127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		resp, err := http.PostForm(<-links, url.Values{"decision": {"approve"}})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		resp.Body.Close()
+	}()
+	if ok, err := w.Confirm(ctx, Request{Question: "Restart checkout?"}); !ok || err != nil {
+		t.Fatalf("Confirm = %v, %v; want approved over HTTP", ok, err)
+	}
+}
@@ -1,6 +1,7 @@
 package credentials
 
 import (
+    "context"
     "encoding/json"
     "errors"
     "fmt"
@@ -22,8 +23,8 @@ type geminiCredential struct {
 }
 
 // GeminiKeyPath returns the path where Gemini credentials are stored.
-func GeminiKeyPath() (string, error) {
-    base, err := config.ConfigDir()
+func GeminiKeyPath(ctx context.Context) (string, error) {
+    base, err := config.ConfigDir(ctx)
     if err != nil {
         return "", err
     }
@@ -31,13 +32,13 @@ func GeminiKeyPath() (string, error) {
 }
 
 // SaveGeminiKey persists the provided API key to disk.
-func SaveGeminiKey(key string) (string, error) {
-    path, err := GeminiKeyPath()
+func SaveGeminiKey(ctx context.Context, key string) (string, error) {
+    path, err := GeminiKeyPath(ctx)
     if err != nil {
         return "", err
     }
 
-    if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+    if err := config.EnsureDir(filepath.Dir(path)); err != nil {
         return "", err
     }
 
@@ -51,7 +52,7 @@ func SaveGeminiKey(key string) (string, error) {
         return "", err
     }
 
-    if err := os.WriteFile(path, data, 0o600); err != nil {
+    if err := config.WriteFile(path, data); err != nil {
         return "", err
     }
 
@@ -59,8 +60,8 @@ func SaveGeminiKey(key string) (string, error) {
 }
 
 // LoadGeminiKey retrieves the persisted Gemini API key if present.
-func LoadGeminiKey() (string, error) {
-    path, err := GeminiKeyPath()
+func LoadGeminiKey(ctx context.Context) (string, error) {
+    path, err := GeminiKeyPath(ctx)
     if err != nil {
         return "", err
     }
@@ -0,0 +1,100 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/example/sre-ai/internal/config"
+)
+
+// RefPrefix marks an MCP server env value that is looked up in the credential store
+// at launch, e.g. GITHUB_TOKEN: credential:github.
+const RefPrefix = "credential:"
+
+type tokenCredential struct {
+	Token   string `json:"token"`
+	Created string `json:"created"`
+}
+
+// ParseRef returns the credential name referenced by value, if it is a reference.
+func ParseRef(value string) (string, bool) {
+	name, ok := strings.CutPrefix(strings.TrimSpace(value), RefPrefix)
+	if !ok {
+		return "", false
+	}
+	return strings.TrimSpace(name), true
+}
+
+// ValidateName accepts lowercase letters, digits, '-', and '_', which keeps names
+// usable as file names on every platform.
+func ValidateName(name string) error {
+	if name == "" {
+		return errors.New("credential name cannot be empty")
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return fmt.Errorf("invalid credential name %q: use lowercase letters, digits, '-', or '_'", name)
+		}
+	}
+	return nil
+}
+
+// TokenPath returns where the named credential is stored.
+func TokenPath(ctx context.Context, name string) (string, error) {
+	if err := ValidateName(name); err != nil {
+		return "", err
+	}
+	base, err := config.ConfigDir(ctx)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, credentialsDirName, name+".json"), nil
+}
+
+// SaveToken stores or replaces the named credential.
+func SaveToken(ctx context.Context, name, token string) (string, error) {
+	path, err := TokenPath(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(tokenCredential{Token: token, Created: time.Now().UTC().Format(time.RFC3339)}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := config.WriteFile(path, data); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// LoadToken reads the named credential. The Gemini key file is readable this way too.
+func LoadToken(ctx context.Context, name string) (string, error) {
+	if name == "gemini" {
+		return LoadGeminiKey(ctx)
+	}
+	path, err := TokenPath(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("credential %s not found; run 'sre-ai config login --provider %s'", name, name)
+		}
+		return "", err
+	}
+	var payload tokenCredential
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return "", fmt.Errorf("parse credential %s: %w", path, err)
+	}
+	if payload.Token == "" {
+		return "", fmt.Errorf("credential file %s missing token", path)
+	}
+	return payload.Token, nil
+}
@@ -0,0 +1,467 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/sre-ai/internal/agent"
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/providers"
+	"gopkg.in/yaml.v3"
+)
+
+// Suite is a set of cases evaluated against every target.
+type Suite struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	Targets     []Target `yaml:"targets"`
+	Cases       []Case   `yaml:"cases"`
+
+	baseDir string
+}
+
+// Target is one provider/model pairing under evaluation.
+type Target struct {
+	Provider string `yaml:"provider" json:"provider"`
+	Model    string `yaml:"model" json:"model"`
+}
+
+// Label renders the target as provider/model.
+func (t Target) Label() string {
+	return fmt.Sprintf("%s/%s", t.Provider, t.Model)
+}
+
+// Case is either a fixed prompt or a workflow fixture whose rendered output is scored.
+type Case struct {
+	Name       string            `yaml:"name"`
+	Prompt     string            `yaml:"prompt"`
+	PromptFile string            `yaml:"prompt_file"`
+	Workflow   string            `yaml:"workflow"`
+	Inputs     map[string]string `yaml:"inputs"`
+	// Output selects which workflow output to score; empty scores the last prompt step's text.
+	Output string  `yaml:"output"`
+	Expect Expect  `yaml:"expect"`
+	Rubric []Check `yaml:"rubric"`
+}
+
+// Expect holds the shorthand assertions; each becomes one weighted check.
+type Expect struct {
+	JSON        interface{} `yaml:"json"`
+	Contains    []string    `yaml:"contains"`
+	NotContains []string    `yaml:"not_contains"`
+	Regex       []string    `yaml:"regex"`
+}
+
+// Check is a single rubric item. Exactly one of the matcher fields should be set.
+type Check struct {
+	Name        string      `yaml:"name" json:"name"`
+	Weight      float64     `yaml:"weight" json:"weight"`
+	Contains    string      `yaml:"contains" json:"contains,omitempty"`
+	NotContains string      `yaml:"not_contains" json:"not_contains,omitempty"`
+	Regex       string      `yaml:"regex" json:"regex,omitempty"`
+	JSON        interface{} `yaml:"json" json:"json,omitempty"`
+	ValidJSON   bool        `yaml:"valid_json" json:"valid_json,omitempty"`
+	MaxChars    int         `yaml:"max_chars" json:"max_chars,omitempty"`
+}
+
+// CheckResult is the outcome of one rubric item.
+type CheckResult struct {
+	Name   string  `json:"name"`
+	Weight float64 `json:"weight"`
+	Passed bool    `json:"passed"`
+	Detail string  `json:"detail,omitempty"`
+}
+
+// CaseResult scores one case against one target.
+type CaseResult struct {
+	Case     string        `json:"case"`
+	Target   string        `json:"target"`
+	Score    float64       `json:"score"`
+	Checks   []CheckResult `json:"checks"`
+	Output   string        `json:"output,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// TargetSummary aggregates a target across all cases.
+type TargetSummary struct {
+	Target    string        `json:"target"`
+	MeanScore float64       `json:"mean_score"`
+	Passed    int           `json:"passed"`
+	Errors    int           `json:"errors"`
+	Latency   time.Duration `json:"mean_latency"`
+}
+
+// Report is the comparison across targets.
+type Report struct {
+	Suite   string          `json:"suite"`
+	Cases   []string        `json:"cases"`
+	Targets []TargetSummary `json:"targets"`
+	Results []CaseResult    `json:"results"`
+	Best    string          `json:"best,omitempty"`
+}
+
+// Options tune a suite run.
+type Options struct {
+	Global *config.GlobalOptions
+	// Targets, when set, replaces the suite's own target list.
+	Targets []Target
+	// Progress receives one line per finished case; nil disables it.
+	Progress func(CaseResult)
+}
+
+// LoadSuite parses a suite file.
+func LoadSuite(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var suite Suite
+	if err := yaml.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("parse suite %s: %w", path, err)
+	}
+	if len(suite.Cases) == 0 {
+		return nil, fmt.Errorf("suite %s defines no cases", path)
+	}
+	for i, c := range suite.Cases {
+		if c.Name == "" {
+			suite.Cases[i].Name = fmt.Sprintf("case_%d", i+1)
+		}
+		sources := 0
+		for _, s := range []string{c.Prompt, c.PromptFile, c.Workflow} {
+			if s != "" {
+				sources++
+			}
+		}
+		if sources != 1 {
+			return nil, fmt.Errorf("case %s must set exactly one of prompt, prompt_file, workflow", suite.Cases[i].Name)
+		}
+	}
+	if suite.Name == "" {
+		suite.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	suite.baseDir = filepath.Dir(path)
+	return &suite, nil
+}
+
+// ParseTarget accepts "provider/model" or a bare model (Gemini).
+func ParseTarget(value string) (Target, error) {
+	provider, model, err := providers.ParseTarget(value)
+	if err != nil {
+		return Target{}, err
+	}
+	return Target{Provider: provider, Model: model}, nil
+}
+
+// Run evaluates every case against every target sequentially.
+func Run(ctx context.Context, suite *Suite, opts Options) (*Report, error) {
+	targets := opts.Targets
+	if len(targets) == 0 {
+		targets = suite.Targets
+	}
+	if len(targets) == 0 {
+		return nil, errors.New("no targets; add targets to the suite or pass --target")
+	}
+	for i := range targets {
+		if targets[i].Provider == "" {
+			targets[i].Provider = "gemini"
+		}
+		if targets[i].Model == "" {
+			targets[i].Model = providers.DefaultModel(targets[i].Provider)
+		}
+	}
+
+	report := &Report{Suite: suite.Name}
+	for _, c := range suite.Cases {
+		report.Cases = append(report.Cases, c.Name)
+	}
+
+	for _, target := range targets {
+		for _, c := range suite.Cases {
+			if err := ctx.Err(); err != nil {
+				return report, err
+			}
+			result := runCase(ctx, suite, c, target, opts)
+			report.Results = append(report.Results, result)
+			if opts.Progress != nil {
+				opts.Progress(result)
+			}
+		}
+	}
+
+	report.Targets = summarize(targets, report.Results)
+	if len(report.Targets) > 0 {
+		best := report.Targets[0]
+		for _, t := range report.Targets[1:] {
+			if t.MeanScore > best.MeanScore || (t.MeanScore == best.MeanScore && t.Latency < best.Latency) {
+				best = t
+			}
+		}
+		report.Best = best.Target
+	}
+	return report, nil
+}
+
+func runCase(ctx context.Context, suite *Suite, c Case, target Target, opts Options) CaseResult {
+	result := CaseResult{Case: c.Name, Target: target.Label()}
+	start := time.Now()
+	output, err := generate(ctx, suite, c, target, opts)
+	result.Duration = time.Since(start)
+	result.Output = output
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Checks = score(c, output)
+	result.Score = weightedScore(result.Checks)
+	return result
+}
+
+func generate(ctx context.Context, suite *Suite, c Case, target Target, opts Options) (string, error) {
+	if c.Workflow != "" {
+		return runWorkflowCase(ctx, suite, c, target, opts)
+	}
+	prompt := c.Prompt
+	if c.PromptFile != "" {
+		data, err := os.ReadFile(suite.resolve(c.PromptFile))
+		if err != nil {
+			return "", err
+		}
+		prompt = string(data)
+	}
+	client, err := providers.New(ctx, target.Provider, target.Model)
+	if err != nil {
+		return "", err
+	}
+	return client.Generate(ctx, prompt)
+}
+
+func runWorkflowCase(ctx context.Context, suite *Suite, c Case, target Target, opts Options) (string, error) {
+	runner, err := agent.NewRunner(ctx, suite.resolve(c.Workflow), opts.Global, c.Inputs, nil)
+	if err != nil {
+		return "", err
+	}
+	runner.SetModel(target.Provider, target.Model)
+	res, err := runner.Execute(ctx, false)
+	if err != nil {
+		return "", err
+	}
+	if c.Output != "" {
+		value, ok := res.Outputs[c.Output]
+		if !ok {
+			return "", fmt.Errorf("workflow has no output %q", c.Output)
+		}
+		return fmt.Sprint(value), nil
+	}
+	for i := len(res.Steps) - 1; i >= 0; i-- {
+		if strings.EqualFold(res.Steps[i].Type, "prompt") {
+			if out, ok := res.Steps[i].Output.(map[string]interface{}); ok {
+				if text, ok := out["text"].(string); ok {
+					return text, nil
+				}
+			}
+		}
+	}
+	return "", errors.New("workflow produced no prompt output to score")
+}
+
+func (s *Suite) resolve(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(s.baseDir, path)
+}
+
+// checksFor expands the Expect shorthand and appends the explicit rubric.
+func checksFor(c Case) []Check {
+	var checks []Check
+	if c.Expect.JSON != nil {
+		checks = append(checks, Check{Name: "expected json", JSON: c.Expect.JSON})
+	}
+	for _, s := range c.Expect.Contains {
+		checks = append(checks, Check{Name: fmt.Sprintf("contains %q", s), Contains: s})
+	}
+	for _, s := range c.Expect.NotContains {
+		checks = append(checks, Check{Name: fmt.Sprintf("omits %q", s), NotContains: s})
+	}
+	for _, s := range c.Expect.Regex {
+		checks = append(checks, Check{Name: fmt.Sprintf("matches /%s/", s), Regex: s})
+	}
+	return append(checks, c.Rubric...)
+}
+
+func score(c Case, output string) []CheckResult {
+	checks := checksFor(c)
+	if len(checks) == 0 {
+		// Without assertions a non-empty answer is the only signal.
+		checks = []Check{{Name: "non-empty output"}}
+	}
+	results := make([]CheckResult, 0, len(checks))
+	for i, check := range checks {
+		weight := check.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		name := check.Name
+		if name == "" {
+			name = fmt.Sprintf("check_%d", i+1)
+		}
+		passed, detail := evaluate(check, output)
+		results = append(results, CheckResult{Name: name, Weight: weight, Passed: passed, Detail: detail})
+	}
+	return results
+}
+
+func evaluate(check Check, output string) (bool, string) {
+	lower := strings.ToLower(output)
+	switch {
+	case check.Contains != "":
+		return strings.Contains(lower, strings.ToLower(check.Contains)), ""
+	case check.NotContains != "":
+		return !strings.Contains(lower, strings.ToLower(check.NotContains)), ""
+	case check.Regex != "":
+		re, err := regexp.Compile(check.Regex)
+		if err != nil {
+			return false, fmt.Sprintf("invalid regex: %v", err)
+		}
+		return re.MatchString(output), ""
+	case check.JSON != nil:
+		actual, err := decodeJSON(output)
+		if err != nil {
+			return false, err.Error()
+		}
+		expected := normalize(check.JSON)
+		if path, ok := subset(expected, actual, "$"); !ok {
+			return false, fmt.Sprintf("mismatch at %s", path)
+		}
+		return true, ""
+	case check.ValidJSON:
+		if _, err := decodeJSON(output); err != nil {
+			return false, err.Error()
+		}
+		return true, ""
+	case check.MaxChars > 0:
+		if len(output) > check.MaxChars {
+			return false, fmt.Sprintf("%d chars", len(output))
+		}
+		return true, ""
+	default:
+		return strings.TrimSpace(output) != "", ""
+	}
+}
+
+func decodeJSON(output string) (interface{}, error) {
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(providers.StripCodeFence(output)), &decoded); err != nil {
+		return nil, fmt.Errorf("output is not valid JSON: %v", err)
+	}
+	return decoded, nil
+}
+
+// normalize turns YAML-decoded values into the shapes encoding/json produces.
+func normalize(value interface{}) interface{} {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return value
+	}
+	return out
+}
+
+// subset reports whether expected is contained in actual: objects may carry extra keys,
+// arrays must match element-wise in order, scalars must be equal.
+func subset(expected, actual interface{}, path string) (string, bool) {
+	switch exp := expected.(type) {
+	case map[string]interface{}:
+		act, ok := actual.(map[string]interface{})
+		if !ok {
+			return path, false
+		}
+		for key, value := range exp {
+			child, ok := act[key]
+			if !ok {
+				return path + "." + key, false
+			}
+			if p, ok := subset(value, child, path+"."+key); !ok {
+				return p, false
+			}
+		}
+		return "", true
+	case []interface{}:
+		act, ok := actual.([]interface{})
+		if !ok || len(act) < len(exp) {
+			return path, false
+		}
+		for i := range exp {
+			if p, ok := subset(exp[i], act[i], fmt.Sprintf("%s[%d]", path, i)); !ok {
+				return p, false
+			}
+		}
+		return "", true
+	case string:
+		act, ok := actual.(string)
+		return path, ok && strings.EqualFold(strings.TrimSpace(exp), strings.TrimSpace(act))
+	default:
+		return path, fmt.Sprint(expected) == fmt.Sprint(actual)
+	}
+}
+
+func weightedScore(results []CheckResult) float64 {
+	var total, passed float64
+	for _, r := range results {
+		total += r.Weight
+		if r.Passed {
+			passed += r.Weight
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return passed / total
+}
+
+func summarize(targets []Target, results []CaseResult) []TargetSummary {
+	byTarget := map[string]*TargetSummary{}
+	counts := map[string]int{}
+	var order []string
+	for _, t := range targets {
+		label := t.Label()
+		if _, ok := byTarget[label]; !ok {
+			byTarget[label] = &TargetSummary{Target: label}
+			order = append(order, label)
+		}
+	}
+	for _, r := range results {
+		summary := byTarget[r.Target]
+		counts[r.Target]++
+		summary.MeanScore += r.Score
+		summary.Latency += r.Duration
+		if r.Error != "" {
+			summary.Errors++
+		} else if r.Score >= 1 {
+			summary.Passed++
+		}
+	}
+	out := make([]TargetSummary, 0, len(order))
+	for _, label := range order {
+		summary := byTarget[label]
+		if n := counts[label]; n > 0 {
+			summary.MeanScore /= float64(n)
+			summary.Latency /= time.Duration(n)
+		}
+		out = append(out, *summary)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].MeanScore > out[j].MeanScore })
+	return out
+}
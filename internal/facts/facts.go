@@ -0,0 +1,198 @@
+package facts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/sre-ai/internal/config"
+)
+
+const (
+	factsDirName = "facts"
+	// DefaultSession holds facts when no --session is given.
+	DefaultSession = "default"
+)
+
+// Fact is one remembered value. Keys are dotted, conventionally prefixed with the
+// service they describe, e.g. "checkout.last_known_good_version".
+type Fact struct {
+	Value     interface{} `json:"value"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	// Source says what wrote the fact, e.g. "workflow lark-oncall-rca (run 20261014T...)".
+	Source string `json:"source,omitempty"`
+}
+
+// Store is the fact file of one session. Writes merge with whatever is on disk so
+// concurrent runs in the same session do not drop each other's facts.
+type Store struct {
+	Session string
+	path    string
+
+	mu    sync.Mutex
+	facts map[string]Fact
+}
+
+// Dir returns the directory holding one fact file per session.
+func Dir(ctx context.Context) (string, error) {
+	base, err := config.ConfigDir(ctx)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, factsDirName), nil
+}
+
+// Open loads the facts of a session; an empty session means DefaultSession.
+func Open(ctx context.Context, session string) (*Store, error) {
+	session = strings.TrimSpace(session)
+	if session == "" {
+		session = DefaultSession
+	}
+	if strings.ContainsAny(session, `/\`) || session == "." || session == ".." {
+		return nil, fmt.Errorf("invalid session name %q", session)
+	}
+	dir, err := Dir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{Session: session, path: filepath.Join(dir, session+".json")}
+	facts, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	s.facts = facts
+	return s, nil
+}
+
+// Get returns the value stored under key.
+func (s *Store) Get(key string) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.facts[key]
+	return f.Value, ok
+}
+
+// All returns a copy of every fact in the session.
+func (s *Store) All() map[string]Fact {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]Fact, len(s.facts))
+	for k, v := range s.facts {
+		out[k] = v
+	}
+	return out
+}
+
+// Keys returns the fact keys in sorted order.
+func (s *Store) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.facts))
+	for k := range s.facts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Set stores values (a nil value deletes its key) and persists the session.
+func (s *Store) Set(values map[string]interface{}, source string) error {
+	for key := range values {
+		if err := ValidateKey(key); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, err := s.read()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	for key, value := range values {
+		if value == nil {
+			delete(current, key)
+			continue
+		}
+		current[key] = Fact{Value: value, UpdatedAt: now, Source: source}
+	}
+	if err := s.write(current); err != nil {
+		return err
+	}
+	s.facts = current
+	return nil
+}
+
+// Delete removes keys; it reports whether any of them existed.
+func (s *Store) Delete(keys ...string) (bool, error) {
+	values := make(map[string]interface{}, len(keys))
+	found := false
+	s.mu.Lock()
+	for _, key := range keys {
+		if _, ok := s.facts[key]; ok {
+			found = true
+		}
+		values[key] = nil
+	}
+	s.mu.Unlock()
+	return found, s.Set(values, "")
+}
+
+// ValidateKey rejects keys that cannot be read back with the fact template function.
+func ValidateKey(key string) error {
+	if strings.TrimSpace(key) == "" || strings.TrimSpace(key) != key {
+		return fmt.Errorf("invalid fact key %q", key)
+	}
+	for _, part := range strings.Split(key, ".") {
+		if part == "" {
+			return fmt.Errorf("invalid fact key %q: empty segment", key)
+		}
+	}
+	return nil
+}
+
+func (s *Store) read() (map[string]Fact, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]Fact{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read facts: %w", err)
+	}
+	facts := map[string]Fact{}
+	if err := json.Unmarshal(data, &facts); err != nil {
+		return nil, fmt.Errorf("parse facts %s: %w", s.path, err)
+	}
+	return facts, nil
+}
+
+func (s *Store) write(facts map[string]Fact) error {
+	if err := config.EnsureDir(filepath.Dir(s.path)); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(facts, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".facts-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,238 @@
+package gameday
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/sre-ai/internal/agent"
+	"github.com/example/sre-ai/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Spec holds the gameday-specific section of a scenario file. Everything else in
+// the file is a regular agent workflow executed by the agent runner.
+type Spec struct {
+	Hypothesis    string    `yaml:"hypothesis"`
+	BlastRadius   string    `yaml:"blast_radius"`
+	SLOs          []SLOSpec `yaml:"slos"`
+	AbortOnBreach *bool     `yaml:"abort_on_breach"`
+}
+
+// SLOSpec describes an objective checked against a captured monitoring value.
+type SLOSpec struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	Step        string   `yaml:"step"`
+	Path        string   `yaml:"path"`
+	Max         *float64 `yaml:"max"`
+	Min         *float64 `yaml:"min"`
+}
+
+// Scenario pairs the gameday spec with the workflow path that implements it.
+type Scenario struct {
+	Path     string
+	Name     string
+	Spec     Spec
+	Workflow *agent.Workflow
+}
+
+// SLOStatus reports the observations made for a single objective.
+type SLOStatus struct {
+	Name         string    `json:"name"`
+	Objective    string    `json:"objective"`
+	Observations []float64 `json:"observations,omitempty"`
+	Breached     bool      `json:"breached"`
+	Detail       string    `json:"detail,omitempty"`
+}
+
+// Report summarises a gameday execution.
+type Report struct {
+	Scenario    string                 `json:"scenario"`
+	Hypothesis  string                 `json:"hypothesis,omitempty"`
+	BlastRadius string                 `json:"blast_radius,omitempty"`
+	PlanOnly    bool                   `json:"plan_only"`
+	StartedAt   time.Time              `json:"started_at"`
+	FinishedAt  time.Time              `json:"finished_at"`
+	Duration    time.Duration          `json:"duration"`
+	Injections  int                    `json:"injections"`
+	Aborted     bool                   `json:"aborted"`
+	AbortReason string                 `json:"abort_reason,omitempty"`
+	SLOs        []SLOStatus            `json:"slos,omitempty"`
+	Steps       []agent.StepResult     `json:"steps"`
+	Outputs     map[string]interface{} `json:"outputs,omitempty"`
+	Error       string                 `json:"error,omitempty"`
+}
+
+// Confirm asks the operator to approve a single failure injection.
+type Confirm func(stage agent.StageSpec, stepName string, step agent.StepSpec) (bool, error)
+
+// LoadScenario parses a scenario file, validating that it declares failure injections.
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc struct {
+		Name    string `yaml:"name"`
+		Gameday Spec   `yaml:"gameday"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse scenario: %w", err)
+	}
+
+	wf, _, err := agent.LoadWorkflow(path)
+	if err != nil {
+		return nil, err
+	}
+
+	injections := 0
+	for _, stage := range wf.Workflow.Stages {
+		for _, step := range stage.Steps {
+			if agent.IsHighRisk(stage, step) {
+				injections++
+			}
+		}
+	}
+	if injections == 0 {
+		return nil, fmt.Errorf("scenario %s declares no failure injection steps (mark them with risk: high)", path)
+	}
+
+	for idx, slo := range doc.Gameday.SLOs {
+		if slo.Step == "" || slo.Path == "" {
+			return nil, fmt.Errorf("slo %d (%s) requires step and path", idx+1, slo.Name)
+		}
+		if slo.Max == nil && slo.Min == nil {
+			return nil, fmt.Errorf("slo %d (%s) requires max or min", idx+1, slo.Name)
+		}
+	}
+
+	return &Scenario{Path: path, Name: doc.Name, Spec: doc.Gameday, Workflow: wf}, nil
+}
+
+// Run executes the scenario. Every high-risk step passes through confirm; once an SLO
+// is breached (and abort_on_breach is not disabled) further injections are refused
+// while non-injection steps, such as restores, still run.
+func Run(ctx context.Context, sc *Scenario, opts *config.GlobalOptions, provided map[string]string, planOnly bool, confirm Confirm, logWriter io.Writer) (*Report, error) {
+	runner, err := agent.NewRunner(ctx, sc.Path, opts, provided, logWriter)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Scenario:    sc.Name,
+		Hypothesis:  sc.Spec.Hypothesis,
+		BlastRadius: sc.Spec.BlastRadius,
+		PlanOnly:    planOnly,
+		StartedAt:   time.Now().UTC(),
+	}
+	statuses := make([]SLOStatus, len(sc.Spec.SLOs))
+	for i, slo := range sc.Spec.SLOs {
+		statuses[i] = SLOStatus{Name: sloName(slo, i), Objective: describeObjective(slo)}
+	}
+
+	abortOnBreach := sc.Spec.AbortOnBreach == nil || *sc.Spec.AbortOnBreach
+
+	runner.SetGate(func(ctx context.Context, stage agent.StageSpec, stepName string, step agent.StepSpec) (bool, error) {
+		if report.Aborted {
+			// Skipped, not blocked, so the restores and checks after it still run.
+			return false, fmt.Errorf("injection %s %w: %s", stepName, agent.ErrSkipStep, report.AbortReason)
+		}
+		if confirm != nil {
+			ok, err := confirm(stage, stepName, step)
+			if err != nil || !ok {
+				return ok, err
+			}
+		}
+		report.Injections++
+		return true, nil
+	})
+
+	runner.SetObserver(func(stage agent.StageSpec, result agent.StepResult) {
+		if result.Status != "ok" {
+			return
+		}
+		for i, slo := range sc.Spec.SLOs {
+			if slo.Step != result.StepName {
+				continue
+			}
+			observeSLO(&statuses[i], slo, runner.StepState()[slo.Step])
+			if statuses[i].Breached && abortOnBreach && !report.Aborted {
+				report.Aborted = true
+				report.AbortReason = fmt.Sprintf("slo %s breached", statuses[i].Name)
+			}
+		}
+	})
+
+	result, runErr := runner.Execute(ctx, planOnly)
+	report.FinishedAt = time.Now().UTC()
+	report.Duration = report.FinishedAt.Sub(report.StartedAt)
+	report.SLOs = statuses
+	if result != nil {
+		report.Steps = result.Steps
+		report.Outputs = result.Outputs
+	}
+	if runErr != nil {
+		report.Error = runErr.Error()
+	}
+	return report, runErr
+}
+
+func observeSLO(status *SLOStatus, slo SLOSpec, state map[string]interface{}) {
+	raw := agent.LookupValue(state, slo.Path)
+	value, ok := toFloat(raw)
+	if !ok {
+		status.Detail = fmt.Sprintf("value at %s.%s is not numeric", slo.Step, slo.Path)
+		return
+	}
+	status.Observations = append(status.Observations, value)
+	if slo.Max != nil && value > *slo.Max {
+		status.Breached = true
+		status.Detail = fmt.Sprintf("observed %g > max %g", value, *slo.Max)
+	}
+	if slo.Min != nil && value < *slo.Min {
+		status.Breached = true
+		status.Detail = fmt.Sprintf("observed %g < min %g", value, *slo.Min)
+	}
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch typed := value.(type) {
+	case float64:
+		return typed, true
+	case float32:
+		return float64(typed), true
+	case int:
+		return float64(typed), true
+	case int64:
+		return float64(typed), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(typed), 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func sloName(slo SLOSpec, idx int) string {
+	if slo.Name != "" {
+		return slo.Name
+	}
+	return fmt.Sprintf("slo_%d", idx+1)
+}
+
+func describeObjective(slo SLOSpec) string {
+	parts := make([]string, 0, 2)
+	if slo.Min != nil {
+		parts = append(parts, fmt.Sprintf(">= %g", *slo.Min))
+	}
+	if slo.Max != nil {
+		parts = append(parts, fmt.Sprintf("<= %g", *slo.Max))
+	}
+	return fmt.Sprintf("%s.%s %s", slo.Step, slo.Path, strings.Join(parts, " and "))
+}
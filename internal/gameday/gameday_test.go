@@ -0,0 +1,91 @@
+package gameday
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/example/sre-ai/internal/agent"
+	"github.com/example/sre-ai/internal/config"
+)
+
+const breachScenario = `version: 0.1
+name: breach-then-restore
+gameday:
+  hypothesis: the error rate stays under 1%
+  slos:
+    - name: error-rate
+      step: observe
+      path: metrics.error_rate
+      max: 0.01
+tools:
+  metrics:
+    kind: sample
+    sample_data:
+      error_rate: 0.5
+  chaos:
+    kind: sample
+    sample_data:
+      action: pod-kill
+workflow:
+  stages:
+    - id: observe
+      steps:
+        - name: observe
+          type: tool
+          tool: metrics
+          capture:
+            metrics: data
+    - id: inject
+      risk: high
+      steps:
+        - name: kill_pod
+          type: tool
+          tool: chaos
+    - id: restore
+      steps:
+        - name: restore
+          type: tool
+          tool: chaos
+        - name: verify
+          type: tool
+          tool: metrics
+`
+
+func TestRunSkipsInjectionsAfterBreachButRunsRestores(t *testing.T) {
+	ctx := config.WithDir(context.Background(), t.TempDir())
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	if err := os.WriteFile(path, []byte(breachScenario), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sc, err := LoadScenario(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	confirmed := 0
+	confirm := func(agent.StageSpec, string, agent.StepSpec) (bool, error) {
+		confirmed++
+		return true, nil
+	}
+	report, err := Run(ctx, sc, &config.GlobalOptions{}, nil, false, confirm, io.Discard)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !report.Aborted || report.Injections != 0 || confirmed != 0 {
+		t.Fatalf("aborted=%v injections=%d confirmed=%d, want the injection refused without asking", report.Aborted, report.Injections, confirmed)
+	}
+
+	statuses := map[string]string{}
+	for _, step := range report.Steps {
+		statuses[step.StepName] = step.Status
+	}
+	want := map[string]string{"observe": "ok", "kill_pod": "skipped", "restore": "ok", "verify": "ok"}
+	for name, status := range want {
+		if statuses[name] != status {
+			t.Errorf("step %s status = %q, want %q (all: %v)", name, statuses[name], status, statuses)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package gitlog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	recordSep = "\x1e"
+	fieldSep  = "\x1f"
+	logFormat = recordSep + "%H" + fieldSep + "%h" + fieldSep + "%an" + fieldSep + "%ae" + fieldSep + "%aI" + fieldSep + "%P" + fieldSep + "%s"
+
+	// DefaultLimit bounds how many commits a query returns when Limit is unset.
+	DefaultLimit = 50
+)
+
+// FileChange is one file touched by a commit.
+type FileChange struct {
+	Path       string `json:"path"`
+	Insertions int    `json:"insertions"`
+	Deletions  int    `json:"deletions"`
+	Binary     bool   `json:"binary,omitempty"`
+}
+
+// Commit is a parsed git log entry.
+type Commit struct {
+	SHA        string       `json:"sha"`
+	Short      string       `json:"short"`
+	Author     string       `json:"author"`
+	Email      string       `json:"email,omitempty"`
+	Date       time.Time    `json:"date"`
+	Subject    string       `json:"subject"`
+	Body       string       `json:"body,omitempty"`
+	Parents    []string     `json:"parents,omitempty"`
+	Merge      bool         `json:"merge"`
+	Files      []FileChange `json:"files,omitempty"`
+	Insertions int          `json:"insertions"`
+	Deletions  int          `json:"deletions"`
+}
+
+// Query selects commits from a repository.
+type Query struct {
+	Dir   string
+	Ref   string
+	Paths []string
+	Since time.Time
+	Until time.Time
+	Limit int
+	// CPU, when set, gets the user and system time, in nanoseconds, of the git process.
+	CPU *atomic.Int64
+}
+
+// Summary aggregates a set of commits for use as incident evidence.
+type Summary struct {
+	Commits  int            `json:"commits"`
+	Merges   int            `json:"merges"`
+	Authors  []string       `json:"authors"`
+	TopPaths []PathActivity `json:"top_paths"`
+	First    *time.Time     `json:"first,omitempty"`
+	Last     *time.Time     `json:"last,omitempty"`
+}
+
+// PathActivity counts how many commits touched a path.
+type PathActivity struct {
+	Path    string `json:"path"`
+	Commits int    `json:"commits"`
+}
+
+// Log runs git log for the query, newest first.
+func Log(ctx context.Context, q Query) ([]Commit, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	args := []string{"log", "--no-color", "--numstat", "--format=" + logFormat, fmt.Sprintf("--max-count=%d", limit)}
+	if !q.Since.IsZero() {
+		args = append(args, "--since="+q.Since.Format(time.RFC3339))
+	}
+	if !q.Until.IsZero() {
+		args = append(args, "--until="+q.Until.Format(time.RFC3339))
+	}
+	if q.Ref != "" {
+		if err := checkRev(q.Ref); err != nil {
+			return nil, err
+		}
+		args = append(args, q.Ref)
+	}
+	args = append(args, "--")
+	args = append(args, q.Paths...)
+
+	out, err := run(ctx, q.CPU, q.Dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseLog(out)
+}
+
+// Show returns a single commit including its message body.
+func Show(ctx context.Context, dir, rev string) (*Commit, error) {
+	if strings.TrimSpace(rev) == "" {
+		return nil, errors.New("commit required")
+	}
+	if err := checkRev(rev); err != nil {
+		return nil, err
+	}
+	out, err := run(ctx, nil, dir, "show", "--no-color", "--numstat", "--format="+logFormat+fieldSep+"%b", rev)
+	if err != nil {
+		return nil, err
+	}
+	commits, err := parseLog(out)
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("commit %s not found", rev)
+	}
+	return &commits[0], nil
+}
+
+// Summarize aggregates commits into authors, merge counts, and the most active paths.
+func Summarize(commits []Commit, topN int) Summary {
+	summary := Summary{Commits: len(commits), Authors: []string{}, TopPaths: []PathActivity{}}
+	authors := map[string]bool{}
+	paths := map[string]int{}
+	for i := range commits {
+		c := commits[i]
+		if c.Merge {
+			summary.Merges++
+		}
+		if !authors[c.Author] {
+			authors[c.Author] = true
+			summary.Authors = append(summary.Authors, c.Author)
+		}
+		for _, f := range c.Files {
+			paths[f.Path]++
+		}
+		if summary.Last == nil || c.Date.After(*summary.Last) {
+			summary.Last = &commits[i].Date
+		}
+		if summary.First == nil || c.Date.Before(*summary.First) {
+			summary.First = &commits[i].Date
+		}
+	}
+	sort.Strings(summary.Authors)
+	for path, count := range paths {
+		summary.TopPaths = append(summary.TopPaths, PathActivity{Path: path, Commits: count})
+	}
+	sort.Slice(summary.TopPaths, func(i, j int) bool {
+		if summary.TopPaths[i].Commits != summary.TopPaths[j].Commits {
+			return summary.TopPaths[i].Commits > summary.TopPaths[j].Commits
+		}
+		return summary.TopPaths[i].Path < summary.TopPaths[j].Path
+	})
+	if topN > 0 && len(summary.TopPaths) > topN {
+		summary.TopPaths = summary.TopPaths[:topN]
+	}
+	return summary
+}
+
+// Head returns the commit checked out in the repository at dir and whether tracked
+// files have uncommitted changes.
+func Head(ctx context.Context, dir string) (sha string, dirty bool, err error) {
+	out, err := run(ctx, nil, dir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", false, err
+	}
+	status, err := run(ctx, nil, dir, "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return "", false, err
+	}
+	return strings.TrimSpace(out), strings.TrimSpace(status) != "", nil
+}
+
+// checkRev rejects a revision git would parse as an option, such as --output=<file>.
+func checkRev(rev string) error {
+	if strings.HasPrefix(strings.TrimSpace(rev), "-") {
+		return fmt.Errorf("invalid revision %q: must not start with -", rev)
+	}
+	return nil
+}
+
+func run(ctx context.Context, cpu *atomic.Int64, dir string, args ...string) (string, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return "", errors.New("git not found in PATH")
+	}
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if cpu != nil && cmd.ProcessState != nil {
+		cpu.Add(int64(cmd.ProcessState.UserTime() + cmd.ProcessState.SystemTime()))
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
+
+func parseLog(out string) ([]Commit, error) {
+	var commits []Commit
+	for _, record := range strings.Split(out, recordSep) {
+		if strings.TrimSpace(record) == "" {
+			continue
+		}
+		header, rest, _ := strings.Cut(record, "\n")
+		fields := strings.Split(header, fieldSep)
+		if len(fields) < 7 {
+			return nil, fmt.Errorf("unexpected git log record %q", header)
+		}
+		commit := Commit{
+			SHA:     fields[0],
+			Short:   fields[1],
+			Author:  fields[2],
+			Email:   fields[3],
+			Subject: fields[6],
+		}
+		if date, err := time.Parse(time.RFC3339, fields[4]); err == nil {
+			commit.Date = date
+		}
+		if parents := strings.Fields(fields[5]); len(parents) > 0 {
+			commit.Parents = parents
+			commit.Merge = len(parents) > 1
+		}
+
+		// With a body placeholder the body runs until the first numstat line.
+		lines := strings.Split(rest, "\n")
+		if len(fields) > 7 {
+			body := []string{fields[7]}
+			for len(lines) > 0 && !isNumstat(lines[0]) {
+				body = append(body, lines[0])
+				lines = lines[1:]
+			}
+			commit.Body = strings.TrimSpace(strings.Join(body, "\n"))
+		}
+		for _, line := range lines {
+			if change, ok := parseNumstat(line); ok {
+				commit.Files = append(commit.Files, change)
+				commit.Insertions += change.Insertions
+				commit.Deletions += change.Deletions
+			}
+		}
+		commits = append(commits, commit)
+	}
+	return commits, nil
+}
+
+func isNumstat(line string) bool {
+	_, ok := parseNumstat(line)
+	return ok
+}
+
+func parseNumstat(line string) (FileChange, bool) {
+	parts := strings.SplitN(line, "\t", 3)
+	if len(parts) != 3 {
+		return FileChange{}, false
+	}
+	if parts[0] == "-" && parts[1] == "-" {
+		return FileChange{Path: parts[2], Binary: true}, true
+	}
+	ins, err1 := strconv.Atoi(parts[0])
+	del, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return FileChange{}, false
+	}
+	return FileChange{Path: parts[2], Insertions: ins, Deletions: del}, true
+}
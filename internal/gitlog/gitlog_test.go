@@ -0,0 +1,39 @@
+package gitlog
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestRevisionsStartingWithADashAreRejected(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found in PATH")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "initial"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	ctx := context.Background()
+	if _, err := Show(ctx, dir, "HEAD"); err != nil {
+		t.Fatalf("Show(HEAD): %v", err)
+	}
+
+	written := filepath.Join(t.TempDir(), "written")
+	if _, err := Show(ctx, dir, "--output="+written); err == nil {
+		t.Error("Show accepted a revision starting with -")
+	}
+	if _, err := Log(ctx, Query{Dir: dir, Ref: "--output=" + written}); err == nil {
+		t.Error("Log accepted a ref starting with -")
+	}
+	if _, err := os.Stat(written); err == nil {
+		t.Errorf("git wrote %s", written)
+	}
+}
@@ -0,0 +1,239 @@
+// Package httpx is the HTTP layer every outgoing request goes through. Hosts matched by
+// an entry of the config file's endpoints block get its client certificate and are
+// reached through its SSH tunnel; everything else uses a plain transport.
+package httpx
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/sre-ai/internal/config"
+)
+
+// Endpoint is a named endpoints entry with its transport, built on first use.
+type Endpoint struct {
+	Name string
+	config.EndpointOptions
+
+	once      sync.Once
+	transport *http.Transport
+	err       error
+	tunnel    *tunnel
+}
+
+// plain serves hosts no endpoint matches.
+var plain = http.DefaultTransport.(*http.Transport).Clone()
+
+// Endpoints is the endpoints block of one command tree, with the tunnels its entries
+// open. A nil *Endpoints has no entries.
+type Endpoints struct {
+	list []*Endpoint
+}
+
+// NewEndpoints builds the endpoints from config. Certificates and keys are read, and
+// tunnels opened, when first needed.
+func NewEndpoints(values map[string]config.EndpointOptions) (*Endpoints, error) {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	next := make([]*Endpoint, 0, len(names))
+	for _, name := range names {
+		opts := values[name]
+		if len(opts.Match) == 0 && (opts.SSH == nil || opts.SSH.Target == "") {
+			return nil, fmt.Errorf("endpoint %s: match is required", name)
+		}
+		for _, pattern := range opts.Match {
+			if _, err := path.Match(strings.ToLower(pattern), ""); err != nil {
+				return nil, fmt.Errorf("endpoint %s: match %q: %w", name, pattern, err)
+			}
+		}
+		ep := &Endpoint{Name: name, EndpointOptions: opts}
+		if opts.SSH != nil {
+			if strings.TrimSpace(opts.SSH.Bastion) == "" {
+				return nil, fmt.Errorf("endpoint %s: ssh.bastion is required", name)
+			}
+			ep.tunnel = &tunnel{endpoint: name, opts: *opts.SSH}
+		}
+		next = append(next, ep)
+	}
+	return &Endpoints{list: next}, nil
+}
+
+type contextKey struct{}
+
+// WithEndpoints returns a context whose requests are routed through e.
+func WithEndpoints(ctx context.Context, e *Endpoints) context.Context {
+	return context.WithValue(ctx, contextKey{}, e)
+}
+
+// FromContext returns the endpoints set with WithEndpoints, or nil.
+func FromContext(ctx context.Context) *Endpoints {
+	e, _ := ctx.Value(contextKey{}).(*Endpoints)
+	return e
+}
+
+// List returns the endpoints in name order.
+func (e *Endpoints) List() []*Endpoint {
+	if e == nil {
+		return nil
+	}
+	return append([]*Endpoint(nil), e.list...)
+}
+
+// Lookup returns the endpoint with the given name.
+func (e *Endpoints) Lookup(name string) (*Endpoint, bool) {
+	for _, ep := range e.List() {
+		if ep.Name == name {
+			return ep, true
+		}
+	}
+	return nil, false
+}
+
+// Match returns the first endpoint, in name order, any of whose patterns matches host,
+// given as host or host:port. A pattern without a port matches every port.
+func (e *Endpoints) Match(host string) (*Endpoint, bool) {
+	host = strings.ToLower(host)
+	bare := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		bare = h
+	}
+	for _, ep := range e.List() {
+		for _, pattern := range ep.Match {
+			pattern = strings.ToLower(strings.TrimSpace(pattern))
+			subject := bare
+			if _, _, err := net.SplitHostPort(pattern); err == nil {
+				subject = host
+			}
+			if ok, _ := path.Match(pattern, subject); ok {
+				return ep, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Client returns an HTTP client that routes each request through the endpoints carried
+// by its context.
+func Client(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: Transport{}}
+}
+
+// Transport is an http.RoundTripper that picks the endpoint by request host, among the
+// endpoints carried by the request's context.
+type Transport struct{}
+
+// RoundTrip sends req through the transport of the endpoint matching its host.
+func (Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ep, ok := FromContext(req.Context()).Match(canonicalHost(req))
+	if !ok {
+		return plain.RoundTrip(req)
+	}
+	transport, err := ep.Transport()
+	if err != nil {
+		return nil, err
+	}
+	return transport.RoundTrip(req)
+}
+
+// canonicalHost is the request's host:port, with the scheme's default port filled in.
+func canonicalHost(req *http.Request) string {
+	host := req.URL.Host
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	port := "80"
+	if req.URL.Scheme == "https" {
+		port = "443"
+	}
+	return net.JoinHostPort(strings.Trim(host, "[]"), port)
+}
+
+// Transport builds the endpoint's transport on first use.
+func (ep *Endpoint) Transport() (*http.Transport, error) {
+	ep.once.Do(func() {
+		transport := plain.Clone()
+		if ep.TLS != nil {
+			tlsConfig, err := ep.tlsConfig()
+			if err != nil {
+				ep.err = fmt.Errorf("endpoint %s: %w", ep.Name, err)
+				return
+			}
+			transport.TLSClientConfig = tlsConfig
+		}
+		if ep.tunnel != nil {
+			transport.Proxy = nil
+			transport.DialContext = ep.tunnel.dial
+		}
+		ep.transport = transport
+	})
+	return ep.transport, ep.err
+}
+
+func (ep *Endpoint) tlsConfig() (*tls.Config, error) {
+	opts := ep.TLS
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: opts.ServerName}
+	if opts.CA != "" {
+		pem, err := os.ReadFile(expandHome(opts.CA))
+		if err != nil {
+			return nil, fmt.Errorf("tls.ca: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls.ca: no certificates in %s", opts.CA)
+		}
+		cfg.RootCAs = pool
+	}
+	switch {
+	case opts.Cert != "" && opts.Key != "":
+		cert, err := tls.LoadX509KeyPair(expandHome(opts.Cert), expandHome(opts.Key))
+		if err != nil {
+			return nil, fmt.Errorf("tls client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	case opts.Cert != "" || opts.Key != "":
+		return nil, errors.New("tls.cert and tls.key go together")
+	}
+	return cfg, nil
+}
+
+// Forward opens the endpoint's listening port forward, if not open yet, and returns its
+// local address. It stays open until Close.
+func (ep *Endpoint) Forward(ctx context.Context) (string, error) {
+	if ep.tunnel == nil {
+		return "", fmt.Errorf("endpoint %s has no ssh tunnel", ep.Name)
+	}
+	return ep.tunnel.forward(ctx)
+}
+
+// Close shuts every open tunnel and port forward of e.
+func (e *Endpoints) Close() {
+	for _, ep := range e.List() {
+		if ep.tunnel != nil {
+			ep.tunnel.close()
+		}
+	}
+}
+
+func expandHome(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, strings.TrimPrefix(p, "~"))
+		}
+	}
+	return p
+}
@@ -0,0 +1,148 @@
+package httpx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/remote"
+	"golang.org/x/crypto/ssh"
+)
+
+// tunnel is an SSH connection to a bastion, opened on first use and reopened after it
+// drops. Connections through it are local port forwards (direct-tcpip channels).
+type tunnel struct {
+	endpoint string
+	opts     config.EndpointSSHOptions
+
+	mu       sync.Mutex
+	client   *ssh.Client
+	listener net.Listener
+}
+
+// dial connects to addr as seen from the bastion.
+func (t *tunnel) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	client, err := t.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := client.Dial(network, addr)
+	if err != nil {
+		// The connection may have dropped; retry once on a fresh one.
+		t.reset(client)
+		if client, err = t.connect(ctx); err != nil {
+			return nil, err
+		}
+		if conn, err = client.Dial(network, addr); err != nil {
+			return nil, fmt.Errorf("endpoint %s: forward to %s: %w", t.endpoint, addr, err)
+		}
+	}
+	return conn, nil
+}
+
+func (t *tunnel) connect(ctx context.Context) (*ssh.Client, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.client != nil {
+		return t.client, nil
+	}
+	client, err := t.open(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("endpoint %s: ssh %s: %w", t.endpoint, t.opts.Bastion, err)
+	}
+	t.client = client
+	go func() {
+		client.Wait()
+		t.reset(client)
+	}()
+	return client, nil
+}
+
+func (t *tunnel) reset(client *ssh.Client) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.client == client {
+		t.client = nil
+		client.Close()
+	}
+}
+
+func (t *tunnel) open(ctx context.Context) (*ssh.Client, error) {
+	return remote.DialSSH(ctx, t.opts.Bastion, remote.SSHAuth{IdentityFile: t.opts.IdentityFile, KnownHosts: t.opts.KnownHosts})
+}
+
+// forward listens on the local address and forwards each connection to the target.
+func (t *tunnel) forward(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	if t.listener != nil {
+		addr := t.listener.Addr().String()
+		t.mu.Unlock()
+		return addr, nil
+	}
+	t.mu.Unlock()
+	if strings.TrimSpace(t.opts.Target) == "" {
+		return "", fmt.Errorf("endpoint %s: ssh.target is required for a port forward", t.endpoint)
+	}
+	// Connect before listening so a bad bastion fails here, not in the process using it.
+	if _, err := t.connect(ctx); err != nil {
+		return "", err
+	}
+	local := t.opts.Local
+	if local == "" {
+		local = "127.0.0.1:0"
+	}
+	listener, err := net.Listen("tcp", local)
+	if err != nil {
+		return "", fmt.Errorf("endpoint %s: listen %s: %w", t.endpoint, local, err)
+	}
+
+	t.mu.Lock()
+	if t.listener != nil {
+		t.mu.Unlock()
+		listener.Close()
+		return t.forward(ctx)
+	}
+	t.listener = listener
+	t.mu.Unlock()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go t.pipe(conn)
+		}
+	}()
+	return listener.Addr().String(), nil
+}
+
+func (t *tunnel) pipe(local net.Conn) {
+	defer local.Close()
+	remote, err := t.dial(context.Background(), "tcp", t.opts.Target)
+	if err != nil {
+		return
+	}
+	defer remote.Close()
+	done := make(chan struct{}, 2)
+	go func() { io.Copy(remote, local); done <- struct{}{} }()
+	go func() { io.Copy(local, remote); done <- struct{}{} }()
+	<-done
+}
+
+func (t *tunnel) close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.listener != nil {
+		t.listener.Close()
+		t.listener = nil
+	}
+	if t.client != nil {
+		t.client.Close()
+		t.client = nil
+	}
+}
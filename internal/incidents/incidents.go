@@ -0,0 +1,182 @@
+package incidents
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/providers"
+	"github.com/example/sre-ai/internal/runs"
+)
+
+const (
+	knowledgeDirName = "knowledge"
+	// maxKnowledgeBytes skips knowledge files too large to be a single incident write-up.
+	maxKnowledgeBytes = 256 << 10
+	// DefaultMinScore is the cosine similarity below which matches are dropped.
+	DefaultMinScore = 0.3
+)
+
+// Incident is a past diagnosis or knowledge note that can be matched against new findings.
+type Incident struct {
+	// Source is "run" or "knowledge".
+	Source     string    `json:"source"`
+	ID         string    `json:"id"`
+	Title      string    `json:"title"`
+	Text       string    `json:"-"`
+	Resolution string    `json:"resolution,omitempty"`
+	When       time.Time `json:"when,omitempty"`
+}
+
+// Match is an incident with its similarity to the query.
+type Match struct {
+	Incident
+	Score float64 `json:"score"`
+}
+
+// KnowledgeDir returns the directory holding incident notes and runbooks.
+func KnowledgeDir(ctx context.Context) (string, error) {
+	base, err := config.ConfigDir(ctx)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, knowledgeDirName), nil
+}
+
+// resolutionHeading matches the section of a note that says what fixed the incident.
+var resolutionHeading = regexp.MustCompile(`(?i)^#+\s*(resolution|fix|fixed by|remediation|what fixed it)\b`)
+
+// LoadKnowledge reads .md and .txt notes under dir. A missing directory yields no incidents.
+func LoadKnowledge(dir string) ([]Incident, error) {
+	var out []Incident
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".md" && ext != ".txt" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > maxKnowledgeBytes {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		out = append(out, parseNote(filepath.ToSlash(rel), string(data), info.ModTime()))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read knowledge: %w", err)
+	}
+	return out, nil
+}
+
+func parseNote(id, text string, modified time.Time) Incident {
+	inc := Incident{Source: "knowledge", ID: id, Text: text, When: modified.UTC()}
+	var resolution []string
+	inResolution := false
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if inc.Title == "" && strings.HasPrefix(trimmed, "# ") {
+			inc.Title = strings.TrimSpace(trimmed[2:])
+			continue
+		}
+		if strings.HasPrefix(trimmed, "#") {
+			inResolution = resolutionHeading.MatchString(trimmed)
+			continue
+		}
+		if inResolution && trimmed != "" {
+			resolution = append(resolution, strings.TrimLeft(trimmed, "-* "))
+		}
+	}
+	if inc.Title == "" {
+		inc.Title = strings.TrimSuffix(filepath.Base(id), filepath.Ext(id))
+	}
+	inc.Resolution = strings.Join(resolution, "; ")
+	return inc
+}
+
+// FromRuns turns recorded diagnoses, and any run with a resolution note, into incidents.
+func FromRuns(records []*runs.Record) []Incident {
+	var out []Incident
+	for _, rec := range records {
+		if rec.Kind != "diagnose" && rec.Resolution == "" {
+			continue
+		}
+		if len(rec.Findings) == 0 && rec.Summary == "" && rec.Resolution == "" {
+			continue
+		}
+		// The first finding says more about what went wrong than the scope-only summary.
+		title := rec.Summary
+		if len(rec.Findings) > 0 {
+			title = rec.Findings[0]
+		}
+		if title == "" {
+			title = fmt.Sprintf("%s %s", rec.Kind, rec.Workflow)
+		}
+		text := strings.Join(append([]string{rec.Summary}, rec.Findings...), "\n")
+		out = append(out, Incident{
+			Source:     "run",
+			ID:         rec.ID,
+			Title:      title,
+			Text:       text,
+			Resolution: rec.Resolution,
+			When:       rec.StartedAt,
+		})
+	}
+	return out
+}
+
+// FindSimilar embeds the query and each candidate and returns up to limit matches scoring
+// at least minScore, best first. Resolved incidents win ties since they say what fixed it.
+func FindSimilar(ctx context.Context, emb providers.Embedder, query string, candidates []Incident, limit int, minScore float64) ([]Match, error) {
+	if limit <= 0 || len(candidates) == 0 || strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+	queryVec, err := emb.Embed(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("embed findings: %w", err)
+	}
+	var matches []Match
+	for _, inc := range candidates {
+		vec, err := emb.Embed(ctx, inc.Title+"\n"+inc.Text)
+		if err != nil {
+			return nil, fmt.Errorf("embed %s %s: %w", inc.Source, inc.ID, err)
+		}
+		score := providers.Cosine(queryVec, vec)
+		if score < minScore {
+			continue
+		}
+		matches = append(matches, Match{Incident: inc, Score: score})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if diff := a.Score - b.Score; diff > 0.01 || diff < -0.01 {
+			return diff > 0
+		}
+		if (a.Resolution != "") != (b.Resolution != "") {
+			return a.Resolution != ""
+		}
+		return a.When.After(b.When)
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
@@ -0,0 +1,203 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Permission is one verb on one resource that a collection needs. An empty Namespace
+// means cluster-wide: a cluster-scoped resource, or a namespaced one across namespaces.
+type Permission struct {
+	Verb        string `json:"verb"`
+	Group       string `json:"group,omitempty"`
+	Resource    string `json:"resource"`
+	Subresource string `json:"subresource,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+	// Need says what the permission is for, e.g. "pods in payments".
+	Need string `json:"need"`
+	// Optional permissions have a fallback; missing them degrades the collection.
+	Optional bool `json:"optional,omitempty"`
+}
+
+// String reads like kubectl auth can-i: "list pods -n payments".
+func (p Permission) String() string {
+	resource := p.Resource
+	if p.Subresource != "" {
+		resource += "/" + p.Subresource
+	}
+	if p.Group != "" {
+		resource += "." + p.Group
+	}
+	if p.Namespace == "" {
+		return fmt.Sprintf("%s %s (cluster-wide)", p.Verb, resource)
+	}
+	return fmt.Sprintf("%s %s -n %s", p.Verb, resource, p.Namespace)
+}
+
+// AccessCheck is the API server's answer for one permission.
+type AccessCheck struct {
+	Permission
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// DiagnosisPermissions lists what diagnose k8s reads: pods and events in each namespace,
+// namespaces when a selector picks them, and for a node the node, its events and pods
+// across namespaces, and the node logs API (ssh is the fallback for that one).
+func DiagnosisPermissions(namespaces []string, selector, node string) []Permission {
+	var perms []Permission
+	if selector != "" {
+		perms = append(perms, Permission{Verb: "list", Resource: "namespaces", Need: "namespaces matching " + selector})
+	}
+	for _, ns := range namespaces {
+		perms = append(perms, namespacePermissions(ns)...)
+	}
+	if node != "" {
+		perms = append(perms, nodePermissions(node)...)
+	}
+	return perms
+}
+
+// CheckAccess asks the API server, with one SelfSubjectAccessReview per permission, which
+// of them the current credentials have. The reviews are created in a single kubectl call.
+func (c Client) CheckAccess(ctx context.Context, perms []Permission) ([]AccessCheck, error) {
+	if len(perms) == 0 {
+		return nil, nil
+	}
+	items := make([]map[string]interface{}, 0, len(perms))
+	for _, p := range perms {
+		attrs := map[string]interface{}{"verb": p.Verb, "group": p.Group, "resource": p.Resource}
+		if p.Subresource != "" {
+			attrs["subresource"] = p.Subresource
+		}
+		if p.Namespace != "" {
+			attrs["namespace"] = p.Namespace
+		}
+		items = append(items, map[string]interface{}{
+			"apiVersion": "authorization.k8s.io/v1",
+			"kind":       "SelfSubjectAccessReview",
+			"spec":       map[string]interface{}{"resourceAttributes": attrs},
+		})
+	}
+	body, err := json.Marshal(map[string]interface{}{"apiVersion": "v1", "kind": "List", "items": items})
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.runInput(ctx, bytes.NewReader(body), "create", "-f", "-", "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("access review: %w", err)
+	}
+
+	type review struct {
+		Kind   string `json:"kind"`
+		Status struct {
+			Allowed bool   `json:"allowed"`
+			Denied  bool   `json:"denied"`
+			Reason  string `json:"reason"`
+		} `json:"status"`
+	}
+	var list struct {
+		review
+		Items []review `json:"items"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("decode access review: %w", err)
+	}
+	// kubectl prints a single created object rather than a List of one.
+	reviews := list.Items
+	if list.Kind != "List" {
+		reviews = []review{list.review}
+	}
+	if len(reviews) != len(perms) {
+		return nil, fmt.Errorf("access review: %d answers for %d permissions", len(reviews), len(perms))
+	}
+	checks := make([]AccessCheck, len(perms))
+	for i, p := range perms {
+		checks[i] = AccessCheck{Permission: p, Allowed: reviews[i].Status.Allowed && !reviews[i].Status.Denied, Reason: reviews[i].Status.Reason}
+	}
+	return checks, nil
+}
+
+// AccessError reports required permissions the credentials lack.
+type AccessError struct {
+	Context string
+	Missing []AccessCheck
+}
+
+func (e *AccessError) Error() string {
+	var b strings.Builder
+	target := "the current context"
+	if e.Context != "" {
+		target = "context " + e.Context
+	}
+	fmt.Fprintf(&b, "missing Kubernetes permissions in %s:", target)
+	for _, check := range e.Missing {
+		fmt.Fprintf(&b, "\n  - %s: needed for %s", check.Permission, check.Need)
+	}
+	b.WriteString("\n\ngrant them with these RBAC rules:\n")
+	b.WriteString(RBACRules(e.Missing))
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// Preflight checks perms and returns an *AccessError when a required one is denied. Denied
+// optional permissions are returned as degraded for the caller to warn about.
+func (c Client) Preflight(ctx context.Context, perms []Permission) (degraded []AccessCheck, err error) {
+	checks, err := c.CheckAccess(ctx, perms)
+	if err != nil {
+		return nil, err
+	}
+	var missing []AccessCheck
+	for _, check := range checks {
+		switch {
+		case check.Allowed:
+		case check.Optional:
+			degraded = append(degraded, check)
+		default:
+			missing = append(missing, check)
+		}
+	}
+	if len(missing) > 0 {
+		return degraded, &AccessError{Context: c.Context, Missing: missing}
+	}
+	return degraded, nil
+}
+
+// RBACRules renders the rules that grant the checks' permissions: a Role per namespace
+// and a ClusterRole for cluster-wide ones, as YAML rule lists.
+func RBACRules(checks []AccessCheck) string {
+	perms := make([]Permission, len(checks))
+	for i, check := range checks {
+		perms[i] = check.Permission
+	}
+	scopes := ruleScopes(perms)
+	var b strings.Builder
+	for _, ns := range sortedScopes(scopes) {
+		if ns == "" {
+			b.WriteString("# ClusterRole\n")
+		} else {
+			fmt.Fprintf(&b, "# Role in namespace %s\n", ns)
+		}
+		writeRules(&b, scopes[ns])
+	}
+	return b.String()
+}
+
+func quoteList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = fmt.Sprintf("%q", v)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,244 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxParallel bounds concurrent kubectl queries across namespaces.
+	maxParallel = 6
+	// maxListed caps the problem pods and events kept per namespace.
+	maxListed = 20
+)
+
+// NamespaceReport is the evidence and findings for one namespace.
+type NamespaceReport struct {
+	Namespace string         `json:"namespace"`
+	Pods      PodSummary     `json:"pods"`
+	Problems  []PodProblem   `json:"problems,omitempty"`
+	Warnings  []EventSummary `json:"warnings,omitempty"`
+	Findings  []string       `json:"findings"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// ReasonCount tallies one failure reason across namespaces.
+type ReasonCount struct {
+	Reason     string   `json:"reason"`
+	Count      int      `json:"count"`
+	Namespaces []string `json:"namespaces"`
+}
+
+// Rollup summarises namespace reports into cross-namespace findings.
+type Rollup struct {
+	Namespaces []string      `json:"namespaces"`
+	Failed     []string      `json:"failed,omitempty"`
+	Pods       PodSummary    `json:"pods"`
+	Reasons    []ReasonCount `json:"reasons,omitempty"`
+	Findings   []string      `json:"findings"`
+}
+
+// CollectNamespace gathers pods and warning events for one namespace. Collection errors
+// are recorded on the report rather than returned, so one bad namespace does not hide the rest.
+func (c Client) CollectNamespace(ctx context.Context, namespace string, since time.Duration) NamespaceReport {
+	report := NamespaceReport{Namespace: namespace}
+
+	var pods podList
+	if err := c.getJSON(ctx, &pods, "get", "pods", "-n", namespace); err != nil {
+		report.Error = err.Error()
+		report.Findings = []string{fmt.Sprintf("collection failed: %v", err)}
+		return report
+	}
+	report.Pods, report.Problems = summarizePods(pods.Items)
+
+	var events eventList
+	if err := c.getJSON(ctx, &events, "get", "events", "-n", namespace); err != nil {
+		report.Error = err.Error()
+	} else {
+		var cutoff time.Time
+		if since > 0 {
+			cutoff = time.Now().Add(-since)
+		}
+		report.Warnings = summarizeWarnings(events.Items, cutoff)
+	}
+
+	report.Findings = namespaceFindings(report)
+	if len(report.Problems) > maxListed {
+		report.Problems = report.Problems[:maxListed]
+	}
+	if len(report.Warnings) > maxListed {
+		report.Warnings = report.Warnings[:maxListed]
+	}
+	return report
+}
+
+// CollectNamespaces runs CollectNamespace concurrently and returns reports in input order.
+func (c Client) CollectNamespaces(ctx context.Context, namespaces []string, since time.Duration) []NamespaceReport {
+	reports := make([]NamespaceReport, len(namespaces))
+	sem := make(chan struct{}, maxParallel)
+	var wg sync.WaitGroup
+	for i, ns := range namespaces {
+		wg.Add(1)
+		go func(i int, ns string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			reports[i] = c.CollectNamespace(ctx, ns, since)
+		}(i, ns)
+	}
+	wg.Wait()
+	return reports
+}
+
+func namespaceFindings(report NamespaceReport) []string {
+	var findings []string
+	if report.Pods.Total == 0 {
+		return []string{"no pods found"}
+	}
+	if report.Pods.Pending > 0 {
+		findings = append(findings, fmt.Sprintf("%d pod(s) pending", report.Pods.Pending))
+	}
+	if report.Pods.Failed > 0 {
+		findings = append(findings, fmt.Sprintf("%d pod(s) failed", report.Pods.Failed))
+	}
+	groups := problemsByReason(report.Problems)
+	for _, reason := range sortedReasons(groups) {
+		pods := groups[reason]
+		findings = append(findings, fmt.Sprintf("%d pod(s) in %s, e.g. %s (%d restarts)", len(pods), reason, pods[0].Pod, pods[0].Restarts))
+	}
+	for i, ev := range report.Warnings {
+		if i == 3 {
+			break
+		}
+		findings = append(findings, fmt.Sprintf("warning %s on %s x%d: %s", ev.Reason, ev.Object, ev.Count, truncate(ev.Message, 160)))
+	}
+	if len(findings) == 0 {
+		findings = append(findings, fmt.Sprintf("all %d pod(s) healthy", report.Pods.Total))
+	}
+	return findings
+}
+
+// BuildRollup aggregates pod counts and failure reasons, and calls out reasons that
+// appear in more than one namespace since those usually share a cause.
+func BuildRollup(reports []NamespaceReport) Rollup {
+	rollup := Rollup{Namespaces: []string{}, Findings: []string{}}
+	reasons := map[string]*ReasonCount{}
+	for _, report := range reports {
+		rollup.Namespaces = append(rollup.Namespaces, report.Namespace)
+		if report.Error != "" && report.Pods.Total == 0 {
+			rollup.Failed = append(rollup.Failed, report.Namespace)
+		}
+		rollup.Pods.Total += report.Pods.Total
+		rollup.Pods.Running += report.Pods.Running
+		rollup.Pods.Pending += report.Pods.Pending
+		rollup.Pods.Failed += report.Pods.Failed
+		rollup.Pods.Restarts += report.Pods.Restarts
+		seen := map[string]bool{}
+		tally := func(reason string, n int) {
+			if reason == "" {
+				return
+			}
+			rc, ok := reasons[reason]
+			if !ok {
+				rc = &ReasonCount{Reason: reason}
+				reasons[reason] = rc
+			}
+			rc.Count += n
+			if !seen[reason] {
+				seen[reason] = true
+				rc.Namespaces = append(rc.Namespaces, report.Namespace)
+			}
+		}
+		for _, p := range report.Problems {
+			reason := p.Reason
+			if reason == "" {
+				reason = p.Phase
+			}
+			tally(reason, 1)
+		}
+		for _, ev := range report.Warnings {
+			tally(ev.Reason, ev.Count)
+		}
+	}
+	for _, rc := range reasons {
+		rollup.Reasons = append(rollup.Reasons, *rc)
+	}
+	sort.Slice(rollup.Reasons, func(i, j int) bool {
+		a, b := rollup.Reasons[i], rollup.Reasons[j]
+		if len(a.Namespaces) != len(b.Namespaces) {
+			return len(a.Namespaces) > len(b.Namespaces)
+		}
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Reason < b.Reason
+	})
+
+	rollup.Findings = append(rollup.Findings, fmt.Sprintf("%d pod(s) across %d namespace(s): %d running, %d pending, %d failed",
+		rollup.Pods.Total, len(reports), rollup.Pods.Running, rollup.Pods.Pending, rollup.Pods.Failed))
+	for _, rc := range rollup.Reasons {
+		if len(rc.Namespaces) < 2 {
+			continue
+		}
+		rollup.Findings = append(rollup.Findings, fmt.Sprintf("%s seen in %d namespaces (%s); likely a shared cause",
+			rc.Reason, len(rc.Namespaces), strings.Join(rc.Namespaces, ", ")))
+	}
+	if len(rollup.Failed) > 0 {
+		rollup.Findings = append(rollup.Findings, fmt.Sprintf("collection failed for %s", strings.Join(rollup.Failed, ", ")))
+	}
+	return rollup
+}
+
+func problemsByReason(problems []PodProblem) map[string][]PodProblem {
+	out := map[string][]PodProblem{}
+	for _, p := range problems {
+		if p.Reason != "" && problemReasons[p.Reason] {
+			out[p.Reason] = append(out[p.Reason], p)
+		}
+	}
+	return out
+}
+
+func sortedReasons(groups map[string][]PodProblem) []string {
+	keys := make([]string, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(groups[keys[i]]) != len(groups[keys[j]]) {
+			return len(groups[keys[i]]) > len(groups[keys[j]])
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+func sortProblems(problems []PodProblem) {
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Restarts != problems[j].Restarts {
+			return problems[i].Restarts > problems[j].Restarts
+		}
+		return problems[i].Pod < problems[j].Pod
+	})
+}
+
+func sortEvents(events []EventSummary) {
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].Count != events[j].Count {
+			return events[i].Count > events[j].Count
+		}
+		return events[i].LastSeen.After(events[j].LastSeen)
+	})
+}
+
+func truncate(text string, max int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) <= max {
+		return text
+	}
+	return text[:max-3] + "..."
+}
@@ -0,0 +1,212 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	jobPollInterval = 2 * time.Second
+	// jobTTL keeps a finished Job (and its pod's logs) around for inspection.
+	jobTTL = 3600
+	// jobContainer is the name of the container that runs the command.
+	jobContainer = "step"
+)
+
+// JobSpec is a one-off command to run as a Kubernetes Job.
+type JobSpec struct {
+	Namespace string
+	// Name prefixes the Job's name; a random suffix keeps runs apart.
+	Name           string
+	Image          string
+	Command        []string
+	Env            map[string]string
+	Workdir        string
+	ServiceAccount string
+	Labels         map[string]string
+}
+
+// JobResult is the outcome of a Job that ran to completion or failure.
+type JobResult struct {
+	Job      string `json:"job"`
+	Pod      string `json:"pod,omitempty"`
+	Logs     string `json:"logs"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// RunJob creates a Job running spec.Command once (no retries), waits for it to finish,
+// and returns its pod's logs and exit code. Finished Jobs are deleted by the cluster an
+// hour later; a Job whose wait is cancelled, or whose image cannot be pulled, is deleted
+// right away.
+func (c Client) RunJob(ctx context.Context, spec JobSpec) (JobResult, error) {
+	name := jobName(spec.Name)
+	result := JobResult{Job: spec.Namespace + "/" + name, ExitCode: -1}
+	body, err := json.Marshal(jobManifest(name, spec))
+	if err != nil {
+		return result, err
+	}
+	if _, err := c.runInput(ctx, bytes.NewReader(body), "create", "-n", spec.Namespace, "-f", "-"); err != nil {
+		return result, fmt.Errorf("create job: %w", err)
+	}
+
+	pod, err := c.waitJob(ctx, spec.Namespace, name)
+	if err != nil {
+		c.deleteJob(spec.Namespace, name)
+		return result, err
+	}
+	result.Pod = pod.Metadata.Name
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.Name == jobContainer && cs.State.Terminated != nil {
+			result.ExitCode = cs.State.Terminated.ExitCode
+		}
+	}
+	logs, err := c.run(ctx, "logs", "-n", spec.Namespace, "pod/"+result.Pod, "-c", jobContainer)
+	if err != nil {
+		return result, fmt.Errorf("job %s logs: %w", result.Job, err)
+	}
+	result.Logs = string(logs)
+	if result.ExitCode != 0 {
+		return result, fmt.Errorf("job %s exited with %d", result.Job, result.ExitCode)
+	}
+	return result, nil
+}
+
+type jobPod struct {
+	Metadata objectMeta `json:"metadata"`
+	Status   struct {
+		Phase             string `json:"phase"`
+		ContainerStatuses []struct {
+			Name  string `json:"name"`
+			State struct {
+				Waiting *struct {
+					Reason  string `json:"reason"`
+					Message string `json:"message"`
+				} `json:"waiting"`
+				Terminated *struct {
+					ExitCode int    `json:"exitCode"`
+					Reason   string `json:"reason"`
+				} `json:"terminated"`
+			} `json:"state"`
+		} `json:"containerStatuses"`
+	} `json:"status"`
+}
+
+// waitJob polls the Job's pod until its container terminates.
+func (c Client) waitJob(ctx context.Context, namespace, name string) (jobPod, error) {
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+	for {
+		var pods struct {
+			Items []jobPod `json:"items"`
+		}
+		if err := c.getJSON(ctx, &pods, "get", "pods", "-n", namespace, "-l", "job-name="+name); err != nil {
+			return jobPod{}, err
+		}
+		for _, pod := range pods.Items {
+			if pod.Status.Phase == "Succeeded" || pod.Status.Phase == "Failed" {
+				return pod, nil
+			}
+			for _, cs := range pod.Status.ContainerStatuses {
+				if w := cs.State.Waiting; w != nil && jobStuck(w.Reason) {
+					return jobPod{}, fmt.Errorf("job %s/%s cannot start: %s: %s", namespace, name, w.Reason, w.Message)
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return jobPod{}, fmt.Errorf("job %s/%s: %w", namespace, name, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// jobStuck reports waiting reasons that do not resolve on their own.
+func jobStuck(reason string) bool {
+	switch reason {
+	case "ErrImagePull", "ImagePullBackOff", "InvalidImageName", "CreateContainerConfigError", "CreateContainerError":
+		return true
+	}
+	return false
+}
+
+// deleteJob removes a Job and its pods without waiting, on a context of its own since the
+// caller's may be done.
+func (c Client) deleteJob(namespace, name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c.run(ctx, "delete", "job", name, "-n", namespace, "--cascade=background", "--wait=false")
+}
+
+func jobManifest(name string, spec JobSpec) map[string]interface{} {
+	labels := map[string]string{"app.kubernetes.io/managed-by": "sre-ai"}
+	for k, v := range spec.Labels {
+		labels[k] = v
+	}
+	keys := make([]string, 0, len(spec.Env))
+	for k := range spec.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	env := make([]map[string]string, 0, len(keys))
+	for _, k := range keys {
+		env = append(env, map[string]string{"name": k, "value": spec.Env[k]})
+	}
+	container := map[string]interface{}{
+		"name":    jobContainer,
+		"image":   spec.Image,
+		"command": spec.Command,
+		"env":     env,
+	}
+	if spec.Workdir != "" {
+		container["workingDir"] = spec.Workdir
+	}
+	podSpec := map[string]interface{}{
+		"restartPolicy": "Never",
+		"containers":    []interface{}{container},
+	}
+	if spec.ServiceAccount != "" {
+		podSpec["serviceAccountName"] = spec.ServiceAccount
+	}
+	return map[string]interface{}{
+		"apiVersion": "batch/v1",
+		"kind":       "Job",
+		"metadata":   map[string]interface{}{"name": name, "namespace": spec.Namespace, "labels": labels},
+		"spec": map[string]interface{}{
+			"backoffLimit":            0,
+			"ttlSecondsAfterFinished": jobTTL,
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{"labels": labels},
+				"spec":     podSpec,
+			},
+		},
+	}
+}
+
+// jobName makes a DNS-1123 name from prefix plus a random suffix.
+func jobName(prefix string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(prefix) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else if b.Len() > 0 && !strings.HasSuffix(b.String(), "-") {
+			b.WriteByte('-')
+		}
+	}
+	name := strings.Trim(b.String(), "-")
+	if len(name) > 40 {
+		name = strings.Trim(name[:40], "-")
+	}
+	if name == "" {
+		name = "sre-ai"
+	}
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return name + "-" + hex.EncodeToString(suffix)
+}
@@ -0,0 +1,299 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Client runs read-only kubectl queries against one kubeconfig context.
+type Client struct {
+	// Kubectl is the binary to run; defaults to $KUBECTL or "kubectl".
+	Kubectl string
+	// Context is the kubeconfig context; empty uses the current context.
+	Context string
+	// CPU, when set, gets the user and system time, in nanoseconds, of every kubectl run.
+	CPU *atomic.Int64
+}
+
+func (c Client) binary() string {
+	if c.Kubectl != "" {
+		return c.Kubectl
+	}
+	if env := strings.TrimSpace(os.Getenv("KUBECTL")); env != "" {
+		return env
+	}
+	return "kubectl"
+}
+
+func (c Client) run(ctx context.Context, args ...string) ([]byte, error) {
+	return c.runInput(ctx, nil, args...)
+}
+
+// runInput is run with stdin, for commands such as create -f -.
+func (c Client) runInput(ctx context.Context, stdin io.Reader, args ...string) ([]byte, error) {
+	if c.Context != "" {
+		args = append([]string{"--context", c.Context}, args...)
+	}
+	cmd := exec.CommandContext(ctx, c.binary(), args...)
+	cmd.Stdin = stdin
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if c.CPU != nil && cmd.ProcessState != nil {
+		c.CPU.Add(int64(cmd.ProcessState.UserTime() + cmd.ProcessState.SystemTime()))
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("kubectl %s: %s", strings.Join(args, " "), msg)
+		}
+		return nil, fmt.Errorf("kubectl %s: %w", strings.Join(args, " "), err)
+	}
+	return stdout.Bytes(), nil
+}
+
+func (c Client) getJSON(ctx context.Context, out interface{}, args ...string) error {
+	data, err := c.run(ctx, append(args, "-o", "json")...)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("decode kubectl %s output: %w", args[0], err)
+	}
+	return nil
+}
+
+// Namespaces lists namespace names matching a label selector.
+func (c Client) Namespaces(ctx context.Context, selector string) ([]string, error) {
+	var list struct {
+		Items []struct {
+			Metadata objectMeta `json:"metadata"`
+		} `json:"items"`
+	}
+	args := []string{"get", "namespaces"}
+	if selector != "" {
+		args = append(args, "-l", selector)
+	}
+	if err := c.getJSON(ctx, &list, args...); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(list.Items))
+	for _, item := range list.Items {
+		names = append(names, item.Metadata.Name)
+	}
+	return names, nil
+}
+
+type objectMeta struct {
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace"`
+	Labels    map[string]string `json:"labels"`
+}
+
+type podList struct {
+	Items []pod `json:"items"`
+}
+
+type pod struct {
+	Metadata objectMeta `json:"metadata"`
+	Spec     struct {
+		NodeName string `json:"nodeName"`
+	} `json:"spec"`
+	Status struct {
+		Phase             string            `json:"phase"`
+		Reason            string            `json:"reason"`
+		ContainerStatuses []containerStatus `json:"containerStatuses"`
+	} `json:"status"`
+}
+
+type containerStatus struct {
+	Name         string `json:"name"`
+	Ready        bool   `json:"ready"`
+	RestartCount int    `json:"restartCount"`
+	State        struct {
+		Waiting *struct {
+			Reason string `json:"reason"`
+		} `json:"waiting"`
+		Terminated *struct {
+			Reason string `json:"reason"`
+		} `json:"terminated"`
+	} `json:"state"`
+	LastState struct {
+		Terminated *struct {
+			Reason string `json:"reason"`
+		} `json:"terminated"`
+	} `json:"lastState"`
+}
+
+// PodProblem is a pod that is not healthy and running.
+type PodProblem struct {
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Node      string `json:"node,omitempty"`
+	Phase     string `json:"phase"`
+	Reason    string `json:"reason,omitempty"`
+	Restarts  int    `json:"restarts"`
+}
+
+// PodSummary counts pods by health.
+type PodSummary struct {
+	Total    int `json:"total"`
+	Running  int `json:"running"`
+	Pending  int `json:"pending"`
+	Failed   int `json:"failed"`
+	Restarts int `json:"restarts"`
+}
+
+// problemReasons are container states worth surfacing even when the pod phase is Running.
+var problemReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"CreateContainerConfigError": true,
+	"OOMKilled":                  true,
+	"Error":                      true,
+}
+
+// summarizePods counts pods and lists the unhealthy ones, most restarts first.
+func summarizePods(pods []pod) (PodSummary, []PodProblem) {
+	var summary PodSummary
+	var problems []PodProblem
+	for _, p := range pods {
+		summary.Total++
+		restarts := 0
+		reason := p.Status.Reason
+		for _, cs := range p.Status.ContainerStatuses {
+			restarts += cs.RestartCount
+			switch {
+			case cs.State.Waiting != nil && problemReasons[cs.State.Waiting.Reason]:
+				reason = cs.State.Waiting.Reason
+			case cs.State.Terminated != nil && problemReasons[cs.State.Terminated.Reason]:
+				reason = cs.State.Terminated.Reason
+			case reason == "" && cs.LastState.Terminated != nil && cs.LastState.Terminated.Reason == "OOMKilled":
+				reason = "OOMKilled"
+			}
+		}
+		summary.Restarts += restarts
+		switch p.Status.Phase {
+		case "Running":
+			summary.Running++
+		case "Pending":
+			summary.Pending++
+		case "Failed":
+			summary.Failed++
+		}
+		if p.Status.Phase == "Pending" || p.Status.Phase == "Failed" || p.Status.Phase == "Unknown" || problemReasons[reason] {
+			problems = append(problems, PodProblem{
+				Namespace: p.Metadata.Namespace,
+				Pod:       p.Metadata.Name,
+				Node:      p.Spec.NodeName,
+				Phase:     p.Status.Phase,
+				Reason:    reason,
+				Restarts:  restarts,
+			})
+		}
+	}
+	sortProblems(problems)
+	return summary, problems
+}
+
+type eventList struct {
+	Items []event `json:"items"`
+}
+
+type event struct {
+	Type           string    `json:"type"`
+	Reason         string    `json:"reason"`
+	Message        string    `json:"message"`
+	Count          int       `json:"count"`
+	LastTimestamp  time.Time `json:"lastTimestamp"`
+	EventTime      time.Time `json:"eventTime"`
+	InvolvedObject struct {
+		Kind      string `json:"kind"`
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"involvedObject"`
+}
+
+// EventSummary groups warning events with the same reason on the same object.
+type EventSummary struct {
+	Reason   string    `json:"reason"`
+	Object   string    `json:"object"`
+	Message  string    `json:"message"`
+	Count    int       `json:"count"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// summarizeWarnings groups Warning events seen after cutoff (zero keeps all), most frequent first.
+func summarizeWarnings(events []event, cutoff time.Time) []EventSummary {
+	return summarizeEvents(events, cutoff, func(ev event) bool { return ev.Type == "Warning" })
+}
+
+func summarizeEvents(events []event, cutoff time.Time, keep func(event) bool) []EventSummary {
+	index := map[string]int{}
+	var out []EventSummary
+	for _, ev := range events {
+		if !keep(ev) {
+			continue
+		}
+		seen := ev.LastTimestamp
+		if seen.IsZero() {
+			seen = ev.EventTime
+		}
+		if !cutoff.IsZero() && !seen.IsZero() && seen.Before(cutoff) {
+			continue
+		}
+		count := ev.Count
+		if count == 0 {
+			count = 1
+		}
+		object := strings.ToLower(ev.InvolvedObject.Kind) + "/" + ev.InvolvedObject.Name
+		key := ev.Reason + "\x00" + object
+		if idx, ok := index[key]; ok {
+			out[idx].Count += count
+			if seen.After(out[idx].LastSeen) {
+				out[idx].LastSeen = seen
+				out[idx].Message = ev.Message
+			}
+			continue
+		}
+		index[key] = len(out)
+		out = append(out, EventSummary{Reason: ev.Reason, Object: object, Message: ev.Message, Count: count, LastSeen: seen})
+	}
+	sortEvents(out)
+	return out
+}
+
+// CurrentContext reads the current-context of the kubeconfig kubectl would use (the
+// first file in $KUBECONFIG, else ~/.kube/config) without running kubectl. It returns
+// "" when there is no kubeconfig or it names no context.
+func CurrentContext() string {
+	path := ""
+	if env := os.Getenv("KUBECONFIG"); env != "" {
+		path = filepath.SplitList(env)[0]
+	} else if home, err := os.UserHomeDir(); err == nil {
+		path = filepath.Join(home, ".kube", "config")
+	}
+	data, err := os.ReadFile(path)
+	if path == "" || err != nil {
+		return ""
+	}
+	var cfg struct {
+		CurrentContext string `yaml:"current-context"`
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return ""
+	}
+	return cfg.CurrentContext
+}
@@ -0,0 +1,256 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxKubeletLines bounds how much kubelet log a node report keeps.
+const maxKubeletLines = 200
+
+// NodeCondition is one entry of a node's status.conditions.
+type NodeCondition struct {
+	Type           string    `json:"type"`
+	Status         string    `json:"status"`
+	Reason         string    `json:"reason,omitempty"`
+	Message        string    `json:"message,omitempty"`
+	LastTransition time.Time `json:"last_transition,omitempty"`
+}
+
+// KubeletLog is the tail of the kubelet log and where it came from.
+type KubeletLog struct {
+	// Source is "node-logs-api" or "ssh".
+	Source string   `json:"source"`
+	Lines  []string `json:"lines,omitempty"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// NodeReport is the evidence and findings for one node.
+type NodeReport struct {
+	Node          string          `json:"node"`
+	Address       string          `json:"address,omitempty"`
+	Unschedulable bool            `json:"unschedulable"`
+	Conditions    []NodeCondition `json:"conditions,omitempty"`
+	Pods          PodSummary      `json:"pods"`
+	Problems      []PodProblem    `json:"problems,omitempty"`
+	Events        []EventSummary  `json:"events,omitempty"`
+	Kubelet       *KubeletLog     `json:"kubelet,omitempty"`
+	Findings      []string        `json:"findings"`
+	Errors        []string        `json:"errors,omitempty"`
+}
+
+// NodeOptions tune node collection.
+type NodeOptions struct {
+	Since time.Duration
+	// SSHFallback reads the kubelet journal over ssh when the node logs API is unavailable.
+	SSHFallback bool
+	// SSHUser is prepended to the ssh target when set.
+	SSHUser string
+}
+
+type nodeObject struct {
+	Metadata objectMeta `json:"metadata"`
+	Spec     struct {
+		Unschedulable bool `json:"unschedulable"`
+	} `json:"spec"`
+	Status struct {
+		Conditions []struct {
+			Type               string    `json:"type"`
+			Status             string    `json:"status"`
+			Reason             string    `json:"reason"`
+			Message            string    `json:"message"`
+			LastTransitionTime time.Time `json:"lastTransitionTime"`
+		} `json:"conditions"`
+		Addresses []struct {
+			Type    string `json:"type"`
+			Address string `json:"address"`
+		} `json:"addresses"`
+	} `json:"status"`
+}
+
+// kubeletErrorLine matches klog error/warning lines and common failure phrases.
+var kubeletErrorLine = regexp.MustCompile(`^[EW]\d{4} |(?i)\b(error|failed|eviction|oom|pleg is not healthy)\b`)
+
+// CollectNode gathers conditions, node events, scheduled pods, and kubelet logs for one node.
+// Each source fails independently; failures are listed in Errors.
+func (c Client) CollectNode(ctx context.Context, name string, opts NodeOptions) NodeReport {
+	report := NodeReport{Node: name}
+
+	var node nodeObject
+	if err := c.getJSON(ctx, &node, "get", "node", name); err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		report.Findings = []string{fmt.Sprintf("collection failed: %v", err)}
+		return report
+	}
+	report.Unschedulable = node.Spec.Unschedulable
+	for _, cond := range node.Status.Conditions {
+		report.Conditions = append(report.Conditions, NodeCondition{
+			Type:           cond.Type,
+			Status:         cond.Status,
+			Reason:         cond.Reason,
+			Message:        cond.Message,
+			LastTransition: cond.LastTransitionTime,
+		})
+	}
+	for _, addr := range node.Status.Addresses {
+		if addr.Type == "InternalIP" {
+			report.Address = addr.Address
+			break
+		}
+	}
+
+	var cutoff time.Time
+	if opts.Since > 0 {
+		cutoff = time.Now().Add(-opts.Since)
+	}
+	var events eventList
+	if err := c.getJSON(ctx, &events, "get", "events", "--all-namespaces",
+		"--field-selector", "involvedObject.kind=Node,involvedObject.name="+name); err != nil {
+		report.Errors = append(report.Errors, err.Error())
+	} else {
+		// Pressure transitions such as NodeHasDiskPressure are Normal events, so keep those too.
+		report.Events = summarizeEvents(events.Items, cutoff, func(ev event) bool {
+			return ev.Type == "Warning" || nodePressureReasons[ev.Reason]
+		})
+	}
+
+	var pods podList
+	if err := c.getJSON(ctx, &pods, "get", "pods", "--all-namespaces", "--field-selector", "spec.nodeName="+name); err != nil {
+		report.Errors = append(report.Errors, err.Error())
+	} else {
+		report.Pods, report.Problems = summarizePods(pods.Items)
+	}
+
+	kubelet, err := c.kubeletLog(ctx, name, report.Address, opts)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+	}
+	report.Kubelet = kubelet
+
+	report.Findings = nodeFindings(report)
+	if len(report.Problems) > maxListed {
+		report.Problems = report.Problems[:maxListed]
+	}
+	if len(report.Events) > maxListed {
+		report.Events = report.Events[:maxListed]
+	}
+	return report
+}
+
+// kubeletLog tries the node logs API (kubelet NodeLogQuery) first, then ssh + journalctl.
+func (c Client) kubeletLog(ctx context.Context, name, address string, opts NodeOptions) (*KubeletLog, error) {
+	query := url.Values{}
+	query.Set("query", "kubelet")
+	query.Set("tailLines", fmt.Sprint(maxKubeletLines))
+	if opts.Since > 0 {
+		query.Set("sinceTime", time.Now().Add(-opts.Since).UTC().Format(time.RFC3339))
+	}
+	data, apiErr := c.run(ctx, "get", "--raw", fmt.Sprintf("/api/v1/nodes/%s/proxy/logs/?%s", url.PathEscape(name), query.Encode()))
+	if apiErr == nil {
+		return newKubeletLog("node-logs-api", string(data)), nil
+	}
+	if !opts.SSHFallback {
+		return nil, fmt.Errorf("kubelet logs: %w", apiErr)
+	}
+
+	host := name
+	if address != "" {
+		host = address
+	}
+	if opts.SSHUser != "" {
+		host = opts.SSHUser + "@" + host
+	}
+	remote := "journalctl -u kubelet --no-pager -o cat -n " + fmt.Sprint(maxKubeletLines)
+	if opts.Since > 0 {
+		remote += fmt.Sprintf(" --since '%d min ago'", int(opts.Since.Minutes()))
+	}
+	cmd := exec.CommandContext(ctx, "ssh", "-o", "BatchMode=yes", "-o", "ConnectTimeout=5", host, remote)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("kubelet logs: node logs API failed (%v); ssh %s failed: %s", apiErr, host, msg)
+	}
+	return newKubeletLog("ssh", stdout.String()), nil
+}
+
+func newKubeletLog(source, text string) *KubeletLog {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		lines = nil
+	}
+	if len(lines) > maxKubeletLines {
+		lines = lines[len(lines)-maxKubeletLines:]
+	}
+	log := &KubeletLog{Source: source, Lines: lines}
+	for _, line := range lines {
+		if kubeletErrorLine.MatchString(line) {
+			log.Errors = append(log.Errors, line)
+		}
+	}
+	return log
+}
+
+// nodePressureReasons are node event reasons that point at the node rather than a workload.
+var nodePressureReasons = map[string]bool{
+	"EvictionThresholdMet":  true,
+	"NodeHasDiskPressure":   true,
+	"NodeHasMemoryPressure": true,
+	"NodeHasPIDPressure":    true,
+	"NodeNotReady":          true,
+	"SystemOOM":             true,
+	"Rebooted":              true,
+	"FreeDiskSpaceFailed":   true,
+	"ImageGCFailed":         true,
+}
+
+func nodeFindings(report NodeReport) []string {
+	var findings []string
+	for _, cond := range report.Conditions {
+		detail := strings.TrimSpace(strings.Join([]string{cond.Reason, truncate(cond.Message, 160)}, ": "))
+		switch {
+		case cond.Type == "Ready" && cond.Status != "True":
+			findings = append(findings, fmt.Sprintf("NotReady (%s)", detail))
+		case cond.Type != "Ready" && cond.Status == "True":
+			findings = append(findings, fmt.Sprintf("%s is True (%s)", cond.Type, detail))
+		}
+	}
+	if report.Unschedulable {
+		findings = append(findings, "cordoned (unschedulable)")
+	}
+	for _, ev := range report.Events {
+		if nodePressureReasons[ev.Reason] {
+			findings = append(findings, fmt.Sprintf("node event %s x%d: %s", ev.Reason, ev.Count, truncate(ev.Message, 160)))
+		}
+	}
+	evicted := 0
+	for _, p := range report.Problems {
+		if p.Reason == "Evicted" {
+			evicted++
+		}
+	}
+	if evicted > 0 {
+		findings = append(findings, fmt.Sprintf("%d pod(s) evicted from this node", evicted))
+	}
+	if n := len(report.Problems) - evicted; n > 0 {
+		findings = append(findings, fmt.Sprintf("%d of %d pod(s) on the node are unhealthy, e.g. %s/%s", n, report.Pods.Total, report.Problems[0].Namespace, report.Problems[0].Pod))
+	}
+	if report.Kubelet != nil && len(report.Kubelet.Errors) > 0 {
+		last := report.Kubelet.Errors[len(report.Kubelet.Errors)-1]
+		findings = append(findings, fmt.Sprintf("%d kubelet error line(s) via %s; latest: %s", len(report.Kubelet.Errors), report.Kubelet.Source, truncate(last, 200)))
+	}
+	if len(findings) == 0 {
+		findings = append(findings, fmt.Sprintf("looks healthy (%d pod(s))", report.Pods.Total))
+	}
+	return findings
+}
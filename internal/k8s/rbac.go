@@ -0,0 +1,164 @@
+package k8s
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Collectors names the kubectl readers of this package, for which RBAC can be generated.
+var Collectors = []string{"diagnose-k8s", "diagnose-node", "rollout"}
+
+// CollectorPermissions lists what a collector reads in the given namespaces; none means
+// across all namespaces. withSelector adds listing namespaces, which diagnose k8s
+// --selector needs. diagnose-node is cluster-wide whatever the namespaces.
+func CollectorPermissions(collector string, namespaces []string, withSelector bool) ([]Permission, error) {
+	scopes := namespaces
+	if len(scopes) == 0 {
+		scopes = []string{""}
+	}
+	var perms []Permission
+	switch collector {
+	case "diagnose-k8s":
+		if withSelector {
+			perms = append(perms, Permission{Verb: "list", Resource: "namespaces", Need: "namespaces matching --selector"})
+		}
+		for _, ns := range scopes {
+			perms = append(perms, namespacePermissions(ns)...)
+		}
+	case "diagnose-node":
+		perms = nodePermissions("")
+	case "rollout":
+		for _, ns := range scopes {
+			for _, resource := range []string{"deployments", "statefulsets", "daemonsets"} {
+				perms = append(perms, Permission{Verb: "get", Group: "apps", Resource: resource, Namespace: ns, Need: "rollout status of " + resource + scopeSuffix(ns)})
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown collector %q (known: %s)", collector, strings.Join(Collectors, ", "))
+	}
+	return perms, nil
+}
+
+func namespacePermissions(ns string) []Permission {
+	return []Permission{
+		{Verb: "list", Resource: "pods", Namespace: ns, Need: "pods" + scopeSuffix(ns)},
+		{Verb: "list", Resource: "events", Namespace: ns, Need: "warning events" + scopeSuffix(ns)},
+	}
+}
+
+// nodePermissions covers CollectNode; node may be empty when generating RBAC for any node.
+func nodePermissions(node string) []Permission {
+	subject := "nodes"
+	if node != "" {
+		subject = "node " + node
+	}
+	return []Permission{
+		{Verb: "get", Resource: "nodes", Need: "conditions of " + subject},
+		{Verb: "list", Resource: "events", Need: "events of " + subject},
+		{Verb: "list", Resource: "pods", Need: "pods scheduled on " + subject},
+		{Verb: "get", Resource: "nodes", Subresource: "proxy", Need: "kubelet logs of " + subject + " (node logs API)", Optional: true},
+	}
+}
+
+func scopeSuffix(ns string) string {
+	if ns == "" {
+		return " in all namespaces"
+	}
+	return " in " + ns
+}
+
+// Subject is who a generated binding grants the roles to.
+type Subject struct {
+	// Kind is ServiceAccount, User, or Group.
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// RBACManifests renders the YAML that grants perms to subject and nothing more: a Role and
+// RoleBinding per namespace, a ClusterRole and ClusterRoleBinding for cluster-wide
+// permissions, and the ServiceAccount itself when the subject is one. Objects are named
+// after name.
+func RBACManifests(name string, subject Subject, perms []Permission) string {
+	var docs []string
+	if subject.Kind == "ServiceAccount" {
+		docs = append(docs, fmt.Sprintf("apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: %s\n  namespace: %s\n", subject.Name, subject.Namespace))
+	}
+	scopes := ruleScopes(perms)
+	for _, ns := range sortedScopes(scopes) {
+		roleKind, bindingKind, meta := "Role", "RoleBinding", fmt.Sprintf("  name: %s\n  namespace: %s\n", name, ns)
+		if ns == "" {
+			roleKind, bindingKind, meta = "ClusterRole", "ClusterRoleBinding", fmt.Sprintf("  name: %s\n", name)
+		}
+		var role strings.Builder
+		fmt.Fprintf(&role, "apiVersion: rbac.authorization.k8s.io/v1\nkind: %s\nmetadata:\n%s", roleKind, meta)
+		writeRules(&role, scopes[ns])
+		docs = append(docs, role.String())
+
+		var binding strings.Builder
+		fmt.Fprintf(&binding, "apiVersion: rbac.authorization.k8s.io/v1\nkind: %s\nmetadata:\n%s", bindingKind, meta)
+		fmt.Fprintf(&binding, "roleRef:\n  apiGroup: rbac.authorization.k8s.io\n  kind: %s\n  name: %s\n", roleKind, name)
+		fmt.Fprintf(&binding, "subjects:\n- kind: %s\n  name: %s\n", subject.Kind, subject.Name)
+		if subject.Kind == "ServiceAccount" {
+			fmt.Fprintf(&binding, "  namespace: %s\n", subject.Namespace)
+		} else {
+			binding.WriteString("  apiGroup: rbac.authorization.k8s.io\n")
+		}
+		docs = append(docs, binding.String())
+	}
+	return strings.Join(docs, "---\n")
+}
+
+type ruleKey struct{ group, verb string }
+
+// ruleScopes groups permissions by namespace ("" for cluster-wide), then by API group
+// and verb, collecting the resources of each rule.
+func ruleScopes(perms []Permission) map[string]map[ruleKey][]string {
+	scopes := map[string]map[ruleKey][]string{}
+	for _, p := range perms {
+		byRule := scopes[p.Namespace]
+		if byRule == nil {
+			byRule = map[ruleKey][]string{}
+			scopes[p.Namespace] = byRule
+		}
+		resource := p.Resource
+		if p.Subresource != "" {
+			resource += "/" + p.Subresource
+		}
+		key := ruleKey{p.Group, p.Verb}
+		if !containsString(byRule[key], resource) {
+			byRule[key] = append(byRule[key], resource)
+		}
+	}
+	return scopes
+}
+
+// sortedScopes puts cluster-wide ("") first, then namespaces by name.
+func sortedScopes(scopes map[string]map[ruleKey][]string) []string {
+	names := make([]string, 0, len(scopes))
+	for ns := range scopes {
+		names = append(names, ns)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func writeRules(b *strings.Builder, byRule map[ruleKey][]string) {
+	b.WriteString("rules:\n")
+	keys := make([]ruleKey, 0, len(byRule))
+	for key := range byRule {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].group != keys[j].group {
+			return keys[i].group < keys[j].group
+		}
+		return keys[i].verb < keys[j].verb
+	})
+	for _, key := range keys {
+		resources := byRule[key]
+		sort.Strings(resources)
+		fmt.Fprintf(b, "- apiGroups: [%q]\n  resources: [%s]\n  verbs: [%q]\n", key.group, quoteList(resources), key.verb)
+	}
+}
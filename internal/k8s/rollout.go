@@ -0,0 +1,95 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// RolloutStatus is the progress of a deployment, statefulset, or daemonset rollout.
+type RolloutStatus struct {
+	Resource  string `json:"resource"`
+	Namespace string `json:"namespace"`
+	Desired   int    `json:"desired"`
+	Updated   int    `json:"updated"`
+	Available int    `json:"available"`
+	// Complete is true once the controller has observed the latest spec and every
+	// desired replica is updated and available.
+	Complete bool   `json:"complete"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+type workload struct {
+	Metadata struct {
+		Generation int64 `json:"generation"`
+	} `json:"metadata"`
+	Spec struct {
+		Replicas *int `json:"replicas"`
+	} `json:"spec"`
+	Status struct {
+		ObservedGeneration int64 `json:"observedGeneration"`
+		Replicas           int   `json:"replicas"`
+		UpdatedReplicas    int   `json:"updatedReplicas"`
+		AvailableReplicas  int   `json:"availableReplicas"`
+		ReadyReplicas      int   `json:"readyReplicas"`
+		// DaemonSet fields.
+		DesiredNumberScheduled int `json:"desiredNumberScheduled"`
+		UpdatedNumberScheduled int `json:"updatedNumberScheduled"`
+		NumberAvailable        int `json:"numberAvailable"`
+	} `json:"status"`
+}
+
+// RolloutStatus reads a workload such as "deployment/checkout-api" and reports whether
+// its rollout has finished. It does not watch; callers poll.
+func (c Client) RolloutStatus(ctx context.Context, namespace, resource string) (RolloutStatus, error) {
+	status := RolloutStatus{Resource: resource, Namespace: namespace}
+	kind, _, ok := strings.Cut(resource, "/")
+	if !ok {
+		return status, fmt.Errorf("rollout resource %q must be kind/name, e.g. deployment/api", resource)
+	}
+	args := []string{"get", resource}
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+	var obj workload
+	if err := c.getJSON(ctx, &obj, args...); err != nil {
+		return status, err
+	}
+
+	switch strings.ToLower(kind) {
+	case "daemonset", "daemonsets", "ds":
+		status.Desired = obj.Status.DesiredNumberScheduled
+		status.Updated = obj.Status.UpdatedNumberScheduled
+		status.Available = obj.Status.NumberAvailable
+	case "statefulset", "statefulsets", "sts":
+		status.Desired = replicasOf(obj)
+		status.Updated = obj.Status.UpdatedReplicas
+		status.Available = obj.Status.ReadyReplicas
+	default:
+		status.Desired = replicasOf(obj)
+		status.Updated = obj.Status.UpdatedReplicas
+		status.Available = obj.Status.AvailableReplicas
+	}
+
+	switch {
+	case obj.Status.ObservedGeneration < obj.Metadata.Generation:
+		status.Detail = "waiting for the controller to observe the new spec"
+	case status.Updated < status.Desired:
+		status.Detail = fmt.Sprintf("%d of %d replicas updated", status.Updated, status.Desired)
+	case status.Available < status.Desired:
+		status.Detail = fmt.Sprintf("%d of %d updated replicas available", status.Available, status.Desired)
+	case obj.Status.Replicas > status.Desired:
+		status.Detail = fmt.Sprintf("%d old replicas pending termination", obj.Status.Replicas-status.Desired)
+	default:
+		status.Complete = true
+		status.Detail = fmt.Sprintf("%d of %d replicas updated and available", status.Available, status.Desired)
+	}
+	return status, nil
+}
+
+func replicasOf(obj workload) int {
+	if obj.Spec.Replicas != nil {
+		return *obj.Spec.Replicas
+	}
+	return 1
+}
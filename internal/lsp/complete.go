@@ -0,0 +1,244 @@
+package lsp
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/example/sre-ai/internal/agent"
+	"github.com/example/sre-ai/internal/remote"
+)
+
+// Completion item kinds.
+const (
+	kindFunction = 3
+	kindField    = 5
+	kindVariable = 6
+	kindModule   = 9
+	kindValue    = 12
+	kindKeyword  = 14
+)
+
+// CompletionItem is one suggestion.
+type CompletionItem struct {
+	Label         string `json:"label"`
+	Kind          int    `json:"kind,omitempty"`
+	Detail        string `json:"detail,omitempty"`
+	Documentation string `json:"documentation,omitempty"`
+	SortText      string `json:"sortText,omitempty"`
+}
+
+// templateBuiltins are the text/template functions every template can call.
+var templateBuiltins = []string{"and", "call", "eq", "ge", "gt", "html", "index", "js", "le", "len", "lt", "ne", "not", "or", "print", "printf", "println", "slice", "urlquery"}
+
+var (
+	// yamlValue matches a line being filled in as "key: value".
+	yamlValue = regexp.MustCompile(`^\s*(?:-\s+)?([a-z_]+):\s*(\S*)$`)
+	// templatePath matches the field path being typed at the end of a template action.
+	templatePath = regexp.MustCompile(`\$?(\.[\w-]*)+$`)
+)
+
+func (s *Server) complete(d *document, pos Position) []CompletionItem {
+	prefix := d.prefix(pos)
+	if open := strings.LastIndex(prefix, "{{"); open >= 0 && !strings.Contains(prefix[open:], "}}") {
+		return d.completeTemplate(prefix[open+2:], pos.Line)
+	}
+	m := yamlValue.FindStringSubmatch(prefix)
+	if m == nil {
+		return []CompletionItem{}
+	}
+	section := d.section(pos.Line)
+	items := []CompletionItem{}
+	switch key := m[1]; {
+	case key == "tool" && section == "workflow":
+		names := make([]string, 0, len(d.syms.Tools))
+		for name := range d.syms.Tools {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			tool := d.syms.Tools[name]
+			items = append(items, CompletionItem{Label: name, Kind: kindModule, Detail: toolDetail(tool), Documentation: tool.Description})
+		}
+	case key == "kind" && section == "tools":
+		for _, kind := range agent.ToolKinds() {
+			items = append(items, CompletionItem{Label: kind, Kind: kindKeyword})
+		}
+	case key == "type" && section == "workflow":
+		for _, t := range agent.StepTypes() {
+			items = append(items, CompletionItem{Label: t, Kind: kindKeyword})
+		}
+	case key == "alias" && section == "tools":
+		for _, server := range s.catalog() {
+			items = append(items, CompletionItem{Label: server.Alias, Kind: kindModule, Detail: "mcp server (" + server.Source + ")", Documentation: s.toolDoc(server.Alias)})
+		}
+	case key == "target" && section == "workflow":
+		items = append(items, CompletionItem{Label: remote.TargetLocal, Kind: kindValue, Detail: "run on this machine"})
+		groups := make([]string, 0, len(s.opts.Hosts))
+		for name := range s.opts.Hosts {
+			groups = append(groups, name)
+		}
+		sort.Strings(groups)
+		for _, name := range groups {
+			items = append(items, CompletionItem{Label: remote.TargetHost + ":" + name, Kind: kindValue, Detail: strings.Join(s.opts.Hosts[name].Addresses, ", ")})
+		}
+		items = append(items, CompletionItem{Label: remote.TargetJob + ":", Kind: kindValue, Detail: "run as a Kubernetes Job in a namespace"})
+	case key == "upload" && section == "outputs":
+		names := make([]string, 0, len(s.opts.Uploads))
+		for name := range s.opts.Uploads {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			items = append(items, CompletionItem{Label: name, Kind: kindValue, Detail: s.opts.Uploads[name].URL})
+		}
+	}
+	return items
+}
+
+// completeTemplate suggests the next field of a path like .steps.fetch. or, outside a
+// path, the template functions and data roots.
+func (d *document) completeTemplate(action string, line int) []CompletionItem {
+	items := []CompletionItem{}
+	path := templatePath.FindString(action)
+	if path == "" {
+		for _, name := range agent.TemplateFuncNames() {
+			items = append(items, CompletionItem{Label: name, Kind: kindFunction, Detail: "sre-ai function", SortText: "0" + name})
+		}
+		for _, name := range templateBuiltins {
+			items = append(items, CompletionItem{Label: name, Kind: kindFunction, Detail: "template builtin", SortText: "1" + name})
+		}
+		for _, root := range agent.TemplateRoots() {
+			items = append(items, CompletionItem{Label: "." + root, Kind: kindVariable})
+		}
+		return items
+	}
+	parts := strings.Split(strings.TrimPrefix(strings.TrimPrefix(path, "$"), "."), ".")
+	parts = parts[:len(parts)-1] // the last part is being typed
+	switch {
+	case len(parts) == 0:
+		for _, root := range agent.TemplateRoots() {
+			items = append(items, CompletionItem{Label: root, Kind: kindVariable})
+		}
+	case len(parts) == 1 && parts[0] == "steps":
+		for i, step := range d.stepsBefore(line) {
+			detail := step.Type + " step"
+			if step.Tool != "" {
+				detail += " using " + step.Tool
+			}
+			items = append(items, CompletionItem{Label: step.Name, Kind: kindField, Detail: detail, Documentation: "Provides: " + strings.Join(step.Provides, ", "), SortText: fmt.Sprintf("%04d", i)})
+		}
+	case len(parts) == 2 && parts[0] == "steps":
+		if step, ok := d.syms.Step(parts[1]); ok {
+			for _, key := range step.Provides {
+				items = append(items, CompletionItem{Label: key, Kind: kindField, Detail: providesDetail(key)})
+			}
+		}
+	case len(parts) == 1 && parts[0] == "inputs":
+		names := make([]string, 0, len(d.syms.Inputs))
+		for name := range d.syms.Inputs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			in := d.syms.Inputs[name]
+			items = append(items, CompletionItem{Label: name, Kind: kindField, Detail: in.Type, Documentation: in.Description})
+		}
+	case len(parts) == 1:
+		for _, key := range agent.TemplateRootKeys(parts[0]) {
+			items = append(items, CompletionItem{Label: key, Kind: kindField})
+		}
+	}
+	return items
+}
+
+func providesDetail(key string) string {
+	switch key {
+	case "_raw":
+		return "the step's full output"
+	case "params":
+		return "the rendered params"
+	case "verification":
+		return "the verify result"
+	}
+	return "capture"
+}
+
+func toolDetail(tool agent.ToolSpec) string {
+	detail := tool.Kind + " tool"
+	if tool.Alias != "" {
+		detail += " (" + tool.Alias + ")"
+	}
+	return detail
+}
+
+// Hover is the markdown shown for the word under the cursor.
+type Hover struct {
+	Contents struct {
+		Kind  string `json:"kind"`
+		Value string `json:"value"`
+	} `json:"contents"`
+}
+
+var (
+	hoverWord   = regexp.MustCompile(`[\w.$-]`)
+	toolLine    = regexp.MustCompile(`^\s*(?:-\s+)?tool:\s*(\S+)\s*$`)
+	stepRefPath = regexp.MustCompile(`\.steps\.([\w-]+)`)
+	inputPath   = regexp.MustCompile(`\.inputs\.([\w-]+)`)
+)
+
+// hover describes the tool, step, or input under the cursor, or returns nil.
+func (d *document) hover(pos Position) *Hover {
+	if pos.Line < 0 || pos.Line >= len(d.lines) {
+		return nil
+	}
+	line := strings.TrimRight(d.lines[pos.Line], "\r")
+	at := byteOffset(line, pos.Character)
+	start, end := at, at
+	for start > 0 && hoverWord.MatchString(line[start-1:start]) {
+		start--
+	}
+	for end < len(line) && hoverWord.MatchString(line[end:end+1]) {
+		end++
+	}
+	word := line[start:end]
+
+	var text string
+	if m := toolLine.FindStringSubmatch(line); m != nil && m[1] == word {
+		if tool, ok := d.syms.Tools[word]; ok {
+			text = fmt.Sprintf("**tool %s** — %s", word, toolDetail(tool))
+			if tool.Description != "" {
+				text += "\n\n" + tool.Description
+			}
+		}
+	} else if m := stepRefPath.FindStringSubmatch(word); m != nil {
+		if step, ok := d.syms.Step(m[1]); ok {
+			text = fmt.Sprintf("**step %s** — %s step in stage %s\n\nProvides: %s", step.Name, step.Type, step.Stage, strings.Join(step.Provides, ", "))
+		} else {
+			text = fmt.Sprintf("step %s is not defined", m[1])
+		}
+	} else if m := inputPath.FindStringSubmatch(word); m != nil {
+		if in, ok := d.syms.Inputs[m[1]]; ok {
+			text = fmt.Sprintf("**input %s**", m[1])
+			if in.Type != "" {
+				text += " (" + in.Type + ")"
+			}
+			if in.Description != "" {
+				text += "\n\n" + in.Description
+			}
+			if in.Default != nil {
+				text += fmt.Sprintf("\n\nDefault: `%v`", in.Default)
+			}
+		} else {
+			text = fmt.Sprintf("input %s is not declared", m[1])
+		}
+	}
+	if text == "" {
+		return nil
+	}
+	h := &Hover{}
+	h.Contents.Kind = "markdown"
+	h.Contents.Value = text
+	return h
+}
@@ -0,0 +1,285 @@
+package lsp
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/example/sre-ai/internal/agent"
+	"gopkg.in/yaml.v3"
+)
+
+// Position is a zero-based line and UTF-16 character offset, as LSP counts them.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range spans two positions.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Diagnostic severities.
+const (
+	severityError   = 1
+	severityWarning = 2
+)
+
+// Diagnostic is one validation issue placed on the line it concerns.
+type Diagnostic struct {
+	Range    Range  `json:"range"`
+	Severity int    `json:"severity"`
+	Source   string `json:"source"`
+	Message  string `json:"message"`
+}
+
+// document is an open workflow buffer and what validation found in it.
+type document struct {
+	uri      string
+	lines    []string
+	parseErr error
+	issues   []agent.LintIssue
+	syms     agent.Symbols
+	outline  outline
+	workflow bool
+}
+
+// outline maps workflow names to the zero-based line that defines them.
+type outline struct {
+	stages  map[string]int
+	steps   map[string]int
+	tools   map[string]int
+	inputs  map[string]int
+	outputs map[string]int
+}
+
+func newDocument(uri, text string) *document {
+	doc := &document{uri: uri, lines: strings.Split(text, "\n")}
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(text), &root); err != nil {
+		doc.parseErr = err
+		return doc
+	}
+	doc.outline, doc.workflow = outlineOf(&root)
+	wf, err := agent.ParseWorkflow([]byte(text))
+	if err != nil {
+		doc.parseErr = err
+		return doc
+	}
+	doc.syms = agent.WorkflowSymbols(wf)
+	if doc.workflow {
+		doc.issues = agent.Validate(wf)
+	}
+	return doc
+}
+
+// outlineOf records where each stage, step, tool, input, and output is defined. ok is
+// false for YAML that is not a workflow, which gets no diagnostics.
+func outlineOf(root *yaml.Node) (outline, bool) {
+	out := outline{stages: map[string]int{}, steps: map[string]int{}, tools: map[string]int{}, inputs: map[string]int{}, outputs: map[string]int{}}
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return out, false
+	}
+	top := root.Content[0]
+	isWorkflow := false
+	for i := 0; i+1 < len(top.Content); i += 2 {
+		key, value := top.Content[i], top.Content[i+1]
+		switch key.Value {
+		case "tools":
+			mapKeys(value, out.tools)
+		case "inputs":
+			mapKeys(value, out.inputs)
+		case "outputs":
+			mapKeys(value, out.outputs)
+		case "workflow":
+			isWorkflow = true
+			stages := mapValue(value, "stages")
+			if stages == nil || stages.Kind != yaml.SequenceNode {
+				continue
+			}
+			for _, stage := range stages.Content {
+				id := scalar(mapValue(stage, "id"))
+				out.stages[id] = stage.Line - 1
+				steps := mapValue(stage, "steps")
+				if steps == nil || steps.Kind != yaml.SequenceNode {
+					continue
+				}
+				for idx, step := range steps.Content {
+					name := scalar(mapValue(step, "name"))
+					if name == "" {
+						name = id + "_step_" + strconv.Itoa(idx+1)
+					}
+					out.steps[name] = step.Line - 1
+				}
+			}
+		}
+	}
+	return out, isWorkflow
+}
+
+func mapKeys(node *yaml.Node, into map[string]int) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		into[node.Content[i].Value] = node.Content[i].Line - 1
+	}
+}
+
+func mapValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func scalar(node *yaml.Node) string {
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return node.Value
+}
+
+var (
+	yamlErrorLine = regexp.MustCompile(`line (\d+)`)
+	// issueSubject finds the tool or output a workflow-level issue is about.
+	issueSubject = regexp.MustCompile(`^(?:(?:wasm|mcp) )?(tool|output) ([^\s:]+)`)
+)
+
+func (d *document) diagnostics() []Diagnostic {
+	diags := []Diagnostic{}
+	if d.parseErr != nil {
+		line := 0
+		if m := yamlErrorLine.FindStringSubmatch(d.parseErr.Error()); m != nil {
+			line, _ = strconv.Atoi(m[1])
+			line--
+		}
+		return append(diags, Diagnostic{Range: d.lineRange(line), Severity: severityError, Source: "sre-ai", Message: d.parseErr.Error()})
+	}
+	for _, issue := range d.issues {
+		severity := severityWarning
+		if issue.Severity == "error" {
+			severity = severityError
+		}
+		diags = append(diags, Diagnostic{Range: d.lineRange(d.issueLine(issue)), Severity: severity, Source: "sre-ai", Message: issue.Message})
+	}
+	return diags
+}
+
+// issueLine places an issue on its step, its stage, or the tool or output it names,
+// falling back to the first line.
+func (d *document) issueLine(issue agent.LintIssue) int {
+	if line, ok := d.outline.steps[issue.Step]; ok && issue.Step != "" {
+		return line
+	}
+	if line, ok := d.outline.stages[issue.Stage]; ok && issue.Stage != "" {
+		return line
+	}
+	if m := issueSubject.FindStringSubmatch(issue.Message); m != nil {
+		names := d.outline.tools
+		if m[1] == "output" {
+			names = d.outline.outputs
+		}
+		if line, ok := names[m[2]]; ok {
+			return line
+		}
+	}
+	return 0
+}
+
+func (d *document) lineRange(line int) Range {
+	if line < 0 || line >= len(d.lines) {
+		line = 0
+	}
+	text := strings.TrimRight(d.lines[line], "\r")
+	start := len(text) - len(strings.TrimLeft(text, " \t"))
+	return Range{Start: Position{Line: line, Character: start}, End: Position{Line: line, Character: utf16Len(text)}}
+}
+
+// prefix returns the text of pos's line before the cursor.
+func (d *document) prefix(pos Position) string {
+	if pos.Line < 0 || pos.Line >= len(d.lines) {
+		return ""
+	}
+	line := strings.TrimRight(d.lines[pos.Line], "\r")
+	return line[:byteOffset(line, pos.Character)]
+}
+
+// section returns the top-level key (tools, workflow, outputs, ...) that line falls under.
+func (d *document) section(line int) string {
+	if line >= len(d.lines) {
+		line = len(d.lines) - 1
+	}
+	for i := line; i >= 0; i-- {
+		text := d.lines[i]
+		if text == "" || text[0] == ' ' || text[0] == '\t' || text[0] == '#' || text[0] == '-' {
+			continue
+		}
+		key, _, ok := strings.Cut(text, ":")
+		if ok {
+			return strings.TrimSpace(key)
+		}
+	}
+	return ""
+}
+
+// stepsBefore lists the steps that run before the one containing line, the ones a
+// template there may read. Outside the workflow section, as in outputs, every step has run.
+func (d *document) stepsBefore(line int) []agent.StepSymbol {
+	if d.section(line) != "workflow" {
+		return d.syms.Steps
+	}
+	current := -1
+	for _, at := range d.outline.steps {
+		if at <= line && at > current {
+			current = at
+		}
+	}
+	var out []agent.StepSymbol
+	for _, step := range d.syms.Steps {
+		if at, ok := d.outline.steps[step.Name]; ok && at < current {
+			out = append(out, step)
+		}
+	}
+	return out
+}
+
+// symbolNames is the workflow's symbols as plain names, for sre-ai/validate.
+func (d *document) symbolNames() map[string]interface{} {
+	inputs := make([]string, 0, len(d.syms.Inputs))
+	for name := range d.syms.Inputs {
+		inputs = append(inputs, name)
+	}
+	sort.Strings(inputs)
+	tools := make([]string, 0, len(d.syms.Tools))
+	for name := range d.syms.Tools {
+		tools = append(tools, name)
+	}
+	sort.Strings(tools)
+	return map[string]interface{}{"inputs": inputs, "tools": tools, "steps": d.syms.Steps, "outputs": d.syms.Outputs}
+}
+
+func utf16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
+
+// byteOffset converts a UTF-16 character offset in line to a byte offset.
+func byteOffset(line string, character int) int {
+	units := 0
+	for i, r := range line {
+		if units >= character {
+			return i
+		}
+		units += len(utf16.Encode([]rune{r}))
+	}
+	return len(line)
+}
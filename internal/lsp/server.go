@@ -0,0 +1,305 @@
+// Package lsp serves workflow authoring support to editors over stdio: a subset of the
+// Language Server Protocol (diagnostics from agent.Validate, completion, and hover) plus
+// sre-ai/validate and sre-ai/tools requests for plugins that want the raw data.
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/sre-ai/internal/agent"
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/mcp"
+)
+
+// probeTimeout bounds starting a local MCP server to list its tools.
+const probeTimeout = 10 * time.Second
+
+// Server holds the open documents of one editor session.
+type Server struct {
+	conn *conn
+	opts *config.GlobalOptions
+	docs map[string]*document
+	// registry holds the MCP servers of the command tree that started the server.
+	registry *mcp.Registry
+
+	// tools caches the probed tool lists of local MCP servers for the session.
+	mu    sync.Mutex
+	tools map[string][]mcp.ToolSummary
+}
+
+// Serve answers requests on in and out until the client sends exit or closes in.
+func Serve(ctx context.Context, in io.Reader, out io.Writer, opts *config.GlobalOptions) error {
+	s := &Server{
+		conn:     &conn{r: bufio.NewReader(in), w: out},
+		opts:     opts,
+		docs:     map[string]*document{},
+		registry: mcp.RegistryFrom(ctx),
+		tools:    map[string][]mcp.ToolSummary{},
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		msg, err := s.conn.read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		var rerr *rpcError
+		if errors.As(err, &rerr) {
+			if err := s.conn.reply(nil, nil, rerr); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		result, rerr := s.handle(ctx, msg)
+		if msg.ID == nil {
+			continue
+		}
+		if err := s.conn.reply(msg.ID, result, rerr); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(ctx context.Context, msg *message) (interface{}, *rpcError) {
+	switch msg.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync": map[string]interface{}{
+					"openClose": true,
+					"change":    1, // full text on every change
+					"save":      map[string]bool{"includeText": true},
+				},
+				"completionProvider": map[string]interface{}{"triggerCharacters": []string{".", ":", " "}},
+				"hoverProvider":      true,
+			},
+			"serverInfo": map[string]string{"name": "sre-ai"},
+		}, nil
+	case "shutdown":
+		return nil, nil
+	case "textDocument/didOpen":
+		var p struct {
+			TextDocument struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"textDocument"`
+		}
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, invalidParams(err)
+		}
+		s.update(p.TextDocument.URI, p.TextDocument.Text)
+	case "textDocument/didChange":
+		var p struct {
+			TextDocument   struct{ URI string } `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, invalidParams(err)
+		}
+		if n := len(p.ContentChanges); n > 0 {
+			s.update(p.TextDocument.URI, p.ContentChanges[n-1].Text)
+		}
+	case "textDocument/didSave":
+		var p struct {
+			TextDocument struct{ URI string } `json:"textDocument"`
+			Text         *string              `json:"text"`
+		}
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, invalidParams(err)
+		}
+		if p.Text != nil {
+			s.update(p.TextDocument.URI, *p.Text)
+		}
+	case "textDocument/didClose":
+		var p struct {
+			TextDocument struct{ URI string } `json:"textDocument"`
+		}
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, invalidParams(err)
+		}
+		delete(s.docs, p.TextDocument.URI)
+		s.publish(p.TextDocument.URI, []Diagnostic{})
+	case "textDocument/completion", "textDocument/hover":
+		var p struct {
+			TextDocument struct{ URI string } `json:"textDocument"`
+			Position     Position             `json:"position"`
+		}
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, invalidParams(err)
+		}
+		doc, ok := s.docs[p.TextDocument.URI]
+		if !ok {
+			return nil, nil
+		}
+		if msg.Method == "textDocument/hover" {
+			return doc.hover(p.Position), nil
+		}
+		return s.complete(doc, p.Position), nil
+	case "sre-ai/validate":
+		var p struct {
+			URI  string  `json:"uri"`
+			Text *string `json:"text"`
+		}
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, invalidParams(err)
+		}
+		doc, ok := s.docs[p.URI]
+		if p.Text != nil {
+			doc, ok = newDocument(p.URI, *p.Text), true
+		}
+		if !ok {
+			return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("document %s is not open; pass its text", p.URI)}
+		}
+		if doc.parseErr != nil {
+			return map[string]interface{}{"valid": false, "error": doc.parseErr.Error()}, nil
+		}
+		return map[string]interface{}{"valid": !agent.HasErrors(doc.issues), "issues": doc.issues, "symbols": doc.symbolNames()}, nil
+	case "sre-ai/tools":
+		var p struct {
+			Alias string `json:"alias"`
+		}
+		if len(msg.Params) > 0 {
+			if err := json.Unmarshal(msg.Params, &p); err != nil {
+				return nil, invalidParams(err)
+			}
+		}
+		if p.Alias == "" {
+			return map[string]interface{}{"servers": s.catalog(), "tool_kinds": agent.ToolKinds(), "step_types": agent.StepTypes()}, nil
+		}
+		tools, err := s.serverTools(ctx, p.Alias)
+		if err != nil {
+			return nil, &rpcError{Code: codeInternalError, Message: err.Error()}
+		}
+		return map[string]interface{}{"alias": p.Alias, "tools": tools}, nil
+	case "initialized", "$/cancelRequest", "$/setTrace", "workspace/didChangeConfiguration":
+	default:
+		if msg.ID != nil {
+			return nil, &rpcError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}
+		}
+	}
+	return nil, nil
+}
+
+func invalidParams(err error) *rpcError {
+	return &rpcError{Code: codeInvalidParams, Message: err.Error()}
+}
+
+// update re-analyses a document and publishes its diagnostics. A buffer that does not
+// parse mid-edit keeps the symbols of its last good version for completion.
+func (s *Server) update(uri, text string) {
+	doc := newDocument(uri, text)
+	if prev, ok := s.docs[uri]; ok && doc.parseErr != nil {
+		doc.syms, doc.outline = prev.syms, prev.outline
+	}
+	s.docs[uri] = doc
+	s.publish(uri, doc.diagnostics())
+}
+
+func (s *Server) publish(uri string, diags []Diagnostic) {
+	_ = s.conn.notify("textDocument/publishDiagnostics", map[string]interface{}{"uri": uri, "diagnostics": diags})
+}
+
+// catalogServer is one registered MCP server and the tools known without starting it.
+type catalogServer struct {
+	Alias  string            `json:"alias"`
+	Source string            `json:"source"`
+	Notes  string            `json:"notes,omitempty"`
+	Tools  []mcp.ToolSummary `json:"tools,omitempty"`
+}
+
+// catalog lists registered MCP servers. Manifest servers carry their tools; local
+// servers carry tools once probed this session (sre-ai/tools with an alias).
+func (s *Server) catalog() []catalogServer {
+	var out []catalogServer
+	for _, info := range s.registry.Snapshot() {
+		entry := catalogServer{Alias: info.Alias, Source: info.Source, Notes: info.Notes}
+		if client, ok := s.registry.Get(info.Alias); ok && client.Manifest != nil {
+			entry.Tools = manifestTools(client.Manifest)
+		}
+		s.mu.Lock()
+		if tools, ok := s.tools[info.Alias]; ok {
+			entry.Tools = tools
+		}
+		s.mu.Unlock()
+		out = append(out, entry)
+	}
+	return out
+}
+
+// serverTools returns the tools of one MCP server, starting a local server once per
+// session to list them.
+func (s *Server) serverTools(ctx context.Context, alias string) ([]mcp.ToolSummary, error) {
+	client, ok := s.registry.Get(alias)
+	if !ok {
+		return nil, fmt.Errorf("mcp server %s is not registered", alias)
+	}
+	if client.Manifest != nil {
+		return manifestTools(client.Manifest), nil
+	}
+	s.mu.Lock()
+	tools, ok := s.tools[alias]
+	s.mu.Unlock()
+	if ok {
+		return tools, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	result, err := mcp.ProbeLocalServer(ctx, alias)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.tools[alias] = result.Tools
+	s.mu.Unlock()
+	return result.Tools, nil
+}
+
+func manifestTools(m *mcp.Manifest) []mcp.ToolSummary {
+	tools := make([]mcp.ToolSummary, 0, len(m.Tools))
+	for _, tool := range m.Tools {
+		name, _ := tool["name"].(string)
+		if name == "" {
+			continue
+		}
+		description, _ := tool["description"].(string)
+		tools = append(tools, mcp.ToolSummary{Name: name, Description: description})
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	return tools
+}
+
+// toolDoc lists the known tools of an MCP server for completion details.
+func (s *Server) toolDoc(alias string) string {
+	s.mu.Lock()
+	tools := s.tools[alias]
+	s.mu.Unlock()
+	if client, ok := s.registry.Get(alias); ok && client.Manifest != nil {
+		tools = manifestTools(client.Manifest)
+	}
+	if len(tools) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(tools))
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	return "Tools: " + strings.Join(names, ", ")
+}
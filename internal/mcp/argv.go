@@ -0,0 +1,108 @@
+package mcp
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// SplitArgs splits a command-line string into arguments the same way on every
+// platform. Whitespace separates arguments; single quotes keep their content
+// literally; double quotes group text, and inside them \" and \\ are escapes.
+// Outside quotes a backslash only escapes whitespace or a quote, so Windows paths
+// such as C:\tools\kubectl.exe survive unchanged. No variables, globs, or pipes are
+// interpreted; use a raw command for those.
+func SplitArgs(line string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inArg   bool
+		quote   rune
+	)
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+				continue
+			}
+			current.WriteRune(r)
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+				continue
+			}
+			if r == '\\' && i+1 < len(runes) && (runes[i+1] == '"' || runes[i+1] == '\\') {
+				i++
+				current.WriteRune(runes[i])
+				continue
+			}
+			current.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == '\\' && i+1 < len(runes) && strings.ContainsRune(" \t\n'\"", runes[i+1]):
+			i++
+			current.WriteRune(runes[i])
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in %q", quote, line)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// ShellCommand returns the platform shell invocation that runs command and args
+// followed by raw, which the shell interprets as written: sh -c on Unix, cmd /C on
+// Windows. command and args are quoted so only raw carries shell syntax.
+func ShellCommand(command string, args []string, raw string) (string, []string) {
+	windows := runtime.GOOS == "windows"
+	parts := make([]string, 0, len(args)+2)
+	for _, part := range append([]string{command}, args...) {
+		if windows {
+			parts = append(parts, quoteWindows(part))
+		} else {
+			parts = append(parts, quotePOSIX(part))
+		}
+	}
+	if strings.TrimSpace(raw) != "" {
+		parts = append(parts, raw)
+	}
+	line := strings.Join(parts, " ")
+	if windows {
+		return "cmd.exe", []string{"/C", line}
+	}
+	return "/bin/sh", []string{"-c", line}
+}
+
+func quotePOSIX(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r == '-' || r == '_' || r == '.' || r == '/' || r == ':' || r == '=' || r == ',' || r == '@' || r == '+' ||
+			(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'))
+	}) == -1 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func quoteWindows(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\"&|<>^%") {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
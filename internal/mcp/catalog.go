@@ -0,0 +1,164 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/sre-ai/internal/config"
+)
+
+// SchemaHash fingerprints the contract of a tool: its name and its input and output
+// schemas, not its description. Schemas are hashed as JSON with sorted keys, so the same
+// schema always hashes alike.
+func (t ToolSummary) SchemaHash() string {
+	data, _ := json.Marshal(struct {
+		Name         string                 `json:"name"`
+		InputSchema  map[string]interface{} `json:"inputSchema,omitempty"`
+		OutputSchema map[string]interface{} `json:"outputSchema,omitempty"`
+	}{t.Name, t.InputSchema, t.OutputSchema})
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])[:16]
+}
+
+// Catalog is the tool list a server reported when it was last probed or started, kept
+// under the cache dir so workflows can check the tools they pin without starting it.
+type Catalog struct {
+	Alias         string        `json:"alias"`
+	ServerName    string        `json:"server_name,omitempty"`
+	ServerVersion string        `json:"server_version,omitempty"`
+	ListedAt      time.Time     `json:"listed_at"`
+	Tools         []CatalogTool `json:"tools"`
+}
+
+// CatalogTool is one tool of a Catalog with its SchemaHash.
+type CatalogTool struct {
+	Name string `json:"name"`
+	Hash string `json:"hash"`
+}
+
+// NewCatalog builds the catalog of tools as listed now.
+func NewCatalog(alias, serverName, serverVersion string, tools []ToolSummary) *Catalog {
+	c := &Catalog{Alias: alias, ServerName: serverName, ServerVersion: serverVersion, ListedAt: time.Now().UTC()}
+	for _, tool := range tools {
+		c.Tools = append(c.Tools, CatalogTool{Name: tool.Name, Hash: tool.SchemaHash()})
+	}
+	sort.Slice(c.Tools, func(i, j int) bool { return c.Tools[i].Name < c.Tools[j].Name })
+	return c
+}
+
+// ManifestCatalog builds the catalog of a manifest server from the tools it declares.
+func ManifestCatalog(alias string, m *Manifest) *Catalog {
+	tools := make([]ToolSummary, 0, len(m.Tools))
+	for _, raw := range m.Tools {
+		tool := ToolSummary{}
+		tool.Name, _ = raw["name"].(string)
+		tool.InputSchema, _ = raw["inputSchema"].(map[string]interface{})
+		tool.OutputSchema, _ = raw["outputSchema"].(map[string]interface{})
+		if tool.Name != "" {
+			tools = append(tools, tool)
+		}
+	}
+	return NewCatalog(alias, m.Name, m.Version, tools)
+}
+
+const catalogDirName = "mcp"
+
+func catalogPath(ctx context.Context, alias string) (string, error) {
+	if alias == "" || strings.ContainsAny(alias, `/\`) || alias == "." || alias == ".." {
+		return "", fmt.Errorf("invalid MCP server alias %q", alias)
+	}
+	base, err := config.ConfigDir(ctx)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "cache", catalogDirName, alias+".json"), nil
+}
+
+// SaveCatalog records the tools of a server in the cache.
+func SaveCatalog(ctx context.Context, c *Catalog) error {
+	path, err := catalogPath(ctx, c.Alias)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return config.WriteFile(path, append(data, '\n'))
+}
+
+// LoadCatalog returns the cached catalog of alias; the error wraps os.ErrNotExist when
+// the server has not been listed since the cache was last pruned.
+func LoadCatalog(ctx context.Context, alias string) (*Catalog, error) {
+	path, err := catalogPath(ctx, alias)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Catalog
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// removeCatalog forgets the cached catalog of a removed server, so a server later added
+// under the same alias is listed afresh.
+func removeCatalog(ctx context.Context, alias string) {
+	if path, err := catalogPath(ctx, alias); err == nil {
+		_ = os.Remove(path)
+	}
+}
+
+// ToolPin is one entry of a workflow's requires list: a tool name, with the schema hash
+// the workflow was written against when it is pinned.
+type ToolPin struct {
+	Name string
+	Hash string
+}
+
+var pinHash = regexp.MustCompile(`^sha256:[0-9a-f]{16}$`)
+
+// ParseToolPin reads "name" or "name@sha256:<hash>" as printed by mcp test.
+func ParseToolPin(s string) (ToolPin, error) {
+	name, hash, pinned := strings.Cut(strings.TrimSpace(s), "@")
+	if name == "" {
+		return ToolPin{}, fmt.Errorf("%q has no tool name", s)
+	}
+	if pinned && !pinHash.MatchString(hash) {
+		return ToolPin{}, fmt.Errorf("%q: hash must look like sha256:<16 hex digits>, as printed by 'sre-ai mcp test'", s)
+	}
+	return ToolPin{Name: name, Hash: hash}, nil
+}
+
+// Mismatches lists, one line per pin, the pins the catalog does not satisfy: tools it
+// does not list and tools whose schema hash differs.
+func (c *Catalog) Mismatches(pins []ToolPin) []string {
+	hashes := make(map[string]string, len(c.Tools))
+	for _, tool := range c.Tools {
+		hashes[tool.Name] = tool.Hash
+	}
+	var out []string
+	for _, pin := range pins {
+		hash, ok := hashes[pin.Name]
+		switch {
+		case !ok:
+			out = append(out, fmt.Sprintf("%s: not listed by the server", pin.Name))
+		case pin.Hash != "" && hash != pin.Hash:
+			out = append(out, fmt.Sprintf("%s: schema changed (pinned %s, now %s)", pin.Name, pin.Hash, hash))
+		}
+	}
+	return out
+}
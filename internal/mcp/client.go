@@ -3,7 +3,6 @@ package mcp
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
@@ -11,6 +10,7 @@ import (
 	"sync"
 
 	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/warnings"
 )
 
 // Manifest models the subset of an MCP manifest understood by the CLI.
@@ -32,6 +32,15 @@ type ServerDefinition struct {
 	Env     map[string]string `json:"env"`
 	Workdir string            `json:"workdir"`
 	Notes   string            `json:"notes,omitempty"`
+	// ProtocolVersion pins the MCP revision offered during initialize.
+	ProtocolVersion string `json:"protocolVersion,omitempty"`
+	// EnvPolicy decides which of the operator's environment variables the process sees:
+	// inherit (default), clean, or allowlist (only EnvAllow, where "NAME*" matches a prefix).
+	EnvPolicy string   `json:"env_policy,omitempty"`
+	EnvAllow  []string `json:"env_allow,omitempty"`
+	// Tunnels names config endpoints whose SSH port forward is opened before the process
+	// starts; each forward's local address is passed as SRE_AI_TUNNEL_<NAME>.
+	Tunnels []string `json:"tunnels,omitempty"`
 }
 
 // Source enumerates how an MCP server was registered.
@@ -60,12 +69,15 @@ type ClientInfo struct {
 	Args                  []string          `json:"args,omitempty"`
 	Env                   map[string]string `json:"env,omitempty"`
 	Workdir               string            `json:"workdir,omitempty"`
+	EnvPolicy             string            `json:"env_policy,omitempty"`
+	EnvAllow              []string          `json:"env_allow,omitempty"`
 	Notes                 string            `json:"notes,omitempty"`
 	Origin                string            `json:"origin,omitempty"`
 	ManifestName          string            `json:"manifest_name,omitempty"`
 	ManifestVersion       string            `json:"manifest_version,omitempty"`
 	ManifestCapabilities  []string          `json:"manifest_capabilities,omitempty"`
 	ManifestTransportType string            `json:"manifest_transport_type,omitempty"`
+	Health                *Health           `json:"health,omitempty"`
 }
 
 // Registry maintains MCP clients keyed by alias.
@@ -146,6 +158,8 @@ func (r *Registry) Snapshot() []ClientInfo {
 				}
 			}
 			info.Workdir = client.Definition.Workdir
+			info.EnvPolicy = client.Definition.EnvPolicy
+			info.EnvAllow = append([]string(nil), client.Definition.EnvAllow...)
 			info.Notes = client.Definition.Notes
 		}
 		if client.Manifest != nil {
@@ -167,27 +181,52 @@ func (r *Registry) Snapshot() []ClientInfo {
 	return infos
 }
 
-// DefaultRegistry is the singleton used by the CLI.
+// DefaultRegistry is the registry of contexts that carry none of their own.
 var DefaultRegistry = NewRegistry()
 
-// Warmup loads embedded defaults, config manifests, and local server definitions.
+type registryKey struct{}
+
+// WithRegistry returns a context whose servers are registered in, and looked up from, r.
+func WithRegistry(ctx context.Context, r *Registry) context.Context {
+	return context.WithValue(ctx, registryKey{}, r)
+}
+
+// RegistryFrom returns the registry set with WithRegistry, else DefaultRegistry.
+func RegistryFrom(ctx context.Context) *Registry {
+	if r, ok := ctx.Value(registryKey{}).(*Registry); ok && r != nil {
+		return r
+	}
+	return DefaultRegistry
+}
+
+// Warmup loads embedded defaults, config manifests, and local server definitions into
+// the registry of ctx. A manifest or local store that cannot be read is skipped with a
+// warning on ctx so the remaining servers stay usable.
 func Warmup(ctx context.Context, opts *config.GlobalOptions) error {
-	DefaultRegistry.Reset()
+	registry := RegistryFrom(ctx)
+	registry.Reset()
 
-	if err := loadEmbeddedDefaults(); err != nil {
+	if err := loadEmbeddedDefaults(registry); err != nil {
 		return err
 	}
 
-	for alias, location := range opts.MCPServers {
+	aliases := make([]string, 0, len(opts.MCPServers))
+	for alias := range opts.MCPServers {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	for _, alias := range aliases {
+		location := opts.MCPServers[alias]
 		manifest, err := LoadManifest(location)
 		if err != nil {
-			return fmt.Errorf("load manifest %s: %w", alias, err)
+			warnings.Add(ctx, "mcp", "skipped server %s: load manifest %s: %v", alias, location, err)
+			continue
 		}
-		DefaultRegistry.RegisterManifest(alias, manifest, SourceConfig, expandPath(location))
+		registry.RegisterManifest(alias, manifest, SourceConfig, expandPath(location))
 	}
 
-	if err := registerLocalServers(); err != nil {
-		return err
+	if err := registerLocalServers(ctx); err != nil {
+		warnings.Add(ctx, "mcp", "skipped local servers: %v", err)
 	}
 
 	return nil
@@ -13,7 +13,7 @@ var githubManifestBytes []byte
 //go:embed testdata/files.json
 var filesManifestBytes []byte
 
-func loadEmbeddedDefaults() error {
+func loadEmbeddedDefaults(registry *Registry) error {
     defaultManifests := map[string][]byte{
         "github": githubManifestBytes,
         "files":  filesManifestBytes,
@@ -24,7 +24,7 @@ func loadEmbeddedDefaults() error {
         if err != nil {
             return fmt.Errorf("parse embedded manifest %s: %w", alias, err)
         }
-        DefaultRegistry.RegisterManifest(alias, manifest, SourceEmbedded, "embedded")
+        registry.RegisterManifest(alias, manifest, SourceEmbedded, "embedded")
     }
 
     return nil
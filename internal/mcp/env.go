@@ -0,0 +1,152 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/example/sre-ai/internal/credentials"
+	"github.com/example/sre-ai/internal/httpx"
+	"github.com/example/sre-ai/internal/runtimes"
+)
+
+// Environment policies for server processes.
+const (
+	EnvPolicyInherit   = "inherit"
+	EnvPolicyClean     = "clean"
+	EnvPolicyAllowlist = "allowlist"
+)
+
+// essentialEnvKeys survive every policy so the child can still locate binaries and start.
+func essentialEnvKeys() []string {
+	if runtime.GOOS == "windows" {
+		return []string{"PATH", "SYSTEMROOT", "TEMP", "TMP"}
+	}
+	return []string{"PATH"}
+}
+
+// ValidateEnvPolicy checks a definition's env_policy and env_allow fields.
+func ValidateEnvPolicy(def ServerDefinition) error {
+	switch strings.ToLower(strings.TrimSpace(def.EnvPolicy)) {
+	case "", EnvPolicyInherit, EnvPolicyClean:
+		if len(def.EnvAllow) > 0 {
+			return fmt.Errorf("env_allow is only used with env_policy %q", EnvPolicyAllowlist)
+		}
+		return nil
+	case EnvPolicyAllowlist:
+		for _, name := range def.EnvAllow {
+			if strings.TrimSpace(name) == "" || strings.Contains(name, "=") {
+				return fmt.Errorf("invalid env_allow entry %q", name)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported env_policy %q (expected %s, %s, or %s)", def.EnvPolicy, EnvPolicyInherit, EnvPolicyClean, EnvPolicyAllowlist)
+	}
+}
+
+// ValidateCredentialRefs checks the names of credential:<name> env values. The
+// credentials themselves are only needed at launch.
+func ValidateCredentialRefs(def ServerDefinition) error {
+	for key, value := range def.Env {
+		if name, ok := credentials.ParseRef(value); ok {
+			if err := credentials.ValidateName(name); err != nil {
+				return fmt.Errorf("env %s: %w", key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// tunnelEnv opens the port forwards a definition names and returns the variables that
+// tell the process their local addresses.
+func tunnelEnv(ctx context.Context, def ServerDefinition) (map[string]string, error) {
+	env := map[string]string{}
+	for _, name := range def.Tunnels {
+		ep, ok := httpx.FromContext(ctx).Lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("tunnel %q is not an endpoint in config.yaml", name)
+		}
+		addr, err := ep.Forward(ctx)
+		if err != nil {
+			return nil, err
+		}
+		key := strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+		env["SRE_AI_TUNNEL_"+key] = addr
+	}
+	return env, nil
+}
+
+// mergeEnv builds the child environment: the parent variables the definition's policy
+// lets through, then custom overrides, with the bundled runtimes prepended to PATH.
+// Override values of the form credential:<name> are read from the credential store here,
+// at launch, so secrets never sit in servers.json. Runtime overrides come from ctx.
+func mergeEnv(ctx context.Context, def ServerDefinition, custom map[string]string) ([]string, error) {
+	if err := ValidateEnvPolicy(def); err != nil {
+		return nil, err
+	}
+	allowed := func(string) bool { return true }
+	switch strings.ToLower(strings.TrimSpace(def.EnvPolicy)) {
+	case EnvPolicyClean:
+		allowed = envMatcher(essentialEnvKeys())
+	case EnvPolicyAllowlist:
+		allowed = envMatcher(append(essentialEnvKeys(), def.EnvAllow...))
+	}
+
+	envMap := map[string]string{}
+	for _, kv := range os.Environ() {
+		if idx := strings.Index(kv, "="); idx != -1 {
+			key := kv[:idx]
+			if allowed(key) {
+				envMap[key] = kv[idx+1:]
+			}
+		}
+	}
+	for k, v := range custom {
+		if name, ok := credentials.ParseRef(v); ok {
+			secret, err := credentials.LoadToken(ctx, name)
+			if err != nil {
+				return nil, fmt.Errorf("env %s: %w", k, err)
+			}
+			v = secret
+		}
+		envMap[k] = v
+	}
+
+	envMap["PATH"] = runtimes.PrependToPath(ctx, envMap["PATH"])
+
+	env := make([]string, 0, len(envMap))
+	for k, v := range envMap {
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(env)
+	return env, nil
+}
+
+// envMatcher matches variable names exactly, or by prefix for entries ending in "*".
+// Names compare case-insensitively on Windows, where the environment is case-insensitive.
+func envMatcher(patterns []string) func(string) bool {
+	fold := runtime.GOOS == "windows"
+	return func(key string) bool {
+		for _, pattern := range patterns {
+			pattern = strings.TrimSpace(pattern)
+			name := key
+			if fold {
+				pattern, name = strings.ToUpper(pattern), strings.ToUpper(name)
+			}
+			if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+				if strings.HasPrefix(name, prefix) {
+					return true
+				}
+				continue
+			}
+			if name == pattern {
+				return true
+			}
+		}
+		return false
+	}
+}
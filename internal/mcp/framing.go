@@ -0,0 +1,243 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Limits applied to messages read from a server's stdout. A hostile or buggy server must
+// not be able to make the probe allocate unbounded buffers or spin forever on garbage.
+const (
+	// maxFrameBytes caps a single message body, whether Content-Length framed or a bare JSON line.
+	maxFrameBytes = 16 << 20
+	// maxHeaderLineBytes caps one header line (or one stray non-JSON line).
+	maxHeaderLineBytes = 8 << 10
+	// maxHeaderLines caps the number of header lines in one frame.
+	maxHeaderLines = 64
+	// maxJunkBytes caps stray output (log lines printed to stdout) skipped before a frame.
+	maxJunkBytes = 64 << 10
+	// maxMalformedMessages is how many bad frames or envelopes are skipped before giving up.
+	maxMalformedMessages = 8
+)
+
+// FramingError reports a malformed message on the server's stdout. Recoverable errors
+// leave the stream aligned on a message boundary, so the caller may keep reading.
+type FramingError struct {
+	Reason      string
+	Recoverable bool
+}
+
+func (e *FramingError) Error() string {
+	return "mcp framing: " + e.Reason
+}
+
+func framingErrorf(recoverable bool, format string, args ...interface{}) error {
+	return &FramingError{Reason: fmt.Sprintf(format, args...), Recoverable: recoverable}
+}
+
+var errLineTooLong = errors.New("line too long")
+
+// frameReader reads a server's stdout on one goroutine for the life of the process. A
+// read abandoned when its context ends keeps its place: the next call receives the
+// frame it was waiting for, instead of racing it for the same buffer.
+type frameReader struct {
+	frames chan frameResult
+	stop   chan struct{}
+	once   sync.Once
+	// err is the error that ended the stream, set before frames is closed.
+	err error
+}
+
+type frameResult struct {
+	msg []byte
+	err error
+}
+
+func newFrameReader(r io.Reader) *frameReader {
+	fr := &frameReader{frames: make(chan frameResult), stop: make(chan struct{})}
+	go fr.run(bufio.NewReader(r))
+	return fr
+}
+
+func (fr *frameReader) run(reader *bufio.Reader) {
+	for {
+		msg, err := readFrame(reader)
+		var frameErr *FramingError
+		if err != nil && !(errors.As(err, &frameErr) && frameErr.Recoverable) {
+			fr.err = err
+			close(fr.frames)
+			return
+		}
+		select {
+		case fr.frames <- frameResult{msg: msg, err: err}:
+		case <-fr.stop:
+			return
+		}
+	}
+}
+
+// next returns the next message from the server. It accepts Content-Length framing and,
+// as a recovery path, a bare JSON object on its own line. Stray non-JSON lines before
+// the headers are skipped up to maxJunkBytes.
+func (fr *frameReader) next(ctx context.Context) ([]byte, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res, ok := <-fr.frames:
+		if !ok {
+			return nil, fr.err
+		}
+		return res.msg, res.err
+	}
+}
+
+// close stops the goroutine once its current read returns, which it does when the
+// server's stdout is closed.
+func (fr *frameReader) close() {
+	fr.once.Do(func() { close(fr.stop) })
+}
+
+func readFrame(reader *bufio.Reader) ([]byte, error) {
+	length := -1
+	headers := 0
+	junk := 0
+	for {
+		limit := maxHeaderLineBytes
+		if headers == 0 {
+			if first, err := reader.Peek(1); err == nil && first[0] == '{' {
+				limit = maxFrameBytes
+			}
+		}
+		raw, err := readBoundedLine(reader, limit)
+		if errors.Is(err, errLineTooLong) {
+			if headers > 0 {
+				return nil, framingErrorf(false, "header line exceeds %d bytes", limit)
+			}
+			return nil, framingErrorf(true, "line exceeds %d bytes", limit)
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) && (headers > 0 || len(raw) > 0) {
+				return nil, framingErrorf(false, "stream ended inside message headers: %v", io.ErrUnexpectedEOF)
+			}
+			return nil, err
+		}
+		line := strings.TrimSpace(string(raw))
+
+		if line == "" {
+			if length >= 0 {
+				break
+			}
+			if headers > 0 {
+				// Headers without a length: the body is not delimited, so resync on the next line.
+				return nil, framingErrorf(true, "missing Content-Length header")
+			}
+			continue
+		}
+
+		if headers == 0 && strings.HasPrefix(line, "{") {
+			return []byte(line), nil
+		}
+
+		name, value, isHeader := strings.Cut(line, ":")
+		if !isHeader || strings.ContainsAny(name, " \t{") {
+			if headers > 0 {
+				return nil, framingErrorf(false, "malformed header line %q", truncateForError(line))
+			}
+			junk += len(raw)
+			if junk > maxJunkBytes {
+				return nil, framingErrorf(false, "more than %d bytes of non-protocol output before a message", maxJunkBytes)
+			}
+			continue
+		}
+		headers++
+		if headers > maxHeaderLines {
+			return nil, framingErrorf(false, "more than %d header lines", maxHeaderLines)
+		}
+		if !strings.EqualFold(strings.TrimSpace(name), "content-length") {
+			continue
+		}
+		l, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return nil, framingErrorf(false, "invalid content-length header %q", truncateForError(line))
+		}
+		switch {
+		case l < 0:
+			return nil, framingErrorf(false, "negative content-length %d", l)
+		case l > maxFrameBytes:
+			return nil, framingErrorf(false, "content-length %d exceeds limit of %d bytes", l, maxFrameBytes)
+		case length >= 0 && int64(length) != l:
+			return nil, framingErrorf(false, "conflicting content-length headers %d and %d", length, l)
+		}
+		length = int(l)
+	}
+
+	buf := make([]byte, length)
+	if n, err := io.ReadFull(reader, buf); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, framingErrorf(false, "stream ended after %d of %d body bytes", n, length)
+		}
+		return nil, err
+	}
+	return buf, nil
+}
+
+// readBoundedLine reads through the next newline without buffering more than limit bytes.
+// An overlong line is drained and reported as errLineTooLong so the stream stays aligned.
+func readBoundedLine(reader *bufio.Reader, limit int) ([]byte, error) {
+	var line []byte
+	tooLong := false
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if !tooLong {
+			if len(line)+len(chunk) > limit {
+				tooLong = true
+				line = nil
+			} else {
+				line = append(line, chunk...)
+			}
+		}
+		switch {
+		case err == nil:
+			if tooLong {
+				return nil, errLineTooLong
+			}
+			return line, nil
+		case errors.Is(err, bufio.ErrBufferFull):
+			continue
+		default:
+			if tooLong {
+				return nil, errLineTooLong
+			}
+			return line, err
+		}
+	}
+}
+
+// decodeEnvelope parses a message body. Invalid UTF-8 is replaced rather than rejected so
+// one bad log string from the server does not abort a probe.
+func decodeEnvelope(msg []byte) (jsonrpcEnvelope, error) {
+	if !utf8.Valid(msg) {
+		msg = bytes.ToValidUTF8(msg, []byte("\uFFFD"))
+	}
+	var env jsonrpcEnvelope
+	if err := json.Unmarshal(msg, &env); err != nil {
+		return jsonrpcEnvelope{}, err
+	}
+	return env, nil
+}
+
+func truncateForError(line string) string {
+	if len(line) > 80 {
+		return line[:77] + "..."
+	}
+	return line
+}
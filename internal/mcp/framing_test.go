@@ -0,0 +1,95 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+var frameSeeds = []string{
+	"Content-Length: 17\r\n\r\n{\"jsonrpc\":\"2.0\"}",
+	"Content-Length: 17\r\nContent-Type: application/json\r\n\r\n{\"jsonrpc\":\"2.0\"}",
+	"{\"jsonrpc\":\"2.0\",\"id\":1,\"result\":{}}\n",
+	"starting server...\n{\"jsonrpc\":\"2.0\",\"id\":1}\n",
+	"Content-Length: 999999999\r\n\r\n{}",
+	"Content-Length: 40\r\n\r\n{\"jsonrpc\":",
+	"Content-Length: -1\r\n\r\n",
+	"Content-Length: abc\r\n\r\n{}",
+	"Content-Length 17\r\n\r\n{\"jsonrpc\":\"2.0\"}",
+	"Content-Length: 2\r\nContent-Length: 3\r\n\r\n{}",
+	strings.Repeat("X-Header: y\r\n", 200) + "\r\n",
+	"\r\n\r\n\r\n",
+	"",
+}
+
+func FuzzReadFrame(f *testing.F) {
+	for _, seed := range frameSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		reader := bufio.NewReader(bytes.NewReader(data))
+		// Every successful read consumes input, so this bounds the loop well above
+		// what any input can need.
+		for i := 0; i <= len(data); i++ {
+			msg, err := readFrame(reader)
+			if len(msg) > maxFrameBytes {
+				t.Fatalf("readFrame returned %d bytes, over the %d byte limit", len(msg), maxFrameBytes)
+			}
+			var frameErr *FramingError
+			if err != nil && !(errors.As(err, &frameErr) && frameErr.Recoverable) {
+				return
+			}
+		}
+	})
+}
+
+func FuzzDecodeEnvelope(f *testing.F) {
+	for _, seed := range []string{
+		`{"jsonrpc":"2.0","id":1,"result":{"tools":[]}}`,
+		`{"jsonrpc":"2.0","id":"abc","error":{"code":-32601,"message":"no such method"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/progress","params":{}}`,
+		`{"jsonrpc":"2.0","id":null}`,
+		`[1,2,3]`,
+		`{"id":`,
+		`null`,
+		``,
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = decodeEnvelope(data)
+	})
+}
+
+func TestFrameReaderKeepsAFrameAcrossACancelledRead(t *testing.T) {
+	pr, pw := io.Pipe()
+	fr := newFrameReader(pr)
+	defer fr.close()
+	defer pw.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := fr.next(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("next with nothing written = %v; want a deadline error", err)
+	}
+
+	go func() {
+		_, _ = io.WriteString(pw, "Content-Length: 8\r\n\r\n{\"id\":1}{\"id\":2}\n")
+	}()
+	for _, want := range []string{`{"id":1}`, `{"id":2}`} {
+		msg, err := fr.next(context.Background())
+		if err != nil || string(msg) != want {
+			t.Fatalf("next = %q, %v; want %s", msg, err, want)
+		}
+	}
+
+	_ = pw.Close()
+	if _, err := fr.next(context.Background()); err == nil {
+		t.Fatal("next after the server closed stdout returned no error")
+	}
+}
@@ -0,0 +1,97 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/example/sre-ai/internal/config"
+)
+
+// maxRecentRestarts bounds the restart times kept per server for flap counts.
+const maxRecentRestarts = 50
+
+// Health is the restart history of one local server across sessions, for mcp ls.
+// LastError is set when the latest restart itself failed.
+type Health struct {
+	Restarts    int         `json:"restarts"`
+	LastRestart time.Time   `json:"last_restart"`
+	LastReason  string      `json:"last_reason,omitempty"`
+	LastError   string      `json:"last_error,omitempty"`
+	Recent      []time.Time `json:"recent,omitempty"`
+}
+
+// Flaps counts the recorded restarts after since.
+func (h Health) Flaps(since time.Time) int {
+	n := 0
+	for _, at := range h.Recent {
+		if at.After(since) {
+			n++
+		}
+	}
+	return n
+}
+
+func healthPath(ctx context.Context) (string, error) {
+	base, err := config.ConfigDir(ctx)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "mcp", "health.json"), nil
+}
+
+// LoadHealth returns the recorded restart history by alias; servers that never
+// restarted have no entry.
+func LoadHealth(ctx context.Context) (map[string]Health, error) {
+	path, err := healthPath(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]Health{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	health := map[string]Health{}
+	if err := json.Unmarshal(data, &health); err != nil {
+		return nil, err
+	}
+	return health, nil
+}
+
+// recordRestart adds a restart of alias at at, why it was needed, and restartErr when
+// the server did not come back.
+func recordRestart(ctx context.Context, alias string, at time.Time, reason, restartErr error) error {
+	health, err := LoadHealth(ctx)
+	if err != nil {
+		// A corrupt file only loses history; start it over.
+		health = map[string]Health{}
+	}
+	h := health[alias]
+	h.Restarts++
+	h.LastRestart = at.UTC()
+	h.LastReason = reason.Error()
+	h.LastError = ""
+	if restartErr != nil {
+		h.LastError = restartErr.Error()
+	}
+	h.Recent = append(h.Recent, at.UTC())
+	if len(h.Recent) > maxRecentRestarts {
+		h.Recent = h.Recent[len(h.Recent)-maxRecentRestarts:]
+	}
+	health[alias] = h
+	path, err := healthPath(ctx)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(health, "", "  ")
+	if err != nil {
+		return err
+	}
+	return config.WriteFile(path, append(data, '\n'))
+}
@@ -7,15 +7,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"os"
 	"os/exec"
-	"path/filepath"
-	"runtime"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/example/sre-ai/internal/runtimes"
 )
 
 type Logger interface {
@@ -24,11 +22,84 @@ type Logger interface {
 
 // RunLocalCommand executes a configured MCP server command with optional arguments and environment overrides.
 func RunLocalCommand(ctx context.Context, alias string, extraArgs []string, stdin string, extraEnv map[string]string, logger Logger) (string, string, int, error) {
-	def, err := GetLocalServer(alias)
+	stdout, stderr, code, _, err := RunLocalCommandWithOptions(ctx, alias, RunOptions{Args: extraArgs, Stdin: stdin, Env: extraEnv}, logger)
+	return stdout, stderr, code, err
+}
+
+// RunOptions customise one invocation of a local server command.
+type RunOptions struct {
+	Args  []string
+	Stdin string
+	Env   map[string]string
+	// Workdir replaces the definition's workdir for this invocation; it may contain placeholders.
+	Workdir string
+	// Vars extend the placeholders available to workdir templates (see resolveWorkdir).
+	Vars map[string]interface{}
+	// RawCommand, when set, is appended to the server's command line and the whole line
+	// runs through the platform shell (see ShellCommand). Args must be empty.
+	RawCommand string
+}
+
+// RunLocalCommandWithOptions is RunLocalCommand with a per-invocation workdir and template
+// values. It also returns the user and system CPU time of the command's process.
+func RunLocalCommandWithOptions(ctx context.Context, alias string, opts RunOptions, logger Logger) (string, string, int, time.Duration, error) {
+	def, err := GetLocalServer(ctx, alias)
+	if err != nil {
+		return "", "", 0, 0, err
+	}
+	workdir := def.Workdir
+	if strings.TrimSpace(opts.Workdir) != "" {
+		workdir = opts.Workdir
+	}
+	def.Workdir, err = resolveWorkdir(workdir, opts.Vars)
 	if err != nil {
-		return "", "", 0, err
+		return "", "", 0, 0, fmt.Errorf("server %s: %w", alias, err)
 	}
-	return runCommandWithDefinition(ctx, alias, def, extraArgs, stdin, extraEnv, logger)
+	if strings.TrimSpace(opts.RawCommand) != "" {
+		if len(opts.Args) > 0 {
+			return "", "", 0, 0, fmt.Errorf("server %s: raw command and args are mutually exclusive", alias)
+		}
+		if def.Command == "" {
+			return "", "", 0, 0, errors.New("server command is empty")
+		}
+		def.Command, def.Args = ShellCommand(def.Command, def.Args, opts.RawCommand)
+	}
+	return runCommandWithDefinition(ctx, alias, def, opts.Args, opts.Stdin, opts.Env, logger)
+}
+
+// RemoteCommand resolves the command line an invocation of alias runs, for running it on
+// another machine: the definition's command and args plus opts.Args (or, with a raw
+// command, the whole line through /bin/sh), and the definition's env plus opts.Env. The
+// definition's workdir, tunnels, and env policy describe this machine and are not used.
+func RemoteCommand(ctx context.Context, alias string, opts RunOptions) ([]string, map[string]string, error) {
+	def, err := GetLocalServer(ctx, alias)
+	if err != nil {
+		return nil, nil, err
+	}
+	if def.Command == "" {
+		return nil, nil, errors.New("server command is empty")
+	}
+	argv := append([]string{def.Command}, def.Args...)
+	if strings.TrimSpace(opts.RawCommand) != "" {
+		if len(opts.Args) > 0 {
+			return nil, nil, fmt.Errorf("server %s: raw command and args are mutually exclusive", alias)
+		}
+		parts := make([]string, 0, len(argv)+1)
+		for _, part := range argv {
+			parts = append(parts, quotePOSIX(part))
+		}
+		argv = []string{"/bin/sh", "-c", strings.Join(append(parts, opts.RawCommand), " ")}
+	} else {
+		argv = append(argv, opts.Args...)
+	}
+	env := make(map[string]string, len(def.Env)+len(opts.Env))
+	for k, v := range def.Env {
+		env[k] = v
+	}
+	for k, v := range opts.Env {
+		env[k] = v
+	}
+	return argv, env, nil
 }
 
 // TestLocalServer attempts to start the configured command and ensures it can be launched.
@@ -45,31 +116,49 @@ func TestLocalServerWithLogger(ctx context.Context, alias string, logger Logger)
 
 // ToolSummary describes a tool exposed by a local MCP server.
 type ToolSummary struct {
-	Name        string                 `json:"name"`
-	Title       string                 `json:"title,omitempty"`
-	Description string                 `json:"description,omitempty"`
-	InputSchema map[string]interface{} `json:"inputSchema,omitempty"`
-	Annotations map[string]interface{} `json:"annotations,omitempty"`
+	Name         string                 `json:"name"`
+	Title        string                 `json:"title,omitempty"`
+	Description  string                 `json:"description,omitempty"`
+	InputSchema  map[string]interface{} `json:"inputSchema,omitempty"`
+	OutputSchema map[string]interface{} `json:"outputSchema,omitempty"`
+	Annotations  map[string]interface{} `json:"annotations,omitempty"`
 }
 
 // Notification captures a server notification observed during probing.
+// Count is how many identical notifications were coalesced into this entry.
 type Notification struct {
 	Method string `json:"method"`
 	Detail string `json:"detail,omitempty"`
+	Count  int    `json:"count"`
 }
 
 // ProbeResult contains metadata collected from a probe run.
 type ProbeResult struct {
-	Alias           string                 `json:"alias"`
-	ServerName      string                 `json:"serverName,omitempty"`
-	ServerVersion   string                 `json:"serverVersion,omitempty"`
-	ProtocolVersion string                 `json:"protocolVersion,omitempty"`
-	Instructions    string                 `json:"instructions,omitempty"`
-	Capabilities    map[string]interface{} `json:"capabilities,omitempty"`
-	Tools           []ToolSummary          `json:"tools,omitempty"`
-	Notifications   []Notification         `json:"notifications,omitempty"`
-	Duration        time.Duration          `json:"duration"`
-	Stderr          string                 `json:"stderr,omitempty"`
+	Alias                  string                 `json:"alias"`
+	ServerName             string                 `json:"serverName,omitempty"`
+	ServerVersion          string                 `json:"serverVersion,omitempty"`
+	ProtocolVersion        string                 `json:"protocolVersion,omitempty"`
+	OfferedProtocolVersion string                 `json:"offeredProtocolVersion,omitempty"`
+	Instructions           string                 `json:"instructions,omitempty"`
+	Capabilities           map[string]interface{} `json:"capabilities,omitempty"`
+	Tools                  []ToolSummary          `json:"tools,omitempty"`
+	Notifications          []Notification         `json:"notifications,omitempty"`
+	NotificationStats      NotificationStats      `json:"notificationStats"`
+	ResourceCount          *int                   `json:"resourceCount,omitempty"`
+	PromptCount            *int                   `json:"promptCount,omitempty"`
+	Timings                ProbeTimings           `json:"timings"`
+	Duration               time.Duration          `json:"duration"`
+	Stderr                 string                 `json:"stderr,omitempty"`
+}
+
+// ProbeTimings breaks a probe down by phase. Zero values mean the phase was skipped or unsupported.
+type ProbeTimings struct {
+	Spawn         time.Duration `json:"spawn"`
+	Initialize    time.Duration `json:"initialize"`
+	ToolsList     time.Duration `json:"toolsList"`
+	ResourcesList time.Duration `json:"resourcesList,omitempty"`
+	PromptsList   time.Duration `json:"promptsList,omitempty"`
+	Ping          time.Duration `json:"ping,omitempty"`
 }
 
 // ProbeLocalServer connects to a local MCP server using the stdio transport and reports its tooling.
@@ -101,16 +190,23 @@ type jsonrpcError struct {
 func probeLocalServer(ctx context.Context, alias string, logger Logger) (*ProbeResult, error) {
 	start := time.Now()
 
-	def, err := GetLocalServer(alias)
+	def, err := GetLocalServer(ctx, alias)
 	if err != nil {
 		return nil, err
 	}
 	if def.Command == "" {
 		return nil, errors.New("server command is empty")
 	}
+	def.Workdir, err = resolveWorkdir(def.Workdir, nil)
+	if err != nil {
+		return nil, fmt.Errorf("server %s: %w", alias, err)
+	}
 
 	args := append([]string{}, def.Args...)
-	envMap := map[string]string{}
+	envMap, err := tunnelEnv(ctx, def)
+	if err != nil {
+		return nil, fmt.Errorf("server %s: %w", alias, err)
+	}
 	for k, v := range def.Env {
 		envMap[k] = v
 	}
@@ -127,7 +223,10 @@ func probeLocalServer(ctx context.Context, alias string, logger Logger) (*ProbeR
 	if def.Workdir != "" {
 		cmd.Dir = def.Workdir
 	}
-	cmd.Env = mergeEnv(envMap)
+	cmd.Env, err = mergeEnv(ctx, def, envMap)
+	if err != nil {
+		return nil, fmt.Errorf("server %s: %w", alias, err)
+	}
 
 	stdoutPipe, err := cmd.StdoutPipe()
 	if err != nil {
@@ -141,14 +240,17 @@ func probeLocalServer(ctx context.Context, alias string, logger Logger) (*ProbeR
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
+	spawnStart := time.Now()
 	if err := cmd.Start(); err != nil {
 		return nil, fmt.Errorf("failed to start %s: %w", alias, err)
 	}
+	spawned := time.Since(spawnStart)
 
 	done := make(chan error, 1)
 	go func() { done <- cmd.Wait() }()
 
-	reader := bufio.NewReader(stdoutPipe)
+	reader := newFrameReader(stdoutPipe)
+	defer reader.close()
 	writer := bufio.NewWriter(stdinPipe)
 
 	success := false
@@ -173,8 +275,19 @@ func probeLocalServer(ctx context.Context, alias string, logger Logger) (*ProbeR
 	}()
 
 	responses := make(map[string]jsonrpcEnvelope)
-	notifications := make([]Notification, 0, 4)
+	notifications := newNotificationLog()
 	result := &ProbeResult{Alias: alias}
+	result.Timings.Spawn = spawned
+
+	offered := PreferredProtocolVersion
+	if def.ProtocolVersion != "" {
+		offered = def.ProtocolVersion
+	}
+	offeredRev, ok := LookupProtocolRevision(offered)
+	if !ok {
+		return nil, fmt.Errorf("server %s pins unsupported protocol version %s (supported: %s)", alias, offered, strings.Join(SupportedProtocolVersions(), ", "))
+	}
+	result.OfferedProtocolVersion = offered
 
 	requestID := 1
 	initReq := map[string]interface{}{
@@ -182,23 +295,22 @@ func probeLocalServer(ctx context.Context, alias string, logger Logger) (*ProbeR
 		"id":      requestID,
 		"method":  "initialize",
 		"params": map[string]interface{}{
-			"protocolVersion": "2025-06-18",
-			"clientInfo": map[string]string{
-				"name":    "sre-ai",
-				"version": "dev",
-			},
-			"capabilities": map[string]interface{}{},
+			"protocolVersion": offered,
+			"clientInfo":      clientInfo(offeredRev),
+			"capabilities":    map[string]interface{}{},
 		},
 	}
 
+	initStart := time.Now()
 	if err := sendJSONMessage(writer, initReq); err != nil {
 		return nil, annotateProbeError(err, &stderr)
 	}
 
-	initEnv, err := awaitResponse(ctx, reader, writer, strconv.Itoa(requestID), responses, &notifications, done, alias, logger)
+	initEnv, err := awaitResponse(ctx, reader, writer, strconv.Itoa(requestID), responses, notifications, done, alias, logger)
 	if err != nil {
 		return nil, annotateProbeError(err, &stderr)
 	}
+	result.Timings.Initialize = time.Since(initStart)
 	if initEnv.Error != nil {
 		return nil, annotateProbeError(fmt.Errorf("initialize failed: %s", initEnv.Error.Message), &stderr)
 	}
@@ -216,9 +328,17 @@ func probeLocalServer(ctx context.Context, alias string, logger Logger) (*ProbeR
 		return nil, annotateProbeError(fmt.Errorf("decode initialize result: %w", err), &stderr)
 	}
 
+	revision, err := negotiateProtocol(offered, initData.ProtocolVersion)
+	if err != nil {
+		return nil, annotateProbeError(err, &stderr)
+	}
+	if logger != nil && revision.Version != offered {
+		logger.Printf("mcp probe alias=%s accepted counter-offer protocol=%s offered=%s", alias, revision.Version, offered)
+	}
+
 	result.Capabilities = initData.Capabilities
 	result.Instructions = strings.TrimSpace(initData.Instructions)
-	result.ProtocolVersion = initData.ProtocolVersion
+	result.ProtocolVersion = revision.Version
 	result.ServerName = initData.ServerInfo.Name
 	result.ServerVersion = initData.ServerInfo.Version
 
@@ -230,73 +350,121 @@ func probeLocalServer(ctx context.Context, alias string, logger Logger) (*ProbeR
 		return nil, annotateProbeError(err, &stderr)
 	}
 
-	cursor := ""
-	for {
+	call := func(method string, params map[string]interface{}) (jsonrpcEnvelope, error) {
 		requestID++
 		req := map[string]interface{}{
 			"jsonrpc": "2.0",
 			"id":      requestID,
-			"method":  "tools/list",
+			"method":  method,
 		}
-		if cursor != "" {
-			req["params"] = map[string]interface{}{"cursor": cursor}
+		if params != nil {
+			req["params"] = params
 		}
-
 		if err := sendJSONMessage(writer, req); err != nil {
-			return nil, annotateProbeError(err, &stderr)
-		}
-
-		resp, err := awaitResponse(ctx, reader, writer, strconv.Itoa(requestID), responses, &notifications, done, alias, logger)
-		if err != nil {
-			return nil, annotateProbeError(err, &stderr)
-		}
-		if resp.Error != nil {
-			return nil, annotateProbeError(fmt.Errorf("tools/list failed: %s", resp.Error.Message), &stderr)
-		}
-
-		var listResult struct {
-			Tools      []map[string]interface{} `json:"tools"`
-			NextCursor string                   `json:"nextCursor"`
-		}
-		if err := json.Unmarshal(resp.Result, &listResult); err != nil {
-			return nil, annotateProbeError(fmt.Errorf("decode tools/list: %w", err), &stderr)
+			return jsonrpcEnvelope{}, err
 		}
+		return awaitResponse(ctx, reader, writer, strconv.Itoa(requestID), responses, notifications, done, alias, logger)
+	}
 
-		for _, tool := range listResult.Tools {
-			summary := ToolSummary{}
-			if name, ok := tool["name"].(string); ok {
-				summary.Name = name
+	// listAll follows nextCursor pagination and returns every item under key.
+	listAll := func(method, key string) ([]map[string]interface{}, error) {
+		var items []map[string]interface{}
+		cursor := ""
+		for {
+			var params map[string]interface{}
+			if cursor != "" {
+				params = map[string]interface{}{"cursor": cursor}
+			}
+			resp, err := call(method, params)
+			if err != nil {
+				return nil, err
 			}
-			if title, ok := tool["title"].(string); ok {
-				summary.Title = title
+			if resp.Error != nil {
+				return nil, fmt.Errorf("%s failed: %s", method, resp.Error.Message)
 			}
-			if desc, ok := tool["description"].(string); ok {
-				summary.Description = desc
+			var page map[string]json.RawMessage
+			if err := json.Unmarshal(resp.Result, &page); err != nil {
+				return nil, fmt.Errorf("decode %s: %w", method, err)
 			}
-			if annotations, ok := tool["annotations"].(map[string]interface{}); ok {
-				summary.Annotations = annotations
-				if summary.Title == "" {
-					if title, ok := annotations["title"].(string); ok {
-						summary.Title = title
-					}
+			var batch []map[string]interface{}
+			if raw, ok := page[key]; ok {
+				if err := json.Unmarshal(raw, &batch); err != nil {
+					return nil, fmt.Errorf("decode %s: %w", method, err)
 				}
 			}
-			if schema, ok := tool["inputSchema"].(map[string]interface{}); ok {
-				summary.InputSchema = schema
+			items = append(items, batch...)
+			var next string
+			if raw, ok := page["nextCursor"]; ok {
+				_ = json.Unmarshal(raw, &next)
+			}
+			if next == "" {
+				return items, nil
+			}
+			cursor = next
+		}
+	}
+
+	phase := time.Now()
+	tools, err := listAll("tools/list", "tools")
+	if err != nil {
+		return nil, annotateProbeError(err, &stderr)
+	}
+	result.Timings.ToolsList = time.Since(phase)
+
+	for _, tool := range tools {
+		result.Tools = append(result.Tools, decodeTool(revision, tool))
+	}
+
+	// Resources and prompts are optional capabilities; failures are logged, not fatal.
+	if _, ok := result.Capabilities["resources"]; ok {
+		phase = time.Now()
+		items, err := listAll("resources/list", "resources")
+		if err != nil {
+			if logger != nil {
+				logger.Printf("mcp probe alias=%s resources/list error=%v", alias, err)
+			}
+		} else {
+			count := len(items)
+			result.ResourceCount = &count
+			result.Timings.ResourcesList = time.Since(phase)
+		}
+	}
+	if _, ok := result.Capabilities["prompts"]; ok {
+		phase = time.Now()
+		items, err := listAll("prompts/list", "prompts")
+		if err != nil {
+			if logger != nil {
+				logger.Printf("mcp probe alias=%s prompts/list error=%v", alias, err)
 			}
-			result.Tools = append(result.Tools, summary)
+		} else {
+			count := len(items)
+			result.PromptCount = &count
+			result.Timings.PromptsList = time.Since(phase)
 		}
+	}
 
-		if listResult.NextCursor == "" {
-			break
+	phase = time.Now()
+	if resp, err := call("ping", nil); err != nil {
+		if logger != nil {
+			logger.Printf("mcp probe alias=%s ping error=%v", alias, err)
 		}
-		cursor = listResult.NextCursor
+	} else if resp.Error != nil {
+		if logger != nil {
+			logger.Printf("mcp probe alias=%s ping unsupported: %s", alias, resp.Error.Message)
+		}
+	} else {
+		result.Timings.Ping = time.Since(phase)
 	}
 
-	result.Notifications = notifications
+	result.Notifications = notifications.items
+	result.NotificationStats = notifications.stats
 	result.Duration = time.Since(start)
 	result.Stderr = strings.TrimSpace(stderr.String())
 
+	// The cache is a convenience for pinned workflows; failing to write it is not a probe failure.
+	if err := SaveCatalog(ctx, NewCatalog(alias, result.ServerName, result.ServerVersion, result.Tools)); err != nil && logger != nil {
+		logger.Printf("mcp probe alias=%s catalog not cached: %v", alias, err)
+	}
 	success = true
 	return result, nil
 }
@@ -318,11 +486,11 @@ func sendJSONMessage(w *bufio.Writer, payload interface{}) error {
 
 func awaitResponse(
 	ctx context.Context,
-	reader *bufio.Reader,
+	reader *frameReader,
 	writer *bufio.Writer,
 	expectID string,
 	pending map[string]jsonrpcEnvelope,
-	notifications *[]Notification,
+	notifications *notificationLog,
 	done <-chan error,
 	alias string,
 	logger Logger,
@@ -332,6 +500,7 @@ func awaitResponse(
 		return env, nil
 	}
 
+	malformed := 0
 	for {
 		select {
 		case <-ctx.Done():
@@ -344,14 +513,29 @@ func awaitResponse(
 		default:
 		}
 
-		msg, err := readFramedMessage(ctx, reader)
+		msg, err := reader.next(ctx)
 		if err != nil {
+			var frameErr *FramingError
+			if errors.As(err, &frameErr) && frameErr.Recoverable && malformed < maxMalformedMessages {
+				malformed++
+				if logger != nil {
+					logger.Printf("mcp probe alias=%s skipped malformed frame: %v", alias, err)
+				}
+				continue
+			}
 			return jsonrpcEnvelope{}, err
 		}
 
-		var env jsonrpcEnvelope
-		if err := json.Unmarshal(msg, &env); err != nil {
-			return jsonrpcEnvelope{}, fmt.Errorf("decode jsonrpc envelope: %w", err)
+		env, err := decodeEnvelope(msg)
+		if err != nil {
+			if malformed < maxMalformedMessages {
+				malformed++
+				if logger != nil {
+					logger.Printf("mcp probe alias=%s skipped undecodable message: %v", alias, err)
+				}
+				continue
+			}
+			return jsonrpcEnvelope{}, fmt.Errorf("decode jsonrpc envelope: %w (after %d malformed messages)", err, malformed)
 		}
 
 		if env.ID != nil {
@@ -377,83 +561,19 @@ func awaitResponse(
 
 		if env.Method != "" {
 			detail := compactJSONRaw(env.Params)
-			if len(detail) > 400 {
-				detail = detail[:397] + "..."
-			}
-			*notifications = append(*notifications, Notification{Method: env.Method, Detail: detail})
+			before := notifications.stats
+			notifications.add(env.Method, detail)
 			if logger != nil {
-				logger.Printf("mcp probe alias=%s notify method=%s detail=%s", alias, env.Method, detail)
-			}
-			continue
-		}
-	}
-}
-
-func readFramedMessage(ctx context.Context, reader *bufio.Reader) ([]byte, error) {
-	length := -1
-	for {
-		line, err := readLineWithContext(ctx, reader)
-		if err != nil {
-			return nil, err
-		}
-		line = strings.TrimSpace(line)
-		if line == "" {
-			if length >= 0 {
-				break
+				// Log each distinct notification once, and the first drop, so floods stay quiet.
+				switch {
+				case notifications.stats.Kept > before.Kept:
+					logger.Printf("mcp probe alias=%s notify method=%s detail=%s", alias, env.Method, notifications.items[len(notifications.items)-1].Detail)
+				case notifications.stats.Dropped == 1 && before.Dropped == 0:
+					logger.Printf("mcp probe alias=%s notification limit reached; further notifications are counted only", alias)
+				}
 			}
 			continue
 		}
-		if strings.HasPrefix(strings.ToLower(line), "content-length:") {
-			value := strings.TrimSpace(line[len("content-length:"):])
-			l, err := strconv.Atoi(value)
-			if err != nil {
-				return nil, fmt.Errorf("invalid content-length header: %s", line)
-			}
-			length = l
-		}
-	}
-	if length < 0 {
-		return nil, errors.New("missing Content-Length header")
-	}
-	buf := make([]byte, length)
-	if err := readFullWithContext(ctx, reader, buf); err != nil {
-		return nil, err
-	}
-	return buf, nil
-}
-
-func readLineWithContext(ctx context.Context, reader *bufio.Reader) (string, error) {
-	type result struct {
-		line string
-		err  error
-	}
-	ch := make(chan result, 1)
-	go func() {
-		line, err := reader.ReadString('\n')
-		ch <- result{line: line, err: err}
-	}()
-	select {
-	case <-ctx.Done():
-		return "", ctx.Err()
-	case res := <-ch:
-		return res.line, res.err
-	}
-}
-
-func readFullWithContext(ctx context.Context, reader *bufio.Reader, buf []byte) error {
-	type result struct {
-		err error
-	}
-	ch := make(chan result, 1)
-	go func() {
-		_, err := io.ReadFull(reader, buf)
-		ch <- result{err: err}
-	}()
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case res := <-ch:
-		return res.err
 	}
 }
 
@@ -514,9 +634,9 @@ func compactJSONRaw(raw json.RawMessage) string {
 	}
 	return buf.String()
 }
-func runCommandWithDefinition(ctx context.Context, alias string, def ServerDefinition, extraArgs []string, stdin string, extraEnv map[string]string, logger Logger) (string, string, int, error) {
+func runCommandWithDefinition(ctx context.Context, alias string, def ServerDefinition, extraArgs []string, stdin string, extraEnv map[string]string, logger Logger) (string, string, int, time.Duration, error) {
 	if def.Command == "" {
-		return "", "", 0, errors.New("server command is empty")
+		return "", "", 0, 0, errors.New("server command is empty")
 	}
 
 	if logger != nil {
@@ -531,7 +651,7 @@ func runCommandWithDefinition(ctx context.Context, alias string, def ServerDefin
 
 	cmd, stdout, stderr, err := buildCommand(ctx, alias, def, extraArgs, stdin, extraEnv, logger)
 	if err != nil {
-		return "", "", 0, err
+		return "", "", 0, 0, err
 	}
 
 	err = cmd.Run()
@@ -542,12 +662,12 @@ func runCommandWithDefinition(ctx context.Context, alias string, def ServerDefin
 			if logger != nil {
 				logger.Printf("mcp command error alias=%s exit=%d stderr=%s", alias, exitCode, tail(stderr.String(), 400))
 			}
-			return stdout.String(), stderr.String(), exitCode, fmt.Errorf("%s exited with %d: %s", alias, exitCode, tail(stderr.String(), 400))
+			return stdout.String(), stderr.String(), exitCode, processCPU(cmd), fmt.Errorf("%s exited with %d: %s", alias, exitCode, tail(stderr.String(), 400))
 		}
 		if logger != nil {
 			logger.Printf("mcp command failed alias=%s err=%v", alias, err)
 		}
-		return stdout.String(), stderr.String(), exitCode, fmt.Errorf("unable to execute %s: %w", alias, err)
+		return stdout.String(), stderr.String(), exitCode, processCPU(cmd), fmt.Errorf("unable to execute %s: %w", alias, err)
 	}
 
 	if cmd.ProcessState != nil {
@@ -564,7 +684,15 @@ func runCommandWithDefinition(ctx context.Context, alias string, def ServerDefin
 		}
 	}
 
-	return stdout.String(), stderr.String(), exitCode, nil
+	return stdout.String(), stderr.String(), exitCode, processCPU(cmd), nil
+}
+
+// processCPU is the user and system time of a command that has exited, or 0.
+func processCPU(cmd *exec.Cmd) time.Duration {
+	if cmd.ProcessState == nil {
+		return 0
+	}
+	return cmd.ProcessState.UserTime() + cmd.ProcessState.SystemTime()
 }
 
 func buildCommand(ctx context.Context, alias string, def ServerDefinition, extraArgs []string, stdin string, extraEnv map[string]string, logger Logger) (*exec.Cmd, *bytes.Buffer, *bytes.Buffer, error) {
@@ -573,7 +701,10 @@ func buildCommand(ctx context.Context, alias string, def ServerDefinition, extra
 		args = append(args, extraArgs...)
 	}
 
-	envMap := map[string]string{}
+	envMap, err := tunnelEnv(ctx, def)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("server %s: %w", alias, err)
+	}
 	for k, v := range def.Env {
 		envMap[k] = v
 	}
@@ -592,7 +723,10 @@ func buildCommand(ctx context.Context, alias string, def ServerDefinition, extra
 	if def.Workdir != "" {
 		cmd.Dir = def.Workdir
 	}
-	mergedEnv := mergeEnv(envMap)
+	mergedEnv, err := mergeEnv(ctx, def, envMap)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("server %s: %w", alias, err)
+	}
 	if logger != nil {
 		logger.Printf("mcp env alias=%s mergedKeys=%s", alias, envKeyList(mergedEnv))
 	}
@@ -607,122 +741,6 @@ func buildCommand(ctx context.Context, alias string, def ServerDefinition, extra
 	return cmd, &stdout, &stderr, nil
 }
 
-func mergeEnv(custom map[string]string) []string {
-	envMap := map[string]string{}
-	for _, kv := range os.Environ() {
-		if idx := strings.Index(kv, "="); idx != -1 {
-			key := kv[:idx]
-			value := kv[idx+1:]
-			envMap[key] = value
-		}
-	}
-	for k, v := range custom {
-		envMap[k] = v
-	}
-
-	envMap["PATH"] = ensureNodeInPath(envMap["PATH"])
-
-	env := make([]string, 0, len(envMap))
-	for k, v := range envMap {
-		env = append(env, fmt.Sprintf("%s=%s", k, v))
-	}
-	sort.Strings(env)
-	return env
-}
-
-func ensureNodeInPath(existing string) string {
-	nodeDirs := localNodeBinDirs()
-	if len(nodeDirs) == 0 {
-		return existing
-	}
-
-	sep := string(os.PathListSeparator)
-	pieces := make([]string, 0, len(nodeDirs)+4)
-	seen := map[string]struct{}{}
-
-	for _, dir := range nodeDirs {
-		if dir == "" {
-			continue
-		}
-		key := strings.ToLower(dir)
-		if _, ok := seen[key]; !ok {
-			pieces = append(pieces, dir)
-			seen[key] = struct{}{}
-		}
-	}
-
-	if existing != "" {
-		for _, part := range strings.Split(existing, sep) {
-			trimmed := strings.TrimSpace(part)
-			if trimmed == "" {
-				continue
-			}
-			key := strings.ToLower(trimmed)
-			if _, ok := seen[key]; ok {
-				continue
-			}
-			seen[key] = struct{}{}
-			pieces = append(pieces, trimmed)
-		}
-	}
-
-	return strings.Join(pieces, sep)
-}
-
-func localNodeBinDirs() []string {
-	exePath, err := os.Executable()
-	if err != nil {
-		return nil
-	}
-	exeDir := filepath.Dir(exePath)
-	searchRoots := []string{
-		filepath.Join(exeDir, "third_party", "node"),
-		filepath.Join(exeDir, "..", "third_party", "node"),
-	}
-
-	var dirs []string
-	for _, root := range searchRoots {
-		entries, err := os.ReadDir(root)
-		if err != nil {
-			continue
-		}
-		for _, entry := range entries {
-			if !entry.IsDir() {
-				continue
-			}
-			candidate := filepath.Join(root, entry.Name())
-			win := filepath.Join(candidate, "node.exe")
-			unix := filepath.Join(candidate, "bin", "node")
-			if fileExists(win) {
-				dirs = append(dirs, candidate)
-			} else if fileExists(unix) {
-				dirs = append(dirs, filepath.Join(candidate, "bin"))
-			}
-		}
-	}
-
-	return dedupePaths(dirs)
-}
-
-func fileExists(path string) bool {
-	info, err := os.Stat(path)
-	return err == nil && !info.IsDir()
-}
-
-func dedupePaths(paths []string) []string {
-	seen := map[string]struct{}{}
-	out := make([]string, 0, len(paths))
-	for _, p := range paths {
-		lp := strings.ToLower(p)
-		if _, ok := seen[lp]; ok {
-			continue
-		}
-		seen[lp] = struct{}{}
-		out = append(out, p)
-	}
-	return out
-}
-
 func tail(input string, max int) string {
 	if len(input) <= max {
 		return strings.TrimSpace(input)
@@ -732,22 +750,7 @@ func tail(input string, max int) string {
 
 // LocalNodeExecutable returns the best guess for the bundled node binary.
 func LocalNodeExecutable() (string, error) {
-	dirs := localNodeBinDirs()
-	for _, dir := range dirs {
-		var candidate string
-		if runtime.GOOS == "windows" {
-			candidate = filepath.Join(dir, "node.exe")
-			if fileExists(candidate) {
-				return candidate, nil
-			}
-		} else {
-			candidate = filepath.Join(dir, "node")
-			if fileExists(candidate) {
-				return candidate, nil
-			}
-		}
-	}
-	return "", errors.New("bundled node runtime not found")
+	return runtimes.Bundled(runtimes.Node)
 }
 
 func debugMap(values map[string]string) string {
@@ -0,0 +1,55 @@
+package mcp
+
+// Bounds on the notifications kept from one probe. Chatty servers can emit thousands of
+// log notifications during startup; beyond these limits they are counted, not stored.
+const (
+	maxNotifications      = 50
+	maxNotificationBytes  = 32 << 10
+	maxNotificationDetail = 400
+)
+
+// NotificationStats reports how many notifications a server sent and how many were kept.
+type NotificationStats struct {
+	Received     int `json:"received"`
+	Kept         int `json:"kept"`
+	Coalesced    int `json:"coalesced"`
+	Dropped      int `json:"dropped"`
+	DroppedBytes int `json:"droppedBytes"`
+}
+
+// notificationLog collects notifications within the limits above. Repeats of a kept
+// notification (same method and detail) bump its Count instead of using a new slot.
+type notificationLog struct {
+	items []Notification
+	index map[string]int
+	bytes int
+	stats NotificationStats
+}
+
+func newNotificationLog() *notificationLog {
+	return &notificationLog{items: make([]Notification, 0, 4), index: map[string]int{}}
+}
+
+// add records a notification, truncating long details.
+func (l *notificationLog) add(method, detail string) {
+	if len(detail) > maxNotificationDetail {
+		detail = detail[:maxNotificationDetail-3] + "..."
+	}
+	l.stats.Received++
+	key := method + "\x00" + detail
+	if idx, ok := l.index[key]; ok {
+		l.items[idx].Count++
+		l.stats.Coalesced++
+		return
+	}
+	size := len(method) + len(detail)
+	if len(l.items) >= maxNotifications || l.bytes+size > maxNotificationBytes {
+		l.stats.Dropped++
+		l.stats.DroppedBytes += size
+		return
+	}
+	l.index[key] = len(l.items)
+	l.items = append(l.items, Notification{Method: method, Detail: detail, Count: 1})
+	l.bytes += size
+	l.stats.Kept++
+}
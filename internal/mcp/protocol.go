@@ -0,0 +1,112 @@
+package mcp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PreferredProtocolVersion is offered in initialize unless a server definition pins another revision.
+const PreferredProtocolVersion = "2025-06-18"
+
+// ProtocolRevision records the message-shape differences between MCP revisions that the client cares about.
+type ProtocolRevision struct {
+	Version string
+	// ToolTitles: tools carry a top-level human-readable title.
+	ToolTitles bool
+	// ToolAnnotations: tools carry an annotations object (which may hold a title).
+	ToolAnnotations bool
+	// OutputSchema: tools may declare outputSchema and results may carry structuredContent.
+	OutputSchema bool
+	// ClientTitle: clientInfo accepts a title field.
+	ClientTitle bool
+}
+
+// supportedRevisions is ordered newest first.
+var supportedRevisions = []ProtocolRevision{
+	{Version: "2025-06-18", ToolTitles: true, ToolAnnotations: true, OutputSchema: true, ClientTitle: true},
+	{Version: "2025-03-26", ToolAnnotations: true},
+	{Version: "2024-11-05"},
+}
+
+// SupportedProtocolVersions lists the revisions the client can speak, newest first.
+func SupportedProtocolVersions() []string {
+	out := make([]string, 0, len(supportedRevisions))
+	for _, rev := range supportedRevisions {
+		out = append(out, rev.Version)
+	}
+	return out
+}
+
+// LookupProtocolRevision returns the revision with the given version string.
+func LookupProtocolRevision(version string) (ProtocolRevision, bool) {
+	for _, rev := range supportedRevisions {
+		if rev.Version == version {
+			return rev, true
+		}
+	}
+	return ProtocolRevision{}, false
+}
+
+// negotiateProtocol applies the initialize version rules: the server either echoes the
+// offered version or counters with one it supports; the client accepts any counter-offer
+// it also supports and fails clearly otherwise.
+func negotiateProtocol(offered, answered string) (ProtocolRevision, error) {
+	if answered == "" {
+		// Some early servers omit the field; keep talking in the revision we offered.
+		answered = offered
+	}
+	if rev, ok := LookupProtocolRevision(answered); ok {
+		return rev, nil
+	}
+	return ProtocolRevision{}, fmt.Errorf("server requested unsupported protocol version %s (offered %s; sre-ai supports %s)",
+		answered, offered, strings.Join(SupportedProtocolVersions(), ", "))
+}
+
+func clientInfo(rev ProtocolRevision) map[string]string {
+	info := map[string]string{
+		"name":    "sre-ai",
+		"version": "dev",
+	}
+	if rev.ClientTitle {
+		info["title"] = "sre-ai CLI"
+	}
+	return info
+}
+
+// decodeTool normalises a tools/list entry according to the negotiated revision.
+func decodeTool(rev ProtocolRevision, tool map[string]interface{}) ToolSummary {
+	summary := ToolSummary{}
+	if name, ok := tool["name"].(string); ok {
+		summary.Name = name
+	}
+	if rev.ToolTitles {
+		if title, ok := tool["title"].(string); ok {
+			summary.Title = title
+		}
+	}
+	if desc, ok := tool["description"].(string); ok {
+		summary.Description = desc
+	}
+	if rev.ToolAnnotations {
+		if annotations, ok := tool["annotations"].(map[string]interface{}); ok {
+			summary.Annotations = annotations
+			if summary.Title == "" {
+				if title, ok := annotations["title"].(string); ok {
+					summary.Title = title
+				}
+			}
+		}
+	}
+	if schema, ok := tool["inputSchema"].(map[string]interface{}); ok {
+		summary.InputSchema = schema
+	} else if schema, ok := tool["input_schema"].(map[string]interface{}); ok {
+		// Pre-2025 servers (and the embedded manifests) sometimes used snake_case.
+		summary.InputSchema = schema
+	}
+	if rev.OutputSchema {
+		if schema, ok := tool["outputSchema"].(map[string]interface{}); ok {
+			summary.OutputSchema = schema
+		}
+	}
+	return summary
+}
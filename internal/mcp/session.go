@@ -0,0 +1,468 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/example/sre-ai/internal/warnings"
+)
+
+// Session is a running local MCP server kept open across tool calls, unlike a probe,
+// which starts the server, lists its tooling, and stops it. A server that exits or
+// stops answering is restarted before the next request (see Watch).
+type Session struct {
+	Alias    string
+	Revision ProtocolRevision
+	// Tools is replaced when the server restarts; use ToolList from other goroutines.
+	Tools []ToolSummary
+
+	def        ServerDefinition
+	env        map[string]string
+	offered    string
+	offeredRev ProtocolRevision
+
+	// mu serializes requests and restarts.
+	mu            sync.Mutex
+	proc          atomic.Pointer[process]
+	nextID        int
+	pending       map[string]jsonrpcEnvelope
+	notifications *notificationLog
+	logger        Logger
+
+	// broken is why the process cannot take another request; failed is set once the
+	// restart budget is spent, after which every request returns it.
+	broken   error
+	failed   error
+	restarts []time.Time
+	watch    WatchOptions
+
+	stopWatch chan struct{}
+	watchDone chan struct{}
+	closed    atomic.Bool
+	closeOnce sync.Once
+}
+
+// process is one run of the server command.
+type process struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	reader *frameReader
+	writer *bufio.Writer
+	stderr lockedBuffer
+	done   chan error
+	exited chan struct{}
+	// exitErr is the Wait result, set before exited is closed.
+	exitErr error
+}
+
+// lockedBuffer is a bytes.Buffer the process can write while a failed call reads it.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// ToolResult is the outcome of a tools/call. IsError marks a failure the tool reported
+// itself; the content then explains it.
+type ToolResult struct {
+	Content           []map[string]interface{} `json:"content,omitempty"`
+	StructuredContent interface{}              `json:"structuredContent,omitempty"`
+	IsError           bool                     `json:"isError,omitempty"`
+}
+
+// Text joins the text items of the result content.
+func (r *ToolResult) Text() string {
+	var parts []string
+	for _, item := range r.Content {
+		if text, ok := item["text"].(string); ok {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// OpenSession starts the local server registered under alias, completes the initialize
+// handshake, and lists its tools. Close stops it.
+func OpenSession(ctx context.Context, alias string, logger Logger) (*Session, error) {
+	def, err := GetLocalServer(ctx, alias)
+	if err != nil {
+		return nil, err
+	}
+	if def.Command == "" {
+		return nil, errors.New("server command is empty")
+	}
+	def.Workdir, err = resolveWorkdir(def.Workdir, nil)
+	if err != nil {
+		return nil, fmt.Errorf("server %s: %w", alias, err)
+	}
+	offered := PreferredProtocolVersion
+	if def.ProtocolVersion != "" {
+		offered = def.ProtocolVersion
+	}
+	offeredRev, ok := LookupProtocolRevision(offered)
+	if !ok {
+		return nil, fmt.Errorf("server %s pins unsupported protocol version %s (supported: %s)", alias, offered, strings.Join(SupportedProtocolVersions(), ", "))
+	}
+	envMap, err := tunnelEnv(ctx, def)
+	if err != nil {
+		return nil, fmt.Errorf("server %s: %w", alias, err)
+	}
+	for k, v := range def.Env {
+		envMap[k] = v
+	}
+
+	s := &Session{
+		Alias:         alias,
+		def:           def,
+		env:           envMap,
+		offered:       offered,
+		offeredRev:    offeredRev,
+		notifications: newNotificationLog(),
+		logger:        logger,
+		watch:         WatchOptions{}.withDefaults(),
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.start(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// start runs the server command and completes the handshake. The caller holds mu.
+func (s *Session) start(ctx context.Context) error {
+	p := &process{done: make(chan error, 1), exited: make(chan struct{})}
+	// The server outlives ctx, which only bounds the handshake.
+	p.cmd = exec.Command(s.def.Command, s.def.Args...)
+	if s.def.Workdir != "" {
+		p.cmd.Dir = s.def.Workdir
+	}
+	var err error
+	if p.cmd.Env, err = mergeEnv(ctx, s.def, s.env); err != nil {
+		return fmt.Errorf("server %s: %w", s.Alias, err)
+	}
+	p.cmd.Stderr = &p.stderr
+	stdout, err := p.cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if p.stdin, err = p.cmd.StdinPipe(); err != nil {
+		return err
+	}
+	if s.logger != nil {
+		s.logger.Printf("mcp session alias=%s command=%s args=%s", s.Alias, s.def.Command, strings.Join(s.def.Args, " "))
+	}
+	if err := p.cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", s.Alias, err)
+	}
+	go func() {
+		// done reports the exit to a waiting call; exited stays closed for stop.
+		err := p.cmd.Wait()
+		p.exitErr = err
+		p.done <- err
+		close(p.exited)
+	}()
+	p.reader = newFrameReader(stdout)
+	p.writer = bufio.NewWriter(p.stdin)
+	s.proc.Store(p)
+	s.pending = map[string]jsonrpcEnvelope{}
+	s.broken = nil
+
+	if err := s.initialize(ctx); err != nil {
+		p.stop()
+		s.broken = err
+		return annotateStderr(err, &p.stderr)
+	}
+	return nil
+}
+
+func (s *Session) initialize(ctx context.Context) error {
+	initEnv, err := s.callLocked(ctx, "initialize", map[string]interface{}{
+		"protocolVersion": s.offered,
+		"clientInfo":      clientInfo(s.offeredRev),
+		"capabilities":    map[string]interface{}{},
+	})
+	if err != nil {
+		return err
+	}
+	if initEnv.Error != nil {
+		return fmt.Errorf("initialize failed: %s", initEnv.Error.Message)
+	}
+	var initData struct {
+		ProtocolVersion string `json:"protocolVersion"`
+		ServerInfo      struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"serverInfo"`
+	}
+	if err := json.Unmarshal(initEnv.Result, &initData); err != nil {
+		return fmt.Errorf("decode initialize result: %w", err)
+	}
+	if s.Revision, err = negotiateProtocol(s.offered, initData.ProtocolVersion); err != nil {
+		return err
+	}
+	if err := sendJSONMessage(s.proc.Load().writer, map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "notifications/initialized",
+		"params":  map[string]interface{}{},
+	}); err != nil {
+		return err
+	}
+
+	var tools []ToolSummary
+	cursor := ""
+	for {
+		var params map[string]interface{}
+		if cursor != "" {
+			params = map[string]interface{}{"cursor": cursor}
+		}
+		resp, err := s.callLocked(ctx, "tools/list", params)
+		if err != nil {
+			return err
+		}
+		if resp.Error != nil {
+			return fmt.Errorf("tools/list failed: %s", resp.Error.Message)
+		}
+		var page struct {
+			Tools      []map[string]interface{} `json:"tools"`
+			NextCursor string                   `json:"nextCursor"`
+		}
+		if err := json.Unmarshal(resp.Result, &page); err != nil {
+			return fmt.Errorf("decode tools/list: %w", err)
+		}
+		for _, tool := range page.Tools {
+			tools = append(tools, decodeTool(s.Revision, tool))
+		}
+		if page.NextCursor == "" {
+			s.Tools = tools
+			if err := SaveCatalog(ctx, NewCatalog(s.Alias, initData.ServerInfo.Name, initData.ServerInfo.Version, tools)); err != nil && s.logger != nil {
+				s.logger.Printf("mcp session alias=%s catalog not cached: %v", s.Alias, err)
+			}
+			return nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// call sends one request and waits for its response, restarting the server first if it
+// exited or stopped answering. Callers hold no lock; call takes it.
+func (s *Session) call(ctx context.Context, method string, params map[string]interface{}) (jsonrpcEnvelope, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.ensure(ctx); err != nil {
+		return jsonrpcEnvelope{}, err
+	}
+	return s.callLocked(ctx, method, params)
+}
+
+// callLocked is call without the restart, for the handshake and pings. The caller holds mu.
+func (s *Session) callLocked(ctx context.Context, method string, params map[string]interface{}) (jsonrpcEnvelope, error) {
+	p := s.proc.Load()
+	s.nextID++
+	req := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      s.nextID,
+		"method":  method,
+	}
+	if params != nil {
+		req["params"] = params
+	}
+	if err := sendJSONMessage(p.writer, req); err != nil {
+		s.broken = err
+		return jsonrpcEnvelope{}, err
+	}
+	env, err := awaitResponse(ctx, p.reader, p.writer, strconv.Itoa(s.nextID), s.pending, s.notifications, p.done, s.Alias, s.logger)
+	if err != nil {
+		// A timed-out read is still waiting on the pipe and an exited server answers
+		// nothing, so either way the next request needs a fresh process.
+		s.broken = err
+	}
+	return env, err
+}
+
+// ensure restarts a server that exited or stopped answering. The caller holds mu.
+func (s *Session) ensure(ctx context.Context) error {
+	if s.closed.Load() {
+		return fmt.Errorf("mcp session %s is closed", s.Alias)
+	}
+	if s.failed != nil {
+		return s.failed
+	}
+	reason := s.needsRestart()
+	if reason == nil {
+		return nil
+	}
+	return s.restart(ctx, reason)
+}
+
+// needsRestart returns why the process cannot take a request, preferring its exit
+// status over the read error an exit causes. The caller holds mu.
+func (s *Session) needsRestart() error {
+	p := s.proc.Load()
+	select {
+	case <-p.exited:
+		if p.exitErr != nil {
+			return fmt.Errorf("server exited: %v", p.exitErr)
+		}
+		return errors.New("server exited")
+	default:
+	}
+	return s.broken
+}
+
+// annotateStderr is annotateProbeError for a session's stderr.
+func annotateStderr(err error, stderr *lockedBuffer) error {
+	if tail := strings.TrimSpace(stderr.String()); tail != "" {
+		return fmt.Errorf("%w\nstderr: %s", err, tail)
+	}
+	return err
+}
+
+// restart replaces the process and replays the handshake, trying up to
+// restartAttempts times. Restarts count against the session's budget of
+// WatchOptions.MaxRestarts within FlapWindow, beyond which the session gives up.
+// The caller holds mu.
+func (s *Session) restart(ctx context.Context, reason error) error {
+	now := time.Now()
+	recent := s.restarts[:0]
+	for _, at := range s.restarts {
+		if now.Sub(at) < s.watch.FlapWindow {
+			recent = append(recent, at)
+		}
+	}
+	s.restarts = recent
+	if len(s.restarts) >= s.watch.MaxRestarts {
+		s.failed = fmt.Errorf("mcp server %s restarted %d times within %s; giving up (last failure: %v)", s.Alias, len(s.restarts), s.watch.FlapWindow, reason)
+		return s.failed
+	}
+	s.restarts = append(s.restarts, now)
+	s.proc.Load().stop()
+
+	var err error
+	for attempt := 0; attempt < restartAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				err = ctx.Err()
+			case <-time.After(restartBackoff << (attempt - 1)):
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		if err = s.start(ctx); err == nil {
+			break
+		}
+	}
+	if err == nil && s.closed.Load() {
+		// Close ran while the server was starting and stopped the old process only.
+		s.proc.Load().stop()
+		return fmt.Errorf("mcp session %s is closed", s.Alias)
+	}
+	if recordErr := recordRestart(ctx, s.Alias, now, reason, err); recordErr != nil && s.logger != nil {
+		s.logger.Printf("mcp session alias=%s could not record restart: %v", s.Alias, recordErr)
+	}
+	if s.logger != nil {
+		s.logger.Printf("mcp session alias=%s restarted after %v err=%v", s.Alias, reason, err)
+	}
+	if err != nil {
+		return fmt.Errorf("mcp server %s failed (%v) and did not restart: %w", s.Alias, reason, err)
+	}
+	warnings.Add(ctx, "mcp", "restarted %s: %v", s.Alias, reason)
+	return nil
+}
+
+// CallTool runs a tool with the given arguments. A call that fails because the server
+// died is not retried, since the tool may have had effects; the next request restarts it.
+func (s *Session) CallTool(ctx context.Context, name string, args map[string]interface{}) (*ToolResult, error) {
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	start := time.Now()
+	resp, err := s.call(ctx, "tools/call", map[string]interface{}{"name": name, "arguments": args})
+	if err != nil {
+		return nil, annotateStderr(fmt.Errorf("%s/%s: %w", s.Alias, name, err), &s.proc.Load().stderr)
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("%s/%s: %s", s.Alias, name, resp.Error.Message)
+	}
+	var result ToolResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return nil, fmt.Errorf("%s/%s: decode result: %w", s.Alias, name, err)
+	}
+	if s.logger != nil {
+		s.logger.Printf("mcp session alias=%s tool=%s isError=%t took=%s", s.Alias, name, result.IsError, time.Since(start))
+	}
+	return &result, nil
+}
+
+// Tool returns the listed tool with the given name.
+func (s *Session) Tool(name string) (ToolSummary, bool) {
+	for _, tool := range s.ToolList() {
+		if tool.Name == name {
+			return tool, true
+		}
+	}
+	return ToolSummary{}, false
+}
+
+// ToolList returns the tools the server listed at its latest start.
+func (s *Session) ToolList() []ToolSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Tools
+}
+
+// Close stops the watchdog and the server.
+func (s *Session) Close() error {
+	s.closeOnce.Do(func() {
+		s.closed.Store(true)
+		if s.stopWatch != nil {
+			close(s.stopWatch)
+			<-s.watchDone
+		}
+		s.proc.Load().stop()
+	})
+	return nil
+}
+
+// stop ends the process: stdin is closed so it can exit on its own, then it is killed.
+func (p *process) stop() {
+	_ = p.stdin.Close()
+	select {
+	case <-p.exited:
+	case <-time.After(750 * time.Millisecond):
+		_ = p.cmd.Process.Kill()
+		<-p.exited
+	}
+	p.reader.close()
+}
+
+// ReadOnly reports whether the tool declares itself free of side effects
+// (annotations.readOnlyHint). Tools that do not say so may change things.
+func (t ToolSummary) ReadOnly() bool {
+	readOnly, _ := t.Annotations["readOnlyHint"].(bool)
+	return readOnly
+}
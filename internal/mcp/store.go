@@ -1,6 +1,7 @@
 package mcp
 
 import (
+    "context"
     "encoding/json"
     "errors"
     "fmt"
@@ -15,8 +16,8 @@ type serverStore struct {
     Servers map[string]ServerDefinition `json:"mcpServers"`
 }
 
-func registerLocalServers() error {
-    store, path, err := loadServerStore()
+func registerLocalServers(ctx context.Context) error {
+    store, path, err := loadServerStore(ctx)
     if err != nil {
         if errors.Is(err, os.ErrNotExist) {
             return nil
@@ -24,21 +25,27 @@ func registerLocalServers() error {
         return err
     }
     for alias, def := range store.Servers {
-        DefaultRegistry.RegisterLocal(alias, def, path)
+        RegistryFrom(ctx).RegisterLocal(alias, def, path)
     }
     return nil
 }
 
 // AddLocalServer persists a server definition and updates the registry.
-func AddLocalServer(alias string, def ServerDefinition, origin string) error {
+func AddLocalServer(ctx context.Context, alias string, def ServerDefinition, origin string) error {
     if alias == "" {
         return errors.New("alias cannot be empty")
     }
     if def.Command == "" {
         return errors.New("server command cannot be empty")
     }
+    if err := ValidateEnvPolicy(def); err != nil {
+        return err
+    }
+    if err := ValidateCredentialRefs(def); err != nil {
+        return err
+    }
 
-    store, path, err := loadServerStore()
+    store, path, err := loadServerStore(ctx)
     if err != nil && !errors.Is(err, os.ErrNotExist) {
         return err
     }
@@ -50,13 +57,13 @@ func AddLocalServer(alias string, def ServerDefinition, origin string) error {
         return err
     }
 
-    DefaultRegistry.RegisterLocal(alias, def, path)
+    RegistryFrom(ctx).RegisterLocal(alias, def, path)
     return nil
 }
 
 // RemoveLocalServer deletes a stored definition and updates the registry.
-func RemoveLocalServer(alias string) error {
-    store, path, err := loadServerStore()
+func RemoveLocalServer(ctx context.Context, alias string) error {
+    store, path, err := loadServerStore(ctx)
     if err != nil {
         if errors.Is(err, os.ErrNotExist) {
             return fmt.Errorf("no MCP servers registered")
@@ -71,13 +78,14 @@ func RemoveLocalServer(alias string) error {
     if err := saveServerStore(path, store); err != nil {
         return err
     }
-    DefaultRegistry.Remove(alias)
+    RegistryFrom(ctx).Remove(alias)
+    removeCatalog(ctx, alias)
     return nil
 }
 
 // ListLocalServers returns all persisted local server definitions.
-func ListLocalServers() (map[string]ServerDefinition, error) {
-    store, _, err := loadServerStore()
+func ListLocalServers(ctx context.Context) (map[string]ServerDefinition, error) {
+    store, _, err := loadServerStore(ctx)
     if err != nil {
         if errors.Is(err, os.ErrNotExist) {
             return map[string]ServerDefinition{}, nil
@@ -93,8 +101,8 @@ func ListLocalServers() (map[string]ServerDefinition, error) {
 }
 
 // GetLocalServer fetches a single stored definition.
-func GetLocalServer(alias string) (ServerDefinition, error) {
-    store, _, err := loadServerStore()
+func GetLocalServer(ctx context.Context, alias string) (ServerDefinition, error) {
+    store, _, err := loadServerStore(ctx)
     if err != nil {
         if errors.Is(err, os.ErrNotExist) {
             return ServerDefinition{}, fmt.Errorf("no MCP servers registered")
@@ -108,8 +116,8 @@ func GetLocalServer(alias string) (ServerDefinition, error) {
     return def, nil
 }
 
-func loadServerStore() (serverStore, string, error) {
-    path, err := serverStorePath()
+func loadServerStore(ctx context.Context) (serverStore, string, error) {
+    path, err := serverStorePath(ctx)
     if err != nil {
         return serverStore{}, "", err
     }
@@ -131,19 +139,15 @@ func saveServerStore(path string, store serverStore) error {
     if path == "" {
         return errors.New("invalid server store path")
     }
-    dir := filepath.Dir(path)
-    if err := os.MkdirAll(dir, 0o755); err != nil {
-        return err
-    }
     data, err := json.MarshalIndent(store, "", "  ")
     if err != nil {
         return err
     }
-    return os.WriteFile(path, data, 0o600)
+    return config.WriteFile(path, data)
 }
 
-func serverStorePath() (string, error) {
-    base, err := config.ConfigDir()
+func serverStorePath(ctx context.Context) (string, error) {
+    base, err := config.ConfigDir(ctx)
     if err != nil {
         return "", err
     }
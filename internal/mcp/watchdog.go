@@ -0,0 +1,107 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/example/sre-ai/internal/warnings"
+)
+
+const (
+	// DefaultPingInterval and DefaultPingTimeout are how often a watched session pings
+	// its server and how long it waits for the reply.
+	DefaultPingInterval = 30 * time.Second
+	DefaultPingTimeout  = 10 * time.Second
+	// DefaultMaxRestarts within DefaultFlapWindow bounds how often a crashy server is
+	// restarted before its session gives up.
+	DefaultMaxRestarts = 5
+	DefaultFlapWindow  = 10 * time.Minute
+
+	// restartAttempts is how many times one restart tries to start the server, waiting
+	// restartBackoff and then twice as long between tries.
+	restartAttempts = 3
+	restartBackoff  = time.Second
+)
+
+// WatchOptions tune the watchdog of a session; zero fields take the defaults.
+type WatchOptions struct {
+	Interval    time.Duration
+	Timeout     time.Duration
+	MaxRestarts int
+	FlapWindow  time.Duration
+}
+
+func (o WatchOptions) withDefaults() WatchOptions {
+	if o.Interval <= 0 {
+		o.Interval = DefaultPingInterval
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultPingTimeout
+	}
+	if o.MaxRestarts <= 0 {
+		o.MaxRestarts = DefaultMaxRestarts
+	}
+	if o.FlapWindow <= 0 {
+		o.FlapWindow = DefaultFlapWindow
+	}
+	return o
+}
+
+// Watch pings the server every interval until Close or ctx ends, restarting it when it
+// has exited or does not answer within the timeout. A ping is skipped while a request is
+// in flight. Restarts are reported as warnings on ctx and recorded for mcp ls.
+func (s *Session) Watch(ctx context.Context, opts WatchOptions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopWatch != nil {
+		return
+	}
+	s.watch = opts.withDefaults()
+	s.stopWatch = make(chan struct{})
+	s.watchDone = make(chan struct{})
+	go s.watchLoop(ctx, s.watch, s.stopWatch, s.watchDone)
+}
+
+func (s *Session) watchLoop(ctx context.Context, opts WatchOptions, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !s.mu.TryLock() {
+			continue
+		}
+		if s.failed == nil {
+			if reason := s.check(ctx, opts.Timeout); reason != nil && ctx.Err() == nil {
+				if err := s.restart(ctx, reason); err != nil {
+					warnings.Add(ctx, "mcp", "%v", err)
+				}
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// check returns why the server needs a restart, or nil when it answered a ping. A ping
+// the server answers with an error (an older server without ping) still counts as
+// alive. The caller holds mu.
+func (s *Session) check(ctx context.Context, timeout time.Duration) error {
+	if reason := s.needsRestart(); reason != nil {
+		return reason
+	}
+	pingCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	_, err := s.callLocked(pingCtx, "ping", nil)
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		return fmt.Errorf("no reply to ping within %s", timeout)
+	}
+	return err
+}
@@ -0,0 +1,55 @@
+package mcp
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/example/sre-ai/internal/workspace"
+)
+
+// resolveWorkdir renders a workdir template at launch. Placeholders use Go template
+// syntax: {{.cwd}} and {{.repo.root}} are always available, and workflow runs add
+// {{.run.dir}}, {{.run.id}}, and {{.inputs.name}} through vars. A placeholder with no
+// value is an error rather than silently launching in the wrong directory.
+func resolveWorkdir(raw string, vars map[string]interface{}) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+	if !strings.Contains(raw, "{{") {
+		return expandPath(raw), nil
+	}
+
+	data := defaultWorkdirVars()
+	for k, v := range vars {
+		data[k] = v
+	}
+	tmpl, err := template.New("workdir").Option("missingkey=error").Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("workdir template: %w", err)
+	}
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("workdir template %q: %w", raw, err)
+	}
+	resolved := strings.TrimSpace(buf.String())
+	if resolved == "" {
+		return "", fmt.Errorf("workdir template %q rendered empty", raw)
+	}
+	return expandPath(resolved), nil
+}
+
+func defaultWorkdirVars() map[string]interface{} {
+	vars := map[string]interface{}{}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return vars
+	}
+	vars["cwd"] = cwd
+	if root, ok := workspace.GitRoot(cwd); ok {
+		vars["repo"] = map[string]interface{}{"root": root}
+	}
+	return vars
+}
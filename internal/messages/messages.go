@@ -0,0 +1,268 @@
+// Package messages is the catalog of confirmation prompts, refusals, and high-risk
+// warnings shown to operators. Each message has an ID, a severity that decides how it
+// is styled, and English text in fmt syntax; a locale file can replace the text.
+package messages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/example/sre-ai/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// ID names a message in the catalog.
+type ID string
+
+// Severity decides how a message is styled: destructive confirmations are bold red,
+// warnings bold yellow, and info plain.
+type Severity string
+
+const (
+	Info        Severity = "info"
+	Warning     Severity = "warning"
+	Destructive Severity = "destructive"
+)
+
+const (
+	ConfirmApplyStack     ID = "confirm.apply_stack"
+	ConfirmInjectFailure  ID = "confirm.inject_failure"
+	ConfirmPage           ID = "confirm.page"
+	ConfirmToolCall       ID = "confirm.tool_call"
+	ConfirmKubectl        ID = "confirm.kubectl_diagnostics"
+	ConfirmRegisterServer ID = "confirm.register_mcp_server"
+
+	RefuseApply  ID = "refuse.apply"
+	RefuseInject ID = "refuse.inject"
+	RefusePage   ID = "refuse.page"
+
+	ApplyCancelled ID = "result.apply_cancelled"
+	PageDeclined   ID = "result.page_declined"
+
+	HighRiskUngated ID = "high_risk.ungated"
+	HighRiskBlocked ID = "high_risk.blocked"
+	RollbackBlocked ID = "high_risk.rollback_blocked"
+
+	Resources     ID = "prompt.resources"
+	AnswerHint    ID = "prompt.answer_hint"
+	AnswerYes     ID = "prompt.answer_yes"
+	NeedsApproval ID = "prompt.needs_approval"
+	SlackHowTo    ID = "prompt.slack_how_to"
+	SlackNoAnswer ID = "prompt.slack_no_answer"
+	WebHowTo      ID = "prompt.web_how_to"
+	WebApprove    ID = "prompt.web_approve"
+	WebDeny       ID = "prompt.web_deny"
+	WebApproved   ID = "prompt.web_approved"
+	WebDenied     ID = "prompt.web_denied"
+)
+
+type entry struct {
+	severity Severity
+	text     string
+}
+
+// catalog holds every message with its severity and English text. Translations may
+// reorder arguments with indexed verbs such as %[2]s.
+var catalog = map[ID]entry{
+	ConfirmApplyStack:     {Destructive, "Apply IaC stack %s?"},
+	ConfirmInjectFailure:  {Destructive, "Inject failure %s?"},
+	ConfirmPage:           {Warning, "Page %s via %s (%s): %s?"},
+	ConfirmToolCall:       {Warning, "Run %s/%s, which may change things?"},
+	ConfirmKubectl:        {Info, "Execute proposed kubectl diagnostics?"},
+	ConfirmRegisterServer: {Info, "%s: register MCP server %s (%s)?"},
+
+	RefuseApply:  {Warning, "refusing to apply without --confirm in no-interactive mode"},
+	RefuseInject: {Warning, "refusing to inject failures without --confirm in no-interactive mode"},
+	RefusePage:   {Warning, "refusing to page without --confirm in no-interactive mode"},
+
+	ApplyCancelled: {Info, "Apply cancelled"},
+	PageDeclined:   {Info, "page declined"},
+
+	HighRiskUngated: {Warning, "step %s is marked high risk; run it through a gated command such as 'sre-ai gameday run'"},
+	HighRiskBlocked: {Warning, "step %s blocked by high-risk policy gate"},
+	RollbackBlocked: {Warning, "rollback step %s blocked by high-risk policy gate"},
+
+	Resources:     {Info, "This affects:"},
+	AnswerHint:    {Info, "[y/N]"},
+	AnswerYes:     {Info, "y,yes"},
+	NeedsApproval: {Info, "sre-ai needs approval"},
+	SlackHowTo:    {Info, "React with :white_check_mark: to approve or :x: to deny."},
+	SlackNoAnswer: {Info, "No answer within %s; not proceeding."},
+	WebHowTo:      {Info, "Open %s to approve or deny."},
+	WebApprove:    {Info, "Approve"},
+	WebDeny:       {Info, "Deny"},
+	WebApproved:   {Info, "Approved."},
+	WebDenied:     {Info, "Denied."},
+}
+
+const messagesDirName = "messages"
+
+// Catalog holds the translations of one command tree. A nil *Catalog speaks English.
+type Catalog struct {
+	translated map[ID]string
+}
+
+// Dir is where locale files live: messages/<locale>.yaml under the config dir.
+func Dir(ctx context.Context) (string, error) {
+	base, err := config.ConfigDir(ctx)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, messagesDirName), nil
+}
+
+// Load reads the locale file for locale, or for $LC_ALL, $LC_MESSAGES, or $LANG when it
+// is empty. de_DE.UTF-8 looks for de_DE.yaml, then de.yaml. English needs no file. An
+// explicit locale without a file is an error; one from the environment just keeps
+// English. The warnings list translations that were skipped.
+func Load(ctx context.Context, locale string) (*Catalog, []string, error) {
+	explicit := strings.TrimSpace(locale) != ""
+	if !explicit {
+		locale = envLocale()
+	}
+	c := &Catalog{translated: map[ID]string{}}
+	candidates := localeCandidates(locale)
+	if len(candidates) == 0 {
+		return c, nil, nil
+	}
+	dir, err := Dir(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, name := range candidates {
+		path := filepath.Join(dir, name+".yaml")
+		data, readErr := os.ReadFile(path)
+		if errors.Is(readErr, os.ErrNotExist) {
+			continue
+		}
+		if readErr != nil {
+			return nil, nil, readErr
+		}
+		warns, err := parseLocale(path, data, c.translated)
+		if err != nil {
+			return nil, nil, err
+		}
+		return c, warns, nil
+	}
+	if explicit {
+		return nil, nil, fmt.Errorf("locale %s: no %s.yaml in %s", locale, candidates[len(candidates)-1], dir)
+	}
+	return c, nil, nil
+}
+
+type contextKey struct{}
+
+// WithCatalog returns a context whose messages are rendered from c.
+func WithCatalog(ctx context.Context, c *Catalog) context.Context {
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// FromContext returns the catalog set with WithCatalog, or nil for English.
+func FromContext(ctx context.Context) *Catalog {
+	c, _ := ctx.Value(contextKey{}).(*Catalog)
+	return c
+}
+
+// parseLocale reads a locale file, a messages map from ID to text, into loaded. Unknown
+// IDs and texts whose verb count differs from the English one are skipped.
+func parseLocale(path string, data []byte, loaded map[ID]string) ([]string, error) {
+	var file struct {
+		Messages map[string]string `yaml:"messages"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	var warns []string
+	for id, text := range file.Messages {
+		e, ok := catalog[ID(id)]
+		if !ok {
+			warns = append(warns, fmt.Sprintf("%s: unknown message %s", path, id))
+			continue
+		}
+		if verbs(text) != verbs(e.text) {
+			warns = append(warns, fmt.Sprintf("%s: %s takes %d argument(s), the translation uses %d; keeping English", path, id, verbs(e.text), verbs(text)))
+			continue
+		}
+		loaded[ID(id)] = text
+	}
+	return warns, nil
+}
+
+func envLocale() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := strings.TrimSpace(os.Getenv(name)); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// localeCandidates lists the file names to try for locale, most specific first; English
+// and the C locale need none.
+func localeCandidates(locale string) []string {
+	locale, _, _ = strings.Cut(locale, ".")
+	locale, _, _ = strings.Cut(locale, "@")
+	locale = strings.ReplaceAll(locale, "-", "_")
+	lang, _, _ := strings.Cut(locale, "_")
+	switch strings.ToLower(lang) {
+	case "", "c", "posix", "en":
+		return nil
+	}
+	if locale == lang {
+		return []string{lang}
+	}
+	return []string{locale, lang}
+}
+
+// verbs counts the formatting verbs of a format string, not counting %%.
+func verbs(format string) int {
+	n := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		if i+1 < len(format) && format[i+1] == '%' {
+			i++
+			continue
+		}
+		n++
+	}
+	return n
+}
+
+// Text formats the message id with args in the locale of the catalog carried by ctx.
+func Text(ctx context.Context, id ID, args ...any) string {
+	return FromContext(ctx).Text(id, args...)
+}
+
+// Error is Text as an error, for refusals.
+func Error(ctx context.Context, id ID, args ...any) error {
+	return errors.New(Text(ctx, id, args...))
+}
+
+// Text formats the message id with args in c's locale.
+func (c *Catalog) Text(id ID, args ...any) string {
+	format, ok := "", false
+	if c != nil {
+		format, ok = c.translated[id]
+	}
+	if !ok {
+		format = catalog[id].text
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// SeverityOf returns the severity of id.
+func SeverityOf(id ID) Severity {
+	if e, ok := catalog[id]; ok {
+		return e.severity
+	}
+	return Info
+}
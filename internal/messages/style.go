@@ -0,0 +1,42 @@
+package messages
+
+import (
+	"io"
+	"os"
+)
+
+const (
+	ansiReset      = "\x1b[0m"
+	ansiBoldRed    = "\x1b[1;31m"
+	ansiBoldYellow = "\x1b[1;33m"
+)
+
+// Style wraps s in the colour of sev when w is a terminal that takes colour: not when
+// $NO_COLOR is set or $TERM is dumb. Info is never styled.
+func Style(w io.Writer, sev Severity, s string) string {
+	var code string
+	switch sev {
+	case Destructive:
+		code = ansiBoldRed
+	case Warning:
+		code = ansiBoldYellow
+	default:
+		return s
+	}
+	if !colorTerminal(w) {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+func colorTerminal(w io.Writer) bool {
+	if _, set := os.LookupEnv("NO_COLOR"); set || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
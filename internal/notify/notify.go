@@ -0,0 +1,485 @@
+// Package notify routes events such as a finished diagnosis or a failed workflow run
+// to notification channels (Slack, email, PagerDuty) according to declarative rules
+// in notify.yaml, with quiet hours and dedupe windows.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/sre-ai/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	configFileName = "notify.yaml"
+	stateFileName  = "notify-sent.json"
+	// DefaultDedupe is the dedupe window when notify.yaml does not set one.
+	DefaultDedupe = 30 * time.Minute
+)
+
+// Delivery statuses.
+const (
+	StatusSent       = "sent"
+	StatusFailed     = "failed"
+	StatusDeduped    = "deduped"
+	StatusSuppressed = "suppressed"
+	StatusDropped    = "dropped"
+	StatusDryRun     = "dry_run"
+)
+
+// severityRanks orders severities for min_severity; medium and warning rank the same.
+var severityRanks = map[string]int{"info": 0, "low": 1, "warning": 2, "medium": 2, "high": 3, "critical": 4}
+
+// Config is the content of notify.yaml.
+type Config struct {
+	// Environment is used for events that do not name one.
+	Environment string             `yaml:"environment"`
+	Channels    map[string]Channel `yaml:"channels"`
+	// Routes are tried in order; the first match wins unless it sets continue.
+	Routes     []Route     `yaml:"routes"`
+	QuietHours *QuietHours `yaml:"quiet_hours"`
+	// Dedupe is how long an event with the same key is not sent to a channel again.
+	Dedupe string `yaml:"dedupe"`
+}
+
+// Channel is a notification destination. Secrets are read from the environment
+// variables named here, never from the file.
+type Channel struct {
+	// Type is slack, email, pagerduty, or none.
+	Type string `yaml:"type"`
+	// Channel and TokenEnv (default SLACK_BOT_TOKEN) are for slack.
+	Channel  string `yaml:"channel"`
+	TokenEnv string `yaml:"token_env"`
+	// To, From, SMTP (host:port), UsernameEnv, and PasswordEnv are for email.
+	To          []string `yaml:"to"`
+	From        string   `yaml:"from"`
+	SMTP        string   `yaml:"smtp"`
+	UsernameEnv string   `yaml:"username_env"`
+	PasswordEnv string   `yaml:"password_env"`
+	// RoutingKeyEnv (default PAGERDUTY_ROUTING_KEY) is for pagerduty.
+	RoutingKeyEnv string `yaml:"routing_key_env"`
+	// URL overrides the Slack API or PagerDuty Events API endpoint.
+	URL string `yaml:"url"`
+}
+
+// Route sends matching events to its channels. The channel name "none" sends nothing,
+// which together with the first-match rule silences what later routes would catch.
+type Route struct {
+	Name     string   `yaml:"name"`
+	Match    Match    `yaml:"match"`
+	Channels []string `yaml:"channels"`
+	Continue bool     `yaml:"continue"`
+	// Dedupe overrides the top-level dedupe window for this route.
+	Dedupe string `yaml:"dedupe"`
+	// QuietHours set to false delivers this route's events during quiet hours too.
+	QuietHours *bool `yaml:"quiet_hours"`
+}
+
+// Match selects events. Every non-empty field must match; lists match any entry and
+// entries may be path.Match globs such as prod-*.
+type Match struct {
+	Kind        []string `yaml:"kind"`
+	Severity    []string `yaml:"severity"`
+	MinSeverity string   `yaml:"min_severity"`
+	Service     []string `yaml:"service"`
+	Environment []string `yaml:"environment"`
+}
+
+// QuietHours holds back notifications between Start and End (HH:MM, wrapping past
+// midnight when End is earlier) in Zone, except for the severities in Except, which
+// defaults to critical.
+type QuietHours struct {
+	Start  string   `yaml:"start"`
+	End    string   `yaml:"end"`
+	Zone   string   `yaml:"zone"`
+	Except []string `yaml:"except"`
+}
+
+// Event is something worth telling people about.
+type Event struct {
+	// Kind is what produced the event: diagnose, agent_run, or manual.
+	Kind        string `json:"kind"`
+	Severity    string `json:"severity"`
+	Service     string `json:"service,omitempty"`
+	Environment string `json:"environment,omitempty"`
+	Title       string `json:"title"`
+	Text        string `json:"text,omitempty"`
+	RunID       string `json:"run_id,omitempty"`
+	// Key identifies repeats of the same event for dedupe; it defaults to kind, service,
+	// environment, and title.
+	Key string `json:"key,omitempty"`
+}
+
+// DedupeKey returns the event's dedupe key.
+func (e Event) DedupeKey() string {
+	if e.Key != "" {
+		return e.Key
+	}
+	return strings.Join([]string{e.Kind, e.Service, e.Environment, e.Title}, "|")
+}
+
+// Delivery is what happened to an event on one channel.
+type Delivery struct {
+	Route   string `json:"route"`
+	Channel string `json:"channel"`
+	Type    string `json:"type,omitempty"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// Notifier routes and sends events.
+type Notifier struct {
+	Config *Config
+	// StatePath is the file recording when each event was last sent to each channel.
+	StatePath string
+	// Now is the clock used for quiet hours and dedupe; nil means time.Now.
+	Now func() time.Time
+	// Senders overrides the senders by channel type.
+	Senders map[string]Sender
+}
+
+// Sender delivers an event to one channel.
+type Sender interface {
+	Send(ctx context.Context, ch Channel, ev Event) error
+}
+
+// Path returns the location of notify.yaml.
+func Path(ctx context.Context) (string, error) {
+	base, err := config.ConfigDir(ctx)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, configFileName), nil
+}
+
+// LoadDefault reads notify.yaml from the config dir. Without the file it returns nil
+// and no error: notifications are off.
+func LoadDefault(ctx context.Context) (*Notifier, error) {
+	p, err := Path(ctx)
+	if err != nil {
+		return nil, err
+	}
+	n, err := Load(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return n, err
+}
+
+// Load reads and validates a notify config file.
+func Load(configPath string) (*Notifier, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parse %s: %w", configPath, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", configPath, err)
+	}
+	return &Notifier{Config: &cfg, StatePath: filepath.Join(filepath.Dir(configPath), stateFileName)}, nil
+}
+
+// Validate checks channel types, route references, durations, and quiet hours.
+func (c *Config) Validate() error {
+	var errs []error
+	names := make([]string, 0, len(c.Channels))
+	for name := range c.Channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ch := c.Channels[name]
+		switch strings.ToLower(ch.Type) {
+		case "slack":
+			if ch.Channel == "" {
+				errs = append(errs, fmt.Errorf("channel %s: slack needs channel", name))
+			}
+		case "email":
+			if len(ch.To) == 0 || ch.From == "" || ch.SMTP == "" {
+				errs = append(errs, fmt.Errorf("channel %s: email needs to, from, and smtp", name))
+			}
+		case "pagerduty", "none":
+		default:
+			errs = append(errs, fmt.Errorf("channel %s: unsupported type %q (slack, email, pagerduty, none)", name, ch.Type))
+		}
+	}
+	if _, err := parseDedupe(c.Dedupe, DefaultDedupe); err != nil {
+		errs = append(errs, fmt.Errorf("dedupe: %w", err))
+	}
+	for i, route := range c.Routes {
+		label := route.label(i)
+		if len(route.Channels) == 0 {
+			errs = append(errs, fmt.Errorf("route %s: no channels; use none to drop events", label))
+		}
+		for _, name := range route.Channels {
+			if _, ok := c.Channels[name]; !ok && name != "none" {
+				errs = append(errs, fmt.Errorf("route %s: undefined channel %s", label, name))
+			}
+		}
+		if m := route.Match.MinSeverity; m != "" {
+			if _, ok := severityRanks[strings.ToLower(m)]; !ok {
+				errs = append(errs, fmt.Errorf("route %s: unknown min_severity %q", label, m))
+			}
+		}
+		if _, err := parseDedupe(route.Dedupe, 0); err != nil {
+			errs = append(errs, fmt.Errorf("route %s: dedupe: %w", label, err))
+		}
+	}
+	if q := c.QuietHours; q != nil {
+		if _, _, err := q.window(); err != nil {
+			errs = append(errs, fmt.Errorf("quiet_hours: %w", err))
+		}
+		if _, err := loadZone(q.Zone); err != nil {
+			errs = append(errs, fmt.Errorf("quiet_hours: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r Route) label(idx int) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return fmt.Sprintf("#%d", idx+1)
+}
+
+// Matches reports whether the route applies to the event.
+func (m Match) Matches(ev Event) bool {
+	if !matchAny(m.Kind, ev.Kind) || !matchAny(m.Severity, ev.Severity) ||
+		!matchAny(m.Service, ev.Service) || !matchAny(m.Environment, ev.Environment) {
+		return false
+	}
+	if m.MinSeverity != "" && SeverityRank(ev.Severity) < SeverityRank(m.MinSeverity) {
+		return false
+	}
+	return true
+}
+
+// SeverityRank orders severities from info (0) to critical (4); unknown ones rank -1.
+func SeverityRank(severity string) int {
+	if rank, ok := severityRanks[strings.ToLower(severity)]; ok {
+		return rank
+	}
+	return -1
+}
+
+func matchAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if strings.EqualFold(pattern, value) {
+			return true
+		}
+		if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(value)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Notify routes the event and sends it to every channel it is due on. With dryRun
+// nothing is sent or recorded and the deliveries say what would happen. Send failures
+// are reported per delivery; the error is only for the dedupe state.
+func (n *Notifier) Notify(ctx context.Context, ev Event, dryRun bool) ([]Delivery, error) {
+	if ev.Environment == "" {
+		ev.Environment = n.Config.Environment
+	}
+	now := time.Now()
+	if n.Now != nil {
+		now = n.Now()
+	}
+	state, err := n.readState()
+	if err != nil {
+		return nil, err
+	}
+	quiet := n.Config.QuietHours.active(now, ev.Severity)
+
+	var deliveries []Delivery
+	sent := false
+	for i, route := range n.Config.Routes {
+		if !route.Match.Matches(ev) {
+			continue
+		}
+		window, _ := parseDedupe(n.Config.Dedupe, DefaultDedupe)
+		if route.Dedupe != "" {
+			window, _ = parseDedupe(route.Dedupe, 0)
+		}
+		for _, name := range route.Channels {
+			d := Delivery{Route: route.label(i), Channel: name}
+			ch := n.Config.Channels[name]
+			d.Type = strings.ToLower(ch.Type)
+			key := name + "|" + ev.DedupeKey()
+			switch {
+			case name == "none" || d.Type == "none":
+				d.Type, d.Status = "none", StatusDropped
+			case quiet && (route.QuietHours == nil || *route.QuietHours):
+				d.Status, d.Reason = StatusSuppressed, "quiet hours"
+			case window > 0 && now.Sub(state[key]) < window:
+				d.Status, d.Reason = StatusDeduped, fmt.Sprintf("sent %s ago", now.Sub(state[key]).Round(time.Second))
+			case dryRun:
+				d.Status = StatusDryRun
+			default:
+				if err := n.sender(d.Type).Send(ctx, ch, ev); err != nil {
+					d.Status, d.Reason = StatusFailed, err.Error()
+				} else {
+					d.Status = StatusSent
+					state[key] = now
+					sent = true
+				}
+			}
+			deliveries = append(deliveries, d)
+		}
+		if !route.Continue {
+			break
+		}
+	}
+	if sent {
+		if err := n.writeState(state, now); err != nil {
+			return deliveries, err
+		}
+	}
+	return deliveries, nil
+}
+
+func (n *Notifier) sender(kind string) Sender {
+	if s, ok := n.Senders[kind]; ok {
+		return s
+	}
+	switch kind {
+	case "slack":
+		return slackSender{}
+	case "email":
+		return emailSender{}
+	default:
+		return pagerDutySender{}
+	}
+}
+
+// active reports whether now falls in quiet hours for an event of the severity.
+func (q *QuietHours) active(now time.Time, severity string) bool {
+	if q == nil {
+		return false
+	}
+	except := q.Except
+	if except == nil {
+		except = []string{"critical"}
+	}
+	if matchAny(except, severity) {
+		return false
+	}
+	start, end, err := q.window()
+	if err != nil {
+		return false
+	}
+	zone, err := loadZone(q.Zone)
+	if err != nil {
+		return false
+	}
+	local := now.In(zone)
+	minute := local.Hour()*60 + local.Minute()
+	if start <= end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// window returns Start and End as minutes past midnight.
+func (q *QuietHours) window() (int, int, error) {
+	start, err := clockMinutes(q.Start)
+	if err != nil {
+		return 0, 0, fmt.Errorf("start: %w", err)
+	}
+	end, err := clockMinutes(q.End)
+	if err != nil {
+		return 0, 0, fmt.Errorf("end: %w", err)
+	}
+	return start, end, nil
+}
+
+func clockMinutes(value string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func loadZone(name string) (*time.Location, error) {
+	if name == "" || strings.EqualFold(name, "local") {
+		return time.Local, nil
+	}
+	return time.LoadLocation(name)
+}
+
+func parseDedupe(value string, fallback time.Duration) (time.Duration, error) {
+	if strings.TrimSpace(value) == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%s is negative", value)
+	}
+	return d, nil
+}
+
+func (n *Notifier) readState() (map[string]time.Time, error) {
+	state := map[string]time.Time{}
+	if n.StatePath == "" {
+		return state, nil
+	}
+	data, err := os.ReadFile(n.StatePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read notify state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("parse notify state %s: %w", n.StatePath, err)
+	}
+	return state, nil
+}
+
+// writeState saves the send times, dropping entries older than any dedupe window.
+func (n *Notifier) writeState(state map[string]time.Time, now time.Time) error {
+	if n.StatePath == "" {
+		return nil
+	}
+	longest, _ := parseDedupe(n.Config.Dedupe, DefaultDedupe)
+	for _, route := range n.Config.Routes {
+		if d, _ := parseDedupe(route.Dedupe, 0); d > longest {
+			longest = d
+		}
+	}
+	kept := make(map[string]time.Time, len(state))
+	for key, at := range state {
+		if now.Sub(at) < longest {
+			kept[key] = at
+		}
+	}
+	data, err := json.MarshalIndent(kept, "", "  ")
+	if err != nil {
+		return err
+	}
+	return config.WriteFile(n.StatePath, append(data, '\n'))
+}
@@ -0,0 +1,261 @@
+package notify
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/providers"
+)
+
+const (
+	// PageCapability must be granted (--cap page) before anything pages a human.
+	PageCapability = "page"
+	// DefaultPageWindow is how long a dedup key is not paged again from this machine.
+	DefaultPageWindow = time.Hour
+
+	pagesFileName      = "pages.json"
+	opsgenieAlertsURL  = "https://api.opsgenie.com/v2/alerts"
+	defaultOpsgenieEnv = "OPSGENIE_API_KEY"
+)
+
+// Page statuses.
+const (
+	PagePaged   = "paged"
+	PageDeduped = "deduped"
+	PageDryRun  = "dry_run"
+)
+
+// Page is an incident to open with a paging provider.
+type Page struct {
+	// Provider is pagerduty or opsgenie.
+	Provider string `json:"provider"`
+	Service  string `json:"service,omitempty"`
+	Severity string `json:"severity"`
+	Title    string `json:"title"`
+	Body     string `json:"body,omitempty"`
+	// DedupKey makes repeated pages for the same problem join one incident: PagerDuty's
+	// dedup_key and Opsgenie's alias.
+	DedupKey    string `json:"dedup_key"`
+	Environment string `json:"environment,omitempty"`
+	// KeyEnv names the environment variable holding the PagerDuty routing key (default
+	// PAGERDUTY_ROUTING_KEY) or Opsgenie API key (default OPSGENIE_API_KEY).
+	KeyEnv string `json:"-"`
+	// URL overrides the provider endpoint, e.g. for an EU account.
+	URL string `json:"-"`
+}
+
+// PageResult is the outcome of a page.
+type PageResult struct {
+	Page   Page      `json:"page"`
+	Status string    `json:"status"`
+	At     time.Time `json:"at"`
+	// Previous is when the dedup key was last paged, for deduped pages.
+	Previous *time.Time `json:"previous,omitempty"`
+}
+
+// opsgeniePriorities maps severities onto Opsgenie priorities.
+var opsgeniePriorities = map[string]string{"critical": "P1", "high": "P2", "medium": "P3", "warning": "P3", "low": "P4", "info": "P5"}
+
+// Validate checks the provider, severity, and that there is something to say.
+func (p Page) Validate() error {
+	switch strings.ToLower(p.Provider) {
+	case "pagerduty", "opsgenie":
+	default:
+		return fmt.Errorf("page provider must be pagerduty or opsgenie, got %q", p.Provider)
+	}
+	if SeverityRank(p.Severity) < 0 {
+		return fmt.Errorf("unknown page severity %q (info, low, warning, medium, high, critical)", p.Severity)
+	}
+	if strings.TrimSpace(p.Title) == "" {
+		return errors.New("page needs a title")
+	}
+	if strings.TrimSpace(p.DedupKey) == "" {
+		return errors.New("page needs a dedup key")
+	}
+	return nil
+}
+
+// PageDedupKey derives a stable dedup key from what identifies the problem, such as
+// the workflow, step, and service, so re-running the same thing does not page twice.
+func PageDedupKey(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return "sre-ai-" + hex.EncodeToString(sum[:8])
+}
+
+// LastPaged returns when the dedup key was last paged from this machine.
+func LastPaged(ctx context.Context, key string) (time.Time, bool, error) {
+	ledger, err := readPages(ctx)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	at, ok := ledger[key]
+	return at, ok, nil
+}
+
+// RecentPage reports the last page of the key if it falls within window.
+func RecentPage(ctx context.Context, key string, window time.Duration, now time.Time) (*time.Time, error) {
+	at, ok, err := LastPaged(ctx, key)
+	if err != nil || !ok || now.Sub(at) >= window {
+		return nil, err
+	}
+	return &at, nil
+}
+
+// SendPage opens the incident with the provider and records the dedup key.
+func SendPage(ctx context.Context, p Page) (PageResult, error) {
+	if err := p.Validate(); err != nil {
+		return PageResult{}, err
+	}
+	var err error
+	switch strings.ToLower(p.Provider) {
+	case "pagerduty":
+		err = pagerDutySender{}.Send(ctx, Channel{RoutingKeyEnv: p.KeyEnv, URL: p.URL}, p.event())
+	case "opsgenie":
+		err = sendOpsgenie(ctx, p)
+	}
+	if err != nil {
+		return PageResult{}, err
+	}
+	now := time.Now()
+	if err := recordPage(ctx, p.DedupKey, now); err != nil {
+		return PageResult{Page: p, Status: PagePaged, At: now}, fmt.Errorf("paged, but could not record dedup key: %w", err)
+	}
+	return PageResult{Page: p, Status: PagePaged, At: now}, nil
+}
+
+func (p Page) event() Event {
+	return Event{Kind: "page", Severity: p.Severity, Service: p.Service, Environment: p.Environment, Title: p.Title, Text: p.Body, Key: p.DedupKey}
+}
+
+func sendOpsgenie(ctx context.Context, p Page) error {
+	key, env := secret(p.KeyEnv, defaultOpsgenieEnv)
+	if key == "" {
+		return fmt.Errorf("opsgenie: %s is not set", env)
+	}
+	priority, ok := opsgeniePriorities[strings.ToLower(p.Severity)]
+	if !ok {
+		priority = "P3"
+	}
+	payload := map[string]any{
+		// Opsgenie folds an alert into the open one with the same alias.
+		"alias":       p.DedupKey,
+		"message":     truncate(p.Title, 130),
+		"description": p.Body,
+		"priority":    priority,
+		"source":      "sre-ai",
+		"tags":        []string{"sre-ai"},
+	}
+	if p.Service != "" {
+		payload["entity"] = p.Service
+	}
+	endpoint := p.URL
+	if endpoint == "" {
+		endpoint = opsgenieAlertsURL
+	}
+	if err := postJSON(ctx, endpoint, "GenieKey "+key, payload, nil); err != nil {
+		return fmt.Errorf("opsgenie: %w", err)
+	}
+	return nil
+}
+
+// truncate shortens s to at most n runes, Opsgenie's limit for alert messages.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+var pagesMu sync.Mutex
+
+func pagesPath(ctx context.Context) (string, error) {
+	base, err := config.ConfigDir(ctx)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, pagesFileName), nil
+}
+
+func readPages(ctx context.Context) (map[string]time.Time, error) {
+	path, err := pagesPath(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ledger := map[string]time.Time{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ledger, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read pages: %w", err)
+	}
+	if err := json.Unmarshal(data, &ledger); err != nil {
+		return nil, fmt.Errorf("parse pages %s: %w", path, err)
+	}
+	return ledger, nil
+}
+
+// recordPage notes the key, dropping entries old enough not to matter to any window.
+func recordPage(ctx context.Context, key string, at time.Time) error {
+	pagesMu.Lock()
+	defer pagesMu.Unlock()
+	ledger, err := readPages(ctx)
+	if err != nil {
+		return err
+	}
+	ledger[key] = at
+	for k, t := range ledger {
+		if at.Sub(t) > 30*24*time.Hour {
+			delete(ledger, k)
+		}
+	}
+	path, err := pagesPath(ctx)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(ledger, "", "  ")
+	if err != nil {
+		return err
+	}
+	return config.WriteFile(path, append(data, '\n'))
+}
+
+// DraftPrompt asks a model to write a page from what is known about the incident. The
+// reply is read with ParseDraft.
+func DraftPrompt(context string) string {
+	return `You are paging the on-call engineer about a production incident. Write the page
+from the context below: a title of at most 100 characters naming the symptom and the
+affected service, and a body of a few short lines covering impact, when it started,
+and what has been checked so far. Do not speculate beyond the context.
+
+Answer with JSON only: {"title": "...", "body": "..."}
+
+Context:
+` + context
+}
+
+// ParseDraft reads a model's reply to DraftPrompt.
+func ParseDraft(reply string) (title, body string, err error) {
+	var draft struct {
+		Title string `json:"title"`
+		Body  string `json:"body"`
+	}
+	if err := json.Unmarshal([]byte(providers.StripCodeFence(reply)), &draft); err != nil {
+		return "", "", fmt.Errorf("draft is not the expected JSON: %w", err)
+	}
+	if strings.TrimSpace(draft.Title) == "" {
+		return "", "", errors.New("draft has no title")
+	}
+	return strings.TrimSpace(draft.Title), strings.TrimSpace(draft.Body), nil
+}
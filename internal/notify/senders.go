@@ -0,0 +1,199 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/example/sre-ai/internal/httpx"
+)
+
+const (
+	slackAPIURL          = "https://slack.com/api/chat.postMessage"
+	pagerDutyEventsURL   = "https://events.pagerduty.com/v2/enqueue"
+	defaultSlackTokenEnv = "SLACK_BOT_TOKEN"
+	defaultPagerDutyEnv  = "PAGERDUTY_ROUTING_KEY"
+)
+
+var httpClient = httpx.Client(30 * time.Second)
+
+// summary is the one-line form of an event used as a Slack headline, email subject,
+// and PagerDuty summary.
+func summary(ev Event) string {
+	line := fmt.Sprintf("[%s] %s", strings.ToUpper(ev.Severity), ev.Title)
+	var where []string
+	if ev.Service != "" {
+		where = append(where, ev.Service)
+	}
+	if ev.Environment != "" {
+		where = append(where, ev.Environment)
+	}
+	if len(where) > 0 {
+		line += " (" + strings.Join(where, ", ") + ")"
+	}
+	return line
+}
+
+func body(ev Event) string {
+	text := ev.Text
+	if ev.RunID != "" {
+		text = strings.TrimSpace(text + "\n\nsre-ai run " + ev.RunID)
+	}
+	return text
+}
+
+func secret(envName, fallback string) (string, string) {
+	if envName == "" {
+		envName = fallback
+	}
+	return strings.TrimSpace(os.Getenv(envName)), envName
+}
+
+type slackSender struct{}
+
+func (slackSender) Send(ctx context.Context, ch Channel, ev Event) error {
+	token, env := secret(ch.TokenEnv, defaultSlackTokenEnv)
+	if token == "" {
+		return fmt.Errorf("%s is not set", env)
+	}
+	text := "*" + summary(ev) + "*"
+	if b := body(ev); b != "" {
+		text += "\n" + b
+	}
+	endpoint := ch.URL
+	if endpoint == "" {
+		endpoint = slackAPIURL
+	}
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := postJSON(ctx, endpoint, "Bearer "+token, map[string]any{"channel": ch.Channel, "text": text}, &status); err != nil {
+		return fmt.Errorf("slack: %w", err)
+	}
+	if !status.OK {
+		return fmt.Errorf("slack: %s", status.Error)
+	}
+	return nil
+}
+
+// pagerDutySeverities maps event severities onto the Events API v2 ones.
+var pagerDutySeverities = map[string]string{"critical": "critical", "high": "error", "medium": "warning", "warning": "warning", "low": "warning", "info": "info"}
+
+type pagerDutySender struct{}
+
+func (pagerDutySender) Send(ctx context.Context, ch Channel, ev Event) error {
+	key, env := secret(ch.RoutingKeyEnv, defaultPagerDutyEnv)
+	if key == "" {
+		return fmt.Errorf("%s is not set", env)
+	}
+	severity, ok := pagerDutySeverities[strings.ToLower(ev.Severity)]
+	if !ok {
+		severity = "warning"
+	}
+	source := ev.Environment
+	if source == "" {
+		source = "sre-ai"
+	}
+	payload := map[string]any{
+		"routing_key":  key,
+		"event_action": "trigger",
+		// PagerDuty groups triggers with the same dedup_key into one incident.
+		"dedup_key": ev.DedupeKey(),
+		"payload": map[string]any{
+			"summary":   summary(ev),
+			"source":    source,
+			"severity":  severity,
+			"component": ev.Service,
+			"group":     ev.Kind,
+			"custom_details": map[string]any{
+				"text":   ev.Text,
+				"run_id": ev.RunID,
+			},
+		},
+	}
+	endpoint := ch.URL
+	if endpoint == "" {
+		endpoint = pagerDutyEventsURL
+	}
+	if err := postJSON(ctx, endpoint, "", payload, nil); err != nil {
+		return fmt.Errorf("pagerduty: %w", err)
+	}
+	return nil
+}
+
+// postJSON posts payload with the given Authorization header, if any, and decodes the
+// reply into out.
+func postJSON(ctx context.Context, endpoint, auth string, payload any, out any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(raw)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(raw, out)
+}
+
+type emailSender struct{}
+
+func (emailSender) Send(ctx context.Context, ch Channel, ev Event) error {
+	host, _, err := net.SplitHostPort(ch.SMTP)
+	if err != nil {
+		return fmt.Errorf("email: smtp %q: %w", ch.SMTP, err)
+	}
+	var auth smtp.Auth
+	if ch.UsernameEnv != "" {
+		user, _ := secret(ch.UsernameEnv, "")
+		password, env := secret(ch.PasswordEnv, "")
+		if password == "" {
+			return fmt.Errorf("email: %s is not set", env)
+		}
+		auth = smtp.PlainAuth("", user, password, host)
+	}
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", ch.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(ch.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", strings.NewReplacer("\r", " ", "\n", " ").Replace(summary(ev)))
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body(ev), "\n", "\r\n"))
+	msg.WriteString("\r\n")
+
+	// smtp.SendMail takes no context; run it aside so a cancelled run does not hang on it.
+	done := make(chan error, 1)
+	go func() { done <- smtp.SendMail(ch.SMTP, auth, ch.From, ch.To, []byte(msg.String())) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("email: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("email: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,47 @@
+// Package principal names who started an action, so run records, audit entries, and
+// confirmations in a shared deployment can be traced back to a person or key. A
+// principal is the OS user by default. A front end acting for someone else sets one
+// with a kind prefix, e.g. "key:ci-bot" for an API key or "slack:U024BE7LH".
+package principal
+
+import (
+	"context"
+	"os"
+	"os/user"
+	"strings"
+)
+
+type contextKey struct{}
+
+// WithName returns a context whose actions are attributed to name; empty falls back to
+// the OS user.
+func WithName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, contextKey{}, strings.TrimSpace(name))
+}
+
+// Current returns the principal set with WithName, else the OS user.
+func Current(ctx context.Context) string {
+	if name, _ := ctx.Value(contextKey{}).(string); name != "" {
+		return name
+	}
+	return OSUser()
+}
+
+// OSUser returns the login name from $USER or $USERNAME, else the account running the
+// process, or "" when neither is known.
+func OSUser() string {
+	for _, key := range []string{"USER", "USERNAME"} {
+		if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+			return v
+		}
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+// Slack is the principal for a Slack user ID.
+func Slack(userID string) string {
+	return "slack:" + userID
+}
@@ -0,0 +1,260 @@
+// Package prompts is a library of reusable, parameterized prompt snippets for one-off
+// asks that do not need a workflow. Snippets are text/template strings rendered with
+// the values given for their vars and, optionally, input text such as a pasted log.
+package prompts
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/templatex"
+	"github.com/example/sre-ai/internal/warnings"
+)
+
+const promptsDirName = "prompts"
+
+// Input modes.
+const (
+	InputRequired = "required"
+	InputOptional = "optional"
+)
+
+//go:embed builtin.yaml
+var builtinPrompts []byte
+
+// Prompt is one snippet.
+type Prompt struct {
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description" json:"description,omitempty"`
+	Vars        []Var  `yaml:"vars" json:"vars,omitempty"`
+	// Input is "required" or "optional" for a prompt that reads text, such as a
+	// channel export, available to the template as .input. Empty means none.
+	Input    string `yaml:"input" json:"input,omitempty"`
+	Template string `yaml:"template" json:"template"`
+	// Source is "builtin" or the file the prompt came from.
+	Source string `yaml:"-" json:"source"`
+}
+
+// Var is a parameter of a prompt, available to the template as .vars.<name>.
+type Var struct {
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description" json:"description,omitempty"`
+	Default     string `yaml:"default" json:"default,omitempty"`
+	Required    bool   `yaml:"required" json:"required,omitempty"`
+}
+
+type promptFile struct {
+	Prompts []Prompt `yaml:"prompts"`
+}
+
+// Library is a collection of prompts by name.
+type Library struct {
+	prompts []Prompt
+}
+
+// Dir returns the directory holding user prompt files.
+func Dir(ctx context.Context) (string, error) {
+	base, err := config.ConfigDir(ctx)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, promptsDirName), nil
+}
+
+// Builtin returns the prompts shipped with the CLI.
+func Builtin() ([]Prompt, error) {
+	return parse(builtinPrompts, "builtin")
+}
+
+// LoadFile reads the prompts of one YAML file.
+func LoadFile(path string) ([]Prompt, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parse(data, path)
+}
+
+// Load returns the built-in prompts overlaid with every *.yaml or *.yml file in dir,
+// where a prompt with the same name replaces the earlier one. Unreadable or invalid
+// files are skipped with a warning on ctx. A missing dir yields only the built-ins.
+func Load(ctx context.Context, dir string) (*Library, error) {
+	all, err := Builtin()
+	if err != nil {
+		return nil, fmt.Errorf("builtin prompts: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		warnings.Add(ctx, "prompts", "skipped %s: %v", dir, err)
+	}
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		loaded, err := LoadFile(path)
+		if err == nil {
+			err = check(loaded)
+		}
+		if err != nil {
+			warnings.Add(ctx, "prompts", "skipped %s: %v", path, err)
+			continue
+		}
+		all = overlay(all, loaded)
+	}
+	if err := check(all); err != nil {
+		return nil, err
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+	return &Library{prompts: all}, nil
+}
+
+// LoadDefault loads prompts from Dir.
+func LoadDefault(ctx context.Context) (*Library, error) {
+	dir, err := Dir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Load(ctx, dir)
+}
+
+func parse(data []byte, source string) ([]Prompt, error) {
+	var file promptFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	for i := range file.Prompts {
+		file.Prompts[i].Source = source
+	}
+	return file.Prompts, nil
+}
+
+func overlay(base, extra []Prompt) []Prompt {
+	index := make(map[string]int, len(base))
+	for i, p := range base {
+		index[p.Name] = i
+	}
+	for _, p := range extra {
+		if i, ok := index[p.Name]; ok {
+			base[i] = p
+			continue
+		}
+		index[p.Name] = len(base)
+		base = append(base, p)
+	}
+	return base
+}
+
+// check validates prompt names, vars, and templates.
+func check(prompts []Prompt) error {
+	seen := map[string]bool{}
+	for _, p := range prompts {
+		if strings.TrimSpace(p.Name) == "" {
+			return errors.New("prompt without a name")
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("prompt %s is defined twice", p.Name)
+		}
+		seen[p.Name] = true
+		if strings.TrimSpace(p.Template) == "" {
+			return fmt.Errorf("prompt %s: template is empty", p.Name)
+		}
+		switch p.Input {
+		case "", InputRequired, InputOptional:
+		default:
+			return fmt.Errorf("prompt %s: input must be required or optional, got %q", p.Name, p.Input)
+		}
+		vars := map[string]bool{}
+		for _, v := range p.Vars {
+			if strings.TrimSpace(v.Name) == "" {
+				return fmt.Errorf("prompt %s: var without a name", p.Name)
+			}
+			if vars[v.Name] {
+				return fmt.Errorf("prompt %s: var %s is declared twice", p.Name, v.Name)
+			}
+			vars[v.Name] = true
+		}
+		if _, err := parseTemplate(p); err != nil {
+			return fmt.Errorf("prompt %s: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+func parseTemplate(p Prompt) (*template.Template, error) {
+	return template.New(p.Name).Option("missingkey=error").Parse(p.Template)
+}
+
+// Prompts returns the library's prompts sorted by name.
+func (l *Library) Prompts() []Prompt {
+	return append([]Prompt(nil), l.prompts...)
+}
+
+// Get returns the prompt named name.
+func (l *Library) Get(name string) (Prompt, bool) {
+	for _, p := range l.prompts {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Prompt{}, false
+}
+
+// Render fills in p's template within limits. Vars missing from values take their
+// default; a required var without a value, a value for a var p does not declare, or
+// input p does not take is an error.
+func (p Prompt) Render(values map[string]string, input string, limits templatex.Limits) (string, error) {
+	vars := make(map[string]string, len(p.Vars))
+	declared := make(map[string]bool, len(p.Vars))
+	var missing []string
+	for _, v := range p.Vars {
+		declared[v.Name] = true
+		value, ok := values[v.Name]
+		if !ok || value == "" {
+			value = v.Default
+		}
+		if value == "" && v.Required {
+			missing = append(missing, v.Name)
+		}
+		vars[v.Name] = value
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("prompt %s needs --var for %s", p.Name, strings.Join(missing, ", "))
+	}
+	var unknown []string
+	for name := range values {
+		if !declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return "", fmt.Errorf("prompt %s has no var %s", p.Name, strings.Join(unknown, ", "))
+	}
+	switch {
+	case p.Input == InputRequired && strings.TrimSpace(input) == "":
+		return "", fmt.Errorf("prompt %s needs input; pass --input", p.Name)
+	case p.Input == "" && input != "":
+		return "", fmt.Errorf("prompt %s does not take input", p.Name)
+	}
+	tmpl, err := parseTemplate(p)
+	if err != nil {
+		return "", err
+	}
+	text, err := templatex.Execute(tmpl, limits, map[string]interface{}{"vars": vars, "input": input})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(text), nil
+}
@@ -0,0 +1,26 @@
+package prompts
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/example/sre-ai/internal/templatex"
+)
+
+func TestRenderStopsAtTheTemplateLimits(t *testing.T) {
+	vars := []Var{{Name: "a", Default: "1"}, {Name: "b", Default: "2"}, {Name: "c", Default: "3"}}
+	nested := Prompt{Name: "nested", Vars: vars, Template: `{{ range .vars }}{{ range $.vars }}{{ range $.vars }}x{{ end }}{{ end }}{{ end }}`}
+	if _, err := nested.Render(nil, "", templatex.Limits{MaxRange: 20}); err == nil || !strings.Contains(err.Error(), "templates.max_range") {
+		t.Fatalf("27 nested iterations with max_range 20: err = %v, want the range limit error", err)
+	}
+	if text, err := nested.Render(nil, "", templatex.Limits{MaxRange: 100}); err != nil || len(text) != 27 {
+		t.Fatalf("within max_range: %q, %v", text, err)
+	}
+
+	echo := Prompt{Name: "echo", Input: InputRequired, Template: `{{ .input }}{{ .input }}`}
+	input := strings.Repeat("log line\n", 1000)
+	_, err := echo.Render(nil, input, templatex.Limits{MaxOutput: int64(len(input))})
+	if err == nil || !strings.Contains(err.Error(), "templates.max_output") {
+		t.Fatalf("output twice the input with max_output of one input: err = %v, want the output limit error", err)
+	}
+}
@@ -0,0 +1,213 @@
+package promql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/sre-ai/internal/httpx"
+)
+
+// URLEnv names the environment variable used when no Prometheus address is given.
+const URLEnv = "PROMETHEUS_URL"
+
+// Sample is one series of an instant query result.
+type Sample struct {
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  float64           `json:"value"`
+}
+
+// Series is one series of a range query result, oldest point first.
+type Series struct {
+	Labels map[string]string `json:"labels,omitempty"`
+	Points []Point           `json:"points"`
+}
+
+// Point is one timestamped value of a series.
+type Point struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// Values returns the values of the series in time order.
+func (s Series) Values() []float64 {
+	values := make([]float64, len(s.Points))
+	for i, p := range s.Points {
+		values[i] = p.Value
+	}
+	return values
+}
+
+// Name renders the series' labels as PromQL does, e.g. up{job="api"}; a series without
+// labels is {}.
+func (s Series) Name() string {
+	keys := make([]string, 0, len(s.Labels))
+	for k := range s.Labels {
+		if k != "__name__" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = fmt.Sprintf("%s=%q", k, s.Labels[k])
+	}
+	name := s.Labels["__name__"]
+	if len(pairs) == 0 && name != "" {
+		return name
+	}
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Client runs queries against the Prometheus HTTP API.
+type Client struct {
+	// BaseURL is the server address, e.g. "http://prometheus:9090"; empty uses $PROMETHEUS_URL.
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// Query evaluates expr at the current time. Vector, scalar, and matrix results are
+// flattened to samples; a matrix keeps the newest point of each series.
+func (c Client) Query(ctx context.Context, expr string) ([]Sample, error) {
+	resultType, result, err := c.get(ctx, "query", url.Values{"query": {expr}})
+	if err != nil {
+		return nil, err
+	}
+
+	switch resultType {
+	case "scalar":
+		var point []interface{}
+		if err := json.Unmarshal(result, &point); err != nil {
+			return nil, fmt.Errorf("prometheus query: %w", err)
+		}
+		v, err := pointValue(point)
+		if err != nil {
+			return nil, err
+		}
+		return []Sample{{Value: v}}, nil
+	case "vector", "matrix":
+		var series []struct {
+			Metric map[string]string `json:"metric"`
+			Value  []interface{}     `json:"value"`
+			Values [][]interface{}   `json:"values"`
+		}
+		if err := json.Unmarshal(result, &series); err != nil {
+			return nil, fmt.Errorf("prometheus query: %w", err)
+		}
+		out := make([]Sample, 0, len(series))
+		for _, s := range series {
+			point := s.Value
+			if len(s.Values) > 0 {
+				point = s.Values[len(s.Values)-1]
+			}
+			v, err := pointValue(point)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, Sample{Labels: s.Metric, Value: v})
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("prometheus query: unsupported result type %q", resultType)
+	}
+}
+
+// QueryRange evaluates expr every step from start to end.
+func (c Client) QueryRange(ctx context.Context, expr string, start, end time.Time, step time.Duration) ([]Series, error) {
+	resultType, result, err := c.get(ctx, "query_range", url.Values{
+		"query": {expr},
+		"start": {strconv.FormatInt(start.Unix(), 10)},
+		"end":   {strconv.FormatInt(end.Unix(), 10)},
+		"step":  {strconv.FormatFloat(step.Seconds(), 'f', -1, 64)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resultType != "matrix" {
+		return nil, fmt.Errorf("prometheus query: unsupported range result type %q", resultType)
+	}
+	var raw []struct {
+		Metric map[string]string `json:"metric"`
+		Values [][]interface{}   `json:"values"`
+	}
+	if err := json.Unmarshal(result, &raw); err != nil {
+		return nil, fmt.Errorf("prometheus query: %w", err)
+	}
+	out := make([]Series, 0, len(raw))
+	for _, r := range raw {
+		series := Series{Labels: r.Metric, Points: make([]Point, 0, len(r.Values))}
+		for _, point := range r.Values {
+			v, err := pointValue(point)
+			if err != nil {
+				return nil, err
+			}
+			ts, ok := point[0].(float64)
+			if !ok {
+				return nil, fmt.Errorf("prometheus query: malformed sample time %v", point[0])
+			}
+			series.Points = append(series.Points, Point{Time: time.Unix(0, int64(ts*float64(time.Second))), Value: v})
+		}
+		out = append(out, series)
+	}
+	return out, nil
+}
+
+// get calls an API endpoint such as query and returns the result type and raw result.
+func (c Client) get(ctx context.Context, endpoint string, params url.Values) (string, json.RawMessage, error) {
+	base := strings.TrimSpace(c.BaseURL)
+	if base == "" {
+		base = strings.TrimSpace(os.Getenv(URLEnv))
+	}
+	if base == "" {
+		return "", nil, fmt.Errorf("no Prometheus address; set it on the query or in $%s", URLEnv)
+	}
+	target := strings.TrimRight(base, "/") + "/api/v1/" + endpoint + "?" + params.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = httpx.Client(30 * time.Second)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("prometheus query: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var decoded struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			ResultType string          `json:"resultType"`
+			Result     json.RawMessage `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return "", nil, fmt.Errorf("prometheus query: %s: %w", resp.Status, err)
+	}
+	if decoded.Status != "success" {
+		return "", nil, fmt.Errorf("prometheus query: %s", decoded.Error)
+	}
+	return decoded.Data.ResultType, decoded.Data.Result, nil
+}
+
+// pointValue reads the value of a [timestamp, "value"] pair.
+func pointValue(point []interface{}) (float64, error) {
+	if len(point) != 2 {
+		return 0, fmt.Errorf("prometheus query: malformed sample %v", point)
+	}
+	text, ok := point[1].(string)
+	if !ok {
+		return 0, fmt.Errorf("prometheus query: malformed sample value %v", point[1])
+	}
+	return strconv.ParseFloat(text, 64)
+}
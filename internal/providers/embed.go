@@ -0,0 +1,200 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+const (
+	defaultOllamaEmbedModel = "nomic-embed-text"
+	defaultGeminiEmbedModel = "text-embedding-004"
+	localEmbedDimensions    = 512
+)
+
+// Embedder turns text into a vector for similarity search.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float64, error)
+}
+
+// NewEmbedder returns an embedder for "local" (no network), "ollama", or "gemini".
+// An empty model selects the provider's default embedding model.
+func NewEmbedder(ctx context.Context, provider, model string) (Embedder, error) {
+	switch strings.ToLower(strings.TrimSpace(provider)) {
+	case "", "local":
+		return localEmbedder{}, nil
+	case "ollama":
+		if model == "" {
+			model = defaultOllamaEmbedModel
+		}
+		return NewOllamaClient(model), nil
+	case "gemini":
+		if model == "" {
+			model = defaultGeminiEmbedModel
+		}
+		client, err := New(ctx, "gemini", model)
+		if err != nil {
+			return nil, err
+		}
+		return client.(*geminiClient), nil
+	default:
+		return nil, fmt.Errorf("embedding provider %s not supported", provider)
+	}
+}
+
+// Embed calls /api/embeddings.
+func (c *ollamaClient) Embed(ctx context.Context, text string) ([]float64, error) {
+	ctx, done, err := beginCall(ctx, "ollama", ollamaCallTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	body, err := json.Marshal(map[string]any{"model": c.model, "prompt": text})
+	if err != nil {
+		return nil, err
+	}
+	var decoded struct {
+		Embedding []float64 `json:"embedding"`
+		Error     string    `json:"error"`
+	}
+	if err := postJSON(ctx, c.httpClient, c.baseURL+"/api/embeddings", body, &decoded); err != nil {
+		return nil, fmt.Errorf("ollama api error: %w", err)
+	}
+	if decoded.Error != "" {
+		return nil, fmt.Errorf("ollama api error: %s", decoded.Error)
+	}
+	return decoded.Embedding, nil
+}
+
+// Embed calls the Gemini embedContent API.
+func (c *geminiClient) Embed(ctx context.Context, text string) ([]float64, error) {
+	ctx, done, err := beginCall(ctx, "gemini", geminiCallTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	body, err := json.Marshal(map[string]any{
+		"content": geminiContent{Parts: []geminiParts{{Text: text}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var decoded struct {
+		Embedding struct {
+			Values []float64 `json:"values"`
+		} `json:"embedding"`
+	}
+	url := fmt.Sprintf("%s/%s:embedContent?key=%s", geminiAPIBaseURL, c.model, c.apiKey)
+	if err := postJSON(ctx, c.httpClient, url, body, &decoded); err != nil {
+		return nil, fmt.Errorf("gemini api error: %w", err)
+	}
+	return decoded.Embedding.Values, nil
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, body []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s", bytes.TrimSpace(data))
+	}
+	return json.Unmarshal(data, out)
+}
+
+// localEmbedder hashes distinct words and word pairs into a fixed-size, L2-normalised vector.
+// It needs no model or network and is good enough to match incidents that share
+// failure reasons and component names.
+type localEmbedder struct{}
+
+func (localEmbedder) Embed(_ context.Context, text string) ([]float64, error) {
+	vec := make([]float64, localEmbedDimensions)
+	tokens := embedTokens(text)
+	seen := map[string]bool{}
+	add := func(feature string, weight float64) {
+		// Count each feature once so repeated words in long notes do not dominate.
+		if seen[feature] {
+			return
+		}
+		seen[feature] = true
+		h := fnv.New32a()
+		h.Write([]byte(feature))
+		sum := h.Sum32()
+		sign := 1.0
+		if sum&1 == 1 {
+			sign = -1.0
+		}
+		vec[(sum>>1)%localEmbedDimensions] += sign * weight
+	}
+	for i, tok := range tokens {
+		add(tok, 1)
+		if i > 0 {
+			add(tokens[i-1]+" "+tok, 0.5)
+		}
+	}
+	var norm float64
+	for _, v := range vec {
+		norm += v * v
+	}
+	if norm > 0 {
+		norm = math.Sqrt(norm)
+		for i := range vec {
+			vec[i] /= norm
+		}
+	}
+	return vec, nil
+}
+
+var embedStopwords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "pod": true, "pods": true,
+	"from": true, "this": true, "that": true, "are": true, "was": true, "seen": true,
+	"namespace": true, "namespaces": true, "node": true, "restarts": true,
+}
+
+func embedTokens(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	tokens := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if len(f) < 3 || embedStopwords[f] || strings.IndexFunc(f, unicode.IsLetter) == -1 {
+			continue
+		}
+		tokens = append(tokens, f)
+	}
+	return tokens
+}
+
+// Cosine returns the cosine similarity of two vectors, or 0 when their sizes differ.
+func Cosine(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
@@ -7,7 +7,6 @@ import (
     "fmt"
     "io"
     "net/http"
-    "time"
 )
 
 const (
@@ -34,9 +33,7 @@ func NewGeminiClient(apiKey, model string) *geminiClient {
     return &geminiClient{
         apiKey: apiKey,
         model:  model,
-        httpClient: &http.Client{
-            Timeout: 60 * time.Second,
-        },
+        httpClient: sharedHTTPClient,
     }
 }
 
@@ -62,10 +59,25 @@ type geminiResponse struct {
         } `json:"content"`
     } `json:"candidates"`
     PromptFeedback any `json:"promptFeedback,omitempty"`
+    UsageMetadata  struct {
+        PromptTokenCount     int `json:"promptTokenCount"`
+        CandidatesTokenCount int `json:"candidatesTokenCount"`
+    } `json:"usageMetadata"`
 }
 
 // Generate runs a single prompt against the Gemini generateContent API.
 func (c *geminiClient) Generate(ctx context.Context, prompt string) (string, error) {
+    text, _, err := c.generateWithUsage(ctx, prompt)
+    return text, err
+}
+
+func (c *geminiClient) generateWithUsage(ctx context.Context, prompt string) (string, Usage, error) {
+    ctx, done, err := beginCall(ctx, "gemini", geminiCallTimeout)
+    if err != nil {
+        return "", Usage{}, err
+    }
+    defer done()
+
     payload := geminiRequest{
         Contents: []geminiContent{
             {
@@ -77,39 +89,43 @@ func (c *geminiClient) Generate(ctx context.Context, prompt string) (string, err
 
     body, err := json.Marshal(payload)
     if err != nil {
-        return "", err
+        return "", Usage{}, err
     }
 
     url := fmt.Sprintf("%s/%s:generateContent?key=%s", geminiAPIBaseURL, c.model, c.apiKey)
     req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
     if err != nil {
-        return "", err
+        return "", Usage{}, err
     }
     req.Header.Set("Content-Type", "application/json")
 
     resp, err := c.httpClient.Do(req)
     if err != nil {
-        return "", err
+        return "", Usage{}, err
     }
     defer resp.Body.Close()
 
     data, err := io.ReadAll(resp.Body)
     if err != nil {
-        return "", err
+        return "", Usage{}, err
     }
 
     if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-        return "", fmt.Errorf("gemini api error: %s", bytes.TrimSpace(data))
+        return "", Usage{}, fmt.Errorf("gemini api error: %s", bytes.TrimSpace(data))
     }
 
     var decoded geminiResponse
     if err := json.Unmarshal(data, &decoded); err != nil {
-        return "", err
+        return "", Usage{}, err
     }
 
     if len(decoded.Candidates) == 0 || len(decoded.Candidates[0].Content.Parts) == 0 {
-        return "", fmt.Errorf("gemini api returned no candidates")
+        return "", Usage{}, fmt.Errorf("gemini api returned no candidates")
     }
 
-    return decoded.Candidates[0].Content.Parts[0].Text, nil
+    usage := Usage{
+        PromptTokens: decoded.UsageMetadata.PromptTokenCount,
+        OutputTokens: decoded.UsageMetadata.CandidatesTokenCount,
+    }
+    return decoded.Candidates[0].Content.Parts[0].Text, usage, nil
 }
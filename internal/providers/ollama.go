@@ -0,0 +1,99 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const (
+	defaultOllamaHost  = "http://127.0.0.1:11434"
+	defaultOllamaModel = "llama3.1"
+)
+
+type ollamaClient struct {
+	baseURL    string
+	model      string
+	httpClient *http.Client
+}
+
+// NewOllamaClient creates a client for a local Ollama server. OLLAMA_HOST overrides the address.
+func NewOllamaClient(model string) *ollamaClient {
+	if model == "" {
+		model = defaultOllamaModel
+	}
+	host := strings.TrimSpace(os.Getenv("OLLAMA_HOST"))
+	if host == "" {
+		host = defaultOllamaHost
+	}
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	return &ollamaClient{
+		baseURL: strings.TrimRight(host, "/"),
+		model:   model,
+		httpClient: sharedHTTPClient,
+	}
+}
+
+// Generate runs a single non-streaming prompt against /api/generate.
+func (c *ollamaClient) Generate(ctx context.Context, prompt string) (string, error) {
+	text, _, err := c.generateWithUsage(ctx, prompt)
+	return text, err
+}
+
+func (c *ollamaClient) generateWithUsage(ctx context.Context, prompt string) (string, Usage, error) {
+	ctx, done, err := beginCall(ctx, "ollama", ollamaCallTimeout)
+	if err != nil {
+		return "", Usage{}, err
+	}
+	defer done()
+
+	body, err := json.Marshal(map[string]any{
+		"model":  c.model,
+		"prompt": prompt,
+		"stream": false,
+	})
+	if err != nil {
+		return "", Usage{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/generate", bytes.NewReader(body))
+	if err != nil {
+		return "", Usage{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", Usage{}, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", Usage{}, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", Usage{}, fmt.Errorf("ollama api error: %s", bytes.TrimSpace(data))
+	}
+
+	var decoded struct {
+		Response        string `json:"response"`
+		Error           string `json:"error"`
+		PromptEvalCount int    `json:"prompt_eval_count"`
+		EvalCount       int    `json:"eval_count"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return "", Usage{}, err
+	}
+	if decoded.Error != "" {
+		return "", Usage{}, fmt.Errorf("ollama api error: %s", decoded.Error)
+	}
+	return decoded.Response, Usage{PromptTokens: decoded.PromptEvalCount, OutputTokens: decoded.EvalCount}, nil
+}
@@ -0,0 +1,133 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/sre-ai/internal/httpx"
+)
+
+// defaultMaxInFlight bounds concurrent calls per provider unless config sets
+// providers.max_in_flight. A local Ollama serves one request at a time by default.
+var defaultMaxInFlight = map[string]int{"gemini": 4, "ollama": 1}
+
+// Call budgets used when the caller's context has no deadline of its own.
+const (
+	geminiCallTimeout = 60 * time.Second
+	// Local models can be slow to load on first use.
+	ollamaCallTimeout = 5 * time.Minute
+)
+
+// sharedHTTPClient carries every provider call, so connections are pooled across
+// clients. It has no timeout of its own; each call's deadline comes from callContext.
+var sharedHTTPClient = httpx.Client(0)
+
+// Pool holds the call slots of one command tree, so a limit applies to the calls of
+// that tree rather than to every tree in the process.
+type Pool struct {
+	mu sync.Mutex
+	// maxInFlight overrides defaultMaxInFlight, by provider.
+	maxInFlight map[string]int
+	slots       map[string]chan struct{}
+}
+
+// NewPool returns a pool with per-provider concurrency limits from config (provider
+// name -> max in-flight calls). Providers it does not name keep their default limit.
+func NewPool(values map[string]int) *Pool {
+	p := &Pool{maxInFlight: make(map[string]int, len(values)), slots: map[string]chan struct{}{}}
+	for k, v := range values {
+		if v > 0 {
+			p.maxInFlight[strings.ToLower(k)] = v
+		}
+	}
+	return p
+}
+
+// defaultPool serves calls whose context carries no pool.
+var defaultPool = NewPool(nil)
+
+type poolKey struct{}
+
+// WithPool returns a context whose provider calls take their slots from p.
+func WithPool(ctx context.Context, p *Pool) context.Context {
+	return context.WithValue(ctx, poolKey{}, p)
+}
+
+func poolFrom(ctx context.Context) *Pool {
+	if p, ok := ctx.Value(poolKey{}).(*Pool); ok && p != nil {
+		return p
+	}
+	return defaultPool
+}
+
+// DefaultMaxInFlight is the concurrency limit of provider when config sets none.
+func DefaultMaxInFlight(provider string) int {
+	return limitFor(strings.ToLower(provider), nil)
+}
+
+// DefaultMaxInFlightProviders lists the providers with a built-in limit.
+func DefaultMaxInFlightProviders() []string {
+	names := make([]string, 0, len(defaultMaxInFlight))
+	for name := range defaultMaxInFlight {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func limitFor(provider string, overrides map[string]int) int {
+	if n, ok := overrides[provider]; ok {
+		return n
+	}
+	if n, ok := defaultMaxInFlight[provider]; ok {
+		return n
+	}
+	return 1
+}
+
+func (p *Pool) slotsFor(provider string) chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ch, ok := p.slots[provider]
+	if !ok {
+		ch = make(chan struct{}, limitFor(provider, p.maxInFlight))
+		p.slots[provider] = ch
+	}
+	return ch
+}
+
+// beginCall waits for a free slot of the provider in ctx's pool and returns the context the call runs
+// under: the caller's, whose deadline is the run's remaining budget, or one bounded by
+// fallback when the caller set none. Time spent waiting counts against that budget. The
+// returned done releases the slot.
+func beginCall(ctx context.Context, provider string, fallback time.Duration) (context.Context, func(), error) {
+	if _, ok := ctx.Deadline(); !ok && fallback > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, fallback)
+		release, err := acquire(ctx, provider)
+		if err != nil {
+			cancel()
+			return nil, nil, err
+		}
+		return ctx, func() { release(); cancel() }, nil
+	}
+	release, err := acquire(ctx, provider)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ctx, release, nil
+}
+
+func acquire(ctx context.Context, provider string) (func(), error) {
+	ch := poolFrom(ctx).slotsFor(provider)
+	select {
+	case ch <- struct{}{}:
+		return func() { <-ch }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%s: waiting for one of %d call slots: %w", provider, cap(ch), ctx.Err())
+	}
+}
@@ -0,0 +1,83 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/example/sre-ai/internal/credentials"
+)
+
+// Client is the minimal surface shared by every model provider.
+type Client interface {
+	Generate(ctx context.Context, prompt string) (string, error)
+}
+
+// Factory builds the client for a provider and model in place of the built-in ones.
+type Factory func(ctx context.Context, provider, model string) (Client, error)
+
+type factoryKey struct{}
+
+// WithFactory returns a context whose New calls are served by f, e.g. by a fake model
+// in tests.
+func WithFactory(ctx context.Context, f Factory) context.Context {
+	return context.WithValue(ctx, factoryKey{}, f)
+}
+
+// New returns a client for the named provider, loading credentials where required.
+// An empty provider selects Gemini. A factory set with WithFactory takes precedence.
+func New(ctx context.Context, provider, model string) (Client, error) {
+	if f, _ := ctx.Value(factoryKey{}).(Factory); f != nil {
+		return f(ctx, provider, model)
+	}
+	switch strings.ToLower(strings.TrimSpace(provider)) {
+	case "", "gemini":
+		apiKey, err := credentials.LoadGeminiKey(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return NewGeminiClient(apiKey, model), nil
+	case "ollama":
+		return NewOllamaClient(model), nil
+	default:
+		return nil, fmt.Errorf("provider %s not supported", provider)
+	}
+}
+
+// ParseTarget splits "provider/model"; a bare value is taken as a Gemini model.
+func ParseTarget(value string) (string, string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", "", fmt.Errorf("empty provider/model target")
+	}
+	provider, model, ok := strings.Cut(value, "/")
+	if !ok {
+		return "gemini", value, nil
+	}
+	return strings.ToLower(provider), model, nil
+}
+
+// DefaultModel returns the model used for a provider when none is configured.
+func DefaultModel(provider string) string {
+	if strings.EqualFold(provider, "ollama") {
+		return defaultOllamaModel
+	}
+	return DefaultGeminiModel()
+}
+
+// StripCodeFence removes a surrounding ```json (or bare ```) fence from a model reply.
+func StripCodeFence(text string) string {
+	trimmed := strings.TrimSpace(text)
+	if !strings.HasPrefix(trimmed, "```") {
+		return trimmed
+	}
+	if i := strings.Index(trimmed, "\n"); i != -1 {
+		trimmed = trimmed[i+1:]
+	} else {
+		trimmed = strings.TrimLeft(trimmed, "`")
+	}
+	if j := strings.LastIndex(trimmed, "```"); j != -1 {
+		trimmed = trimmed[:j]
+	}
+	return strings.TrimSpace(trimmed)
+}
@@ -0,0 +1,55 @@
+package providers
+
+import "context"
+
+// estimateCharsPerToken approximates tokens when a provider does not report usage.
+const estimateCharsPerToken = 4
+
+// Usage counts the tokens spent on one or more model calls.
+type Usage struct {
+	PromptTokens int `json:"prompt_tokens"`
+	OutputTokens int `json:"output_tokens"`
+	// Estimated is set when some counts were derived from text length because the
+	// provider did not report them.
+	Estimated bool `json:"estimated,omitempty"`
+}
+
+// Add accumulates other into u.
+func (u *Usage) Add(other Usage) {
+	u.PromptTokens += other.PromptTokens
+	u.OutputTokens += other.OutputTokens
+	u.Estimated = u.Estimated || other.Estimated
+}
+
+// Total is the prompt and output tokens together.
+func (u Usage) Total() int {
+	return u.PromptTokens + u.OutputTokens
+}
+
+// usageReporter is implemented by clients whose API reports token counts.
+type usageReporter interface {
+	generateWithUsage(ctx context.Context, prompt string) (string, Usage, error)
+}
+
+// GenerateWithUsage runs prompt on c and returns the tokens it used: the provider's own
+// counts when it reports them, otherwise an estimate from the prompt and reply lengths.
+func GenerateWithUsage(ctx context.Context, c Client, prompt string) (string, Usage, error) {
+	if reporter, ok := c.(usageReporter); ok {
+		text, usage, err := reporter.generateWithUsage(ctx, prompt)
+		if err != nil || usage.Total() > 0 {
+			return text, usage, err
+		}
+		return text, EstimateUsage(prompt, text), nil
+	}
+	text, err := c.Generate(ctx, prompt)
+	if err != nil {
+		return "", Usage{}, err
+	}
+	return text, EstimateUsage(prompt, text), nil
+}
+
+// EstimateUsage approximates the tokens of one call from its prompt and reply.
+func EstimateUsage(prompt, reply string) Usage {
+	estimate := func(s string) int { return (len(s) + estimateCharsPerToken - 1) / estimateCharsPerToken }
+	return Usage{PromptTokens: estimate(prompt), OutputTokens: estimate(reply), Estimated: true}
+}
@@ -0,0 +1,172 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/example/sre-ai/internal/config"
+	"golang.org/x/crypto/ssh"
+)
+
+// Target kinds.
+const (
+	TargetLocal = "local"
+	TargetHost  = "host"
+	TargetJob   = "k8s-job"
+)
+
+// Target is where a command runs: "local" (the default), "host:<group>" for a config
+// hosts group, or "k8s-job:<namespace>" for a Kubernetes Job.
+type Target struct {
+	Kind string `json:"kind"`
+	// Name is the host group or the namespace.
+	Name string `json:"name,omitempty"`
+}
+
+// ParseTarget reads a target as written in a workflow; "" is local.
+func ParseTarget(value string) (Target, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == TargetLocal {
+		return Target{Kind: TargetLocal}, nil
+	}
+	kind, name, ok := strings.Cut(value, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || (kind != TargetHost && kind != TargetJob) {
+		return Target{}, fmt.Errorf("target %q must be local, host:<group>, or k8s-job:<namespace>", value)
+	}
+	return Target{Kind: kind, Name: name}, nil
+}
+
+// Local reports whether the command runs on this machine.
+func (t Target) Local() bool {
+	return t.Kind == "" || t.Kind == TargetLocal
+}
+
+func (t Target) String() string {
+	if t.Local() {
+		return TargetLocal
+	}
+	return t.Kind + ":" + t.Name
+}
+
+// Command is a command line to run remotely. Only Env is set in its environment.
+type Command struct {
+	Argv    []string
+	Env     map[string]string
+	Workdir string
+	Stdin   string
+}
+
+// Result is the outcome of a remote command. Err is set when the command ran and failed.
+type Result struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	// Where names the host or Job the command ran on.
+	Where string
+}
+
+// RunOnHosts runs cmd on the first address of the group that accepts the SSH connection.
+// A command that runs and exits non-zero is not retried elsewhere.
+func RunOnHosts(ctx context.Context, group string, hosts config.HostGroupOptions, cmd Command) (Result, error) {
+	if len(hosts.Addresses) == 0 {
+		return Result{}, fmt.Errorf("host group %s has no addresses", group)
+	}
+	auth := SSHAuth{IdentityFile: hosts.IdentityFile, KnownHosts: hosts.KnownHosts}
+	var dialErrs []string
+	for _, address := range hosts.Addresses {
+		client, err := DialSSH(ctx, address, auth)
+		if err != nil {
+			if ctx.Err() != nil {
+				return Result{}, ctx.Err()
+			}
+			dialErrs = append(dialErrs, fmt.Sprintf("%s: %v", address, err))
+			continue
+		}
+		result, err := runSSH(ctx, client, cmd)
+		client.Close()
+		result.Where = address
+		return result, err
+	}
+	return Result{}, fmt.Errorf("host group %s: no host reachable (%s)", group, strings.Join(dialErrs, "; "))
+}
+
+func runSSH(ctx context.Context, client *ssh.Client, cmd Command) (Result, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return Result{}, err
+	}
+	defer session.Close()
+	var stdout, stderr bytes.Buffer
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+	if cmd.Stdin != "" {
+		session.Stdin = strings.NewReader(cmd.Stdin)
+	}
+	done := make(chan error, 1)
+	go func() { done <- session.Run(ShellLine(cmd)) }()
+
+	var runErr error
+	select {
+	case runErr = <-done:
+	case <-ctx.Done():
+		session.Signal(ssh.SIGTERM)
+		client.Close()
+		return Result{Stdout: stdout.String(), Stderr: stderr.String(), ExitCode: -1}, ctx.Err()
+	}
+	result := Result{Stdout: stdout.String(), Stderr: stderr.String()}
+	var exitErr *ssh.ExitError
+	if errors.As(runErr, &exitErr) {
+		result.ExitCode = exitErr.ExitStatus()
+		return result, fmt.Errorf("exited with %d: %s", result.ExitCode, tail(result.Stderr, 400))
+	}
+	if runErr != nil {
+		return result, runErr
+	}
+	return result, nil
+}
+
+// ShellLine renders cmd as one POSIX shell line: cd to Workdir, then exec the command
+// under env -i with only cmd.Env set (plus PATH and HOME from the remote login).
+func ShellLine(cmd Command) string {
+	var parts []string
+	if cmd.Workdir != "" {
+		parts = append(parts, "cd", quote(cmd.Workdir), "&&")
+	}
+	parts = append(parts, "exec", "env", "-i", `PATH="$PATH"`, `HOME="$HOME"`)
+	keys := make([]string, 0, len(cmd.Env))
+	for k := range cmd.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		parts = append(parts, quote(k+"="+cmd.Env[k]))
+	}
+	for _, arg := range cmd.Argv {
+		parts = append(parts, quote(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+// quote single-quotes s for a POSIX shell unless it is made only of safe characters.
+func quote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r == '-' || r == '_' || r == '.' || r == '/' || r == ':' || r == '=' || r == ',' || r == '@' || r == '+' ||
+			(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'))
+	}) == -1 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func tail(s string, max int) string {
+	s = strings.TrimSpace(s)
+	if len(s) <= max {
+		return s
+	}
+	return s[len(s)-max:]
+}
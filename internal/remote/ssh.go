@@ -0,0 +1,122 @@
+// Package remote runs commands somewhere other than the operator's machine: on a
+// configured host group over SSH, or as a Kubernetes Job.
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const sshDialTimeout = 15 * time.Second
+
+// SSHAuth is how a connection authenticates and verifies the server. Paths may start with ~.
+type SSHAuth struct {
+	// IdentityFile is a private key; without one the ssh-agent at $SSH_AUTH_SOCK is used.
+	IdentityFile string
+	// KnownHosts verifies the host key; default ~/.ssh/known_hosts.
+	KnownHosts string
+}
+
+// DialSSH connects to address, written [user@]host[:port].
+func DialSSH(ctx context.Context, address string, auth SSHAuth) (*ssh.Client, error) {
+	userName, addr := SplitAddress(address)
+	knownHostsPath := auth.KnownHosts
+	if knownHostsPath == "" {
+		knownHostsPath = "~/.ssh/known_hosts"
+	}
+	hostKeys, err := knownhosts.New(expandHome(knownHostsPath))
+	if err != nil {
+		return nil, fmt.Errorf("known_hosts: %w", err)
+	}
+	methods, err := auth.methods()
+	if err != nil {
+		return nil, err
+	}
+	cfg := &ssh.ClientConfig{
+		User:            userName,
+		Auth:            methods,
+		HostKeyCallback: hostKeys,
+		Timeout:         sshDialTimeout,
+	}
+
+	dialer := net.Dialer{Timeout: sshDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, cfg)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// methods uses the identity file when one is set, else the running ssh-agent.
+func (a SSHAuth) methods() ([]ssh.AuthMethod, error) {
+	if a.IdentityFile != "" {
+		key, err := os.ReadFile(expandHome(a.IdentityFile))
+		if err != nil {
+			return nil, fmt.Errorf("identity_file: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) {
+			return nil, fmt.Errorf("identity_file %s is passphrase-protected; add it to ssh-agent and drop identity_file", a.IdentityFile)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("identity_file: %w", err)
+		}
+		return []ssh.AuthMethod{ssh.PublicKeys(signer)}, nil
+	}
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil, errors.New("no identity_file and no ssh-agent ($SSH_AUTH_SOCK is not set)")
+	}
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		return nil, fmt.Errorf("ssh-agent: %w", err)
+	}
+	return []ssh.AuthMethod{ssh.PublicKeysCallback(agent.NewClient(conn).Signers)}, nil
+}
+
+// SplitAddress reads [user@]host[:port], defaulting to the current user and port 22.
+func SplitAddress(address string) (string, string) {
+	userName, host := "", strings.TrimSpace(address)
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		userName, host = host[:i], host[i+1:]
+	}
+	if userName == "" {
+		if u, err := user.Current(); err == nil {
+			userName = u.Username
+		}
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(strings.Trim(host, "[]"), "22")
+	}
+	return userName, host
+}
+
+func expandHome(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, strings.TrimPrefix(p, "~"))
+		}
+	}
+	return p
+}
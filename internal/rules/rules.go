@@ -0,0 +1,384 @@
+// Package rules matches known failure signatures in collected evidence. Rules are
+// deterministic: they run before any model is asked, produce findings with canned
+// remediations, and can be handed to a prompt as hints.
+package rules
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/warnings"
+)
+
+const (
+	rulesDirName = "rules"
+	// maxEvidenceLines bounds how much flattened evidence a rule set scans.
+	maxEvidenceLines = 20000
+	// maxSamples is how many matching lines a hit keeps as evidence.
+	maxSamples = 3
+)
+
+//go:embed builtin.yaml
+var builtinRules []byte
+
+// Rule is one failure signature.
+type Rule struct {
+	ID       string `yaml:"id" json:"id"`
+	Title    string `yaml:"title" json:"title"`
+	Severity string `yaml:"severity" json:"severity,omitempty"`
+	Match    Match  `yaml:"match" json:"match"`
+	// Finding is the deterministic finding reported when the rule matches.
+	Finding     string   `yaml:"finding" json:"finding"`
+	Remediation []string `yaml:"remediation" json:"remediation,omitempty"`
+	// Source is "builtin" or the file the rule came from.
+	Source string `yaml:"-" json:"source"`
+}
+
+// Match is the condition of a rule over evidence lines. Any and All are regular
+// expressions: at least MinCount lines (default 1) must match one of Any, every All
+// pattern must match some line, and no line may match None.
+type Match struct {
+	Any      []string `yaml:"any" json:"any,omitempty"`
+	All      []string `yaml:"all" json:"all,omitempty"`
+	None     []string `yaml:"none" json:"none,omitempty"`
+	MinCount int      `yaml:"min_count" json:"min_count,omitempty"`
+}
+
+// Hit is a rule that matched.
+type Hit struct {
+	Rule        string   `json:"rule"`
+	Title       string   `json:"title"`
+	Severity    string   `json:"severity,omitempty"`
+	Finding     string   `json:"finding"`
+	Remediation []string `json:"remediation,omitempty"`
+	// Count is the number of evidence lines that matched; Evidence samples them.
+	Count    int      `json:"count"`
+	Evidence []string `json:"evidence"`
+}
+
+type ruleFile struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+type compiled struct {
+	rule           Rule
+	any, all, none []*regexp.Regexp
+}
+
+// Set is a compiled collection of rules.
+type Set struct {
+	rules []compiled
+}
+
+// Dir returns the directory holding user rule files.
+func Dir(ctx context.Context) (string, error) {
+	base, err := config.ConfigDir(ctx)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, rulesDirName), nil
+}
+
+// Builtin returns the rules shipped with the CLI.
+func Builtin() ([]Rule, error) {
+	return parse(builtinRules, "builtin")
+}
+
+// LoadFile reads the rules of one YAML file.
+func LoadFile(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parse(data, path)
+}
+
+// Load returns the built-in rules overlaid with every *.yaml or *.yml file in dir,
+// where a rule with the same id replaces the earlier one. Unreadable or invalid files
+// are skipped with a warning on ctx. A missing dir yields only the built-ins.
+func Load(ctx context.Context, dir string) (*Set, error) {
+	all, err := Builtin()
+	if err != nil {
+		return nil, fmt.Errorf("builtin rules: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		warnings.Add(ctx, "rules", "skipped %s: %v", dir, err)
+	}
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		loaded, err := LoadFile(path)
+		if err == nil {
+			_, err = Compile(loaded)
+		}
+		if err != nil {
+			warnings.Add(ctx, "rules", "skipped %s: %v", path, err)
+			continue
+		}
+		all = overlay(all, loaded)
+	}
+	return Compile(all)
+}
+
+// LoadDefault loads rules from Dir.
+func LoadDefault(ctx context.Context) (*Set, error) {
+	dir, err := Dir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Load(ctx, dir)
+}
+
+func parse(data []byte, source string) ([]Rule, error) {
+	var file ruleFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	for i := range file.Rules {
+		file.Rules[i].Source = source
+	}
+	return file.Rules, nil
+}
+
+func overlay(base, extra []Rule) []Rule {
+	index := make(map[string]int, len(base))
+	for i, rule := range base {
+		index[rule.ID] = i
+	}
+	for _, rule := range extra {
+		if i, ok := index[rule.ID]; ok {
+			base[i] = rule
+			continue
+		}
+		index[rule.ID] = len(base)
+		base = append(base, rule)
+	}
+	return base
+}
+
+// Compile validates rules and compiles their patterns.
+func Compile(rules []Rule) (*Set, error) {
+	set := &Set{}
+	seen := map[string]bool{}
+	for _, rule := range rules {
+		if strings.TrimSpace(rule.ID) == "" {
+			return nil, errors.New("rule without an id")
+		}
+		if seen[rule.ID] {
+			return nil, fmt.Errorf("rule %s defined twice", rule.ID)
+		}
+		seen[rule.ID] = true
+		if len(rule.Match.Any) == 0 && len(rule.Match.All) == 0 {
+			return nil, fmt.Errorf("rule %s: match needs any or all patterns", rule.ID)
+		}
+		if rule.Match.MinCount < 0 {
+			return nil, fmt.Errorf("rule %s: min_count must not be negative", rule.ID)
+		}
+		c := compiled{rule: rule}
+		for _, group := range []struct {
+			name     string
+			patterns []string
+			dst      *[]*regexp.Regexp
+		}{{"any", rule.Match.Any, &c.any}, {"all", rule.Match.All, &c.all}, {"none", rule.Match.None, &c.none}} {
+			for _, pattern := range group.patterns {
+				re, err := regexp.Compile(pattern)
+				if err != nil {
+					return nil, fmt.Errorf("rule %s: match.%s %q: %w", rule.ID, group.name, pattern, err)
+				}
+				*group.dst = append(*group.dst, re)
+			}
+		}
+		set.rules = append(set.rules, c)
+	}
+	return set, nil
+}
+
+// Rules returns the rules in the set, sorted by id.
+func (s *Set) Rules() []Rule {
+	out := make([]Rule, 0, len(s.rules))
+	for _, c := range s.rules {
+		out = append(out, c.rule)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// Evaluate runs every rule over the evidence lines and returns the hits in rule order.
+func (s *Set) Evaluate(lines []string) []Hit {
+	if s == nil {
+		return nil
+	}
+	var hits []Hit
+	for _, c := range s.rules {
+		if hit, ok := c.evaluate(lines); ok {
+			hits = append(hits, hit)
+		}
+	}
+	return hits
+}
+
+func (c compiled) evaluate(lines []string) (Hit, bool) {
+	hit := Hit{
+		Rule:        c.rule.ID,
+		Title:       c.rule.Title,
+		Severity:    c.rule.Severity,
+		Finding:     c.rule.Finding,
+		Remediation: c.rule.Remediation,
+		Evidence:    []string{},
+	}
+	allSeen := make([]bool, len(c.all))
+	for _, line := range lines {
+		for _, re := range c.none {
+			if re.MatchString(line) {
+				return Hit{}, false
+			}
+		}
+		for i, re := range c.all {
+			if !allSeen[i] && re.MatchString(line) {
+				allSeen[i] = true
+			}
+		}
+		for _, re := range c.any {
+			if re.MatchString(line) {
+				hit.Count++
+				if len(hit.Evidence) < maxSamples {
+					hit.Evidence = append(hit.Evidence, clip(line, 240))
+				}
+				break
+			}
+		}
+	}
+	for _, seen := range allSeen {
+		if !seen {
+			return Hit{}, false
+		}
+	}
+	minCount := c.rule.Match.MinCount
+	if minCount == 0 && len(c.any) > 0 {
+		minCount = 1
+	}
+	if hit.Count < minCount {
+		return Hit{}, false
+	}
+	return hit, true
+}
+
+// Lines flattens evidence into text lines for matching. Values are taken through their
+// JSON form: each object becomes one line of its scalar fields as key=value pairs in
+// key order, nested objects and arrays get lines of their own, and multi-line strings
+// are split so log output matches line by line.
+func Lines(values ...interface{}) []string {
+	var lines []string
+	for _, value := range values {
+		data, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		var decoded interface{}
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			continue
+		}
+		lines = flatten(decoded, lines)
+		if len(lines) >= maxEvidenceLines {
+			return lines[:maxEvidenceLines]
+		}
+	}
+	return lines
+}
+
+func flatten(value interface{}, lines []string) []string {
+	if len(lines) >= maxEvidenceLines {
+		return lines
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var pairs []string
+		for _, k := range keys {
+			switch field := v[k].(type) {
+			case map[string]interface{}, []interface{}:
+				lines = flatten(field, lines)
+			case string:
+				if strings.Contains(field, "\n") {
+					lines = splitLines(field, lines)
+					continue
+				}
+				pairs = append(pairs, fmt.Sprintf("%s=%s", k, field))
+			case nil:
+			default:
+				pairs = append(pairs, fmt.Sprintf("%s=%v", k, field))
+			}
+		}
+		if len(pairs) > 0 {
+			lines = append(lines, strings.Join(pairs, " "))
+		}
+	case []interface{}:
+		for _, item := range v {
+			lines = flatten(item, lines)
+		}
+	case string:
+		lines = splitLines(v, lines)
+	case nil:
+	default:
+		lines = append(lines, fmt.Sprint(v))
+	}
+	return lines
+}
+
+func splitLines(text string, lines []string) []string {
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func clip(line string, max int) string {
+	if r := []rune(line); len(r) > max {
+		return string(r[:max-3]) + "..."
+	}
+	return line
+}
+
+// Hints renders hits as a prompt section. It is empty when nothing matched.
+func Hints(hits []Hit) string {
+	if len(hits) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Known failure signatures matched in the evidence by deterministic rules. Treat them as hints to confirm, not conclusions:\n")
+	for _, hit := range hits {
+		fmt.Fprintf(&b, "- %s (%s", hit.Rule, hit.Title)
+		if hit.Severity != "" {
+			fmt.Fprintf(&b, ", severity %s", hit.Severity)
+		}
+		fmt.Fprintf(&b, ", %d matching line(s)): %s\n", hit.Count, hit.Finding)
+		for _, line := range hit.Evidence {
+			fmt.Fprintf(&b, "  evidence: %s\n", line)
+		}
+		for _, step := range hit.Remediation {
+			fmt.Fprintf(&b, "  suggested: %s\n", step)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,215 @@
+package runs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/example/sre-ai/internal/agent"
+)
+
+// maxDiffLines bounds the LCS table; larger prompts fall back to a whole-text replace.
+const maxDiffLines = 4000
+
+// PromptChange compares the prompt a step rendered in two runs.
+type PromptChange struct {
+	Stage           string `json:"stage"`
+	Step            string `json:"step"`
+	Status          string `json:"status"`
+	OldHash         string `json:"old_hash,omitempty"`
+	NewHash         string `json:"new_hash,omitempty"`
+	TemplateChanged bool   `json:"template_changed"`
+	Diff            string `json:"diff,omitempty"`
+}
+
+// PromptDiff pairs prompt steps by name across two runs. Status is one of unchanged,
+// changed, added, or removed.
+func PromptDiff(oldRun, newRun *Record) []PromptChange {
+	type promptStep struct {
+		stage, prompt, hash, template string
+	}
+	collect := func(rec *Record) (map[string]promptStep, []string) {
+		steps := map[string]promptStep{}
+		var order []string
+		if rec.Result == nil {
+			return steps, order
+		}
+		for _, step := range rec.Result.Steps {
+			if step.Prompt == "" {
+				continue
+			}
+			if _, ok := steps[step.StepName]; !ok {
+				order = append(order, step.StepName)
+			}
+			hash := step.PromptHash
+			if hash == "" {
+				// Runs recorded before hashing still carry the prompt text.
+				hash = agent.ContentHash(step.Prompt)
+			}
+			steps[step.StepName] = promptStep{stage: step.StageID, prompt: step.Prompt, hash: hash, template: step.TemplateHash}
+		}
+		return steps, order
+	}
+
+	oldSteps, oldOrder := collect(oldRun)
+	newSteps, newOrder := collect(newRun)
+
+	var changes []PromptChange
+	for _, name := range newOrder {
+		n := newSteps[name]
+		o, ok := oldSteps[name]
+		if !ok {
+			changes = append(changes, PromptChange{Stage: n.stage, Step: name, Status: "added", NewHash: n.hash,
+				Diff: unifiedDiff("", n.prompt, oldRun.ID, newRun.ID)})
+			continue
+		}
+		change := PromptChange{Stage: n.stage, Step: name, OldHash: o.hash, NewHash: n.hash}
+		change.TemplateChanged = o.template != "" && n.template != "" && o.template != n.template
+		if o.hash == n.hash {
+			change.Status = "unchanged"
+		} else {
+			change.Status = "changed"
+			change.Diff = unifiedDiff(o.prompt, n.prompt, oldRun.ID, newRun.ID)
+		}
+		changes = append(changes, change)
+	}
+	for _, name := range oldOrder {
+		if _, ok := newSteps[name]; ok {
+			continue
+		}
+		o := oldSteps[name]
+		changes = append(changes, PromptChange{Stage: o.stage, Step: name, Status: "removed", OldHash: o.hash,
+			Diff: unifiedDiff(o.prompt, "", oldRun.ID, newRun.ID)})
+	}
+	return changes
+}
+
+type diffOp struct {
+	kind byte // ' ', '-', '+'
+	line string
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines computes a line-level edit script via longest common subsequence.
+func diffLines(a, b []string) []diffOp {
+	if len(a) > maxDiffLines || len(b) > maxDiffLines {
+		ops := make([]diffOp, 0, len(a)+len(b))
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+		return ops
+	}
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// unifiedDiff renders a diff with three lines of context per hunk.
+func unifiedDiff(oldText, newText, oldLabel, newLabel string) string {
+	const context = 3
+	ops := diffLines(splitLines(oldText), splitLines(newText))
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", oldLabel, newLabel))
+
+	oldLine, newLine := 1, 1
+	for start := 0; start < len(ops); {
+		// Find the next change.
+		next := start
+		for next < len(ops) && ops[next].kind == ' ' {
+			next++
+		}
+		if next == len(ops) {
+			break
+		}
+		hunkStart := next - context
+		if hunkStart < start {
+			hunkStart = start
+		}
+		// Advance counters over skipped context.
+		for k := start; k < hunkStart; k++ {
+			oldLine++
+			newLine++
+		}
+		// Extend the hunk until context lines of equality follow the last change.
+		end := next
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*context {
+				end += min(context, run-end)
+				break
+			}
+			end = run
+		}
+
+		oldCount, newCount := 0, 0
+		for _, op := range ops[hunkStart:end] {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+		builder.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", oldLine, oldCount, newLine, newCount))
+		for _, op := range ops[hunkStart:end] {
+			builder.WriteByte(op.kind)
+			builder.WriteString(op.line)
+			builder.WriteByte('\n')
+		}
+		oldLine += oldCount
+		newLine += newCount
+		start = end
+	}
+	return strings.TrimRight(builder.String(), "\n")
+}
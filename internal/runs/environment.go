@@ -0,0 +1,153 @@
+package runs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/gitlog"
+	"github.com/example/sre-ai/internal/k8s"
+	"github.com/example/sre-ai/internal/providers"
+	"github.com/example/sre-ai/internal/workspace"
+)
+
+// gitTimeout bounds reading the repository's HEAD; a snapshot must not stall a run.
+const gitTimeout = 2 * time.Second
+
+// Environment is what a run depended on besides its inputs, captured when it starts so
+// a past result can be reproduced.
+type Environment struct {
+	CLIVersion  string `json:"cli_version"`
+	GoVersion   string `json:"go_version"`
+	Platform    string `json:"platform"`
+	Provider    string `json:"provider"`
+	Model       string `json:"model"`
+	KubeContext string `json:"kubecontext,omitempty"`
+	// Repo is the git repository the CLI ran in, with its HEAD and whether tracked
+	// files had uncommitted changes.
+	Repo     string `json:"repo,omitempty"`
+	GitSHA   string `json:"git_sha,omitempty"`
+	GitDirty bool   `json:"git_dirty,omitempty"`
+	// ConfigFile and ProjectConfig are the config files in effect.
+	ConfigFile    string `json:"config_file,omitempty"`
+	ProjectConfig string `json:"project_config,omitempty"`
+}
+
+// CaptureEnvironment snapshots the environment of a run about to start. kubecontext is
+// the context the command targets; empty falls back to contexts.k8s.kubecontext, then
+// the kubeconfig's current context. Parts that cannot be read are left empty.
+func CaptureEnvironment(ctx context.Context, opts *config.GlobalOptions, kubecontext string) *Environment {
+	env := &Environment{
+		CLIVersion:    CLIVersion(),
+		GoVersion:     runtime.Version(),
+		Platform:      runtime.GOOS + "/" + runtime.GOARCH,
+		Provider:      opts.Provider,
+		Model:         opts.ExplicitModel(),
+		KubeContext:   kubecontext,
+		ProjectConfig: opts.ProjectConfig,
+	}
+	if env.Model == "" {
+		env.Model = providers.DefaultModel(opts.Provider)
+	}
+	if env.KubeContext == "" {
+		env.KubeContext = opts.Kube.Context
+	}
+	if env.KubeContext == "" {
+		env.KubeContext = k8s.CurrentContext()
+	}
+	env.ConfigFile = opts.ConfigPath
+	if env.ConfigFile == "" {
+		if path, err := config.DefaultConfigPath(ctx); err == nil {
+			if _, err := os.Stat(path); err == nil {
+				env.ConfigFile = path
+			}
+		}
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		if root, ok := workspace.GitRoot(cwd); ok {
+			env.Repo = root
+			gitCtx, cancel := context.WithTimeout(ctx, gitTimeout)
+			env.GitSHA, env.GitDirty, _ = gitlog.Head(gitCtx, root)
+			cancel()
+		}
+	}
+	return env
+}
+
+// CLIVersion is the module version the binary was built from, with its VCS revision
+// when the build recorded one.
+func CLIVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	version := info.Main.Version
+	var revision string
+	var modified bool
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	// Module pseudo-versions already carry the revision.
+	if revision != "" && !strings.Contains(version, revision) {
+		if modified {
+			revision += "-dirty"
+		}
+		version = fmt.Sprintf("%s (%s)", version, revision)
+	}
+	return version
+}
+
+// EnvironmentChanges lists what differs between the environments of two runs, as
+// "field: old -> new". Runs recorded before snapshots have none to compare.
+func EnvironmentChanges(oldRun, newRun *Record) []string {
+	if oldRun.Environment == nil || newRun.Environment == nil {
+		return nil
+	}
+	o, n := oldRun.Environment, newRun.Environment
+	var changes []string
+	add := func(field, old, new string) {
+		if old != new {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", field, orNone(old), orNone(new)))
+		}
+	}
+	add("cli_version", o.CLIVersion, n.CLIVersion)
+	add("provider", o.Provider, n.Provider)
+	add("model", o.Model, n.Model)
+	add("kubecontext", o.KubeContext, n.KubeContext)
+	add("git_sha", o.shortSHA(), n.shortSHA())
+	add("config_file", o.ConfigFile, n.ConfigFile)
+	add("project_config", o.ProjectConfig, n.ProjectConfig)
+	return changes
+}
+
+// shortSHA abbreviates GitSHA, marking uncommitted changes.
+func (e *Environment) shortSHA() string {
+	sha := e.GitSHA
+	if len(sha) > 12 {
+		sha = sha[:12]
+	}
+	if sha != "" && e.GitDirty {
+		sha += " (dirty)"
+	}
+	return sha
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
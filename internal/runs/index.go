@@ -0,0 +1,425 @@
+package runs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/example/sre-ai/internal/config"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	indexFileName = "runs.db"
+	// indexSchemaVersion is stored as PRAGMA user_version; a different version is rebuilt.
+	indexSchemaVersion = 4
+	// indexTimeLayout is fixed-width so stored timestamps sort and compare as text.
+	indexTimeLayout = "2006-01-02T15:04:05.000Z"
+)
+
+// indexSchema is the read replica of run history. The run.json files stay the source of
+// truth; the index is brought up to date from them whenever it is opened.
+const indexSchema = `
+CREATE TABLE runs (
+	id            TEXT PRIMARY KEY,
+	kind          TEXT NOT NULL,
+	workflow      TEXT NOT NULL,
+	principal     TEXT,
+	workflow_path TEXT,
+	status        TEXT NOT NULL,
+	started_at    TEXT NOT NULL,
+	finished_at   TEXT,
+	duration_ms   INTEGER,
+	error         TEXT,
+	summary       TEXT,
+	resolution    TEXT,
+	ratings       INTEGER NOT NULL,
+	mean_score    REAL,
+	prompt_tokens INTEGER NOT NULL,
+	output_tokens INTEGER NOT NULL,
+	provider      TEXT,
+	model         TEXT,
+	kubecontext   TEXT,
+	git_sha       TEXT,
+	cli_version   TEXT,
+	source_mtime  INTEGER NOT NULL
+);
+CREATE INDEX runs_started ON runs (started_at);
+CREATE INDEX runs_workflow ON runs (workflow, started_at);
+CREATE TABLE steps (
+	run_id        TEXT NOT NULL REFERENCES runs (id),
+	seq           INTEGER NOT NULL,
+	stage         TEXT NOT NULL,
+	step          TEXT NOT NULL,
+	type          TEXT,
+	status        TEXT,
+	error         TEXT,
+	attempt       INTEGER NOT NULL,
+	duration_ms   INTEGER,
+	cpu_ms        INTEGER,
+	output_bytes  INTEGER,
+	prompt_tokens INTEGER,
+	output_tokens INTEGER,
+	PRIMARY KEY (run_id, seq)
+);
+CREATE TABLE events (
+	run_id TEXT NOT NULL REFERENCES runs (id),
+	at     TEXT NOT NULL,
+	type   TEXT NOT NULL,
+	actor  TEXT,
+	detail TEXT
+);
+CREATE INDEX events_at ON events (at);
+`
+
+// Index is the SQLite read replica of run history: tables runs, steps (one row per
+// executed step with its duration, subprocess CPU time, tool output, and tokens), and
+// events (started, finished, rated, resolved).
+type Index struct {
+	db *sql.DB
+}
+
+// IndexPath returns where the index is kept.
+func IndexPath(ctx context.Context) (string, error) {
+	base, err := config.ConfigDir(ctx)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, indexFileName), nil
+}
+
+// OpenIndex opens the index, creating it if needed, and syncs it with the run records.
+// After that the connection is read-only.
+func OpenIndex(ctx context.Context) (*Index, error) {
+	path, err := IndexPath(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.EnsureDir(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// One connection, so the query_only pragma below covers every later statement.
+	db.SetMaxOpenConns(1)
+	idx := &Index{db: db}
+	if err := idx.migrate(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("run index %s: %w", path, err)
+	}
+	if err := idx.sync(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("run index %s: %w", path, err)
+	}
+	if _, err := db.ExecContext(ctx, "PRAGMA query_only = ON"); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return idx, nil
+}
+
+// Close closes the database.
+func (idx *Index) Close() error {
+	return idx.db.Close()
+}
+
+func (idx *Index) migrate(ctx context.Context) error {
+	var version int
+	if err := idx.db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version == indexSchemaVersion {
+		return nil
+	}
+	// The index only mirrors the run files, so an old layout is dropped and rebuilt.
+	tx, err := idx.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, table := range []string{"events", "steps", "runs"} {
+		if _, err := tx.ExecContext(ctx, "DROP TABLE IF EXISTS "+table); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, indexSchema); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", indexSchemaVersion)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// sync re-reads every run whose run.json changed since it was indexed and drops runs
+// whose directory is gone. Unreadable records are skipped, as in List.
+func (idx *Index) sync(ctx context.Context) error {
+	base, err := Dir(ctx)
+	if err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(base)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	indexed := map[string]int64{}
+	rows, err := idx.db.QueryContext(ctx, "SELECT id, source_mtime FROM runs")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id string
+		var mtime int64
+		if err := rows.Scan(&id, &mtime); err != nil {
+			rows.Close()
+			return err
+		}
+		indexed[id] = mtime
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tx, err := idx.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(base, entry.Name())
+		info, err := os.Stat(filepath.Join(dir, recordFileName))
+		if err != nil {
+			continue
+		}
+		mtime := info.ModTime().UnixNano()
+		if previous, ok := indexed[entry.Name()]; ok && previous == mtime {
+			seen[entry.Name()] = true
+			continue
+		}
+		rec, err := readRecord(dir)
+		if err != nil || rec.ID != entry.Name() {
+			continue
+		}
+		seen[rec.ID] = true
+		if err := upsertRecord(ctx, tx, rec, mtime); err != nil {
+			return fmt.Errorf("index run %s: %w", rec.ID, err)
+		}
+	}
+	for id := range indexed {
+		if !seen[id] {
+			if err := deleteRecord(ctx, tx, id); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+func deleteRecord(ctx context.Context, tx *sql.Tx, id string) error {
+	for _, stmt := range []string{"DELETE FROM events WHERE run_id = ?", "DELETE FROM steps WHERE run_id = ?", "DELETE FROM runs WHERE id = ?"} {
+		if _, err := tx.ExecContext(ctx, stmt, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func upsertRecord(ctx context.Context, tx *sql.Tx, rec *Record, mtime int64) error {
+	if err := deleteRecord(ctx, tx, rec.ID); err != nil {
+		return err
+	}
+	var finished, duration interface{}
+	if rec.FinishedAt != nil {
+		finished = indexTime(*rec.FinishedAt)
+		duration = rec.FinishedAt.Sub(rec.StartedAt).Milliseconds()
+	}
+	var meanScore interface{}
+	if mean, ok := rec.MeanScore(); ok {
+		meanScore = mean
+	}
+	usage := rec.Usage()
+	env := rec.Environment
+	if env == nil {
+		env = &Environment{}
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO runs (id, kind, workflow, principal, workflow_path, status, started_at, finished_at,
+		duration_ms, error, summary, resolution, ratings, mean_score, prompt_tokens, output_tokens,
+		provider, model, kubecontext, git_sha, cli_version, source_mtime)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rec.ID, rec.Kind, rec.Workflow, nullable(rec.Principal), nullable(rec.WorkflowPath), rec.Status, indexTime(rec.StartedAt), finished,
+		duration, nullable(rec.Error), nullable(rec.Summary), nullable(rec.Resolution), len(rec.Ratings), meanScore,
+		usage.PromptTokens, usage.OutputTokens,
+		nullable(env.Provider), nullable(env.Model), nullable(env.KubeContext), nullable(env.GitSHA), nullable(env.CLIVersion),
+		mtime); err != nil {
+		return err
+	}
+
+	if rec.Result != nil {
+		for i, step := range rec.Result.Steps {
+			var prompt, output interface{}
+			if step.Usage != nil {
+				prompt, output = step.Usage.PromptTokens, step.Usage.OutputTokens
+			}
+			var duration, cpu, outputBytes interface{}
+			if step.Duration > 0 {
+				duration = step.Duration.Milliseconds()
+			}
+			if step.CPUTime > 0 {
+				cpu = step.CPUTime.Milliseconds()
+			}
+			if step.OutputBytes > 0 {
+				outputBytes = step.OutputBytes
+			}
+			if _, err := tx.ExecContext(ctx, `INSERT INTO steps (run_id, seq, stage, step, type, status, error, attempt,
+				duration_ms, cpu_ms, output_bytes, prompt_tokens, output_tokens) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				rec.ID, i, step.StageID, step.StepName, step.Type, step.Status, nullable(step.Error), step.Attempt,
+				duration, cpu, outputBytes, prompt, output); err != nil {
+				return err
+			}
+		}
+	}
+
+	type event struct {
+		at                  time.Time
+		kind, actor, detail string
+	}
+	events := []event{{at: rec.StartedAt, kind: "started", actor: rec.Principal, detail: rec.Workflow}}
+	if rec.FinishedAt != nil {
+		events = append(events, event{at: *rec.FinishedAt, kind: "finished", detail: rec.Status})
+	}
+	for _, rating := range rec.Ratings {
+		events = append(events, event{at: rating.At, kind: "rated", actor: rating.Rater, detail: strings.TrimSpace(fmt.Sprintf("%d %s", rating.Score, rating.Comment))})
+	}
+	if rec.Resolution != "" {
+		// The record does not keep when it was resolved; the file's mtime is the best bound.
+		events = append(events, event{at: time.Unix(0, mtime), kind: "resolved", detail: rec.Resolution})
+	}
+	for _, ev := range events {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO events (run_id, at, type, actor, detail) VALUES (?, ?, ?, ?, ?)",
+			rec.ID, indexTime(ev.at), ev.kind, nullable(ev.actor), nullable(ev.detail)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func indexTime(t time.Time) string {
+	return t.UTC().Format(indexTimeLayout)
+}
+
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// Filter selects runs by field; zero fields match everything.
+type Filter struct {
+	Workflow string
+	Kind     string
+	Status   string
+	// Principal keeps runs started by that principal.
+	Principal string
+	// Since keeps runs started at or after it.
+	Since time.Time
+	// Limit caps the result; 0 is no limit.
+	Limit int
+}
+
+// Match reports whether rec passes the filter, ignoring Limit.
+func (f Filter) Match(rec *Record) bool {
+	return (f.Workflow == "" || rec.Workflow == f.Workflow) &&
+		(f.Kind == "" || rec.Kind == f.Kind) &&
+		(f.Status == "" || rec.Status == f.Status) &&
+		(f.Principal == "" || rec.Principal == f.Principal) &&
+		(f.Since.IsZero() || !rec.StartedAt.Before(f.Since))
+}
+
+// Find returns the IDs of the runs passing the filter, newest first.
+func (idx *Index) Find(ctx context.Context, f Filter) ([]string, error) {
+	var where []string
+	var args []interface{}
+	for _, cond := range []struct{ column, value string }{{"workflow", f.Workflow}, {"kind", f.Kind}, {"status", f.Status}, {"principal", f.Principal}} {
+		if cond.value != "" {
+			where = append(where, cond.column+" = ?")
+			args = append(args, cond.value)
+		}
+	}
+	if !f.Since.IsZero() {
+		where = append(where, "started_at >= ?")
+		args = append(args, indexTime(f.Since))
+	}
+	query := "SELECT id FROM runs"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY started_at DESC"
+	if f.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", f.Limit)
+	}
+	rows, err := idx.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// QueryResult is the outcome of a free-form query, rows in column order.
+type QueryResult struct {
+	Columns []string        `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+}
+
+// Query runs a read-only SQL statement against the index.
+func (idx *Index) Query(ctx context.Context, query string, args ...interface{}) (*QueryResult, error) {
+	rows, err := idx.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	result := &QueryResult{Columns: columns, Rows: [][]interface{}{}}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		result.Rows = append(result.Rows, values)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,442 @@
+package runs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/sre-ai/internal/agent"
+	"github.com/example/sre-ai/internal/audit"
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/principal"
+)
+
+const (
+	runsDirName    = "runs"
+	recordFileName = "run.json"
+
+	// LowScoreThreshold and LowScoreMinRatings decide when a workflow is flagged as
+	// consistently low-scoring: at least that many ratings averaging at or below the threshold.
+	LowScoreThreshold  = 2.5
+	LowScoreMinRatings = 3
+)
+
+// Rating is one piece of human feedback on a run.
+type Rating struct {
+	Score   int       `json:"score"`
+	Comment string    `json:"comment,omitempty"`
+	Rater   string    `json:"rater,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// Record is the persisted history of one workflow run or diagnosis. Diagnoses carry
+// Summary and Findings instead of a Result; Resolution notes what fixed the incident.
+// Environment is the snapshot taken at Start; runs recorded before snapshots have none.
+// Principal is who started the run.
+type Record struct {
+	ID           string        `json:"id"`
+	Kind         string        `json:"kind"`
+	Workflow     string        `json:"workflow"`
+	Principal    string        `json:"principal,omitempty"`
+	WorkflowPath string        `json:"workflow_path,omitempty"`
+	Status       string        `json:"status"`
+	StartedAt    time.Time     `json:"started_at"`
+	FinishedAt   *time.Time    `json:"finished_at,omitempty"`
+	Error        string        `json:"error,omitempty"`
+	Result       *agent.Result `json:"result,omitempty"`
+	Summary      string        `json:"summary,omitempty"`
+	Findings     []string      `json:"findings,omitempty"`
+	Resolution   string        `json:"resolution,omitempty"`
+	Ratings      []Rating      `json:"ratings,omitempty"`
+	Environment  *Environment  `json:"environment,omitempty"`
+
+	dir string
+}
+
+// Dir returns the directory holding run history.
+func Dir(ctx context.Context) (string, error) {
+	base, err := config.ConfigDir(ctx)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, runsDirName), nil
+}
+
+// Start allocates an ID and artifacts directory for a run and persists it as "running"
+// with its environment snapshot, on behalf of the principal carried by ctx.
+func Start(ctx context.Context, kind, workflow, workflowPath string, env *Environment) (*Record, error) {
+	base, err := Dir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := newID(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	rec := &Record{
+		ID:           id,
+		Kind:         kind,
+		Workflow:     workflow,
+		Principal:    principal.Current(ctx),
+		WorkflowPath: workflowPath,
+		Status:       "running",
+		StartedAt:    time.Now().UTC(),
+		Environment:  env,
+		dir:          filepath.Join(base, id),
+	}
+	if err := config.EnsureDir(rec.dir); err != nil {
+		return nil, err
+	}
+	return rec, rec.Save()
+}
+
+// ArtifactsDir is where a run may store extra files alongside its record.
+func (r *Record) ArtifactsDir() string {
+	return r.dir
+}
+
+// Finish stores the result and final status, and logs the finished record's digest in
+// the audit log.
+func (r *Record) Finish(ctx context.Context, res *agent.Result, runErr error) error {
+	now := time.Now().UTC()
+	r.FinishedAt = &now
+	r.Result = res
+	switch {
+	case runErr != nil:
+		r.Status = "error"
+		r.Error = runErr.Error()
+	case res != nil && res.PlanOnly:
+		r.Status = "planned"
+	case res != nil && res.StoppedAt != "":
+		r.Status = "stopped"
+	default:
+		r.Status = "completed"
+	}
+	if err := r.Save(); err != nil {
+		return err
+	}
+	return r.audit(ctx, audit.ActionRunFinish, r.Status)
+}
+
+// Save writes the record to disk.
+func (r *Record) Save() error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return config.WriteFile(filepath.Join(r.dir, recordFileName), append(data, '\n'))
+}
+
+// MeanScore averages the record's ratings; ok is false when it has none.
+func (r *Record) MeanScore() (float64, bool) {
+	if len(r.Ratings) == 0 {
+		return 0, false
+	}
+	total := 0
+	for _, rating := range r.Ratings {
+		total += rating.Score
+	}
+	return float64(total) / float64(len(r.Ratings)), true
+}
+
+// idPrefix matches run IDs as newID generates them, and their prefixes.
+var idPrefix = regexp.MustCompile(`^[0-9]{1,8}(T[0-9]{0,6}(-[0-9a-f]{0,6})?)?$`)
+
+// Load reads a run by full ID or unique prefix. Anything else is rejected before it
+// is joined to the runs dir, so an ID such as ../.. cannot read files outside it.
+func Load(ctx context.Context, id string) (*Record, error) {
+	base, err := Dir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return nil, errors.New("run id required")
+	}
+	if !idPrefix.MatchString(id) {
+		return nil, fmt.Errorf("invalid run id %q: want an ID such as 20250301T101500-ab12cd or a prefix of one", id)
+	}
+	// A full ID needs no directory scan.
+	if rec, err := readRecord(filepath.Join(base, id)); err == nil {
+		return rec, nil
+	}
+	entries, err := os.ReadDir(base)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("run %s not found", id)
+		}
+		return nil, err
+	}
+	var matches []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if entry.Name() == id {
+			matches = []string{id}
+			break
+		}
+		if strings.HasPrefix(entry.Name(), id) {
+			matches = append(matches, entry.Name())
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("run %s not found", id)
+	case 1:
+		return readRecord(filepath.Join(base, matches[0]))
+	default:
+		return nil, fmt.Errorf("run id %s is ambiguous (%d matches)", id, len(matches))
+	}
+}
+
+// List returns every recorded run, newest first. Unreadable entries are skipped.
+func List(ctx context.Context) ([]*Record, error) {
+	base, err := Dir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(base)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var records []*Record
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		rec, err := readRecord(filepath.Join(base, entry.Name()))
+		if err != nil {
+			continue
+		}
+		records = append(records, rec)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].StartedAt.After(records[j].StartedAt) })
+	return records, nil
+}
+
+// Rate appends human feedback to a run.
+func Rate(ctx context.Context, id string, score int, comment string) (*Record, error) {
+	if score < 1 || score > 5 {
+		return nil, fmt.Errorf("score must be between 1 and 5, got %d", score)
+	}
+	rec, err := Load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	rec.Ratings = append(rec.Ratings, Rating{
+		Score:   score,
+		Comment: strings.TrimSpace(comment),
+		Rater:   principal.Current(ctx),
+		At:      time.Now().UTC(),
+	})
+	if err := rec.Save(); err != nil {
+		return nil, err
+	}
+	return rec, rec.audit(ctx, audit.ActionRunRate, fmt.Sprintf("score %d", score))
+}
+
+// Resolve records what fixed the incident behind a run, replacing any earlier note.
+func Resolve(ctx context.Context, id, note string) (*Record, error) {
+	note = strings.TrimSpace(note)
+	if note == "" {
+		return nil, errors.New("resolution note required")
+	}
+	rec, err := Load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	rec.Resolution = note
+	if err := rec.Save(); err != nil {
+		return nil, err
+	}
+	return rec, rec.audit(ctx, audit.ActionRunResolve, "")
+}
+
+// audit logs the record as saved, so a later edit to run.json no longer matches.
+func (r *Record) audit(ctx context.Context, action, detail string) error {
+	digest, err := audit.DigestFile(filepath.Join(r.dir, recordFileName))
+	if err != nil {
+		return err
+	}
+	if _, err := audit.Record(ctx, action, r.ID, digest, detail); err != nil {
+		return fmt.Errorf("audit log: %w", err)
+	}
+	return nil
+}
+
+// AuditCheck is the outcome of comparing run records with the audit log.
+type AuditCheck struct {
+	Checked  int             `json:"checked"`
+	Pruned   int             `json:"pruned"`
+	Problems []audit.Problem `json:"problems,omitempty"`
+}
+
+// VerifyAudit compares each logged run's record with the digest of its latest audit
+// entry. Runs removed by state prune are counted, not reported.
+func VerifyAudit(ctx context.Context, entries []audit.Entry) (AuditCheck, error) {
+	var check AuditCheck
+	base, err := Dir(ctx)
+	if err != nil {
+		return check, err
+	}
+	latest := map[string]audit.Entry{}
+	var order []string
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Action, "run.") {
+			continue
+		}
+		if _, seen := latest[entry.Subject]; !seen {
+			order = append(order, entry.Subject)
+		}
+		latest[entry.Subject] = entry
+	}
+	for _, id := range order {
+		entry := latest[id]
+		digest, err := audit.DigestFile(filepath.Join(base, id, recordFileName))
+		if errors.Is(err, os.ErrNotExist) {
+			check.Pruned++
+			continue
+		}
+		if err != nil {
+			return check, err
+		}
+		check.Checked++
+		if digest != entry.Digest {
+			check.Problems = append(check.Problems, audit.Problem{Seq: entry.Seq, Subject: id, Reason: "run record was modified after it was logged"})
+		}
+	}
+	return check, nil
+}
+
+// WorkflowStats aggregates feedback for one workflow name.
+type WorkflowStats struct {
+	Workflow  string  `json:"workflow"`
+	Runs      int     `json:"runs"`
+	Ratings   int     `json:"ratings"`
+	MeanScore float64 `json:"mean_score,omitempty"`
+	LowScore  bool    `json:"low_score"`
+}
+
+// Stats groups records by workflow and flags consistently low-scoring ones.
+func Stats(records []*Record) map[string]WorkflowStats {
+	stats := map[string]WorkflowStats{}
+	totals := map[string]int{}
+	for _, rec := range records {
+		s := stats[rec.Workflow]
+		s.Workflow = rec.Workflow
+		s.Runs++
+		for _, rating := range rec.Ratings {
+			s.Ratings++
+			totals[rec.Workflow] += rating.Score
+		}
+		stats[rec.Workflow] = s
+	}
+	for name, s := range stats {
+		if s.Ratings > 0 {
+			s.MeanScore = float64(totals[name]) / float64(s.Ratings)
+			s.LowScore = s.Ratings >= LowScoreMinRatings && s.MeanScore <= LowScoreThreshold
+		}
+		stats[name] = s
+	}
+	return stats
+}
+
+// ExportEntry is one JSONL line of feedback export, shaped for prompt tuning.
+type ExportEntry struct {
+	RunID    string         `json:"run_id"`
+	Workflow string         `json:"workflow"`
+	Status   string         `json:"status"`
+	Started  time.Time      `json:"started_at"`
+	Inputs   map[string]any `json:"inputs,omitempty"`
+	Prompts  []PromptSample `json:"prompts,omitempty"`
+	Ratings  []Rating       `json:"ratings"`
+	Score    float64        `json:"mean_score"`
+	Env      *Environment   `json:"environment,omitempty"`
+}
+
+// PromptSample pairs a rendered prompt with the model reply it produced.
+type PromptSample struct {
+	Step   string `json:"step"`
+	Prompt string `json:"prompt"`
+	Reply  string `json:"reply,omitempty"`
+}
+
+// Export writes rated runs as JSON lines. Unrated runs are skipped unless includeUnrated.
+func Export(w io.Writer, records []*Record, includeUnrated bool) (int, error) {
+	enc := json.NewEncoder(w)
+	count := 0
+	for _, rec := range records {
+		score, rated := rec.MeanScore()
+		if !rated && !includeUnrated {
+			continue
+		}
+		entry := ExportEntry{
+			RunID:    rec.ID,
+			Workflow: rec.Workflow,
+			Status:   rec.Status,
+			Started:  rec.StartedAt,
+			Ratings:  rec.Ratings,
+			Score:    score,
+			Env:      rec.Environment,
+		}
+		if entry.Ratings == nil {
+			entry.Ratings = []Rating{}
+		}
+		if rec.Result != nil {
+			entry.Inputs = rec.Result.Inputs
+			for _, step := range rec.Result.Steps {
+				if step.Prompt == "" {
+					continue
+				}
+				sample := PromptSample{Step: step.StepName, Prompt: step.Prompt}
+				if out, ok := step.Output.(map[string]interface{}); ok {
+					if text, ok := out["text"].(string); ok {
+						sample.Reply = text
+					}
+				}
+				entry.Prompts = append(entry.Prompts, sample)
+			}
+		}
+		if err := enc.Encode(entry); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+func readRecord(dir string) (*Record, error) {
+	data, err := os.ReadFile(filepath.Join(dir, recordFileName))
+	if err != nil {
+		return nil, err
+	}
+	var rec Record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("parse run %s: %w", filepath.Base(dir), err)
+	}
+	rec.dir = dir
+	return &rec, nil
+}
+
+// newID is sortable by start time with a random suffix to avoid collisions.
+func newID(now time.Time) (string, error) {
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%s", now.UTC().Format("20060102T150405"), hex.EncodeToString(suffix)), nil
+}
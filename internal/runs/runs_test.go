@@ -0,0 +1,34 @@
+package runs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/example/sre-ai/internal/config"
+)
+
+func TestLoadRejectsIDsOutsideTheRunsDir(t *testing.T) {
+	base := t.TempDir()
+	ctx := config.WithDir(context.Background(), base)
+	rec, err := Start(ctx, "agent", "demo", "demo.yaml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A run.json outside the runs dir that a crafted ID could reach.
+	if err := os.WriteFile(filepath.Join(base, recordFileName), []byte(`{"id":"outside"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{rec.ID, rec.ID[:8], rec.ID[:15]} {
+		if got, err := Load(ctx, id); err != nil || got.ID != rec.ID {
+			t.Errorf("Load(%q) = %v, %v; want run %s", id, got, err, rec.ID)
+		}
+	}
+	for _, id := range []string{"..", "../", "../runs/" + rec.ID, "/etc", rec.ID + "/..", `..\..`, "-rf"} {
+		if got, err := Load(ctx, id); err == nil {
+			t.Errorf("Load(%q) = run %s; want an invalid run id error", id, got.ID)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package runs
+
+import (
+	"sort"
+	"time"
+
+	"github.com/example/sre-ai/internal/providers"
+)
+
+// WorkflowUsage summarises how one workflow was used over a report window.
+type WorkflowUsage struct {
+	Workflow  string `json:"workflow"`
+	Runs      int    `json:"runs"`
+	Completed int    `json:"completed"`
+	Failed    int    `json:"failed"`
+	// Incomplete counts runs still marked running, e.g. because the process was killed.
+	Incomplete int `json:"incomplete"`
+	// SuccessRate is Completed over finished runs; it is zero when none finished.
+	SuccessRate  float64         `json:"success_rate"`
+	MeanDuration time.Duration   `json:"mean_duration"`
+	Tokens       providers.Usage `json:"tokens"`
+	LastRun      time.Time       `json:"last_run"`
+	FailingSteps []StepFailures  `json:"failing_steps,omitempty"`
+	// Trend counts the runs started in each bucket of the report (see UsageReport).
+	Trend []int `json:"trend"`
+}
+
+// StepFailures counts how often one step failed.
+type StepFailures struct {
+	Workflow  string `json:"workflow,omitempty"`
+	Step      string `json:"step"`
+	Count     int    `json:"count"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// UsageReport is the result of Usage. The window from TrendStart to the report time is
+// cut into buckets of TrendBucket, one day unless that would make more than
+// maxTrendBuckets; TokenTrend and each workflow's Trend hold one value per bucket.
+type UsageReport struct {
+	// Since is the start of the window; nil means all history.
+	Since     *time.Time      `json:"since,omitempty"`
+	Runs      int             `json:"runs"`
+	Tokens    providers.Usage `json:"tokens"`
+	Workflows []WorkflowUsage `json:"workflows"`
+	// TopFailingSteps ranks failing steps across every workflow.
+	TopFailingSteps []StepFailures `json:"top_failing_steps,omitempty"`
+	TrendStart      time.Time      `json:"trend_start"`
+	TrendBucket     time.Duration  `json:"trend_bucket"`
+	// TokenTrend sums the tokens of the runs started in each bucket.
+	TokenTrend []int `json:"token_trend"`
+}
+
+// maxTrendBuckets bounds the trend series of a report over a long window.
+const maxTrendBuckets = 60
+
+// Usage aggregates agent runs started at or after since (zero keeps all) per workflow,
+// busiest first, with trends up to now. Each workflow keeps its top failing steps, as
+// does the report overall.
+func Usage(records []*Record, since, now time.Time, top int) UsageReport {
+	report := UsageReport{Workflows: []WorkflowUsage{}}
+	if !since.IsZero() {
+		report.Since = &since
+	}
+	report.TrendStart, report.TrendBucket = trendBuckets(records, since, now)
+	buckets := int(now.Sub(report.TrendStart)/report.TrendBucket) + 1
+	report.TokenTrend = make([]int, buckets)
+	byName := map[string]*WorkflowUsage{}
+	durations := map[string]time.Duration{}
+	finished := map[string]int{}
+	failures := map[string]map[string]*StepFailures{}
+
+	// Records are newest first, so the first error seen for a step is its latest.
+	for _, rec := range records {
+		if rec.Kind != "agent" || (!since.IsZero() && rec.StartedAt.Before(since)) {
+			continue
+		}
+		u := byName[rec.Workflow]
+		if u == nil {
+			u = &WorkflowUsage{Workflow: rec.Workflow, LastRun: rec.StartedAt}
+			byName[rec.Workflow] = u
+			failures[rec.Workflow] = map[string]*StepFailures{}
+		}
+		report.Runs++
+		u.Runs++
+		if u.Trend == nil {
+			u.Trend = make([]int, buckets)
+		}
+		bucket := trendBucket(report, rec.StartedAt, buckets)
+		u.Trend[bucket]++
+		switch rec.Status {
+		case "completed":
+			u.Completed++
+		case "error":
+			u.Failed++
+		default:
+			u.Incomplete++
+		}
+		if rec.FinishedAt != nil {
+			durations[rec.Workflow] += rec.FinishedAt.Sub(rec.StartedAt)
+			finished[rec.Workflow]++
+		}
+		if rec.Result == nil {
+			continue
+		}
+		usage := rec.Usage()
+		u.Tokens.Add(usage)
+		report.TokenTrend[bucket] += usage.Total()
+		for _, step := range rec.Result.Steps {
+			switch step.Status {
+			case "error", "verify_failed", "blocked":
+				f := failures[rec.Workflow][step.StepName]
+				if f == nil {
+					f = &StepFailures{Workflow: rec.Workflow, Step: step.StepName, LastError: step.Error}
+					failures[rec.Workflow][step.StepName] = f
+				}
+				f.Count++
+			}
+		}
+	}
+
+	var all []StepFailures
+	for name, u := range byName {
+		if done := u.Completed + u.Failed; done > 0 {
+			u.SuccessRate = float64(u.Completed) / float64(done)
+		}
+		if n := finished[name]; n > 0 {
+			u.MeanDuration = durations[name] / time.Duration(n)
+		}
+		var steps []StepFailures
+		for _, f := range failures[name] {
+			steps = append(steps, *f)
+			all = append(all, *f)
+		}
+		sortFailures(steps)
+		for i := range steps {
+			steps[i].Workflow = ""
+		}
+		u.FailingSteps = truncateFailures(steps, top)
+		report.Tokens.Add(u.Tokens)
+		report.Workflows = append(report.Workflows, *u)
+	}
+	sort.Slice(report.Workflows, func(i, j int) bool {
+		a, b := report.Workflows[i], report.Workflows[j]
+		if a.Runs != b.Runs {
+			return a.Runs > b.Runs
+		}
+		return a.Workflow < b.Workflow
+	})
+	sortFailures(all)
+	report.TopFailingSteps = truncateFailures(all, top)
+	return report
+}
+
+// trendBuckets starts the trend at the local midnight of since, or of the oldest agent
+// run for all history, and widens the bucket by whole days to stay within
+// maxTrendBuckets.
+func trendBuckets(records []*Record, since, now time.Time) (time.Time, time.Duration) {
+	start := since
+	if start.IsZero() {
+		start = now
+		for _, rec := range records {
+			if rec.Kind == "agent" && rec.StartedAt.Before(start) {
+				start = rec.StartedAt
+			}
+		}
+	}
+	start = start.In(now.Location())
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	const day = 24 * time.Hour
+	days := int(now.Sub(start)/day) + 1
+	return start, day * time.Duration((days+maxTrendBuckets-1)/maxTrendBuckets)
+}
+
+// trendBucket is the bucket a run started at belongs to, clamped to the report so a
+// clock skewed into the future cannot index past it.
+func trendBucket(report UsageReport, at time.Time, buckets int) int {
+	i := int(at.Sub(report.TrendStart) / report.TrendBucket)
+	if i < 0 {
+		return 0
+	}
+	if i >= buckets {
+		return buckets - 1
+	}
+	return i
+}
+
+// Usage sums the tokens spent by the run's steps.
+func (r *Record) Usage() providers.Usage {
+	var total providers.Usage
+	if r.Result == nil {
+		return total
+	}
+	for _, step := range r.Result.Steps {
+		if step.Usage != nil {
+			total.Add(*step.Usage)
+		}
+	}
+	return total
+}
+
+func sortFailures(steps []StepFailures) {
+	sort.Slice(steps, func(i, j int) bool {
+		if steps[i].Count != steps[j].Count {
+			return steps[i].Count > steps[j].Count
+		}
+		if steps[i].Workflow != steps[j].Workflow {
+			return steps[i].Workflow < steps[j].Workflow
+		}
+		return steps[i].Step < steps[j].Step
+	})
+}
+
+func truncateFailures(steps []StepFailures, top int) []StepFailures {
+	if top > 0 && len(steps) > top {
+		return steps[:top]
+	}
+	return steps
+}
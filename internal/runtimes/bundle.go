@@ -0,0 +1,518 @@
+package runtimes
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/sre-ai/internal/httpx"
+)
+
+// ManifestFile is the checksum manifest written into every bundled distribution.
+const ManifestFile = "sre-ai-bundle.json"
+
+// Target is an os/arch pair in GOOS/GOARCH terms, e.g. linux/arm64.
+type Target struct {
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+}
+
+// CurrentTarget is the platform the CLI is running on.
+func CurrentTarget() Target {
+	return Target{OS: runtime.GOOS, Arch: runtime.GOARCH}
+}
+
+// ParseTarget parses "os/arch".
+func ParseTarget(value string) (Target, error) {
+	osName, arch, ok := strings.Cut(strings.ToLower(strings.TrimSpace(value)), "/")
+	if !ok || osName == "" || arch == "" {
+		return Target{}, fmt.Errorf("target %q: want os/arch, e.g. linux/arm64", value)
+	}
+	return Target{OS: osName, Arch: arch}, nil
+}
+
+func (t Target) String() string {
+	return t.OS + "/" + t.Arch
+}
+
+// Release is a downloadable runtime version.
+type Release struct {
+	Runtime Name   `json:"runtime"`
+	Version string `json:"version"`
+	// BaseURL holds the archives and the checksum list of the version.
+	BaseURL string `json:"base_url"`
+	// Checksums is the file under BaseURL listing "sha256  archive" lines.
+	Checksums string `json:"checksums"`
+}
+
+// nodeMirror is the official Node.js download site.
+const nodeMirror = "https://nodejs.org/dist"
+
+// Pinned lists the runtime versions the CLI is tested with and bundles by default.
+var Pinned = map[Name]Release{
+	Node: NodeRelease("v20.16.0", ""),
+}
+
+// NodeRelease describes a Node.js version hosted on mirror (the official site when empty).
+func NodeRelease(version, mirror string) Release {
+	if !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+	if mirror == "" {
+		mirror = nodeMirror
+	}
+	return Release{Runtime: Node, Version: version, BaseURL: strings.TrimRight(mirror, "/") + "/" + version, Checksums: "SHASUMS256.txt"}
+}
+
+// Archive names the release archive for target.
+func (r Release) Archive(target Target) (string, error) {
+	switch r.Runtime {
+	case Node:
+		osName := map[string]string{"linux": "linux", "darwin": "darwin", "windows": "win"}[target.OS]
+		arch := map[string]string{"amd64": "x64", "arm64": "arm64", "arm": "armv7l", "ppc64le": "ppc64le", "s390x": "s390x"}[target.Arch]
+		if osName == "" || arch == "" {
+			return "", fmt.Errorf("node %s has no build for %s", r.Version, target)
+		}
+		ext := ".tar.gz"
+		if target.OS == "windows" {
+			ext = ".zip"
+		}
+		return fmt.Sprintf("node-%s-%s-%s%s", r.Version, osName, arch, ext), nil
+	}
+	return "", fmt.Errorf("bundling %s is not supported", r.Runtime)
+}
+
+// Manifest records what a bundle contains so it can be checked before it is used.
+type Manifest struct {
+	Runtime Name   `json:"runtime"`
+	Version string `json:"version"`
+	Target  Target `json:"target"`
+	Source  string `json:"source"`
+	// ArchiveSHA256 is the checksum the archive was verified against.
+	ArchiveSHA256 string `json:"archive_sha256"`
+	// Binary is the runtime executable, relative to the bundle directory.
+	Binary string `json:"binary"`
+	// Files maps every regular file, relative and slash separated, to its sha256.
+	Files   map[string]string `json:"files"`
+	Created time.Time         `json:"created"`
+}
+
+// BundleResult describes one bundled distribution.
+type BundleResult struct {
+	Target  Target `json:"target"`
+	Archive string `json:"archive"`
+	URL     string `json:"url"`
+	SHA256  string `json:"sha256,omitempty"`
+	Dir     string `json:"dir"`
+	Files   int    `json:"files,omitempty"`
+	// Skipped is set when a verified bundle for the target was already present.
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// BundleOptions controls Bundle.
+type BundleOptions struct {
+	// Dir is the third_party directory; the bundle lands in Dir/<runtime>/<dist>.
+	Dir string
+	// Force replaces an existing bundle of the same distribution.
+	Force      bool
+	HTTPClient *http.Client
+}
+
+// Bundle downloads the release archive for target, verifies it against the release
+// checksum list, and unpacks it under opts.Dir with a manifest of file checksums.
+func Bundle(ctx context.Context, release Release, target Target, opts BundleOptions) (BundleResult, error) {
+	archive, err := release.Archive(target)
+	if err != nil {
+		return BundleResult{}, err
+	}
+	result := BundleResult{Target: target, Archive: archive, URL: release.BaseURL + "/" + archive}
+	root := filepath.Join(opts.Dir, string(release.Runtime))
+	result.Dir = filepath.Join(root, distName(archive))
+
+	if !opts.Force {
+		if manifest, err := ReadManifest(result.Dir); err == nil {
+			if err := VerifyBundle(result.Dir); err != nil {
+				return result, fmt.Errorf("%s already exists but does not verify (use --force to replace it): %w", result.Dir, err)
+			}
+			result.SHA256, result.Files, result.Skipped = manifest.ArchiveSHA256, len(manifest.Files), true
+			return result, nil
+		} else if _, statErr := os.Stat(result.Dir); statErr == nil {
+			return result, fmt.Errorf("%s exists without a bundle manifest; use --force to replace it", result.Dir)
+		}
+	}
+
+	client := opts.HTTPClient
+	if client == nil {
+		client = httpx.Client(10 * time.Minute)
+	}
+	want, err := fetchChecksum(ctx, client, release.BaseURL+"/"+release.Checksums, archive)
+	if err != nil {
+		return result, err
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return result, err
+	}
+	tmp, err := os.CreateTemp(root, ".download-*")
+	if err != nil {
+		return result, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	got, err := download(ctx, client, result.URL, tmp)
+	if err != nil {
+		return result, err
+	}
+	if got != want {
+		return result, fmt.Errorf("%s: sha256 %s does not match %s from %s", archive, got, want, release.Checksums)
+	}
+	result.SHA256 = got
+
+	staging, err := os.MkdirTemp(root, ".unpack-*")
+	if err != nil {
+		return result, err
+	}
+	defer os.RemoveAll(staging)
+	if strings.HasSuffix(archive, ".zip") {
+		err = unzip(tmp, staging)
+	} else {
+		err = untar(tmp, staging)
+	}
+	if err != nil {
+		return result, fmt.Errorf("unpack %s: %w", archive, err)
+	}
+	unpacked := filepath.Join(staging, distName(archive))
+	if _, err := os.Stat(unpacked); err != nil {
+		return result, fmt.Errorf("unpack %s: no %s directory in the archive", archive, distName(archive))
+	}
+
+	manifest := Manifest{
+		Runtime:       release.Runtime,
+		Version:       release.Version,
+		Target:        target,
+		Source:        result.URL,
+		ArchiveSHA256: got,
+		Created:       time.Now().UTC(),
+	}
+	if manifest.Files, err = hashTree(unpacked); err != nil {
+		return result, err
+	}
+	manifest.Binary = binaryFor(release.Runtime, target, manifest.Files)
+	if manifest.Binary == "" {
+		return result, fmt.Errorf("%s contains no %s binary for %s", archive, release.Runtime, target)
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return result, err
+	}
+	if err := os.WriteFile(filepath.Join(unpacked, ManifestFile), append(data, '\n'), 0o644); err != nil {
+		return result, err
+	}
+
+	if err := os.RemoveAll(result.Dir); err != nil {
+		return result, err
+	}
+	if err := os.Rename(unpacked, result.Dir); err != nil {
+		return result, err
+	}
+	result.Files = len(manifest.Files)
+	return result, nil
+}
+
+// VerifyBundle checks every file listed in the bundle's manifest.
+func VerifyBundle(dir string) error {
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(manifest.Files))
+	for name := range manifest.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		got, err := hashFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return err
+		}
+		if got != manifest.Files[name] {
+			return fmt.Errorf("%s: checksum mismatch", name)
+		}
+	}
+	return nil
+}
+
+// distName is the archive's top-level directory, e.g. node-v20.16.0-linux-arm64.
+func distName(archive string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(archive, ".zip"), ".tar.gz")
+}
+
+func binaryFor(name Name, target Target, files map[string]string) string {
+	candidates := []string{"bin/" + string(name), string(name)}
+	if target.OS == "windows" {
+		candidates = []string{string(name) + ".exe", "bin/" + string(name) + ".exe"}
+	}
+	for _, candidate := range candidates {
+		if _, ok := files[candidate]; ok {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// ReadManifest returns the manifest of a distribution written by Bundle.
+func ReadManifest(dir string) (Manifest, error) {
+	var manifest Manifest
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return manifest, err
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("%s: %w", ManifestFile, err)
+	}
+	return manifest, nil
+}
+
+var (
+	verifiedMu sync.Mutex
+	// verified caches binary checks by path, size, and modification time.
+	verified = map[string]error{}
+)
+
+// checkBundledBinary verifies binary against the manifest of dist. Bundles without a
+// manifest predate it and are accepted as they are.
+func checkBundledBinary(dist, binary string) (bool, error) {
+	manifest, err := ReadManifest(dist)
+	if errors.Is(err, fs.ErrNotExist) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if manifest.Target != CurrentTarget() {
+		return false, nil
+	}
+	rel, err := filepath.Rel(dist, binary)
+	if err != nil {
+		return false, err
+	}
+	want, ok := manifest.Files[filepath.ToSlash(rel)]
+	if !ok {
+		return false, fmt.Errorf("%s is not listed in %s", rel, ManifestFile)
+	}
+	info, err := os.Stat(binary)
+	if err != nil {
+		return false, err
+	}
+	key := fmt.Sprintf("%s|%d|%d", binary, info.Size(), info.ModTime().UnixNano())
+	verifiedMu.Lock()
+	defer verifiedMu.Unlock()
+	if err, ok := verified[key]; ok {
+		return err == nil, err
+	}
+	got, err := hashFile(binary)
+	if err == nil && got != want {
+		err = fmt.Errorf("%s: checksum mismatch with %s", binary, ManifestFile)
+	}
+	verified[key] = err
+	return err == nil, err
+}
+
+func fetchChecksum(ctx context.Context, client *http.Client, url, archive string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetch checksums: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetch checksums %s: %s", url, resp.Status)
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == archive {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("fetch checksums: %w", err)
+	}
+	return "", fmt.Errorf("%s lists no checksum for %s", url, archive)
+}
+
+func download(ctx context.Context, client *http.Client, url string, dst *os.File) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("download: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download %s: %s", url, resp.Status)
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(dst, hash), resp.Body); err != nil {
+		return "", fmt.Errorf("download %s: %w", url, err)
+	}
+	if _, err := dst.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// safeJoin resolves an archive entry under dir, rejecting entries that escape it.
+func safeJoin(dir, name string) (string, error) {
+	clean := path.Clean("/" + filepath.ToSlash(name))
+	if clean == "/" {
+		return "", fmt.Errorf("invalid entry %q", name)
+	}
+	return filepath.Join(dir, filepath.FromSlash(clean[1:])), nil
+}
+
+func untar(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if strings.Trim(hdr.Name, "./") == "" {
+			continue
+		}
+		target, err := safeJoin(dir, hdr.Name)
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, 0o755)
+		case tar.TypeReg:
+			err = writeEntry(target, tr, hdr.FileInfo().Mode().Perm())
+		case tar.TypeSymlink:
+			// Links such as bin/npm -> ../lib/node_modules/npm/bin/npm-cli.js stay relative
+			// and inside the bundle.
+			if filepath.IsAbs(hdr.Linkname) || !strings.HasPrefix(filepath.Join(filepath.Dir(target), hdr.Linkname), dir+string(filepath.Separator)) {
+				return fmt.Errorf("entry %s links outside the archive", hdr.Name)
+			}
+			if err = os.MkdirAll(filepath.Dir(target), 0o755); err == nil {
+				err = os.Symlink(hdr.Linkname, target)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func unzip(f *os.File, dir string) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return err
+	}
+	for _, entry := range zr.File {
+		target, err := safeJoin(dir, entry.Name)
+		if err != nil {
+			return err
+		}
+		if entry.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+			continue
+		}
+		rc, err := entry.Open()
+		if err != nil {
+			return err
+		}
+		err = writeEntry(target, rc, entry.Mode().Perm()|0o600)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeEntry(target string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func hashTree(dir string) (map[string]string, error) {
+	files := map[string]string{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		sum, err := hashFile(p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = sum
+		return nil
+	})
+	return files, err
+}
+
+func hashFile(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
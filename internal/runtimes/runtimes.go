@@ -0,0 +1,516 @@
+package runtimes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Name identifies a language runtime that MCP servers commonly depend on.
+type Name string
+
+const (
+	Node   Name = "node"
+	Python Name = "python"
+)
+
+// Known lists the runtimes the CLI detects, in display order.
+var Known = []Name{Node, Python}
+
+// Source describes where a runtime installation was found.
+type Source string
+
+const (
+	SourceOverride Source = "override"
+	SourceBundled  Source = "bundled"
+	SourceSystem   Source = "system"
+	SourceVolta    Source = "volta"
+	SourceNvm      Source = "nvm"
+	SourceAsdf     Source = "asdf"
+	SourcePyenv    Source = "pyenv"
+	SourceHomebrew Source = "homebrew"
+	SourceScoop    Source = "scoop"
+)
+
+// Detection is a single runtime installation candidate.
+type Detection struct {
+	Runtime Name   `json:"runtime"`
+	Source  Source `json:"source"`
+	Binary  string `json:"binary"`
+	BinDir  string `json:"bin_dir"`
+	Version string `json:"version,omitempty"`
+	// OnPath is true when BinDir is already part of the inherited PATH.
+	OnPath bool `json:"on_path"`
+}
+
+// Report is the outcome of detecting one runtime: the chosen installation plus every candidate seen.
+type Report struct {
+	Runtime    Name        `json:"runtime"`
+	Chosen     *Detection  `json:"chosen,omitempty"`
+	Candidates []Detection `json:"candidates,omitempty"`
+	Override   string      `json:"override,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+type contextKey struct{}
+
+// WithOverrides returns a context whose detection prefers the per-runtime overrides from
+// config (runtime name -> binary or bin dir).
+func WithOverrides(ctx context.Context, values map[string]string) context.Context {
+	overrides := make(map[Name]string, len(values))
+	for k, v := range values {
+		if strings.TrimSpace(v) != "" {
+			overrides[Name(strings.ToLower(k))] = expandHome(strings.TrimSpace(v))
+		}
+	}
+	return context.WithValue(ctx, contextKey{}, overrides)
+}
+
+func override(ctx context.Context, name Name) string {
+	overrides, _ := ctx.Value(contextKey{}).(map[Name]string)
+	return overrides[name]
+}
+
+// Detect finds every installation of the runtime and picks the preferred one.
+// Preference order: config override, bundled third_party copy, the inherited PATH,
+// then version managers (volta, nvm, asdf, pyenv) and package managers (Homebrew, Scoop),
+// which matter when the CLI is launched without the user's shell init.
+func Detect(ctx context.Context, name Name) Report {
+	report := Report{Runtime: name, Override: override(ctx, name)}
+
+	if report.Override != "" {
+		det, err := fromOverride(name, report.Override)
+		if err != nil {
+			report.Error = err.Error()
+		} else {
+			report.Candidates = append(report.Candidates, det)
+		}
+	}
+
+	found, err := candidates(name)
+	if err != nil && report.Error == "" {
+		report.Error = err.Error()
+	}
+	report.Candidates = append(report.Candidates, found...)
+	report.Candidates = dedupe(report.Candidates)
+	if len(report.Candidates) > 0 {
+		chosen := report.Candidates[0]
+		report.Chosen = &chosen
+	} else if report.Error == "" {
+		report.Error = fmt.Sprintf("%s runtime not found", name)
+	}
+	return report
+}
+
+// DetectAll runs Detect for every known runtime.
+func DetectAll(ctx context.Context) []Report {
+	reports := make([]Report, 0, len(Known))
+	for _, name := range Known {
+		reports = append(reports, Detect(ctx, name))
+	}
+	return reports
+}
+
+// PathDirs returns bin directories that should be prepended to PATH so child
+// processes resolve the chosen runtimes. Runtimes already resolved from PATH are skipped.
+func PathDirs(ctx context.Context) []string {
+	var dirs []string
+	for _, report := range DetectAll(ctx) {
+		if report.Chosen == nil || report.Chosen.OnPath {
+			continue
+		}
+		dirs = append(dirs, report.Chosen.BinDir)
+	}
+	return dedupePaths(dirs)
+}
+
+// PrependToPath returns existing with the chosen runtime directories placed first.
+func PrependToPath(ctx context.Context, existing string) string {
+	dirs := PathDirs(ctx)
+	if len(dirs) == 0 {
+		return existing
+	}
+
+	sep := string(os.PathListSeparator)
+	pieces := make([]string, 0, len(dirs)+4)
+	seen := map[string]struct{}{}
+	add := func(dir string) {
+		trimmed := strings.TrimSpace(dir)
+		if trimmed == "" {
+			return
+		}
+		key := strings.ToLower(trimmed)
+		if _, ok := seen[key]; ok {
+			return
+		}
+		seen[key] = struct{}{}
+		pieces = append(pieces, trimmed)
+	}
+	for _, dir := range dirs {
+		add(dir)
+	}
+	for _, part := range strings.Split(existing, sep) {
+		add(part)
+	}
+	return strings.Join(pieces, sep)
+}
+
+// Bundled returns the bundled installation of the runtime, if any.
+func Bundled(name Name) (string, error) {
+	found, err := bundled(name)
+	if len(found) > 0 {
+		return found[0].Binary, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("bundled %s runtime not found", name)
+}
+
+// candidates lists installations in preference order. The error reports bundles that
+// failed verification; they are left out of the list.
+func candidates(name Name) ([]Detection, error) {
+	out, err := bundled(name)
+	if det, ok := fromPath(name); ok {
+		out = append(out, det)
+	}
+	switch name {
+	case Node:
+		out = append(out, volta()...)
+		out = append(out, nvm()...)
+		out = append(out, asdf("nodejs", name)...)
+	case Python:
+		out = append(out, pyenv()...)
+		out = append(out, asdf("python", name)...)
+	}
+	out = append(out, packageManagers(name)...)
+	return out, err
+}
+
+func binaryNames(name Name) []string {
+	switch name {
+	case Python:
+		if runtime.GOOS == "windows" {
+			return []string{"python.exe"}
+		}
+		return []string{"python3", "python"}
+	default:
+		if runtime.GOOS == "windows" {
+			return []string{"node.exe"}
+		}
+		return []string{"node"}
+	}
+}
+
+func findIn(dir string, name Name) (string, bool) {
+	for _, bin := range binaryNames(name) {
+		candidate := filepath.Join(dir, bin)
+		if fileExists(candidate) {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+func detection(name Name, source Source, binary, version string) Detection {
+	dir := filepath.Dir(binary)
+	return Detection{Runtime: name, Source: source, Binary: binary, BinDir: dir, Version: version, OnPath: dirOnPath(dir)}
+}
+
+func fromOverride(name Name, value string) (Detection, error) {
+	info, err := os.Stat(value)
+	if err != nil {
+		return Detection{}, fmt.Errorf("%s override %s: %w", name, value, err)
+	}
+	if info.IsDir() {
+		for _, dir := range []string{value, filepath.Join(value, "bin")} {
+			if bin, ok := findIn(dir, name); ok {
+				return detection(name, SourceOverride, bin, ""), nil
+			}
+		}
+		return Detection{}, fmt.Errorf("%s override %s contains no %s binary", name, value, name)
+	}
+	return detection(name, SourceOverride, value, ""), nil
+}
+
+func fromPath(name Name) (Detection, bool) {
+	for _, bin := range binaryNames(name) {
+		if p, err := exec.LookPath(bin); err == nil {
+			if abs, err := filepath.Abs(p); err == nil {
+				p = abs
+			}
+			det := detection(name, SourceSystem, p, "")
+			det.OnPath = true
+			return det, true
+		}
+	}
+	return Detection{}, false
+}
+
+// bundled scans third_party/<runtime>/<dist> next to the executable (the layout used for node since the MVP).
+// Distributions written by Bundle carry a manifest: those built for another platform are
+// skipped, and the binary must match its recorded checksum.
+func bundled(name Name) ([]Detection, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return nil, nil
+	}
+	exeDir := filepath.Dir(exePath)
+	roots := []string{
+		filepath.Join(exeDir, "third_party", string(name)),
+		filepath.Join(exeDir, "..", "third_party", string(name)),
+	}
+
+	var out []Detection
+	var errs []error
+	for _, root := range roots {
+		for _, dist := range sortedVersionDirs(root) {
+			for _, dir := range []string{dist, filepath.Join(dist, "bin")} {
+				if bin, ok := findIn(dir, name); ok {
+					usable, err := checkBundledBinary(dist, bin)
+					if err != nil {
+						errs = append(errs, fmt.Errorf("bundled %s %s: %w", name, filepath.Base(dist), err))
+					} else if usable {
+						out = append(out, detection(name, SourceBundled, bin, filepath.Base(dist)))
+					}
+					break
+				}
+			}
+		}
+	}
+	return out, errors.Join(errs...)
+}
+
+func volta() []Detection {
+	home := envOr("VOLTA_HOME", filepath.Join(homeDir(), ".volta"))
+	if bin, ok := findIn(filepath.Join(home, "bin"), Node); ok {
+		return []Detection{detection(Node, SourceVolta, bin, "")}
+	}
+	return nil
+}
+
+func nvm() []Detection {
+	var out []Detection
+	if runtime.GOOS == "windows" {
+		if root := os.Getenv("NVM_HOME"); root != "" {
+			for _, dir := range sortedVersionDirs(root) {
+				if bin, ok := findIn(dir, Node); ok {
+					out = append(out, detection(Node, SourceNvm, bin, filepath.Base(dir)))
+				}
+			}
+		}
+		return out
+	}
+	root := envOr("NVM_DIR", filepath.Join(homeDir(), ".nvm"))
+	for _, dir := range sortedVersionDirs(filepath.Join(root, "versions", "node")) {
+		if bin, ok := findIn(filepath.Join(dir, "bin"), Node); ok {
+			out = append(out, detection(Node, SourceNvm, bin, filepath.Base(dir)))
+		}
+	}
+	return out
+}
+
+func asdf(plugin string, name Name) []Detection {
+	root := envOr("ASDF_DATA_DIR", filepath.Join(homeDir(), ".asdf"))
+	var out []Detection
+	for _, dir := range sortedVersionDirs(filepath.Join(root, "installs", plugin)) {
+		if bin, ok := findIn(filepath.Join(dir, "bin"), name); ok {
+			out = append(out, detection(name, SourceAsdf, bin, filepath.Base(dir)))
+		}
+	}
+	return out
+}
+
+func pyenv() []Detection {
+	root := envOr("PYENV_ROOT", filepath.Join(homeDir(), ".pyenv"))
+	var out []Detection
+	for _, dir := range sortedVersionDirs(filepath.Join(root, "versions")) {
+		if bin, ok := findIn(filepath.Join(dir, "bin"), Python); ok {
+			out = append(out, detection(Python, SourcePyenv, bin, filepath.Base(dir)))
+		}
+	}
+	return out
+}
+
+func packageManagers(name Name) []Detection {
+	var out []Detection
+	if runtime.GOOS == "windows" {
+		root := envOr("SCOOP", filepath.Join(homeDir(), "scoop"))
+		for _, dir := range []string{filepath.Join(root, "shims"), filepath.Join(root, "apps", scoopApp(name), "current")} {
+			if bin, ok := findIn(dir, name); ok {
+				out = append(out, detection(name, SourceScoop, bin, ""))
+			}
+		}
+		return out
+	}
+	for _, dir := range []string{"/opt/homebrew/bin", "/usr/local/bin", "/home/linuxbrew/.linuxbrew/bin"} {
+		if bin, ok := findIn(dir, name); ok {
+			out = append(out, detection(name, SourceHomebrew, bin, ""))
+		}
+	}
+	return out
+}
+
+func scoopApp(name Name) string {
+	if name == Node {
+		return "nodejs"
+	}
+	return string(name)
+}
+
+// sortedVersionDirs lists sub-directories newest version first (v20.16.0 before v18.19.1).
+func sortedVersionDirs(root string) []string {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil
+	}
+	var dirs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, entry.Name())
+		}
+	}
+	sort.SliceStable(dirs, func(i, j int) bool { return compareVersions(dirs[i], dirs[j]) > 0 })
+	out := make([]string, 0, len(dirs))
+	for _, d := range dirs {
+		out = append(out, filepath.Join(root, d))
+	}
+	return out
+}
+
+func compareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x > y {
+				return 1
+			}
+			return -1
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+func versionParts(name string) []int {
+	var parts []int
+	current, inNumber := 0, false
+	for _, r := range name {
+		if r >= '0' && r <= '9' {
+			current = current*10 + int(r-'0')
+			inNumber = true
+			continue
+		}
+		if inNumber {
+			parts = append(parts, current)
+			current, inNumber = 0, false
+		}
+		if r == '-' && len(parts) >= 3 {
+			break
+		}
+	}
+	if inNumber {
+		parts = append(parts, current)
+	}
+	return parts
+}
+
+func dirOnPath(dir string) bool {
+	for _, part := range filepath.SplitList(os.Getenv("PATH")) {
+		if part != "" && strings.EqualFold(filepath.Clean(part), filepath.Clean(dir)) {
+			return true
+		}
+	}
+	return false
+}
+
+func dedupe(list []Detection) []Detection {
+	seen := map[string]struct{}{}
+	out := make([]Detection, 0, len(list))
+	for _, det := range list {
+		key := strings.ToLower(det.Binary)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, det)
+	}
+	return out
+}
+
+func dedupePaths(paths []string) []string {
+	seen := map[string]struct{}{}
+	out := make([]string, 0, len(paths))
+	for _, p := range paths {
+		lp := strings.ToLower(p)
+		if _, ok := seen[lp]; ok {
+			continue
+		}
+		seen[lp] = struct{}{}
+		out = append(out, p)
+	}
+	return out
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+func homeDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return home
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~") {
+		if home := homeDir(); home != "" {
+			return filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	return path
+}
+
+// ErrNotFound is returned when no installation of a runtime exists.
+var ErrNotFound = errors.New("runtime not found")
+
+// Binary returns the chosen binary for the runtime.
+func Binary(ctx context.Context, name Name) (string, error) {
+	report := Detect(ctx, name)
+	if report.Chosen == nil {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return report.Chosen.Binary, nil
+}
+
+// Version runs the binary with --version; best effort and used only for display.
+func Version(binary string) string {
+	out, err := exec.Command(binary, "--version").CombinedOutput()
+	if err != nil {
+		return ""
+	}
+	line := strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0])
+	return strings.TrimPrefix(line, "Python ")
+}
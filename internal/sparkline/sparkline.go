@@ -0,0 +1,110 @@
+// Package sparkline draws time series as one line of block characters for terminal
+// reports, e.g. "▁▂▂▃▅▇█▆".
+package sparkline
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultWidth is how many characters a sparkline takes unless told otherwise.
+const DefaultWidth = 24
+
+var blocks = []rune("▁▂▃▄▅▆▇█")
+
+// Render draws values, oldest first, in at most width characters; longer series are
+// averaged into width buckets. The lowest value gets the lowest block and the highest
+// the full one; a flat series is drawn at mid height. NaN and infinite values, which
+// Prometheus returns for empty ratios, leave a gap.
+func Render(values []float64, width int) string {
+	if width <= 0 {
+		width = DefaultWidth
+	}
+	values = resample(values, width)
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		if finite(v) {
+			low = math.Min(low, v)
+			high = math.Max(high, v)
+		}
+	}
+	var b strings.Builder
+	for _, v := range values {
+		switch {
+		case !finite(v):
+			b.WriteRune(' ')
+		case high == low:
+			b.WriteRune(blocks[len(blocks)/2-1])
+		default:
+			idx := int(math.Round((v - low) / (high - low) * float64(len(blocks)-1)))
+			b.WriteRune(blocks[idx])
+		}
+	}
+	return b.String()
+}
+
+// Stats summarizes values as "min 1.2 max 9.8 last 4.5" next to a sparkline, so its
+// scale can be read. It is empty when no value is finite.
+func Stats(values []float64) string {
+	low, high, last := math.Inf(1), math.Inf(-1), math.NaN()
+	for _, v := range values {
+		if finite(v) {
+			low = math.Min(low, v)
+			high = math.Max(high, v)
+			last = v
+		}
+	}
+	if math.IsNaN(last) {
+		return ""
+	}
+	return fmt.Sprintf("min %s max %s last %s", Format(low), Format(high), Format(last))
+}
+
+// Format renders a value compactly: three significant digits, and k/M/G
+// suffixes for large magnitudes.
+func Format(v float64) string {
+	abs := math.Abs(v)
+	switch {
+	case abs >= 1e9:
+		return trim(v/1e9) + "G"
+	case abs >= 1e6:
+		return trim(v/1e6) + "M"
+	case abs >= 1e3:
+		return trim(v/1e3) + "k"
+	}
+	return trim(v)
+}
+
+func trim(v float64) string {
+	return strconv.FormatFloat(v, 'g', 3, 64)
+}
+
+// resample averages values into width buckets, skipping values that are not finite;
+// a bucket with none of those stays NaN.
+func resample(values []float64, width int) []float64 {
+	if len(values) <= width {
+		return values
+	}
+	out := make([]float64, width)
+	for i := range out {
+		from, to := i*len(values)/width, (i+1)*len(values)/width
+		sum, n := 0.0, 0
+		for _, v := range values[from:to] {
+			if finite(v) {
+				sum += v
+				n++
+			}
+		}
+		out[i] = math.NaN()
+		if n > 0 {
+			out[i] = sum / float64(n)
+		}
+	}
+	return out
+}
+
+func finite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
@@ -0,0 +1,420 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/timeparse"
+)
+
+// Kinds of local state that retention applies to, in the order they are pruned.
+const (
+	KindRuns      = "runs"
+	KindArtifacts = "artifacts"
+	KindFacts     = "facts"
+	KindSessions  = "sessions"
+	KindLogs      = "logs"
+	KindCache     = "cache"
+)
+
+// PruneKinds lists every kind retention knows.
+var PruneKinds = []string{KindRuns, KindArtifacts, KindFacts, KindSessions, KindLogs, KindCache}
+
+// Policy bounds one kind of state. Items older than MaxAge go first, then the oldest
+// until the rest fits in MaxSize. Zero means no bound.
+type Policy struct {
+	MaxAge  time.Duration `json:"max_age,omitempty"`
+	MaxSize int64         `json:"max_size,omitempty"`
+}
+
+// DefaultPolicies apply unless the config file's retention block overrides them. Facts
+// are what workflows remember, so they are only pruned when configured.
+var DefaultPolicies = map[string]Policy{
+	KindRuns:      {MaxAge: 90 * 24 * time.Hour, MaxSize: 1 << 30},
+	KindArtifacts: {MaxAge: 30 * 24 * time.Hour},
+	KindSessions:  {MaxAge: 30 * 24 * time.Hour},
+	KindLogs:      {MaxAge: 14 * 24 * time.Hour, MaxSize: 100 << 20},
+	KindCache:     {MaxAge: 7 * 24 * time.Hour, MaxSize: 500 << 20},
+}
+
+const (
+	// autoPruneEvery is how often AutoPrune does any work.
+	autoPruneEvery = 24 * time.Hour
+	autoPruneStamp = ".last-prune"
+	// activeRunGrace protects a run still marked running that was written this recently.
+	activeRunGrace = 24 * time.Hour
+	runRecordName  = "run.json"
+)
+
+// Policies merges the configured limits over DefaultPolicies. A limit set to "0" or
+// "none" removes that bound.
+func Policies(limits map[string]config.RetentionLimit) (map[string]Policy, error) {
+	policies := make(map[string]Policy, len(PruneKinds))
+	for kind, p := range DefaultPolicies {
+		policies[kind] = p
+	}
+	for kind, limit := range limits {
+		if !knownKind(kind) {
+			return nil, fmt.Errorf("retention.%s: unknown kind (known: %s)", kind, strings.Join(PruneKinds, ", "))
+		}
+		p := policies[kind]
+		switch {
+		case limit.MaxAge == "":
+		case unlimited(limit.MaxAge):
+			p.MaxAge = 0
+		default:
+			age, err := timeparse.Duration(limit.MaxAge)
+			if err != nil {
+				return nil, fmt.Errorf("retention.%s.max_age: %w", kind, err)
+			}
+			p.MaxAge = age
+		}
+		switch {
+		case limit.MaxSize == "":
+		case unlimited(limit.MaxSize):
+			p.MaxSize = 0
+		default:
+			size, err := ParseSize(limit.MaxSize)
+			if err != nil {
+				return nil, fmt.Errorf("retention.%s.max_size: %w", kind, err)
+			}
+			p.MaxSize = size
+		}
+		policies[kind] = p
+	}
+	return policies, nil
+}
+
+func unlimited(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "0", "none", "unlimited":
+		return true
+	}
+	return false
+}
+
+func knownKind(kind string) bool {
+	for _, k := range PruneKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseSize reads a byte size such as 500MB, 1.5GiB, or 2048 (bytes). K, M, and G are
+// binary multiples whether or not the i is written.
+func ParseSize(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+	multiplier := int64(1)
+	if n := len(s); n > 0 {
+		switch s[n-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		case 'T':
+			multiplier = 1 << 40
+		}
+		if multiplier > 1 {
+			s = s[:n-1]
+		}
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q: expected e.g. 500MB or 2GB", value)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// Removal is one file or directory pruned (or, in a dry run, that would be).
+type Removal struct {
+	Kind    string    `json:"kind"`
+	Path    string    `json:"path"`
+	Bytes   int64     `json:"bytes"`
+	ModTime time.Time `json:"modified"`
+	// Reason is "age" or "size".
+	Reason string `json:"reason"`
+}
+
+// KindUsage is how much of one kind is kept after pruning.
+type KindUsage struct {
+	Kind   string `json:"kind"`
+	Policy Policy `json:"policy"`
+	Items  int    `json:"items"`
+	Bytes  int64  `json:"bytes"`
+}
+
+// PruneResult reports a prune.
+type PruneResult struct {
+	DryRun  bool        `json:"dry_run,omitempty"`
+	Removed []Removal   `json:"removed"`
+	Freed   int64       `json:"freed_bytes"`
+	Kept    []KindUsage `json:"kept"`
+}
+
+// pruneItem is one unit of a kind: a run directory, an artifact file, a fact file, or
+// an entry of the sessions, logs, or cache directory.
+type pruneItem struct {
+	path    string
+	bytes   int64
+	modTime time.Time
+	// protected items are never pruned, such as a run still in progress.
+	protected bool
+}
+
+// Prune applies the policies to the config dir. With dryRun nothing is removed. Runs are
+// pruned before artifacts, so artifacts of removed runs are not counted twice.
+func Prune(ctx context.Context, policies map[string]Policy, dryRun bool, now time.Time) (PruneResult, error) {
+	base, err := config.ConfigDir(ctx)
+	if err != nil {
+		return PruneResult{}, err
+	}
+	result := PruneResult{DryRun: dryRun, Removed: []Removal{}}
+	removed := map[string]bool{}
+	for _, kind := range PruneKinds {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		items, err := collectItems(base, kind, now, removed)
+		if err != nil {
+			return result, fmt.Errorf("%s: %w", kind, err)
+		}
+		policy := policies[kind]
+		keep, drop := selectPrunable(items, policy, now)
+		usage := KindUsage{Kind: kind, Policy: policy, Items: len(keep)}
+		for _, item := range keep {
+			usage.Bytes += item.bytes
+		}
+		result.Kept = append(result.Kept, usage)
+		for _, d := range drop {
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+			if !dryRun {
+				if err := os.RemoveAll(d.item.path); err != nil {
+					return result, err
+				}
+			}
+			removed[d.item.path] = true
+			result.Freed += d.item.bytes
+			rel, _ := filepath.Rel(base, d.item.path)
+			result.Removed = append(result.Removed, Removal{Kind: kind, Path: rel, Bytes: d.item.bytes, ModTime: d.item.modTime, Reason: d.reason})
+		}
+	}
+	return result, nil
+}
+
+type prunable struct {
+	item   pruneItem
+	reason string
+}
+
+// selectPrunable splits items into kept and dropped: first everything past MaxAge, then
+// the oldest of the rest until the total fits MaxSize.
+func selectPrunable(items []pruneItem, policy Policy, now time.Time) ([]pruneItem, []prunable) {
+	sort.Slice(items, func(i, j int) bool { return items[i].modTime.Before(items[j].modTime) })
+	var keep []pruneItem
+	var drop []prunable
+	var total int64
+	for _, item := range items {
+		if !item.protected && policy.MaxAge > 0 && now.Sub(item.modTime) > policy.MaxAge {
+			drop = append(drop, prunable{item: item, reason: "age"})
+			continue
+		}
+		keep = append(keep, item)
+		total += item.bytes
+	}
+	if policy.MaxSize <= 0 || total <= policy.MaxSize {
+		return keep, drop
+	}
+	var rest []pruneItem
+	for _, item := range keep {
+		if total > policy.MaxSize && !item.protected {
+			drop = append(drop, prunable{item: item, reason: "size"})
+			total -= item.bytes
+			continue
+		}
+		rest = append(rest, item)
+	}
+	return rest, drop
+}
+
+func collectItems(base, kind string, now time.Time, removed map[string]bool) ([]pruneItem, error) {
+	switch kind {
+	case KindRuns:
+		return runItems(filepath.Join(base, "runs"), now)
+	case KindArtifacts:
+		return artifactItems(filepath.Join(base, "runs"), removed)
+	case KindFacts:
+		return entryItems(filepath.Join(base, "facts"))
+	default:
+		return entryItems(filepath.Join(base, kind))
+	}
+}
+
+// runItems are run directories, dated by their record's last write. A run still marked
+// running and written within activeRunGrace is protected.
+func runItems(dir string, now time.Time) ([]pruneItem, error) {
+	entries, err := readDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var items []pruneItem
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		item, err := measure(path)
+		if err != nil {
+			return nil, err
+		}
+		if info, err := os.Stat(filepath.Join(path, runRecordName)); err == nil {
+			item.modTime = info.ModTime()
+			item.protected = now.Sub(item.modTime) < activeRunGrace && runStatus(path) == "running"
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func runStatus(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, runRecordName))
+	if err != nil {
+		return ""
+	}
+	var rec struct {
+		Status string `json:"status"`
+	}
+	_ = json.Unmarshal(data, &rec)
+	return rec.Status
+}
+
+// artifactItems are the files runs keep next to their record.
+func artifactItems(dir string, removed map[string]bool) ([]pruneItem, error) {
+	entries, err := readDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var items []pruneItem
+	for _, entry := range entries {
+		runDir := filepath.Join(dir, entry.Name())
+		if !entry.IsDir() || removed[runDir] {
+			continue
+		}
+		err := filepath.WalkDir(runDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || path == filepath.Join(runDir, runRecordName) {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			items = append(items, pruneItem{path: path, bytes: info.Size(), modTime: info.ModTime()})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// entryItems are the top-level entries of dir, each dated by its newest file.
+func entryItems(dir string) ([]pruneItem, error) {
+	entries, err := readDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]pruneItem, 0, len(entries))
+	for _, entry := range entries {
+		item, err := measure(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// measure sums the size of path and finds its newest modification.
+func measure(path string) (pruneItem, error) {
+	item := pruneItem{path: path}
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			item.bytes += info.Size()
+		}
+		if info.ModTime().After(item.modTime) {
+			item.modTime = info.ModTime()
+		}
+		return nil
+	})
+	return item, err
+}
+
+func readDir(dir string) ([]os.DirEntry, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return entries, err
+}
+
+// AutoPrune prunes with the policies unless it already ran within the last day, as
+// recorded by a stamp file in the config dir. It is meant to run alongside a command.
+func AutoPrune(ctx context.Context, policies map[string]Policy, now time.Time) (PruneResult, bool, error) {
+	base, err := config.ConfigDir(ctx)
+	if err != nil {
+		return PruneResult{}, false, err
+	}
+	stamp := filepath.Join(base, autoPruneStamp)
+	if info, err := os.Stat(stamp); err == nil && now.Sub(info.ModTime()) < autoPruneEvery {
+		return PruneResult{}, false, nil
+	} else if errors.Is(err, os.ErrNotExist) {
+		// A config dir that does not exist yet holds nothing to prune.
+		if _, err := os.Stat(base); errors.Is(err, os.ErrNotExist) {
+			return PruneResult{}, false, nil
+		}
+	}
+	// Stamp first, so a prune cut short by the command exiting waits for the next day.
+	if err := config.WriteFile(stamp, []byte(now.UTC().Format(time.RFC3339)+"\n")); err != nil {
+		return PruneResult{}, false, err
+	}
+	result, err := Prune(ctx, policies, false, now)
+	return result, true, err
+}
+
+// FormatSize renders bytes with a binary unit, e.g. 1.5 GiB.
+func FormatSize(n int64) string {
+	const unit = 1 << 10
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit && exp < 3; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGT"[exp])
+}
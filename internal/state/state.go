@@ -0,0 +1,417 @@
+package state
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/sre-ai/internal/config"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	manifestName      = "manifest.json"
+	encryptedCredName = "credentials.enc"
+	archiveVersion    = 1
+	saltSize          = 16
+)
+
+// ErrPassphraseRequired is wrapped by Import when the archive holds encrypted
+// credentials and ImportOptions has no passphrase.
+var ErrPassphraseRequired = errors.New("a passphrase is required")
+
+// Component names a piece of local state that can be exported.
+type Component string
+
+const (
+	ComponentConfig      Component = "config"
+	ComponentMCP         Component = "mcp"
+	ComponentCredentials Component = "credentials"
+	ComponentSessions    Component = "sessions"
+	ComponentRuns        Component = "runs"
+	ComponentKnowledge   Component = "knowledge"
+	ComponentFacts       Component = "facts"
+)
+
+// directoryComponents map to sub-directories of the config dir.
+var directoryComponents = []Component{
+	ComponentMCP,
+	ComponentCredentials,
+	ComponentSessions,
+	ComponentRuns,
+	ComponentKnowledge,
+	ComponentFacts,
+}
+
+// Manifest is stored at the root of every archive.
+type Manifest struct {
+	Version              int         `json:"version"`
+	Created              string      `json:"created"`
+	Components           []Component `json:"components"`
+	Files                int         `json:"files"`
+	EncryptedCredentials bool        `json:"encrypted_credentials,omitempty"`
+}
+
+// ExportOptions controls what goes into an archive.
+type ExportOptions struct {
+	// ConfigPath is the config file to export (defaults to the standard location).
+	ConfigPath string
+	// IncludeCredentials adds the credentials directory.
+	IncludeCredentials bool
+	// Passphrase encrypts the credentials when non-empty.
+	Passphrase string
+}
+
+// ImportOptions controls how an archive is restored.
+type ImportOptions struct {
+	ConfigPath string
+	Passphrase string
+	// Force overwrites files that already exist.
+	Force bool
+	// DryRun reports what would be written without touching disk.
+	DryRun bool
+}
+
+// ImportResult lists what an import wrote (or would write).
+type ImportResult struct {
+	Manifest Manifest `json:"manifest"`
+	Written  []string `json:"written"`
+	Skipped  []string `json:"skipped,omitempty"`
+}
+
+// Export writes an archive of local state to archivePath.
+func Export(ctx context.Context, archivePath string, opts ExportOptions) (*Manifest, error) {
+	base, err := config.ConfigDir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cfgPath, err := resolveConfigPath(ctx, opts.ConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte)
+	manifest := &Manifest{Version: archiveVersion, Created: time.Now().UTC().Format(time.RFC3339)}
+
+	if data, err := os.ReadFile(cfgPath); err == nil {
+		files["config/config.yaml"] = data
+		manifest.Components = append(manifest.Components, ComponentConfig)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	for _, comp := range directoryComponents {
+		if comp == ComponentCredentials && !opts.IncludeCredentials {
+			continue
+		}
+		collected, err := collectDir(filepath.Join(base, string(comp)), string(comp))
+		if err != nil {
+			return nil, err
+		}
+		if len(collected) == 0 {
+			continue
+		}
+		manifest.Components = append(manifest.Components, comp)
+		if comp == ComponentCredentials && opts.Passphrase != "" {
+			blob, err := encryptFiles(collected, opts.Passphrase)
+			if err != nil {
+				return nil, err
+			}
+			files[encryptedCredName] = blob
+			manifest.EncryptedCredentials = true
+			manifest.Files += len(collected)
+			continue
+		}
+		for name, data := range collected {
+			files[name] = data
+		}
+		manifest.Files += len(collected)
+	}
+	if _, ok := files["config/config.yaml"]; ok {
+		manifest.Files++
+	}
+
+	if len(manifest.Components) == 0 {
+		return nil, errors.New("no local state found to export")
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	files[manifestName] = manifestData
+
+	if err := os.MkdirAll(filepath.Dir(archivePath), 0o700); err != nil {
+		return nil, err
+	}
+	out, err := os.OpenFile(archivePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	defer out.Close()
+	if err := writeArchive(out, files); err != nil {
+		return nil, err
+	}
+	return manifest, out.Close()
+}
+
+// Import restores an archive produced by Export.
+func Import(ctx context.Context, archivePath string, opts ImportOptions) (*ImportResult, error) {
+	base, err := config.ConfigDir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cfgPath, err := resolveConfigPath(ctx, opts.ConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	in, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	files, err := readArchive(in)
+	if err != nil {
+		return nil, err
+	}
+
+	rawManifest, ok := files[manifestName]
+	if !ok {
+		return nil, fmt.Errorf("%s is not an sre-ai state archive (missing %s)", archivePath, manifestName)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(rawManifest, &manifest); err != nil {
+		return nil, fmt.Errorf("parse archive manifest: %w", err)
+	}
+	if manifest.Version > archiveVersion {
+		return nil, fmt.Errorf("archive version %d is newer than supported version %d", manifest.Version, archiveVersion)
+	}
+	delete(files, manifestName)
+
+	if blob, ok := files[encryptedCredName]; ok {
+		if opts.Passphrase == "" {
+			return nil, fmt.Errorf("archive contains encrypted credentials; %w", ErrPassphraseRequired)
+		}
+		decrypted, err := decryptFiles(blob, opts.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		delete(files, encryptedCredName)
+		for name, data := range decrypted {
+			files[name] = data
+		}
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := &ImportResult{Manifest: manifest}
+	for _, name := range names {
+		target, err := targetPath(base, cfgPath, name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(target); err == nil && !opts.Force {
+			result.Skipped = append(result.Skipped, target)
+			continue
+		}
+		result.Written = append(result.Written, target)
+		if opts.DryRun {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o700); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(target, files[name], 0o600); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func resolveConfigPath(ctx context.Context, explicit string) (string, error) {
+	if explicit != "" {
+		return explicit, nil
+	}
+	return config.DefaultConfigPath(ctx)
+}
+
+func targetPath(base, cfgPath, name string) (string, error) {
+	clean := path.Clean(name)
+	if clean == "." || path.IsAbs(clean) || strings.HasPrefix(clean, "../") || clean == ".." {
+		return "", fmt.Errorf("archive entry %q escapes the state directory", name)
+	}
+	if clean == "config/config.yaml" {
+		return cfgPath, nil
+	}
+	top := strings.SplitN(clean, "/", 2)[0]
+	known := false
+	for _, comp := range directoryComponents {
+		if string(comp) == top {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return "", fmt.Errorf("archive entry %q is not a known state component", name)
+	}
+	return filepath.Join(base, filepath.FromSlash(clean)), nil
+}
+
+func collectDir(root, prefix string) (map[string][]byte, error) {
+	out := make(map[string][]byte)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		out[path.Join(prefix, filepath.ToSlash(rel))] = data
+		return nil
+	})
+	return out, err
+}
+
+func writeArchive(w io.Writer, files map[string][]byte) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	now := time.Now()
+	for _, name := range names {
+		data := files[name]
+		hdr := &tar.Header{Name: name, Mode: 0o600, Size: int64(len(data)), ModTime: now, Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func readArchive(r io.Reader) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+	defer gz.Close()
+
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[hdr.Name] = data
+	}
+	return files, nil
+}
+
+func encryptFiles(files map[string][]byte, passphrase string) ([]byte, error) {
+	var plain bytes.Buffer
+	if err := writeArchive(&plain, files); err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	gcm, err := newCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := append([]byte{}, salt...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, plain.Bytes(), nil), nil
+}
+
+func decryptFiles(blob []byte, passphrase string) (map[string][]byte, error) {
+	if len(blob) < saltSize {
+		return nil, errors.New("encrypted credentials are truncated")
+	}
+	salt := blob[:saltSize]
+	gcm, err := newCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	rest := blob[saltSize:]
+	if len(rest) < gcm.NonceSize() {
+		return nil, errors.New("encrypted credentials are truncated")
+	}
+	plain, err := gcm.Open(nil, rest[:gcm.NonceSize()], rest[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("unable to decrypt credentials; wrong passphrase?")
+	}
+	return readArchive(bytes.NewReader(plain))
+}
+
+func newCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,35 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/example/sre-ai/internal/config"
+)
+
+func TestImportOfEncryptedCredentialsNeedsThePassphrase(t *testing.T) {
+	base := t.TempDir()
+	ctx := config.WithDir(context.Background(), base)
+	creds := filepath.Join(base, string(ComponentCredentials), "gemini.json")
+	if err := os.MkdirAll(filepath.Dir(creds), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(creds, []byte(`{"api_key":"secret"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(t.TempDir(), "state.tar.gz")
+	if _, err := Export(ctx, archive, ExportOptions{IncludeCredentials: true, Passphrase: "hunter2"}); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	_, err := Import(ctx, archive, ImportOptions{DryRun: true})
+	if !errors.Is(err, ErrPassphraseRequired) {
+		t.Fatalf("Import without a passphrase: %v; want ErrPassphraseRequired", err)
+	}
+	if _, err := Import(ctx, archive, ImportOptions{Passphrase: "hunter2", DryRun: true}); err != nil {
+		t.Fatalf("Import with the passphrase: %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+// Package templatex runs text/template templates within limits on their output size,
+// range iterations, and rendering time, so that ranging over a huge input fails the
+// render instead of hanging the command or building a giant string.
+package templatex
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/state"
+)
+
+// Limits bounds the work of rendering one template. A zero field is unlimited.
+type Limits struct {
+	MaxOutput int64
+	// MaxRange counts iterations over every range in the template, nested ones included.
+	MaxRange int
+	Timeout  time.Duration
+}
+
+// Defaults apply unless the config's templates block says otherwise.
+var Defaults = Limits{MaxOutput: 4 << 20, MaxRange: 100000, Timeout: 10 * time.Second}
+
+// LimitsFor returns the limits set in opts.Templates over the defaults.
+func LimitsFor(opts *config.GlobalOptions) (Limits, error) {
+	limits := Defaults
+	if opts == nil {
+		return limits, nil
+	}
+	t := opts.Templates
+	if strings.TrimSpace(t.MaxOutput) != "" {
+		n, err := state.ParseSize(t.MaxOutput)
+		if err != nil || n <= 0 {
+			return limits, fmt.Errorf("templates.max_output: %q is not a positive size such as 4MiB", t.MaxOutput)
+		}
+		limits.MaxOutput = n
+	}
+	if t.MaxRange < 0 {
+		return limits, fmt.Errorf("templates.max_range must not be negative")
+	}
+	if t.MaxRange > 0 {
+		limits.MaxRange = t.MaxRange
+	}
+	if t.Timeout < 0 {
+		return limits, fmt.Errorf("templates.timeout must not be negative")
+	}
+	if t.Timeout > 0 {
+		limits.Timeout = t.Timeout
+	}
+	return limits, nil
+}
+
+// The checks Execute adds to a parsed template. Their names are not valid in
+// template source, since they are only known once it has been parsed.
+const (
+	rangeCheckFunc = "_rangeCheck"
+	enterCheckFunc = "_enterCheck"
+)
+
+// Execute renders tmpl, already parsed, against data within limits. The range limit
+// and timeout are checked on every range iteration, every template entered (which
+// catches recursive {{ template }} calls), and every write. Execute adds its checks to
+// the parse trees of tmpl, so a template is executed once.
+func Execute(tmpl *template.Template, limits Limits, data interface{}) (string, error) {
+	b := &budget{limits: limits, name: tmpl.Name()}
+	if limits.Timeout > 0 {
+		b.deadline = time.Now().Add(limits.Timeout)
+	}
+	tmpl.Funcs(template.FuncMap{rangeCheckFunc: b.iterate, enterCheckFunc: b.enter})
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil && t.Tree.Root != nil {
+			guardTree(t.Tree)
+		}
+	}
+
+	out := &limitedBuilder{budget: b}
+	if err := tmpl.Execute(out, data); err != nil {
+		if b.err != nil {
+			return "", b.err
+		}
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// budget tracks one execution against its limits; err is the first limit hit.
+type budget struct {
+	limits     Limits
+	name       string
+	deadline   time.Time
+	iterations int
+	err        error
+}
+
+func (b *budget) fail(err error) error {
+	if b.err == nil {
+		b.err = err
+	}
+	return b.err
+}
+
+func (b *budget) checkDeadline(at string) error {
+	if b.err != nil {
+		return b.err
+	}
+	if !b.deadline.IsZero() && time.Now().After(b.deadline) {
+		return b.fail(fmt.Errorf("%s: rendering takes longer than %s (templates.timeout)", at, b.limits.Timeout))
+	}
+	return nil
+}
+
+func (b *budget) iterate(at string) (string, error) {
+	b.iterations++
+	if b.limits.MaxRange > 0 && b.iterations > b.limits.MaxRange {
+		return "", b.fail(fmt.Errorf("%s: range runs more than %d iterations (templates.max_range)", at, b.limits.MaxRange))
+	}
+	return "", b.checkDeadline(at)
+}
+
+func (b *budget) enter(at string) (string, error) {
+	return "", b.checkDeadline(at)
+}
+
+// limitedBuilder collects template output up to the budget's MaxOutput.
+type limitedBuilder struct {
+	strings.Builder
+	budget *budget
+}
+
+func (w *limitedBuilder) Write(p []byte) (int, error) {
+	if err := w.budget.checkDeadline(w.budget.name); err != nil {
+		return 0, err
+	}
+	if max := w.budget.limits.MaxOutput; max > 0 && int64(w.Len()+len(p)) > max {
+		return 0, w.budget.fail(fmt.Errorf("%s: output exceeds %s (templates.max_output)", w.budget.name, state.FormatSize(max)))
+	}
+	return w.Builder.Write(p)
+}
+
+// guardTree adds an enter check at the top of tree and a range check at the top of every
+// range body in it.
+func guardTree(tree *parse.Tree) {
+	tree.Root.Nodes = append([]parse.Node{checkAction(tree, tree.Root, enterCheckFunc)}, tree.Root.Nodes...)
+	guardList(tree, tree.Root)
+}
+
+func guardList(tree *parse.Tree, list *parse.ListNode) {
+	if list == nil {
+		return
+	}
+	for _, node := range list.Nodes {
+		switch n := node.(type) {
+		case *parse.RangeNode:
+			n.List.Nodes = append([]parse.Node{checkAction(tree, n, rangeCheckFunc)}, n.List.Nodes...)
+			guardList(tree, n.List)
+			guardList(tree, n.ElseList)
+		case *parse.IfNode:
+			guardList(tree, n.List)
+			guardList(tree, n.ElseList)
+		case *parse.WithNode:
+			guardList(tree, n.List)
+			guardList(tree, n.ElseList)
+		}
+	}
+}
+
+// checkAction is {{ fn "location" }}, with the location of at (e.g. "workflow:3:12") so
+// a limit error points at the range that hit it.
+func checkAction(tree *parse.Tree, at parse.Node, fn string) *parse.ActionNode {
+	location, _ := tree.ErrorContext(at)
+	pos := at.Position()
+	cmd := &parse.CommandNode{NodeType: parse.NodeCommand, Pos: pos, Args: []parse.Node{
+		parse.NewIdentifier(fn).SetTree(tree).SetPos(pos),
+		&parse.StringNode{NodeType: parse.NodeString, Pos: pos, Quoted: strconv.Quote(location), Text: location},
+	}}
+	return &parse.ActionNode{NodeType: parse.NodeAction, Pos: pos, Pipe: &parse.PipeNode{NodeType: parse.NodePipe, Pos: pos, Cmds: []*parse.CommandNode{cmd}}}
+}
@@ -0,0 +1,36 @@
+package templatex
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func execute(t *testing.T, body string, limits Limits, data interface{}) (string, error) {
+	t.Helper()
+	tmpl, err := template.New("test").Parse(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Execute(tmpl, limits, data)
+}
+
+func TestExecuteEnforcesLimits(t *testing.T) {
+	items := make([]int, 50)
+	data := map[string]interface{}{"items": items}
+
+	if out, err := execute(t, `{{ range .items }}.{{ end }}`, Limits{MaxRange: 50}, data); err != nil || out != strings.Repeat(".", 50) {
+		t.Fatalf("at the range limit: %q, %v", out, err)
+	}
+	_, err := execute(t, `{{ range .items }}{{ range $.items }}.{{ end }}{{ end }}`, Limits{MaxRange: 1000}, data)
+	if err == nil || !strings.Contains(err.Error(), "test:1:") || !strings.Contains(err.Error(), "max_range") {
+		t.Fatalf("nested ranges past the limit: err = %v, want a located range limit error", err)
+	}
+	if _, err := execute(t, `{{ range .items }}0123456789{{ end }}`, Limits{MaxOutput: 100}, data); err == nil || !strings.Contains(err.Error(), "max_output") {
+		t.Fatalf("500 bytes with max_output 100: err = %v", err)
+	}
+	if _, err := execute(t, `{{ range .items }}.{{ end }}`, Limits{Timeout: time.Nanosecond}, data); err == nil || !strings.Contains(err.Error(), "templates.timeout") {
+		t.Fatalf("past the deadline: err = %v, want the timeout error", err)
+	}
+}
@@ -0,0 +1,148 @@
+package timefmt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultLayout is used when config does not set time.layout.
+const DefaultLayout = "2006-01-02 15:04:05 MST"
+
+// namedLayouts are shorthands accepted for time.layout.
+var namedLayouts = map[string]string{
+	"default":  DefaultLayout,
+	"rfc3339":  time.RFC3339,
+	"datetime": time.DateTime,
+	"rfc1123":  time.RFC1123,
+}
+
+// Format is how one command tree renders timestamps.
+type Format struct {
+	location *time.Location
+	layout   string
+	relative bool
+}
+
+// Default is the local zone and DefaultLayout, with relative suffixes.
+var Default = Format{location: time.Local, layout: DefaultLayout, relative: true}
+
+// New builds a Format from the zone ("Local", "UTC", or an IANA name such as
+// "Europe/Berlin"), the layout (a Go layout or one of default, rfc3339, datetime,
+// rfc1123), and whether timestamps carry a relative suffix. Empty values keep the
+// defaults.
+func New(zone, layoutName string, withRelative bool) (Format, error) {
+	loc := time.Local
+	switch z := strings.TrimSpace(zone); {
+	case z == "" || strings.EqualFold(z, "local"):
+	case strings.EqualFold(z, "utc"):
+		loc = time.UTC
+	default:
+		loaded, err := time.LoadLocation(z)
+		if err != nil {
+			return Format{}, fmt.Errorf("time.zone %q: %w", zone, err)
+		}
+		loc = loaded
+	}
+	chosen := DefaultLayout
+	if l := strings.TrimSpace(layoutName); l != "" {
+		if named, ok := namedLayouts[strings.ToLower(l)]; ok {
+			chosen = named
+		} else {
+			chosen = l
+		}
+	}
+	return Format{location: loc, layout: chosen, relative: withRelative}, nil
+}
+
+type contextKey struct{}
+
+// WithFormat returns a context whose timestamps render in f.
+func WithFormat(ctx context.Context, f Format) context.Context {
+	return context.WithValue(ctx, contextKey{}, f)
+}
+
+// FromContext returns the Format set with WithFormat, else Default.
+func FromContext(ctx context.Context) Format {
+	if f, ok := ctx.Value(contextKey{}).(Format); ok {
+		return f
+	}
+	return Default
+}
+
+// Absolute renders t in the zone and layout carried by ctx, or "-" for the zero time.
+func Absolute(ctx context.Context, t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	f := FromContext(ctx)
+	return t.In(f.location).Format(f.layout)
+}
+
+// Timestamp renders t like Absolute, followed by "(3m ago)" unless relative times are off.
+func Timestamp(ctx context.Context, t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	if !FromContext(ctx).relative {
+		return Absolute(ctx, t)
+	}
+	return fmt.Sprintf("%s (%s)", Absolute(ctx, t), Ago(t))
+}
+
+// Ago renders the distance from now to t: "just now", "42s ago", "1h20m ago", "in 5m".
+func Ago(t time.Time) string {
+	d := time.Since(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+	if d < 5*time.Second {
+		return "just now"
+	}
+	text := coarse(d)
+	if future {
+		return "in " + text
+	}
+	return text + " ago"
+}
+
+// coarse keeps the two most significant units of d.
+func coarse(d time.Duration) string {
+	const day = 24 * time.Hour
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d/time.Second))
+	case d < time.Hour:
+		return withUnit(int(d/time.Minute), "m", int(d%time.Minute/time.Second), "s")
+	case d < day:
+		return withUnit(int(d/time.Hour), "h", int(d%time.Hour/time.Minute), "m")
+	default:
+		return withUnit(int(d/day), "d", int(d%day/time.Hour), "h")
+	}
+}
+
+func withUnit(major int, majorUnit string, minor int, minorUnit string) string {
+	if minor == 0 {
+		return fmt.Sprintf("%d%s", major, majorUnit)
+	}
+	return fmt.Sprintf("%d%s%d%s", major, majorUnit, minor, minorUnit)
+}
+
+// Duration renders an elapsed time with precision that fits its size: "850µs", "12.3ms",
+// "1.25s", "2m5s", "1h3m".
+func Duration(d time.Duration) string {
+	switch {
+	case d < time.Millisecond:
+		return d.Round(time.Microsecond).String()
+	case d < time.Second:
+		return d.Round(100 * time.Microsecond).String()
+	case d < time.Minute:
+		return d.Round(10 * time.Millisecond).String()
+	case d < time.Hour:
+		return d.Round(time.Second).String()
+	default:
+		return strings.TrimSuffix(d.Round(time.Minute).String(), "0s")
+	}
+}
@@ -0,0 +1,161 @@
+package timeparse
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// hint is appended to every parse error so the accepted forms are one read away.
+const hint = "expected a duration (90m, 2h30m, 1d, 1w), a timestamp (RFC 3339, 2006-01-02, 2006-01-02 15:04), or today/yesterday [HH:MM]"
+
+// durationPart matches one number+unit pair; Go's units plus d (24h) and w (7d).
+var durationPart = regexp.MustCompile(`(\d+(?:\.\d+)?)(ns|us|µs|ms|s|m|h|d|w)`)
+
+// localLayouts are timestamp forms without a zone, read in the local zone.
+var localLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// Cutoff resolves a --since style value to the instant it names, relative to now.
+// An empty value returns the zero time, meaning no bound.
+func Cutoff(value string, now time.Time) (time.Time, error) {
+	raw := value
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	lower := strings.ToLower(value)
+
+	if d, ok, err := parseDuration(strings.TrimSpace(strings.TrimSuffix(lower, " ago"))); ok {
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q: %v", raw, err)
+		}
+		return now.Add(-d), nil
+	}
+	if ts, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return notFuture(raw, ts, now)
+	}
+	for _, layout := range localLayouts {
+		if ts, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			return notFuture(raw, ts, now)
+		}
+	}
+	if ts, ok := parseRelativeDay(lower, now); ok {
+		return notFuture(raw, ts, now)
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: %s", raw, hint)
+}
+
+// Window is Cutoff expressed as a look-back duration from now; empty input returns 0.
+func Window(value string, now time.Time) (time.Duration, error) {
+	cutoff, err := Cutoff(value, now)
+	if err != nil || cutoff.IsZero() {
+		return 0, err
+	}
+	return now.Sub(cutoff), nil
+}
+
+// Duration reads a duration such as 90m, 1d12h, or 2w; Go's units plus d and w.
+func Duration(value string) (time.Duration, error) {
+	d, ok, err := parseDuration(strings.ToLower(strings.TrimSpace(value)))
+	if !ok {
+		return 0, fmt.Errorf("invalid duration %q: expected e.g. 90m, 36h, 7d, or 2w", value)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q: %v", value, err)
+	}
+	return d, nil
+}
+
+// parseDuration reads compound durations such as "1d12h" or "2h30m". ok is false when
+// the value does not look like a duration at all, so other forms can be tried.
+func parseDuration(value string) (time.Duration, bool, error) {
+	if value == "" || strings.Trim(durationPart.ReplaceAllString(value, ""), " ") != "" {
+		return 0, false, nil
+	}
+	var total time.Duration
+	for _, match := range durationPart.FindAllStringSubmatch(value, -1) {
+		n, err := strconv.ParseFloat(match[1], 64)
+		if err != nil {
+			return 0, true, err
+		}
+		unit := match[2]
+		switch unit {
+		case "d":
+			total += time.Duration(n * float64(24*time.Hour))
+		case "w":
+			total += time.Duration(n * float64(7*24*time.Hour))
+		default:
+			d, err := time.ParseDuration(match[1] + unit)
+			if err != nil {
+				return 0, true, err
+			}
+			total += d
+		}
+	}
+	return total, true, nil
+}
+
+// parseRelativeDay handles "now", "today", "yesterday", each optionally followed by a
+// clock time, and a bare "HH:MM" meaning its most recent occurrence.
+func parseRelativeDay(value string, now time.Time) (time.Time, bool) {
+	if value == "now" {
+		return now, true
+	}
+	fields := strings.Fields(value)
+	if len(fields) == 0 || len(fields) > 2 {
+		return time.Time{}, false
+	}
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	day, clock := midnight, ""
+	switch fields[0] {
+	case "today":
+	case "yesterday":
+		day = midnight.AddDate(0, 0, -1)
+	default:
+		if len(fields) != 1 {
+			return time.Time{}, false
+		}
+		clock = fields[0]
+		offset, ok := parseClock(clock)
+		if !ok {
+			return time.Time{}, false
+		}
+		ts := midnight.Add(offset)
+		if ts.After(now) {
+			ts = ts.AddDate(0, 0, -1)
+		}
+		return ts, true
+	}
+	if len(fields) == 2 {
+		offset, ok := parseClock(fields[1])
+		if !ok {
+			return time.Time{}, false
+		}
+		return day.Add(offset), true
+	}
+	return day, true
+}
+
+func parseClock(value string) (time.Duration, bool) {
+	for _, layout := range []string{"15:04", "15:04:05", "3pm", "3:04pm"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second, true
+		}
+	}
+	return 0, false
+}
+
+func notFuture(raw string, ts, now time.Time) (time.Time, error) {
+	if ts.After(now) {
+		return time.Time{}, fmt.Errorf("invalid time %q: %s is in the future", raw, ts.Format(time.RFC3339))
+	}
+	return ts, nil
+}
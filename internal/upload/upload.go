@@ -0,0 +1,167 @@
+// Package upload publishes artifacts such as run bundles, exports, and reports to object
+// storage: Amazon S3 (or an S3-compatible store), Google Cloud Storage, or Azure Blob
+// Storage. Requests go through httpx, so endpoints' certificates and tunnels apply.
+package upload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/example/sre-ai/internal/config"
+	"github.com/example/sre-ai/internal/httpx"
+)
+
+const uploadTimeout = 5 * time.Minute
+
+// Content types of the artifacts sre-ai publishes.
+const (
+	TypeGzip     = "application/gzip"
+	TypeJSON     = "application/json"
+	TypeJSONL    = "application/x-ndjson"
+	TypeMarkdown = "text/markdown; charset=utf-8"
+	TypeBinary   = "application/octet-stream"
+)
+
+// Object is one uploaded (or, in a dry run, planned) object.
+type Object struct {
+	Destination string `json:"destination"`
+	URL         string `json:"url"`
+	Size        int    `json:"size"`
+	DryRun      bool   `json:"dry_run,omitempty"`
+}
+
+// target is a parsed destination URL.
+type target struct {
+	scheme string
+	// bucket is the S3 or GCS bucket, or the Azure storage account.
+	bucket string
+	// container is the Azure container.
+	container string
+	prefix    string
+}
+
+func parseURL(raw string) (target, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return target{}, fmt.Errorf("url %q must be s3://bucket/prefix, gs://bucket/prefix, or azblob://account/container/prefix", raw)
+	}
+	t := target{scheme: u.Scheme, bucket: u.Host, prefix: strings.Trim(u.Path, "/")}
+	switch t.scheme {
+	case "s3", "gs":
+	case "azblob":
+		t.container, t.prefix, _ = strings.Cut(t.prefix, "/")
+		if t.container == "" {
+			return target{}, fmt.Errorf("url %q needs a container: azblob://account/container/prefix", raw)
+		}
+	default:
+		return target{}, fmt.Errorf("url %q: unsupported scheme %q (use s3, gs, or azblob)", raw, u.Scheme)
+	}
+	return t, nil
+}
+
+func (t target) key(name string) string {
+	if t.prefix == "" {
+		return name
+	}
+	return t.prefix + "/" + name
+}
+
+// Check validates a destination's settings without contacting it.
+func Check(dest config.UploadOptions) error {
+	if _, err := parseURL(dest.URL); err != nil {
+		return err
+	}
+	switch dest.Encryption {
+	case "", "default":
+	case "kms":
+		if strings.TrimSpace(dest.KMSKey) == "" {
+			return errors.New("encryption kms needs kms_key")
+		}
+	default:
+		return fmt.Errorf("encryption %q must be default or kms", dest.Encryption)
+	}
+	return nil
+}
+
+// Publish uploads body as name (under the destination's prefix) to the destination
+// configured as upload.<destination>. Under --dry-run nothing is sent and the object is
+// returned with DryRun set.
+func Publish(ctx context.Context, opts *config.GlobalOptions, destination, name string, body []byte, contentType string) (*Object, error) {
+	dest, ok := opts.Uploads[destination]
+	if !ok {
+		return nil, fmt.Errorf("upload destination %s is not configured; add it under upload in the config file", destination)
+	}
+	if err := Check(dest); err != nil {
+		return nil, fmt.Errorf("upload.%s: %w", destination, err)
+	}
+	t, _ := parseURL(dest.URL)
+	key := t.key(name)
+	obj := &Object{Destination: destination, URL: objectURL(t, key), Size: len(body), DryRun: opts.DryRun}
+	if opts.DryRun {
+		return obj, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, uploadTimeout)
+	defer cancel()
+	var err error
+	switch t.scheme {
+	case "s3":
+		err = putS3(ctx, dest, t, key, body, contentType)
+	case "gs":
+		err = putGCS(ctx, dest, t, key, body, contentType)
+	case "azblob":
+		err = putAzure(ctx, dest, t, key, body, contentType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("upload %s to %s: %w", name, destination, err)
+	}
+	return obj, nil
+}
+
+func objectURL(t target, key string) string {
+	switch t.scheme {
+	case "azblob":
+		return fmt.Sprintf("azblob://%s/%s/%s", t.bucket, t.container, key)
+	default:
+		return fmt.Sprintf("%s://%s/%s", t.scheme, t.bucket, key)
+	}
+}
+
+// encodedTags renders tags as a URL query (k1=v1&k2=v2), sorted by key, as S3 and Azure
+// take them.
+func encodedTags(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, url.QueryEscape(k)+"="+url.QueryEscape(tags[k]))
+	}
+	return strings.Join(parts, "&")
+}
+
+// send runs req and turns a non-2xx response into an error carrying the service's message.
+func send(req *http.Request) error {
+	resp, err := httpx.Client(0).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if msg := strings.TrimSpace(string(data)); msg != "" {
+		return fmt.Errorf("%s: %s", resp.Status, msg)
+	}
+	return errors.New(resp.Status)
+}
@@ -0,0 +1,95 @@
+// Package warnings collects non-fatal problems met while running a command, such as a
+// manifest that failed to load or a collector that timed out, so they can be reported
+// alongside the result instead of being logged and lost or aborting the command.
+package warnings
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Warning is one non-fatal problem.
+type Warning struct {
+	// Source names the subsystem that raised it, e.g. "mcp" or "k8s".
+	Source  string `json:"source"`
+	Message string `json:"message"`
+}
+
+func (w Warning) String() string {
+	if w.Source == "" {
+		return w.Message
+	}
+	return w.Source + ": " + w.Message
+}
+
+// Collector accumulates warnings for one command; it is safe for concurrent use.
+// A nil Collector discards everything.
+type Collector struct {
+	mu       sync.Mutex
+	items    []Warning
+	reported int
+}
+
+// Add records a warning.
+func (c *Collector) Add(source, format string, args ...interface{}) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items = append(c.items, Warning{Source: source, Message: fmt.Sprintf(format, args...)})
+}
+
+// All returns every warning recorded so far.
+func (c *Collector) All() []Warning {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Warning(nil), c.items...)
+}
+
+// Pending returns the warnings not yet reported to the user.
+func (c *Collector) Pending() []Warning {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Warning(nil), c.items[c.reported:]...)
+}
+
+// Take returns the pending warnings and marks them reported.
+func (c *Collector) Take() []Warning {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := append([]Warning(nil), c.items[c.reported:]...)
+	c.reported = len(c.items)
+	return out
+}
+
+type contextKey struct{}
+
+// WithCollector returns a context whose warnings go to c.
+func WithCollector(ctx context.Context, c *Collector) context.Context {
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// FromContext returns the context's collector, or nil when there is none.
+func FromContext(ctx context.Context) *Collector {
+	if ctx == nil {
+		return nil
+	}
+	c, _ := ctx.Value(contextKey{}).(*Collector)
+	return c
+}
+
+// Add records a warning on the context's collector.
+func Add(ctx context.Context, source, format string, args ...interface{}) {
+	FromContext(ctx).Add(source, format, args...)
+}
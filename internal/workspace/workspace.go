@@ -0,0 +1,221 @@
+package workspace
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Kind names a class of deployable or build artifact found in a workspace.
+type Kind string
+
+const (
+	KindKustomize Kind = "kustomize"
+	KindHelm      Kind = "helm"
+	KindTerraform Kind = "terraform"
+	KindDocker    Kind = "docker"
+	KindCompose   Kind = "compose"
+	KindCI        Kind = "ci"
+)
+
+const (
+	maxDepth      = 5
+	maxComponents = 40
+)
+
+// skipDirs are never descended into; they are either VCS metadata or vendored caches.
+var skipDirs = map[string]bool{
+	".git":         true,
+	".terraform":   true,
+	".venv":        true,
+	"node_modules": true,
+	"vendor":       true,
+	"dist":         true,
+	"build":        true,
+}
+
+// Component is one detected artifact, with Path relative to the workspace root.
+type Component struct {
+	Kind   Kind   `json:"kind"`
+	Path   string `json:"path"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Context summarises the project the user is standing in.
+type Context struct {
+	Root       string      `json:"root"`
+	Git        bool        `json:"git"`
+	Components []Component `json:"components"`
+	Truncated  bool        `json:"truncated,omitempty"`
+}
+
+// Detect inspects dir (or the enclosing git repository, when there is one) for
+// kustomize overlays, helm charts, terraform modules, Dockerfiles, and CI configs.
+func Detect(dir string) (*Context, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	ctx := &Context{Root: abs}
+	if root, ok := GitRoot(abs); ok {
+		ctx.Root = root
+		ctx.Git = true
+	}
+
+	terraformDirs := map[string]bool{}
+	walkErr := filepath.WalkDir(ctx.Root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable subtrees are skipped rather than failing detection.
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, _ := filepath.Rel(ctx.Root, path)
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if path != ctx.Root && (skipDirs[d.Name()] || strings.Count(rel, "/") >= maxDepth) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if len(ctx.Components) >= maxComponents {
+			ctx.Truncated = true
+			return filepath.SkipAll
+		}
+
+		name := d.Name()
+		dirRel := filepath.ToSlash(filepath.Dir(rel))
+		switch {
+		case name == "kustomization.yaml" || name == "kustomization.yml" || name == "Kustomization":
+			ctx.add(KindKustomize, dirRel, "")
+		case name == "Chart.yaml":
+			ctx.add(KindHelm, dirRel, chartName(path))
+		case strings.HasSuffix(name, ".tf"):
+			if !terraformDirs[dirRel] {
+				terraformDirs[dirRel] = true
+				ctx.add(KindTerraform, dirRel, "")
+			}
+		case name == "Dockerfile" || strings.HasPrefix(name, "Dockerfile.") || strings.HasSuffix(name, ".Dockerfile"):
+			ctx.add(KindDocker, rel, "")
+		case name == "docker-compose.yml" || name == "docker-compose.yaml" || name == "compose.yml" || name == "compose.yaml":
+			ctx.add(KindCompose, rel, "")
+		default:
+			if provider := ciProvider(rel); provider != "" {
+				ctx.add(KindCI, rel, provider)
+			}
+		}
+		return nil
+	})
+	if walkErr != nil {
+		return nil, walkErr
+	}
+
+	sort.SliceStable(ctx.Components, func(i, j int) bool {
+		if ctx.Components[i].Kind != ctx.Components[j].Kind {
+			return ctx.Components[i].Kind < ctx.Components[j].Kind
+		}
+		return ctx.Components[i].Path < ctx.Components[j].Path
+	})
+	return ctx, nil
+}
+
+func (c *Context) add(kind Kind, path, detail string) {
+	c.Components = append(c.Components, Component{Kind: kind, Path: path, Detail: detail})
+}
+
+// Stacks returns the distinct component kinds present, sorted.
+func (c *Context) Stacks() []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, comp := range c.Components {
+		if !seen[string(comp.Kind)] {
+			seen[string(comp.Kind)] = true
+			out = append(out, string(comp.Kind))
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Summary renders the context as a compact block suitable for prepending to a prompt.
+func (c *Context) Summary() string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Workspace: %s", c.Root))
+	if c.Git {
+		builder.WriteString(" (git repository)")
+	}
+	builder.WriteString("\n")
+	if len(c.Components) == 0 {
+		builder.WriteString("No kustomize, helm, terraform, docker, or CI files detected.\n")
+		return builder.String()
+	}
+	builder.WriteString(fmt.Sprintf("Stack: %s\n", strings.Join(c.Stacks(), ", ")))
+	for _, comp := range c.Components {
+		line := fmt.Sprintf("- %s %s", comp.Kind, comp.Path)
+		if comp.Detail != "" {
+			line = fmt.Sprintf("%s (%s)", line, comp.Detail)
+		}
+		builder.WriteString(line)
+		builder.WriteString("\n")
+	}
+	if c.Truncated {
+		builder.WriteString(fmt.Sprintf("(listing truncated at %d entries)\n", maxComponents))
+	}
+	return builder.String()
+}
+
+// GitRoot walks up from dir to the nearest directory containing .git.
+func GitRoot(dir string) (string, bool) {
+	for current := dir; ; {
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			return current, true
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return "", false
+		}
+		current = parent
+	}
+}
+
+func chartName(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	var chart struct {
+		Name    string `yaml:"name"`
+		Version string `yaml:"version"`
+	}
+	if err := yaml.Unmarshal(data, &chart); err != nil {
+		return ""
+	}
+	if chart.Version != "" {
+		return fmt.Sprintf("%s %s", chart.Name, chart.Version)
+	}
+	return chart.Name
+}
+
+func ciProvider(rel string) string {
+	switch {
+	case strings.HasPrefix(rel, ".github/workflows/") && (strings.HasSuffix(rel, ".yml") || strings.HasSuffix(rel, ".yaml")):
+		return "github-actions"
+	case rel == ".gitlab-ci.yml":
+		return "gitlab-ci"
+	case rel == ".circleci/config.yml":
+		return "circleci"
+	case rel == "azure-pipelines.yml":
+		return "azure-pipelines"
+	case rel == "Jenkinsfile":
+		return "jenkins"
+	case rel == "bitbucket-pipelines.yml":
+		return "bitbucket-pipelines"
+	}
+	return ""
+}